@@ -0,0 +1,10 @@
+// Package api contains the OpenAPI spec of the shortener HTTP API.
+// The spec is written by hand and is the source of the generated clients.
+package api
+
+import _ "embed" // for the spec
+
+// Spec is the OpenAPI 3 spec in YAML.
+//
+//go:embed openapi.yaml
+var Spec []byte
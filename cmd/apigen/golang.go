@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generateGo returns the source of the Go client package.
+func generateGo(spec *Spec, pkg, source string) ([]byte, error) {
+	var body printer
+	for _, s := range spec.Components.Schemas {
+		goSchema(&body, s.Key, s.Value)
+	}
+	for _, op := range spec.operations() {
+		goOperation(&body, op)
+	}
+
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http"}
+	if strings.Contains(body.String(), "url.PathEscape(") {
+		imports = append(imports, "net/url")
+	}
+	imports = append(imports, "strings")
+	if strings.Contains(body.String(), "time.Time") {
+		imports = append(imports, "time")
+	}
+
+	var p printer
+	p.printf("// Code generated by apigen from %s. DO NOT EDIT.", source)
+	p.printf("")
+	p.printf("package %s", pkg)
+	p.printf("")
+	p.printf("import (")
+	for _, imp := range imports {
+		p.printf("%q", imp)
+	}
+	p.printf(")")
+	p.printf("")
+	p.WriteString(goRuntime)
+	p.printf("")
+	p.Write(body.Bytes())
+
+	src, err := format.Source(p.Bytes())
+	if err != nil {
+		return p.Bytes(), fmt.Errorf("format Go source: %w", err)
+	}
+	return src, nil
+}
+
+// goSchema writes the type of the component schema.
+func goSchema(p *printer, name string, s *Schema) {
+	if s.Description != "" {
+		p.comment("//", name+" is "+lowerFirst(s.Description))
+	} else {
+		p.printf("// %s is the %s schema of the API.", name, name)
+	}
+
+	if s.Type != "object" {
+		p.printf("type %s %s", name, goType(s))
+		p.printf("")
+		return
+	}
+
+	p.printf("type %s struct {", name)
+	for _, prop := range s.Properties {
+		field := exported(prop.Key)
+		if prop.Value.Description != "" {
+			p.comment("//", field+" is "+lowerFirst(prop.Value.Description))
+		}
+		if len(prop.Value.Enum) > 0 {
+			p.printf("// One of: %s.", strings.Join(prop.Value.Enum, ", "))
+		}
+
+		typ := goType(prop.Value)
+		tag := prop.Key
+		if !s.IsRequired(prop.Key) {
+			tag += ",omitempty"
+			// omitempty has no effect on structs
+			if prop.Value.Ref != "" || prop.Value.Format == "date-time" {
+				typ = "*" + typ
+			}
+		}
+		p.printf("%s %s `json:%q`", field, typ, tag)
+	}
+	p.printf("}")
+	p.printf("")
+}
+
+// goType returns the Go type of the schema.
+func goType(s *Schema) string {
+	switch {
+	case s.Ref != "":
+		return s.RefName()
+	case s.Type == "array":
+		return "[]" + goType(s.Items)
+	case s.Type == "string" && s.Format == "date-time":
+		return "time.Time"
+	case s.Type == "string" && s.Format == "binary":
+		return "[]byte"
+	case s.Type == "string":
+		return "string"
+	case s.Type == "integer" && s.Format == "int64":
+		return "int64"
+	case s.Type == "integer":
+		return "int"
+	case s.Type == "number":
+		return "float64"
+	case s.Type == "boolean":
+		return "bool"
+	default:
+		return "map[string]any"
+	}
+}
+
+// goOperation writes the response type and the client method of the operation.
+func goOperation(p *printer, op *Operation) {
+	name := op.OperationID
+	resName := name + "Response"
+	jsonResponses := op.jsonResponses()
+
+	p.printf("// %s is the response of %s.", resName, name)
+	p.printf("type %s struct {", resName)
+	p.printf("// HTTPResponse is the raw response, its body is already read.")
+	p.printf("HTTPResponse *http.Response")
+	p.printf("// Body is the raw response body.")
+	p.printf("Body []byte")
+	for _, r := range jsonResponses {
+		typ := goType(r.Value)
+		p.printf("// JSON%s is the decoded body of the %s response.", r.Key, r.Key)
+		p.printf("JSON%s *%s", r.Key, typ)
+	}
+	p.printf("}")
+	p.printf("")
+	p.printf("// StatusCode returns the HTTP status code of the response.")
+	p.printf("func (r *%s) StatusCode() int {", resName)
+	p.printf("return r.HTTPResponse.StatusCode")
+	p.printf("}")
+	p.printf("")
+
+	args := []string{"ctx context.Context"}
+	for _, param := range append(op.params("path"), op.required("header")...) {
+		args = append(args, unexported(param.Name)+" "+goType(param.Schema))
+	}
+	contentType, bodySchema := op.body()
+	switch {
+	case bodySchema == nil:
+	case contentType == contentJSON:
+		typ := goType(bodySchema)
+		args = append(args, "body "+typ)
+	case contentType == contentText:
+		args = append(args, "body string")
+	default:
+		args = append(args, "body io.Reader")
+	}
+	// optional header and query parameters are omitted if nil
+	for _, param := range append(op.optional("header"), op.params("query")...) {
+		typ := goType(param.Schema)
+		if !param.Required {
+			typ = "*" + typ
+		}
+		args = append(args, unexported(param.Name)+" "+typ)
+	}
+	args = append(args, "reqEditors ...RequestEditorFn")
+
+	p.printf("// %s %s", name, sentence(op.Summary))
+	if op.Description != "" {
+		p.printf("//")
+		p.comment("//", op.Description)
+	}
+	p.printf("//")
+	p.printf("//\t%s %s", op.Method, op.Path)
+	p.printf("func (c *Client) %s(%s) (*%s, error) {", name, strings.Join(args, ", "), resName)
+
+	switch {
+	case bodySchema == nil:
+		p.printf("var reqBody io.Reader")
+	case contentType == contentJSON:
+		p.printf("reqBody, err := jsonBody(body)")
+		p.printf("if err != nil {")
+		p.printf("return nil, err")
+		p.printf("}")
+	case contentType == contentText:
+		p.printf("reqBody := strings.NewReader(body)")
+	default:
+		p.printf("reqBody := body")
+	}
+
+	p.printf("req, err := c.newRequest(ctx, %q, %s, %q, reqBody)", op.Method, goPath(op), contentType)
+	p.printf("if err != nil {")
+	p.printf("return nil, err")
+	p.printf("}")
+	for _, param := range op.params("header") {
+		name := unexported(param.Name)
+		if param.Required {
+			p.printf("req.Header.Set(%q, fmt.Sprint(%s))", param.Name, name)
+			continue
+		}
+		p.printf("if %s != nil {", name)
+		p.printf("req.Header.Set(%q, fmt.Sprint(*%s))", param.Name, name)
+		p.printf("}")
+	}
+	if query := op.params("query"); len(query) > 0 {
+		p.printf("query := req.URL.Query()")
+		for _, param := range query {
+			name := unexported(param.Name)
+			if param.Required {
+				p.printf("query.Set(%q, fmt.Sprint(%s))", param.Name, name)
+				continue
+			}
+			p.printf("if %s != nil {", name)
+			p.printf("query.Set(%q, fmt.Sprint(*%s))", param.Name, name)
+			p.printf("}")
+		}
+		p.printf("req.URL.RawQuery = query.Encode()")
+	}
+	p.printf("")
+	p.printf("httpRes, resBody, err := c.do(ctx, req, reqEditors)")
+	p.printf("if err != nil {")
+	p.printf("return nil, err")
+	p.printf("}")
+	p.printf("")
+	p.printf("res := &%s{HTTPResponse: httpRes, Body: resBody}", resName)
+	if len(jsonResponses) > 0 {
+		p.printf("if !isJSON(httpRes) {")
+		p.printf("return res, nil")
+		p.printf("}")
+		p.printf("")
+		p.printf("switch httpRes.StatusCode {")
+		for _, r := range jsonResponses {
+			typ := goType(r.Value)
+			p.printf("case %s:", r.Key)
+			p.printf("var dest %s", typ)
+			p.printf("if err = json.Unmarshal(resBody, &dest); err != nil {")
+			p.printf("return nil, fmt.Errorf(\"decode %s response: %%w\", err)", r.Key)
+			p.printf("}")
+			p.printf("res.JSON%s = &dest", r.Key)
+		}
+		p.printf("}")
+		p.printf("")
+	}
+	p.printf("return res, nil")
+	p.printf("}")
+	p.printf("")
+}
+
+// goPath returns the Go expression building the path of the operation.
+func goPath(op *Operation) string {
+	var parts []string
+	rest := op.Path
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest, '}')
+		parts = append(parts,
+			fmt.Sprintf("%q", rest[:start]),
+			fmt.Sprintf("url.PathEscape(%s)", unexported(rest[start+1:end])))
+		rest = rest[end+1:]
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// goRuntime is the part of the client independent of the spec.
+const goRuntime = `// HTTPDoer sends the HTTP requests, *http.Client implements it.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RequestEditorFn modifies the request before it is sent,
+// e.g. to set the headers or cookies.
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// Client is the API client.
+type Client struct {
+	// Server is the base URL of the API, e.g. "http://localhost:8080".
+	Server string
+	// HTTPClient sends the requests.
+	HTTPClient HTTPDoer
+	// RequestEditors are applied to every request.
+	RequestEditors []RequestEditorFn
+}
+
+// ClientOption configures the client.
+type ClientOption func(*Client) error
+
+// WithHTTPClient sets the client sending the requests. It should not
+// follow redirects, so that the redirect responses are returned.
+func WithHTTPClient(doer HTTPDoer) ClientOption {
+	return func(c *Client) error {
+		c.HTTPClient = doer
+		return nil
+	}
+}
+
+// WithRequestEditorFn adds the editor applied to every request.
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+// NewClient returns the client of the API at the server base URL.
+// By default the requests are sent by the HTTP client that
+// does not follow redirects.
+func NewClient(server string, opts ...ClientOption) (*Client, error) {
+	c := &Client{Server: strings.TrimRight(server, "/")}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return c, nil
+}
+
+// newRequest returns the request to the path of the server.
+func (c *Client) newRequest(
+	ctx context.Context, method, path, contentType string, body io.Reader,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.Server+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// do applies the editors, sends the request and reads the response body.
+func (c *Client) do(
+	ctx context.Context, req *http.Request, reqEditors []RequestEditorFn,
+) (*http.Response, []byte, error) {
+	editors := append(append([]RequestEditorFn(nil), c.RequestEditors...), reqEditors...)
+	for _, edit := range editors {
+		if err := edit(ctx, req); err != nil {
+			return nil, nil, fmt.Errorf("edit request: %w", err)
+		}
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+	return res, body, nil
+}
+
+// jsonBody encodes the request body.
+func jsonBody(v any) (io.Reader, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	return &buf, nil
+}
+
+// isJSON reports whether the response body is JSON.
+func isJSON(res *http.Response) bool {
+	return strings.HasPrefix(res.Header.Get("Content-Type"), "application/json")
+}
+`
@@ -0,0 +1,58 @@
+// Apigen generates the API clients from the OpenAPI spec.
+//
+// It supports the subset of OpenAPI 3 used by the shortener spec:
+// path and header parameters, JSON, plain text and binary request bodies,
+// and JSON response bodies referring to the component schemas. It writes
+// the typed Go client package and the TypeScript client module depending
+// only on the fetch API. It is run by go generate in pkg/apiclient.
+//
+// Usage:
+//
+//	apigen -spec api/openapi.yaml -go client.gen.go -package apiclient -ts ts/client.gen.ts
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("apigen: ")
+
+	specPath := flag.String("spec", "api/openapi.yaml", "path of the OpenAPI spec")
+	goPath := flag.String("go", "", "path of the generated Go client, skipped if empty")
+	pkg := flag.String("package", "apiclient", "package of the generated Go client")
+	tsPath := flag.String("ts", "", "path of the generated TypeScript client, skipped if empty")
+	flag.Parse()
+
+	spec, err := load(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source := filepath.ToSlash(filepath.Base(*specPath))
+
+	if *goPath != "" {
+		src, err := generateGo(spec, *pkg, source)
+		if err != nil {
+			log.Fatal(err)
+		}
+		write(*goPath, src)
+	}
+
+	if *tsPath != "" {
+		write(*tsPath, generateTS(spec, source))
+	}
+}
+
+// write writes the generated file creating its directory.
+func write(path string, src []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
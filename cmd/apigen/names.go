@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words written in upper case in Go names.
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true,
+	"JSON": true, "OS": true, "URL": true, "UUID": true,
+}
+
+// words splits the name into words on separators and case changes,
+// keeping the upper case runs like "URL" in "shortURL" together.
+func words(name string) []string {
+	var res []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			// the last upper case letter of the run starts the next word: "URLDetails"
+			upperRunEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) &&
+				unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || upperRunEnd {
+				res = append(res, string(runes[start:i]))
+				start = i
+			}
+		}
+		res = append(res, string(runes[start:]))
+	}
+	return res
+}
+
+// exported returns the exported Go name: "short_url" becomes "ShortURL".
+func exported(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		if initialisms[strings.ToUpper(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// unexported returns the unexported Go name: "Upload-Offset" becomes "uploadOffset".
+func unexported(name string) string {
+	ws := words(name)
+	if len(ws) == 0 {
+		return ""
+	}
+	first := strings.ToLower(ws[0])
+	return first + strings.TrimPrefix(exported(name), exported(ws[0]))
+}
+
+// lowerFirst lowercases the first letter: "ShortenJSON" becomes "shortenJSON".
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// sentence turns the summary into the continuation of the doc comment
+// starting with the name: "Shortens the URL." becomes "shortens the URL.".
+func sentence(summary string) string {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "calls the API."
+	}
+	ws := words(summary)
+	if len(ws) > 0 && initialisms[ws[0]] {
+		return summary
+	}
+	return lowerFirst(summary)
+}
+
+// printer accumulates the generated source.
+type printer struct {
+	bytes.Buffer
+}
+
+// printf writes the formatted line.
+func (p *printer) printf(format string, args ...any) {
+	fmt.Fprintf(&p.Buffer, format, args...)
+	p.WriteByte('\n')
+}
+
+// comment writes the text as the comment with the given line prefix.
+func (p *printer) comment(prefix, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		p.printf("%s%s", prefix, strings.TrimRight(" "+line, " "))
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the subset of the OpenAPI 3 document the generator supports.
+type Spec struct {
+	Info struct {
+		Title   string `yaml:"title"`
+		Version string `yaml:"version"`
+	} `yaml:"info"`
+	Paths      ordered[ordered[*Operation]] `yaml:"paths"`
+	Components struct {
+		Schemas ordered[*Schema] `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// Operation is a single API operation.
+type Operation struct {
+	OperationID string             `yaml:"operationId"`
+	Summary     string             `yaml:"summary"`
+	Description string             `yaml:"description"`
+	Parameters  []*Parameter       `yaml:"parameters"`
+	RequestBody *RequestBody       `yaml:"requestBody"`
+	Responses   ordered[*Response] `yaml:"responses"`
+
+	// Method and Path are filled from the paths object.
+	Method string `yaml:"-"`
+	Path   string `yaml:"-"`
+}
+
+// Parameter is a path, header or query parameter of the operation.
+type Parameter struct {
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *Schema `yaml:"schema"`
+}
+
+// RequestBody is the body of the operation request.
+// Only the first content type is used.
+type RequestBody struct {
+	Required bool                `yaml:"required"`
+	Content  ordered[*MediaType] `yaml:"content"`
+}
+
+// Response is the operation response of a single status code.
+type Response struct {
+	Description string              `yaml:"description"`
+	Content     ordered[*MediaType] `yaml:"content"`
+}
+
+// MediaType is the body of the given content type.
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema is the data type definition.
+type Schema struct {
+	Ref         string           `yaml:"$ref"`
+	Type        string           `yaml:"type"`
+	Format      string           `yaml:"format"`
+	Description string           `yaml:"description"`
+	Enum        []string         `yaml:"enum"`
+	Required    []string         `yaml:"required"`
+	Properties  ordered[*Schema] `yaml:"properties"`
+	Items       *Schema          `yaml:"items"`
+}
+
+// IsRequired reports whether the property is required.
+func (s *Schema) IsRequired(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RefName returns the name of the referenced component schema.
+func (s *Schema) RefName() string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// pair is the key and value of the ordered mapping.
+type pair[T any] struct {
+	Key   string
+	Value T
+}
+
+// ordered is the YAML mapping keeping the order of the document,
+// so that the generated code follows the spec.
+type ordered[T any] []pair[T]
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (o *ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: mapping expected", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, pair[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
+
+// load reads and validates the spec.
+func load(path string) (*Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read spec: %w", err)
+	}
+
+	var spec Spec
+	if err = yaml.Unmarshal(b, &spec); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+
+	schemas := make(map[string]bool, len(spec.Components.Schemas))
+	for _, s := range spec.Components.Schemas {
+		schemas[s.Key] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range spec.Paths {
+		for _, m := range p.Value {
+			op := m.Value
+			op.Method = strings.ToUpper(m.Key)
+			op.Path = p.Key
+
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s: no operationId", op.Method, op.Path)
+			}
+			if seen[op.OperationID] {
+				return nil, fmt.Errorf("%s: duplicate operationId", op.OperationID)
+			}
+			seen[op.OperationID] = true
+
+			for _, param := range op.Parameters {
+				if param.In != "path" && param.In != "header" && param.In != "query" {
+					return nil, fmt.Errorf("%s: %s parameters are not supported",
+						op.OperationID, param.In)
+				}
+			}
+			for _, r := range op.Responses {
+				if _, err = strconv.Atoi(r.Key); err != nil {
+					return nil, fmt.Errorf("%s: response %q: numeric status code expected",
+						op.OperationID, r.Key)
+				}
+			}
+			if err = checkRefs(op, schemas); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+
+	return &spec, nil
+}
+
+// checkRefs checks that all the schemas the operation refers to exist.
+func checkRefs(op *Operation, schemas map[string]bool) error {
+	var check func(s *Schema) error
+	check = func(s *Schema) error {
+		switch {
+		case s == nil:
+			return nil
+		case s.Ref != "":
+			if !schemas[s.RefName()] {
+				return fmt.Errorf("unknown schema %s", s.Ref)
+			}
+			return nil
+		default:
+			return check(s.Items)
+		}
+	}
+
+	if op.RequestBody != nil {
+		for _, c := range op.RequestBody.Content {
+			if err := check(c.Value.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range op.Responses {
+		for _, c := range r.Value.Content {
+			if err := check(c.Value.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// operations returns all the operations of the spec sorted by ID.
+func (s *Spec) operations() []*Operation {
+	var ops []*Operation
+	for _, p := range s.Paths {
+		for _, m := range p.Value {
+			ops = append(ops, m.Value)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+// params returns the parameters of the operation in the given location.
+func (op *Operation) params(in string) []*Parameter {
+	var res []*Parameter
+	for _, param := range op.Parameters {
+		if param.In == in {
+			res = append(res, param)
+		}
+	}
+	return res
+}
+
+// required returns the required parameters of the operation in the given location.
+func (op *Operation) required(in string) []*Parameter {
+	var res []*Parameter
+	for _, param := range op.params(in) {
+		if param.Required {
+			res = append(res, param)
+		}
+	}
+	return res
+}
+
+// optional returns the optional parameters of the operation in the given location.
+func (op *Operation) optional(in string) []*Parameter {
+	var res []*Parameter
+	for _, param := range op.params(in) {
+		if !param.Required {
+			res = append(res, param)
+		}
+	}
+	return res
+}
+
+// body returns the content type and schema of the request body, if any.
+func (op *Operation) body() (string, *Schema) {
+	if op.RequestBody == nil || len(op.RequestBody.Content) == 0 {
+		return "", nil
+	}
+	c := op.RequestBody.Content[0]
+	return c.Key, c.Value.Schema
+}
+
+// jsonResponses returns the status codes and schemas
+// of the responses with JSON bodies.
+func (op *Operation) jsonResponses() []pair[*Schema] {
+	var res []pair[*Schema]
+	for _, r := range op.Responses {
+		for _, c := range r.Value.Content {
+			if c.Key == contentJSON && c.Value.Schema != nil {
+				res = append(res, pair[*Schema]{Key: r.Key, Value: c.Value.Schema})
+			}
+		}
+	}
+	return res
+}
+
+// Content types with the special handling.
+const (
+	contentJSON = "application/json"
+	contentText = "text/plain"
+)
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generateTS returns the source of the TypeScript client module.
+// The client depends only on the fetch API.
+func generateTS(spec *Spec, source string) []byte {
+	var p printer
+	p.printf("// Code generated by apigen from %s. DO NOT EDIT.", source)
+	p.printf("")
+
+	for _, s := range spec.Components.Schemas {
+		tsSchema(&p, s.Key, s.Value)
+	}
+
+	p.WriteString(tsRuntime)
+	p.printf("")
+	p.printf("/** Client is the %s client. */", spec.Info.Title)
+	p.printf("export class Client {")
+	p.printf("  private readonly server: string;")
+	p.printf("  private readonly options: ClientOptions;")
+	p.printf("")
+	p.printf("  /** server is the base URL of the API, e.g. \"http://localhost:8080\". */")
+	p.printf("  constructor(server: string, options: ClientOptions = {}) {")
+	p.printf("    this.server = server.replace(/\\/+$/, \"\");")
+	p.printf("    this.options = options;")
+	p.printf("  }")
+
+	ops := spec.operations()
+	for _, op := range ops {
+		p.printf("")
+		tsMethod(&p, op)
+	}
+
+	p.printf("")
+	p.WriteString(tsDo)
+	p.printf("}")
+
+	for _, op := range ops {
+		p.printf("")
+		tsResponse(&p, op)
+	}
+
+	return p.Bytes()
+}
+
+// tsSchema writes the type of the component schema.
+func tsSchema(p *printer, name string, s *Schema) {
+	if s.Description != "" {
+		p.printf("/** %s */", strings.TrimSpace(s.Description))
+	}
+
+	if s.Type != "object" {
+		p.printf("export type %s = %s;", name, tsType(s))
+		p.printf("")
+		return
+	}
+
+	p.printf("export interface %s {", name)
+	for _, prop := range s.Properties {
+		if prop.Value.Description != "" {
+			p.printf("  /** %s */", strings.TrimSpace(prop.Value.Description))
+		}
+		optional := "?"
+		if s.IsRequired(prop.Key) {
+			optional = ""
+		}
+		p.printf("  %s%s: %s;", prop.Key, optional, tsType(prop.Value))
+	}
+	p.printf("}")
+	p.printf("")
+}
+
+// tsType returns the TypeScript type of the schema.
+func tsType(s *Schema) string {
+	switch {
+	case s.Ref != "":
+		return s.RefName()
+	case s.Type == "array":
+		return tsType(s.Items) + "[]"
+	case s.Type == "string" && len(s.Enum) > 0:
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprintf("%q", v)
+		}
+		return strings.Join(values, " | ")
+	case s.Type == "string":
+		return "string"
+	case s.Type == "integer", s.Type == "number":
+		return "number"
+	case s.Type == "boolean":
+		return "boolean"
+	default:
+		return "Record<string, unknown>"
+	}
+}
+
+// tsMethod writes the client method of the operation.
+func tsMethod(p *printer, op *Operation) {
+	method := lowerFirst(op.OperationID)
+	resName := op.OperationID + "Response"
+
+	var args []string
+	for _, param := range append(op.params("path"), op.required("header")...) {
+		args = append(args, unexported(param.Name)+": "+tsType(param.Schema))
+	}
+	contentType, bodySchema := op.body()
+	body := "undefined"
+	switch {
+	case bodySchema == nil:
+	case contentType == contentJSON:
+		args = append(args, "body: "+tsType(bodySchema))
+		body = "JSON.stringify(body)"
+	case contentType == contentText:
+		args = append(args, "body: string")
+		body = "body"
+	default:
+		args = append(args, "body: BodyInit")
+		body = "body"
+	}
+	for _, param := range append(op.optional("header"), op.params("query")...) {
+		optional := "?"
+		if param.Required {
+			optional = ""
+		}
+		args = append(args, unexported(param.Name)+optional+": "+tsType(param.Schema))
+	}
+	args = append(args, "init?: RequestInit")
+
+	var headers []string
+	if contentType != "" {
+		headers = append(headers, fmt.Sprintf("%q: %q", "Content-Type", contentType))
+	}
+	for _, param := range op.params("header") {
+		name := unexported(param.Name)
+		if param.Required {
+			headers = append(headers, fmt.Sprintf("%q: String(%s)", param.Name, name))
+			continue
+		}
+		// optional headers are omitted if undefined
+		headers = append(headers, fmt.Sprintf("...(%s !== undefined ? { %q: String(%s) } : {})",
+			name, param.Name, name))
+	}
+
+	p.printf("  /**")
+	p.printf("   * %s %s", method, sentence(op.Summary))
+	if op.Description != "" {
+		p.printf("   *")
+		p.comment("   *", op.Description)
+	}
+	p.printf("   *")
+	p.printf("   * %s %s", op.Method, op.Path)
+	p.printf("   */")
+	p.printf("  async %s(%s): Promise<%s> {", method, strings.Join(args, ", "), resName)
+	headersObject := "{}"
+	if len(headers) > 0 {
+		headersObject = "{ " + strings.Join(headers, ", ") + " }"
+	}
+	path := tsPath(op)
+	if query := op.params("query"); len(query) > 0 {
+		p.printf("    const query = new URLSearchParams();")
+		for _, param := range query {
+			name := unexported(param.Name)
+			if param.Required {
+				p.printf("    query.set(%q, String(%s));", param.Name, name)
+				continue
+			}
+			p.printf("    if (%s !== undefined) query.set(%q, String(%s));", name, param.Name, name)
+		}
+		path += " + (query.toString() ? `?${query}` : \"\")"
+	}
+	p.printf("    const res: %s = await this.do(%q, %s, %s, %s, init);",
+		resName, op.Method, path, headersObject, body)
+
+	if jsonResponses := op.jsonResponses(); len(jsonResponses) > 0 {
+		p.printf("    if (isJSON(res)) {")
+		p.printf("      switch (res.status) {")
+		for _, r := range jsonResponses {
+			p.printf("        case %s:", r.Key)
+			p.printf("          res.json%s = JSON.parse(res.body) as %s;", r.Key, tsType(r.Value))
+			p.printf("          break;")
+		}
+		p.printf("      }")
+		p.printf("    }")
+	}
+	p.printf("    return res;")
+	p.printf("  }")
+}
+
+// tsResponse writes the response type of the operation.
+func tsResponse(p *printer, op *Operation) {
+	p.printf("/** %sResponse is the response of %s. */", op.OperationID, lowerFirst(op.OperationID))
+	p.printf("export interface %sResponse extends ClientResponse {", op.OperationID)
+	for _, r := range op.jsonResponses() {
+		p.printf("  /** json%s is the decoded body of the %s response. */", r.Key, r.Key)
+		p.printf("  json%s?: %s;", r.Key, tsType(r.Value))
+	}
+	p.printf("}")
+}
+
+// tsPath returns the TypeScript expression building the path of the operation.
+func tsPath(op *Operation) string {
+	var b strings.Builder
+	b.WriteByte('`')
+	rest := op.Path
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest, '}')
+		fmt.Fprintf(&b, "%s${encodeURIComponent(%s)}", rest[:start], unexported(rest[start+1:end]))
+		rest = rest[end+1:]
+	}
+	b.WriteString(rest)
+	b.WriteByte('`')
+	return b.String()
+}
+
+// tsRuntime is the part of the client module independent of the spec.
+const tsRuntime = `/** ClientResponse is the raw response of the API. */
+export interface ClientResponse {
+  status: number;
+  headers: Headers;
+  body: string;
+}
+
+/** ClientOptions configures the client. */
+export interface ClientOptions {
+  /** fetch sends the requests, the global fetch by default. */
+  fetch?: typeof fetch;
+  /** init is applied to every request, e.g. to set the credentials mode. */
+  init?: RequestInit;
+}
+
+function isJSON(res: ClientResponse): boolean {
+  return (res.headers.get("Content-Type") ?? "").startsWith("application/json");
+}
+`
+
+// tsDo is the method of the client sending the requests.
+const tsDo = `  private async do(
+    method: string,
+    path: string,
+    headers: Record<string, string>,
+    body: BodyInit | undefined,
+    init?: RequestInit,
+  ): Promise<ClientResponse> {
+    const merged = new Headers(this.options.init?.headers);
+    new Headers(init?.headers).forEach((value, key) => merged.set(key, value));
+    Object.entries(headers).forEach(([key, value]) => merged.set(key, value));
+
+    const send = this.options.fetch ?? fetch;
+    const response = await send(this.server + path, {
+      credentials: "include",
+      redirect: "manual",
+      ...this.options.init,
+      ...init,
+      method,
+      headers: merged,
+      body,
+    });
+    return { status: response.status, headers: response.headers, body: await response.text() };
+  }
+`
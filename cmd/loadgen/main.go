@@ -0,0 +1,94 @@
+// Loadgen is a load generator for the shortener HTTP API.
+//
+// It runs the configured number of concurrent virtual users, each with its
+// own authorization cookie, issuing a random mix of shorten, redirect and
+// delete requests for the given duration, and reports latency percentiles
+// per operation. The same seed produces the same sequence of operations.
+//
+// Usage:
+//
+//	loadgen -target http://localhost:8080 -c 50 -d 1m -mix shorten=10,redirect=85,delete=5
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	target := flag.String("target", "http://localhost:8080", "base URL of the target instance")
+	concurrency := flag.Int("c", 10, "number of concurrent virtual users")
+	duration := flag.Duration("d", 30*time.Second, "test duration")
+	mixFlag := flag.String("mix", "shorten=10,redirect=85,delete=5", "relative weights of the operations")
+	timeout := flag.Duration("timeout", 5*time.Second, "single request timeout")
+	seed := flag.Int64("seed", 1, "random generator seed")
+	insecure := flag.Bool("insecure", false, "skip TLS certificate verification")
+	flag.Parse()
+
+	m, err := parseMix(*mixFlag)
+	if err != nil {
+		log.Fatalf("invalid mix: %v", err)
+	}
+	if *concurrency <= 0 {
+		log.Fatal("concurrency should be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = *concurrency
+	if *insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // test tool
+	}
+
+	rec := newRecorder()
+	codes := newPool(poolSize)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < *concurrency; i++ {
+		jar, _ := cookiejar.New(nil)
+		w := &worker{
+			target: strings.TrimRight(*target, "/"),
+			client: &http.Client{
+				Transport: transport,
+				Jar:       jar,
+				Timeout:   *timeout,
+				// redirects are measured, not followed
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+			id:    i,
+			rnd:   newRand(*seed, i),
+			mix:   m,
+			codes: codes,
+			rec:   rec,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	rec.report(os.Stdout, time.Since(start))
+	fmt.Printf("\n%d virtual users against %s\n", *concurrency, *target)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// percentiles are reported for every operation.
+var percentiles = []float64{0.5, 0.9, 0.99}
+
+// recorder collects latencies and errors of the operations.
+// It is safe for concurrent use.
+type recorder struct {
+	mu        sync.Mutex
+	latencies [numOps][]time.Duration
+	errors    [numOps]int
+	// lastErr is the last error of each operation, shown in the report.
+	lastErr [numOps]error
+}
+
+// newRecorder returns an empty recorder.
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+// record saves the result of a single operation.
+func (r *recorder) record(o op, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.errors[o]++
+		r.lastErr[o] = err
+		return
+	}
+	r.latencies[o] = append(r.latencies[o], latency)
+}
+
+// report writes the summary table of the successful requests
+// of every operation performed during elapsed time.
+func (r *recorder) report(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "op\tok\terrors\trps\t")
+	for _, p := range percentiles {
+		fmt.Fprintf(tw, "p%g\t", p*100)
+	}
+	fmt.Fprintln(tw, "max\t")
+
+	for o := op(0); o < numOps; o++ {
+		l := r.latencies[o]
+		if len(l) == 0 && r.errors[o] == 0 {
+			continue
+		}
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t", o, len(l), r.errors[o],
+			float64(len(l))/elapsed.Seconds())
+		for _, p := range percentiles {
+			fmt.Fprintf(tw, "%s\t", percentile(l, p))
+		}
+		fmt.Fprintf(tw, "%s\t\n", percentile(l, 1))
+	}
+	_ = tw.Flush()
+
+	for o := op(0); o < numOps; o++ {
+		if r.lastErr[o] != nil {
+			fmt.Fprintf(w, "last %s error: %v\n", o, r.lastErr[o])
+		}
+	}
+}
+
+// percentile returns the p-th percentile of the sorted latencies
+// using the nearest rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)].Round(time.Microsecond)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// op is a single kind of the API request.
+type op int
+
+const (
+	opShorten op = iota
+	opRedirect
+	opDelete
+	numOps
+)
+
+// opNames are the names of the operations used in the mix and the report.
+var opNames = [numOps]string{"shorten", "redirect", "delete"}
+
+// String returns the name of the operation.
+func (o op) String() string {
+	return opNames[o]
+}
+
+const (
+	// poolSize is the number of recently shortened codes used for redirects.
+	poolSize = 10000
+	// deleteBatchSize is the maximum number of codes deleted in one request.
+	deleteBatchSize = 10
+)
+
+// mix is the relative weights of the operations.
+type mix [numOps]int
+
+// parseMix parses the comma separated list of name=weight pairs.
+// Operations that are not listed get zero weight.
+func parseMix(s string) (mix, error) {
+	var m mix
+	total := 0
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return m, fmt.Errorf("%q: expected name=weight", pair)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return m, fmt.Errorf("%q: weight should be a non negative integer", pair)
+		}
+		found := false
+		for o, opName := range opNames {
+			if opName == name {
+				m[o] = weight
+				found = true
+			}
+		}
+		if !found {
+			return m, fmt.Errorf("%q: unknown operation", name)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return m, errors.New("total weight should be positive")
+	}
+	return m, nil
+}
+
+// pick returns a random operation according to the weights.
+func (m mix) pick(rnd *rand.Rand) op {
+	total := 0
+	for _, w := range m {
+		total += w
+	}
+	n := rnd.Intn(total)
+	for o, w := range m {
+		if n < w {
+			return op(o)
+		}
+		n -= w
+	}
+	return opShorten
+}
+
+// newRand returns the random generator of the i-th virtual user.
+func newRand(seed int64, i int) *rand.Rand {
+	return rand.New(rand.NewSource(seed + int64(i)))
+}
+
+// pool is a bounded ring of the shortened codes shared by the virtual users.
+// It is safe for concurrent use.
+type pool struct {
+	mu    sync.Mutex
+	codes []string
+	next  int
+}
+
+// newPool returns a pool keeping up to size most recent codes.
+func newPool(size int) *pool {
+	return &pool{codes: make([]string, 0, size)}
+}
+
+// add puts the code to the pool evicting the oldest one if it is full.
+func (p *pool) add(code string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.codes) < cap(p.codes) {
+		p.codes = append(p.codes, code)
+		return
+	}
+	p.codes[p.next] = code
+	p.next = (p.next + 1) % len(p.codes)
+}
+
+// len returns the number of codes in the pool.
+func (p *pool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.codes)
+}
+
+// random returns a random code from the pool, false if it is empty.
+func (p *pool) random(rnd *rand.Rand) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.codes) == 0 {
+		return "", false
+	}
+	return p.codes[rnd.Intn(len(p.codes))], true
+}
+
+// worker is a single virtual user.
+type worker struct {
+	target string
+	client *http.Client
+	id     int
+	rnd    *rand.Rand
+	mix    mix
+	codes  *pool
+	rec    *recorder
+	// owned are the codes shortened by this user, which it may delete.
+	owned []string
+	// seq is the number of URLs shortened by this user.
+	seq int
+}
+
+// run issues requests until the context is done.
+func (w *worker) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		o := w.mix.pick(w.rnd)
+
+		// nothing to redirect to or delete yet
+		if o == opRedirect && w.codes.len() == 0 || o == opDelete && len(w.owned) == 0 {
+			o = opShorten
+		}
+
+		start := time.Now()
+		err := w.do(ctx, o)
+		// requests interrupted by the end of the test are not counted
+		if ctx.Err() != nil {
+			return
+		}
+		w.rec.record(o, time.Since(start), err)
+	}
+}
+
+// do performs a single operation.
+func (w *worker) do(ctx context.Context, o op) error {
+	switch o {
+	case opRedirect:
+		return w.redirect(ctx)
+	case opDelete:
+		return w.delete(ctx)
+	default:
+		return w.shorten(ctx)
+	}
+}
+
+// shorten shortens a new unique URL.
+func (w *worker) shorten(ctx context.Context) error {
+	w.seq++
+	body, _ := json.Marshal(map[string]string{
+		"url": fmt.Sprintf("https://loadgen.example.com/%d/%d/%d", w.id, w.seq, w.rnd.Int63()),
+	})
+
+	res, err := w.send(ctx, http.MethodPost, "/api/shorten", body,
+		http.StatusCreated, http.StatusConflict)
+	if err != nil {
+		return err
+	}
+
+	var payload struct {
+		Result string `json:"result"`
+	}
+	if err = json.Unmarshal(res, &payload); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	code := payload.Result[strings.LastIndex(payload.Result, "/")+1:]
+	w.codes.add(code)
+	w.owned = append(w.owned, code)
+
+	return nil
+}
+
+// redirect follows one of the recently shortened codes.
+// Deleted codes are answered with 410 Gone, which is not an error.
+func (w *worker) redirect(ctx context.Context) error {
+	code, _ := w.codes.random(w.rnd)
+	_, err := w.send(ctx, http.MethodGet, "/"+code, nil,
+		http.StatusTemporaryRedirect, http.StatusGone)
+	return err
+}
+
+// delete deletes a batch of the codes owned by the user.
+func (w *worker) delete(ctx context.Context) error {
+	n := min(1+w.rnd.Intn(deleteBatchSize), len(w.owned))
+	batch := w.owned[len(w.owned)-n:]
+	w.owned = w.owned[:len(w.owned)-n]
+
+	body, _ := json.Marshal(batch)
+	_, err := w.send(ctx, http.MethodDelete, "/api/user/urls", body, http.StatusAccepted)
+	return err
+}
+
+// send performs the request and checks the response status code.
+// It returns the response body.
+func (w *worker) send(
+	ctx context.Context, method, path string, body []byte, codes ...int,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, w.target+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	for _, code := range codes {
+		if res.StatusCode == code {
+			return resBody, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+}
@@ -11,15 +11,11 @@ import (
 	"syscall"
 	"time"
 
-	_ "net/http/pprof"
-
+	"github.com/KretovDmitry/shortener/internal/app"
 	"github.com/KretovDmitry/shortener/internal/config"
-	"github.com/KretovDmitry/shortener/internal/handler"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/repository"
-	"github.com/go-chi/chi/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
-	"golang.org/x/crypto/acme/autocert"
 )
 
 var (
@@ -41,7 +37,13 @@ func run() error {
 	defer serverStopCtx()
 
 	// Load application configuration.
-	cfg := config.MustLoad()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.ValidateOnly {
+		return validateConfig(serverCtx, cfg)
+	}
 
 	// Create root logger tagged with server version.
 	logger := logger.New(cfg).With(serverCtx, "version", buildVersion)
@@ -49,35 +51,42 @@ func run() error {
 		_ = logger.Sync()
 	}()
 
-	// Init URL repository.
-	store, err := repository.NewURLStore(cfg, logger)
-	if err != nil {
-		return fmt.Errorf("failed to init store: %w", err)
-	}
-
-	// Init HTTP handlers.
-	handler, err := handler.New(store, cfg, logger)
+	// Init the storage, the features it supports and the HTTP handlers.
+	app, err := app.New(cfg, logger, buildVersion)
 	if err != nil {
-		return fmt.Errorf("new handler: %w", err)
+		return err
 	}
-	// Stop async short URL deletion.
-	defer handler.Stop()
+	// Stop async short URL deletion and the background jobs of the store.
+	defer app.Close()
+	reloader, manager := app.Certificates, app.CertManager
 
 	// Init HTTP server.
 	hs := &http.Server{
 		Addr:              cfg.HTTPServer.RunAddress.String(),
 		ReadHeaderTimeout: cfg.HTTPServer.Timeout,
 		IdleTimeout:       cfg.HTTPServer.IdleTimeout,
-		Handler:           handler.Register(chi.NewRouter(), cfg, logger),
+		Handler:           app.Router(),
 	}
 
-	// Graceful shutdown.
+	// Graceful shutdown. SIGHUP reloads the configuration and
+	// the certificate files instead.
 	go func() {
 		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT,
 			syscall.SIGTERM, syscall.SIGQUIT, os.Interrupt)
 
 		signal := <-sig
+		for signal == syscall.SIGHUP {
+			reloadConfig(cfg, logger)
+			if reloader != nil {
+				if err := reloader.Reload(); err != nil {
+					logger.Errorf("reload TLS certificate: %s", err)
+				} else {
+					logger.Info("TLS certificate reloaded")
+				}
+			}
+			signal = <-sig
+		}
 
 		logger.With(serverCtx, "signal", signal.String()).
 			Infof("Shutting down server with %s timeout",
@@ -92,12 +101,30 @@ func run() error {
 	logger.Infof("Server has started: %s", cfg.HTTPServer.RunAddress)
 	logger.Infof("Return address: %s", cfg.HTTPServer.ReturnAddress)
 	if cfg.TLSEnabled {
-		cm := &autocert.Manager{
-			Cache:  autocert.DirCache("cache/certs"),
-			Prompt: autocert.AcceptTOS,
+		if reloader != nil {
+			hs.TLSConfig = reloader.TLSConfig()
+			logger.Infof("The server is running over the SSL protocol with the certificate from %s",
+				cfg.TLS.CertFile)
+		} else {
+			hs.TLSConfig = manager.TLSConfig()
+			logger.Info("The server is running over the SSL protocol")
+		}
+		// Answer the http-01 challenge if tls-alpn-01 fails.
+		if manager != nil && cfg.TLS.Challenge == config.ACMEChallengeHTTP {
+			challenges := &http.Server{
+				Addr:              cfg.TLS.HTTPChallengeAddress,
+				ReadHeaderTimeout: cfg.HTTPServer.Timeout,
+				Handler:           manager.HTTPHandler(nil),
+			}
+			go func() {
+				if err := challenges.ListenAndServe(); err != nil &&
+					!errors.Is(err, http.ErrServerClosed) {
+					logger.Errorf("run challenge server failed: %s", err)
+				}
+			}()
+			defer challenges.Close()
+			logger.Infof("The http-01 challenge is answered on %s", cfg.TLS.HTTPChallengeAddress)
 		}
-		hs.TLSConfig = cm.TLSConfig()
-		logger.Info("The server is running over the SSL protocol")
 		if err = hs.ListenAndServeTLS("", ""); err != nil &&
 			!errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("run server failed: %w", err)
@@ -119,6 +146,37 @@ func run() error {
 	return nil
 }
 
+// validateConfig prints the report of the configuration and the connections
+// to its databases. It fails if the server would not start with it.
+func validateConfig(ctx context.Context, cfg *config.Config) error {
+	report := cfg.Validate()
+	repository.CheckConnections(ctx, cfg, report)
+
+	fmt.Println(report)
+	if report.HasErrors() {
+		return errors.New("invalid config")
+	}
+	return nil
+}
+
+// reloadConfig applies the settings safe to change at runtime read again
+// from the configuration. The settings in use are kept if it is invalid.
+func reloadConfig(cfg *config.Config, log logger.Logger) {
+	r, err := config.ReadReloadable(cfg.Live())
+	if err != nil {
+		log.Errorf("reload config: %s", err)
+		return
+	}
+	if err = logger.SetLevel(r.LogLevel); err != nil {
+		log.Errorf("reload config: %s", err)
+		return
+	}
+	cfg.Apply(r)
+	log.Infof("Config reloaded: log level %s, trusted subnet %q, delete buffer length %d, "+
+		"rate limits %d per user and %d per IP per %s", r.LogLevel, r.TrustedSubnet,
+		r.DeleteBufLen, r.RateLimit.UserLimit, r.RateLimit.IPLimit, r.RateLimit.Period)
+}
+
 func printBuildInfo() {
 	if buildVersion == "" {
 		fmt.Println("Build version: N/A")
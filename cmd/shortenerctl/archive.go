@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/KretovDmitry/shortener/internal/archive"
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+// storeFlags are the flags selecting the storage of a command.
+type storeFlags struct {
+	dsn, redisDSN, path *string
+}
+
+// addStoreFlags adds the storage flags to the flag set.
+func addStoreFlags(fs *flag.FlagSet) storeFlags {
+	return storeFlags{
+		dsn:      fs.String("d", os.Getenv("DATABASE_DSN"), "server data source name"),
+		redisDSN: fs.String("r", os.Getenv("REDIS_DSN"), "redis URL"),
+		path:     fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path"),
+	}
+}
+
+// open returns the storage selected the same way the server does.
+func (f storeFlags) open() (repository.URLStorage, error) {
+	if *f.dsn == "" && *f.redisDSN == "" && *f.path == "" {
+		return nil, errors.New("neither database DSN, redis URL nor file storage path is set")
+	}
+
+	cfg := &config.Config{DSN: *f.dsn, FileStoragePath: *f.path}
+	cfg.Redis.DSN = *f.redisDSN
+	cfg.Redis.KeyPrefix = os.Getenv("REDIS_KEY_PREFIX")
+	if cfg.Redis.KeyPrefix == "" {
+		cfg.Redis.KeyPrefix = "shortener:"
+	}
+	cfg.FileStorage.EncryptionKey = os.Getenv("FILE_STORAGE_ENCRYPTION_KEY")
+	cfg.FileStorage.Integrity, _ = strconv.ParseBool(os.Getenv("FILE_STORAGE_INTEGRITY"))
+	cfg.FileStorage.IntegrityKey = os.Getenv("FILE_STORAGE_INTEGRITY_KEY")
+
+	store, err := repository.NewURLStore(cfg, logger.NewWithZap(zap.NewNop()))
+	if err != nil {
+		return nil, fmt.Errorf("init store: %w", err)
+	}
+	return store, nil
+}
+
+// runExport writes the archive of the whole instance, the same one
+// served at /api/admin/export, without the running server.
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	sf := addStoreFlags(fs)
+	out := fs.String("o", "", "archive file, standard output if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := sf.open()
+	if err != nil {
+		return err
+	}
+	scanner, err := repository.NewURLScanner(store)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return fmt.Errorf("create archive: %w", err)
+		}
+	}
+
+	settings := archive.Settings{
+		UserIDFormat: config.UserIDFormat(os.Getenv("USER_ID_FORMAT")),
+		JSONNaming:   config.JSONNaming(os.Getenv("JSON_NAMING")),
+	}
+	if settings.UserIDFormat == "" {
+		settings.UserIDFormat = config.UserIDFormatUUID
+	}
+	if settings.JSONNaming == "" {
+		settings.JSONNaming = config.JSONNamingSnakeCase
+	}
+
+	manifest, err := archive.Write(context.Background(), w, scanner, settings, "")
+	if err != nil {
+		return err
+	}
+	if w != os.Stdout {
+		if err = w.Close(); err != nil {
+			return fmt.Errorf("close archive: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d urls of %d users exported\n", manifest.URLs, manifest.Users)
+	return nil
+}
+
+// runImport restores the archive into the configured storage,
+// which is expected to be empty.
+func runImport(args []string) error {
+	fs := newFlagSet("import")
+	sf := addStoreFlags(fs)
+	in := fs.String("i", "", "archive file, standard input if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r := os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("open archive: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ar, err := archive.Open(r)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+
+	// opaque user IDs are rejected by the instances accepting UUIDs only
+	if ar.Settings.UserIDFormat == config.UserIDFormatOpaque &&
+		config.UserIDFormat(os.Getenv("USER_ID_FORMAT")) != config.UserIDFormatOpaque {
+		fmt.Fprintln(os.Stderr, "warning: the archive has opaque user IDs, "+
+			"set USER_ID_FORMAT=opaque on the target instance")
+	}
+
+	store, err := sf.open()
+	if err != nil {
+		return err
+	}
+
+	n, err := archive.Restore(context.Background(), ar, store)
+	if err != nil {
+		return fmt.Errorf("%d of %d urls imported: %w", n, ar.Manifest.URLs, err)
+	}
+
+	fmt.Printf("%d urls of %d users imported\n", n, ar.Manifest.Users)
+	return nil
+}
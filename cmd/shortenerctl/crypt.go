@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/KretovDmitry/shortener/internal/repository/filestore"
+)
+
+// runEncrypt encrypts all the records of the file storage with the given
+// key. Records already encrypted with one of the old keys are re-encrypted,
+// which makes it suitable for key rotation.
+func runEncrypt(args []string) error {
+	fs := newFlagSet("encrypt")
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path")
+	key := fs.String("key", os.Getenv("FILE_STORAGE_ENCRYPTION_KEY"),
+		"hex encoded encryption key")
+	oldKeys := fs.String("old-keys", os.Getenv("FILE_STORAGE_DECRYPTION_KEYS"),
+		"comma separated hex encoded keys the file may be encrypted with")
+	integrityKey := fs.String("integrity-key", os.Getenv("FILE_STORAGE_INTEGRITY_KEY"),
+		"integrity key the hash chain is rebuilt with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := requireFile(*path); err != nil {
+		return err
+	}
+	if *key == "" {
+		return errors.New("encryption key is not set")
+	}
+
+	cipher, err := filestore.NewCipher(*key, splitList(*oldKeys)...)
+	if err != nil {
+		return fmt.Errorf("new cipher: %w", err)
+	}
+
+	n, err := filestore.Migrate(*path, cipher, cipher, []byte(*integrityKey))
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+
+	fmt.Printf("%d records encrypted in %s\n", n, *path)
+	return nil
+}
+
+// runDecrypt decrypts all the records of the file storage
+// and stores them in plain text. The active key is not required
+// if the records are encrypted with the old keys only.
+func runDecrypt(args []string) error {
+	fs := newFlagSet("decrypt")
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path")
+	key := fs.String("key", os.Getenv("FILE_STORAGE_ENCRYPTION_KEY"),
+		"hex encoded encryption key")
+	oldKeys := fs.String("old-keys", os.Getenv("FILE_STORAGE_DECRYPTION_KEYS"),
+		"comma separated hex encoded keys the file may be encrypted with")
+	integrityKey := fs.String("integrity-key", os.Getenv("FILE_STORAGE_INTEGRITY_KEY"),
+		"integrity key the hash chain is rebuilt with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := requireFile(*path); err != nil {
+		return err
+	}
+	keys := splitList(*oldKeys)
+	if *key != "" {
+		keys = append([]string{*key}, keys...)
+	}
+	if len(keys) == 0 {
+		return errors.New("neither encryption nor old keys are set")
+	}
+
+	cipher, err := filestore.NewDecrypter(keys...)
+	if err != nil {
+		return fmt.Errorf("new cipher: %w", err)
+	}
+
+	n, err := filestore.Migrate(*path, cipher, nil, []byte(*integrityKey))
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+
+	fmt.Printf("%d records decrypted in %s\n", n, *path)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/filestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return hex.EncodeToString(key)
+}
+
+// readRecords reads all the records of the file storage.
+func readRecords(t *testing.T, path string, cipher *filestore.Cipher) ([]*models.URL, error) {
+	t.Helper()
+	consumer, err := filestore.NewConsumer(path, cipher)
+	require.NoError(t, err)
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	var records []*models.URL
+	for {
+		record, err := consumer.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	// the flags default to the environment of the server
+	for _, env := range []string{
+		"FILE_STORAGE_PATH", "FILE_STORAGE_ENCRYPTION_KEY",
+		"FILE_STORAGE_DECRYPTION_KEYS", "FILE_STORAGE_INTEGRITY_KEY",
+	} {
+		t.Setenv(env, "")
+	}
+
+	path := filepath.Join(t.TempDir(), "storage.json")
+	producer, err := filestore.NewProducer(path, nil)
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		record := models.NewRecord(fmt.Sprintf("short%d", i), fmt.Sprintf("https://example.com/%d", i), "user")
+		require.NoError(t, producer.WriteRecord(record))
+	}
+	require.NoError(t, producer.Close())
+
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+
+	require.Error(t, runEncrypt([]string{"-f", path}), "the key is required")
+	require.NoError(t, runEncrypt([]string{"-f", path, "-key", oldKey}))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "example.com")
+
+	// rotation
+	require.NoError(t, runEncrypt([]string{"-f", path, "-key", newKey, "-old-keys", oldKey}))
+	_, err = readRecords(t, path, mustCipher(t, oldKey))
+	require.ErrorIs(t, err, filestore.ErrUnknownKey)
+
+	// only the keys the file is encrypted with are needed
+	require.Error(t, runDecrypt([]string{"-f", path}), "a key is required")
+	require.Error(t, runDecrypt([]string{"-f", path, "-old-keys", oldKey}))
+	require.NoError(t, runDecrypt([]string{"-f", path, "-old-keys", newKey}))
+
+	records, err := readRecords(t, path, nil)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, models.OriginalURL("https://example.com/3"), records[2].OriginalURL)
+}
+
+func mustCipher(t *testing.T, key string) *filestore.Cipher {
+	t.Helper()
+	c, err := filestore.NewCipher(key)
+	require.NoError(t, err)
+	return c
+}
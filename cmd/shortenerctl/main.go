@@ -0,0 +1,135 @@
+// Shortenerctl is a command line tool for the shortener maintenance tasks.
+//
+// Usage:
+//
+//	shortenerctl <command> [flags]
+//
+// Commands:
+//
+//	encrypt   encrypt the file storage or re-encrypt it with a new key
+//	decrypt   decrypt the file storage
+//	verify    check the file storage hash chain for tampering or truncation
+//	protect   start the hash chain of the existing file storage
+//	seed      generate synthetic users, links and clicks for testing
+//	export    write the archive of the whole instance
+//	import    restore the archive into an empty storage
+//	token     mint the service token, e.g. the first admin one
+//
+// Run 'shortenerctl <command> -h' to see flags of a specific command.
+// Flags default to the same environment variables the server reads.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a single shortenerctl subcommand.
+type command struct {
+	// usage is a short description of the command.
+	usage string
+	// run executes the command with the given arguments.
+	run func(args []string) error
+}
+
+// commands is the registry of all available subcommands.
+var commands = map[string]command{
+	"encrypt": {
+		usage: "encrypt the file storage or re-encrypt it with a new key",
+		run:   runEncrypt,
+	},
+	"decrypt": {
+		usage: "decrypt the file storage",
+		run:   runDecrypt,
+	},
+	"verify": {
+		usage: "check the file storage hash chain for tampering or truncation",
+		run:   runVerify,
+	},
+	"protect": {
+		usage: "start the hash chain of the existing file storage",
+		run:   runProtect,
+	},
+	"seed": {
+		usage: "generate synthetic users, links and clicks for testing",
+		run:   runSeed,
+	},
+	"export": {
+		usage: "write the archive of the whole instance",
+		run:   runExport,
+	},
+	"import": {
+		usage: "restore the archive into an empty storage",
+		run:   runImport,
+	},
+	"token": {
+		usage: "mint the service token, e.g. the first admin one",
+		run:   runToken,
+	},
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// usage prints the list of available commands.
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: shortenerctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+// newFlagSet returns a flag set for the named command.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("shortenerctl "+name, flag.ExitOnError)
+}
+
+// splitList splits comma separated list ignoring empty values.
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// requireFile checks that the file at path exists.
+func requireFile(path string) error {
+	if path == "" {
+		return errors.New("file storage path is not set")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("file storage: %w", err)
+	}
+	return nil
+}
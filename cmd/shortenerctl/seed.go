@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/shorturl"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// seedBatchSize is the number of links saved in a single transaction.
+const seedBatchSize = 500
+
+// clickWindow is the period the synthetic clicks are spread over.
+const clickWindow = 30 * 24 * time.Hour
+
+// seedDomains are the hosts of the generated original URLs.
+var seedDomains = []string{
+	"example.com", "go.dev", "github.com", "news.ycombinator.com",
+	"en.wikipedia.org", "medium.com", "youtube.com", "habr.com",
+}
+
+// seedWords are the path segments of the generated original URLs.
+var seedWords = []string{
+	"blog", "docs", "release", "guide", "spring", "sale", "campaign",
+	"product", "video", "article", "news", "promo", "event", "talk",
+}
+
+// runSeed generates synthetic users and links with optional click
+// history into the configured storage. Link ownership and click counts
+// follow Zipf distribution, so that a few users and links dominate,
+// as they do in real traffic. The same seed produces the same data.
+func runSeed(args []string) error {
+	fs := newFlagSet("seed")
+	dsn := fs.String("d", os.Getenv("DATABASE_DSN"), "server data source name")
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path")
+	users := fs.Int("users", 100, "number of users to generate")
+	links := fs.Int("links", 1000, "number of links to generate")
+	clicks := fs.Int("clicks", 0, "maximum number of clicks per link, 0 disables click history")
+	seed := fs.Int64("seed", 1, "random generator seed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *dsn == "" && *path == "":
+		return errors.New("neither database DSN nor file storage path is set")
+	case *users <= 0:
+		return errors.New("number of users should be positive")
+	case *links < 0 || *clicks < 0:
+		return errors.New("number of links and clicks should not be negative")
+	}
+
+	cfg := &config.Config{DSN: *dsn, FileStoragePath: *path}
+	cfg.FileStorage.EncryptionKey = os.Getenv("FILE_STORAGE_ENCRYPTION_KEY")
+	cfg.FileStorage.Integrity, _ = strconv.ParseBool(os.Getenv("FILE_STORAGE_INTEGRITY"))
+	cfg.FileStorage.IntegrityKey = os.Getenv("FILE_STORAGE_INTEGRITY_KEY")
+
+	store, err := repository.NewURLStore(cfg, logger.NewWithZap(zap.NewNop()))
+	if err != nil {
+		return fmt.Errorf("init store: %w", err)
+	}
+
+	g := newSeeder(*seed, *users, *clicks)
+	ctx := context.Background()
+	accessed := make(map[models.ShortURL]time.Time)
+
+	for saved := 0; saved < *links; {
+		batch := make([]*models.URL, 0, min(seedBatchSize, *links-saved))
+		for len(batch) < cap(batch) {
+			url := g.link(saved + len(batch))
+			if at, ok := g.lastClick(); ok {
+				accessed[url.ShortURL] = at
+			}
+			batch = append(batch, url)
+		}
+
+		if err = store.SaveAll(ctx, batch); err != nil {
+			return fmt.Errorf("save links: %w", err)
+		}
+		saved += len(batch)
+	}
+
+	if len(accessed) > 0 {
+		if err = store.UpdateLastAccessed(ctx, accessed); err != nil {
+			return fmt.Errorf("save click history: %w", err)
+		}
+	}
+
+	fmt.Printf("%d links of %d users seeded, %d links clicked\n",
+		*links, *users, len(accessed))
+	if *dsn == "" && len(accessed) > 0 {
+		fmt.Println("note: the file storage does not persist click history")
+	}
+	return nil
+}
+
+// seeder generates deterministic synthetic data.
+type seeder struct {
+	// rnd generates users and links, clickRnd generates clicks,
+	// so that links don't depend on whether clicks are enabled.
+	rnd, clickRnd *rand.Rand
+	// users are the generated user IDs.
+	users []user.ID
+	// owners picks the owner index of the next link.
+	owners *rand.Zipf
+	// clicks picks the number of clicks of the next link,
+	// nil if click history is disabled.
+	clicks *rand.Zipf
+	// now is the end of the click window.
+	now time.Time
+}
+
+// newSeeder returns the generator of users, links and clicks.
+func newSeeder(seed int64, users, maxClicks int) *seeder {
+	rnd := rand.New(rand.NewSource(seed))
+	clickRnd := rand.New(rand.NewSource(seed + 1))
+
+	s := &seeder{
+		rnd:      rnd,
+		clickRnd: clickRnd,
+		users:    make([]user.ID, users),
+		owners:   rand.NewZipf(rnd, 1.1, 1, uint64(users-1)),
+		now:      time.Now().UTC(),
+	}
+	if maxClicks > 0 {
+		s.clicks = rand.NewZipf(clickRnd, 1.2, 1, uint64(maxClicks))
+	}
+
+	for i := range s.users {
+		s.users[i] = user.ID(s.uuid())
+	}
+
+	return s
+}
+
+// link returns the i-th synthetic link owned by one of the users.
+func (s *seeder) link(i int) *models.URL {
+	var path strings.Builder
+	for n := 1 + s.rnd.Intn(3); n > 0; n-- {
+		path.WriteByte('/')
+		path.WriteString(seedWords[s.rnd.Intn(len(seedWords))])
+	}
+
+	// index keeps original URLs unique
+	originalURL := fmt.Sprintf("https://%s%s/%d",
+		seedDomains[s.rnd.Intn(len(seedDomains))], path.String(), i)
+
+	url := models.NewRecord(shorturl.Generate(originalURL), originalURL,
+		s.users[s.owners.Uint64()])
+	url.ID = s.uuid()
+
+	return url
+}
+
+// lastClick returns the time of the latest of the random number of clicks
+// uniformly spread over the click window. It returns false if the link
+// has not been clicked.
+func (s *seeder) lastClick() (time.Time, bool) {
+	if s.clicks == nil {
+		return time.Time{}, false
+	}
+
+	n := s.clicks.Uint64()
+	if n == 0 {
+		return time.Time{}, false
+	}
+
+	// maximum of n uniform values is distributed as U^(1/n)
+	age := time.Duration((1 - math.Pow(s.clickRnd.Float64(), 1/float64(n))) * float64(clickWindow))
+
+	return s.now.Add(-age), true
+}
+
+// uuid returns a random UUID drawn from the seeded generator.
+func (s *seeder) uuid() string {
+	id, _ := uuid.NewRandomFromReader(s.rnd)
+	return id.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// runToken mints the service token signed with the JWT signing key,
+// e.g. the first admin token the admins mint the other tokens with.
+func runToken(args []string) error {
+	fs := newFlagSet("token")
+	key := fs.String("k", os.Getenv("JWT_SIGNING_KEY"), "JWT signing key")
+	userID := fs.String("user", "", "user ID, a new one if not set")
+	role := fs.String("role", string(user.RoleAdmin), "role of the token")
+	exp := fs.Duration("exp", time.Hour, "expiration of the token")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *key == "" {
+		return errors.New("JWT signing key is not set")
+	}
+
+	u := &user.User{ID: user.NewID(), Token: user.TokenService}
+	if *userID != "" {
+		id, err := user.ParseID(*userID)
+		if err != nil {
+			return err
+		}
+		u.ID = id
+	}
+	r, err := user.ParseRole(*role)
+	if err != nil {
+		return err
+	}
+	u.Role = r
+
+	token, err := jwt.BuildUserJWTString(u, *key, *exp)
+	if err != nil {
+		return fmt.Errorf("build token: %w", err)
+	}
+
+	fmt.Println(token)
+	return nil
+}
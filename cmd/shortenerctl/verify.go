@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/KretovDmitry/shortener/internal/repository/filestore"
+)
+
+// runVerify checks the hash chain of the file storage
+// and reports whether it has been tampered with or truncated.
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path")
+	key := fs.String("key", os.Getenv("FILE_STORAGE_INTEGRITY_KEY"), "integrity key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := requireFile(*path); err != nil {
+		return err
+	}
+
+	res, err := filestore.Verify(*path, []byte(*key))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("OK: %d records, %d chained, last hash %s\n",
+		res.Records, res.Chained, res.LastHash)
+	if res.Recovered {
+		fmt.Println("the checkpoint is one record behind and will catch up on the next start")
+	}
+	return nil
+}
+
+// runProtect starts the hash chain of the file storage written
+// without the integrity mode, so that the mode can be enabled.
+func runProtect(args []string) error {
+	fs := newFlagSet("protect")
+	path := fs.String("f", os.Getenv("FILE_STORAGE_PATH"), "file storage path")
+	key := fs.String("key", os.Getenv("FILE_STORAGE_INTEGRITY_KEY"), "integrity key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := requireFile(*path); err != nil {
+		return err
+	}
+
+	n, err := filestore.Protect(*path, []byte(*key))
+	if err != nil {
+		return fmt.Errorf("protect: %w", err)
+	}
+
+	fmt.Printf("%d records protected in %s\n", n, *path)
+	return nil
+}
@@ -0,0 +1,358 @@
+// Package app composes the server: the URL storage, the optional features
+// the storage supports, the certificates and the HTTP handlers on top of
+// them. It is the single place wiring the dependencies, so the servers
+// built on the handlers share the same repositories and options.
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/certs"
+	"github.com/KretovDmitry/shortener/internal/clickexport"
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/handler"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/ratelimit"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/sandbox"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// App holds the dependencies of the server.
+type App struct {
+	Config *config.Config
+	Logger logger.Logger
+	// URL repository with the optional features built on it.
+	Store repository.URLStorage
+	// HTTP handlers, see Router.
+	Handler *handler.Handler
+	// Certificate loaded from the files, nil unless configured.
+	Certificates *certs.Reloader
+	// Manager of the certificates issued by Let's Encrypt,
+	// nil unless TLS is enabled without the certificate files.
+	CertManager *autocert.Manager
+}
+
+// New initializes the storage and the handlers of the configuration.
+// The version is reported by the instance export. Close releases
+// everything New started.
+func New(cfg *config.Config, logger logger.Logger, version string) (*App, error) {
+	// Check for dependencies that can lead to panic.
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: config", errs.ErrNilDependency)
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("%w: logger", errs.ErrNilDependency)
+	}
+
+	a := &App{Config: cfg, Logger: logger}
+
+	// Init URL repository.
+	store, err := repository.NewURLStore(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init store: %w", err)
+	}
+	a.Store = store
+
+	if cfg.Sandbox.Enabled {
+		if err = a.seedSandbox(); err != nil {
+			a.closeStore()
+			return nil, err
+		}
+	}
+
+	opts, err := a.options(version)
+	if err != nil {
+		a.closeStore()
+		return nil, err
+	}
+
+	// Init HTTP handlers.
+	a.Handler, err = handler.New(store, cfg, logger, opts...)
+	if err != nil {
+		a.closeStore()
+		return nil, fmt.Errorf("new handler: %w", err)
+	}
+
+	return a, nil
+}
+
+// seedSandbox seeds the store with the demo data of the sandbox and logs
+// how to authenticate as the demo user.
+func (a *App) seedSandbox() error {
+	if err := sandbox.Seed(context.Background(), a.Store, time.Now()); err != nil {
+		return fmt.Errorf("failed to seed sandbox: %w", err)
+	}
+
+	token, err := jwt.BuildJWTString(sandbox.DemoUserID, user.TokenRegistered, a.Config.JWT.SigningKey,
+		a.Config.JWT.ExpirationOf(user.TokenRegistered))
+	if err != nil {
+		return fmt.Errorf("failed to build sandbox token: %w", err)
+	}
+	a.Logger.Infof("Sandbox is enabled: everything is kept in memory and everyone is trusted, "+
+		"never expose it. Log in as %q with the password %q or pass the Authorization cookie %q",
+		sandbox.DemoLogin, sandbox.DemoPassword, token)
+	if a.Config.Sandbox.Latency > 0 {
+		a.Logger.Infof("Sandbox requests are delayed by %s", a.Config.Sandbox.Latency)
+	}
+	return nil
+}
+
+// options returns the options of the handlers enabling the features
+// the store supports, the rate limits and the certificates.
+func (a *App) options(version string) ([]handler.Option, error) {
+	cfg, store, logger := a.Config, a.Store, a.Logger
+
+	// Init reserved short codes repository if the store supports it.
+	var opts []handler.Option
+	if reservations, err := repository.NewReservationStore(store); err != nil {
+		logger.Infof("short code reservations are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithReservations(reservations))
+	}
+
+	// Init click analytics if the store supports it.
+	if clicks, err := repository.NewClickStore(store); err != nil {
+		logger.Infof("click analytics is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithClicks(clicks))
+	}
+
+	// Share the cache of the resolved hostnames between the outbound requests.
+	resolver := dnscache.New(nil, cfg.DNSCache.TTL, cfg.DNSCache.NegativeTTL, cfg.DNSCache.Size)
+	opts = append(opts, handler.WithResolver(resolver))
+
+	// Forward clicks to the external analytics if any are configured.
+	if exporters := clickexport.NewExporters(cfg.ClickExport, resolver); len(exporters) > 0 {
+		opts = append(opts, handler.WithClickExporter(
+			clickexport.NewDispatcher(exporters, cfg.ClickExport, logger)))
+		logger.Infof("click export is enabled for %d exporters", len(exporters))
+	}
+
+	// Publish the events of the URLs to the brokers if any are configured.
+	publishers, err := events.NewPublishers(cfg.Events, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("new event publishers: %w", err)
+	}
+	if len(publishers) > 0 {
+		opts = append(opts, handler.WithEvents(events.NewBus(publishers, cfg.Events, logger)))
+		logger.Infof("events are published to %d brokers", len(publishers))
+	}
+
+	// Persist scheduled deletions if the store supports it.
+	if deletions, err := repository.NewDeletionQueue(store); err != nil {
+		logger.Infof("scheduled deletions are not persisted: %s", err)
+	} else {
+		opts = append(opts, handler.WithDeletionQueue(deletions))
+	}
+
+	// Enable API key authentication if the store supports it.
+	if apiKeys, err := repository.NewAPIKeyStore(store); err != nil {
+		logger.Infof("API keys are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithAPIKeys(apiKeys))
+	}
+
+	// Enable the Idempotency-Key header if the store supports it.
+	if idempotency, err := repository.NewIdempotencyStore(store); err != nil {
+		logger.Infof("idempotency keys are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithIdempotency(idempotency))
+	}
+
+	// Let the owners cap the redirects if the store supports it.
+	if limits, err := repository.NewRedirectLimitStore(store); err != nil {
+		logger.Infof("redirect limits are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithRedirectLimits(limits))
+	}
+
+	// Let the owners annotate their links if the store supports it.
+	if notes, err := repository.NewNoteStore(store); err != nil {
+		logger.Infof("notes are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithNotes(notes))
+	}
+
+	// Let the owners edit their links if the store supports it.
+	if updates, err := repository.NewURLUpdateStore(store); err != nil {
+		logger.Infof("editing is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithURLUpdates(updates))
+	}
+
+	// Let the owners transfer and share their links if the store supports it.
+	if shares, err := repository.NewShareStore(store); err != nil {
+		logger.Infof("sharing is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithShares(shares))
+	}
+
+	// Let the users export their URLs if the store supports it.
+	if scanner, err := repository.NewUserURLScanner(store); err != nil {
+		logger.Infof("export of the user URLs is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithUserExport(scanner))
+	}
+
+	// Let the users import the files of the URLs if the store supports it.
+	if results, err := repository.NewSaveResultStore(store); err != nil {
+		logger.Infof("import of the user URLs is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithUserImport(results))
+	}
+
+	// Report the saved short URLs of the conflicts if the store supports it.
+	if originals, err := repository.NewOriginalURLStore(store); err != nil {
+		logger.Infof("lookup of the original URLs is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithOriginalURLs(originals))
+	}
+
+	// Let the users register and log in if the store supports it.
+	if accounts, err := repository.NewAccountStore(store); err != nil {
+		logger.Infof("accounts are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithAccounts(accounts))
+	}
+
+	// Let the users revoke their tokens if the store supports it.
+	if revocations, err := repository.NewRevocationStore(store); err != nil {
+		logger.Infof("token revocation is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithRevocations(revocations))
+	}
+
+	// Let the administrators moderate the links if the store supports it.
+	if moderation, err := repository.NewModerationStore(store); err != nil {
+		logger.Infof("moderation is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithModeration(moderation))
+	}
+
+	// Let the owners pause their links if the store supports it.
+	if states, err := repository.NewLinkStateStore(store); err != nil {
+		logger.Infof("link states are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithLinkStates(states))
+	}
+
+	// Let the owners group their links if the store supports it.
+	if campaigns, err := repository.NewCampaignStore(store); err != nil {
+		logger.Infof("campaigns are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithCampaigns(campaigns))
+	}
+
+	// Enable the service statistics if the store supports it.
+	if stats, err := repository.NewStatsStore(cfg, store); err != nil {
+		logger.Infof("stats are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithStats(stats))
+	}
+
+	// Extend the statistics if the store supports it.
+	if stats, err := repository.NewExtendedStatsStore(store); err != nil {
+		logger.Infof("extended stats are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithExtendedStats(stats))
+	}
+
+	// Enable the creation trends if the store supports it.
+	if trends, err := repository.NewTrendStore(store); err != nil {
+		logger.Infof("trends are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithTrends(trends))
+	}
+
+	// Report the connection pool if the store has one.
+	if pool, err := repository.NewPoolStats(store); err != nil {
+		logger.Infof("pool metrics are disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithPoolStats(pool))
+	}
+
+	// Enable the instance export if the store supports it.
+	if scanner, err := repository.NewURLScanner(store); err != nil {
+		logger.Infof("export is disabled: %s", err)
+	} else {
+		opts = append(opts, handler.WithExport(scanner, version))
+	}
+
+	// Init rate limits storage.
+	limiter, err := ratelimit.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init rate limiter: %w", err)
+	}
+	opts = append(opts, handler.WithRateLimiter(limiter))
+
+	// Serve the certificate from the files if they are configured,
+	// issue it with Let's Encrypt otherwise.
+	if cfg.TLSEnabled && cfg.TLS.CertFile != "" {
+		a.Certificates, err = certs.New(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		opts = append(opts, handler.WithCertificates(a.Certificates))
+	} else if cfg.TLSEnabled {
+		a.CertManager, err = newCertManager(cfg, store)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init certificate manager: %w", err)
+		}
+		opts = append(opts, handler.WithCertificates(
+			certs.ACMECache{Cache: a.CertManager.Cache, Hosts: cfg.ACMEHosts()}))
+	}
+
+	return opts, nil
+}
+
+// Router returns the routes of the HTTP server.
+func (a *App) Router() http.Handler {
+	return a.Handler.Register(chi.NewRouter(), a.Config, a.Logger)
+}
+
+// Close stops the async short URL deletion and then the background jobs
+// of the store, e.g. degraded mode monitoring.
+func (a *App) Close() {
+	a.Handler.Stop()
+	a.closeStore()
+}
+
+// closeStore stops the background jobs of the store if it has any.
+func (a *App) closeStore() {
+	if closer, ok := a.Store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			a.Logger.Errorf("close store: %s", err)
+		}
+	}
+}
+
+// newCertManager returns the manager of the certificates issued
+// by Let's Encrypt for the configured hosts.
+func newCertManager(cfg *config.Config, store repository.URLStorage) (*autocert.Manager, error) {
+	m := &autocert.Manager{
+		Cache:  autocert.DirCache(cfg.TLS.CacheDir),
+		Prompt: autocert.AcceptTOS,
+	}
+	if cfg.TLS.Cache == config.TLSCacheDatabase {
+		storage, err := repository.NewCertificateCacheStore(store)
+		if err != nil {
+			return nil, err
+		}
+		m.Cache = certs.StorageCache{Storage: storage}
+	}
+	if hosts := cfg.ACMEHosts(); len(hosts) > 0 {
+		m.HostPolicy = autocert.HostWhitelist(hosts...)
+	}
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/sandbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	cfg := config.NewForTest()
+	cfg.FileStoragePath = filepath.Join(t.TempDir(), "urls.json")
+	l, _ := logger.NewForTest()
+
+	a, err := New(cfg, l, "test")
+	require.NoError(t, err)
+	defer a.Close()
+
+	assert.Nil(t, a.Certificates)
+	assert.Nil(t, a.CertManager)
+
+	w := httptest.NewRecorder()
+	a.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestNew_Sandbox(t *testing.T) {
+	cfg := config.NewForTest()
+	cfg.DSN = "postgres://localhost/unreachable"
+	cfg.Sandbox.Enabled = true
+	cfg.Sandbox.Apply(cfg)
+	l, _ := logger.NewForTest()
+
+	a, err := New(cfg, l, "test")
+	require.NoError(t, err)
+	defer a.Close()
+
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		a.Router().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// the demo links are seeded
+	assert.Equal(t, http.StatusTemporaryRedirect,
+		serve(httptest.NewRequest(http.MethodGet, "/GoDev", http.NoBody)))
+	assert.Equal(t, http.StatusServiceUnavailable,
+		serve(httptest.NewRequest(http.MethodGet, "/GoTour", http.NoBody)))
+
+	// the demo user logs in
+	r := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		strings.NewReader(fmt.Sprintf(`{"login":%q,"password":%q}`, sandbox.DemoLogin, sandbox.DemoPassword)))
+	r.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, http.StatusOK, serve(r))
+
+	// everyone is trusted
+	assert.Equal(t, http.StatusOK,
+		serve(httptest.NewRequest(http.MethodGet, "/api/internal/stats", http.NoBody)))
+}
+
+func TestNew_NilConfig(t *testing.T) {
+	l, _ := logger.NewForTest()
+
+	_, err := New(nil, l, "test")
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+}
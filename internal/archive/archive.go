@@ -0,0 +1,370 @@
+// Package archive implements the portable archive of the whole instance,
+// so that it can be moved to another storage backend or service version.
+//
+// The archive is a tar.gz file with the following entries in this order:
+//
+//	manifest.json  format version, creation time and number of the records
+//	settings.json  settings of the instance the records depend on
+//	users.jsonl    one user per line: {"id": "...", "urls": 2}
+//	urls.jsonl     one URL record per line in the JSON schema of models.URL
+//
+// The manifest goes first, so that readers reject unsupported versions
+// before reading the records. The version is incremented on incompatible
+// changes only, new optional fields keep it. Users are derived from
+// the owners of the URLs and are informational.
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository"
+)
+
+// Version is the format version of the archives written by this package.
+const Version = 1
+
+// restoreBatchSize is the number of URL records saved in a single transaction.
+const restoreBatchSize = 500
+
+// Names of the archive entries.
+const (
+	manifestEntry = "manifest.json"
+	settingsEntry = "settings.json"
+	usersEntry    = "users.jsonl"
+	urlsEntry     = "urls.jsonl"
+)
+
+// ErrUnsupported is returned when the archive is of an unknown format.
+var ErrUnsupported = errors.New("unsupported archive")
+
+type (
+	// Manifest describes the archive.
+	Manifest struct {
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+		// ServiceVersion is the version of the service that wrote the archive.
+		ServiceVersion string `json:"service_version,omitempty"`
+		URLs           int    `json:"urls"`
+		Users          int    `json:"users"`
+	}
+
+	// Settings are the settings of the instance the records depend on.
+	Settings struct {
+		// UserIDFormat is the format of the user IDs of the records.
+		UserIDFormat config.UserIDFormat `json:"user_id_format"`
+		// JSONNaming is the default naming of the API fields.
+		JSONNaming config.JSONNaming `json:"json_naming"`
+		// MaxTTL is the maximum TTL of the short URLs, e.g. "720h".
+		MaxTTL string `json:"max_ttl,omitempty"`
+	}
+
+	// User is the owner of the URL records.
+	User struct {
+		ID   user.ID `json:"id"`
+		URLs int     `json:"urls"`
+	}
+)
+
+// NewSettings returns the settings of the configured instance.
+func NewSettings(cfg *config.Config) Settings {
+	s := Settings{
+		UserIDFormat: cfg.UserIDFormat,
+		JSONNaming:   cfg.JSONNaming,
+	}
+	if cfg.Expiration.MaxTTL > 0 {
+		s.MaxTTL = cfg.Expiration.MaxTTL.String()
+	}
+	return s
+}
+
+// Write writes the archive of all the URL records of the scanner.
+// The records are buffered in a temporary file, as the size
+// of every entry is written before its content.
+func Write(
+	ctx context.Context, w io.Writer, scanner repository.URLScanner, settings Settings, serviceVersion string,
+) (*Manifest, error) {
+	urls, err := os.CreateTemp("", "shortener-archive-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file: %w", err)
+	}
+	defer func() {
+		_ = urls.Close()
+		_ = os.Remove(urls.Name())
+	}()
+
+	manifest := &Manifest{
+		Version:        Version,
+		CreatedAt:      time.Now().UTC(),
+		ServiceVersion: serviceVersion,
+	}
+
+	buf := bufio.NewWriter(urls)
+	enc := json.NewEncoder(buf)
+	owned := make(map[user.ID]int)
+	err = scanner.ScanURLs(ctx, func(u *models.URL) error {
+		owned[u.UserID]++
+		manifest.URLs++
+		return enc.Encode(u)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan urls: %w", err)
+	}
+	if err = buf.Flush(); err != nil {
+		return nil, fmt.Errorf("write urls: %w", err)
+	}
+
+	users := make([]User, 0, len(owned))
+	for id, n := range owned {
+		users = append(users, User{ID: id, URLs: n})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	manifest.Users = len(users)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err = writeJSON(tw, manifestEntry, manifest); err != nil {
+		return nil, err
+	}
+	if err = writeJSON(tw, settingsEntry, settings); err != nil {
+		return nil, err
+	}
+	if err = writeLines(tw, usersEntry, users); err != nil {
+		return nil, err
+	}
+	if err = writeFile(tw, urlsEntry, urls); err != nil {
+		return nil, err
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, fmt.Errorf("close tar: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return nil, fmt.Errorf("close gzip: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// writeJSON writes the entry with the JSON encoded value.
+func writeJSON(tw *tar.Writer, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	return writeEntry(tw, name, append(b, '\n'))
+}
+
+// writeLines writes the entry with the JSON encoded users, one per line.
+func writeLines(tw *tar.Writer, name string, users []User) error {
+	var b []byte
+	for _, u := range users {
+		line, err := json.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+		b = append(append(b, line...), '\n')
+	}
+	return writeEntry(tw, name, b)
+}
+
+// writeEntry writes the entry with the given content.
+func writeEntry(tw *tar.Writer, name string, b []byte) error {
+	if err := tw.WriteHeader(header(name, int64(len(b)))); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err := tw.Write(b); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeFile writes the entry with the content of the file.
+func writeFile(tw *tar.Writer, name string, f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err = tw.WriteHeader(header(name, info.Size())); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err = io.Copy(tw, f); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// header returns the header of the regular file entry.
+func header(name string, size int64) *tar.Header {
+	return &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now().UTC(),
+		Format:  tar.FormatPAX,
+	}
+}
+
+// Reader reads the archive.
+type Reader struct {
+	Manifest Manifest
+	Settings Settings
+
+	gz *gzip.Reader
+	tr *tar.Reader
+	// urls is true if the reader is at the URL records entry.
+	urls bool
+}
+
+// Open reads the manifest and the settings of the archive.
+// Unknown entries are skipped for forward compatibility.
+func Open(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	ar := &Reader{gz: gz, tr: tar.NewReader(gz)}
+
+	for first := true; ; first = false {
+		hdr, err := ar.tr.Next()
+		if errors.Is(err, io.EOF) {
+			if first {
+				return nil, fmt.Errorf("%w: no %s", ErrUnsupported, manifestEntry)
+			}
+			return ar, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if first && hdr.Name != manifestEntry {
+			return nil, fmt.Errorf("%w: %s is not the first entry", ErrUnsupported, manifestEntry)
+		}
+
+		switch hdr.Name {
+		case manifestEntry:
+			if err = json.NewDecoder(ar.tr).Decode(&ar.Manifest); err != nil {
+				return nil, fmt.Errorf("decode %s: %w", hdr.Name, err)
+			}
+			if ar.Manifest.Version < 1 || ar.Manifest.Version > Version {
+				return nil, fmt.Errorf("%w: version %d, need at most %d",
+					ErrUnsupported, ar.Manifest.Version, Version)
+			}
+		case settingsEntry:
+			if err = json.NewDecoder(ar.tr).Decode(&ar.Settings); err != nil {
+				return nil, fmt.Errorf("decode %s: %w", hdr.Name, err)
+			}
+		case urlsEntry:
+			ar.urls = true
+			return ar, nil
+		}
+	}
+}
+
+// ScanURLs calls fn for every URL record of the archive.
+// It stops at the first error returned by fn.
+func (ar *Reader) ScanURLs(fn func(*models.URL) error) error {
+	n := 0
+	if ar.urls {
+		dec := json.NewDecoder(ar.tr)
+		for {
+			u := new(models.URL)
+			err := dec.Decode(u)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("decode %s line %d: %w", urlsEntry, n+1, err)
+			}
+			if err = fn(u); err != nil {
+				return err
+			}
+			n++
+		}
+		ar.urls = false
+	}
+
+	if n != ar.Manifest.URLs {
+		return fmt.Errorf("archive is truncated: %d of %d urls read", n, ar.Manifest.URLs)
+	}
+	return nil
+}
+
+// Close closes the archive. It does not close the underlying reader.
+func (ar *Reader) Close() error {
+	return ar.gz.Close()
+}
+
+// Restore saves all the URL records of the archive to the store and
+// returns their number. The deletion flags and the last access times
+// are applied after the records are saved, as not every store keeps them
+// on save. It is meant to fill an empty store, records with the short URLs
+// already in the store are skipped or rejected depending on the store.
+func Restore(ctx context.Context, ar *Reader, store repository.URLStorage) (int, error) {
+	var (
+		n        int
+		batch    = make([]*models.URL, 0, restoreBatchSize)
+		deleted  []*models.URL
+		accessed = make(map[models.ShortURL]time.Time)
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := store.SaveAll(ctx, batch); err != nil {
+			return fmt.Errorf("save urls: %w", err)
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := ar.ScanURLs(func(u *models.URL) error {
+		if u.IsDeleted {
+			deleted = append(deleted, u)
+		}
+		if u.LastAccessedAt != nil {
+			accessed[u.ShortURL] = *u.LastAccessedAt
+		}
+		batch = append(batch, u)
+		if len(batch) < restoreBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return n, err
+	}
+	if err = flush(); err != nil {
+		return n, err
+	}
+
+	if len(accessed) > 0 {
+		if err = store.UpdateLastAccessed(ctx, accessed); err != nil {
+			return n, fmt.Errorf("restore last access times: %w", err)
+		}
+	}
+	if len(deleted) > 0 {
+		if err = store.DeleteURLs(ctx, deleted...); err != nil {
+			return n, fmt.Errorf("restore deletions: %w", err)
+		}
+	}
+
+	return n, nil
+}
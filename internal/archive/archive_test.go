@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.TODO()
+	accessedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	alice, bob := user.NewID(), user.ID("oidc|bob")
+	active := models.NewRecord("YBbxJEcQ9vq", "https://go.dev/", alice)
+	deleted := models.NewRecord("Vp3Vf5tXoSK", "https://go.dev/doc/", alice)
+	accessed := models.NewRecord("2XgQvCJ9pLs", "https://pkg.go.dev/", bob)
+	accessed.Metadata = models.NewMetadata("192.0.2.1", "curl/8.5.0", models.OriginAPI)
+
+	src := memstore.NewURLRepository()
+	require.NoError(t, src.SaveAll(ctx, []*models.URL{active, deleted, accessed}))
+	require.NoError(t, src.DeleteURLs(ctx, deleted))
+	require.NoError(t, src.UpdateLastAccessed(ctx,
+		map[models.ShortURL]time.Time{accessed.ShortURL: accessedAt}))
+
+	settings := Settings{UserIDFormat: config.UserIDFormatOpaque, JSONNaming: config.JSONNamingCamelCase}
+	var buf bytes.Buffer
+	manifest, err := Write(ctx, &buf, src, settings, "v1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, Version, manifest.Version)
+	assert.Equal(t, 3, manifest.URLs)
+	assert.Equal(t, 2, manifest.Users)
+
+	ar, err := Open(&buf)
+	require.NoError(t, err)
+	defer ar.Close()
+	assert.Equal(t, *manifest, ar.Manifest)
+	assert.Equal(t, settings, ar.Settings)
+
+	dst := memstore.NewURLRepository()
+	n, err := Restore(ctx, ar, dst)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	for _, want := range []*models.URL{active, deleted, accessed} {
+		got, err := dst.Get(ctx, want.ShortURL)
+		require.NoError(t, err)
+		orig, err := src.Get(ctx, want.ShortURL)
+		require.NoError(t, err)
+		assert.Equal(t, orig, got)
+	}
+
+	got, err := dst.Get(ctx, deleted.ShortURL)
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+	got, err = dst.Get(ctx, active.ShortURL)
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted, "only the deleted record is restored as deleted")
+}
+
+func TestOpen_Unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string]string
+		order   []string
+	}{
+		{
+			name:  "newer version",
+			order: []string{manifestEntry},
+			entries: map[string]string{
+				manifestEntry: `{"version": 99}`,
+			},
+		},
+		{
+			name:  "no manifest first",
+			order: []string{urlsEntry, manifestEntry},
+			entries: map[string]string{
+				urlsEntry:     "",
+				manifestEntry: `{"version": 1}`,
+			},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, name := range tt.order {
+				require.NoError(t, writeEntry(tw, name, []byte(tt.entries[name])))
+			}
+			require.NoError(t, tw.Close())
+			require.NoError(t, gz.Close())
+
+			_, err := Open(&buf)
+			assert.ErrorIs(t, err, ErrUnsupported)
+		})
+	}
+
+	_, err := Open(bytes.NewReader([]byte("not an archive")))
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestRestore_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	url := `{"id":"1","short_url":"YBbxJEcQ9vq","original_url":"https://go.dev/","user_id":"user"}` + "\n"
+	require.NoError(t, writeEntry(tw, manifestEntry, []byte(`{"version": 1, "urls": 2}`)))
+	require.NoError(t, writeEntry(tw, urlsEntry, []byte(url)))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	ar, err := Open(&buf)
+	require.NoError(t, err)
+
+	_, err = Restore(context.TODO(), ar, memstore.NewURLRepository())
+	assert.ErrorContains(t, err, "archive is truncated")
+}
@@ -0,0 +1,45 @@
+package certs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Storage keeps the cache entries of autocert,
+// e.g. repository.CertificateCacheStorage.
+type Storage interface {
+	GetCertificateCache(ctx context.Context, key string) ([]byte, error)
+	PutCertificateCache(ctx context.Context, key string, data []byte) error
+	DeleteCertificateCache(ctx context.Context, key string) error
+}
+
+// StorageCache is the autocert cache kept in the storage,
+// so that the replicas share the account and the certificates.
+type StorageCache struct {
+	Storage Storage
+}
+
+// Interface implementation guard.
+var _ autocert.Cache = StorageCache{}
+
+// Get returns the cache entry. It implements autocert.Cache.
+func (c StorageCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Storage.GetCertificateCache(ctx, key)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put saves the cache entry. It implements autocert.Cache.
+func (c StorageCache) Put(ctx context.Context, key string, data []byte) error {
+	return c.Storage.PutCertificateCache(ctx, key, data)
+}
+
+// Delete removes the cache entry. It implements autocert.Cache.
+func (c StorageCache) Delete(ctx context.Context, key string) error {
+	return c.Storage.DeleteCertificateCache(ctx, key)
+}
@@ -0,0 +1,49 @@
+package certs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// memStorage keeps the cache entries in the map.
+type memStorage map[string][]byte
+
+func (s memStorage) GetCertificateCache(_ context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s memStorage) PutCertificateCache(_ context.Context, key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func (s memStorage) DeleteCertificateCache(_ context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func TestStorageCache(t *testing.T) {
+	ctx := context.Background()
+	cache := StorageCache{Storage: memStorage{}}
+
+	_, err := cache.Get(ctx, "example.com")
+	require.ErrorIs(t, err, autocert.ErrCacheMiss, "autocert requests the certificate on the miss only")
+
+	require.NoError(t, cache.Put(ctx, "example.com", []byte("cert")))
+	data, err := cache.Get(ctx, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert"), data)
+
+	require.NoError(t, cache.Delete(ctx, "example.com"))
+	_, err = cache.Get(ctx, "example.com")
+	require.ErrorIs(t, err, autocert.ErrCacheMiss)
+}
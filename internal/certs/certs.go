@@ -0,0 +1,65 @@
+// Package certs serves the TLS certificate loaded from the files,
+// reloading it on demand, e.g. once the files are renewed, and reports
+// the expiry of the served certificates.
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Reloader keeps the certificate loaded from the files.
+// It is safe for concurrent use.
+type Reloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New returns the reloader of the certificate and key files in PEM.
+// The certificate is loaded right away, so that the invalid files fail
+// the startup.
+func New(certFile, keyFile string) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both certificate and key files are required")
+	}
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate from the files again. The certificate
+// in use is kept if the files are invalid.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate returns the loaded certificate.
+// It implements tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns the TLS config serving the loaded certificate.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
@@ -0,0 +1,82 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes the self-signed certificate of the host and its key.
+func writeCert(t *testing.T, certFile, keyFile, host string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "old.example")
+
+	r, err := New(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "old.example", commonName(t, r))
+
+	// the renewed files are served after the reload
+	writeCert(t, certFile, keyFile, "new.example")
+	assert.Equal(t, "old.example", commonName(t, r))
+	require.NoError(t, r.Reload())
+	assert.Equal(t, "new.example", commonName(t, r))
+
+	// the invalid files keep the certificate in use
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	require.Error(t, r.Reload())
+	assert.Equal(t, "new.example", commonName(t, r))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New("", "")
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	_, err = New(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"))
+	require.Error(t, err)
+}
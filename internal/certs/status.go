@@ -0,0 +1,191 @@
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Sources of the certificates.
+const (
+	// SourceFile is the certificate loaded from the files.
+	SourceFile = "file"
+	// SourceACME is the certificate issued by Let's Encrypt.
+	SourceACME = "acme"
+)
+
+// DefaultRenewBefore is the time before the expiry autocert renews
+// the certificates at.
+const DefaultRenewBefore = 30 * 24 * time.Hour
+
+// renewalGrace is the time the renewal of the ACME certificate may take
+// before it is reported as overdue. autocert delays the renewal by up to
+// an hour and retries the failed ones.
+const renewalGrace = 24 * time.Hour
+
+// Status describes the certificate served by the server.
+type Status struct {
+	// Source is the source of the certificate: file or acme.
+	Source string `json:"source"`
+	// Names are the DNS names the certificate is valid for.
+	Names []string `json:"names"`
+	// NotAfter is the expiry of the certificate.
+	NotAfter time.Time `json:"not_after"`
+	// RenewAt is the time the ACME certificate is renewed at.
+	// It is zero for the certificates from the files, which are renewed
+	// by the operators.
+	RenewAt time.Time `json:"renew_at,omitempty"`
+}
+
+// ExpiresIn returns the time left until the expiry, negative once expired.
+func (s Status) ExpiresIn(now time.Time) time.Duration {
+	return s.NotAfter.Sub(now)
+}
+
+// RenewalOverdue reports whether the ACME certificate should have been
+// renewed by now, i.e. the renewal keeps failing.
+func (s Status) RenewalOverdue(now time.Time) bool {
+	return !s.RenewAt.IsZero() && now.After(s.RenewAt.Add(renewalGrace))
+}
+
+// Source reports the certificates served by the server.
+type Source interface {
+	// Certificates returns the statuses of the served certificates.
+	Certificates() ([]Status, error)
+}
+
+// Interface implementation guards.
+var (
+	_ Source = (*Reloader)(nil)
+	_ Source = ACMECache{}
+)
+
+// Certificates returns the status of the loaded certificate.
+func (r *Reloader) Certificates() ([]Status, error) {
+	r.mu.RLock()
+	cert := r.cert
+	r.mu.RUnlock()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	return []Status{newStatus(SourceFile, leaf)}, nil
+}
+
+// ACMECache reports the certificates kept by autocert in its cache.
+type ACMECache struct {
+	// Cache is autocert.Manager.Cache.
+	Cache autocert.Cache
+	// Hosts are the hosts the certificates are issued for. If empty,
+	// the hosts are listed from the autocert.DirCache, the other caches
+	// are not reported.
+	Hosts []string
+	// RenewBefore is autocert.Manager.RenewBefore,
+	// DefaultRenewBefore is used if zero.
+	RenewBefore time.Duration
+}
+
+// Certificates returns the statuses of the cached certificates. The cache
+// is empty until the first certificate is issued on the first request.
+func (c ACMECache) Certificates() ([]Status, error) {
+	keys, err := c.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	renewBefore := c.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = DefaultRenewBefore
+	}
+
+	statuses := make([]Status, 0, len(keys))
+	for _, key := range keys {
+		b, err := c.Cache.Get(context.Background(), key)
+		if errors.Is(err, autocert.ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read cache: %w", err)
+		}
+		leaf, err := cachedLeaf(b)
+		if err != nil {
+			return nil, fmt.Errorf("parse cached certificate %s: %w", key, err)
+		}
+
+		s := newStatus(SourceACME, leaf)
+		s.RenewAt = leaf.NotAfter.Add(-renewBefore)
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NotAfter.Before(statuses[j].NotAfter)
+	})
+
+	return statuses, nil
+}
+
+// keys returns the cache keys of the certificates, the ECDSA and the RSA
+// ones of every host.
+func (c ACMECache) keys() ([]string, error) {
+	if len(c.Hosts) > 0 {
+		keys := make([]string, 0, 2*len(c.Hosts))
+		for _, host := range c.Hosts {
+			keys = append(keys, host, host+"+rsa")
+		}
+		return keys, nil
+	}
+
+	dir, ok := c.Cache.(autocert.DirCache)
+	if !ok {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(string(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cache: %w", err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		// the account key and the challenge tokens are kept next to the certificates
+		if e.IsDir() || strings.HasPrefix(e.Name(), "acme_account") ||
+			strings.Contains(e.Name(), "+token") || strings.Contains(e.Name(), "+http-01") {
+			continue
+		}
+		keys = append(keys, e.Name())
+	}
+	return keys, nil
+}
+
+// cachedLeaf returns the leaf certificate of the cache entry, which is
+// the private key followed by the certificate chain in PEM.
+func cachedLeaf(b []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, errors.New("no certificate")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func newStatus(source string, leaf *x509.Certificate) Status {
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = []string{leaf.Subject.CommonName}
+	}
+	return Status{Source: source, Names: names, NotAfter: leaf.NotAfter}
+}
@@ -0,0 +1,86 @@
+package certs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestReloader_Certificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "example.com")
+
+	r, err := New(certFile, keyFile)
+	require.NoError(t, err)
+
+	statuses, err := r.Certificates()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, SourceFile, statuses[0].Source)
+	assert.Equal(t, []string{"example.com"}, statuses[0].Names)
+	assert.True(t, statuses[0].RenewAt.IsZero(), "the files are renewed by the operators")
+	assert.InDelta(t, time.Hour, statuses[0].ExpiresIn(time.Now()), float64(time.Minute))
+}
+
+func TestACMECache_Certificates(t *testing.T) {
+	dir := t.TempDir()
+
+	// the cache is empty until the first certificate is issued
+	statuses, err := ACMECache{Cache: autocert.DirCache(filepath.Join(dir, "missing"))}.Certificates()
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+
+	// autocert keeps the private key followed by the chain
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "example.com")
+	key, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+	cert, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	cache := filepath.Join(dir, "cache")
+	require.NoError(t, os.Mkdir(cache, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(cache, "example.com"), append(key, cert...), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(cache, "acme_account+key"), key, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(cache, "example.com+token"), []byte("token"), 0o600))
+
+	statuses, err = ACMECache{Cache: autocert.DirCache(cache), RenewBefore: 30 * time.Minute}.Certificates()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	s := statuses[0]
+	assert.Equal(t, SourceACME, s.Source)
+	assert.Equal(t, []string{"example.com"}, s.Names)
+	assert.Equal(t, s.NotAfter.Add(-30*time.Minute), s.RenewAt)
+	assert.False(t, s.RenewalOverdue(time.Now()))
+	assert.True(t, s.RenewalOverdue(time.Now().Add(2*renewalGrace)))
+}
+
+func TestACMECache_Hosts(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "example.com")
+	key, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+	cert, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+
+	cache := StorageCache{Storage: memStorage{}}
+	require.NoError(t, cache.Put(context.Background(), "example.com+rsa", append(key, cert...)))
+
+	// the hosts are looked up in any cache, the missing ones are skipped
+	statuses, err := ACMECache{Cache: cache, Hosts: []string{"example.com", "other.example"}}.Certificates()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, []string{"example.com"}, statuses[0].Names)
+
+	// the other caches than the directory can't be listed
+	statuses, err = ACMECache{Cache: cache}.Certificates()
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
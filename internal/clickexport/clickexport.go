@@ -0,0 +1,256 @@
+// Package clickexport forwards the clicks to the external analytics,
+// so that teams can keep their existing analytics stack.
+//
+// The clicks are collected by the Dispatcher and exported in batches
+// to every configured Exporter concurrently. Failed exports are retried
+// with exponential backoff unless the request is rejected.
+package clickexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// Exporter forwards the clicks to the external analytics.
+type Exporter interface {
+	// Name returns the name of the exporter used in the logs.
+	Name() string
+
+	// Export sends the clicks. Errors wrapped with Permanent are not retried.
+	Export(ctx context.Context, clicks []*models.Click) error
+}
+
+// Default values used if the config values are not set.
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 10 * time.Second
+	defaultRetryBackoff  = time.Second
+	requestTimeout       = 10 * time.Second
+)
+
+// NewExporters returns the exporters enabled by the configuration.
+// The hosts of the analytics are resolved with the resolver
+// if it is not nil.
+func NewExporters(cfg config.ClickExport, resolver *dnscache.Resolver) []Exporter {
+	client := &http.Client{Timeout: requestTimeout}
+	if resolver != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = resolver.DialContext(&net.Dialer{Timeout: requestTimeout})
+		client.Transport = transport
+	}
+
+	var exporters []Exporter
+	if ga := cfg.GoogleAnalytics; ga.MeasurementID != "" && ga.APISecret != "" {
+		exporters = append(exporters, NewGoogleAnalytics(client, ga.MeasurementID, ga.APISecret, ga.Endpoint))
+	}
+	if cfg.Segment.WriteKey != "" {
+		exporters = append(exporters, NewSegment(client, cfg.Segment.WriteKey, cfg.Segment.Endpoint))
+	}
+	if cfg.HTTP.URL != "" {
+		exporters = append(exporters, NewHTTP(client, cfg.HTTP.URL, cfg.HTTP.Headers))
+	}
+	return exporters
+}
+
+// Dispatcher collects the clicks and exports them in batches.
+// It is safe for concurrent use.
+type Dispatcher struct {
+	exporters []Exporter
+	logger    logger.Logger
+	// clicks is the buffer of the collected clicks.
+	clicks chan *models.Click
+	// batchSize is the maximum number of the clicks exported at once.
+	batchSize int
+	// interval is how often the collected clicks are exported.
+	interval time.Duration
+	// retries is the number of retries of the failed export.
+	retries int
+	// backoff is the delay before the first retry.
+	backoff time.Duration
+}
+
+// NewDispatcher returns the dispatcher exporting to the given exporters.
+// It must be run to export anything.
+func NewDispatcher(exporters []Exporter, cfg config.ClickExport, logger logger.Logger) *Dispatcher {
+	d := &Dispatcher{
+		exporters: exporters,
+		logger:    logger,
+		batchSize: cfg.BatchSize,
+		interval:  cfg.FlushInterval,
+		retries:   cfg.MaxRetries,
+		backoff:   cfg.RetryBackoff,
+	}
+	if d.batchSize <= 0 {
+		d.batchSize = defaultBatchSize
+	}
+	if d.interval <= 0 {
+		d.interval = defaultFlushInterval
+	}
+	if d.backoff <= 0 {
+		d.backoff = defaultRetryBackoff
+	}
+	d.clicks = make(chan *models.Click, 10*d.batchSize)
+	return d
+}
+
+// Send schedules the export of the click.
+// It never blocks: the click is dropped if the buffer is full.
+func (d *Dispatcher) Send(click *models.Click) {
+	select {
+	case d.clicks <- click:
+	default:
+		d.logger.Debug("click export buffer is full, click dropped")
+	}
+}
+
+// Run exports the collected clicks when the batch is full or the flush
+// interval passes. When done is closed, it exports the buffered clicks
+// without retries and returns.
+func (d *Dispatcher) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// stop waiting for the retries
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	batch := make([]*models.Click, 0, d.batchSize)
+	for {
+		select {
+		case c := <-d.clicks:
+			batch = append(batch, c)
+			if len(batch) < d.batchSize {
+				continue
+			}
+			d.export(ctx, batch)
+			batch = make([]*models.Click, 0, d.batchSize)
+
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			d.export(ctx, batch)
+			batch = make([]*models.Click, 0, d.batchSize)
+
+		case <-done:
+			for len(d.clicks) > 0 {
+				batch = append(batch, <-d.clicks)
+			}
+			if len(batch) > 0 {
+				d.export(context.Background(), batch)
+			}
+			return
+		}
+	}
+}
+
+// export sends the batch to all the exporters concurrently.
+func (d *Dispatcher) export(ctx context.Context, batch []*models.Click) {
+	var wg sync.WaitGroup
+	for _, e := range d.exporters {
+		wg.Add(1)
+		go func(e Exporter) {
+			defer wg.Done()
+			if err := d.exportWithRetries(ctx, e, batch); err != nil {
+				d.logger.Errorf("export %d clicks to %s: %s", len(batch), e.Name(), err)
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// exportWithRetries exports the batch retrying the failures with
+// exponential backoff. Retries stop when the context is canceled.
+func (d *Dispatcher) exportWithRetries(ctx context.Context, e Exporter, batch []*models.Click) error {
+	backoff := d.backoff
+	for attempt := 0; ; attempt++ {
+		err := e.Export(ctx, batch)
+		if err == nil || attempt >= d.retries || IsPermanent(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// permanentError is the export error not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not worth retrying, e.g. rejected request.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error is marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// postJSON sends the body encoded as JSON. The responses with 4xx status
+// codes other than 429 Too Many Requests are permanent errors.
+func postJSON(
+	ctx context.Context, client *http.Client, url string, body any, edit func(*http.Request),
+) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return Permanent(fmt.Errorf("encode request: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return Permanent(fmt.Errorf("new request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if edit != nil {
+		edit(req)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status %s", res.Status)
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
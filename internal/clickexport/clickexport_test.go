@@ -0,0 +1,209 @@
+package clickexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExporter records the exported clicks
+// failing the first given number of calls.
+type recordingExporter struct {
+	mu       sync.Mutex
+	calls    int
+	failures int
+	err      error
+	exported []*models.Click
+}
+
+func (e *recordingExporter) Name() string { return "recording" }
+
+func (e *recordingExporter) Export(_ context.Context, clicks []*models.Click) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	if e.calls <= e.failures {
+		return e.err
+	}
+	e.exported = append(e.exported, clicks...)
+	return nil
+}
+
+func newClicks(n int) []*models.Click {
+	clicks := make([]*models.Click, n)
+	for i := range clicks {
+		clicks[i] = models.NewClick("YBbxJEcQ9vq", time.Now(), "192.0.2.1", "", "curl/8.5.0")
+	}
+	return clicks
+}
+
+func TestDispatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		exporter  *recordingExporter
+		wantCalls int
+		wantCount int
+	}{
+		{
+			name:      "exported in batches",
+			exporter:  &recordingExporter{},
+			wantCalls: 2,
+			wantCount: 3,
+		},
+		{
+			name:      "retried",
+			exporter:  &recordingExporter{failures: 2, err: errors.New("unavailable")},
+			wantCalls: 4,
+			wantCount: 3,
+		},
+		{
+			name:      "permanent error is not retried",
+			exporter:  &recordingExporter{failures: 1, err: Permanent(errors.New("rejected"))},
+			wantCalls: 2,
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			d := NewDispatcher([]Exporter{tt.exporter}, config.ClickExport{
+				BatchSize:     2,
+				FlushInterval: time.Hour,
+				MaxRetries:    3,
+				RetryBackoff:  time.Millisecond,
+			}, l)
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				d.Run(done)
+			}()
+
+			for _, c := range newClicks(3) {
+				d.Send(c)
+			}
+			// the full batch is exported without waiting for the stop
+			require.Eventually(t, func() bool {
+				tt.exporter.mu.Lock()
+				defer tt.exporter.mu.Unlock()
+				return tt.exporter.calls >= tt.wantCalls-1
+			}, time.Second, time.Millisecond)
+
+			// the rest is exported on stop
+			close(done)
+			<-stopped
+
+			assert.Equal(t, tt.wantCalls, tt.exporter.calls)
+			assert.Len(t, tt.exporter.exported, tt.wantCount)
+		})
+	}
+}
+
+func TestExporters(t *testing.T) {
+	type request struct {
+		path     string
+		query    string
+		user     string
+		header   string
+		body     json.RawMessage
+		hasBasic bool
+	}
+	var (
+		mu       sync.Mutex
+		requests []request
+		status   = http.StatusOK
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		user, _, ok := r.BasicAuth()
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{
+			path:     r.URL.Path,
+			query:    r.URL.RawQuery,
+			user:     user,
+			header:   r.Header.Get("X-Token"),
+			body:     body,
+			hasBasic: ok,
+		})
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	exporters := NewExporters(config.ClickExport{}, nil)
+	assert.Empty(t, exporters, "no exporters are configured")
+
+	var cfg config.ClickExport
+	cfg.GoogleAnalytics.MeasurementID = "G-TEST"
+	cfg.GoogleAnalytics.APISecret = "secret"
+	cfg.GoogleAnalytics.Endpoint = server.URL + "/mp/collect"
+	cfg.Segment.WriteKey = "key"
+	cfg.Segment.Endpoint = server.URL + "/v1/batch"
+	cfg.HTTP.URL = server.URL + "/clicks"
+	cfg.HTTP.Headers = map[string]string{"X-Token": "token"}
+	exporters = NewExporters(cfg, nil)
+	require.Len(t, exporters, 3)
+
+	// clicks of two clients, the first one exceeds the GA request limit
+	clicks := newClicks(googleAnalyticsMaxEvents + 1)
+	clicks = append(clicks, models.NewClick("YBbxJEcQ9vq", time.Now(), "", "", ""))
+
+	for _, e := range exporters {
+		require.NoError(t, e.Export(context.Background(), clicks), e.Name())
+	}
+
+	require.Len(t, requests, 5)
+
+	ga := requests[:3]
+	for _, r := range ga {
+		assert.Equal(t, "/mp/collect", r.path)
+		assert.Equal(t, "api_secret=secret&measurement_id=G-TEST", r.query)
+	}
+	var gaReq googleAnalyticsRequest
+	require.NoError(t, json.Unmarshal(ga[0].body, &gaReq))
+	assert.Equal(t, models.HashIP("192.0.2.1"), gaReq.ClientID)
+	assert.Len(t, gaReq.Events, googleAnalyticsMaxEvents)
+	require.NoError(t, json.Unmarshal(ga[2].body, &gaReq))
+	assert.Equal(t, anonymousID, gaReq.ClientID)
+
+	segment := requests[3]
+	assert.Equal(t, "/v1/batch", segment.path)
+	assert.True(t, segment.hasBasic)
+	assert.Equal(t, "key", segment.user)
+	var segmentReq segmentRequest
+	require.NoError(t, json.Unmarshal(segment.body, &segmentReq))
+	assert.Len(t, segmentReq.Batch, len(clicks))
+	assert.Equal(t, "track", segmentReq.Batch[0].Type)
+
+	generic := requests[4]
+	assert.Equal(t, "/clicks", generic.path)
+	assert.Equal(t, "token", generic.header)
+	var exported []models.Click
+	require.NoError(t, json.Unmarshal(generic.body, &exported))
+	assert.Len(t, exported, len(clicks))
+
+	// rejected requests are permanent errors, unavailable are retried
+	status = http.StatusBadRequest
+	err := exporters[2].Export(context.Background(), clicks)
+	require.Error(t, err)
+	assert.True(t, IsPermanent(err))
+
+	status = http.StatusServiceUnavailable
+	err = exporters[2].Export(context.Background(), clicks)
+	require.Error(t, err)
+	assert.False(t, IsPermanent(err))
+}
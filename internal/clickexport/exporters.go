@@ -0,0 +1,221 @@
+package clickexport
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// Interface implementation checks.
+var (
+	_ Exporter = (*GoogleAnalytics)(nil)
+	_ Exporter = (*Segment)(nil)
+	_ Exporter = (*HTTP)(nil)
+)
+
+// Default endpoints of the analytics services.
+const (
+	defaultGoogleAnalyticsEndpoint = "https://www.google-analytics.com/mp/collect"
+	defaultSegmentEndpoint         = "https://api.segment.io/v1/batch"
+)
+
+// Limits of the events sent in a single request.
+const (
+	googleAnalyticsMaxEvents = 25
+	segmentMaxEvents         = 100
+)
+
+// clickEvent is the name of the click event in the analytics.
+const clickEvent = "short_link_click"
+
+// anonymousID identifies the clicks without the client IP.
+const anonymousID = "anonymous"
+
+// GoogleAnalytics exports the clicks as the events
+// of the Google Analytics 4 Measurement Protocol.
+type GoogleAnalytics struct {
+	client   *http.Client
+	endpoint string
+}
+
+// NewGoogleAnalytics returns the exporter to the data stream with the given
+// measurement ID. Default endpoint is used if the endpoint is empty.
+func NewGoogleAnalytics(client *http.Client, measurementID, apiSecret, endpoint string) *GoogleAnalytics {
+	if endpoint == "" {
+		endpoint = defaultGoogleAnalyticsEndpoint
+	}
+	q := url.Values{}
+	q.Set("measurement_id", measurementID)
+	q.Set("api_secret", apiSecret)
+	return &GoogleAnalytics{client: client, endpoint: endpoint + "?" + q.Encode()}
+}
+
+// Name implements Exporter.
+func (ga *GoogleAnalytics) Name() string {
+	return "google analytics"
+}
+
+type (
+	googleAnalyticsRequest struct {
+		ClientID        string                 `json:"client_id"`
+		TimestampMicros int64                  `json:"timestamp_micros"`
+		Events          []googleAnalyticsEvent `json:"events"`
+	}
+
+	googleAnalyticsEvent struct {
+		Name   string            `json:"name"`
+		Params map[string]string `json:"params"`
+	}
+)
+
+// Export sends the clicks of every client in a separate request.
+// The hashed IP is the client ID. The request timestamp is the time
+// of the first click in it, as the protocol has no event timestamps.
+func (ga *GoogleAnalytics) Export(ctx context.Context, clicks []*models.Click) error {
+	byClient := make(map[string][]*models.Click)
+	var order []string
+	for _, c := range clicks {
+		id := clientID(c)
+		if _, ok := byClient[id]; !ok {
+			order = append(order, id)
+		}
+		byClient[id] = append(byClient[id], c)
+	}
+
+	for _, id := range order {
+		for _, chunk := range chunks(byClient[id], googleAnalyticsMaxEvents) {
+			req := googleAnalyticsRequest{
+				ClientID:        id,
+				TimestampMicros: chunk[0].ClickedAt.UnixMicro(),
+				Events:          make([]googleAnalyticsEvent, len(chunk)),
+			}
+			for i, c := range chunk {
+				req.Events[i] = googleAnalyticsEvent{
+					Name: clickEvent,
+					Params: map[string]string{
+						"short_url":     string(c.ShortURL),
+						"page_referrer": c.Referrer,
+					},
+				}
+			}
+			if err := postJSON(ctx, ga.client, ga.endpoint, req, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Segment exports the clicks as the track calls of the Segment HTTP API.
+type Segment struct {
+	client   *http.Client
+	writeKey string
+	endpoint string
+}
+
+// NewSegment returns the exporter to the source with the given write key.
+// Default endpoint is used if the endpoint is empty.
+func NewSegment(client *http.Client, writeKey, endpoint string) *Segment {
+	if endpoint == "" {
+		endpoint = defaultSegmentEndpoint
+	}
+	return &Segment{client: client, writeKey: writeKey, endpoint: endpoint}
+}
+
+// Name implements Exporter.
+func (s *Segment) Name() string {
+	return "segment"
+}
+
+type (
+	segmentRequest struct {
+		Batch []segmentTrack `json:"batch"`
+	}
+
+	segmentTrack struct {
+		Type        string            `json:"type"`
+		Event       string            `json:"event"`
+		AnonymousID string            `json:"anonymousId"`
+		Timestamp   time.Time         `json:"timestamp"`
+		Properties  map[string]string `json:"properties"`
+		Context     map[string]string `json:"context"`
+	}
+)
+
+// Export sends the clicks in batches. The hashed IP is the anonymous ID.
+func (s *Segment) Export(ctx context.Context, clicks []*models.Click) error {
+	for _, chunk := range chunks(clicks, segmentMaxEvents) {
+		req := segmentRequest{Batch: make([]segmentTrack, len(chunk))}
+		for i, c := range chunk {
+			req.Batch[i] = segmentTrack{
+				Type:        "track",
+				Event:       clickEvent,
+				AnonymousID: clientID(c),
+				Timestamp:   c.ClickedAt,
+				Properties: map[string]string{
+					"short_url": string(c.ShortURL),
+					"referrer":  c.Referrer,
+				},
+				Context: map[string]string{"userAgent": c.UserAgent},
+			}
+		}
+		err := postJSON(ctx, s.client, s.endpoint, req, func(r *http.Request) {
+			r.SetBasicAuth(s.writeKey, "")
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTP exports the clicks as the JSON array to the generic endpoint.
+type HTTP struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// NewHTTP returns the exporter to the given URL
+// adding the headers to every request.
+func NewHTTP(client *http.Client, url string, headers map[string]string) *HTTP {
+	return &HTTP{client: client, url: url, headers: headers}
+}
+
+// Name implements Exporter.
+func (h *HTTP) Name() string {
+	return "http"
+}
+
+// Export sends all the clicks in a single request.
+func (h *HTTP) Export(ctx context.Context, clicks []*models.Click) error {
+	return postJSON(ctx, h.client, h.url, clicks, func(r *http.Request) {
+		for k, v := range h.headers {
+			r.Header.Set(k, v)
+		}
+	})
+}
+
+// clientID returns the anonymous ID of the client that clicked.
+func clientID(c *models.Click) string {
+	if c.IPHash == "" {
+		return anonymousID
+	}
+	return c.IPHash
+}
+
+// chunks splits the clicks into the chunks of at most size clicks.
+func chunks(clicks []*models.Click, size int) [][]*models.Click {
+	var res [][]*models.Click
+	for len(clicks) > size {
+		res = append(res, clicks[:size])
+		clicks = clicks[size:]
+	}
+	if len(clicks) > 0 {
+		res = append(res, clicks)
+	}
+	return res
+}
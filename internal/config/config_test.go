@@ -3,9 +3,14 @@ package config_test
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +48,260 @@ func TestNetAddress_SetInvalid(t *testing.T) {
 		require.Error(t, err, "invalid address produces no error")
 	}
 }
+
+func TestJWT_ExpirationOf(t *testing.T) {
+	cfg := config.JWT{
+		Expiration:        24 * time.Hour,
+		ServiceExpiration: 365 * 24 * time.Hour,
+		RefreshExpiration: 90 * 24 * time.Hour,
+	}
+
+	require.Equal(t, 365*24*time.Hour, cfg.ExpirationOf(user.TokenService))
+	require.Equal(t, 90*24*time.Hour, cfg.ExpirationOf(user.TokenRefresh))
+	require.Equal(t, 24*time.Hour, cfg.ExpirationOf(user.TokenAnonymous), "falls back to the default")
+	require.Equal(t, 24*time.Hour, cfg.ExpirationOf(user.TokenRegistered), "falls back to the default")
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	cases := []struct {
+		input   string
+		wantErr bool
+	}{
+		{input: "https://sho.rt"},
+		{input: "https://sho.rt:8443/s/"},
+		{input: "http://localhost:8080"},
+		{input: "sho.rt", wantErr: true},
+		{input: "ftp://sho.rt", wantErr: true},
+		{input: "https://", wantErr: true},
+		{input: "https://sho.rt/?a=1", wantErr: true},
+		{input: "https://sho.rt/#top", wantErr: true},
+	}
+
+	for _, c := range cases {
+		err := config.ValidateBaseURL(c.input)
+		if c.wantErr {
+			require.Error(t, err, c.input)
+			continue
+		}
+		require.NoError(t, err, c.input)
+	}
+}
+
+func TestLoadOrGenerateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt-dev.key")
+
+	key, generated, err := config.LoadOrGenerateKey(path)
+	require.NoError(t, err)
+	require.True(t, generated)
+	require.Len(t, key, 64)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// the persisted key is reused
+	again, generated, err := config.LoadOrGenerateKey(path)
+	require.NoError(t, err)
+	require.False(t, generated)
+	require.Equal(t, key, again)
+}
+
+func TestEnvironment_Set(t *testing.T) {
+	var env config.Environment
+
+	require.NoError(t, env.Set("production"))
+	require.True(t, env.IsProduction())
+	require.NoError(t, env.Set("development"))
+	require.False(t, env.IsProduction())
+	require.True(t, env.IsDevelopment())
+	require.NoError(t, env.Set("staging"))
+	require.False(t, env.IsProduction())
+	require.False(t, env.IsDevelopment())
+	require.Error(t, env.Set("testing"))
+}
+
+func TestReadEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	t.Setenv("CONFIG", path)
+	t.Setenv("ENVIRONMENT", "")
+	require.NoError(t, os.Unsetenv("ENVIRONMENT"))
+
+	require.NoError(t, os.WriteFile(path, []byte("delete_buffer_length: 5\n"), 0o600))
+	env, err := config.ReadEnvironment()
+	require.NoError(t, err)
+	require.Equal(t, config.EnvironmentProduction, env, "production expected if unset")
+
+	require.NoError(t, os.WriteFile(path, []byte("environment: \"development\"\n"), 0o600))
+	env, err = config.ReadEnvironment()
+	require.NoError(t, err)
+	require.Equal(t, config.EnvironmentDevelopment, env)
+
+	t.Setenv("ENVIRONMENT", "staging")
+	env, err = config.ReadEnvironment()
+	require.NoError(t, err)
+	require.Equal(t, config.EnvironmentStaging, env, "the environment variable overrides the file")
+
+	t.Setenv("ENVIRONMENT", "testing")
+	_, err = config.ReadEnvironment()
+	require.Error(t, err)
+}
+
+func TestEnvironment_Defaults(t *testing.T) {
+	var dev config.Config
+	config.EnvironmentDevelopment.Defaults(&dev)
+	require.False(t, dev.Logger.Format.IsJSON())
+	require.True(t, dev.Pprof.Enabled)
+	require.False(t, dev.JSON.DisallowUnknownFields)
+	require.Equal(t, []string{"*"}, dev.CORS.AllowedOrigins)
+
+	for _, env := range []config.Environment{config.EnvironmentStaging, config.EnvironmentProduction} {
+		var cfg config.Config
+		env.Defaults(&cfg)
+		require.True(t, cfg.Logger.Format.IsJSON(), env)
+		require.False(t, cfg.Pprof.Enabled, env)
+		require.True(t, cfg.JSON.DisallowUnknownFields, env)
+		require.Empty(t, cfg.CORS.AllowedOrigins, env)
+	}
+}
+
+func TestSandbox_Apply(t *testing.T) {
+	cfg := config.NewForTest()
+	cfg.DSN = "postgres://localhost/shortener"
+	cfg.FileStoragePath = "urls.json"
+	cfg.TLSEnabled = true
+	cfg.RateLimit.UserLimit = 10
+	cfg.Sandbox.Apply(cfg)
+	require.Equal(t, "postgres://localhost/shortener", cfg.DSN, "nothing applied if disabled")
+
+	cfg.Sandbox.Enabled = true
+	cfg.Sandbox.Apply(cfg)
+	require.Empty(t, cfg.DSN)
+	require.Empty(t, cfg.FileStoragePath)
+	require.False(t, bool(cfg.TLSEnabled))
+	require.Zero(t, cfg.RateLimit.UserLimit)
+	require.Equal(t, config.SandboxSigningKey, cfg.JWT.SigningKey)
+
+	cfg.Environment = config.EnvironmentProduction
+	report := cfg.Validate()
+	require.True(t, report.HasErrors())
+	require.Equal(t, "sandbox.enabled", report.Problems[0].Setting)
+}
+
+func TestLogFormat_Set(t *testing.T) {
+	var f config.LogFormat
+
+	require.NoError(t, f.Set("json"))
+	require.True(t, f.IsJSON())
+	require.NoError(t, f.Set("console"))
+	require.False(t, f.IsJSON())
+	require.Error(t, f.Set("text"))
+}
+
+func TestReadReloadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	t.Setenv("CONFIG", path)
+
+	c := config.NewForTest()
+	c.Logger.Level = "info"
+	c.RateLimit.Period = time.Minute
+	c.RateLimit.Redis = true
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+logger:
+  level: "debug"
+trusted_subnet: "10.0.0.0/8"
+delete_buffer_length: 50
+rate_limit:
+  user_limit: 10
+  redis: false
+`), 0o600))
+
+	r, err := config.ReadReloadable(c.Live())
+	require.NoError(t, err)
+	require.Equal(t, "debug", r.LogLevel)
+	require.Equal(t, "10.0.0.0/8", r.TrustedSubnet)
+	require.Equal(t, 50, r.DeleteBufLen)
+	require.Equal(t, 10, r.RateLimit.UserLimit)
+	require.Equal(t, time.Minute, r.RateLimit.Period, "missing settings keep the current values")
+	require.True(t, r.RateLimit.Redis, "the storage of the limits is not reloaded")
+
+	// the environment still overrides the file
+	t.Setenv("TRUSTED_SUBNET", "192.168.0.0/16")
+	r, err = config.ReadReloadable(c.Live())
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.0/16", r.TrustedSubnet)
+
+	// the reloaded settings replace the loaded ones
+	c.Apply(r)
+	require.Equal(t, r, c.Live())
+	require.Equal(t, config.NewForTest().TrustedSubnet, c.TrustedSubnet)
+
+	// the invalid file keeps the current settings
+	require.NoError(t, os.WriteFile(path, []byte("delete_buffer_length: 0\n"), 0o600))
+	_, err = config.ReadReloadable(c.Live())
+	require.Error(t, err)
+	require.Equal(t, r, c.Live())
+}
+
+func TestValidate(t *testing.T) {
+	c := config.NewForTest()
+	c.JWT.SigningKey = strings.Repeat("k", config.MinSigningKeyLength)
+	report := c.Validate()
+	require.Empty(t, report.Problems, report.String())
+	require.NoError(t, report.Err())
+
+	c.TrustedSubnet = "10.0.0.0"
+	c.JWT.SigningKey = "short"
+	c.TLS.CertFile = "tls.crt"
+	c.RateLimit.Redis = true
+	c.JSONNaming = "kebab-case"
+	c.SLO.Availability = 1
+	c.Postgres.MaxOpenConns = -1
+	c.HTTPServer.Timeout = 0
+	c.Postgres.StatementTimeout = -time.Second
+	c.FileStorage.Integrity = true
+	report = c.Validate()
+	require.True(t, report.HasErrors())
+
+	var verr *config.ValidationError
+	require.ErrorAs(t, report.Err(), &verr)
+	require.Same(t, report, verr.Report)
+
+	severities := make(map[string]config.Severity, len(report.Problems))
+	for _, p := range report.Problems {
+		severities[p.Setting] = p.Severity
+	}
+	require.Equal(t, map[string]config.Severity{
+		"trusted_subnet":             config.SeverityError,
+		"jwt.signing_key":            config.SeverityWarning,
+		"tls":                        config.SeverityError,
+		"tls.cert_file":              config.SeverityWarning,
+		"rate_limit.redis":           config.SeverityError,
+		"json_naming":                config.SeverityError,
+		"slo.availability":           config.SeverityError,
+		"postgres.max_open_conns":    config.SeverityError,
+		"http_server.timeout":        config.SeverityError,
+		"postgres.statement_timeout": config.SeverityError,
+		"file_storage.integrity_key": config.SeverityError,
+	}, severities)
+	require.Contains(t, report.String(), "config: 9 errors, 2 warnings")
+
+	// the weak key is fatal outside the development
+	c = config.NewForTest()
+	c.Environment = config.EnvironmentProduction
+	report = c.Validate()
+	require.True(t, report.HasErrors())
+	require.Equal(t, "jwt.signing_key", report.Problems[0].Setting)
+}
+
+func TestACMEHosts(t *testing.T) {
+	c := config.NewForTest()
+	require.Empty(t, c.ACMEHosts(), "any host is allowed")
+
+	c.HTTPServer.BaseURL = "https://sho.rt:8443/s"
+	c.HTTPServer.Domains = []string{"go.example", "sho.rt"}
+	require.Equal(t, []string{"sho.rt", "go.example"}, c.ACMEHosts())
+
+	c.TLS.Hosts = []string{"only.example"}
+	require.Equal(t, []string{"only.example"}, c.ACMEHosts())
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap/zapcore"
+)
+
+// Reloadable are the settings safe to change without restarting the server.
+// They are read again from the configuration file on SIGHUP.
+type Reloadable struct {
+	// Application logging level.
+	LogLevel string
+	// Trusted subnet in CIDR notation, see Config.TrustedSubnet.
+	TrustedSubnet string
+	// Length of the buffer for asynchronous deletion.
+	DeleteBufLen int
+	// Rate limits of the shorten endpoints. The storage of the limits
+	// is chosen on startup, so RateLimit.Redis is not reloaded.
+	RateLimit RateLimit
+}
+
+// Live returns the settings reloadable at runtime: the ones applied last
+// or the loaded ones if the configuration was not reloaded.
+// It is safe for concurrent use.
+func (c *Config) Live() Reloadable {
+	if r := c.live.Load(); r != nil {
+		return *r
+	}
+	return Reloadable{
+		LogLevel:      c.Logger.Level,
+		TrustedSubnet: c.TrustedSubnet,
+		DeleteBufLen:  c.DeleteBufLen,
+		RateLimit:     c.RateLimit,
+	}
+}
+
+// Apply replaces the settings reloadable at runtime. It is safe
+// for concurrent use. The other fields of the config are not changed.
+func (c *Config) Apply(r Reloadable) {
+	c.live.Store(&r)
+}
+
+// ReadReloadable reads the reloadable settings from the configuration file
+// given by the CONFIG environment variable, keeping the precedence of
+// Load: the file, the flags and the environment variables. The settings
+// missing everywhere keep the current values.
+func ReadReloadable(current Reloadable) (Reloadable, error) {
+	// the fields are tagged the same as the ones of Config
+	var file struct {
+		Logger struct {
+			Level string `yaml:"level" env:"LOG_LEVEL"`
+		} `yaml:"logger"`
+		TrustedSubnet string    `yaml:"trusted_subnet" env:"TRUSTED_SUBNET"`
+		DeleteBufLen  int       `yaml:"delete_buffer_length"`
+		RateLimit     RateLimit `yaml:"rate_limit"`
+	}
+	file.Logger.Level = current.LogLevel
+	file.TrustedSubnet = current.TrustedSubnet
+	file.DeleteBufLen = current.DeleteBufLen
+	file.RateLimit = current.RateLimit
+
+	if configPath, set := os.LookupEnv("CONFIG"); set {
+		if err := readConfigFile(configPath, &file); err != nil {
+			return current, err
+		}
+	}
+
+	// the flags given on startup still override the file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "l":
+			file.Logger.Level = f.Value.String()
+		case "t":
+			file.TrustedSubnet = f.Value.String()
+		}
+	})
+
+	if err := cleanenv.ReadEnv(&file); err != nil {
+		return current, fmt.Errorf("failed to read environment variables: %w", err)
+	}
+
+	r := Reloadable{
+		LogLevel:      file.Logger.Level,
+		TrustedSubnet: file.TrustedSubnet,
+		DeleteBufLen:  file.DeleteBufLen,
+		RateLimit:     file.RateLimit,
+	}
+	// the storage of the limits can't be switched at runtime
+	r.RateLimit.Redis = current.RateLimit.Redis
+
+	if err := r.Validate(); err != nil {
+		return current, err
+	}
+	return r, nil
+}
+
+// Validate checks the reloadable settings.
+func (r Reloadable) Validate() error {
+	if _, err := zapcore.ParseLevel(r.LogLevel); err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	if r.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(r.TrustedSubnet); err != nil {
+			return fmt.Errorf("invalid trusted subnet: %w", err)
+		}
+	}
+	if r.DeleteBufLen <= 0 {
+		return errors.New("delete buffer length should be >= 1")
+	}
+	if r.RateLimit.UserLimit < 0 || r.RateLimit.IPLimit < 0 || r.RateLimit.Period <= 0 {
+		return errors.New("rate limits should be >= 0 and the period > 0")
+	}
+	return nil
+}
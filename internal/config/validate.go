@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/ipallow"
+)
+
+// MinSigningKeyLength is the length of the JWT signing key in bytes
+// required outside the development environment.
+const MinSigningKeyLength = 32
+
+// Severity is the severity of the configuration problem.
+type Severity string
+
+// Severities of the problems.
+const (
+	// SeverityError stops the server from starting.
+	SeverityError Severity = "error"
+	// SeverityWarning is reported, the server starts anyway.
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single problem of the configuration.
+type Problem struct {
+	Severity Severity
+	// Setting is the key of the setting in the configuration file,
+	// e.g. jwt.signing_key.
+	Setting string
+	Message string
+}
+
+// Report is the result of the configuration validation.
+type Report struct {
+	Problems []Problem
+}
+
+// Errorf adds the error of the setting.
+func (r *Report) Errorf(setting, format string, args ...any) {
+	r.Problems = append(r.Problems, Problem{SeverityError, setting, fmt.Sprintf(format, args...)})
+}
+
+// Warnf adds the warning of the setting.
+func (r *Report) Warnf(setting, format string, args ...any) {
+	r.Problems = append(r.Problems, Problem{SeverityWarning, setting, fmt.Sprintf(format, args...)})
+}
+
+// ValidationError is the error of the invalid configuration.
+// The report lists all the problems, including the warnings.
+type ValidationError struct {
+	Report *Report
+}
+
+func (e *ValidationError) Error() string { return e.Report.String() }
+
+// Err returns the report as *ValidationError if the configuration
+// is invalid, nil otherwise.
+func (r *Report) Err() error {
+	if !r.HasErrors() {
+		return nil
+	}
+	return &ValidationError{Report: r}
+}
+
+// HasErrors reports whether the configuration is invalid.
+func (r *Report) HasErrors() bool {
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the report with a problem per line, e.g.:
+//
+//	config: 1 error, 1 warning
+//	  error    trusted_subnet: invalid CIDR address: 10.0.0.0
+//	  warning  jwt.signing_key: shorter than 32 bytes
+func (r *Report) String() string {
+	var errors, warnings int
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "config: %s, %s", plural(errors, "error"), plural(warnings, "warning"))
+	for _, p := range r.Problems {
+		fmt.Fprintf(&b, "\n  %-8s %s: %s", p.Severity, p.Setting, p.Message)
+	}
+	return b.String()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Validate checks the syntax of the settings and their combinations.
+// It doesn't connect anywhere, see repository.CheckConnections for that.
+func (c *Config) Validate() *Report {
+	var r Report
+
+	// the values of the enums bypass the setters when read from the file
+	enums := []struct {
+		setting string
+		value   interface{ Set(string) error }
+		raw     string
+	}{
+		{"user_id_format", new(UserIDFormat), string(c.UserIDFormat)},
+		{"json_naming", new(JSONNaming), string(c.JSONNaming)},
+		{"stats.counting", new(StatsCounting), string(c.Stats.Counting)},
+		{"logger.format", new(LogFormat), string(c.Logger.Format)},
+		{"tls.cache", new(TLSCache), string(c.TLS.Cache)},
+		{"tls.challenge", new(ACMEChallenge), string(c.TLS.Challenge)},
+	}
+	for _, e := range enums {
+		if err := e.value.Set(e.raw); err != nil {
+			r.Errorf(e.setting, "%s", err)
+		}
+	}
+
+	if c.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(c.TrustedSubnet); err != nil {
+			r.Errorf("trusted_subnet", "%s", err)
+		}
+	}
+
+	if _, err := ipallow.Parse(c.WriteAllowlist); err != nil {
+		r.Errorf("write_allowlist", "%s", err)
+	}
+
+	if _, err := ipallow.Parse(c.TrustedProxies); err != nil {
+		r.Errorf("trusted_proxies", "%s", err)
+	}
+
+	switch {
+	case c.JWT.SigningKey == "":
+		r.Errorf("jwt.signing_key", "required in %s", c.Environment)
+	case len(c.JWT.SigningKey) < MinSigningKeyLength && c.Environment.IsDevelopment():
+		r.Warnf("jwt.signing_key", "shorter than %d bytes", MinSigningKeyLength)
+	case len(c.JWT.SigningKey) < MinSigningKeyLength:
+		r.Errorf("jwt.signing_key", "shorter than %d bytes", MinSigningKeyLength)
+	}
+
+	if c.FileStorage.Integrity && c.FileStorage.IntegrityKey == "" {
+		r.Errorf("file_storage.integrity_key", "required in the integrity mode")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		r.Errorf("tls", "both certificate and key files must be set")
+	}
+	if c.TLS.CertFile != "" && !c.TLSEnabled {
+		r.Warnf("tls.cert_file", "ignored since HTTPS is not enabled")
+	}
+	if c.TLSEnabled && c.TLS.CertFile == "" {
+		if len(c.ACMEHosts()) == 0 {
+			r.Warnf("tls.hosts", "certificates are issued for any host requested")
+		}
+		if c.TLS.Cache == TLSCacheDatabase && c.DSN == "" {
+			r.Errorf("tls.cache", "database cache requires dsn")
+		}
+	}
+
+	if c.HTTPServer.BaseURL != "" {
+		if err := ValidateBaseURL(c.HTTPServer.BaseURL); err != nil {
+			r.Errorf("http_server.base_url", "%s", err)
+		}
+	}
+	if c.NotFoundRedirect != "" {
+		if u, err := url.Parse(c.NotFoundRedirect); err != nil || !u.IsAbs() || u.Host == "" {
+			r.Errorf("not_found_redirect", "%q is not an absolute URL", c.NotFoundRedirect)
+		}
+	}
+
+	for _, o := range []struct {
+		setting string
+		value   float64
+	}{
+		{"slo.availability", c.SLO.Availability},
+		{"slo.redirect_latency_target", c.SLO.RedirectLatencyTarget},
+	} {
+		if o.value < 0 || o.value >= 1 {
+			r.Errorf(o.setting, "%v is not between 0 and 1", o.value)
+		}
+	}
+
+	// the durations disabling the feature if zero are not required
+	for _, d := range []struct {
+		setting  string
+		value    time.Duration
+		required bool
+	}{
+		{"http_server.timeout", c.HTTPServer.Timeout, true},
+		{"http_server.idle_timeout", c.HTTPServer.IdleTimeout, false},
+		{"http_server.shutdown_timeout", c.HTTPServer.ShutdownTimeout, true},
+		{"jwt.expiration", c.JWT.Expiration, true},
+		{"jwt.anonymous_expiration", c.JWT.AnonymousExpiration, false},
+		{"jwt.registered_expiration", c.JWT.RegisteredExpiration, false},
+		{"jwt.service_expiration", c.JWT.ServiceExpiration, false},
+		{"jwt.oauth_expiration", c.JWT.OAuthExpiration, false},
+		{"jwt.refresh_expiration", c.JWT.RefreshExpiration, false},
+		{"cache.ttl", c.Cache.TTL, c.Cache.Size > 0},
+		{"degraded_mode.check_interval", c.Degraded.CheckInterval, c.Degraded.Enabled},
+		{"rate_limit.period", c.RateLimit.Period, c.RateLimit.UserLimit > 0 || c.RateLimit.IPLimit > 0},
+		{"expiration.max_ttl", c.Expiration.MaxTTL, false},
+		{"idempotency.ttl", c.Idempotency.TTL, false},
+		{"feeds.poll_interval", c.Feeds.PollInterval, false},
+		{"stats.cache_ttl", c.Stats.CacheTTL, false},
+		{"redis.ttl", c.Redis.TTL, false},
+		{"consistency.read_your_writes_window", c.Consistency.ReadYourWritesWindow, false},
+		{"postgres.conn_max_lifetime", c.Postgres.ConnMaxLifetime, false},
+		{"postgres.statement_timeout", c.Postgres.StatementTimeout, false},
+		{"sandbox.latency", c.Sandbox.Latency, false},
+	} {
+		switch {
+		case d.value < 0:
+			r.Errorf(d.setting, "must not be negative")
+		case d.value == 0 && d.required:
+			r.Errorf(d.setting, "must be positive")
+		}
+	}
+
+	if c.Sandbox.Enabled && !c.Environment.IsDevelopment() {
+		r.Errorf("sandbox.enabled", "not allowed in %s", c.Environment)
+	}
+
+	if c.Postgres.ReplicaDSN != "" && (c.DSN == "" || strings.HasPrefix(c.DSN, "sqlite://")) {
+		r.Errorf("postgres.replica_dsn", "requires postgres dsn")
+	}
+	if c.Postgres.MaxOpenConns < 0 {
+		r.Errorf("postgres.max_open_conns", "must not be negative")
+	}
+	if c.Postgres.MaxOpenConns > 0 && c.Postgres.MaxIdleConns > c.Postgres.MaxOpenConns {
+		r.Warnf("postgres.max_idle_conns", "capped at max_open_conns %d", c.Postgres.MaxOpenConns)
+	}
+	if c.Postgres.MaxRetries < 0 {
+		r.Errorf("postgres.max_retries", "must not be negative")
+	}
+	if c.Postgres.MaxRetries > 0 && c.Postgres.MaxRetryBackoff < c.Postgres.RetryBackoff {
+		r.Warnf("postgres.max_retry_backoff", "less than retry_backoff %s", c.Postgres.RetryBackoff)
+	}
+
+	if c.RateLimit.Redis && c.Redis.DSN == "" {
+		r.Errorf("rate_limit.redis", "requires redis.dsn")
+	}
+	if c.Degraded.Enabled && c.DSN == "" && c.Redis.DSN == "" {
+		r.Warnf("degraded_mode.enabled", "ignored without the database")
+	}
+
+	return &r
+}
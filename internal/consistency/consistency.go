@@ -0,0 +1,23 @@
+// Package consistency marks the reads which have to see the recent writes
+// of the client, i.e. the read-your-writes consistency. The storages
+// serve the marked reads from the primary database, bypassing the caches
+// and the replicas which may lag behind it.
+package consistency
+
+import "context"
+
+// primaryKey is the context key of the mark.
+type primaryKey struct{}
+
+// WithPrimary returns the context of the reads which have to see
+// the recent writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Primary reports whether the reads with the context
+// have to see the recent writes.
+func Primary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
@@ -0,0 +1,241 @@
+// Package dnscache caches the hostnames resolved by the outbound requests,
+// e.g. of the feeds and the click export, to cut the resolution latency
+// and to protect the resolvers from the bursts of the same lookups.
+//
+// The addresses are reused for the positive TTL and the failed lookups
+// for the negative TTL. The system resolver doesn't report the TTLs of
+// the records, so the TTLs are the upper bounds of how stale the cached
+// addresses get. Concurrent lookups of the same host wait for the single
+// query to the resolver.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lookuper resolves the hostnames, e.g. net.DefaultResolver.
+type Lookuper interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Resolver resolves the hostnames through the cache.
+// It is safe for concurrent use.
+type Resolver struct {
+	lookuper    Lookuper
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+
+	hits, negativeHits, misses, errors atomic.Int64
+}
+
+// entry is the result of the lookup of the host. done is closed
+// once the lookup is finished, the other fields are set before.
+type entry struct {
+	done    chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Metrics are the metrics of the cache.
+type Metrics struct {
+	// Entries is the number of the cached hosts.
+	Entries int `json:"entries"`
+	// Hits is the number of the lookups answered with the cached addresses.
+	Hits int64 `json:"hits"`
+	// NegativeHits is the number of the lookups answered
+	// with the cached failure.
+	NegativeHits int64 `json:"negative_hits"`
+	// Misses is the number of the lookups sent to the resolver.
+	Misses int64 `json:"misses"`
+	// Errors is the number of the failed lookups sent to the resolver.
+	Errors int64 `json:"errors"`
+}
+
+// New returns the resolver caching up to size hosts resolved by the
+// lookuper, net.DefaultResolver if nil. The cache is disabled if ttl
+// is not positive; the failed lookups are not cached if negativeTTL
+// is not positive.
+func New(lookuper Lookuper, ttl, negativeTTL time.Duration, size int) *Resolver {
+	if lookuper == nil {
+		lookuper = net.DefaultResolver
+	}
+	return &Resolver{
+		lookuper:    lookuper,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		size:        size,
+		now:         time.Now,
+		entries:     make(map[string]*entry),
+	}
+}
+
+// LookupHost returns the addresses of the host.
+// IP addresses are returned as is.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	if r.ttl <= 0 || r.size <= 0 {
+		r.misses.Add(1)
+		return r.lookup(ctx, host)
+	}
+
+	r.mu.Lock()
+	e, ok := r.entries[host]
+	if ok {
+		select {
+		case <-e.done:
+			if r.now().Before(e.expires) {
+				r.mu.Unlock()
+				return r.cached(e)
+			}
+		default:
+			// the lookup is in flight, wait for it
+			r.mu.Unlock()
+			select {
+			case <-e.done:
+				return r.cached(e)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	e = &entry{done: make(chan struct{})}
+	r.evict()
+	r.entries[host] = e
+	r.mu.Unlock()
+
+	r.misses.Add(1)
+	// the lookup is shared by the waiters, so that it is not
+	// canceled with the context of the first caller
+	lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+	e.addrs, e.err = r.lookup(lookupCtx, host)
+	cancel()
+
+	ttl := r.ttl
+	if e.err != nil {
+		ttl = r.negativeTTL
+	}
+	e.expires = r.now().Add(ttl)
+	close(e.done)
+
+	if e.err != nil && ttl <= 0 {
+		r.mu.Lock()
+		if r.entries[host] == e {
+			delete(r.entries, host)
+		}
+		r.mu.Unlock()
+	}
+
+	return e.addrs, e.err
+}
+
+// lookupTimeout bounds the lookups shared by the concurrent callers.
+const lookupTimeout = 10 * time.Second
+
+// lookup resolves the host with the lookuper counting the errors.
+func (r *Resolver) lookup(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.lookuper.LookupHost(ctx, host)
+	if err != nil {
+		r.errors.Add(1)
+		return nil, err
+	}
+	return addrs, nil
+}
+
+// cached returns the result of the finished lookup counting the hit.
+func (r *Resolver) cached(e *entry) ([]string, error) {
+	if e.err != nil {
+		r.negativeHits.Add(1)
+		return nil, e.err
+	}
+	r.hits.Add(1)
+	return e.addrs, nil
+}
+
+// evict makes room for the new entry: the expired entries are removed
+// first and then any finished one. It must be called with mu held.
+func (r *Resolver) evict() {
+	if len(r.entries) < r.size {
+		return
+	}
+	now := r.now()
+	for host, e := range r.entries {
+		select {
+		case <-e.done:
+			if !now.Before(e.expires) {
+				delete(r.entries, host)
+			}
+		default:
+		}
+	}
+	for host, e := range r.entries {
+		if len(r.entries) < r.size {
+			return
+		}
+		select {
+		case <-e.done:
+			delete(r.entries, host)
+		default:
+		}
+	}
+}
+
+// Metrics returns the current metrics of the cache.
+func (r *Resolver) Metrics() Metrics {
+	r.mu.Lock()
+	entries := len(r.entries)
+	r.mu.Unlock()
+
+	return Metrics{
+		Entries:      entries,
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Errors:       r.errors.Load(),
+	}
+}
+
+// DialContext returns the function dialing the address with the dialer,
+// its host resolved through the cache. The resolved addresses are tried
+// in order until one connects, so that the dialer's Control still sees
+// every address, e.g. to deny the private networks.
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if len(errs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return nil, errors.Join(errs...)
+	}
+}
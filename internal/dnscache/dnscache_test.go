@@ -0,0 +1,154 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookuper counts the lookups and resolves every host to the addrs
+// or fails with err. The lookups wait for release if it is set.
+type lookuper struct {
+	addrs   []string
+	err     error
+	release chan struct{}
+	calls   atomic.Int64
+}
+
+func (l *lookuper) LookupHost(context.Context, string) ([]string, error) {
+	l.calls.Add(1)
+	if l.release != nil {
+		<-l.release
+	}
+	return l.addrs, l.err
+}
+
+func TestResolver_Cache(t *testing.T) {
+	l := &lookuper{addrs: []string{"192.0.2.1"}}
+	r := New(l, time.Minute, 0, 16)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		addrs, err := r.LookupHost(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.1"}, addrs)
+	}
+	assert.Equal(t, int64(1), l.calls.Load())
+
+	// resolved again once expired
+	now = now.Add(time.Minute)
+	_, err := r.LookupHost(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), l.calls.Load())
+
+	assert.Equal(t, Metrics{Entries: 1, Hits: 2, Misses: 2}, r.Metrics())
+}
+
+func TestResolver_Negative(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "missing.test", IsNotFound: true}
+
+	l := &lookuper{err: notFound}
+	r := New(l, time.Minute, 10*time.Second, 16)
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupHost(context.Background(), "missing.test")
+		require.ErrorIs(t, err, notFound)
+	}
+	assert.Equal(t, int64(1), l.calls.Load())
+	assert.Equal(t, Metrics{Entries: 1, NegativeHits: 1, Misses: 1, Errors: 1}, r.Metrics())
+
+	// failures are not cached without the negative TTL
+	l = &lookuper{err: notFound}
+	r = New(l, time.Minute, 0, 16)
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupHost(context.Background(), "missing.test")
+		require.Error(t, err)
+	}
+	assert.Equal(t, int64(2), l.calls.Load())
+	assert.Zero(t, r.Metrics().Entries)
+}
+
+func TestResolver_ConcurrentLookups(t *testing.T) {
+	l := &lookuper{addrs: []string{"192.0.2.1"}, release: make(chan struct{})}
+	r := New(l, time.Minute, 0, 16)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := r.LookupHost(context.Background(), "example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"192.0.2.1"}, addrs)
+		}()
+	}
+	assert.Eventually(t, func() bool { return l.calls.Load() == 1 },
+		time.Second, time.Millisecond)
+	close(l.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), l.calls.Load(), "single query for the burst")
+}
+
+func TestResolver_Size(t *testing.T) {
+	l := &lookuper{addrs: []string{"192.0.2.1"}}
+	r := New(l, time.Minute, 0, 2)
+
+	for _, host := range []string{"a.test", "b.test", "c.test"} {
+		_, err := r.LookupHost(context.Background(), host)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, r.Metrics().Entries)
+}
+
+func TestResolver_Disabled(t *testing.T) {
+	l := &lookuper{addrs: []string{"192.0.2.1"}}
+	r := New(l, 0, 0, 16)
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupHost(context.Background(), "example.com")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), l.calls.Load())
+
+	// IP addresses are not resolved
+	addrs, err := r.LookupHost(context.Background(), "192.0.2.7")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.7"}, addrs)
+	assert.Equal(t, int64(2), l.calls.Load())
+}
+
+func TestResolver_DialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			_ = conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	l := &lookuper{addrs: []string{"127.0.0.1"}}
+	dial := New(l, time.Minute, 0, 16).DialContext(&net.Dialer{Timeout: time.Second})
+
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("service.test", port))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// the dialer sees the resolved addresses
+	denied := errors.New("denied")
+	dial = New(l, time.Minute, 0, 16).DialContext(&net.Dialer{
+		Control: func(string, string, syscall.RawConn) error { return denied },
+	})
+	_, err = dial(context.Background(), "tcp", net.JoinHostPort("service.test", port))
+	require.ErrorIs(t, err, denied)
+}
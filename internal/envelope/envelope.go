@@ -0,0 +1,105 @@
+// Package envelope provides the uniform body of the JSON responses
+// of the API: the payload is in the data field, the failure in the
+// error field and the details of the response, e.g. the pagination
+// of the lists, in the meta field.
+//
+//	{
+//		"data": [ ... ],
+//		"meta": { "pagination": { "total": 120, "limit": 20, "offset": 40 } }
+//	}
+//
+//	{
+//		"data": null,
+//		"error": { "code": "invalid_request", "message": "invalid URL" }
+//	}
+package envelope
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// Response is the enveloped body of the response.
+type Response struct {
+	// Data is the payload, null if the request failed.
+	Data any `json:"data"`
+	// Error is set if the request failed.
+	Error *Error `json:"error,omitempty"`
+	// Meta describes the payload, nil if there is nothing to describe.
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// Error is the failure of the request. The failed requests respond
+// with it as the body if the envelope is not configured.
+type Error struct {
+	// Code is the machine-readable code of the failure, e.g. not_found.
+	Code string `json:"code"`
+	// Message is the human-readable description of the failure.
+	Message string `json:"message"`
+	// Details is the cause of the failure, if it is worth telling.
+	Details string `json:"details,omitempty"`
+	// RequestID is the ID of the request to look the failure up in the logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Meta describes the payload.
+type Meta struct {
+	// Pagination is set if the payload is the page of the list.
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination is the position of the page in the list.
+type Pagination struct {
+	// Total is the number of the items of the whole list.
+	Total int `json:"total"`
+	// Limit is the maximum number of the items of the page,
+	// zero if not limited.
+	Limit int `json:"limit,omitempty"`
+	// Offset is the number of the items before the page.
+	Offset int `json:"offset"`
+}
+
+// Data returns the response with the payload.
+func Data(v any) Response {
+	return Response{Data: v}
+}
+
+// Page returns the response with the page of the list.
+func Page(v any, p Pagination) Response {
+	return Response{Data: v, Meta: &Meta{Pagination: &p}}
+}
+
+// Failure returns the response of the failed request.
+func Failure(e Error) Response {
+	return Response{Error: &e}
+}
+
+// NewError returns the failure of the request with the status code.
+// The code of the error is looked up by the common error it wraps and
+// the status. The cause is reported in the details unless it is the common
+// error itself or the server error, whose causes are logged only.
+func NewError(message string, err error, status int, requestID string) Error {
+	e := Error{
+		Code:      errs.CodeOf(err, status),
+		Message:   message,
+		RequestID: requestID,
+	}
+	if status < http.StatusInternalServerError && err != errs.StatusOf(err).Err {
+		e.Details = err.Error()
+	}
+	return e
+}
+
+// WriteError writes the failure as the JSON body of the response with
+// the status code, in the envelope if enveloped is set.
+func WriteError(w http.ResponseWriter, status int, e Error, enveloped bool) error {
+	var v any = e
+	if enveloped {
+		v = Failure(e)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
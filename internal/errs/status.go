@@ -0,0 +1,87 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+// GRPCCode is the code of the gRPC status. The values are the ones
+// of google.golang.org/grpc/codes, so that they convert with codes.Code.
+type GRPCCode uint32
+
+// The gRPC codes the errors are reported with.
+const (
+	GRPCInvalidArgument GRPCCode = 3
+	GRPCNotFound        GRPCCode = 5
+	GRPCAlreadyExists   GRPCCode = 6
+	GRPCInternal        GRPCCode = 13
+	GRPCUnavailable     GRPCCode = 14
+	GRPCUnauthenticated GRPCCode = 16
+)
+
+// Status is how the error is reported to the clients.
+type Status struct {
+	// Err is the common error reported, nil for the internal errors.
+	Err error
+	// Code is the machine-readable code of the error, e.g. not_found.
+	Code string
+	// HTTP is the status code of the HTTP response.
+	HTTP int
+	// GRPC is the code of the gRPC status.
+	GRPC GRPCCode
+}
+
+// statuses maps the common errors to their statuses.
+// The first error the reported one wraps wins.
+var statuses = []Status{
+	{Err: ErrInvalidRequest, Code: "invalid_request", HTTP: http.StatusBadRequest, GRPC: GRPCInvalidArgument},
+	{Err: ErrUnauthorized, Code: "unauthorized", HTTP: http.StatusUnauthorized, GRPC: GRPCUnauthenticated},
+	{Err: ErrNotFound, Code: "not_found", HTTP: http.StatusNotFound, GRPC: GRPCNotFound},
+	{Err: ErrConflict, Code: "conflict", HTTP: http.StatusConflict, GRPC: GRPCAlreadyExists},
+	{Err: ErrDBNotConnected, Code: "unavailable", HTTP: http.StatusServiceUnavailable, GRPC: GRPCUnavailable},
+}
+
+// internalStatus is the status of the errors wrapping none of the common ones.
+var internalStatus = Status{Code: "internal", HTTP: http.StatusInternalServerError, GRPC: GRPCInternal}
+
+// httpCodes are the codes of the HTTP statuses the handlers respond
+// with regardless of the error, e.g. 501 Not Implemented of the features
+// the storage does not support.
+var httpCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusGone:                  "gone",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusUnprocessableEntity:   "unprocessable",
+	http.StatusTooManyRequests:       "too_many_requests",
+	http.StatusInternalServerError:   "internal",
+	http.StatusNotImplemented:        "not_implemented",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// StatusOf returns the status of the error by the common error it wraps.
+func StatusOf(err error) Status {
+	for _, s := range statuses {
+		if errors.Is(err, s.Err) {
+			return s
+		}
+	}
+	return internalStatus
+}
+
+// CodeOf returns the code of the error responded with the HTTP status.
+// The code of the status wins if it differs from the status of the error.
+func CodeOf(err error, httpStatus int) string {
+	s := StatusOf(err)
+	if s.HTTP == httpStatus {
+		return s.Code
+	}
+	if code, ok := httpCodes[httpStatus]; ok {
+		return code
+	}
+	return s.Code
+}
@@ -0,0 +1,55 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+		wantHTTP int
+		wantGRPC GRPCCode
+	}{
+		{"not found", ErrNotFound, "not_found", http.StatusNotFound, GRPCNotFound},
+		{"wrapped conflict", fmt.Errorf("save: %w", ErrConflict), "conflict", http.StatusConflict, GRPCAlreadyExists},
+		{
+			"first of the joined", fmt.Errorf("%w: %w", ErrInvalidRequest, ErrNotFound),
+			"invalid_request", http.StatusBadRequest, GRPCInvalidArgument,
+		},
+		{"internal", errors.New("broken"), "internal", http.StatusInternalServerError, GRPCInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := StatusOf(tt.err)
+			assert.Equal(t, tt.wantCode, s.Code)
+			assert.Equal(t, tt.wantHTTP, s.HTTP)
+			assert.Equal(t, tt.wantGRPC, s.GRPC)
+		})
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		httpStatus int
+		want       string
+	}{
+		{"status of the error", ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+		{"status differs", ErrInvalidRequest, http.StatusNotImplemented, "not_implemented"},
+		{"unknown status", ErrInvalidRequest, http.StatusTeapot, "invalid_request"},
+		{"internal error of the client", errors.New("bad URL"), http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CodeOf(tt.err, tt.httpStatus))
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package events publishes the lifecycle and redirect events of the URLs
+// to the message brokers, so that the analytics pipelines can consume
+// the traffic without scraping the database.
+//
+// The events are collected by the Bus and published in batches to every
+// configured Publisher concurrently. Failed batches are retried with
+// exponential backoff unless the broker rejects them. The delivery is
+// at least once: the events of the batch are published again on retry,
+// and the created events of the batch shortenings and imports are published
+// for the URLs shortened already, so the consumers should deduplicate
+// the events by their IDs and treat them as idempotent by the short URL.
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// Type is the type of the event.
+type Type string
+
+const (
+	// TypeCreated is the URL shortened by the user.
+	TypeCreated Type = "url.created"
+	// TypeUpdated is the URL edited, paused, resumed, blocked
+	// or transferred to another owner.
+	TypeUpdated Type = "url.updated"
+	// TypeDeleted is the URL deleted by its owner or the moderators.
+	// The expired URLs are deleted in bulk without the events.
+	TypeDeleted Type = "url.deleted"
+	// TypeRedirected is the redirect of the short URL.
+	TypeRedirected Type = "url.redirected"
+)
+
+// Event is the published event of the URL.
+type Event struct {
+	// ID is unique for every event, so that the consumers
+	// can deduplicate the events published again.
+	ID          string             `json:"id"`
+	Type        Type               `json:"type"`
+	ShortURL    models.ShortURL    `json:"short_url"`
+	OriginalURL models.OriginalURL `json:"original_url,omitempty"`
+	UserID      user.ID            `json:"user_id,omitempty"`
+	OccurredAt  time.Time          `json:"occurred_at"`
+	// The details of the redirects.
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IPHash    string `json:"ip_hash,omitempty"`
+}
+
+// New returns the lifecycle event of the URL occurred now.
+func New(typ Type, u *models.URL) *Event {
+	return &Event{
+		ID:          uuid.NewString(),
+		Type:        typ,
+		ShortURL:    u.ShortURL,
+		OriginalURL: u.OriginalURL,
+		UserID:      u.UserID,
+		OccurredAt:  time.Now().UTC(),
+	}
+}
+
+// NewRedirect returns the redirect event of the click.
+func NewRedirect(click *models.Click) *Event {
+	return &Event{
+		ID:         uuid.NewString(),
+		Type:       TypeRedirected,
+		ShortURL:   click.ShortURL,
+		OccurredAt: click.ClickedAt,
+		Referrer:   click.Referrer,
+		UserAgent:  click.UserAgent,
+		IPHash:     click.IPHash,
+	}
+}
+
+// Publisher publishes the events to the broker.
+type Publisher interface {
+	// Name returns the name of the publisher used in the logs.
+	Name() string
+
+	// Publish sends the events in their order.
+	// Errors wrapped with Permanent are not retried.
+	Publish(ctx context.Context, events []*Event) error
+}
+
+// Interface implementation checks.
+var (
+	_ Publisher = (*Kafka)(nil)
+	_ Publisher = (*NATS)(nil)
+)
+
+// Default values used if the config values are not set.
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultRetryBackoff  = time.Second
+	requestTimeout       = 10 * time.Second
+)
+
+// NewPublishers returns the publishers enabled by the configuration.
+// The hosts of the brokers are resolved with the resolver if it is not nil.
+func NewPublishers(cfg config.Events, resolver *dnscache.Resolver) ([]Publisher, error) {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	dial := dialer.DialContext
+	if resolver != nil {
+		dial = resolver.DialContext(dialer)
+	}
+
+	var publishers []Publisher
+	if cfg.Kafka.RESTProxyURL != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dial
+		client := &http.Client{Timeout: requestTimeout, Transport: transport}
+		publishers = append(publishers,
+			NewKafka(client, cfg.Kafka.RESTProxyURL, cfg.Kafka.Topic, cfg.Kafka.Headers))
+	}
+	if cfg.NATS.URL != "" {
+		nats, err := NewNATS(cfg.NATS.URL, cfg.NATS.SubjectPrefix, dial)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, nats)
+	}
+	return publishers, nil
+}
+
+// Bus collects the events and publishes them in batches.
+// It is safe for concurrent use.
+type Bus struct {
+	publishers []Publisher
+	logger     logger.Logger
+	// events is the buffer of the collected events.
+	events chan *Event
+	// batchSize is the maximum number of the events published at once.
+	batchSize int
+	// interval is how often the collected events are published.
+	interval time.Duration
+	// retries is the number of retries of the failed publishing.
+	retries int
+	// backoff is the delay before the first retry.
+	backoff time.Duration
+}
+
+// NewBus returns the bus publishing to the given publishers.
+// It must be run to publish anything.
+func NewBus(publishers []Publisher, cfg config.Events, logger logger.Logger) *Bus {
+	b := &Bus{
+		publishers: publishers,
+		logger:     logger,
+		batchSize:  cfg.BatchSize,
+		interval:   cfg.FlushInterval,
+		retries:    cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+	}
+	if b.batchSize <= 0 {
+		b.batchSize = defaultBatchSize
+	}
+	if b.interval <= 0 {
+		b.interval = defaultFlushInterval
+	}
+	if b.backoff <= 0 {
+		b.backoff = defaultRetryBackoff
+	}
+	b.events = make(chan *Event, 10*b.batchSize)
+	return b
+}
+
+// Publish schedules the publishing of the events.
+// It never blocks: the events are dropped if the buffer is full.
+func (b *Bus) Publish(events ...*Event) {
+	for _, e := range events {
+		select {
+		case b.events <- e:
+		default:
+			b.logger.Debugf("events buffer is full, %s event of %s dropped", e.Type, e.ShortURL)
+		}
+	}
+}
+
+// Run publishes the collected events when the batch is full or the flush
+// interval passes. When done is closed, it publishes the buffered events
+// without retries, closes the publishers and returns.
+func (b *Bus) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// stop waiting for the retries
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	batch := make([]*Event, 0, b.batchSize)
+	for {
+		select {
+		case e := <-b.events:
+			batch = append(batch, e)
+			if len(batch) < b.batchSize {
+				continue
+			}
+			b.publish(ctx, batch)
+			batch = make([]*Event, 0, b.batchSize)
+
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			b.publish(ctx, batch)
+			batch = make([]*Event, 0, b.batchSize)
+
+		case <-done:
+			for len(b.events) > 0 {
+				batch = append(batch, <-b.events)
+			}
+			if len(batch) > 0 {
+				b.publish(context.Background(), batch)
+			}
+			b.close()
+			return
+		}
+	}
+}
+
+// publish sends the batch to all the publishers concurrently.
+func (b *Bus) publish(ctx context.Context, batch []*Event) {
+	var wg sync.WaitGroup
+	for _, p := range b.publishers {
+		wg.Add(1)
+		go func(p Publisher) {
+			defer wg.Done()
+			if err := b.publishWithRetries(ctx, p, batch); err != nil {
+				b.logger.Errorf("publish %d events to %s: %s", len(batch), p.Name(), err)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// publishWithRetries publishes the batch retrying the failures with
+// exponential backoff. Retries stop when the context is canceled.
+func (b *Bus) publishWithRetries(ctx context.Context, p Publisher, batch []*Event) error {
+	backoff := b.backoff
+	for attempt := 0; ; attempt++ {
+		err := p.Publish(ctx, batch)
+		if err == nil || attempt >= b.retries || IsPermanent(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// close closes the connections of the publishers keeping them.
+func (b *Bus) close() {
+	for _, p := range b.publishers {
+		c, ok := p.(interface{ Close() error })
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			b.logger.Errorf("close %s: %s", p.Name(), err)
+		}
+	}
+}
+
+// permanentError is the publishing error not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not worth retrying, e.g. rejected request.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether the error is marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,305 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher records the published events
+// failing the first given number of calls.
+type recordingPublisher struct {
+	mu        sync.Mutex
+	calls     int
+	failures  int
+	err       error
+	published []*Event
+	closed    bool
+}
+
+func (p *recordingPublisher) Name() string { return "recording" }
+
+func (p *recordingPublisher) Publish(_ context.Context, events []*Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls <= p.failures {
+		return p.err
+	}
+	p.published = append(p.published, events...)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func newEvents(n int) []*Event {
+	events := make([]*Event, n)
+	for i := range events {
+		events[i] = New(TypeCreated, &models.URL{
+			ShortURL: models.ShortURL(fmt.Sprintf("YBbxJEcQ9v%d", i)), OriginalURL: "https://go.dev/", UserID: "test",
+		})
+	}
+	return events
+}
+
+func TestBus(t *testing.T) {
+	tests := []struct {
+		name      string
+		publisher *recordingPublisher
+		wantCalls int
+		wantCount int
+	}{
+		{
+			name:      "published in batches",
+			publisher: &recordingPublisher{},
+			wantCalls: 2,
+			wantCount: 3,
+		},
+		{
+			name:      "retried",
+			publisher: &recordingPublisher{failures: 2, err: errors.New("unavailable")},
+			wantCalls: 4,
+			wantCount: 3,
+		},
+		{
+			name:      "permanent error is not retried",
+			publisher: &recordingPublisher{failures: 1, err: Permanent(errors.New("rejected"))},
+			wantCalls: 2,
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			b := NewBus([]Publisher{tt.publisher}, config.Events{
+				BatchSize:     2,
+				FlushInterval: time.Hour,
+				MaxRetries:    3,
+				RetryBackoff:  time.Millisecond,
+			}, l)
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				b.Run(done)
+			}()
+
+			b.Publish(newEvents(3)...)
+			// the full batch is published without waiting for the stop
+			require.Eventually(t, func() bool {
+				tt.publisher.mu.Lock()
+				defer tt.publisher.mu.Unlock()
+				return tt.publisher.calls >= tt.wantCalls-1
+			}, time.Second, time.Millisecond)
+
+			// the rest is published on stop
+			close(done)
+			<-stopped
+
+			assert.Equal(t, tt.wantCalls, tt.publisher.calls)
+			assert.Len(t, tt.publisher.published, tt.wantCount)
+			assert.True(t, tt.publisher.closed, "publisher is closed on stop")
+		})
+	}
+}
+
+func TestKafka(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   []kafkaRequest
+		status   = http.StatusOK
+		response = `{"offsets": [{"partition": 0, "offset": 1}, {"partition": 1, "offset": 7}]}`
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body kafkaRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests, bodies = append(requests, r), append(bodies, body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	publishers, err := NewPublishers(config.Events{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, publishers, "no brokers are configured")
+
+	var cfg config.Events
+	cfg.Kafka.RESTProxyURL = server.URL + "/"
+	cfg.Kafka.Topic = "shortener-events"
+	cfg.Kafka.Headers = map[string]string{"X-Token": "token"}
+	publishers, err = NewPublishers(cfg, nil)
+	require.NoError(t, err)
+	require.Len(t, publishers, 1)
+	kafka := publishers[0]
+
+	events := newEvents(2)
+	require.NoError(t, kafka.Publish(context.Background(), events))
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/topics/shortener-events", requests[0].URL.Path)
+	assert.Equal(t, kafkaContentType, requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "token", requests[0].Header.Get("X-Token"))
+	require.Len(t, bodies[0].Records, 2)
+	assert.Equal(t, string(events[1].ShortURL), bodies[0].Records[1].Key, "keyed by the short URL")
+	assert.Equal(t, events[1].ID, bodies[0].Records[1].Value.ID)
+
+	// the records failed to be written are retried
+	response = `{"offsets": [{"partition": 0, "offset": 2}, {"error_code": 50003, "error": "timeout"}]}`
+	err = kafka.Publish(context.Background(), events)
+	require.ErrorContains(t, err, "timeout")
+	assert.False(t, IsPermanent(err))
+
+	// rejected requests are permanent errors, unavailable are retried
+	status = http.StatusUnprocessableEntity
+	assert.True(t, IsPermanent(kafka.Publish(context.Background(), events)))
+	status = http.StatusServiceUnavailable
+	err = kafka.Publish(context.Background(), events)
+	require.Error(t, err)
+	assert.False(t, IsPermanent(err))
+}
+
+// natsServer is the fake NATS server recording the published messages.
+type natsServer struct {
+	listener net.Listener
+	// reply is sent to the PING instead of PONG if it is set.
+	reply string
+
+	mu       sync.Mutex
+	connects []string
+	messages map[string][]string
+	conns    int
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &natsServer{listener: l, messages: make(map[string][]string)}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *natsServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_, _ = fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch op {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connects = append(s.connects, args)
+			s.mu.Unlock()
+		case "PUB":
+			subject, size, _ := strings.Cut(args, " ")
+			n, _ := strconv.Atoi(size)
+			payload := make([]byte, n+2)
+			if _, err = io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages[subject] = append(s.messages[subject], string(payload[:n]))
+			s.mu.Unlock()
+		case "PING":
+			s.mu.Lock()
+			reply := s.reply
+			s.mu.Unlock()
+			if reply == "" {
+				// the client answers the PINGs of the server
+				reply = "PING\r\nPONG"
+			}
+			_, _ = fmt.Fprintf(conn, "+OK\r\n%s\r\n", reply)
+		}
+	}
+}
+
+func TestNATS(t *testing.T) {
+	server := newNATSServer(t)
+
+	_, err := NewNATS("http://"+server.listener.Addr().String(), "shortener", nil)
+	require.Error(t, err, "unknown scheme")
+
+	var cfg config.Events
+	cfg.NATS.URL = "nats://user:secret@" + server.listener.Addr().String()
+	cfg.NATS.SubjectPrefix = "shortener."
+	publishers, err := NewPublishers(cfg, nil)
+	require.NoError(t, err)
+	require.Len(t, publishers, 1)
+	nats := publishers[0].(*NATS)
+	defer func() {
+		require.NoError(t, nats.Close())
+	}()
+
+	events := newEvents(2)
+	events = append(events, NewRedirect(models.NewClick("YBbxJEcQ9vq", time.Now(), "192.0.2.1", "", "curl/8.5.0")))
+	require.NoError(t, nats.Publish(context.Background(), events))
+	require.NoError(t, nats.Publish(context.Background(), events[:1]))
+
+	server.mu.Lock()
+	require.Len(t, server.connects, 1, "the connection is kept")
+	var connect natsConnect
+	require.NoError(t, json.Unmarshal([]byte(server.connects[0]), &connect))
+	assert.Equal(t, "user", connect.User)
+	assert.Equal(t, "secret", connect.Pass)
+	require.Len(t, server.messages["shortener.url.created"], 3)
+	require.Len(t, server.messages["shortener.url.redirected"], 1)
+	var got Event
+	require.NoError(t, json.Unmarshal([]byte(server.messages["shortener.url.created"][1]), &got))
+	assert.Equal(t, events[1].ID, got.ID)
+	assert.Equal(t, events[1].ShortURL, got.ShortURL)
+	server.reply = "-ERR 'Permissions Violation for Publish to shortener.url.created'"
+	server.mu.Unlock()
+
+	// the connection is dialed again after the failure
+	assert.True(t, IsPermanent(nats.Publish(context.Background(), events)))
+	server.mu.Lock()
+	server.reply = ""
+	server.mu.Unlock()
+	require.NoError(t, nats.Publish(context.Background(), events))
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, 2, server.conns)
+}
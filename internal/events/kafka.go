@@ -0,0 +1,121 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Content types of the v2 API of the Kafka REST Proxy.
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+// Kafka publishes the events to the topic through the Kafka REST Proxy,
+// so that no Kafka client is linked in. The events are keyed by their
+// short URLs, so that the events of the URL are kept in order within
+// its partition.
+type Kafka struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+}
+
+// NewKafka returns the publisher to the topic of the REST Proxy
+// at the given base URL. The headers are added to every request.
+func NewKafka(client *http.Client, proxyURL, topic string, headers map[string]string) *Kafka {
+	return &Kafka{
+		client:   client,
+		endpoint: strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		headers:  headers,
+	}
+}
+
+// Name implements Publisher.
+func (k *Kafka) Name() string {
+	return "kafka"
+}
+
+type (
+	kafkaRequest struct {
+		Records []kafkaRecord `json:"records"`
+	}
+
+	kafkaRecord struct {
+		Key   string `json:"key"`
+		Value *Event `json:"value"`
+	}
+
+	kafkaResponse struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+)
+
+// Publish sends the events in a single request. The responses with 4xx
+// status codes other than 429 Too Many Requests are permanent errors.
+// The events failed to be written are reported with the first error,
+// the whole batch is published again on retry.
+func (k *Kafka) Publish(ctx context.Context, events []*Event) error {
+	payload := kafkaRequest{Records: make([]kafkaRecord, len(events))}
+	for i, e := range events {
+		payload.Records[i] = kafkaRecord{Key: string(e.ShortURL), Value: e}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(fmt.Errorf("encode request: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return Permanent(fmt.Errorf("new request: %w", err))
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+	for key, value := range k.headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = fmt.Errorf("unexpected status %s", res.Status)
+		if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	var result kafkaResponse
+	if err = json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	failed := 0
+	for _, o := range result.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			if failed == 0 {
+				err = fmt.Errorf("write record: %s", o.Error)
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d events failed: %w", failed, len(events), err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultNATSPort is the client port of the NATS servers.
+const defaultNATSPort = "4222"
+
+// NATS publishes the events to the subjects named by the prefix and
+// the event type, e.g. shortener.url.created, with the core protocol
+// of the NATS server, so that no NATS client is linked in. The connection
+// is kept between the batches and dialed again after the failure.
+type NATS struct {
+	addr   string
+	host   string
+	tls    bool
+	prefix string
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	// connect is the CONNECT message authenticating the client.
+	connect []byte
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// natsConnect is the CONNECT message of the client.
+type natsConnect struct {
+	Verbose     bool   `json:"verbose"`
+	Pedantic    bool   `json:"pedantic"`
+	TLSRequired bool   `json:"tls_required"`
+	Name        string `json:"name"`
+	Lang        string `json:"lang"`
+	Version     string `json:"version"`
+	User        string `json:"user,omitempty"`
+	Pass        string `json:"pass,omitempty"`
+	AuthToken   string `json:"auth_token,omitempty"`
+}
+
+// natsInfo is the INFO message of the server the client needs.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// NewNATS returns the publisher to the server at the given URL with
+// the nats or tls scheme. The user and the password of the URL, or
+// the user alone as the token, authenticate the client.
+func NewNATS(
+	rawURL, prefix string, dial func(ctx context.Context, network, addr string) (net.Conn, error),
+) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("NATS URL scheme %q, want nats or tls", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultNATSPort
+	}
+
+	msg := natsConnect{Name: "shortener", Lang: "go", Version: "1.0.0", TLSRequired: u.Scheme == "tls"}
+	if pass, ok := u.User.Password(); ok {
+		msg.User, msg.Pass = u.User.Username(), pass
+	} else if u.User != nil {
+		msg.AuthToken = u.User.Username()
+	}
+	connect, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("encode CONNECT: %w", err)
+	}
+
+	return &NATS{
+		addr:    net.JoinHostPort(u.Hostname(), port),
+		host:    u.Hostname(),
+		tls:     u.Scheme == "tls",
+		prefix:  strings.TrimSuffix(prefix, "."),
+		dial:    dial,
+		connect: connect,
+	}, nil
+}
+
+// Name implements Publisher.
+func (n *NATS) Name() string {
+	return "nats"
+}
+
+// Publish sends the events and waits for the server to process them,
+// the PING sent after them is answered once all of them are. The violations
+// of the permissions and the authorization are permanent errors.
+func (n *NATS) Publish(ctx context.Context, events []*Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	err := n.publish(ctx, events)
+	if err != nil && n.conn != nil {
+		// the state of the connection is unknown after the failure
+		_ = n.conn.Close()
+		n.conn = nil
+	}
+	return err
+}
+
+func (n *NATS) publish(ctx context.Context, events []*Event) error {
+	if n.conn == nil {
+		if err := n.open(ctx); err != nil {
+			return err
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	if err := n.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return Permanent(fmt.Errorf("encode event: %w", err))
+		}
+		fmt.Fprintf(n.w, "PUB %s.%s %d\r\n", n.prefix, e.Type, len(b))
+		_, _ = n.w.Write(b)
+		_, _ = n.w.WriteString("\r\n")
+	}
+	_, _ = n.w.WriteString("PING\r\n")
+	if err := n.w.Flush(); err != nil {
+		return fmt.Errorf("write events: %w", err)
+	}
+
+	return n.waitPong()
+}
+
+// open dials the server, upgrades the connection to TLS if either side
+// requires it and authenticates the client.
+func (n *NATS) open(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	conn, err := n.dial(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("dial NATS: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// the server greets the client with INFO before the TLS handshake
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("read INFO: %w", err)
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if !strings.EqualFold(op, "INFO") {
+		_ = conn.Close()
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info natsInfo
+	if err = json.Unmarshal([]byte(args), &info); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("decode INFO: %w", err)
+	}
+
+	if n.tls || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: n.host, MinVersion: tls.VersionTLS12})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("TLS handshake: %w", err)
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	n.conn, n.r, n.w = conn, r, bufio.NewWriter(conn)
+	fmt.Fprintf(n.w, "CONNECT %s\r\n", n.connect)
+	return nil
+}
+
+// waitPong reads the replies of the server up to PONG answering its PINGs.
+func (n *NATS) waitPong() error {
+	for {
+		line, err := readLine(n.r)
+		if err != nil {
+			return fmt.Errorf("read reply: %w", err)
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PONG":
+			return nil
+		case "PING":
+			if _, err = n.w.WriteString("PONG\r\n"); err == nil {
+				err = n.w.Flush()
+			}
+			if err != nil {
+				return fmt.Errorf("write PONG: %w", err)
+			}
+		case "-ERR":
+			err = fmt.Errorf("NATS error: %s", args)
+			msg := strings.ToLower(args)
+			if strings.Contains(msg, "authorization") || strings.Contains(msg, "permissions") {
+				return Permanent(err)
+			}
+			return err
+		default:
+			// +OK and INFO updates are of no interest
+		}
+	}
+}
+
+// Close closes the connection to the server, if any.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// readLine reads the control line of the protocol without CRLF.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,347 @@
+// Package feeds polls RSS and Atom feeds and shortens their new items.
+//
+// A feed is subscribed by the user with an optional campaign name and is
+// polled right away and then on schedule. The link of every new item is
+// shortened and the mapping from the items to the short URLs is kept with
+// the feed, so that auto-posting workflows can pick the short URLs up.
+// The feeds state is kept in memory and is lost on restart.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// ErrTooMany is returned when the user has subscribed to too many feeds.
+var ErrTooMany = errors.New("too many feeds")
+
+const (
+	// maxItems is the number of the latest items kept with the feed.
+	// Feeds rarely carry more items than that, so older items
+	// are not shortened again.
+	maxItems = 1000
+	// pollsLen is the number of the new feeds waiting to be polled.
+	// New feeds are polled on schedule if it is full.
+	pollsLen = 100
+	// fetchTimeout is the timeout of the single feed request.
+	fetchTimeout = 30 * time.Second
+)
+
+type (
+	// Feed is the subscription of the user to RSS or Atom feed.
+	Feed struct {
+		ID     string  `json:"id"`
+		UserID user.ID `json:"-"`
+		URL    string  `json:"url"`
+		// Campaign is the name the short URLs are grouped under.
+		Campaign string `json:"campaign,omitempty"`
+		// Items are the shortened items, latest last.
+		Items []Item `json:"items"`
+		// Error is the reason the last poll failed.
+		Error        string     `json:"error,omitempty"`
+		LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
+		CreatedAt    time.Time  `json:"created_at"`
+	}
+
+	// Item is the shortened item of the feed.
+	Item struct {
+		ID          string     `json:"id"`
+		Title       string     `json:"title,omitempty"`
+		Link        string     `json:"link"`
+		PublishedAt *time.Time `json:"published_at,omitempty"`
+		ShortURL    string     `json:"short_url"`
+		ShortenedAt time.Time  `json:"shortened_at"`
+	}
+)
+
+// Shortener shortens the link of the feed item and returns the short URL.
+type Shortener func(ctx context.Context, feed Feed, item Item) (string, error)
+
+// Manager keeps track of the feeds and polls them.
+// It is safe for concurrent use.
+type Manager struct {
+	client     *http.Client
+	maxSize    int64
+	maxPerUser int
+	// mu protects feeds.
+	mu    sync.Mutex
+	feeds map[string]*Feed
+	// polls is the IDs of the new feeds.
+	polls chan string
+}
+
+// NewManager returns the feeds manager. Feeds larger than maxSize bytes
+// fail to poll. Unless allowPrivate is set, the feeds are not fetched
+// from the loopback and private networks, so that users can't probe them.
+// The hosts of the feeds are resolved with the resolver if it is not nil.
+func NewManager(maxPerUser int, maxSize int64, allowPrivate bool, resolver *dnscache.Resolver) *Manager {
+	dialer := &net.Dialer{Timeout: fetchTimeout}
+	if !allowPrivate {
+		dialer.Control = denyPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	if resolver != nil {
+		transport.DialContext = resolver.DialContext(dialer)
+	}
+
+	return &Manager{
+		client:     &http.Client{Transport: transport, Timeout: fetchTimeout},
+		maxSize:    maxSize,
+		maxPerUser: maxPerUser,
+		feeds:      make(map[string]*Feed),
+		polls:      make(chan string, pollsLen),
+	}
+}
+
+// Create subscribes the user to the feed at url and schedules its first poll.
+func (m *Manager) Create(userID user.ID, url, campaign string) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, f := range m.feeds {
+		if f.UserID == userID {
+			n++
+		}
+	}
+	if n >= m.maxPerUser {
+		return Feed{}, fmt.Errorf("%w: at most %d", ErrTooMany, m.maxPerUser)
+	}
+
+	feed := &Feed{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		URL:       url,
+		Campaign:  campaign,
+		Items:     []Item{},
+		CreatedAt: time.Now().UTC(),
+	}
+	m.feeds[feed.ID] = feed
+
+	select {
+	case m.polls <- feed.ID:
+	default:
+	}
+
+	return feed.copy(), nil
+}
+
+// Get returns the feed of the user.
+// If there is no such feed, ErrNotFound is returned.
+func (m *Manager) Get(id string, userID user.ID) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed, err := m.get(id, userID)
+	if err != nil {
+		return Feed{}, err
+	}
+	return feed.copy(), nil
+}
+
+// List returns all the feeds of the user in the order they were created.
+func (m *Manager) List(userID user.ID) []Feed {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feeds := make([]Feed, 0)
+	for _, f := range m.feeds {
+		if f.UserID == userID {
+			feeds = append(feeds, f.copy())
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].CreatedAt.Before(feeds[j].CreatedAt) })
+
+	return feeds
+}
+
+// Delete unsubscribes the user from the feed. The short URLs are kept.
+// If there is no such feed, ErrNotFound is returned.
+func (m *Manager) Delete(id string, userID user.ID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.get(id, userID); err != nil {
+		return err
+	}
+	delete(m.feeds, id)
+
+	return nil
+}
+
+// Run polls the new feeds right away and all the feeds every interval
+// with shorten until done is closed. The poll in progress is canceled on stop.
+func (m *Manager) Run(done <-chan struct{}, interval time.Duration, shorten Shortener) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case id := <-m.polls:
+			m.poll(ctx, id, shorten)
+		case <-ticker.C:
+			for _, id := range m.ids() {
+				m.poll(ctx, id, shorten)
+			}
+		}
+	}
+}
+
+// poll fetches the feed and shortens its new items oldest first.
+// The items shortened before the failure are kept, the rest
+// are retried on the next poll.
+func (m *Manager) poll(ctx context.Context, id string, shorten Shortener) {
+	m.mu.Lock()
+	feed, ok := m.feeds[id]
+	var snapshot Feed
+	if ok {
+		snapshot = feed.copy()
+	}
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	seen := make(map[string]struct{}, len(snapshot.Items))
+	for _, it := range snapshot.Items {
+		seen[it.ID] = struct{}{}
+	}
+
+	var shortened []Item
+	items, err := m.fetch(ctx, snapshot.URL)
+	// feeds list the latest items first
+	for i := len(items) - 1; i >= 0 && err == nil; i-- {
+		it := items[i]
+		if _, ok := seen[it.ID]; ok {
+			continue
+		}
+		seen[it.ID] = struct{}{}
+
+		it.ShortURL, err = shorten(ctx, snapshot, it)
+		if err != nil {
+			err = fmt.Errorf("shorten %s: %w", it.Link, err)
+			break
+		}
+		it.ShortenedAt = time.Now().UTC()
+		shortened = append(shortened, it)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the feed could be deleted while polled
+	feed, ok = m.feeds[id]
+	if !ok {
+		return
+	}
+	feed.Items = append(feed.Items, shortened...)
+	if len(feed.Items) > maxItems {
+		feed.Items = append([]Item(nil), feed.Items[len(feed.Items)-maxItems:]...)
+	}
+	now := time.Now().UTC()
+	feed.LastPolledAt = &now
+	feed.Error = ""
+	if err != nil {
+		feed.Error = err.Error()
+	}
+}
+
+// fetch requests and parses the feed.
+func (m *Manager) fetch(ctx context.Context, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch feed: %s", res.Status)
+	}
+
+	// read one byte more than allowed to detect the excess
+	body := io.LimitReader(res.Body, m.maxSize+1)
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read feed: %w", err)
+	}
+	if int64(len(b)) > m.maxSize {
+		return nil, fmt.Errorf("feed is larger than %d bytes", m.maxSize)
+	}
+
+	return Parse(bytes.NewReader(b))
+}
+
+// ids returns the IDs of all the feeds.
+func (m *Manager) ids() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.feeds))
+	for id := range m.feeds {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// get returns the feed of the user. It must be called with mu held.
+func (m *Manager) get(id string, userID user.ID) (*Feed, error) {
+	feed, ok := m.feeds[id]
+	// don't disclose feeds of the other users
+	if !ok || feed.UserID != userID {
+		return nil, fmt.Errorf("feed %s: %w", id, errs.ErrNotFound)
+	}
+	return feed, nil
+}
+
+// copy returns the copy of the feed not sharing the items.
+func (f *Feed) copy() Feed {
+	c := *f
+	c.Items = append(make([]Item, 0, len(f.Items)), f.Items...)
+	return c
+}
+
+// denyPrivate refuses the connections to the loopback, private
+// and link-local addresses.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("%w: address %s is not allowed", errs.ErrInvalidRequest, host)
+	}
+	return nil
+}
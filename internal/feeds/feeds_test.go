@@ -0,0 +1,170 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <item>
+      <title>Second</title>
+      <link>https://example.com/2</link>
+      <guid>post-2</guid>
+      <pubDate>Tue, 04 Jun 2024 10:00:00 +0000</pubDate>
+    </item>
+    <item>
+      <title>First</title>
+      <link>https://example.com/1</link>
+    </item>
+    <item>
+      <title>No link</title>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Blog</title>
+  <entry>
+    <id>tag:example.com,2024:1</id>
+    <title>First</title>
+    <link rel="self" href="https://example.com/1.atom"/>
+    <link href="https://example.com/1"/>
+    <updated>2024-06-01T12:00:00Z</updated>
+  </entry>
+</feed>`
+
+func TestParse(t *testing.T) {
+	published := time.Date(2024, 6, 4, 10, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		doc     string
+		want    []Item
+		wantErr error
+	}{
+		{
+			name: "rss",
+			doc:  rssFeed,
+			want: []Item{
+				{ID: "post-2", Title: "Second", Link: "https://example.com/2", PublishedAt: &published},
+				{ID: "https://example.com/1", Title: "First", Link: "https://example.com/1"},
+			},
+		},
+		{
+			name: "atom",
+			doc:  atomFeed,
+			want: []Item{
+				{ID: "tag:example.com,2024:1", Title: "First", Link: "https://example.com/1", PublishedAt: &updated},
+			},
+		},
+		{
+			name:    "html",
+			doc:     `<html><body>not a feed</body></html>`,
+			wantErr: ErrUnsupported,
+		},
+		{
+			name:    "not xml",
+			doc:     `{"items": []}`,
+			wantErr: ErrUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.doc))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		doc = rssFeed
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = fmt.Fprint(w, doc)
+	}))
+	defer srv.Close()
+
+	m := NewManager(1, 1<<20, true, nil)
+	var shortened []string
+	shorten := func(_ context.Context, feed Feed, item Item) (string, error) {
+		assert.Equal(t, "spring", feed.Campaign)
+		if item.Link == "https://example.com/fail" {
+			return "", errors.New("storage is down")
+		}
+		shortened = append(shortened, item.Link)
+		return "short-" + item.ID, nil
+	}
+
+	feed, err := m.Create("owner", srv.URL, "spring")
+	require.NoError(t, err)
+	_, err = m.Create("owner", srv.URL, "")
+	assert.ErrorIs(t, err, ErrTooMany)
+
+	// the new feed is polled right away, oldest items first
+	m.poll(context.TODO(), <-m.polls, shorten)
+	got, err := m.Get(feed.ID, "owner")
+	require.NoError(t, err)
+	assert.Empty(t, got.Error)
+	assert.NotNil(t, got.LastPolledAt)
+	assert.Equal(t, []string{"https://example.com/1", "https://example.com/2"}, shortened)
+	require.Len(t, got.Items, 2)
+	assert.Equal(t, "short-post-2", got.Items[1].ShortURL)
+
+	// only the new items are shortened, the failed ones are retried
+	mu.Lock()
+	doc = strings.Replace(rssFeed, "<channel>", `<channel>
+		<item><link>https://example.com/fail</link></item>
+		<item><link>https://example.com/3</link></item>`, 1)
+	mu.Unlock()
+	shortened = nil
+	m.poll(context.TODO(), feed.ID, shorten)
+	got, err = m.Get(feed.ID, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/3"}, shortened)
+	assert.Len(t, got.Items, 3)
+	assert.Contains(t, got.Error, "storage is down")
+
+	_, err = m.Get(feed.ID, "intruder")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.ErrorIs(t, m.Delete(feed.ID, "intruder"), errs.ErrNotFound)
+	require.NoError(t, m.Delete(feed.ID, "owner"))
+	assert.Empty(t, m.List("owner"))
+}
+
+func TestManager_DenyPrivate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, rssFeed)
+	}))
+	defer srv.Close()
+
+	m := NewManager(1, 1<<20, false, dnscache.New(nil, time.Minute, 0, 16))
+	_, err := m.fetch(context.TODO(), srv.URL)
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+}
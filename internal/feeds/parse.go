@@ -0,0 +1,127 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned when the document is neither RSS nor Atom feed.
+var ErrUnsupported = errors.New("unsupported feed format")
+
+type (
+	// document is the union of RSS 2.0, RSS 1.0 and Atom documents.
+	document struct {
+		XMLName xml.Name
+		// Items of RSS 2.0.
+		Channel struct {
+			Items []rssItem `xml:"item"`
+		} `xml:"channel"`
+		// Items of RSS 1.0.
+		Items []rssItem `xml:"item"`
+		// Entries of Atom.
+		Entries []atomEntry `xml:"entry"`
+	}
+
+	rssItem struct {
+		GUID    string `xml:"guid"`
+		Title   string `xml:"title"`
+		Link    string `xml:"link"`
+		PubDate string `xml:"pubDate"`
+		// Date is the Dublin Core date of RSS 1.0.
+		Date string `xml:"date"`
+	}
+
+	atomEntry struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	}
+)
+
+// timeLayouts are the layouts of the dates found in the feeds.
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+}
+
+// Parse reads the items of RSS or Atom feed in the document order.
+// Items without the link are skipped. The ID of the item is its GUID,
+// falling back to the link.
+func Parse(r io.Reader) ([]Item, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+
+	var items []Item
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			items = appendItem(items, it.GUID, it.Title, it.Link, parseTime(it.PubDate, it.Date))
+		}
+	case "feed":
+		for _, e := range doc.Entries {
+			items = appendItem(items, e.ID, e.Title, e.link(), parseTime(e.Published, e.Updated))
+		}
+	default:
+		return nil, fmt.Errorf("%w: root element %q", ErrUnsupported, doc.XMLName.Local)
+	}
+
+	return items, nil
+}
+
+// appendItem appends the item if it has the link.
+func appendItem(items []Item, id, title, link string, publishedAt *time.Time) []Item {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return items
+	}
+	if id = strings.TrimSpace(id); id == "" {
+		id = link
+	}
+	return append(items, Item{
+		ID:          id,
+		Title:       strings.TrimSpace(title),
+		Link:        link,
+		PublishedAt: publishedAt,
+	})
+}
+
+// link returns the alternate link of the entry.
+func (e atomEntry) link() string {
+	for _, l := range e.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// parseTime returns the first of the values parsed, nil if none is.
+func parseTime(values ...string) *time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				t = t.UTC()
+				return &t
+			}
+		}
+	}
+	return nil
+}
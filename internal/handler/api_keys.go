@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAPIKeyNameLength is the maximum length of the API key name.
+const maxAPIKeyNameLength = 255
+
+type (
+	apiKeyRequestPayload struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	apiKeyResponsePayload struct {
+		ID        string       `json:"id"`
+		Name      string       `json:"name"`
+		Key       string       `json:"key"`
+		Scopes    []user.Scope `json:"scopes"`
+		CreatedAt time.Time    `json:"created_at"`
+	}
+
+	apiKeyUsagePayload struct {
+		ID         string       `json:"id"`
+		Name       string       `json:"name"`
+		Scopes     []user.Scope `json:"scopes"`
+		CreatedAt  time.Time    `json:"created_at"`
+		LastUsedAt *time.Time   `json:"last_used_at"`
+		Requests   int64        `json:"requests"`
+	}
+)
+
+// PostAPIKey mints a new API key of the user. The key authenticates
+// the requests passed in the "Authorization: ApiKey <key>" header instead
+// of the cookie. Only its hash is stored, so the key is returned once.
+// The name helps the user to tell the keys apart and is optional.
+//
+// The key is limited to the scopes: read, write, delete, stats and admin.
+// The keys without the scopes have the scopes of the user minting them,
+// i.e. no limits if the user is authenticated with the cookie. The users
+// limited to the scopes can't mint the keys with other ones.
+//
+// Request:
+//
+//	POST /api/user/keys
+//	Content-Type: application/json
+//	{ "name": "ci", "scopes": ["write", "read"] }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"id": "8f0c0c1e-6bf5-4a1c-9f1d-3c2d7d1c3f0e",
+//		"name": "ci",
+//		"key": "sk_...",
+//		"scopes": ["write", "read"],
+//		"created_at": "2024-06-01T12:00:00Z"
+//	}
+func (h *Handler) PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeys == nil {
+		h.jsonError(w, r, "API keys are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	// decode the request body, the empty one is allowed
+	var payload apiKeyRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	if len(payload.Name) > maxAPIKeyNameLength {
+		h.jsonError(w, r, fmt.Sprintf("name is longer than %d characters", maxAPIKeyNameLength),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	scopes, err := apiKeyScopes(payload.Scopes)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if scopes == nil {
+		scopes = user.Scopes
+	}
+	if !user.AllowsAll(scopes) {
+		h.jsonError(w, r, "scopes exceed the scopes of the user", errs.ErrUnauthorized, http.StatusForbidden)
+		return
+	}
+
+	key, apiKey, err := models.NewAPIKey(user.ID, payload.Name, scopes)
+	if err != nil {
+		h.jsonError(w, r, "failed to mint API key", err, http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.apiKeys.SaveAPIKey(r.Context(), apiKey); err != nil {
+		h.jsonError(w, r, "failed to save to database", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAPIKey(w, r, http.StatusCreated, key, apiKey)
+}
+
+// GetAPIKeys returns the API keys of the user with their usage
+// for the audit. The keys themselves are never returned.
+//
+// Request:
+//
+//	GET /api/user/keys
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[
+//		{
+//			"id": "8f0c0c1e-6bf5-4a1c-9f1d-3c2d7d1c3f0e",
+//			"name": "ci",
+//			"scopes": ["write", "read"],
+//			"created_at": "2024-06-01T12:00:00Z",
+//			"last_used_at": "2024-06-02T08:30:00Z",
+//			"requests": 42
+//		}
+//	]
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeys == nil {
+		h.jsonError(w, r, "API keys are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(r.Context(), user.ID)
+	if err != nil {
+		h.jsonError(w, r, "failed to list API keys", err, http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]apiKeyUsagePayload, len(keys))
+	for i, key := range keys {
+		res[i] = apiKeyUsagePayload{
+			ID:         key.ID,
+			Name:       key.Name,
+			Scopes:     key.Scopes,
+			CreatedAt:  key.CreatedAt,
+			LastUsedAt: key.LastUsedAt,
+			Requests:   key.Requests,
+		}
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// encode the response body
+	if err = h.encodeData(w, r, res); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// PostAPIKeyRotate replaces the API key of the user with the new one
+// keeping its name, scopes and usage. The old key is rejected at once,
+// the new one is returned once. The users limited to the scopes can
+// rotate the keys within their scopes only.
+//
+// Request:
+//
+//	POST /api/user/keys/8f0c0c1e-6bf5-4a1c-9f1d-3c2d7d1c3f0e/rotate
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"id": "8f0c0c1e-6bf5-4a1c-9f1d-3c2d7d1c3f0e",
+//		"name": "ci",
+//		"key": "sk_...",
+//		"scopes": ["write", "read"],
+//		"created_at": "2024-06-01T12:00:00Z"
+//	}
+func (h *Handler) PostAPIKeyRotate(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeys == nil {
+		h.jsonError(w, r, "API keys are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(r.Context(), user.ID)
+	if err != nil {
+		h.jsonError(w, r, "failed to list API keys", err, http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	i := slices.IndexFunc(keys, func(k *models.APIKey) bool { return k.ID == id })
+	if i < 0 {
+		h.jsonError(w, r, "API key not found", errs.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	apiKey := keys[i]
+	if !user.AllowsAll(apiKey.Scopes) {
+		h.jsonError(w, r, "scopes of the key exceed the scopes of the user", errs.ErrUnauthorized,
+			http.StatusForbidden)
+		return
+	}
+
+	key, err := apiKey.Rotate()
+	if err != nil {
+		h.jsonError(w, r, "failed to rotate API key", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = h.apiKeys.RotateAPIKey(r.Context(), user.ID, apiKey.ID, apiKey.Hash)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.jsonError(w, r, "API key not found", err, http.StatusNotFound)
+			return
+		}
+		h.jsonError(w, r, "failed to save to database", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAPIKey(w, r, http.StatusOK, key, apiKey)
+}
+
+// apiKeyScopes parses the requested scopes of the API key,
+// nil if none is requested.
+func apiKeyScopes(requested []string) ([]user.Scope, error) {
+	if len(requested) == 0 {
+		return nil, nil
+	}
+	scopes := make([]user.Scope, 0, len(requested))
+	for _, s := range requested {
+		scope, err := user.ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// writeAPIKey writes the minted or rotated API key.
+func (h *Handler) writeAPIKey(w http.ResponseWriter, r *http.Request, code int, key string, apiKey *models.APIKey) {
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	// encode the response body
+	err := h.encodeData(w, r, apiKeyResponsePayload{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Key:       key,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	})
+	if err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
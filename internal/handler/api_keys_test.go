@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	store := memstore.NewURLRepository()
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(store, c, l, WithAPIKeys(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	serve := func(method, path, body string, edit func(*http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		edit(r)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// the key is minted by the user authenticated with the cookie
+	userID := user.NewID()
+	token, err := jwt.BuildJWTString(userID, user.TokenAnonymous, c.JWT.SigningKey, c.JWT.Expiration)
+	require.NoError(t, err)
+	w := serve(http.MethodPost, "/api/user/keys", `{"name":"ci"}`, func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var minted apiKeyResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&minted))
+	assert.Equal(t, "ci", minted.Name)
+	assert.True(t, strings.HasPrefix(minted.Key, "sk_"))
+
+	withKey := func(key string) func(*http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("Authorization", "ApiKey "+key)
+		}
+	}
+
+	// the URLs shortened with the key belong to the user
+	w = serve(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`, withKey(minted.Key))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	urls, err := store.GetAllByUserID(context.TODO(), userID)
+	require.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	w = serve(http.MethodGet, "/api/user/urls", "", withKey(minted.Key))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// unknown keys are rejected even on the public endpoints
+	w = serve(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`, withKey("sk_unknown"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// keys are not minted without the user
+	w = serve(http.MethodPost, "/api/user/keys", "", func(*http.Request) {})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestPostAPIKey_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/keys", http.NoBody)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: user.NewID()}))
+	w := httptest.NewRecorder()
+
+	handler.PostAPIKey(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestAPIKey_ScopesAndRotation(t *testing.T) {
+	store := memstore.NewURLRepository()
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(store, c, l, WithAPIKeys(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	serve := func(method, path, body string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		auth(r)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	token, err := jwt.BuildJWTString(user.NewID(), user.TokenAnonymous, c.JWT.SigningKey, c.JWT.Expiration)
+	require.NoError(t, err)
+	withCookie := func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+	}
+	withKey := func(key string) func(*http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("Authorization", "ApiKey "+key)
+		}
+	}
+	mint := func(body string, auth func(*http.Request)) (*httptest.ResponseRecorder, apiKeyResponsePayload) {
+		w := serve(http.MethodPost, "/api/user/keys", body, auth)
+		var minted apiKeyResponsePayload
+		if w.Code == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&minted))
+		}
+		return w, minted
+	}
+
+	w, _ := mint(`{"scopes":["owner"]}`, withCookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the read only key can't shorten or manage the keys
+	w, reader := mint(`{"name":"dashboard","scopes":["read","read"]}`, withCookie)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, []user.Scope{user.ScopeRead}, reader.Scopes)
+
+	w = serve(http.MethodGet, "/api/user/urls", "", withKey(reader.Key))
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+	w = serve(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`, withKey(reader.Key))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = mint(`{}`, withKey(reader.Key))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the admin key manages the keys within its scopes only
+	w, admin := mint(`{"name":"ops","scopes":["admin","write"]}`, withCookie)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = serve(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`, withKey(admin.Key))
+	assert.Equal(t, http.StatusCreated, w.Code, "write includes shorten")
+
+	w, _ = mint(`{"scopes":["write","read"]}`, withKey(admin.Key))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, inherited := mint(`{"name":"ci"}`, withKey(admin.Key))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, admin.Scopes, inherited.Scopes)
+
+	w = serve(http.MethodPost, "/api/user/keys/"+reader.ID+"/rotate", "", withKey(admin.Key))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the usage is audited
+	w = serve(http.MethodGet, "/api/user/keys", "", withCookie)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var keys []apiKeyUsagePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&keys))
+	require.Len(t, keys, 3)
+	assert.Equal(t, reader.ID, keys[0].ID)
+	assert.Equal(t, int64(3), keys[0].Requests)
+	assert.NotNil(t, keys[0].LastUsedAt)
+	assert.Nil(t, keys[2].LastUsedAt)
+	assert.NotContains(t, w.Body.String(), reader.Key)
+
+	// the rotated key replaces the old one at once
+	w = serve(http.MethodPost, "/api/user/keys/"+reader.ID+"/rotate", "", withCookie)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rotated apiKeyResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&rotated))
+	assert.Equal(t, reader.ID, rotated.ID)
+	assert.Equal(t, reader.Scopes, rotated.Scopes)
+	assert.NotEqual(t, reader.Key, rotated.Key)
+
+	w = serve(http.MethodGet, "/api/user/urls", "", withKey(reader.Key))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve(http.MethodGet, "/api/user/urls", "", withKey(rotated.Key))
+	assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(http.MethodPost, "/api/user/keys/unknown/rotate", "", withCookie)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
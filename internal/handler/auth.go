@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+type (
+	credentialsRequestPayload struct {
+		Login    string `json:"login"`
+		Password string `json:"password"`
+	}
+
+	accountTokenResponsePayload struct {
+		UserID           user.ID        `json:"user_id"`
+		Token            string         `json:"token"`
+		TokenType        user.TokenType `json:"token_type"`
+		ExpiresAt        time.Time      `json:"expires_at"`
+		RefreshToken     string         `json:"refresh_token,omitempty"`
+		RefreshExpiresAt *time.Time     `json:"refresh_expires_at,omitempty"`
+	}
+
+	refreshRequestPayload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+)
+
+// refreshCookie is the cookie of the refresh token. It is sent
+// to the endpoints of the authentication only.
+const refreshCookie = "Refresh-Token"
+
+// PostRegister creates the account of the user with the login and
+// the password. The account keeps the ID of the user the request is
+// authenticated with, so the URLs shortened anonymously stay with the
+// user after the registration. The registered token is passed in the
+// "Authorization" cookie and in the response body, and so is the refresh
+// token in the "Refresh-Token" cookie if the tokens can be revoked.
+//
+// Request:
+//
+//	POST /api/auth/register
+//	Content-Type: application/json
+//	{ "login": "gopher", "password": "correct horse" }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"user_id": "2a5c1d63-...",
+//		"token": "Bearer eyJhbGciOi...",
+//		"token_type": "registered",
+//		"expires_at": "2025-06-01T12:00:00Z"
+//	}
+//
+// The taken login or the user already registered respond with 409 Conflict.
+func (h *Handler) PostRegister(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		h.jsonError(w, r, "accounts are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	payload, ok := h.decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+	login := models.NormalizeLogin(payload.Login)
+	if err := models.ValidateLogin(login); err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidatePassword(payload.Password); err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// the anonymous user becomes the registered one
+	userID := user.NewID()
+	if u, ok := user.FromContext(r.Context()); ok {
+		userID = u.ID
+	}
+
+	account, err := models.NewAccount(userID, login, payload.Password)
+	if err != nil {
+		h.jsonError(w, r, "failed to create account", err, http.StatusInternalServerError)
+		return
+	}
+	if err = h.accounts.CreateAccount(r.Context(), account); err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "login is taken or user is registered", errs.ErrConflict, http.StatusConflict)
+			return
+		}
+		h.jsonError(w, r, "failed to save account", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.issueAccountToken(w, r, account.UserID, http.StatusCreated)
+}
+
+// PostLogin authenticates the user with the login and the password
+// of the account and issues the registered token of the user the same
+// way as PostRegister does.
+//
+// Request:
+//
+//	POST /api/auth/login
+//	Content-Type: application/json
+//	{ "login": "gopher", "password": "correct horse" }
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"user_id": "2a5c1d63-...",
+//		"token": "Bearer eyJhbGciOi...",
+//		"token_type": "registered",
+//		"expires_at": "2025-06-01T12:00:00Z"
+//	}
+//
+// The unknown login and the wrong password respond alike
+// with 401 Unauthorized.
+func (h *Handler) PostLogin(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		h.jsonError(w, r, "accounts are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	payload, ok := h.decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	account, err := h.accounts.GetAccount(r.Context(), models.NormalizeLogin(payload.Login))
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		h.jsonError(w, r, "failed to retrieve account", err, http.StatusInternalServerError)
+		return
+	}
+	// the unknown login is checked too, see CheckPassword
+	if err = account.CheckPassword(payload.Password); err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	h.issueAccountToken(w, r, account.UserID, http.StatusOK)
+}
+
+// PostRefresh issues the new registered token and the new refresh token
+// of the user for the refresh token passed in the "Refresh-Token" cookie
+// or in the request body. The refresh token is revoked, so every one
+// gets the new tokens once.
+//
+// Request:
+//
+//	POST /api/auth/refresh
+//	Content-Type: application/json
+//	{ "refresh_token": "Bearer eyJhbGciOi..." }
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"user_id": "2a5c1d63-...",
+//		"token": "Bearer eyJhbGciOi...",
+//		"token_type": "registered",
+//		"expires_at": "2025-06-01T12:00:00Z",
+//		"refresh_token": "Bearer eyJhbGciOi...",
+//		"refresh_expires_at": "2025-08-30T12:00:00Z"
+//	}
+//
+// The invalid, expired and revoked refresh tokens respond
+// with 401 Unauthorized.
+func (h *Handler) PostRefresh(w http.ResponseWriter, r *http.Request) {
+	if h.revocations == nil {
+		h.jsonError(w, r, "refresh tokens are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	token, ok := h.refreshToken(w, r)
+	if !ok {
+		return
+	}
+	if token == "" {
+		h.jsonError(w, r, "no refresh token", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	u, err := jwt.GetUser(token, h.config.JWT.SigningKey)
+	if err != nil || u.Token != user.TokenRefresh || u.TokenID == "" {
+		h.jsonError(w, r, "invalid refresh token", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	// revoking the token first lets the concurrent requests refresh once
+	if err = h.revocations.RevokeToken(r.Context(), u.TokenID, u.ExpiresAt); err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "refresh token is revoked", errs.ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+		h.jsonError(w, r, "failed to revoke refresh token", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.issueAccountToken(w, r, u.ID, http.StatusOK)
+}
+
+// PostLogout revokes the token the request is authenticated with and
+// the refresh token passed in the "Refresh-Token" cookie or in the request
+// body, and removes their cookies.
+//
+// Request:
+//
+//	POST /api/auth/logout
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) PostLogout(w http.ResponseWriter, r *http.Request) {
+	if h.revocations == nil {
+		h.jsonError(w, r, "token revocation is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	u, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	token, ok := h.refreshToken(w, r)
+	if !ok {
+		return
+	}
+
+	revoke := []*user.User{u}
+	// the refresh tokens of other users are left as is
+	if refresh, err := jwt.GetUser(token, h.config.JWT.SigningKey); err == nil &&
+		refresh.Token == user.TokenRefresh && refresh.ID == u.ID {
+		revoke = append(revoke, refresh)
+	}
+	for _, t := range revoke {
+		if t.TokenID == "" {
+			continue
+		}
+		err := h.revocations.RevokeToken(r.Context(), t.TokenID, t.ExpiresAt)
+		if err != nil && !errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "failed to revoke token", err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: "Authorization", MaxAge: -1, HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: refreshCookie, Path: "/api/auth", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// refreshToken returns the refresh token of the "Refresh-Token" cookie,
+// falling back to the one of the request body, empty if there is neither.
+// It responds with the error and returns false if the body can't be decoded.
+func (h *Handler) refreshToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(refreshCookie); err == nil {
+		return cookie.Value, true
+	}
+
+	var payload refreshRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return "", false
+	}
+	return payload.RefreshToken, true
+}
+
+// decodeCredentials decodes the login and the password from the request
+// body. It responds with the error and returns false if it can't.
+func (h *Handler) decodeCredentials(w http.ResponseWriter, r *http.Request) (credentialsRequestPayload, bool) {
+	var payload credentialsRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, "only application/json content-type allowed",
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return payload, false
+	}
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return payload, false
+	}
+	return payload, true
+}
+
+// issueAccountToken sets the "Authorization" cookie with the registered
+// token of the user and responds with the token in the body. The refresh
+// token is issued too if the tokens can be revoked.
+func (h *Handler) issueAccountToken(w http.ResponseWriter, r *http.Request, userID user.ID, code int) {
+	cookie, err := h.authCookie(&user.User{ID: userID, Token: user.TokenRegistered})
+	if err != nil {
+		h.jsonError(w, r, "failed to build JWT token", err, http.StatusInternalServerError)
+		return
+	}
+	payload := accountTokenResponsePayload{
+		UserID:    userID,
+		Token:     cookie.Value,
+		TokenType: user.TokenRegistered,
+		ExpiresAt: cookie.Expires.UTC().Truncate(time.Second),
+	}
+
+	var refresh *http.Cookie
+	if h.revocations != nil {
+		exp := h.config.JWT.ExpirationOf(user.TokenRefresh)
+		token, err := jwt.BuildJWTString(userID, user.TokenRefresh, h.config.JWT.SigningKey, exp)
+		if err != nil {
+			h.jsonError(w, r, "failed to build refresh token", err, http.StatusInternalServerError)
+			return
+		}
+		refresh = &http.Cookie{
+			Name:     refreshCookie,
+			Value:    token,
+			Path:     "/api/auth",
+			Expires:  time.Now().Add(exp),
+			HttpOnly: true,
+		}
+		expiresAt := refresh.Expires.UTC().Truncate(time.Second)
+		payload.RefreshToken, payload.RefreshExpiresAt = token, &expiresAt
+	}
+
+	// set the response headers and status code
+	http.SetCookie(w, cookie)
+	if refresh != nil {
+		http.SetCookie(w, refresh)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	// encode the response body
+	err = h.encodeData(w, r, payload)
+	if err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
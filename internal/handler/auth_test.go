@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterLogin(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	store := memstore.NewURLRepository()
+	handler, err := New(store, c, l, WithAccounts(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// serve returns the recorded response to the request with the cookie
+	serve := func(method, target, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	// registered returns the cookie of the registered token of the response
+	registered := func(w *httptest.ResponseRecorder) *http.Cookie {
+		var got accountTokenResponsePayload
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal(t, user.TokenRegistered, got.TokenType)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, got.Token, cookies[0].Value)
+		u, err := jwt.GetUser(cookies[0].Value, c.JWT.SigningKey)
+		require.NoError(t, err)
+		assert.Equal(t, got.UserID, u.ID)
+		assert.Equal(t, user.TokenRegistered, u.Token)
+		return cookies[0]
+	}
+
+	// the anonymous user shortens a URL before the registration
+	w := serve(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	anonymous := w.Result().Cookies()[0]
+
+	w = serve(http.MethodPost, "/api/auth/register", `{"login":"Gopher","password":"short"}`, anonymous)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serve(http.MethodPost, "/api/auth/register", `{"login":"Gopher","password":"correct horse"}`, anonymous)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	cookie := registered(w)
+
+	// the URLs stay with the registered user
+	w = serve(http.MethodGet, "/api/user/urls", "", cookie)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "https://go.dev/")
+
+	w = serve(http.MethodPost, "/api/auth/register", `{"login":"another","password":"correct horse"}`, cookie)
+	assert.Equal(t, http.StatusConflict, w.Code, "the user is registered")
+	w = serve(http.MethodPost, "/api/auth/register", `{"login":"gopher","password":"correct horse"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code, "the login is taken")
+
+	w = serve(http.MethodPost, "/api/auth/login", `{"login":"gopher","password":"wrong horse"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve(http.MethodPost, "/api/auth/login", `{"login":"unknown","password":"correct horse"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(http.MethodPost, "/api/auth/login", `{"login":" GOPHER ","password":"correct horse"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	login := registered(w)
+	w = serve(http.MethodGet, "/api/user/urls", "", login)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "https://go.dev/")
+}
+
+func TestRegisterLogin_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	for _, fn := range []http.HandlerFunc{handler.PostRegister, handler.PostLogin} {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+			strings.NewReader(`{"login":"gopher","password":"correct horse"}`))
+		r.Header.Set(contentType, applicationJSON)
+		w := httptest.NewRecorder()
+		fn(w, r)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	}
+}
+
+func TestRefreshLogout(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	store := memstore.NewURLRepository()
+	handler, err := New(store, c, l, WithAccounts(store), WithRevocations(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// serve returns the recorded response to the request with the cookies
+	serve := func(target, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	// tokens returns the issued tokens and the cookies of the response
+	tokens := func(w *httptest.ResponseRecorder) (accountTokenResponsePayload, map[string]*http.Cookie) {
+		var got accountTokenResponsePayload
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.NotEmpty(t, got.RefreshToken)
+		require.NotNil(t, got.RefreshExpiresAt)
+
+		cookies := make(map[string]*http.Cookie)
+		for _, cookie := range w.Result().Cookies() {
+			cookies[cookie.Name] = cookie
+		}
+		require.Contains(t, cookies, refreshCookie)
+		assert.Equal(t, got.RefreshToken, cookies[refreshCookie].Value)
+		return got, cookies
+	}
+
+	w := serve("/api/auth/register", `{"login":"gopher","password":"correct horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	registered, cookies := tokens(w)
+
+	// the refresh token can't authorize the requests
+	w = serve("/api/shorten", `{"url":"https://go.dev/"}`,
+		&http.Cookie{Name: "Authorization", Value: registered.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// the refresh token of the cookie is exchanged once
+	w = serve("/api/auth/refresh", "", cookies[refreshCookie])
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	refreshed, _ := tokens(w)
+	assert.Equal(t, registered.UserID, refreshed.UserID)
+	w = serve("/api/auth/refresh", "", cookies[refreshCookie])
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the refresh token is revoked")
+
+	// the access token is no refresh token
+	w = serve("/api/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, refreshed.Token))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// the logout revokes both tokens
+	access := &http.Cookie{Name: "Authorization", Value: refreshed.Token}
+	w = serve("/api/auth/logout", fmt.Sprintf(`{"refresh_token":%q}`, refreshed.RefreshToken), access)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	w = serve("/api/shorten", `{"url":"https://go.dev/"}`, access)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the access token is revoked")
+	w = serve("/api/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, refreshed.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the refresh token is revoked")
+
+	// the previous access token is still valid
+	w = serve("/api/shorten", `{"url":"https://go.dev/"}`,
+		&http.Cookie{Name: "Authorization", Value: registered.Token})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCampaignURLs is the maximum number of the short URLs
+// added to the campaign at once.
+const maxCampaignURLs = 10000
+
+type campaignURLsResponsePayload struct {
+	Added int `json:"added"`
+}
+
+type campaignTransferRequestPayload struct {
+	To string `json:"to"`
+}
+
+// PostCampaignURLs adds the short URLs owned by the user to the campaign
+// in a single transaction. The campaign is created with its first link.
+// Short URLs of other users, deleted or already in the campaign are skipped.
+//
+// Request:
+//
+//	POST /api/user/campaigns/{campaign}/urls
+//	Content-Type: application/json
+//	[ "Base58", "Base59", ... ]
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{ "added": 2 }
+func (h *Handler) PostCampaignURLs(w http.ResponseWriter, r *http.Request) {
+	if h.campaigns == nil {
+		h.campaignsDisabled(w, r)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	campaign := chi.URLParam(r, "campaign")
+	if err := models.ValidateCampaign(campaign); err != nil {
+		h.jsonError(w, r, "invalid campaign", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+			http.StatusBadRequest)
+		return
+	}
+
+	var payload []string
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	if len(payload) > maxCampaignURLs {
+		h.jsonError(w, r, fmt.Sprintf("more than %d URLs", maxCampaignURLs),
+			errs.ErrInvalidRequest, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	shortURLs := make([]models.ShortURL, len(payload))
+	for i, s := range payload {
+		shortURL, err := h.parseShortURL(s)
+		if err != nil {
+			h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+			return
+		}
+		shortURLs[i] = shortURL
+	}
+
+	added, err := h.campaigns.AddToCampaign(r.Context(), user.ID, campaign, shortURLs)
+	if err != nil {
+		h.jsonError(w, r, "failed to add to campaign", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, campaignURLsResponsePayload{Added: added}); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetCampaignURLs returns the short URLs of the user in the campaign
+// in the format of GetAllByUserID, paged the same way. The campaign without
+// links is empty.
+//
+// Request:
+//
+//	GET /api/user/campaigns/{campaign}/urls
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	[
+//		{
+//		    "short_url": "http://config.AddrToReturn/Base58",
+//		    "original_url": "http://..."
+//		},
+//		...
+//	]
+func (h *Handler) GetCampaignURLs(w http.ResponseWriter, r *http.Request) {
+	if h.campaigns == nil {
+		h.campaignsDisabled(w, r)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	campaign := chi.URLParam(r, "campaign")
+	if err := models.ValidateCampaign(campaign); err != nil {
+		h.jsonError(w, r, "invalid campaign", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+			http.StatusBadRequest)
+		return
+	}
+
+	URLs, err := h.campaigns.GetCampaignURLs(r.Context(), user.ID, campaign)
+	if err != nil {
+		h.jsonError(w, r, "failed to get URLs", err, http.StatusInternalServerError)
+		return
+	}
+	URLs, page, err := paginate(r, URLs)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
+		return
+	}
+
+	response := make([]getAllByUserIDResponsePayload, len(URLs))
+	now := time.Now()
+	for i, u := range URLs {
+		response[i].ShortURL = models.ShortURL(h.absoluteURL(u.Domain, u.ShortURL))
+		response[i].OriginalURL = u.OriginalURL
+		response[i].LastAccessedAt = u.LastAccessedAt
+		response[i].ExpiresAt = u.ExpiresAt
+		response[i].RedirectLimit = u.RedirectLimit
+		response[i].Note = u.Note
+		response[i].State = u.Lifecycle(now)
+		response[i].Version = u.Version
+		if u.Metadata != (models.Metadata{}) {
+			response[i].Metadata = &u.Metadata
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodePage(w, r, response, page); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// PostCampaignCopy adds the links of the campaign to the other campaign
+// of the user in a single transaction. The links are deduplicated by their
+// destinations: the ones already in the target campaign are skipped.
+//
+// Request:
+//
+//	POST /api/user/campaigns/{campaign}/copy
+//	Content-Type: application/json
+//	{ "to": "autumn" }
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{ "copied": 120, "skipped": 3, "removed": 0 }
+func (h *Handler) PostCampaignCopy(w http.ResponseWriter, r *http.Request) {
+	h.transferCampaign(w, r, false)
+}
+
+// PostCampaignMove moves the links of the campaign to the other campaign
+// of the user in a single transaction, like PostCampaignCopy, and then
+// empties the source campaign.
+//
+// Request:
+//
+//	POST /api/user/campaigns/{campaign}/move
+//	Content-Type: application/json
+//	{ "to": "autumn" }
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{ "copied": 120, "skipped": 3, "removed": 123 }
+func (h *Handler) PostCampaignMove(w http.ResponseWriter, r *http.Request) {
+	h.transferCampaign(w, r, true)
+}
+
+// transferCampaign copies or moves the links between the campaigns.
+func (h *Handler) transferCampaign(w http.ResponseWriter, r *http.Request, move bool) {
+	if h.campaigns == nil {
+		h.campaignsDisabled(w, r)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	var payload campaignTransferRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	from := chi.URLParam(r, "campaign")
+	for _, campaign := range []string{from, payload.To} {
+		if err := models.ValidateCampaign(campaign); err != nil {
+			h.jsonError(w, r, "invalid campaign", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+				http.StatusBadRequest)
+			return
+		}
+	}
+	if from == payload.To {
+		h.jsonError(w, r, "same campaign", errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.campaigns.TransferCampaign(r.Context(), user.ID, from, payload.To, move)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidRequest) {
+			h.jsonError(w, r, "invalid campaign", err, http.StatusBadRequest)
+			return
+		}
+		h.jsonError(w, r, "failed to transfer campaign", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, res); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// campaignsDisabled responds that the storage doesn't support the campaigns.
+func (h *Handler) campaignsDisabled(w http.ResponseWriter, r *http.Request) {
+	h.jsonError(w, r, "campaigns are disabled",
+		fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+		http.StatusNotImplemented)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaigns(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "test"},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Wxyz9876", UserID: "test"},
+		{OriginalURL: "https://example.com/", ShortURL: "Foreign", UserID: "other"},
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithCampaigns(store))
+	require.NoError(t, err, "new handler error")
+
+	do := func(fn http.HandlerFunc, campaign, body string) *http.Response {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/campaigns/{campaign}", strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("campaign", campaign)
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+		r = r.WithContext(user.NewContext(ctx, &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		fn(w, r)
+
+		res := w.Result()
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+	list := func(campaign string) []getAllByUserIDResponsePayload {
+		res := do(handler.GetCampaignURLs, campaign, "")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got []getAllByUserIDResponsePayload
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		return got
+	}
+	transfer := func(fn http.HandlerFunc, from, to string) models.CampaignTransfer {
+		res := do(fn, from, `{"to": "`+to+`"}`)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got models.CampaignTransfer
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		return got
+	}
+
+	res := do(handler.PostCampaignURLs, "spring", `["YBbxJEcQ9vq", "RTfd56hn", "Foreign", "Unknown"]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var added campaignURLsResponsePayload
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&added))
+	assert.Equal(t, 2, added.Added, "URLs of other users and unknown ones are skipped")
+
+	res = do(handler.PostCampaignURLs, "autumn", `["YBbxJEcQ9vq", "Wxyz9876"]`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	got := transfer(handler.PostCampaignCopy, "spring", "autumn")
+	assert.Equal(t, models.CampaignTransfer{Copied: 1, Skipped: 1}, got,
+		"destinations already in the campaign are skipped")
+	assert.Len(t, list("autumn"), 3)
+	assert.Len(t, list("spring"), 2, "copy keeps the source")
+
+	got = transfer(handler.PostCampaignMove, "spring", "summer")
+	assert.Equal(t, models.CampaignTransfer{Copied: 2, Removed: 2}, got)
+	assert.Len(t, list("summer"), 2)
+	assert.Empty(t, list("spring"), "move empties the source")
+
+	res = do(handler.PostCampaignCopy, "summer", `{"to": "summer"}`)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "same campaign")
+
+	res = do(handler.PostCampaignCopy, "summer", `{"to": "`+strings.Repeat("a", models.MaxCampaignLength+1)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "too long campaign")
+}
+
+func TestCampaigns_Disabled(t *testing.T) {
+	store := memstore.NewURLRepository()
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/campaigns/{campaign}/copy",
+		strings.NewReader(`{"to": "autumn"}`))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.PostCampaignCopy(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
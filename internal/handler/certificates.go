@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// certificateCheckInterval is the interval the certificates are checked at
+// to warn about their expiry.
+const certificateCheckInterval = time.Hour
+
+// Health of the certificates.
+const (
+	certificateOK             = "ok"
+	certificateExpiring       = "expiring"
+	certificateRenewalOverdue = "renewal_overdue"
+	certificateExpired        = "expired"
+)
+
+// certificateHealth is the health of the served TLS certificate.
+type certificateHealth struct {
+	// Source is the source of the certificate: file or acme.
+	Source string `json:"source"`
+	// Names are the DNS names the certificate is valid for.
+	Names []string `json:"names"`
+	// NotAfter is the expiry of the certificate.
+	NotAfter time.Time `json:"not_after"`
+	// ExpiresInSeconds is the time left until the expiry,
+	// negative once expired.
+	ExpiresInSeconds int64 `json:"expires_in_seconds"`
+	// RenewAt is the time the ACME certificate is renewed at.
+	RenewAt *time.Time `json:"renew_at,omitempty"`
+	// Status is ok, expiring, renewal_overdue or expired.
+	Status string `json:"status"`
+}
+
+// certificateHealths returns the health of the served certificates as of now.
+func (h *Handler) certificateHealths(now time.Time) ([]certificateHealth, error) {
+	statuses, err := h.certificates.Certificates()
+	if err != nil {
+		return nil, err
+	}
+
+	healths := make([]certificateHealth, len(statuses))
+	for i, s := range statuses {
+		expiresIn := s.ExpiresIn(now)
+		c := certificateHealth{
+			Source:           s.Source,
+			Names:            s.Names,
+			NotAfter:         s.NotAfter.UTC(),
+			ExpiresInSeconds: int64(expiresIn / time.Second),
+			Status:           certificateOK,
+		}
+		if !s.RenewAt.IsZero() {
+			renewAt := s.RenewAt.UTC()
+			c.RenewAt = &renewAt
+		}
+		switch {
+		case expiresIn <= 0:
+			c.Status = certificateExpired
+		case s.RenewalOverdue(now):
+			c.Status = certificateRenewalOverdue
+		case expiresIn <= h.config.TLS.ExpiryWarning:
+			c.Status = certificateExpiring
+		}
+		healths[i] = c
+	}
+
+	return healths, nil
+}
+
+// GetCertificates returns the health of the served TLS certificates:
+// their expiry and, for the ones issued by Let's Encrypt, whether
+// the renewal is overdue. The certificates expiring within the configured
+// warning are reported as expiring.
+//
+// Request:
+//
+//	GET /api/internal/tls/certificates
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	[
+//		{
+//			"source": "acme",
+//			"names": ["example.com"],
+//			"not_after": "2024-09-01T12:00:00Z",
+//			"expires_in_seconds": 864000,
+//			"renew_at": "2024-08-02T12:00:00Z",
+//			"status": "renewal_overdue"
+//		}
+//	]
+func (h *Handler) GetCertificates(w http.ResponseWriter, r *http.Request) {
+	if h.certificates == nil {
+		h.jsonError(w, r, "TLS is disabled",
+			fmt.Errorf("%w: the server doesn't serve TLS", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	healths, err := h.certificateHealths(time.Now())
+	if err != nil {
+		h.jsonError(w, r, "failed to check certificates", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, healths); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// certificateDetails returns the lines of the readiness details
+// describing the served certificates.
+func (h *Handler) certificateDetails() string {
+	healths, err := h.certificateHealths(time.Now())
+	if err != nil {
+		return fmt.Sprintf("\ntls: %s", err)
+	}
+
+	var b strings.Builder
+	for _, c := range healths {
+		fmt.Fprintf(&b, "\ntls %s: %s, expires %s",
+			strings.Join(c.Names, ","), c.Status, c.NotAfter.Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// watchCertificates logs the certificates expiring within the configured
+// warning and the overdue renewals until the handler is stopped.
+func (h *Handler) watchCertificates() {
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.checkCertificates()
+
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkCertificates logs the certificates requiring the attention.
+func (h *Handler) checkCertificates() {
+	healths, err := h.certificateHealths(time.Now())
+	if err != nil {
+		h.logger.Errorf("failed to check TLS certificates: %s", err)
+		return
+	}
+
+	for _, c := range healths {
+		names := strings.Join(c.Names, ",")
+		switch c.Status {
+		case certificateExpired:
+			h.logger.Errorf("TLS certificate of %s expired at %s", names, c.NotAfter)
+		case certificateRenewalOverdue:
+			h.logger.Errorf("TLS certificate of %s was not renewed since %s, expires at %s",
+				names, c.RenewAt, c.NotAfter)
+		case certificateExpiring:
+			h.logger.Errorf("TLS certificate of %s expires at %s", names, c.NotAfter)
+		}
+	}
+}
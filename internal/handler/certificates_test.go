@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/certs"
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticCertificates is the source of the fixed certificate statuses.
+type staticCertificates []certs.Status
+
+func (s staticCertificates) Certificates() ([]certs.Status, error) { return s, nil }
+
+func newCertificatesHandler(t *testing.T, source certs.Source) *Handler {
+	t.Helper()
+	c := config.NewForTest()
+	c.TLS.ExpiryWarning = 14 * 24 * time.Hour
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l, WithCertificates(source))
+	require.NoError(t, err, "failed to init new handler")
+	t.Cleanup(handler.Stop)
+	return handler
+}
+
+func TestGetCertificates(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	handler := newCertificatesHandler(t, staticCertificates{
+		{Source: certs.SourceFile, Names: []string{"ok.example"}, NotAfter: now.Add(60 * day)},
+		{Source: certs.SourceFile, Names: []string{"expiring.example"}, NotAfter: now.Add(7 * day)},
+		{Source: certs.SourceFile, Names: []string{"expired.example"}, NotAfter: now.Add(-day)},
+		{
+			Source: certs.SourceACME, Names: []string{"overdue.example"},
+			NotAfter: now.Add(20 * day), RenewAt: now.Add(-10 * day),
+		},
+		{
+			Source: certs.SourceACME, Names: []string{"renewing.example"},
+			NotAfter: now.Add(30 * day), RenewAt: now.Add(-time.Hour),
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.GetCertificates(w, httptest.NewRequest(http.MethodGet, "/api/internal/tls/certificates", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var got []certificateHealth
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	statuses := make(map[string]string, len(got))
+	for _, c := range got {
+		statuses[c.Names[0]] = c.Status
+	}
+	assert.Equal(t, map[string]string{
+		"ok.example":       certificateOK,
+		"expiring.example": certificateExpiring,
+		"expired.example":  certificateExpired,
+		"overdue.example":  certificateRenewalOverdue,
+		"renewing.example": certificateOK,
+	}, statuses)
+}
+
+func TestGetCertificates_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "failed to init new handler")
+
+	w := httptest.NewRecorder()
+	handler.GetCertificates(w, httptest.NewRequest(http.MethodGet, "/api/internal/tls/certificates", http.NoBody))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestGetReadyz_Verbose(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := newCertificatesHandler(t, staticCertificates{
+		{Source: certs.SourceFile, Names: []string{"example.com"}, NotAfter: notAfter},
+	})
+
+	w := httptest.NewRecorder()
+	handler.GetReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+	assert.Equal(t, "ok", w.Body.String(), "details are reported on demand only")
+
+	w = httptest.NewRecorder()
+	handler.GetReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz?verbose", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(w.Body.String(), "\n")
+	assert.Equal(t, []string{"ok", "tls example.com: ok, expires 2030-01-02T03:04:05Z"}, lines)
+}
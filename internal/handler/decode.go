@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// errTooLarge is returned when the JSON request exceeds the configured limits.
+var errTooLarge = errors.New("request is too large")
+
+// jsonDecoder returns the decoder of the request body enforcing the size
+// and depth limits. In camelCase naming the snake_case fields are also
+// accepted, which needs the whole body to be read first.
+func (h *Handler) jsonDecoder(r *http.Request) (*json.Decoder, error) {
+	limits := h.config.JSON
+
+	body := r.Body
+	if limits.MaxBodySize > 0 {
+		body = http.MaxBytesReader(nil, body, limits.MaxBodySize)
+	}
+	var reader io.Reader = body
+	if limits.MaxDepth > 0 {
+		reader = &depthReader{r: reader, max: limits.MaxDepth}
+	}
+
+	if h.jsonNaming(r).IsCamelCase() {
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, limitError(err)
+		}
+		reader = bytes.NewReader(renameKeys(b, camelToSnake))
+	}
+
+	dec := json.NewDecoder(reader)
+	if limits.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec, nil
+}
+
+// decodeJSONArray reads the JSON array of the request body item by item,
+// so that the arrays over the configured number of items are rejected
+// before they are decoded in full. Null is decoded as the empty array.
+func decodeJSONArray[T any](h *Handler, r *http.Request) ([]T, error) {
+	dec, err := h.jsonDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, limitError(err)
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("array expected, got %v", tok)
+	}
+
+	var items []T
+	for dec.More() {
+		if maxItems := h.config.JSON.MaxItems; maxItems > 0 && len(items) == maxItems {
+			return nil, fmt.Errorf("%w: more than %d items", errTooLarge, maxItems)
+		}
+		var item T
+		if err = dec.Decode(&item); err != nil {
+			return nil, limitError(err)
+		}
+		items = append(items, item)
+	}
+	if _, err = dec.Token(); err != nil {
+		return nil, limitError(err)
+	}
+
+	return items, nil
+}
+
+// limitError reports the body over the size limit as errTooLarge.
+func limitError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: body exceeds %d bytes", errTooLarge, maxBytesErr.Limit)
+	}
+	return err
+}
+
+// decodeStatus returns the status code of the decoding error:
+// 413 Request Entity Too Large for the requests over the limits
+// and the fallback one for the rest.
+func decodeStatus(err error, fallback int) int {
+	if errors.Is(err, errTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return fallback
+}
+
+// depthReader fails reading the JSON nested deeper than the limit.
+type depthReader struct {
+	r   io.Reader
+	max int
+	// depth is the number of the open objects and arrays.
+	depth int
+	// inString and escaped track the string literals,
+	// whose brackets don't count.
+	inString bool
+	escaped  bool
+	// err is returned by all the reads once the limit is exceeded,
+	// as the decoder may drop the error of the read it has data from.
+	err error
+}
+
+func (d *depthReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.r.Read(p)
+	for i, c := range p[:n] {
+		switch {
+		case d.escaped:
+			d.escaped = false
+		case d.inString:
+			switch c {
+			case '\\':
+				d.escaped = true
+			case '"':
+				d.inString = false
+			}
+		case c == '"':
+			d.inString = true
+		case c == '{' || c == '[':
+			d.depth++
+			if d.depth > d.max {
+				d.err = fmt.Errorf("%w: nested deeper than %d", errTooLarge, d.max)
+				return i, d.err
+			}
+		case c == '}' || c == ']':
+			d.depth--
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSONArray(t *testing.T) {
+	c := config.NewForTest()
+	c.JSON.MaxBodySize = 64
+	c.JSON.MaxItems = 3
+	c.JSON.MaxDepth = 2
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+
+	type item struct {
+		ShortURL string `json:"short_url"`
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		naming   string
+		want     []item
+		wantErr  bool
+		tooLarge bool
+	}{
+		{
+			name: "items",
+			body: `[{"short_url": "a"}, {"short_url": "b"}]`,
+			want: []item{{"a"}, {"b"}},
+		},
+		{
+			name:   "camelCase items",
+			body:   `[{"shortURL": "a"}]`,
+			naming: "camelCase",
+			want:   []item{{"a"}},
+		},
+		{
+			name: "null",
+			body: `null`,
+		},
+		{
+			name:    "not an array",
+			body:    `{"short_url": "a"}`,
+			wantErr: true,
+		},
+		{
+			name:     "too many items",
+			body:     `[{}, {}, {}, {}]`,
+			wantErr:  true,
+			tooLarge: true,
+		},
+		{
+			name:     "too deep",
+			body:     `[{"short_url": "a", "extra": [1]}]`,
+			wantErr:  true,
+			tooLarge: true,
+		},
+		{
+			name: "brackets in strings don't count",
+			body: `[{"short_url": "[[{{\"]]"}]`,
+			want: []item{{`[[{{"]]`}},
+		},
+		{
+			name:     "body too large",
+			body:     `["` + strings.Repeat("a", 64) + `"]`,
+			wantErr:  true,
+			tooLarge: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.naming != "" {
+				r.Header.Set(acceptProfile, tt.naming)
+			}
+
+			got, err := decodeJSONArray[item](handler, r)
+			if tt.wantErr {
+				require.Error(t, err)
+				want := http.StatusBadRequest
+				if tt.tooLarge {
+					want = http.StatusRequestEntityTooLarge
+				}
+				assert.Equal(t, want, decodeStatus(err, http.StatusBadRequest), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeJSON_DisallowUnknownFields(t *testing.T) {
+	body := `{"url": "https://go.dev/", "unknown": true}`
+
+	for _, disallow := range []bool{false, true} {
+		c := config.NewForTest()
+		c.JSON.DisallowUnknownFields = disallow
+		l, _ := logger.NewForTest()
+		handler, err := New(memstore.NewURLRepository(), c, l)
+		require.NoError(t, err, "new handler error")
+
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		handler.PostShortenJSON(w, r)
+
+		if disallow {
+			assert.Equal(t, http.StatusInternalServerError, w.Code, "unknown fields expected to be rejected")
+		} else {
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestPostShortenBatch_TooLarge(t *testing.T) {
+	c := config.NewForTest()
+	c.JSON.MaxItems = 1
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+
+	body := `[
+		{"correlation_id": "1", "original_url": "https://go.dev/"},
+		{"correlation_id": "2", "original_url": "https://pkg.go.dev/"}
+	]`
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.PostShortenBatch(w, r)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
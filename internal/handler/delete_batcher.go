@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of the deletion batches and workers used if they are not configured.
+const (
+	defaultDeleteMinBatch = 10
+	defaultDeleteMaxBatch = 1000
+	defaultDeleteMaxDelay = 10 * time.Second
+	defaultDeleteWorkers  = 1
+	defaultDeleteQueueLen = 1024
+)
+
+// deleteEWMAWeight is the weight of the latest sample
+// of the arrival gap and the flush latency.
+const deleteEWMAWeight = 0.2
+
+// deleteBatcher sizes the batches of the deletions flushed to the storage.
+// A batch is flushed once it reaches the target size or once its oldest
+// deletion has waited for the maximum delay.
+//
+// The target size is the number of the deletions arriving at every worker
+// while the storage flushes a batch, i.e. the arrival rate times the flush
+// latency divided by the number of the workers, kept within the configured
+// bounds. Slow arrivals don't reach the minimum size and wait for the delay,
+// so that the storage is not churned with tiny batches. Fast arrivals are
+// flushed as soon as the batch keeps up with them, and larger batches
+// amortize the slow storage.
+//
+// It is safe for concurrent use.
+type deleteBatcher struct {
+	minSize, maxSize int
+	maxDelay         time.Duration
+	// workers is the number of the workers sharing the arrivals.
+	workers int
+
+	mu sync.Mutex
+	// gap is the moving average of the time between the arrivals.
+	gap time.Duration
+	// latency is the moving average of the flush duration.
+	latency     time.Duration
+	lastArrival time.Time
+	metrics     deleteMetrics
+}
+
+// deleteMetrics are the metrics of the deletion batches.
+type deleteMetrics struct {
+	// Pending is the number of the deletions waiting for the flush
+	// in the buffers of the workers.
+	Pending int `json:"pending"`
+	// Queued is the number of the deletions waiting for the workers.
+	Queued int `json:"queued"`
+	// TargetBatchSize is the current batch size flushed without waiting.
+	TargetBatchSize int `json:"target_batch_size"`
+	// ArrivalRate is the moving average of the deletions per second.
+	ArrivalRate float64 `json:"arrival_rate"`
+	// FlushLatencyMS is the moving average of the flush duration.
+	FlushLatencyMS float64 `json:"flush_latency_ms"`
+	// Flushes is the number of the successful flushes.
+	Flushes int64 `json:"flushes"`
+	// FailedFlushes is the number of the failed flushes retried later.
+	FailedFlushes int64 `json:"failed_flushes"`
+	// Deleted is the number of the flushed deletions.
+	Deleted int64 `json:"deleted"`
+	// LastBatchSize is the size of the last flushed batch.
+	LastBatchSize int `json:"last_batch_size"`
+	// MinBatchSize, MaxBatchSize and MaxDelayMS are the configured bounds.
+	MinBatchSize int   `json:"min_batch_size"`
+	MaxBatchSize int   `json:"max_batch_size"`
+	MaxDelayMS   int64 `json:"max_delay_ms"`
+	// Workers is the number of the workers flushing the batches.
+	Workers int `json:"workers"`
+}
+
+// GetDeletionMetrics returns the metrics of the batches
+// of the asynchronous deletion.
+//
+// Request:
+//
+//	GET /api/internal/deletions/metrics
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"pending": 3,
+//		"queued": 0,
+//		"target_batch_size": 10,
+//		"arrival_rate": 0.5,
+//		"flush_latency_ms": 4.2,
+//		"flushes": 12,
+//		"failed_flushes": 0,
+//		"deleted": 130,
+//		"last_batch_size": 10,
+//		"min_batch_size": 10,
+//		"max_batch_size": 1000,
+//		"max_delay_ms": 10000,
+//		"workers": 1
+//	}
+func (h *Handler) GetDeletionMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	metrics := h.batcher.snapshot()
+	metrics.Queued = len(h.deleteURLsChan)
+	if err := h.encodeData(w, r, metrics); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// newDeleteBatcher returns the batcher of the given number of the workers
+// with the given bounds, the defaults used for the ones not set.
+func newDeleteBatcher(minSize, maxSize int, maxDelay time.Duration, workers int) *deleteBatcher {
+	if minSize <= 0 {
+		minSize = defaultDeleteMinBatch
+	}
+	if maxSize <= 0 {
+		maxSize = defaultDeleteMaxBatch
+	}
+	maxSize = max(maxSize, minSize)
+	if maxDelay <= 0 {
+		maxDelay = defaultDeleteMaxDelay
+	}
+	if workers <= 0 {
+		workers = defaultDeleteWorkers
+	}
+	return &deleteBatcher{minSize: minSize, maxSize: maxSize, maxDelay: maxDelay, workers: workers}
+}
+
+// arrived records the arrival of the deletion at now.
+func (b *deleteBatcher) arrived(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.lastArrival.IsZero() {
+		b.gap = ewma(b.gap, now.Sub(b.lastArrival))
+	}
+	b.lastArrival = now
+	b.metrics.Pending++
+}
+
+// recovered records the deletions left pending by the previous run.
+func (b *deleteBatcher) recovered(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.metrics.Pending += n
+}
+
+// flushed records the flush of the batch of the given size.
+func (b *deleteBatcher) flushed(size int, took time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latency = ewma(b.latency, took)
+	if err != nil {
+		b.metrics.FailedFlushes++
+		return
+	}
+	b.metrics.Flushes++
+	b.metrics.Deleted += int64(size)
+	b.metrics.LastBatchSize = size
+	b.metrics.Pending = max(b.metrics.Pending-size, 0)
+}
+
+// target returns the batch size flushed without waiting for the delay.
+func (b *deleteBatcher) target() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.targetLocked()
+}
+
+func (b *deleteBatcher) targetLocked() int {
+	if b.gap <= 0 {
+		return b.minSize
+	}
+	n := math.Ceil(float64(b.latency) / float64(b.gap) / float64(b.workers))
+	if n >= float64(b.maxSize) {
+		return b.maxSize
+	}
+	return max(int(n), b.minSize)
+}
+
+// snapshot returns the current metrics.
+func (b *deleteBatcher) snapshot() deleteMetrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m := b.metrics
+	m.TargetBatchSize = b.targetLocked()
+	if b.gap > 0 {
+		m.ArrivalRate = float64(time.Second) / float64(b.gap)
+	}
+	m.FlushLatencyMS = float64(b.latency) / float64(time.Millisecond)
+	m.MinBatchSize = b.minSize
+	m.MaxBatchSize = b.maxSize
+	m.MaxDelayMS = b.maxDelay.Milliseconds()
+	m.Workers = b.workers
+	return m
+}
+
+// ewma returns the moving average updated with the sample.
+// The first sample is taken as is.
+func ewma(avg, sample time.Duration) time.Duration {
+	if avg == 0 {
+		return sample
+	}
+	return time.Duration(deleteEWMAWeight*float64(sample) + (1-deleteEWMAWeight)*float64(avg))
+}
+
+// stopTimer stops the timer and drains its channel,
+// so that the timer can be reset without the stale fire.
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteBatcher_Target(t *testing.T) {
+	tests := []struct {
+		name    string
+		gap     time.Duration
+		latency time.Duration
+		want    int
+	}{
+		{name: "no arrivals", want: 10},
+		{name: "slow arrivals", gap: time.Second, latency: 5 * time.Millisecond, want: 10},
+		{name: "fast arrivals", gap: time.Millisecond, latency: 50 * time.Millisecond, want: 50},
+		{name: "too fast arrivals", gap: time.Microsecond, latency: time.Second, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDeleteBatcher(10, 100, time.Second, 1)
+
+			now := time.Now()
+			if tt.gap > 0 {
+				b.arrived(now)
+				b.arrived(now.Add(tt.gap))
+			}
+			if tt.latency > 0 {
+				b.flushed(2, tt.latency, nil)
+			}
+
+			assert.Equal(t, tt.want, b.target())
+		})
+	}
+}
+
+func TestDeleteBatcher_Defaults(t *testing.T) {
+	b := newDeleteBatcher(0, 0, 0, 0)
+
+	m := b.snapshot()
+	assert.Equal(t, defaultDeleteMinBatch, m.MinBatchSize)
+	assert.Equal(t, defaultDeleteMaxBatch, m.MaxBatchSize)
+	assert.Equal(t, defaultDeleteMaxDelay.Milliseconds(), m.MaxDelayMS)
+}
+
+func TestDeleteBatcher_Metrics(t *testing.T) {
+	b := newDeleteBatcher(10, 100, time.Second, 1)
+
+	now := time.Now()
+	b.arrived(now)
+	b.arrived(now.Add(500 * time.Millisecond))
+	b.flushed(2, 10*time.Millisecond, errors.New("unavailable"))
+	b.flushed(2, 10*time.Millisecond, nil)
+
+	m := b.snapshot()
+	assert.Zero(t, m.Pending)
+	assert.InDelta(t, 2.0, m.ArrivalRate, 0.001)
+	assert.InDelta(t, 10.0, m.FlushLatencyMS, 0.001)
+	assert.Equal(t, int64(1), m.Flushes)
+	assert.Equal(t, int64(1), m.FailedFlushes)
+	assert.Equal(t, int64(2), m.Deleted)
+	assert.Equal(t, 2, m.LastBatchSize)
+}
+
+func TestFlushDeletedURLs_BatchSize(t *testing.T) {
+	store := memstore.NewURLRepository()
+	URLs := []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "test"},
+	}
+	require.NoError(t, store.SaveAll(context.TODO(), URLs))
+
+	cfg := config.NewForTest()
+	cfg.Deletion.MinBatchSize = 2
+	cfg.Deletion.MaxDelay = time.Hour
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	// the batch of the minimum size is flushed without waiting for the delay
+	require.NoError(t, handler.scheduleDeletions(context.TODO(), URLs))
+
+	assert.Eventually(t, func() bool {
+		return handler.batcher.snapshot().Flushes == 1
+	}, time.Second, 10*time.Millisecond)
+
+	for _, u := range URLs {
+		got, err := store.Get(context.TODO(), u.ShortURL)
+		require.NoError(t, err)
+		assert.True(t, got.IsDeleted, u.ShortURL)
+	}
+}
+
+func TestFlushDeletedURLs_MaxDelay(t *testing.T) {
+	store := memstore.NewURLRepository()
+	URLs := []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+	}
+	require.NoError(t, store.SaveAll(context.TODO(), URLs))
+
+	cfg := config.NewForTest()
+	cfg.Deletion.MaxDelay = 20 * time.Millisecond
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	// the single deletion waits for the delay only
+	require.NoError(t, handler.scheduleDeletions(context.TODO(), URLs))
+
+	assert.Eventually(t, func() bool {
+		return handler.batcher.snapshot().Deleted == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFlushDeletedURLs_Workers(t *testing.T) {
+	store := memstore.NewURLRepository()
+	URLs := make([]*models.URL, 0, 20)
+	for i := 0; i < cap(URLs); i++ {
+		URLs = append(URLs, models.NewRecord(fmt.Sprintf("code%d", i), "https://go.dev/", "test"))
+	}
+	require.NoError(t, store.SaveAll(context.TODO(), URLs))
+
+	cfg := config.NewForTest()
+	cfg.Deletion.Workers = 4
+	cfg.Deletion.MinBatchSize = 1
+	cfg.Deletion.MaxDelay = time.Hour
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	require.NoError(t, handler.scheduleDeletions(context.TODO(), URLs))
+
+	assert.Eventually(t, func() bool {
+		return handler.batcher.snapshot().Deleted == int64(len(URLs))
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4, handler.batcher.snapshot().Workers)
+}
+
+func TestScheduleDeletions_QueueFull(t *testing.T) {
+	cfg := config.NewForTest()
+	cfg.Deletion.QueueLength = 1
+
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), cfg, l)
+	require.NoError(t, err, "new handler error")
+	// the workers are stopped, so nothing leaves the queue
+	handler.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = handler.scheduleDeletions(ctx, []*models.URL{
+		{ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{ShortURL: "RTfd56hn", UserID: "test"},
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
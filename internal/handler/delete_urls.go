@@ -1,15 +1,39 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
 )
 
+// Statuses of the synchronous deletion of the short URL.
+const (
+	deleteStatusDeleted   = "deleted"
+	deleteStatusNotFound  = "not_found"
+	deleteStatusForbidden = "forbidden"
+)
+
+type deleteURLResult struct {
+	ShortURL models.ShortURL `json:"short_url"`
+	Status   string          `json:"status"`
+}
+
+type deleteByOriginalResult struct {
+	OriginalURL models.OriginalURL `json:"original_url"`
+	ShortURL    models.ShortURL    `json:"short_url"`
+}
+
 // DeleteByUserID deletes a list of shortened URLs owned by a specific user.
+// The deletion is asynchronous. If the storage supports it, the scheduled
+// deletions are persisted before the response and are applied after
+// the restart if the process crashes.
 //
 // Request:
 //
@@ -20,44 +44,239 @@ import (
 // Response:
 //
 //	HTTP/1.1 202 Accepted
+//
+// With the "sync" query parameter the URLs are deleted before the response,
+// which reports the status of every short URL: deleted, not_found,
+// or forbidden if it is owned by another user, including the URLs shared
+// with the user, which are read-only.
+//
+// Request:
+//
+//	DELETE /api/user/urls?sync=1
+//
+//	[ "6qxTVvsy", "RTfd56hn" ]
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[
+//		{ "short_url": "6qxTVvsy", "status": "deleted" },
+//		{ "short_url": "RTfd56hn", "status": "forbidden" }
+//	]
 func (h *Handler) DeleteURLs(w http.ResponseWriter, r *http.Request) {
 	// Check the request method.
 	if r.Method != http.MethodDelete {
-		// Return a "Bad Request" error if the request method is not "DELETE".
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodDelete)
 		return
 	}
 
 	// Check content type.
 	if !h.IsApplicationJSONContentType(r) {
-		h.textError(w, r.Header.Get("Content-Type"), errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
 	// Extract the user from the request context.
 	user, ok := user.FromContext(r.Context())
 	if !ok {
-		h.textError(w, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 
-	// Decode the request body.
-	var payload []models.ShortURL
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	// Decode the request body item by item.
+	payload, err := decodeJSONArray[models.ShortURL](h, r)
+	if err != nil {
 		// Return an internal server error if the request body cannot be decoded.
-		h.textError(w, "failed to decode request",
-			err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to decode request",
+			err, decodeStatus(err, http.StatusInternalServerError))
 		return
 	}
 
-	// Schedule deletion of the URLs.
 	for _, shortURL := range payload {
-		h.deleteURLsChan <- &models.URL{
+		if _, err = h.parseShortURL(string(shortURL)); err != nil {
+			h.jsonError(w, r, "invalid short URL", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if sync, _ := strconv.ParseBool(r.URL.Query().Get("sync")); sync {
+		results, err := h.deleteURLsSync(r.Context(), user.ID, payload)
+		if err != nil {
+			h.jsonError(w, r, "failed to delete URLs", err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err = h.encodeData(w, r, results); err != nil {
+			h.logger.Errorf("failed to encode response: %s", err)
+		}
+		return
+	}
+
+	URLs := make([]*models.URL, len(payload))
+	for i, shortURL := range payload {
+		URLs[i] = &models.URL{
 			ShortURL: shortURL,
 			UserID:   user.ID,
 		}
 	}
 
+	if err := h.scheduleDeletions(r.Context(), URLs); err != nil {
+		h.jsonError(w, r, "failed to save pending deletions",
+			err, http.StatusInternalServerError)
+		return
+	}
+
 	// Return an "Accepted" status code.
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// DeleteURLsByOriginal deletes the shortened URLs of the user
+// by their original URLs, e.g. for the cleanup scripts which only know
+// the destinations. Every short URL of the user pointing to the given
+// original URL is deleted. The deletion is asynchronous, the response
+// lists the scheduled short URLs. Original URLs the user has not
+// shortened are skipped.
+//
+// Request:
+//
+//	DELETE /api/user/urls/by-original
+//
+//	[ "https://go.dev/", "https://pkg.go.dev/" ]
+//
+// Response:
+//
+//	HTTP/1.1 202 Accepted
+//	Content-Type: application/json
+//	[
+//		{ "original_url": "https://go.dev/", "short_url": "6qxTVvsy" },
+//		{ "original_url": "https://go.dev/", "short_url": "go" }
+//	]
+func (h *Handler) DeleteURLsByOriginal(w http.ResponseWriter, r *http.Request) {
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := decodeJSONArray[models.OriginalURL](h, r)
+	if err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	// The storage has no index of the original URLs of the user,
+	// so they are resolved from the whole list.
+	records, err := h.store.GetAllByUserID(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		h.jsonError(w, r, "failed to get user URLs", err, http.StatusInternalServerError)
+		return
+	}
+	byOriginal := make(map[models.OriginalURL][]models.ShortURL, len(records))
+	for _, record := range records {
+		if record.IsDeleted {
+			continue
+		}
+		byOriginal[record.OriginalURL] = append(byOriginal[record.OriginalURL], record.ShortURL)
+	}
+
+	results := make([]deleteByOriginalResult, 0, len(payload))
+	URLs := make([]*models.URL, 0, len(payload))
+	for _, originalURL := range payload {
+		for _, shortURL := range byOriginal[originalURL] {
+			results = append(results, deleteByOriginalResult{originalURL, shortURL})
+			URLs = append(URLs, &models.URL{ShortURL: shortURL, UserID: user.ID})
+		}
+		// repeated original URLs are scheduled once
+		delete(byOriginal, originalURL)
+	}
+
+	if err = h.scheduleDeletions(r.Context(), URLs); err != nil {
+		h.jsonError(w, r, "failed to save pending deletions",
+			err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err = h.encodeData(w, r, results); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+	}
+}
+
+// scheduleDeletions hands the URLs over to the asynchronous deletion.
+// If the storage supports it, the deletions are persisted first,
+// so that they survive the crash. It waits for the room in the queue
+// of the workers until the context is done.
+func (h *Handler) scheduleDeletions(ctx context.Context, URLs []*models.URL) error {
+	if h.deletions != nil && len(URLs) > 0 {
+		if err := h.deletions.SavePendingDeletions(ctx, URLs...); err != nil {
+			return err
+		}
+	}
+
+	for _, url := range URLs {
+		select {
+		case h.deleteURLsChan <- url:
+		case <-ctx.Done():
+			return fmt.Errorf("deletion queue is full: %w", ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+// deleteURLsSync deletes the URLs of the user in the request scope
+// and returns the status of every requested short URL in the request
+// order. Repeated short URLs are reported once.
+func (h *Handler) deleteURLsSync(
+	ctx context.Context, userID user.ID, shortURLs []models.ShortURL,
+) ([]deleteURLResult, error) {
+	records, err := h.store.GetMany(ctx, shortURLs)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[models.ShortURL]user.ID, len(records))
+	for _, record := range records {
+		owners[record.ShortURL] = record.UserID
+	}
+
+	results := make([]deleteURLResult, 0, len(shortURLs))
+	owned := make([]*models.URL, 0, len(records))
+	seen := make(map[models.ShortURL]struct{}, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		if _, ok := seen[shortURL]; ok {
+			continue
+		}
+		seen[shortURL] = struct{}{}
+
+		owner, ok := owners[shortURL]
+		switch {
+		case !ok:
+			results = append(results, deleteURLResult{shortURL, deleteStatusNotFound})
+		case owner != userID:
+			results = append(results, deleteURLResult{shortURL, deleteStatusForbidden})
+		default:
+			results = append(results, deleteURLResult{shortURL, deleteStatusDeleted})
+			owned = append(owned, &models.URL{ShortURL: shortURL, UserID: userID})
+		}
+	}
+
+	if len(owned) > 0 {
+		if err = h.store.DeleteURLs(ctx, owned...); err != nil {
+			return nil, err
+		}
+		h.publishEvents(events.TypeDeleted, owned...)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deletionQueue is the in-memory queue of the scheduled deletions.
+type deletionQueue struct {
+	mu      sync.Mutex
+	pending []*models.URL
+	err     error
+}
+
+var _ repository.DeletionQueue = (*deletionQueue)(nil)
+
+func (q *deletionQueue) SavePendingDeletions(_ context.Context, urls ...*models.URL) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return q.err
+	}
+	q.pending = append(q.pending, urls...)
+	return nil
+}
+
+func (q *deletionQueue) GetPendingDeletions(context.Context) ([]*models.URL, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*models.URL(nil), q.pending...), q.err
+}
+
+func (q *deletionQueue) RemovePendingDeletions(_ context.Context, urls ...*models.URL) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, u := range urls {
+		for i, p := range q.pending {
+			if *p == *u {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	return q.err
+}
+
+func (q *deletionQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+func TestDeleteURLs_Persisted(t *testing.T) {
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+		UserID:      "test",
+	})
+	queue := &deletionQueue{}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithDeletionQueue(queue))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["YBbxJEcQ9vq"]`))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.DeleteURLs(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	// the deletion is persisted before it is applied
+	record, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.False(t, record.IsDeleted)
+	assert.Equal(t, 1, queue.len())
+
+	// Stop flushes the buffered deletions.
+	handler.Stop()
+
+	record, err = store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.True(t, record.IsDeleted)
+	assert.Zero(t, queue.len(), "applied deletion must be removed from the queue")
+}
+
+func TestDeleteURLs_QueueError(t *testing.T) {
+	queue := &deletionQueue{err: errIntentionallyNotWorkingMethod}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(&brokenStore{}, config.NewForTest(), l, WithDeletionQueue(queue))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["YBbxJEcQ9vq"]`))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.DeleteURLs(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
+
+func TestPendingDeletions_Recovered(t *testing.T) {
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+		UserID:      "test",
+	})
+	queue := &deletionQueue{pending: []*models.URL{{ShortURL: "YBbxJEcQ9vq", UserID: "test"}}}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithDeletionQueue(queue))
+	require.NoError(t, err, "new handler error")
+
+	// Stop flushes the recovered deletions.
+	handler.Stop()
+
+	record, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.True(t, record.IsDeleted)
+	assert.Zero(t, queue.len())
+}
+
+func TestDeleteURLs_Sync(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		models.NewRecord("YBbxJEcQ9vq", "https://go.dev/", "owner"),
+		models.NewRecord("Vp3Vf5tXoSK", "https://go.dev/doc/", "stranger"),
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	body := `["YBbxJEcQ9vq", "Vp3Vf5tXoSK", "unknown", "YBbxJEcQ9vq"]`
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls?sync=1", strings.NewReader(body))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "owner"}))
+	w := httptest.NewRecorder()
+
+	handler.DeleteURLs(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `[
+		{"short_url": "YBbxJEcQ9vq", "status": "deleted"},
+		{"short_url": "Vp3Vf5tXoSK", "status": "forbidden"},
+		{"short_url": "unknown", "status": "not_found"}
+	]`, w.Body.String())
+
+	// deleted in the request scope
+	got, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+	got, err = store.Get(context.TODO(), "Vp3Vf5tXoSK")
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted, "URL of another user is kept")
+}
+
+func TestDeleteURLs_InvalidShortURL(t *testing.T) {
+	for _, query := range []string{"", "?sync=1"} {
+		store := memstore.NewURLRepository()
+		require.NoError(t, store.Save(context.TODO(), models.NewRecord("YBbxJEcQ9vq", "https://go.dev/", "owner")))
+
+		l, _ := logger.NewForTest()
+		handler, err := New(store, config.NewForTest(), l)
+		require.NoError(t, err, "new handler error")
+
+		body := `["YBbxJEcQ9vq", "../O0Il"]`
+		r := httptest.NewRequest(http.MethodDelete, "/api/user/urls"+query, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "owner"}))
+		w := httptest.NewRecorder()
+
+		handler.DeleteURLs(w, r)
+		handler.Stop()
+
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), models.ErrInvalidShortURL.Error(), query)
+
+		// nothing is deleted
+		got, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+		require.NoError(t, err)
+		assert.False(t, got.IsDeleted, query)
+	}
+}
+
+func TestDeleteURLsByOriginal(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		models.NewRecord("YBbxJEcQ9vq", "https://go.dev/", "owner"),
+		models.NewRecord("go", "https://go.dev/", "owner"),
+		models.NewRecord("Vp3Vf5tXoSK", "https://go.dev/doc/", "owner"),
+		models.NewRecord("Foreign", "https://go.dev/doc/", "stranger"),
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	body := `["https://go.dev/", "https://pkg.go.dev/", "https://go.dev/"]`
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls/by-original", strings.NewReader(body))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "owner"}))
+	w := httptest.NewRecorder()
+
+	handler.DeleteURLsByOriginal(w, r)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var results []deleteByOriginalResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.ElementsMatch(t, []deleteByOriginalResult{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq"},
+		{OriginalURL: "https://go.dev/", ShortURL: "go"},
+	}, results)
+
+	// Stop flushes the buffered deletions.
+	handler.Stop()
+
+	for shortURL, deleted := range map[models.ShortURL]bool{
+		"YBbxJEcQ9vq": true,
+		"go":          true,
+		"Vp3Vf5tXoSK": false,
+		"Foreign":     false,
+	} {
+		got, err := store.Get(context.TODO(), shortURL)
+		require.NoError(t, err)
+		assert.Equal(t, deleted, got.IsDeleted, shortURL)
+	}
+
+	// users without URLs have nothing to delete
+	r = httptest.NewRequest(http.MethodDelete, "/api/user/urls/by-original", strings.NewReader(body))
+	r.Header.Set(contentType, applicationJSON)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "nobody"}))
+	w = httptest.NewRecorder()
+
+	handler.DeleteURLsByOriginal(w, r)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+// stuckStore is the storage whose deletions hang until the context is done.
+type stuckStore struct {
+	repository.URLStorage
+	deadline chan bool
+}
+
+func (s *stuckStore) DeleteURLs(ctx context.Context, _ ...*models.URL) error {
+	_, ok := ctx.Deadline()
+	s.deadline <- ok
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestShutdown_StuckFlush(t *testing.T) {
+	store := &stuckStore{URLStorage: memstore.NewURLRepository(), deadline: make(chan bool, 1)}
+	queue := &deletionQueue{pending: []*models.URL{{ShortURL: "YBbxJEcQ9vq", UserID: "test"}}}
+
+	cfg := config.NewForTest()
+	cfg.HTTPServer.ShutdownTimeout = 50 * time.Millisecond
+	cfg.Deletion.MaxDelay = time.Hour
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l, WithDeletionQueue(queue))
+	require.NoError(t, err, "new handler error")
+
+	start := time.Now()
+	handler.Stop()
+
+	assert.Less(t, time.Since(start), time.Second, "stop waits for the timeout only")
+	assert.True(t, <-store.deadline, "final flush is bounded by the shutdown timeout")
+	assert.Equal(t, 1, queue.len(), "deletion is kept for the restart")
+}
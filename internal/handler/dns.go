@@ -0,0 +1,33 @@
+package handler
+
+import "net/http"
+
+// GetDNSMetrics returns the metrics of the cache of the hostnames
+// resolved by the outbound requests, e.g. of the feeds.
+//
+// Request:
+//
+//	GET /api/internal/dns/metrics
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"entries": 12,
+//		"hits": 340,
+//		"negative_hits": 3,
+//		"misses": 15,
+//		"errors": 1
+//	}
+func (h *Handler) GetDNSMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.encodeData(w, r, h.resolver.Metrics()); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/KretovDmitry/shortener/api"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// openAPIPath is the path of the OpenAPI spec in JSON.
+	openAPIPath = "/api/docs/openapi.json"
+	// swaggerUIVersion is the version of the Swagger UI loaded by the docs page.
+	swaggerUIVersion = "5.17.14"
+)
+
+// openAPIJSON returns the OpenAPI spec converted to JSON once.
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var spec any
+	if err := yaml.Unmarshal(api.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("decode spec: %w", err)
+	}
+	return json.Marshal(spec)
+})
+
+// docsPage is the data of the API docs page template.
+type docsPage struct {
+	Title   string
+	Version string
+	SpecURL string
+}
+
+// GetOpenAPI serves the OpenAPI spec of the API in JSON,
+// so that the API consumers don't reverse-engineer the JSON shapes.
+//
+// Request:
+//
+//	GET /api/docs/openapi.json
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{ "openapi": "3.0.3", ... }
+func (h *Handler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := openAPIJSON()
+	if err != nil {
+		h.jsonError(w, r, "failed to load spec", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(spec); err != nil {
+		h.logger.Errorf("failed to write response: %s", err)
+	}
+}
+
+// GetDocs serves the Swagger UI page browsing the OpenAPI spec.
+// The Swagger UI itself is loaded from the CDN.
+//
+// Request:
+//
+//	GET /api/docs
+func (h *Handler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := pages.ExecuteTemplate(&buf, "docs.html", docsPage{
+		Title:   "Shortener API",
+		Version: swaggerUIVersion,
+		SpecURL: openAPIPath,
+	})
+	if err != nil {
+		h.jsonError(w, r, "failed to render docs page", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err = buf.WriteTo(w); err != nil {
+		h.logger.Errorf("failed to write response: %s", err)
+	}
+}
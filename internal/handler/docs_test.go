@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOpenAPI(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "failed to init new handler")
+
+	w := httptest.NewRecorder()
+	handler.GetOpenAPI(w, httptest.NewRequest(http.MethodGet, openAPIPath, http.NoBody))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get(contentType))
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Contains(t, spec.Paths, "/api/shorten")
+}
+
+func TestGetDocs(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "failed to init new handler")
+
+	w := httptest.NewRecorder()
+	handler.GetDocs(w, httptest.NewRequest(http.MethodGet, "/api/docs", http.NoBody))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get(contentType), "text/html")
+	assert.Contains(t, w.Body.String(), "swagger-ui-dist@"+swaggerUIVersion)
+	assert.Contains(t, w.Body.String(), `\/api\/docs\/openapi.json`)
+}
+
+// TestOpenAPI_Routes checks that the spec documents every API route.
+func TestOpenAPI_Routes(t *testing.T) {
+	// the routes following the standards instead of the spec
+	undocumented := map[string]bool{
+		"GET /oauth/authorize":       true,
+		"POST /oauth/token":          true,
+		"GET /api/docs":              true,
+		"GET /api/docs/openapi.json": true,
+	}
+
+	spec, err := openAPIJSON()
+	require.NoError(t, err)
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(spec, &doc))
+
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "failed to init new handler")
+
+	router := handler.Register(chi.NewRouter(), c, l)
+	var walked int
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		walked++
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		if undocumented[method+" "+route] {
+			return nil
+		}
+		ops, ok := doc.Paths[route]
+		if assert.True(t, ok, "route %s %s is not documented", method, route) {
+			assert.Contains(t, ops, strings.ToLower(method), "route %s %s is not documented", method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Greater(t, walked, len(undocumented))
+}
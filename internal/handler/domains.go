@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// domainQueryParam is the query parameter of the text shortening
+// selecting the domain of the short URL.
+const domainQueryParam = "domain"
+
+// resolveDomain returns the domain of the short URL requested by the client.
+// The empty one and the return address are the default domain, stored as
+// empty. Other domains must be configured, ErrInvalidRequest is returned
+// otherwise.
+func (h *Handler) resolveDomain(requested string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	if requested == "" || strings.EqualFold(requested, h.config.HTTPServer.ReturnAddress.String()) ||
+		strings.EqualFold(requested, h.base.Host) {
+		return "", nil
+	}
+	for _, domain := range h.config.HTTPServer.Domains {
+		if strings.EqualFold(requested, domain) {
+			return domain, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown domain %q", errs.ErrInvalidRequest, requested)
+}
+
+// newBaseURL returns the base URL of the short URLs of the default domain:
+// the configured one or, if it is not set, the return address with https
+// if TLS is enabled and http otherwise.
+func newBaseURL(cfg *config.Config) (*url.URL, error) {
+	if base := cfg.HTTPServer.BaseURL; base != "" {
+		if err := config.ValidateBaseURL(base); err != nil {
+			return nil, err
+		}
+		u, _ := url.Parse(base)
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+		return u, nil
+	}
+
+	scheme := "http"
+	if cfg.TLSEnabled {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: cfg.HTTPServer.ReturnAddress.String()}, nil
+}
+
+// baseURL returns the base URL of the domain without the trailing slash.
+// The configured domains share the scheme and the path prefix of the
+// default one.
+func (h *Handler) baseURL(domain string) string {
+	base := *h.base
+	if domain != "" {
+		base.Host = domain
+	}
+	return base.String()
+}
+
+// absoluteURL returns the short URL of the domain,
+// the default one if the domain is empty.
+func (h *Handler) absoluteURL(domain string, shortURL models.ShortURL) string {
+	return h.baseURL(domain) + "/" + string(shortURL)
+}
+
+// servesDomain reports whether the short URL of the domain redirects
+// from the host of the request. The URLs of the configured domains
+// redirect only from their hosts, the URLs of the default domain redirect
+// from any host but the configured domains. Ports are ignored.
+func (h *Handler) servesDomain(r *http.Request, domain string) bool {
+	host := hostname(r.Host)
+	if domain != "" {
+		return strings.EqualFold(host, hostname(domain))
+	}
+	for _, d := range h.config.HTTPServer.Domains {
+		if strings.EqualFold(host, hostname(d)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostname returns the host without the port.
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
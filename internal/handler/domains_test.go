@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDomainsHandler(t *testing.T, store *memstore.URLRepository) *Handler {
+	t.Helper()
+	cfg := config.NewForTest()
+	require.NoError(t, cfg.HTTPServer.ReturnAddress.Set("sho.rt:80"))
+	cfg.HTTPServer.Domains = []string{"acme.link", "brand.example:8080"}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	return handler
+}
+
+func TestPostShortenJSON_Domain(t *testing.T) {
+	tests := []struct {
+		name       string
+		domain     string
+		statusCode int
+		want       string
+	}{
+		{name: "default domain", statusCode: http.StatusCreated, want: "http://sho.rt:80/"},
+		{name: "return address", domain: "sho.rt:80", statusCode: http.StatusCreated, want: "http://sho.rt:80/"},
+		{name: "configured domain", domain: "ACME.link", statusCode: http.StatusCreated, want: "http://acme.link/"},
+		{name: "unknown domain", domain: "evil.example", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewURLRepository()
+			handler := newDomainsHandler(t, store)
+
+			body := `{"url":"https://go.dev/","domain":"` + tt.domain + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+			r.Header.Set(contentType, applicationJSON)
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+			w := httptest.NewRecorder()
+
+			handler.PostShortenJSON(w, r)
+
+			res := w.Result()
+			defer func() { _ = res.Body.Close() }()
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusCreated {
+				return
+			}
+
+			var got shortenJSONResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.True(t, strings.HasPrefix(got.Result, tt.want), got.Result)
+		})
+	}
+}
+
+func TestGetRedirect_Domain(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Main", UserID: "test"},
+		{OriginalURL: "https://acme.example/", ShortURL: "Acme", UserID: "test", Domain: "acme.link"},
+	}))
+	handler := newDomainsHandler(t, store)
+
+	tests := []struct {
+		name       string
+		host       string
+		shortURL   string
+		statusCode int
+	}{
+		{name: "default URL on return address", host: "sho.rt", shortURL: "Main", statusCode: http.StatusTemporaryRedirect},
+		{name: "default URL on any host", host: "localhost:8080", shortURL: "Main", statusCode: http.StatusTemporaryRedirect},
+		{name: "default URL on configured domain", host: "acme.link", shortURL: "Main", statusCode: http.StatusBadRequest},
+		{name: "domain URL on its domain", host: "acme.link:80", shortURL: "Acme", statusCode: http.StatusTemporaryRedirect},
+		{name: "domain URL on return address", host: "sho.rt", shortURL: "Acme", statusCode: http.StatusBadRequest},
+		{name: "domain URL on another domain", host: "brand.example:8080", shortURL: "Acme", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+			r.Host = tt.host
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.GetRedirect(w, r)
+
+			res := w.Result()
+			defer func() { _ = res.Body.Close() }()
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestGetAllByUserID_Domain(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Main", UserID: "test"},
+		{OriginalURL: "https://acme.example/", ShortURL: "Acme", UserID: "test", Domain: "acme.link"},
+	}))
+	handler := newDomainsHandler(t, store)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.GetAllByUserID(w, r)
+
+	res := w.Result()
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var got []getAllByUserIDResponsePayload
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+	shortURLs := make([]models.ShortURL, len(got))
+	for i, u := range got {
+		shortURLs[i] = u.ShortURL
+	}
+	assert.ElementsMatch(t, []models.ShortURL{"http://sho.rt:80/Main", "http://acme.link/Acme"}, shortURLs)
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		tls     bool
+		domain  string
+		want    string
+	}{
+		{name: "return address", want: "http://sho.rt:80/Abc"},
+		{name: "return address with TLS", tls: true, want: "https://sho.rt:80/Abc"},
+		{name: "base URL", baseURL: "https://sho.rt", want: "https://sho.rt/Abc"},
+		{name: "base URL with path prefix", baseURL: "https://sho.rt/s/", want: "https://sho.rt/s/Abc"},
+		{name: "configured domain", baseURL: "https://sho.rt/s", domain: "acme.link", want: "https://acme.link/s/Abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewForTest()
+			require.NoError(t, cfg.HTTPServer.ReturnAddress.Set("sho.rt:80"))
+			cfg.HTTPServer.BaseURL = tt.baseURL
+			cfg.TLSEnabled = config.TLSEnabled(tt.tls)
+
+			l, _ := logger.NewForTest()
+			handler, err := New(memstore.NewURLRepository(), cfg, l)
+			require.NoError(t, err, "new handler error")
+
+			assert.Equal(t, tt.want, handler.absoluteURL(tt.domain, "Abc"))
+		})
+	}
+
+	cfg := config.NewForTest()
+	cfg.HTTPServer.BaseURL = "sho.rt/s"
+	l, _ := logger.NewForTest()
+	_, err := New(memstore.NewURLRepository(), cfg, l)
+	require.Error(t, err, "relative base URL")
+}
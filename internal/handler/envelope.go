@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// Query parameters selecting the page of the lists.
+const (
+	limitParam  = "limit"
+	offsetParam = "offset"
+)
+
+// maxPageLimit is the maximum number of the items of the page.
+const maxPageLimit = 1000
+
+// encodeData writes v as the payload of the successful response,
+// in the envelope if it is configured.
+func (h *Handler) encodeData(w http.ResponseWriter, r *http.Request, v any) error {
+	if !h.config.JSONEnvelope {
+		return h.encodeJSON(w, r, v)
+	}
+	return h.encodeJSON(w, r, envelope.Data(v))
+}
+
+// encodePage writes v as the page of the list, in the envelope
+// with the pagination if it is configured.
+func (h *Handler) encodePage(w http.ResponseWriter, r *http.Request, v any, p envelope.Pagination) error {
+	if !h.config.JSONEnvelope {
+		return h.encodeJSON(w, r, v)
+	}
+	return h.encodeJSON(w, r, envelope.Page(v, p))
+}
+
+// encodeFailure writes v as the payload of the failed response,
+// or the error in the envelope if it is configured.
+func (h *Handler) encodeFailure(w http.ResponseWriter, r *http.Request, v any, e envelope.Error) error {
+	if !h.config.JSONEnvelope {
+		return h.encodeJSON(w, r, v)
+	}
+	return h.encodeJSON(w, r, envelope.Failure(e))
+}
+
+// paginate returns the page of the items selected by the limit
+// and offset query parameters and its position in the list.
+// All the items are returned if the parameters are not set.
+func paginate[T any](r *http.Request, items []T) ([]T, envelope.Pagination, error) {
+	p := envelope.Pagination{Total: len(items)}
+
+	query := r.URL.Query()
+	if s := query.Get(limitParam); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return nil, p, fmt.Errorf("%w: limit must be from 1 to %d", errs.ErrInvalidRequest, maxPageLimit)
+		}
+		p.Limit = n
+	}
+	if s := query.Get(offsetParam); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, p, fmt.Errorf("%w: offset must not be negative", errs.ErrInvalidRequest)
+		}
+		p.Offset = n
+	}
+
+	start := min(p.Offset, len(items))
+	end := len(items)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, end)
+	}
+	return items[start:end], p, nil
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []int
+		page    envelope.Pagination
+		wantErr bool
+	}{
+		{name: "all", want: items, page: envelope.Pagination{Total: 5}},
+		{name: "first page", query: "?limit=2", want: []int{1, 2}, page: envelope.Pagination{Total: 5, Limit: 2}},
+		{name: "last page", query: "?limit=2&offset=4", want: []int{5},
+			page: envelope.Pagination{Total: 5, Limit: 2, Offset: 4}},
+		{name: "past the end", query: "?offset=10", want: []int{}, page: envelope.Pagination{Total: 5, Offset: 10}},
+		{name: "zero limit", query: "?limit=0", wantErr: true},
+		{name: "over max limit", query: "?limit=1001", wantErr: true},
+		{name: "negative offset", query: "?offset=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls"+tt.query, http.NoBody)
+			got, page, err := paginate(r, items)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.page, page)
+		})
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "test"},
+	}))
+
+	l, _ := logger.NewForTest()
+	cfg := config.NewForTest()
+	cfg.JSONEnvelope = true
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+
+	t.Run("page", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/user/urls?limit=1", http.NoBody)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		handler.GetAllByUserID(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var got struct {
+			Data []getAllByUserIDResponsePayload `json:"data"`
+			Meta envelope.Meta                   `json:"meta"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Len(t, got.Data, 1)
+		assert.Equal(t, &envelope.Pagination{Total: 2, Limit: 1}, got.Meta.Pagination)
+	})
+
+	t.Run("data", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+			strings.NewReader(`{"url": "https://go.dev/blog/"}`))
+		r.Header.Set(contentType, applicationJSON)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		handler.PostShortenJSON(w, r)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var got map[string]any
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Contains(t, got, "data")
+		assert.NotContains(t, got, "error")
+		data, ok := got["data"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, []string{"result"}, keys(data), "success is told by the envelope")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "not a URL"}`))
+		r.Header.Set(contentType, applicationJSON)
+		w := httptest.NewRecorder()
+
+		handler.PostShortenJSON(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var got envelope.Response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Nil(t, got.Data)
+		require.NotNil(t, got.Error)
+		assert.Equal(t, "invalid_request", got.Error.Code)
+		assert.NotEmpty(t, got.Error.Message)
+	})
+}
+
+// keys returns the keys of the object.
+func keys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
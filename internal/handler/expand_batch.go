@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxExpandBatchSize is the maximum number of short URLs
+// expanded in a single request.
+const maxExpandBatchSize = 1000
+
+type expandBatchResponsePayload struct {
+	ShortURL    models.ShortURL    `json:"short_url"`
+	OriginalURL models.OriginalURL `json:"original_url"`
+}
+
+// PostExpandBatch handles requests to expand multiple short URLs in a single
+// request. Unknown short URLs and the ones not active, i.e. paused, blocked,
+// deleted or expired, are omitted from the response, the rest keep the order
+// of the request.
+//
+// Request:
+//
+//	POST /api/expand/batch
+//	Content-Type: application/json
+//
+//	[ "6qxTVvsy", "RTfd56hn", ... ]
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	[
+//		{
+//			"short_url": "6qxTVvsy",
+//			"original_url": "http://..."
+//		},
+//		...
+//	]
+func (h *Handler) PostExpandBatch(w http.ResponseWriter, r *http.Request) {
+	// check content type
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// decode the request body item by item
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	payload, err := decodeJSONArray[models.ShortURL](h, r)
+	if err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	if len(payload) > maxExpandBatchSize {
+		h.jsonError(w, r, fmt.Sprintf("no more than %d short URLs can be expanded at once",
+			maxExpandBatchSize), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	for _, shortURL := range payload {
+		if _, err := h.parseShortURL(string(shortURL)); err != nil {
+			h.jsonError(w, r, "invalid short URL", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// retrieve all the records in a single round trip
+	records, err := h.store.GetMany(r.Context(), payload)
+	if err != nil {
+		h.jsonError(w, r, "failed to retrieve from database", err, http.StatusInternalServerError)
+		return
+	}
+
+	found := make(map[models.ShortURL]*models.URL, len(records))
+	for _, record := range records {
+		found[record.ShortURL] = record
+	}
+
+	now := time.Now()
+	result := make([]expandBatchResponsePayload, 0, len(records))
+	for _, shortURL := range payload {
+		record, ok := found[shortURL]
+		if !ok || record.Lifecycle(now) != models.LinkActive {
+			continue
+		}
+		// skip repeated short URLs
+		delete(found, shortURL)
+		result = append(result, expandBatchResponsePayload{shortURL, record.OriginalURL})
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// encode the response body
+	if err = h.encodeData(w, r, result); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
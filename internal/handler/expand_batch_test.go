@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostExpandBatch(t *testing.T) {
+	path := "/api/expand/batch"
+
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{ShortURL: "YBbxJEcQ9vq", OriginalURL: "https://go.dev/", UserID: "test"},
+		{ShortURL: "TZqSKV4tcyE", OriginalURL: "https://e.mail.ru/inbox/", UserID: "test"},
+		{ShortURL: "Removed", OriginalURL: "https://deleted.com/", UserID: "test", IsDeleted: true},
+	}))
+
+	type want struct {
+		statusCode int
+		response   envelope.Error
+		result     []expandBatchResponsePayload
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		payload     string
+		store       repository.URLStorage
+		want        want
+	}{
+		{
+			name:        "positive test #1: request order is kept",
+			contentType: applicationJSON,
+			payload:     `["TZqSKV4tcyE","YBbxJEcQ9vq"]`,
+			store:       store,
+			want: want{
+				statusCode: http.StatusOK,
+				result: []expandBatchResponsePayload{
+					{"TZqSKV4tcyE", "https://e.mail.ru/inbox/"},
+					{"YBbxJEcQ9vq", "https://go.dev/"},
+				},
+			},
+		},
+		{
+			name:        "positive test #2: unknown, deleted and repeated are skipped",
+			contentType: applicationJSON,
+			payload:     `["Unknown","Removed","YBbxJEcQ9vq","YBbxJEcQ9vq"]`,
+			store:       store,
+			want: want{
+				statusCode: http.StatusOK,
+				result: []expandBatchResponsePayload{
+					{"YBbxJEcQ9vq", "https://go.dev/"},
+				},
+			},
+		},
+		{
+			name:        "invalid content-type",
+			contentType: textPlain,
+			payload:     `["YBbxJEcQ9vq"]`,
+			store:       store,
+			want: want{
+				statusCode: http.StatusBadRequest,
+				response: envelope.Error{
+					Code:    "invalid_request",
+					Message: fmt.Sprintf("unsupported content type %q", textPlain),
+				},
+			},
+		},
+		{
+			name:        "invalid short URL",
+			contentType: applicationJSON,
+			payload:     `["O0Il"]`,
+			store:       store,
+			want: want{
+				statusCode: http.StatusBadRequest,
+				response: envelope.Error{
+					Code:    "invalid_request",
+					Message: "invalid short URL",
+					Details: fmt.Sprintf("%s: %s: \"O0Il\" has unexpected characters",
+						errs.ErrInvalidRequest, models.ErrInvalidShortURL),
+				},
+			},
+		},
+		{
+			name:        "failed to retrieve from database",
+			contentType: applicationJSON,
+			payload:     `["YBbxJEcQ9vq"]`,
+			store:       &brokenStore{},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+				response:   envelope.Error{Code: "internal", Message: "failed to retrieve from database"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.payload))
+			r.Header.Set(contentType, tt.contentType)
+			w := httptest.NewRecorder()
+
+			l, _ := logger.NewForTest()
+			handler, err := New(tt.store, config.NewForTest(), l)
+			require.NoError(t, err, "new handler error")
+
+			handler.PostExpandBatch(w, r)
+
+			res := w.Result()
+			require.Equal(t, tt.want.statusCode, res.StatusCode)
+
+			if tt.want.result == nil {
+				assert.Equal(t, tt.want.response, getResponseErrorPayload(t, res))
+				return
+			}
+
+			var got []expandBatchResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.NoError(t, res.Body.Close(), "failed close body")
+			assert.Equal(t, tt.want.result, got)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"go.uber.org/zap"
+)
+
+// defaultReapInterval is used if the reap interval is not configured.
+const defaultReapInterval = time.Minute
+
+// expiresAt returns the expiration time of the URL shortened now with
+// the given TTL in seconds. Zero TTL means the URL never expires and nil
+// is returned. Negative TTL and TTL above the configured maximum are
+// invalid.
+func (h *Handler) expiresAt(ttl int64) (*time.Time, error) {
+	if ttl == 0 {
+		return nil, nil
+	}
+	if ttl < 0 || ttl > math.MaxInt64/int64(time.Second) {
+		return nil, fmt.Errorf("%w: TTL %d is out of range", errs.ErrInvalidRequest, ttl)
+	}
+
+	d := time.Duration(ttl) * time.Second
+	if limit := h.config.Expiration.MaxTTL; limit > 0 && d > limit {
+		return nil, fmt.Errorf("%w: TTL %s exceeds %s", errs.ErrInvalidRequest, d, limit)
+	}
+
+	at := time.Now().UTC().Add(d)
+	return &at, nil
+}
+
+// parseTTL returns the expiration time for the TTL in seconds
+// given as a string, e.g. in the query. Empty TTL means no expiration.
+func (h *Handler) parseTTL(ttl string) (*time.Time, error) {
+	if ttl == "" {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(ttl, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: TTL %q is not a number", errs.ErrInvalidRequest, ttl)
+	}
+	return h.expiresAt(seconds)
+}
+
+// reapExpiredURLs is a goroutine that periodically marks the expired URLs
+// as deleted. Expired URLs are not redirected even before they are reaped,
+// so the interval only affects how soon they disappear from the listings.
+// It stops when the handler is stopped.
+func (h *Handler) reapExpiredURLs() {
+	interval := h.config.Expiration.ReapInterval
+	if interval <= 0 {
+		interval = defaultReapInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			_ = h.reap()
+		}
+	}
+}
+
+// reap marks the URLs expired by now as deleted.
+// It logs and returns the error encountered.
+func (h *Handler) reap() error {
+	n, err := h.store.DeleteExpired(context.TODO(), time.Now().UTC())
+	if err != nil {
+		h.logger.Error("failed to delete expired URLs", zap.Error(err))
+		return err
+	}
+	if n > 0 {
+		h.logger.Info("expired URLs deleted", zap.Int("num", n))
+	}
+	return nil
+}
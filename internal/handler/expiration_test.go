@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiresAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     int64
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "no expiration", ttl: 0},
+		{name: "one minute", ttl: 60, want: time.Minute},
+		{name: "max TTL", ttl: 3600, want: time.Hour},
+		{name: "negative", ttl: -1, wantErr: true},
+		{name: "above max TTL", ttl: 3601, wantErr: true},
+		{name: "overflow", ttl: 1 << 62, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			c := config.NewForTest()
+			c.Expiration.MaxTTL = time.Hour
+
+			handler, err := New(memstore.NewURLRepository(), c, l)
+			require.NoError(t, err, "new handler error")
+
+			before := time.Now().UTC()
+			got, err := handler.expiresAt(tt.ttl)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == 0 {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.WithinDuration(t, before.Add(tt.want), *got, time.Second)
+		})
+	}
+}
+
+func TestShortenJSON_TTL(t *testing.T) {
+	store := memstore.NewURLRepository()
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	shorten := func(payload string) *http.Response {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(payload))
+		r.Header.Set(contentType, applicationJSON)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+		handler.PostShortenJSON(w, r)
+		return w.Result()
+	}
+
+	res := shorten(`{"url":"https://go.dev/","ttl":-5}`)
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = shorten(`{"url":"https://go.dev/","ttl":60}`)
+	response := getShortenJSONResponsePayload(t, res)
+	require.NoError(t, res.Body.Close(), "failed close body")
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	record, err := store.Get(context.TODO(), models.ShortURL(getShortURL(response.Result)))
+	require.NoError(t, err)
+	require.NotNil(t, record.ExpiresAt, "expiration time is not set")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *record.ExpiresAt, time.Second)
+}
+
+func TestGetRedirect_Expired(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+		ExpiresAt:   &past,
+	})
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", "YBbxJEcQ9vq")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.GetRedirect(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+}
+
+func TestReap(t *testing.T) {
+	past, future := time.Now().Add(-time.Second), time.Now().Add(time.Hour)
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Expired", ExpiresAt: &past},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Alive", ExpiresAt: &future},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Forever"},
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	require.NoError(t, handler.reap())
+
+	for code, deleted := range map[models.ShortURL]bool{"Expired": true, "Alive": false, "Forever": false} {
+		record, err := store.Get(context.TODO(), code)
+		require.NoError(t, err)
+		assert.Equal(t, deleted, record.IsDeleted, code)
+	}
+
+	assert.Error(t, (&Handler{store: &brokenStore{}, logger: l}).reap())
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/archive"
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// GetExport streams the archive of the whole instance: all the URL records
+// of all the users, including the deleted ones, and the settings they
+// depend on. The archive is restored with 'shortenerctl import' into
+// an instance with any storage backend. See the archive package for
+// the format.
+//
+// Request:
+//
+//	GET /api/admin/export
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/gzip
+//	Content-Disposition: attachment; filename="shortener-20240601T120000Z.tar.gz"
+//
+//	<tar.gz archive>
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	if h.scanner == nil {
+		h.jsonError(w, r, "export is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	// the records are scanned before the archive is written,
+	// so the failed scan is reported with the status code
+	sw := &startedWriter{ResponseWriter: w}
+	sw.Header().Set("Content-Type", "application/gzip")
+	sw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		"shortener-"+time.Now().UTC().Format("20060102T150405Z")+".tar.gz"))
+
+	manifest, err := archive.Write(r.Context(), sw, h.scanner,
+		archive.NewSettings(h.config), h.serviceVersion)
+	if err != nil {
+		if !sw.started {
+			sw.Header().Del("Content-Disposition")
+			h.jsonError(w, r, "failed to export", err, http.StatusInternalServerError)
+			return
+		}
+		h.logger.Errorf("failed to write export: %s", err)
+		return
+	}
+
+	h.logger.Infof("exported %d urls of %d users", manifest.URLs, manifest.Users)
+}
+
+// startedWriter records whether the response body has been started.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// Write writes the response body.
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
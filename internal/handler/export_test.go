@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/archive"
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetExport(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		models.NewRecord("YBbxJEcQ9vq", "https://go.dev/", user.NewID()),
+		models.NewRecord("Vp3Vf5tXoSK", "https://go.dev/doc/", user.NewID()),
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithExport(store, "v1.2.3"))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/export", http.NoBody)
+	w := httptest.NewRecorder()
+
+	handler.GetExport(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	ar, err := archive.Open(w.Body)
+	require.NoError(t, err)
+	defer ar.Close()
+	assert.Equal(t, 2, ar.Manifest.URLs)
+	assert.Equal(t, "v1.2.3", ar.Manifest.ServiceVersion)
+	assert.Equal(t, config.UserIDFormatUUID, ar.Settings.UserIDFormat)
+}
+
+func TestGetExport_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/export", http.NoBody)
+	w := httptest.NewRecorder()
+
+	handler.GetExport(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
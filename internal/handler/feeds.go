@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/feeds"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/asaskevich/govalidator"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCampaignLength is the maximum length of the campaign name.
+const maxCampaignLength = 255
+
+type feedRequestPayload struct {
+	URL      string `json:"url"`
+	Campaign string `json:"campaign"`
+}
+
+// PostFeed subscribes the user to RSS or Atom feed. The feed is polled
+// right away and then on schedule, the link of every new item is shortened
+// on behalf of the user. The short URLs of the items are returned by GetFeed.
+//
+// Request:
+//
+//	POST /api/user/feeds
+//	Content-Type: application/json
+//	{ "url": "https://go.dev/blog/feed.atom", "campaign": "go-blog" }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"id": "5d1c7f0e-...",
+//		"url": "https://go.dev/blog/feed.atom",
+//		"campaign": "go-blog",
+//		"items": [],
+//		"created_at": "2024-06-01T12:00:00Z"
+//	}
+func (h *Handler) PostFeed(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.feedsUser(w, r)
+	if !ok {
+		return
+	}
+
+	var payload feedRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	u, err := url.Parse(payload.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !govalidator.IsURL(payload.URL) {
+		h.jsonError(w, r, "invalid feed URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if len(payload.Campaign) > maxCampaignLength {
+		h.jsonError(w, r, fmt.Sprintf("campaign is longer than %d characters", maxCampaignLength),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	feed, err := h.feeds.Create(user.ID, payload.URL, payload.Campaign)
+	if err != nil {
+		if errors.Is(err, feeds.ErrTooMany) {
+			h.jsonError(w, r, "failed to create feed", err, http.StatusConflict)
+			return
+		}
+		h.jsonError(w, r, "failed to create feed", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeFeed(w, r, http.StatusCreated, feed)
+}
+
+// GetFeeds returns all the feeds of the user with the short URLs of their items.
+//
+// Request:
+//
+//	GET /api/user/feeds
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[ { "id": "5d1c7f0e-...", "url": "https://go.dev/blog/feed.atom", ... } ]
+func (h *Handler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.feedsUser(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeFeed(w, r, http.StatusOK, h.feeds.List(user.ID))
+}
+
+// GetFeed returns the feed with the short URLs of its items, latest last.
+//
+// Request:
+//
+//	GET /api/user/feeds/{id}
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"id": "5d1c7f0e-...",
+//		"url": "https://go.dev/blog/feed.atom",
+//		"campaign": "go-blog",
+//		"items": [
+//			{
+//				"id": "tag:blog.golang.org,2013:blog.golang.org/go1.22",
+//				"title": "Go 1.22 is released!",
+//				"link": "https://go.dev/blog/go1.22",
+//				"published_at": "2024-02-06T00:00:00Z",
+//				"short_url": "http://localhost:8080/YBbxJEcQ9vq",
+//				"shortened_at": "2024-06-01T12:00:01Z"
+//			}
+//		],
+//		"last_polled_at": "2024-06-01T12:00:01Z",
+//		"created_at": "2024-06-01T12:00:00Z"
+//	}
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.feedsUser(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := h.feeds.Get(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		h.feedError(w, r, "failed to get feed", err)
+		return
+	}
+
+	h.writeFeed(w, r, http.StatusOK, feed)
+}
+
+// DeleteFeed unsubscribes the user from the feed.
+// The short URLs of its items are kept.
+//
+// Request:
+//
+//	DELETE /api/user/feeds/{id}
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.feedsUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.feeds.Delete(chi.URLParam(r, "id"), user.ID); err != nil {
+		h.feedError(w, r, "failed to delete feed", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shortenFeedItem shortens the link of the feed item on behalf
+// of the owner of the feed. Links that were already shortened
+// get the existing short URL.
+func (h *Handler) shortenFeedItem(ctx context.Context, feed feeds.Feed, item feeds.Item) (string, error) {
+	if !govalidator.IsURL(item.Link) {
+		return "", fmt.Errorf("%w: invalid link", errs.ErrInvalidRequest)
+	}
+	if err := h.checkHost(ctx, item.Link); err != nil {
+		return "", err
+	}
+
+	record := models.NewRecord("", item.Link, feed.UserID)
+	record.Metadata = models.Metadata{Origin: models.OriginFeed}
+	err := h.saveGenerated(ctx, record)
+	if err != nil && !errors.Is(err, errs.ErrConflict) {
+		return "", fmt.Errorf("save url: %w", err)
+	}
+	if err == nil {
+		h.publishEvents(events.TypeCreated, record)
+	}
+
+	return h.absoluteURL(record.Domain, record.ShortURL), nil
+}
+
+// feedsUser checks that feeds are enabled and returns the user
+// of the request. It writes the error response otherwise.
+func (h *Handler) feedsUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	if h.feeds == nil {
+		h.jsonError(w, r, "feeds are not supported", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return nil, false
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// feedError writes the error response of the feeds manager.
+func (h *Handler) feedError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	if errors.Is(err, errs.ErrNotFound) {
+		h.jsonError(w, r, message, err, http.StatusNotFound)
+		return
+	}
+	h.jsonError(w, r, message, err, http.StatusInternalServerError)
+}
+
+// writeFeed writes the feed or the feeds as the JSON response
+// in the naming of the request.
+func (h *Handler) writeFeed(w http.ResponseWriter, r *http.Request, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := h.encodeData(w, r, v); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
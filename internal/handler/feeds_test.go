@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/feeds"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeds(t *testing.T) {
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<rss version="2.0"><channel>
+			<item><title>Second</title><link>https://example.com/2</link></item>
+			<item><title>First</title><link>https://example.com/1</link></item>
+		</channel></rss>`)
+	}))
+	defer feedServer.Close()
+
+	store := memstore.NewURLRepository()
+	cfg := config.NewForTest()
+	cfg.Feeds.PollInterval = time.Hour
+	cfg.Feeds.MaxPerUser = 10
+	cfg.Feeds.MaxSize = 1 << 20
+	cfg.Feeds.AllowPrivate = true
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/api/user/feeds", handler.PostFeed)
+	router.Get("/api/user/feeds", handler.GetFeeds)
+	router.Get("/api/user/feeds/{id}", handler.GetFeed)
+	router.Delete("/api/user/feeds/{id}", handler.DeleteFeed)
+
+	do := func(userID user.ID, method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: userID}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("owner", http.MethodPost, "/api/user/feeds", `{"url":"ftp://example.com/feed"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "not an HTTP feed")
+
+	w = do("owner", http.MethodPost, "/api/user/feeds",
+		fmt.Sprintf(`{"url":%q,"campaign":"spring"}`, feedServer.URL))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created feeds.Feed
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "spring", created.Campaign)
+
+	path := "/api/user/feeds/" + created.ID
+	var got feeds.Feed
+	require.Eventually(t, func() bool {
+		w = do("owner", http.MethodGet, path, "")
+		return w.Code == http.StatusOK &&
+			json.NewDecoder(w.Body).Decode(&got) == nil && got.LastPolledAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Empty(t, got.Error)
+	require.Len(t, got.Items, 2)
+	assert.Equal(t, "https://example.com/1", got.Items[0].Link)
+	for _, it := range got.Items {
+		assert.True(t, strings.HasPrefix(it.ShortURL, "http://"+cfg.HTTPServer.ReturnAddress.String()+"/"),
+			it.ShortURL)
+	}
+
+	all, err := store.GetAllByUserID(context.TODO(), "owner")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	for _, u := range all {
+		assert.Equal(t, models.OriginFeed, u.Metadata.Origin)
+	}
+
+	w = do("owner", http.MethodGet, "/api/user/feeds", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []feeds.Feed
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Len(t, list, 1)
+
+	w = do("intruder", http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "feed of another user")
+
+	w = do("owner", http.MethodDelete, path, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do("owner", http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFeeds_NotSupported(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/user/feeds", http.NoBody)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	handler.GetFeeds(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// fieldsParam is the query parameter selecting the returned fields,
+// e.g. ?fields=short_url,original_url.
+const fieldsParam = "fields"
+
+// parseFields parses the comma separated fields of the JSON objects
+// of the given type selected by the request. It returns nil if all the
+// fields are requested. Unknown fields are invalid. The fields may be
+// named in camelCase, they are returned in snake_case.
+func parseFields(r *http.Request, typ reflect.Type) ([]string, error) {
+	param := r.URL.Query().Get(fieldsParam)
+	if param == "" {
+		return nil, nil
+	}
+
+	known := jsonFields(typ)
+	fields := strings.Split(param, ",")
+	for i, f := range fields {
+		f = camelToSnake(strings.TrimSpace(f))
+		if _, ok := known[f]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", errs.ErrInvalidRequest, f)
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// jsonFields returns the JSON names of the struct fields.
+func jsonFields(typ reflect.Type) map[string]struct{} {
+	fields := make(map[string]struct{}, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// selectFields returns the JSON object or array of objects keeping
+// only the given fields. Nil fields keep all of them.
+func selectFields(v any, fields []string) (any, error) {
+	if fields == nil {
+		return v, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := func(object map[string]json.RawMessage) map[string]json.RawMessage {
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if value, ok := object[f]; ok {
+				selected[f] = value
+			}
+		}
+		return selected
+	}
+
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		var objects []map[string]json.RawMessage
+		if err = json.Unmarshal(b, &objects); err != nil {
+			return nil, err
+		}
+		for i := range objects {
+			objects[i] = keep(objects[i])
+		}
+		return objects, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err = json.Unmarshal(b, &object); err != nil {
+		return nil, err
+	}
+	return keep(object), nil
+}
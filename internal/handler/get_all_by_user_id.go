@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/models"
@@ -12,15 +12,31 @@ import (
 )
 
 type getAllByUserIDResponsePayload struct {
-	ShortURL    models.ShortURL    `json:"short_url"`
-	OriginalURL models.OriginalURL `json:"original_url"`
+	ShortURL       models.ShortURL    `json:"short_url"`
+	OriginalURL    models.OriginalURL `json:"original_url"`
+	LastAccessedAt *time.Time         `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty"`
+	RedirectLimit  int                `json:"redirect_limit,omitempty"`
+	Note           string             `json:"note,omitempty"`
+	State          models.LinkState   `json:"state"`
+	Metadata       *models.Metadata   `json:"metadata,omitempty"`
+	Version        int                `json:"version"`
+	// Shared is set for the read-only URLs shared with the user
+	// by their owners.
+	Shared bool `json:"shared,omitempty"`
 }
 
 // GetAllByUserID returns shortened and original URLs for a given user ID.
+// The fields query parameter selects the returned fields.
+// The q query parameter selects the URLs with the notes containing it.
+// The limit and offset query parameters select the page of the URLs.
+// The URLs shared with the user by other users follow the owned ones.
+// The short URLs are of the domains they were created on.
+// The state is the one of the link at the time of the request.
 //
 // Request:
 //
-//	GET /api/user/urls
+//	GET /api/user/urls?fields=short_url,original_url&q=spring&limit=20&offset=40
 //
 // Response:
 //
@@ -30,7 +46,17 @@ type getAllByUserIDResponsePayload struct {
 //	[
 //		{
 //		    "short_url": "http://config.AddrToReturn/Base58",
-//		    "original_url": "http://..."
+//		    "original_url": "http://...",
+//		    "last_accessed_at": "2024-06-01T12:00:00Z",
+//		    "expires_at": "2024-07-01T12:00:00Z",
+//		    "note": "Spring campaign",
+//		    "state": "active",
+//		    "metadata": {
+//		        "creator_ip": "192.0.2.1",
+//		        "user_agent": "curl/8.5.0",
+//		        "origin": "api"
+//		    },
+//		    "version": 0
 //		},
 //		...
 //	]
@@ -43,34 +69,79 @@ func (h *Handler) GetAllByUserID(w http.ResponseWriter, r *http.Request) {
 
 	// check request method
 	if r.Method != http.MethodGet {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	// Extract the user ID from the request context.
 	user, ok := user.FromContext(r.Context())
 	if !ok {
-		h.textError(w, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 
-	URLs, err := h.store.GetAllByUserID(r.Context(), user.ID)
+	fields, err := parseFields(r, reflect.TypeOf(getAllByUserIDResponsePayload{}))
 	if err != nil {
-		if errors.Is(err, errs.ErrNotFound) {
-			h.textError(w, "nothing found", err, http.StatusNoContent)
+		h.jsonError(w, r, "invalid fields", err, http.StatusBadRequest)
+		return
+	}
+
+	URLs, err := h.store.GetAllByUserID(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		h.jsonError(w, r, "failed to get URLs", err, http.StatusInternalServerError)
+		return
+	}
+
+	// the URLs shared with the user follow the owned ones
+	shared := make(map[models.ShortURL]struct{})
+	if h.shares != nil {
+		sharedURLs, sharedErr := h.shares.GetSharedURLs(r.Context(), user.ID)
+		if sharedErr != nil {
+			h.jsonError(w, r, "failed to get shared URLs", sharedErr, http.StatusInternalServerError)
 			return
 		}
-		h.textError(w, "failed to get URLs", err, http.StatusInternalServerError)
+		for _, u := range sharedURLs {
+			shared[u.ShortURL] = struct{}{}
+		}
+		URLs = append(URLs, sharedURLs...)
+	}
+	if err != nil && len(URLs) == 0 {
+		h.jsonError(w, r, "nothing found", err, http.StatusNoContent)
+		return
+	}
+
+	URLs = filterByNote(URLs, r.URL.Query().Get(noteQueryParam))
+	URLs, page, err := paginate(r, URLs)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
 		return
 	}
 
 	response := make([]getAllByUserIDResponsePayload, len(URLs))
+	now := time.Now()
 	for i, u := range URLs {
-		su := fmt.Sprintf("http://%s/%s",
-			h.config.HTTPServer.ReturnAddress, u.ShortURL)
-		response[i].ShortURL = models.ShortURL(su)
+		response[i].ShortURL = models.ShortURL(h.absoluteURL(u.Domain, u.ShortURL))
 		response[i].OriginalURL = u.OriginalURL
+		response[i].LastAccessedAt = u.LastAccessedAt
+		response[i].ExpiresAt = u.ExpiresAt
+		response[i].RedirectLimit = u.RedirectLimit
+		response[i].Note = u.Note
+		response[i].State = u.Lifecycle(now)
+		response[i].Version = u.Version
+		if _, ok := shared[u.ShortURL]; ok {
+			// the details of the creation are kept private to the owner
+			response[i].Shared = true
+			continue
+		}
+		if u.Metadata != (models.Metadata{}) {
+			response[i].Metadata = &u.Metadata
+		}
+	}
+
+	selected, err := selectFields(response, fields)
+	if err != nil {
+		h.jsonError(w, r, "failed to select fields", err, http.StatusInternalServerError)
+		return
 	}
 
 	// set the response header content type
@@ -78,7 +149,7 @@ func (h *Handler) GetAllByUserID(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	// encode response body
-	if err = json.NewEncoder(w).Encode(response); err != nil {
+	if err = h.encodePage(w, r, selected, page); err != nil {
 		h.logger.Errorf("failed to encode response: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
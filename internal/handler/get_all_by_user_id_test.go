@@ -9,7 +9,7 @@ import (
 	"testing"
 
 	"github.com/KretovDmitry/shortener/internal/config"
-	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
@@ -52,13 +52,11 @@ func TestGetAllByUserID_Method(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-			assert.Equal(t, textPlain, res.Header.Get(contentType))
-			assert.Equal(t, fmt.Sprintf("%s: %s",
-				errs.ErrInvalidRequest, tt.method), response)
+			assert.Equal(t, envelope.Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("method %s is not allowed", tt.method),
+			}, getResponseErrorPayload(t, res))
 		})
 	}
 }
@@ -80,13 +78,10 @@ func TestGetAllByUserID_WithoutUserInContext(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed close body")
-
 	assert.Equal(t, http.StatusUnauthorized, res.StatusCode,
 		"status code mismatch")
-	assert.Equal(t, fmt.Sprintf("%s: no user found", errs.ErrUnauthorized),
-		response, "response message mismatch")
+	assert.Equal(t, envelope.Error{Code: "unauthorized", Message: "no user found"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
 
 func TestGetAllByUserID_NoData(t *testing.T) {
@@ -108,17 +103,14 @@ func TestGetAllByUserID_NoData(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed close body")
-
 	assert.Equal(t, http.StatusNoContent, res.StatusCode)
-	assert.Equal(t, textPlain, res.Header.Get(contentType))
-	assert.Equal(t, fmt.Sprintf("%s: nothing found", errs.ErrNotFound), response)
+	assert.Equal(t, envelope.Error{Code: "not_found", Message: "nothing found"},
+		getResponseErrorPayload(t, res))
 }
 
 func TestGetAllByUserID_Data(t *testing.T) {
 	path := "/api/user/urls"
-	userID := "test"
+	userID := user.ID("test")
 	data := []*models.URL{
 		{
 			ID:          "some id 1",
@@ -168,6 +160,61 @@ func TestGetAllByUserID_Data(t *testing.T) {
 	assert.Equal(t, len(all), len(response), "response mismatch")
 }
 
+func TestGetAllByUserID_Fields(t *testing.T) {
+	mocks := memstore.NewURLRepository()
+	require.NoError(t, mocks.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+	}), "save failed")
+
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		want       []map[string]any
+	}{
+		{
+			name:       "selected fields",
+			query:      "?fields=short_url,%20original_url",
+			statusCode: http.StatusOK,
+			want: []map[string]any{{
+				"short_url":    "http://0.0.0.0:8080/YBbxJEcQ9vq",
+				"original_url": "https://go.dev",
+			}},
+		},
+		{
+			name:       "unknown field",
+			query:      "?fields=short_url,created_at",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls"+tt.query, http.NoBody)
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+			w := httptest.NewRecorder()
+
+			l, _ := logger.NewForTest()
+			handler, err := New(mocks, config.NewForTest(), l)
+			require.NoError(t, err, "new handler error")
+
+			handler.GetAllByUserID(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.want == nil {
+				return
+			}
+			var got []map[string]any
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func decodeAllByUserIDResponsePayload(
 	t *testing.T, r *http.Response,
 ) []getAllByUserIDResponsePayload {
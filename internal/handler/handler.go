@@ -4,17 +4,36 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/KretovDmitry/shortener/internal/certs"
+	"github.com/KretovDmitry/shortener/internal/clickexport"
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/dnscache"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/feeds"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
+	"github.com/KretovDmitry/shortener/internal/i18n"
+	"github.com/KretovDmitry/shortener/internal/imports"
+	"github.com/KretovDmitry/shortener/internal/jwt"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/middleware"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/oauth"
+	"github.com/KretovDmitry/shortener/internal/qrexport"
+	"github.com/KretovDmitry/shortener/internal/ratelimit"
 	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/shorturl"
+	"github.com/KretovDmitry/shortener/internal/slo"
 	"github.com/KretovDmitry/shortener/pkg/accesslog"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -26,18 +45,371 @@ import (
 type Handler struct {
 	// store is the database URL storage.
 	store repository.URLStorage
+	// reservations is the reserved short codes storage.
+	// Reservations are disabled if it is nil.
+	reservations repository.ReservationStorage
+	// clicks is the redirects storage used for the click analytics.
+	// Click analytics is disabled if it is nil.
+	clicks repository.ClickStorage
+	// exporter forwards the clicks to the external analytics.
+	// Clicks are not exported if it is nil.
+	exporter *clickexport.Dispatcher
+	// events publishes the events of the URLs to the brokers.
+	// Events are not published if it is nil.
+	events *events.Bus
+	// deletions is the durable queue of the scheduled deletions.
+	// Scheduled deletions are lost on crash if it is nil.
+	deletions repository.DeletionQueue
+	// apiKeys is the API keys storage.
+	// API keys are disabled if it is nil.
+	apiKeys repository.APIKeyStorage
+	// idempotency stores the responses replayed to the retries.
+	// The Idempotency-Key header is ignored if it is nil.
+	idempotency repository.IdempotencyStorage
+	// redirectLimits stores the per-link redirect limits set by the owners.
+	// Redirect limits can't be set if it is nil.
+	redirectLimits repository.RedirectLimitStorage
+	// notes stores the notes the owners annotate their links with.
+	// Notes can't be edited if it is nil.
+	notes repository.NoteStorage
+	// revocations stores the revoked tokens. Refresh tokens
+	// and logout are disabled if it is nil.
+	revocations repository.RevocationStorage
+	// moderation stores the banned domains and finds the blocked links.
+	// Moderation is disabled if it is nil.
+	moderation repository.ModerationStorage
+	// updates stores the edits the owners make to their links.
+	// Links can't be edited if it is nil.
+	updates repository.URLUpdateStorage
+	// shares stores the owners of the links and the users they are
+	// shared with. Links can't be transferred or shared if it is nil.
+	shares repository.ShareStorage
+	// userScanner streams the URLs of the users.
+	// The export of the URLs of the user is disabled if it is nil.
+	userScanner repository.UserURLScanner
+	// saveResults saves the imported URLs reporting the outcome of every URL.
+	// The import of the uploaded files of the URLs is disabled if it is nil.
+	saveResults repository.SaveResultStorage
+	// originals looks the URLs up by their original URLs, so that
+	// the conflicting shortenings report the short URL already saved.
+	// The short URL generated for the request is reported if it is nil.
+	originals repository.OriginalURLStorage
+	// accounts stores the accounts the users register and log in with.
+	// Accounts are disabled if it is nil.
+	accounts repository.AccountStorage
+	// linkStates stores the states the owners pause and resume
+	// their links with. Links can't be paused if it is nil.
+	linkStates repository.LinkStateStorage
+	// campaigns stores the campaigns the owners group their links with.
+	// Campaigns are disabled if it is nil.
+	campaigns repository.CampaignStorage
+	// stats counts the URLs and the users of the service.
+	// Stats are disabled if it is nil.
+	stats repository.StatsRepository
+	// extendedStats aggregates the top links and the active users
+	// of the stats. The stats are not extended if it is nil.
+	extendedStats repository.ExtendedStatsStorage
+	// trends stores the daily creation rollups of the admin dashboard.
+	// Trends are disabled if it is nil.
+	trends repository.TrendStorage
+	// scanner enumerates all the URL records for the instance export.
+	// Export is disabled if it is nil.
+	scanner repository.URLScanner
+	// codes generates the short URLs.
+	codes *shorturl.Generator
+	// hosts decides which destination hosts can be shortened.
+	hosts *hostpolicy.Policy
+	// serviceVersion is recorded in the exported archives.
+	serviceVersion string
+	// limiter keeps the rate limits of the shorten endpoints
+	// and the redirect limits of the links.
+	limiter ratelimit.Limiter
 	// application configuration.
 	config *config.Config
 	// logger is the application logger.
 	logger logger.Logger
-	// deleteURLsChan is a channel for sending deleted URLs to be flushed from the database.
+	// i18n is the catalog of the user facing messages.
+	i18n *i18n.Catalog
+	// imports is the bulk imports manager.
+	// Imports are disabled if it is nil.
+	imports *imports.Manager
+	// qrExports is the bulk QR code exports manager.
+	// QR exports are disabled if it is nil.
+	qrExports *qrexport.Manager
+	// oauth is the OAuth authorization server.
+	// OAuth is disabled if it is nil.
+	oauth *oauth.Server
+	// feeds is the RSS and Atom feeds manager.
+	// Feeds are disabled if it is nil.
+	feeds *feeds.Manager
+	// deleteURLsChan is the queue of the deleted URLs
+	// to be flushed from the database by the workers.
 	deleteURLsChan chan *models.URL
+	// accessedURLsChan is a channel for sending redirected short URLs
+	// to update their last access time in the database.
+	accessedURLsChan chan accessedURL
 	// wg is a wait group used to manage the goroutine that flushes deleted URLs.
 	wg *sync.WaitGroup
 	// done is a channel used to signal the stop of the handler.
 	done chan struct{}
-	// bufLen is the buffer length for storing deleted URLs before flushing them to the database.
-	bufLen int
+	// stopOnce closes done once.
+	stopOnce sync.Once
+	// stopCtx bounds the work left on stop, e.g. the final flush
+	// of the deletions. It is set before done is closed.
+	stopCtx context.Context
+	// base is the base URL of the short URLs of the default domain.
+	base *url.URL
+	// shortURLs parses the short URL codes accepted from the clients.
+	shortURLs models.ShortURLParser
+	// batcher sizes the batches of the deleted URLs flushed to the database.
+	batcher *deleteBatcher
+	// certificates reports the served TLS certificates.
+	// Certificate health is not reported if it is nil.
+	certificates certs.Source
+	// resolver caches the hostnames resolved by the outbound requests.
+	resolver *dnscache.Resolver
+	// pool reports the stats of the database connections.
+	// Pool metrics are disabled if it is nil.
+	pool repository.PoolStatsStorage
+	// slo tracks the availability and the redirect latency.
+	slo *slo.Tracker
+}
+
+// accessedURLsBufLen is the capacity of the last access updates channel.
+// Updates are dropped when it is full, so that redirects never block.
+const accessedURLsBufLen = 1024
+
+// maxPendingAccesses is the maximum number of the last access times
+// and of the clicks kept for the retry while the database is unavailable.
+// The oldest ones are dropped beyond it, so that an outage doesn't
+// exhaust the memory.
+const maxPendingAccesses = 100_000
+
+// accessedURL is a single redirect event.
+type accessedURL struct {
+	shortURL   models.ShortURL
+	accessedAt time.Time
+	// click is the redirect details, nil if click analytics is disabled.
+	click *models.Click
+}
+
+// Option configures optional dependencies of the handler.
+type Option func(*Handler)
+
+// WithReservations enables short code reservations backed by the given storage.
+func WithReservations(reservations repository.ReservationStorage) Option {
+	return func(h *Handler) {
+		h.reservations = reservations
+	}
+}
+
+// WithClicks enables the click analytics backed by the given storage.
+func WithClicks(clicks repository.ClickStorage) Option {
+	return func(h *Handler) {
+		h.clicks = clicks
+	}
+}
+
+// WithClickExporter forwards the clicks to the external analytics
+// with the given dispatcher. The handler runs it until stopped.
+func WithClickExporter(exporter *clickexport.Dispatcher) Option {
+	return func(h *Handler) {
+		h.exporter = exporter
+	}
+}
+
+// WithEvents publishes the lifecycle and redirect events of the URLs
+// with the given bus. The handler runs it until stopped.
+func WithEvents(bus *events.Bus) Option {
+	return func(h *Handler) {
+		h.events = bus
+	}
+}
+
+// WithDeletionQueue persists the scheduled deletions in the given queue
+// before they are accepted, so that they are applied after the restart.
+func WithDeletionQueue(deletions repository.DeletionQueue) Option {
+	return func(h *Handler) {
+		h.deletions = deletions
+	}
+}
+
+// WithAPIKeys enables the API key authentication backed by the given storage.
+func WithAPIKeys(apiKeys repository.APIKeyStorage) Option {
+	return func(h *Handler) {
+		h.apiKeys = apiKeys
+	}
+}
+
+// WithIdempotency enables the Idempotency-Key header of the shorten
+// requests with the responses stored in the given storage.
+func WithIdempotency(idempotency repository.IdempotencyStorage) Option {
+	return func(h *Handler) {
+		h.idempotency = idempotency
+	}
+}
+
+// WithRedirectLimits lets the owners cap the redirects of their links
+// with the limits stored in the given storage.
+func WithRedirectLimits(limits repository.RedirectLimitStorage) Option {
+	return func(h *Handler) {
+		h.redirectLimits = limits
+	}
+}
+
+// WithNotes lets the owners edit the notes of their links
+// stored in the given storage.
+func WithNotes(notes repository.NoteStorage) Option {
+	return func(h *Handler) {
+		h.notes = notes
+	}
+}
+
+// WithRevocations rejects the tokens revoked in the given storage
+// and lets the users refresh their tokens and log out.
+func WithRevocations(revocations repository.RevocationStorage) Option {
+	return func(h *Handler) {
+		h.revocations = revocations
+	}
+}
+
+// WithModeration lets the administrators ban the destination domains
+// and review the blocked links stored in the given storage.
+func WithModeration(moderation repository.ModerationStorage) Option {
+	return func(h *Handler) {
+		h.moderation = moderation
+	}
+}
+
+// WithURLUpdates lets the owners change the destinations
+// and the expirations of their links stored in the given storage.
+func WithURLUpdates(updates repository.URLUpdateStorage) Option {
+	return func(h *Handler) {
+		h.updates = updates
+	}
+}
+
+// WithShares lets the owners transfer their links to other users
+// or share the read access to them, as stored in the given storage.
+func WithShares(shares repository.ShareStorage) Option {
+	return func(h *Handler) {
+		h.shares = shares
+	}
+}
+
+// WithUserExport lets the users export their URLs streamed
+// from the given storage.
+func WithUserExport(scanner repository.UserURLScanner) Option {
+	return func(h *Handler) {
+		h.userScanner = scanner
+	}
+}
+
+// WithUserImport lets the users import the URLs of the uploaded files
+// saved to the given storage.
+func WithUserImport(results repository.SaveResultStorage) Option {
+	return func(h *Handler) {
+		h.saveResults = results
+	}
+}
+
+// WithOriginalURLs lets the conflicting shortenings report the short URL
+// the original URL is already saved under, looked up in the given storage.
+func WithOriginalURLs(originals repository.OriginalURLStorage) Option {
+	return func(h *Handler) {
+		h.originals = originals
+	}
+}
+
+// WithAccounts lets the users register and log in with the login
+// and the password of the accounts stored in the given storage.
+func WithAccounts(accounts repository.AccountStorage) Option {
+	return func(h *Handler) {
+		h.accounts = accounts
+	}
+}
+
+// WithLinkStates lets the owners pause and resume their links
+// with the states stored in the given storage.
+func WithLinkStates(states repository.LinkStateStorage) Option {
+	return func(h *Handler) {
+		h.linkStates = states
+	}
+}
+
+// WithCampaigns lets the owners group their links in the campaigns
+// stored in the given storage.
+func WithCampaigns(campaigns repository.CampaignStorage) Option {
+	return func(h *Handler) {
+		h.campaigns = campaigns
+	}
+}
+
+// WithPoolStats enables the metrics of the pool
+// of the database connections.
+func WithPoolStats(pool repository.PoolStatsStorage) Option {
+	return func(h *Handler) {
+		h.pool = pool
+	}
+}
+
+// WithStats enables the numbers of the URLs and the users
+// counted by the given storage.
+func WithStats(stats repository.StatsRepository) Option {
+	return func(h *Handler) {
+		h.stats = stats
+	}
+}
+
+// WithExtendedStats extends the stats with the most clicked links
+// and the active users aggregated by the given storage.
+func WithExtendedStats(stats repository.ExtendedStatsStorage) Option {
+	return func(h *Handler) {
+		h.extendedStats = stats
+	}
+}
+
+// WithTrends enables the daily creation trends
+// read from the given storage.
+func WithTrends(trends repository.TrendStorage) Option {
+	return func(h *Handler) {
+		h.trends = trends
+	}
+}
+
+// WithExport enables the export of the whole instance with the records
+// enumerated by the given scanner. The service version is recorded
+// in the archives.
+func WithExport(scanner repository.URLScanner, serviceVersion string) Option {
+	return func(h *Handler) {
+		h.scanner = scanner
+		h.serviceVersion = serviceVersion
+	}
+}
+
+// WithRateLimiter keeps the rate limits in the given limiter,
+// e.g. shared between the instances. Limits are kept in memory by default.
+func WithRateLimiter(limiter ratelimit.Limiter) Option {
+	return func(h *Handler) {
+		h.limiter = limiter
+	}
+}
+
+// WithCertificates reports the expiry of the TLS certificates
+// of the given source and warns ahead of it.
+func WithCertificates(certificates certs.Source) Option {
+	return func(h *Handler) {
+		h.certificates = certificates
+	}
+}
+
+// WithResolver resolves the hostnames of the outbound requests, e.g.
+// of the feeds, with the given resolver, so that its cache is shared
+// with the other subsystems. The handler creates its own by default.
+func WithResolver(resolver *dnscache.Resolver) Option {
+	return func(h *Handler) {
+		h.resolver = resolver
+	}
 }
 
 // New constructs a new handler, ensuring that the dependencies are valid values.
@@ -45,6 +417,7 @@ func New(
 	store repository.URLStorage,
 	config *config.Config,
 	logger logger.Logger,
+	opts ...Option,
 ) (*Handler, error) {
 	if config == nil {
 		return nil, fmt.Errorf("%w: config", errs.ErrNilDependency)
@@ -52,34 +425,182 @@ func New(
 	if config.DeleteBufLen <= 0 {
 		return nil, errors.New("buffer length should be >= 1")
 	}
+	if d := config.Deletion; d.MinBatchSize < 0 || d.MaxBatchSize < 0 || d.MaxDelay < 0 {
+		return nil, errors.New("deletion batch sizes and delay should be >= 0")
+	}
+	if d := config.Deletion; d.Workers < 0 || d.QueueLength < 0 {
+		return nil, errors.New("deletion workers and queue length should be >= 0")
+	}
+	deleteQueueLen := config.Deletion.QueueLength
+	if deleteQueueLen == 0 {
+		deleteQueueLen = defaultDeleteQueueLen
+	}
+
+	catalog, err := i18n.New()
+	if err != nil {
+		return nil, fmt.Errorf("load translations: %w", err)
+	}
 
 	h := &Handler{
-		store:          store,
-		config:         config,
-		logger:         logger,
-		deleteURLsChan: make(chan *models.URL),
-		wg:             &sync.WaitGroup{},
-		done:           make(chan struct{}),
-		bufLen:         config.DeleteBufLen,
+		store:            store,
+		config:           config,
+		logger:           logger,
+		i18n:             catalog,
+		deleteURLsChan:   make(chan *models.URL, deleteQueueLen),
+		accessedURLsChan: make(chan accessedURL, accessedURLsBufLen),
+		wg:               &sync.WaitGroup{},
+		done:             make(chan struct{}),
+		batcher: newDeleteBatcher(config.Deletion.MinBatchSize, config.Deletion.MaxBatchSize,
+			config.Deletion.MaxDelay, config.Deletion.Workers),
+		limiter: ratelimit.NewMemory(),
+		slo: slo.New(slo.Objectives{
+			Availability:          config.SLO.Availability,
+			RedirectLatency:       config.SLO.RedirectLatency,
+			RedirectLatencyTarget: config.SLO.RedirectLatencyTarget,
+		}),
+	}
+
+	h.codes, err = shorturl.New(
+		shorturl.WithLength(config.ShortURL.Length),
+		shorturl.WithAlphabet(config.ShortURL.Alphabet),
+		shorturl.WithHash(config.ShortURL.Hash),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("new short URL generator: %w", err)
+	}
+
+	h.base, err = newBaseURL(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	h.shortURLs = models.ShortURLParser{
+		MaxLength: config.ShortURL.MaxLength,
+		Alphabets: []string{shorturl.Base58, config.ShortURL.Alphabet},
+	}
+
+	h.hosts, err = hostpolicy.New(config.Hosts.Allow, config.Hosts.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("new destination host policy: %w", err)
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.resolver == nil {
+		h.resolver = dnscache.New(nil, config.DNSCache.TTL,
+			config.DNSCache.NegativeTTL, config.DNSCache.Size)
+	}
+
+	if config.Imports.Dir != "" {
+		h.imports, err = imports.NewManager(config.Imports.Dir, config.Imports.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("new imports manager: %w", err)
+		}
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.imports.Run(h.done, h.processImport)
+		}()
+	}
+
+	if config.QRExport.Dir != "" {
+		h.qrExports, err = qrexport.NewManager(config.QRExport.Dir, config.QRExport.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("new QR exports manager: %w", err)
+		}
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.qrExports.Run(h.done)
+		}()
+	}
+
+	if len(config.OAuth.Clients) > 0 {
+		h.oauth = oauth.NewServer(config.OAuth.Clients)
 	}
 
-	h.wg.Add(1)
+	if config.Feeds.PollInterval > 0 {
+		h.feeds = feeds.NewManager(config.Feeds.MaxPerUser, config.Feeds.MaxSize,
+			config.Feeds.AllowPrivate, h.resolver)
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.feeds.Run(h.done, config.Feeds.PollInterval, h.shortenFeedItem)
+		}()
+	}
+
+	if h.certificates != nil {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.watchCertificates()
+		}()
+	}
+
+	if h.exporter != nil {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.exporter.Run(h.done)
+		}()
+	}
+
+	if h.events != nil {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.events.Run(h.done)
+		}()
+	}
+
+	// the first worker flushes the deletions left by the previous run
+	pending := h.pendingDeletions()
+	for i := 0; i < h.batcher.workers; i++ {
+		h.wg.Add(1)
+		go func(pending []*models.URL) {
+			defer h.wg.Done()
+			h.flushDeletedURLs(pending)
+		}(pending)
+		pending = nil
+	}
+
+	h.wg.Add(2)
 	go func() {
 		defer h.wg.Done()
-		h.flushDeletedURLs()
+		h.flushAccessedURLs()
+	}()
+	go func() {
+		defer h.wg.Done()
+		h.reapExpiredURLs()
 	}()
 
 	return h, nil
 }
 
-// Stop stops the handler and waits for all goroutines to finish.
-// It sends a close signal to the done channel and then waits for the
-// WaitGroup to finish. If the shutdown timeout is exceeded, it logs an error.
-// It is safe for concurrent use.
+// Stop stops the handler and waits for all goroutines to finish
+// within the shutdown timeout, see Shutdown. It logs an error
+// if the timeout is exceeded.
 func (h *Handler) Stop() {
-	sync.OnceFunc(func() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := h.Shutdown(ctx); err != nil {
+		h.logger.Errorf("handler stop: %s", err)
+	}
+}
+
+// Shutdown stops the handler and waits for all goroutines to finish
+// until the context is done. The deletions still buffered are flushed
+// with the context, so that a stuck database can't hang the shutdown;
+// the ones not flushed in time are applied after the restart if the
+// deletion queue is configured. It is safe for concurrent use.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.stopOnce.Do(func() {
+		h.stopCtx = ctx
 		close(h.done)
-	})()
+	})
 
 	ready := make(chan struct{})
 	go func() {
@@ -88,105 +609,579 @@ func (h *Handler) Stop() {
 	}()
 
 	select {
-	case <-time.After(h.config.HTTPServer.ShutdownTimeout):
-		h.logger.Error("handler stop: shutdown timeout exceeded")
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown timeout exceeded: %w", ctx.Err())
 	case <-ready:
-		return
+		return nil
 	}
 }
 
 // Register sets up the routes for the HTTP server.
 func (h *Handler) Register(r chi.Router, config *config.Config, logger logger.Logger) chi.Router {
-	r.Use(accesslog.Handler(logger))
+	r.Use(accesslog.Handler(logger,
+		accesslog.WithSampling(config.Logger.AccessLogSampleRate)))
+	r.Use(middleware.Latency(config, logger))
+	r.Use(h.measureSLO)
+	r.Use(middleware.CORS(config))
 	r.Use(gzip.DefaultHandler().WrapHandler)
-	r.Use(middleware.Unzip(logger))
+	r.Use(middleware.Unzip(config, logger))
+	if h.apiKeys != nil {
+		r.Use(middleware.APIKey(config, h.apiKeys, logger))
+	}
 	r.Use(middleware.Authorization(config, logger))
+	if h.revocations != nil {
+		r.Use(middleware.NotRevoked(config, h.revocations, logger))
+	}
+	r.Use(middleware.ReadYourWrites(config, logger))
 	r.Use(chimiddleware.Recoverer)
+	r.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		h.methodNotAllowed(w, req, allowedMethods(r, req.URL.Path))
+	})
 
-	r.Post("/", h.PostShortenText)
-	r.Post("/api/shorten", h.PostShortenJSON)
-	r.Post("/api/shorten/batch", h.PostShortenBatch)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.OnlyAllowedWriters(config, logger))
+		r.Use(middleware.RequireScope(config, user.ScopeShorten, logger))
+		r.Use(middleware.RateLimit(config, logger, h.limiter))
+		r.Post("/", h.PostShortenText)
+		r.With(h.idempotent).Post("/api/shorten", h.PostShortenJSON)
+		r.With(h.idempotent).Post("/api/shorten/batch", h.PostShortenBatch)
+	})
+	r.Post("/api/expand/batch", h.PostExpandBatch)
 
 	r.Get("/ping", h.GetPingDB)
+	r.Get("/healthz", h.GetHealthz)
+	r.Get("/readyz", h.GetReadyz)
+	r.Get("/api/docs", h.GetDocs)
+	r.Get(openAPIPath, h.GetOpenAPI)
 	r.Get("/{shortURL}", h.GetRedirect)
 
-	r.Delete("/api/user/urls", h.DeleteURLs)
+	r.With(middleware.OnlyAllowedWriters(config, logger), middleware.RequireScope(config, user.ScopeDelete, logger)).
+		Delete("/api/user/urls", h.DeleteURLs)
+	r.With(middleware.OnlyAllowedWriters(config, logger), middleware.RequireScope(config, user.ScopeDelete, logger)).
+		Delete("/api/user/urls/by-original", h.DeleteURLsByOriginal)
+
+	// the scoped tokens can't authorize other clients
+	r.With(middleware.OnlyWithToken(config, logger), middleware.RequireScope(config, "", logger)).
+		Get("/oauth/authorize", h.GetAuthorize)
+	r.Post("/oauth/token", h.PostToken)
+
+	r.Route("/api/auth", func(r chi.Router) {
+		r.Use(middleware.RateLimit(config, logger, h.limiter))
+		// the scoped tokens can't be turned into the registered ones
+		r.With(middleware.RequireScope(config, "", logger)).
+			Post("/register", h.PostRegister)
+		r.Post("/login", h.PostLogin)
+		r.Post("/refresh", h.PostRefresh)
+		r.With(middleware.OnlyWithToken(config, logger)).
+			Post("/logout", h.PostLogout)
+	})
 
 	r.Route("/api/user", func(r chi.Router) {
 		r.Use(middleware.OnlyWithToken(config, logger))
-		r.Get("/urls", h.GetAllByUserID)
+		r.Use(h.timezone)
+		r.With(middleware.RequireScope(config, user.ScopeRead, logger)).
+			Get("/urls", h.GetAllByUserID)
+		r.With(middleware.RequireScope(config, user.ScopeRead, logger)).
+			Get("/urls/export", h.GetUserURLsExport)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Patch("/urls/{shortURL}", h.PatchURL)
+		r.With(middleware.RequireScope(config, user.ScopeStats, logger)).
+			Get("/urls/{shortURL}/stats", h.GetURLStats)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Put("/urls/{shortURL}/redirect-limit", h.PutRedirectLimit)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Put("/urls/{shortURL}/note", h.PutNote)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Post("/urls/{shortURL}/pause", h.PostPause)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Post("/urls/{shortURL}/resume", h.PostResume)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Post("/urls/{shortURL}/transfer", h.PostTransfer)
+		r.With(middleware.RequireScope(config, user.ScopeRead, logger)).
+			Get("/urls/{shortURL}/shares", h.GetShares)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Put("/urls/{shortURL}/shares/{userID}", h.PutShare)
+		r.With(middleware.RequireScope(config, user.ScopeWrite, logger)).
+			Delete("/urls/{shortURL}/shares/{userID}", h.DeleteShare)
+
+		r.With(middleware.RequireScope(config, user.ScopeRead, logger)).
+			Get("/campaigns/{campaign}/urls", h.GetCampaignURLs)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(config, user.ScopeWrite, logger))
+			r.Post("/campaigns/{campaign}/urls", h.PostCampaignURLs)
+			r.Post("/campaigns/{campaign}/copy", h.PostCampaignCopy)
+			r.Post("/campaigns/{campaign}/move", h.PostCampaignMove)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(config, user.ScopeRead, logger))
+			r.Post("/qr-exports", h.PostQRExport)
+			r.Get("/qr-exports/{id}", h.GetQRExport)
+			r.Get("/qr-exports/{id}/download", h.GetQRExportDownload)
+		})
+
+		// the scoped tokens manage the keys within their scopes
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(config, user.ScopeAdmin, logger))
+			r.Post("/keys", h.PostAPIKey)
+			r.Get("/keys", h.GetAPIKeys)
+			r.Post("/keys/{id}/rotate", h.PostAPIKeyRotate)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.OnlyAllowedWriters(config, logger))
+			r.Use(middleware.RequireScope(config, user.ScopeShorten, logger))
+
+			r.Post("/urls/import", h.PostUserURLsImport)
+
+			r.Post("/imports", h.PostImport)
+			r.Get("/imports/{id}", h.GetImport)
+			r.Patch("/imports/{id}", h.PatchImport)
+			r.Post("/imports/{id}/complete", h.PostImportComplete)
+
+			r.Post("/feeds", h.PostFeed)
+			r.Get("/feeds", h.GetFeeds)
+			r.Get("/feeds/{id}", h.GetFeed)
+			r.Delete("/feeds/{id}", h.DeleteFeed)
+		})
 	})
 
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.TrustedSubnetOrRole(config, user.RoleAdmin, logger))
+			r.Post("/reservations", h.PostReservations)
+			r.Get("/urls/{shortURL}", h.GetURLDetails)
+			r.Get("/export", h.GetExport)
+		})
+
+		// the tokens are minted from the trusted subnet only,
+		// so that a leaked admin token can't mint new ones, and
+		// the admin ones by the admins only, see PostServiceToken
+		r.With(middleware.OnlyTrustedSubnet(config, logger)).
+			Post("/tokens", h.PostServiceToken)
+
+		// the moderators are told by the role of their tokens
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.OnlyWithToken(config, logger))
+			r.Use(middleware.RequireRole(config, user.RoleAdmin, logger))
+			r.Get("/users/{userID}/urls", h.GetAnyUserURLs)
+			r.Delete("/urls/{shortURL}", h.DeleteAnyURL)
+			r.Post("/domain-bans", h.PostDomainBan)
+			r.Get("/domain-bans", h.GetDomainBans)
+			r.Get("/flagged", h.GetFlaggedURLs)
+		})
+	})
+
+	r.Route("/api/internal", func(r chi.Router) {
+		r.Use(middleware.TrustedSubnetOrRole(config, user.RoleService, logger))
+		r.Get("/stats", h.GetStats)
+		r.Get("/stats/trends", h.GetStatsTrends)
+		r.Get("/deletions/metrics", h.GetDeletionMetrics)
+		r.Get("/dns/metrics", h.GetDNSMetrics)
+		r.Get("/slo", h.GetSLO)
+		r.Get("/db/metrics", h.GetPoolMetrics)
+		r.Get("/tls/certificates", h.GetCertificates)
+	})
+
+	if config.Pprof.Enabled {
+		r.With(middleware.OnlyTrustedSubnet(config, logger)).
+			Mount("/debug", chimiddleware.Profiler())
+	}
+
 	return r
 }
 
-// flushDeletedURLs is a goroutine that periodically flushes the deleted URLs
-// from the buffer to the database. It uses a ticker to trigger the flush
-// operation every 10 seconds. If the channel for sending deleted URLs is closed,
-// the goroutine stops.
+// flushDeletedURLs is a worker that takes the deleted URLs from the queue
+// and flushes them from its buffer to the database in batches. The batch
+// is flushed once it reaches the target size of the batcher or once its
+// oldest URL has waited for the maximum delay. The failed batch is retried
+// on the next delay only, so that the unavailable database is not hammered.
+// When the handler stops, the queue is drained, the buffer is flushed and
+// the worker returns. The given pending deletions are flushed first.
 // It is safe for concurrent use.
-func (h *Handler) flushDeletedURLs() {
-	ticker := time.NewTicker(10 * time.Second)
-	URLs := make([]*models.URL, 0, h.bufLen)
+func (h *Handler) flushDeletedURLs(pending []*models.URL) {
+	URLs := append(make([]*models.URL, 0, h.config.Live().DeleteBufLen), pending...)
+	h.batcher.recovered(len(pending))
+
+	// deadline fires when the oldest URL in the buffer has waited
+	// for the maximum delay. It is stopped while the buffer is empty.
+	deadline := time.NewTimer(h.batcher.maxDelay)
+	if len(URLs) == 0 {
+		stopTimer(deadline)
+	}
+	// failed disables the size trigger until the next deadline.
+	failed := false
+
+	flush := func() {
+		start := time.Now()
+		err := h.flush(context.Background(), URLs...)
+		h.batcher.flushed(len(URLs), time.Since(start), err)
+		stopTimer(deadline)
+		if err != nil {
+			failed = true
+			deadline.Reset(h.batcher.maxDelay)
+			return
+		}
+		failed = false
+		// reset buffer only when flush succeeded,
+		// its length may have been reloaded
+		URLs = make([]*models.URL, 0, h.config.Live().DeleteBufLen)
+	}
 
 	for {
 		select {
 		case url := <-h.deleteURLsChan:
+			if len(URLs) == 0 {
+				deadline.Reset(h.batcher.maxDelay)
+			}
 			URLs = append(URLs, url)
+			h.batcher.arrived(time.Now())
+			if !failed && len(URLs) >= h.batcher.target() {
+				flush()
+			}
 
 		case <-h.done:
+			stopTimer(deadline)
+			URLs = h.drainDeletions(URLs)
 			if len(URLs) == 0 {
 				return
 			}
-			_ = h.flush(URLs...)
+			_ = h.flush(h.stopCtx, URLs...)
 			return
 
-		case <-ticker.C:
+		case <-deadline.C:
 			if len(URLs) == 0 {
 				continue
 			}
-			if err := h.flush(URLs...); err != nil {
-				continue
-			}
-			// reset buffer only when flush succeeded
-			URLs = URLs[:0:h.bufLen]
+			flush()
 		}
 	}
 }
 
-// flush deletes the given URLs from the database.
+// drainDeletions appends the URLs left in the queue to the buffer.
+func (h *Handler) drainDeletions(URLs []*models.URL) []*models.URL {
+	for {
+		select {
+		case url := <-h.deleteURLsChan:
+			URLs = append(URLs, url)
+		default:
+			return URLs
+		}
+	}
+}
+
+// flush deletes the given URLs from the database within the context.
 // If an error occurs during the deletion process, it logs an error message
 // with the error details. It returns the error encountered during the deletion process.
-func (h *Handler) flush(URLs ...*models.URL) error {
+func (h *Handler) flush(ctx context.Context, URLs ...*models.URL) error {
 	if len(URLs) == 0 {
 		return nil
 	}
 
-	err := h.store.DeleteURLs(context.TODO(), URLs...)
+	err := h.store.DeleteURLs(ctx, URLs...)
 	if err != nil {
 		h.logger.Error("failed to delete URLs", zap.Error(err),
 			zap.Int("num", len(URLs)), zap.Any("urls", URLs))
+		return err
+	}
+	h.publishEvents(events.TypeDeleted, URLs...)
+
+	// failing to remove is not an error: deletions are safe to repeat
+	if h.deletions != nil {
+		if err = h.deletions.RemovePendingDeletions(ctx, URLs...); err != nil {
+			h.logger.Error("failed to remove pending deletions", zap.Error(err),
+				zap.Int("num", len(URLs)))
+		}
+	}
+
+	return nil
+}
+
+// pendingDeletions returns the deletions scheduled before the restart.
+func (h *Handler) pendingDeletions() []*models.URL {
+	if h.deletions == nil {
+		return nil
+	}
+
+	pending, err := h.deletions.GetPendingDeletions(context.TODO())
+	if err != nil {
+		h.logger.Error("failed to get pending deletions", zap.Error(err))
+		return nil
+	}
+	if len(pending) > 0 {
+		h.logger.Info("pending deletions recovered", zap.Int("num", len(pending)))
+	}
+
+	return pending
+}
+
+// recordAccess schedules the update of the short URL last access time,
+// the click recording if click analytics is enabled, its export and
+// the publishing of the redirect event. It never blocks: the update
+// is dropped if the buffer is full.
+func (h *Handler) recordAccess(r *http.Request, shortURL models.ShortURL) {
+	a := accessedURL{shortURL: shortURL, accessedAt: time.Now().UTC()}
+	if h.clicks != nil || h.exporter != nil || h.events != nil {
+		click := models.NewClick(shortURL, a.accessedAt,
+			requestMetadata(r, "").CreatorIP, r.Referer(), r.UserAgent())
+		if h.clicks != nil {
+			a.click = click
+		}
+		if h.exporter != nil {
+			h.exporter.Send(click)
+		}
+		if h.events != nil {
+			h.events.Publish(events.NewRedirect(click))
+		}
+	}
+
+	select {
+	case h.accessedURLsChan <- a:
+	default:
+		h.logger.Debug("last access buffer is full, update dropped")
+	}
+}
+
+// publishEvents schedules the publishing of the lifecycle events
+// of the URLs if the events are enabled. It never blocks.
+func (h *Handler) publishEvents(typ events.Type, urls ...*models.URL) {
+	if h.events == nil {
+		return
+	}
+	for _, u := range urls {
+		h.events.Publish(events.New(typ, u))
+	}
+}
+
+// flushAccessedURLs is a goroutine that periodically flushes last access
+// times and clicks of the redirected short URLs to the database. Repeated
+// redirects to the same short URL between flushes result in a single
+// last access update, while every click is saved. The failed flushes
+// are retried on the next tick, keeping the newest maxPendingAccesses
+// updates and clicks at most.
+// It is safe for concurrent use.
+func (h *Handler) flushAccessedURLs() {
+	ticker := time.NewTicker(10 * time.Second)
+	accessed := make(map[models.ShortURL]time.Time)
+	clicks := make([]*models.Click, 0)
+
+	// trim drops the oldest updates beyond the limit
+	trim := func(limit int) {
+		if n := trimAccessed(accessed, limit); n > 0 {
+			h.logger.Error("last access buffer overflow, oldest updates dropped", zap.Int("num", n))
+		}
+		var n int
+		if clicks, n = trimClicks(clicks, limit); n > 0 {
+			h.logger.Error("click buffer overflow, oldest clicks dropped", zap.Int("num", n))
+		}
+	}
+
+	add := func(a accessedURL) {
+		if a.accessedAt.After(accessed[a.shortURL]) {
+			accessed[a.shortURL] = a.accessedAt
+		}
+		if a.click != nil {
+			clicks = append(clicks, a.click)
+		}
+		// trimmed in batches between the flushes
+		if len(accessed) >= 2*maxPendingAccesses || len(clicks) >= 2*maxPendingAccesses {
+			trim(maxPendingAccesses)
+		}
+	}
+
+	for {
+		select {
+		case a := <-h.accessedURLsChan:
+			add(a)
+
+		case <-h.done:
+			// drain updates buffered before the stop
+			for len(h.accessedURLsChan) > 0 {
+				add(<-h.accessedURLsChan)
+			}
+			if len(accessed) > 0 {
+				_ = h.flushAccessed(accessed)
+			}
+			if len(clicks) > 0 {
+				_ = h.flushClicks(clicks)
+			}
+			return
+
+		case <-ticker.C:
+			// reset buffers only when flush succeeded
+			if len(accessed) > 0 && h.flushAccessed(accessed) == nil {
+				accessed = make(map[models.ShortURL]time.Time)
+			}
+			if len(clicks) > 0 && h.flushClicks(clicks) == nil {
+				clicks = clicks[:0]
+			}
+			// the failed ones wait for the next tick
+			trim(maxPendingAccesses)
+		}
+	}
+}
+
+// trimAccessed removes the oldest last access times beyond the limit
+// and returns the number of the removed ones.
+func trimAccessed(accessed map[models.ShortURL]time.Time, limit int) int {
+	n := len(accessed) - limit
+	if n <= 0 {
+		return 0
+	}
+	urls := make([]models.ShortURL, 0, len(accessed))
+	for u := range accessed {
+		urls = append(urls, u)
+	}
+	slices.SortFunc(urls, func(a, b models.ShortURL) int {
+		return accessed[a].Compare(accessed[b])
+	})
+	for _, u := range urls[:n] {
+		delete(accessed, u)
+	}
+	return n
+}
+
+// trimClicks removes the oldest clicks beyond the limit. It returns
+// the remaining clicks and the number of the removed ones.
+func trimClicks(clicks []*models.Click, limit int) ([]*models.Click, int) {
+	n := len(clicks) - limit
+	if n <= 0 {
+		return clicks, 0
+	}
+	kept := copy(clicks, clicks[n:])
+	// cleared, so that the removed clicks are collected
+	clear(clicks[kept:])
+	return clicks[:kept], n
+}
+
+// flushClicks saves the clicks of the redirected short URLs.
+// It logs and returns the error encountered while saving.
+func (h *Handler) flushClicks(clicks []*models.Click) error {
+	err := h.clicks.SaveClicks(context.TODO(), clicks...)
+	if err != nil {
+		h.logger.Error("failed to save clicks", zap.Error(err),
+			zap.Int("num", len(clicks)))
 	}
 
 	return err
 }
 
-// textError writes error response to the response writer in a text/plain format.
-func (h *Handler) textError(w http.ResponseWriter, message string, err error, code int) {
-	logger := h.logger.SkipCaller(1)
+// flushAccessed updates the last access time of the given short URLs.
+// It logs and returns the error encountered during the update.
+func (h *Handler) flushAccessed(accessed map[models.ShortURL]time.Time) error {
+	err := h.store.UpdateLastAccessed(context.TODO(), accessed)
+	if err != nil {
+		h.logger.Error("failed to update last access time", zap.Error(err),
+			zap.Int("num", len(accessed)))
+	}
+
+	return err
+}
+
+// jsonError writes the failure of the request as the JSON error
+// in the envelope if it is configured, see envelope.NewError.
+func (h *Handler) jsonError(w http.ResponseWriter, r *http.Request, message string, err error, code int) {
+	log := h.logger.SkipCaller(1)
 	if code >= http.StatusInternalServerError {
-		logger.Errorf("%s: %s", message, err)
+		log.Errorf("%s: %s", message, err)
 	} else {
-		logger.Infof("%s: %s", message, err)
+		log.Infof("%s: %s", message, err)
 	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	payload := envelope.NewError(message, err, code, logger.RequestIDFromContext(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	if _, err = fmt.Fprintf(w, "%s: %s", err, message); err != nil {
+	if err = h.encodeFailure(w, r, payload, payload); err != nil {
 		h.logger.Errorf("failed to write response: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// methodNotAllowed responds to the request with the method the endpoint
+// doesn't allow. Yandex Practicum requires 400 Bad Request, so 405 Method
+// Not Allowed with the allowed method in the Allow header is responded
+// with the strict HTTP semantics only.
+func (h *Handler) methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	code := http.StatusBadRequest
+	if h.config.StrictHTTPSemantics {
+		w.Header().Set("Allow", allowed)
+		code = http.StatusMethodNotAllowed
+	}
+	h.jsonError(w, r, fmt.Sprintf("method %s is not allowed", r.Method), errs.ErrInvalidRequest, code)
+}
+
+// allowedMethods returns the methods the routes allow for the path
+// in the format of the Allow header.
+func allowedMethods(routes chi.Routes, path string) string {
+	var allowed []string
+	for _, method := range []string{
+		http.MethodGet, http.MethodHead, http.MethodPost,
+		http.MethodPut, http.MethodPatch, http.MethodDelete,
+	} {
+		if routes.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return strings.Join(allowed, ", ")
+}
+
+// urlNotFound responds to the request for the URL the user doesn't have
+// with 404 Not Found. With the strict HTTP semantics, the deleted URLs
+// of the user respond with 410 Gone instead.
+func (h *Handler) urlNotFound(w http.ResponseWriter, r *http.Request, shortURL models.ShortURL, userID user.ID) {
+	if h.config.StrictHTTPSemantics {
+		record, err := h.store.Get(r.Context(), shortURL)
+		if err == nil && record.UserID == userID && record.IsDeleted {
+			h.urlGone(w, r)
+			return
+		}
+	}
+	h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusNotFound)
+}
+
+// urlGone responds to the request for the deleted URL with 410 Gone.
+func (h *Handler) urlGone(w http.ResponseWriter, r *http.Request) {
+	h.jsonError(w, r, "URL is deleted", errs.ErrNotFound, http.StatusGone)
+}
+
+// authCookie returns the "Authorization" cookie with the JWT token of the user.
+// The token keeps the type, the scopes and the role the user is authenticated with
+// and expires as configured for the type.
+func (h *Handler) authCookie(u *user.User) (*http.Cookie, error) {
+	tokenType := u.Token
+	if tokenType == "" {
+		tokenType = user.TokenAnonymous
+	}
+	exp := h.config.JWT.ExpirationOf(tokenType)
+
+	token, err := jwt.BuildUserJWTString(&user.User{ID: u.ID, Token: tokenType, Scopes: u.Scopes, Role: u.Role},
+		h.config.JWT.SigningKey, exp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Cookie{
+		Name:     "Authorization",
+		Value:    token,
+		Expires:  time.Now().Add(exp),
+		HttpOnly: true,
+	}, nil
+}
+
+// requestMetadata returns the metadata of the client that sent the request.
+// The client IP is taken from the "X-Real-IP" header set by the reverse
+// proxy, falling back to the remote address of the connection.
+func requestMetadata(r *http.Request, origin models.Origin) models.Metadata {
+	ip := r.Header.Get("X-Real-IP")
+	if net.ParseIP(ip) == nil {
+		ip = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return models.NewMetadata(ip, r.UserAgent(), origin)
+}
+
 // IsApplicationJSONContentType returns true if the content type of the
 // HTTP request is application/json.
 func (h *Handler) IsApplicationJSONContentType(r *http.Request) bool {
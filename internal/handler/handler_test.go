@@ -2,16 +2,22 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/KretovDmitry/shortener/internal/repository"
 	"github.com/KretovDmitry/shortener/internal/repository/memstore"
 	"github.com/stretchr/testify/assert"
@@ -43,7 +49,11 @@ func (s *brokenStore) Get(context.Context, models.ShortURL) (*models.URL, error)
 	return nil, errIntentionallyNotWorkingMethod
 }
 
-func (s *brokenStore) GetAllByUserID(context.Context, string) ([]*models.URL, error) {
+func (s *brokenStore) GetMany(context.Context, []models.ShortURL) ([]*models.URL, error) {
+	return nil, errIntentionallyNotWorkingMethod
+}
+
+func (s *brokenStore) GetAllByUserID(context.Context, user.ID) ([]*models.URL, error) {
 	return nil, errIntentionallyNotWorkingMethod
 }
 
@@ -51,6 +61,14 @@ func (s *brokenStore) DeleteURLs(context.Context, ...*models.URL) error {
 	return errIntentionallyNotWorkingMethod
 }
 
+func (s *brokenStore) UpdateLastAccessed(context.Context, map[models.ShortURL]time.Time) error {
+	return errIntentionallyNotWorkingMethod
+}
+
+func (s *brokenStore) DeleteExpired(context.Context, time.Time) (int, error) {
+	return 0, errIntentionallyNotWorkingMethod
+}
+
 func (s *brokenStore) Ping(context.Context) error {
 	return errIntentionallyNotWorkingMethod
 }
@@ -125,6 +143,64 @@ func TestIsTextPlainContentType(t *testing.T) {
 	}
 }
 
+func TestJSONError(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		err     error
+		code    int
+		want    envelope.Error
+	}{
+		{
+			name:    "common error",
+			message: "no such URL",
+			err:     errs.ErrNotFound,
+			code:    http.StatusNotFound,
+			want:    envelope.Error{Code: "not_found", Message: "no such URL"},
+		},
+		{
+			name:    "cause in details",
+			message: "invalid URL",
+			err:     fmt.Errorf("%w: no host", errs.ErrInvalidRequest),
+			code:    http.StatusBadRequest,
+			want:    envelope.Error{Code: "invalid_request", Message: "invalid URL", Details: "invalid request: no host"},
+		},
+		{
+			name:    "code of the status",
+			message: "stats are disabled",
+			err:     fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			code:    http.StatusNotImplemented,
+			want:    envelope.Error{Code: "not_implemented", Message: "stats are disabled"},
+		},
+		{
+			name:    "server error",
+			message: "failed to save to database",
+			err:     errIntentionallyNotWorkingMethod,
+			code:    http.StatusInternalServerError,
+			want:    envelope.Error{Code: "internal", Message: "failed to save to database"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set("X-Request-ID", "42b4cb1b-abf0-44e7-89f9-72ad3a277e0a")
+			r = r.WithContext(logger.WithRequest(r.Context(), r))
+			w := httptest.NewRecorder()
+
+			handler.jsonError(w, r, tt.message, tt.err, tt.code)
+
+			res := w.Result()
+			assert.Equal(t, tt.code, res.StatusCode)
+			tt.want.RequestID = "42b4cb1b-abf0-44e7-89f9-72ad3a277e0a"
+			assert.Equal(t, tt.want, getResponseErrorPayload(t, res))
+		})
+	}
+}
+
 func getResponseTextPayload(t *testing.T, res *http.Response) string {
 	resBody, err := io.ReadAll(res.Body)
 	require.NoError(t, res.Body.Close(), "failed close body")
@@ -132,6 +208,14 @@ func getResponseTextPayload(t *testing.T, res *http.Response) string {
 	return strings.TrimSpace(string(resBody))
 }
 
+func getResponseErrorPayload(t *testing.T, res *http.Response) envelope.Error {
+	var payload envelope.Error
+	require.Equal(t, "application/json", res.Header.Get(contentType))
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&payload))
+	require.NoError(t, res.Body.Close(), "failed close body")
+	return payload
+}
+
 func getShortURL(s string) string {
 	var res string
 	if strings.HasPrefix(s, "http") {
@@ -140,3 +224,25 @@ func getShortURL(s string) string {
 	}
 	return res
 }
+
+func TestTrimPendingAccesses(t *testing.T) {
+	now := time.Now()
+	accessed := map[models.ShortURL]time.Time{
+		"oldest": now.Add(-3 * time.Hour),
+		"older":  now.Add(-2 * time.Hour),
+		"old":    now.Add(-time.Hour),
+		"new":    now,
+	}
+	assert.Zero(t, trimAccessed(accessed, 4), "nothing is dropped within the limit")
+	assert.Equal(t, 2, trimAccessed(accessed, 2))
+	assert.Equal(t, map[models.ShortURL]time.Time{"old": now.Add(-time.Hour), "new": now}, accessed)
+
+	clicks := []*models.Click{{ShortURL: "a"}, {ShortURL: "b"}, {ShortURL: "c"}}
+	kept, n := trimClicks(clicks, 3)
+	assert.Zero(t, n, "nothing is dropped within the limit")
+	assert.Len(t, kept, 3)
+	kept, n = trimClicks(clicks, 1)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []*models.Click{{ShortURL: "c"}}, kept, "the newest clicks are kept")
+	assert.Nil(t, clicks[1], "the dropped clicks are released")
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// GetHealthz reports that the process is alive and serves requests.
+// It doesn't check the storage, so that the liveness probes don't restart
+// the instances while the database is down.
+//
+// Request:
+//
+//	GET /healthz
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: text/plain; charset=utf-8
+//	ok
+func (h *Handler) GetHealthz(w http.ResponseWriter, _ *http.Request) {
+	h.probeOK(w, "ok")
+}
+
+// GetReadyz reports whether the instance is ready to serve the traffic,
+// i.e. its storage is reachable. The in-memory and file storages are
+// always ready. While the database is down in the degraded mode the
+// instance keeps serving the redirects from the cache, so it is reported
+// as degraded but ready.
+//
+// The verbose query parameter adds the details of the served TLS
+// certificates, one per line. The expiring certificates don't fail
+// the probe, since restarting the instances doesn't renew them.
+//
+// Request:
+//
+//	GET /readyz?verbose
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: text/plain; charset=utf-8
+//	ok
+//	tls example.com: ok, expires 2024-09-01T12:00:00Z
+//
+//	HTTP/1.1 503 Service Unavailable
+func (h *Handler) GetReadyz(w http.ResponseWriter, r *http.Request) {
+	var status string
+	err := h.store.Ping(r.Context())
+	switch {
+	case err == nil, errors.Is(err, errs.ErrDBNotConnected):
+		status = "ok"
+	case h.degraded():
+		status = "degraded"
+	default:
+		h.jsonError(w, r, "storage is not ready", err, http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, verbose := r.URL.Query()["verbose"]; verbose && h.certificates != nil {
+		status += h.certificateDetails()
+	}
+	h.probeOK(w, status)
+}
+
+// degraded reports whether the storage serves possibly stale records
+// while the database is unavailable.
+func (h *Handler) degraded() bool {
+	d, ok := h.store.(degradable)
+	return ok && d.Degraded()
+}
+
+// probeOK responds to the probe with 200 OK and the status in the body.
+func (h *Handler) probeOK(w http.ResponseWriter, status string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, status); err != nil {
+		h.logger.Errorf("failed to write response: %s", err)
+	}
+}
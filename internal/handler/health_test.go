@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// downDegradedStore is the storage serving stale records
+// while the database is down.
+type downDegradedStore struct {
+	brokenStore
+}
+
+func (s *downDegradedStore) Degraded() bool { return true }
+
+func TestGetHealthz(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(&brokenStore{}, config.NewForTest(), l)
+	require.NoError(t, err, "failed to init new handler")
+
+	w := httptest.NewRecorder()
+	handler.GetHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, w.Code, "liveness must not depend on the storage")
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestGetReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      repository.URLStorage
+		statusCode int
+		response   string
+	}{
+		{
+			name:       "database is connected",
+			store:      &connectedStore{},
+			statusCode: http.StatusOK,
+			response:   "ok",
+		},
+		{
+			name:       "no database",
+			store:      memstore.NewURLRepository(),
+			statusCode: http.StatusOK,
+			response:   "ok",
+		},
+		{
+			name:       "degraded mode",
+			store:      &downDegradedStore{},
+			statusCode: http.StatusOK,
+			response:   "degraded",
+		},
+		{
+			name:       "database is down",
+			store:      &brokenStore{},
+			statusCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			handler, err := New(tt.store, config.NewForTest(), l)
+			require.NoError(t, err, "failed to init new handler")
+
+			w := httptest.NewRecorder()
+			handler.GetReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.response != "" {
+				assert.Equal(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
+)
+
+type hostBlockedResponsePayload struct {
+	// Error is the machine-readable reason: host_denied, host_not_allowed
+	// or host_banned.
+	Error   string `json:"error"`
+	Host    string `json:"host"`
+	Message string `json:"message"`
+	// CorrelationID is the batch item of the blocked URL.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// checkHost checks the destination host of the URL against the configured
+// host lists and the domains banned by the moderators. The URLs are not
+// blocked if the bans can't be retrieved, so that the storage failure
+// doesn't stop the shortening.
+func (h *Handler) checkHost(ctx context.Context, rawURL string) error {
+	if err := h.hosts.Check(rawURL); err != nil {
+		return err
+	}
+	if h.moderation == nil {
+		return nil
+	}
+
+	host := hostpolicy.Host(rawURL)
+	if host == "" {
+		return nil
+	}
+	if _, err := h.moderation.GetDomainBan(ctx, host); err != nil {
+		if !errors.Is(err, errs.ErrNotFound) {
+			h.logger.Errorf("retrieve domain ban of %s: %s", host, err)
+		}
+		return nil
+	}
+
+	return &hostpolicy.BlockedError{Host: host, Reason: hostpolicy.ReasonBanned}
+}
+
+// hostBlockedError writes 422 Unprocessable Entity with the reason
+// the destination host is blocked by the configured host lists
+// or banned by the moderators.
+//
+// Response:
+//
+//	HTTP/1.1 422 Unprocessable Entity
+//	Content-Type: application/json
+//	{
+//		"error": "host_denied",
+//		"host": "example.com",
+//		"message": "host is blocked: example.com is denied"
+//	}
+func (h *Handler) hostBlockedError(w http.ResponseWriter, r *http.Request, err error, correlationID string) {
+	h.logger.SkipCaller(1).Infof("destination host blocked: %s", err)
+
+	payload := hostBlockedResponsePayload{
+		Error:         hostpolicy.ReasonNotAllowed,
+		Message:       err.Error(),
+		CorrelationID: correlationID,
+	}
+	var blocked *hostpolicy.BlockedError
+	if errors.As(err, &blocked) {
+		payload.Error = blocked.Reason
+		payload.Host = blocked.Host
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if err = h.encodeData(w, r, payload); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShorten_BlockedHosts(t *testing.T) {
+	cfg := config.NewForTest()
+	cfg.Hosts.Allow = []string{"*.go.dev", "example.com"}
+	cfg.Hosts.Deny = []string{"evil.go.dev"}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), cfg, l)
+	require.NoError(t, err, "new handler error")
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		mediaType  string
+		body       string
+		statusCode int
+		want       hostBlockedResponsePayload
+	}{
+		{
+			name:       "text allowed",
+			handler:    handler.PostShortenText,
+			mediaType:  textPlain,
+			body:       "https://pkg.go.dev/",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "text denied",
+			handler:    handler.PostShortenText,
+			mediaType:  textPlain,
+			body:       "https://evil.go.dev/",
+			statusCode: http.StatusUnprocessableEntity,
+			want:       hostBlockedResponsePayload{Error: hostpolicy.ReasonDenied, Host: "evil.go.dev"},
+		},
+		{
+			name:       "JSON not allowed",
+			handler:    handler.PostShortenJSON,
+			mediaType:  applicationJSON,
+			body:       `{"url": "https://practicum.yandex.ru/"}`,
+			statusCode: http.StatusUnprocessableEntity,
+			want:       hostBlockedResponsePayload{Error: hostpolicy.ReasonNotAllowed, Host: "practicum.yandex.ru"},
+		},
+		{
+			name:       "JSON allowed",
+			handler:    handler.PostShortenJSON,
+			mediaType:  applicationJSON,
+			body:       `{"url": "https://example.com/"}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:      "batch with not allowed item",
+			handler:   handler.PostShortenBatch,
+			mediaType: applicationJSON,
+			body: `[{"correlation_id": "1", "original_url": "https://example.com/"},
+				{"correlation_id": "2", "original_url": "https://www.example.com/"}]`,
+			statusCode: http.StatusUnprocessableEntity,
+			want: hostBlockedResponsePayload{Error: hostpolicy.ReasonNotAllowed, Host: "www.example.com",
+				CorrelationID: "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set(contentType, tt.mediaType)
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, r)
+
+			res := w.Result()
+			defer func() { _ = res.Body.Close() }()
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusUnprocessableEntity {
+				return
+			}
+
+			assert.Equal(t, applicationJSON, res.Header.Get(contentType))
+			var got hostBlockedResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.NotEmpty(t, got.Message)
+			got.Message = ""
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
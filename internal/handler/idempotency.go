@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+const (
+	// idempotencyKey is the header of the key chosen by the client
+	// for the request and all its retries.
+	idempotencyKey = "Idempotency-Key"
+	// idempotentReplayed marks the replayed responses.
+	idempotentReplayed = "Idempotent-Replayed"
+)
+
+// idempotent is a middleware function that replays the stored response
+// to the retries of the request with the Idempotency-Key header, so that
+// the retried shorten requests don't end up with unexpected conflicts.
+// The keys are scoped to the user, so the retries must carry the cookie
+// issued by the first response. Reusing the key for another request is
+// rejected. Server errors and rate limited responses are not stored,
+// so that the retries are processed again.
+//
+// Requests without the header pass through as is, as well as all the
+// requests if the storage does not support the idempotency keys.
+func (h *Handler) idempotent(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKey)
+		if key == "" || h.idempotency == nil || h.config.Idempotency.TTL <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > models.MaxIdempotencyKeyLength {
+			h.jsonError(w, r, fmt.Sprintf("%s is longer than %d characters",
+				idempotencyKey, models.MaxIdempotencyKeyLength),
+				errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+
+		user, ok := user.FromContext(r.Context())
+		if !ok {
+			h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+
+		// the body is hashed and passed on
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.jsonError(w, r, "failed to read request", errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		if err = r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		stored, err := h.idempotency.GetIdempotentResponse(r.Context(), user.ID, key)
+		switch {
+		case err == nil:
+			if stored.RequestHash != hash {
+				h.jsonError(w, r, fmt.Sprintf("%s is already used for another request", idempotencyKey),
+					errs.ErrInvalidRequest, http.StatusUnprocessableEntity)
+				return
+			}
+			h.logger.Debugf("replay response to %s %q", idempotencyKey, key)
+			w.Header().Set("Content-Type", stored.ContentType)
+			w.Header().Set(idempotentReplayed, "true")
+			w.WriteHeader(stored.StatusCode)
+			if _, err = w.Write(stored.Body); err != nil {
+				h.logger.Errorf("failed to write response: %s", err)
+			}
+			return
+		case !errors.Is(err, errs.ErrNotFound):
+			h.jsonError(w, r, "failed to retrieve response", err, http.StatusInternalServerError)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		if rw.status >= http.StatusInternalServerError || rw.status == http.StatusTooManyRequests {
+			return
+		}
+
+		// the response is already sent, so the errors are only logged
+		err = h.idempotency.SaveIdempotentResponse(r.Context(), &models.IdempotentResponse{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: hash,
+			StatusCode:  rw.status,
+			ContentType: rw.Header().Get("Content-Type"),
+			Body:        rw.body.Bytes(),
+			ExpiresAt:   time.Now().Add(h.config.Idempotency.TTL),
+		})
+		if err != nil && !errors.Is(err, errs.ErrConflict) {
+			h.logger.Errorf("failed to save response to %s %q: %s", idempotencyKey, key, err)
+		}
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// requestHash returns the hash of everything the response depends on:
+// the method, the URL, the naming of the fields and the body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, s := range []string{
+		r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get(acceptProfile),
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter records the status code and the body of the response.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code and writes it.
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the body and writes it.
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
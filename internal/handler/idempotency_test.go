@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	store := memstore.NewURLRepository()
+	c := config.NewForTest()
+	c.Idempotency.TTL = time.Hour
+	l, _ := logger.NewForTest()
+	handler, err := New(store, c, l, WithIdempotency(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	token, err := jwt.BuildJWTString(user.NewID(), user.TokenAnonymous, c.JWT.SigningKey, c.JWT.Expiration)
+	require.NoError(t, err)
+
+	serve := func(path, body, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+		if key != "" {
+			r.Header.Set(idempotencyKey, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// the retry gets the response to the first request, not the conflict
+	first := serve("/api/shorten", `{"url":"https://go.dev/"}`, "retry-1")
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	assert.Empty(t, first.Header().Get(idempotentReplayed))
+
+	retry := serve("/api/shorten", `{"url":"https://go.dev/"}`, "retry-1")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayed))
+	assert.Equal(t, first.Header().Get(contentType), retry.Header().Get(contentType))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	// without the key the same request is processed again
+	w := serve("/api/shorten", `{"url":"https://go.dev/"}`, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// the key can't be reused for another request
+	w = serve("/api/shorten", `{"url":"https://pkg.go.dev/"}`, "retry-1")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// batches are replayed as well
+	batch := `[{"correlation_id":"1","original_url":"https://go.dev/blog/"}]`
+	first = serve("/api/shorten/batch", batch, "retry-2")
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	retry = serve("/api/shorten/batch", batch, "retry-2")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	w = serve("/api/shorten", `{"url":"https://go.dev/doc/"}`, strings.Repeat("k", 256))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/imports"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/asaskevich/govalidator"
+	"github.com/go-chi/chi/v5"
+)
+
+// importBatchSize is the number of URLs saved at once during the import.
+const importBatchSize = 500
+
+type importResponsePayload struct {
+	imports.Import
+	UploadURL string `json:"upload_url,omitempty"`
+}
+
+// PostImport starts a bulk import of URLs. The response contains the URL
+// the file should be uploaded to in chunks with PatchImport. The file is
+// plain text with one URL per line.
+//
+// Request:
+//
+//	POST /api/user/imports
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"id": "0b0ef7a1-...",
+//		"status": "uploading",
+//		"upload_url": "http://config.AddrToReturn/api/user/imports/0b0ef7a1-...",
+//		...
+//	}
+func (h *Handler) PostImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.importsUser(w, r)
+	if !ok {
+		return
+	}
+
+	imp, err := h.imports.Create(user.ID)
+	if err != nil {
+		h.jsonError(w, r, "failed to create import", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeImport(w, r, http.StatusCreated, imp)
+}
+
+// GetImport returns the status of the import.
+//
+// Request:
+//
+//	GET /api/user/imports/{id}
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"id": "0b0ef7a1-...",
+//		"status": "done",
+//		"size": 1048576,
+//		"imported": 20000,
+//		"failed": 12,
+//		...
+//	}
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.importsUser(w, r)
+	if !ok {
+		return
+	}
+
+	imp, err := h.imports.Get(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		h.importError(w, r, "failed to get import", err)
+		return
+	}
+
+	h.writeImport(w, r, http.StatusOK, imp)
+}
+
+// PatchImport appends the chunk of the file to the import. The Upload-Offset
+// header must be equal to the number of bytes uploaded so far, which is
+// returned in the same header of the response. A chunk that failed can be
+// retried with the same offset.
+//
+// Request:
+//
+//	PATCH /api/user/imports/{id}
+//	Upload-Offset: 0
+//
+//	https://example.com/1
+//	https://example.com/2
+//	...
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+//	Upload-Offset: 44
+func (h *Handler) PatchImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.importsUser(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		h.jsonError(w, r, "invalid Upload-Offset header", errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	defer func() {
+		if err = r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+
+	size, err := h.imports.Append(chi.URLParam(r, "id"), user.ID, offset, r.Body)
+	if err != nil {
+		if errors.Is(err, imports.ErrTooLarge) {
+			h.jsonError(w, r, "failed to upload", err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.importError(w, r, "failed to upload", err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostImportComplete finishes the upload and schedules the import.
+// Its progress can be polled with GetImport.
+//
+// Request:
+//
+//	POST /api/user/imports/{id}/complete
+//
+// Response:
+//
+//	HTTP/1.1 202 Accepted
+//	Content-Type: application/json
+//	{ "id": "0b0ef7a1-...", "status": "queued", ... }
+func (h *Handler) PostImportComplete(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.importsUser(w, r)
+	if !ok {
+		return
+	}
+
+	imp, err := h.imports.Complete(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		h.importError(w, r, "failed to complete import", err)
+		return
+	}
+
+	h.writeImport(w, r, http.StatusAccepted, imp)
+}
+
+// processImport imports the URLs read line by line from the file.
+// Empty lines are skipped, invalid URLs are counted as failed.
+// URLs that were already shortened are counted as imported.
+func (h *Handler) processImport(
+	ctx context.Context, imp imports.Import, file io.Reader, progress func(imported, failed int),
+) error {
+	metadata := models.Metadata{Origin: models.OriginImport}
+	batch := make([]*models.URL, 0, importBatchSize)
+	failed := 0
+
+	flush := func() error {
+		imported, err := h.saveImported(ctx, batch)
+		if err != nil {
+			return err
+		}
+		progress(imported, failed+len(batch)-imported)
+		batch, failed = batch[:0], 0
+		return nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		originalURL := strings.TrimSpace(scanner.Text())
+		if originalURL == "" {
+			continue
+		}
+		if !govalidator.IsURL(originalURL) || h.checkHost(ctx, originalURL) != nil {
+			failed++
+			continue
+		}
+
+		shortURL, err := h.generateShortURL(ctx, originalURL)
+		if err != nil {
+			return fmt.Errorf("generate short URL: %w", err)
+		}
+		record := models.NewRecord(shortURL, originalURL, imp.UserID)
+		record.Metadata = metadata
+		batch = append(batch, record)
+
+		if len(batch) == importBatchSize {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	return flush()
+}
+
+// saveImported saves the batch and returns the number of saved URLs.
+// If the batch conflicts with the existing URLs, they are saved one by one.
+func (h *Handler) saveImported(ctx context.Context, batch []*models.URL) (int, error) {
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	err := h.store.SaveAll(ctx, batch)
+	if err == nil {
+		h.publishEvents(events.TypeCreated, batch...)
+		return len(batch), nil
+	}
+	if !errors.Is(err, errs.ErrConflict) && !errors.Is(err, errs.ErrInvalidRequest) {
+		return 0, fmt.Errorf("save urls: %w", err)
+	}
+
+	imported := 0
+	for _, record := range batch {
+		err = h.store.Save(ctx, record)
+		switch {
+		case err == nil:
+			h.publishEvents(events.TypeCreated, record)
+			imported++
+		case errors.Is(err, errs.ErrConflict):
+			imported++
+		case errors.Is(err, errs.ErrInvalidRequest):
+			continue
+		default:
+			return 0, fmt.Errorf("save url: %w", err)
+		}
+	}
+
+	return imported, nil
+}
+
+// importsUser checks that imports are enabled and returns the user
+// of the request. It writes the error response otherwise.
+func (h *Handler) importsUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	if h.imports == nil {
+		h.jsonError(w, r, "imports are not supported", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return nil, false
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// importError writes the error response of the imports manager.
+func (h *Handler) importError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		h.jsonError(w, r, message, err, http.StatusNotFound)
+	case errors.Is(err, errs.ErrConflict):
+		h.jsonError(w, r, message, err, http.StatusConflict)
+	default:
+		h.jsonError(w, r, message, err, http.StatusInternalServerError)
+	}
+}
+
+// writeImport writes the import as the JSON response in the naming of the request.
+func (h *Handler) writeImport(w http.ResponseWriter, r *http.Request, code int, imp imports.Import) {
+	payload := importResponsePayload{Import: imp}
+	if imp.Status == imports.StatusUploading {
+		payload.UploadURL = h.baseURL("") + "/api/user/imports/" + imp.ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := h.encodeData(w, r, payload); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/imports"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImports(t *testing.T) {
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+		UserID:      "test",
+	})
+
+	cfg := config.NewForTest()
+	cfg.Imports.Dir = t.TempDir()
+	cfg.Imports.MaxSize = 1 << 20
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/api/user/imports", handler.PostImport)
+	router.Get("/api/user/imports/{id}", handler.GetImport)
+	router.Patch("/api/user/imports/{id}", handler.PatchImport)
+	router.Post("/api/user/imports/{id}/complete", handler.PostImportComplete)
+
+	do := func(userID user.ID, method, path, offset, body string) (*http.Response, importResponsePayload) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if offset != "" {
+			r.Header.Set("Upload-Offset", offset)
+		}
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: userID}))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+
+		var payload importResponsePayload
+		if res.Header.Get(contentType) == applicationJSON {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&payload))
+		}
+		return res, payload
+	}
+
+	res, created := do("owner", http.MethodPost, "/api/user/imports", "", "")
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Equal(t, imports.StatusUploading, created.Status)
+	require.True(t, strings.HasSuffix(created.UploadURL, "/api/user/imports/"+created.ID))
+
+	path := "/api/user/imports/" + created.ID
+	chunks := []string{
+		"https://go.dev/\nhttps://example.com/1\n",
+		"\nnot a url\nhttps://example.com/2\n",
+	}
+
+	offset := 0
+	for _, chunk := range chunks {
+		res, _ = do("owner", http.MethodPatch, path, strconv.Itoa(offset), chunk)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		offset += len(chunk)
+		require.Equal(t, strconv.Itoa(offset), res.Header.Get("Upload-Offset"))
+	}
+
+	res, _ = do("owner", http.MethodPatch, path, "0", "https://example.com/3\n")
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "offset mismatch")
+
+	res, _ = do("owner", http.MethodPatch, path, "", "https://example.com/3\n")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "no offset")
+
+	res, _ = do("intruder", http.MethodGet, path, "", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "import of another user")
+
+	res, completed := do("owner", http.MethodPost, path+"/complete", "", "")
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, int64(offset), completed.Size)
+	assert.Empty(t, completed.UploadURL)
+
+	res, _ = do("owner", http.MethodPost, path+"/complete", "", "")
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "already completed")
+
+	var got importResponsePayload
+	require.Eventually(t, func() bool {
+		res, got = do("owner", http.MethodGet, path, "", "")
+		return res.StatusCode == http.StatusOK && got.Status == imports.StatusDone
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 3, got.Imported)
+	assert.Equal(t, 1, got.Failed)
+
+	all, err := store.GetAllByUserID(context.TODO(), "owner")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	for _, u := range all {
+		assert.Equal(t, models.OriginImport, u.Metadata.Origin)
+	}
+}
+
+func TestImports_NotSupported(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/user/imports", http.NoBody)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	handler.PostImport(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/i18n"
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// interstitialPage is the data of the interstitial page template.
+type interstitialPage struct {
+	Lang            string
+	Title           string
+	Text            string
+	Destination     string
+	Continue        string
+	Seconds         int
+	Countdown       string
+	CountdownFormat string
+}
+
+// needsInterstitial reports whether the redirect to the original URL
+// is confirmed with the interstitial page: the page is enabled and
+// the destination is neither one of the hosts of the short URLs nor trusted.
+func (h *Handler) needsInterstitial(originalURL models.OriginalURL) bool {
+	if !h.config.Interstitial.Enabled {
+		return false
+	}
+
+	u, err := url.Parse(string(originalURL))
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return true
+	}
+
+	if returnHost, _, err := net.SplitHostPort(h.config.HTTPServer.ReturnAddress.String()); err == nil &&
+		strings.EqualFold(host, returnHost) {
+		return false
+	}
+	if strings.EqualFold(host, h.base.Hostname()) {
+		return false
+	}
+	for _, domain := range h.config.HTTPServer.Domains {
+		if strings.EqualFold(host, hostname(domain)) {
+			return false
+		}
+	}
+	for _, trusted := range h.config.Interstitial.TrustedHosts {
+		trusted = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(trusted), "."))
+		if trusted != "" && (host == trusted || strings.HasSuffix(host, "."+trusted)) {
+			return false
+		}
+	}
+	return true
+}
+
+// interstitial writes the page telling the visitor the short URL leaves
+// for another site, localized according to the Accept-Language header.
+// The page continues to the destination after the configured countdown
+// or once the visitor follows the link.
+func (h *Handler) interstitial(w http.ResponseWriter, r *http.Request, shortURL string, originalURL models.OriginalURL) {
+	l := h.i18n.Localizer(r.Header.Get("Accept-Language"))
+	seconds := int(h.config.Interstitial.Countdown.Seconds())
+
+	var buf bytes.Buffer
+	err := pages.ExecuteTemplate(&buf, "interstitial.html", interstitialPage{
+		Lang:            l.Language().String(),
+		Title:           l.T("page.leaving.title"),
+		Text:            l.T("page.leaving.text", shortURL),
+		Destination:     string(originalURL),
+		Continue:        l.T("page.leaving.continue"),
+		Seconds:         seconds,
+		Countdown:       l.T("page.leaving.countdown", seconds),
+		CountdownFormat: l.T("page.leaving.countdown"),
+	})
+	if err != nil {
+		h.logger.Errorf("failed to render interstitial page: %s", err)
+		http.Error(w, l.T(i18n.ErrInternal), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", l.Language().String())
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Add("Vary", "Accept, Accept-Language")
+	w.WriteHeader(http.StatusOK)
+	if _, err = buf.WriteTo(w); err != nil {
+		h.logger.Errorf("failed to write response: %s", err)
+	}
+}
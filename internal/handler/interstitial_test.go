@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRedirect_Interstitial(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://example.com/offer?a=1&b=2", ShortURL: "Untrusted", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/net/http", ShortURL: "Trusted", UserID: "test"},
+		{OriginalURL: "https://go.dev/", ShortURL: "TrustedHost", UserID: "test"},
+		{OriginalURL: "http://sho.rt/docs", ShortURL: "Home", UserID: "test"},
+	}))
+
+	cfg := config.NewForTest()
+	cfg.Interstitial.Enabled = true
+	cfg.Interstitial.Countdown = 5 * time.Second
+	cfg.Interstitial.TrustedHosts = []string{"go.dev"}
+	require.NoError(t, cfg.HTTPServer.ReturnAddress.Set("sho.rt:80"))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+
+	redirect := func(shortURL, accept, lang string) (*http.Response, string) {
+		r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+		r.Header.Set("Accept", accept)
+		r.Header.Set("Accept-Language", lang)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("shortURL", shortURL)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		handler.GetRedirect(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(b)
+	}
+
+	res, body := redirect("Untrusted", "text/html", "en")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get(contentType), "text/html")
+	assert.Empty(t, res.Header.Get("Location"))
+	assert.Contains(t, body, `href="https://example.com/offer?a=1&amp;b=2"`)
+	assert.Contains(t, body, "You will be redirected automatically in 5 seconds.")
+	assert.Contains(t, body, "var left =  5 ;")
+
+	res, body = redirect("Untrusted", "text/html", "ru")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ru", res.Header.Get("Content-Language"))
+	assert.Contains(t, body, "Вы покидаете сайт")
+
+	tests := []struct {
+		name     string
+		shortURL string
+		accept   string
+	}{
+		{name: "API clients", shortURL: "Untrusted", accept: "*/*"},
+		{name: "subdomain of trusted host", shortURL: "Trusted", accept: "text/html"},
+		{name: "trusted host", shortURL: "TrustedHost", accept: "text/html"},
+		{name: "host of short URLs", shortURL: "Home", accept: "text/html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := redirect(tt.shortURL, tt.accept, "en")
+			assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+			assert.NotEmpty(t, res.Header.Get("Location"))
+		})
+	}
+}
+
+func TestGetRedirect_InterstitialWithoutCountdown(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://example.com/", ShortURL: "Untrusted", UserID: "test"},
+	}))
+
+	cfg := config.NewForTest()
+	cfg.Interstitial.Enabled = true
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	r.Header.Set("Accept", "text/html")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", "Untrusted")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.GetRedirect(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "<script>", "the visitor continues by the link")
+}
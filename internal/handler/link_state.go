@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+type linkStateResponsePayload struct {
+	ShortURL models.ShortURL  `json:"short_url"`
+	State    models.LinkState `json:"state"`
+}
+
+// PostPause pauses the active short URL owned by the user. The paused
+// URL responds with 503 Service Unavailable instead of the redirect until
+// it is resumed. Short URLs of other users are reported as not found.
+//
+// Request:
+//
+//	POST /api/user/urls/{shortURL}/pause
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"short_url": "6qxTVvsy",
+//		"state": "paused"
+//	}
+//
+// The URLs not active respond with 409 Conflict.
+func (h *Handler) PostPause(w http.ResponseWriter, r *http.Request) {
+	h.setLinkState(w, r, models.LinkActive, models.LinkPaused)
+}
+
+// PostResume resumes the short URL paused by the user.
+//
+// Request:
+//
+//	POST /api/user/urls/{shortURL}/resume
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"short_url": "6qxTVvsy",
+//		"state": "active"
+//	}
+//
+// The URLs not paused respond with 409 Conflict, e.g. the blocked ones
+// can't be resumed by their owners.
+func (h *Handler) PostResume(w http.ResponseWriter, r *http.Request) {
+	h.setLinkState(w, r, models.LinkPaused, models.LinkActive)
+}
+
+// setLinkState changes the state of the short URL of the user
+// from the given one to the other.
+func (h *Handler) setLinkState(w http.ResponseWriter, r *http.Request, from, to models.LinkState) {
+	if h.linkStates == nil {
+		h.jsonError(w, r, "link states are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	// the state is changed from the latest one
+	record, err := h.store.Get(consistency.WithPrimary(r.Context()), shortURL)
+	if err == nil && record.UserID != user.ID {
+		err = fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, user.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	state := record.Lifecycle(now)
+	if state == models.LinkDeleted && h.config.StrictHTTPSemantics {
+		h.urlGone(w, r)
+		return
+	}
+	if state != from {
+		h.jsonError(w, r, fmt.Sprintf("link is %s", state),
+			fmt.Errorf("%w: from %s to %s", models.ErrInvalidTransition, state, to),
+			http.StatusConflict)
+		return
+	}
+	stored := record.StoredState()
+	if err = record.Transition(to, now); err != nil {
+		h.jsonError(w, r, "invalid state", err, http.StatusConflict)
+		return
+	}
+
+	// the state read is checked by the storage, so that e.g. the link
+	// blocked by the moderators meanwhile is not resumed
+	err = h.linkStates.SetLinkState(r.Context(), user.ID, shortURL, stored, record.State)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, user.ID)
+			return
+		}
+		if errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "link state has changed", err, http.StatusConflict)
+			return
+		}
+		h.jsonError(w, r, "failed to set state", err, http.StatusInternalServerError)
+		return
+	}
+	h.publishEvents(events.TypeUpdated, record)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	payload := linkStateResponsePayload{ShortURL: shortURL, State: record.State}
+	if err = h.encodeData(w, r, payload); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostPauseResume(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Foreign", UserID: "other"},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Expired", UserID: "test", ExpiresAt: &expired},
+		{OriginalURL: "https://go.dev/doc/", ShortURL: "Banned", UserID: "test", State: models.LinkBlocked},
+	}))
+
+	tests := []struct {
+		name       string
+		resume     bool
+		shortURL   string
+		user       *user.User
+		disabled   bool
+		statusCode int
+		wantState  models.LinkState
+	}{
+		{
+			name:       "pause",
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusOK,
+			wantState:  models.LinkPaused,
+		},
+		{
+			name:       "pause paused",
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "resume",
+			resume:     true,
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusOK,
+			wantState:  models.LinkActive,
+		},
+		{
+			name:       "resume active",
+			resume:     true,
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "pause expired",
+			shortURL:   "Expired",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "resume blocked",
+			resume:     true,
+			shortURL:   "Banned",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "URL of another user",
+			shortURL:   "Foreign",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "no user",
+			shortURL:   "YBbxJEcQ9vq",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "link states disabled",
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithLinkStates(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			fn, target := handler.PostPause, "/api/user/urls/{shortURL}/pause"
+			if tt.resume {
+				fn, target = handler.PostResume, "/api/user/urls/{shortURL}/resume"
+			}
+			r := httptest.NewRequest(http.MethodPost, target, http.NoBody)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.user != nil {
+				ctx = user.NewContext(ctx, tt.user)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			fn(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			var got linkStateResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.Equal(t, tt.wantState, got.State)
+
+			record, err := store.Get(context.TODO(), models.ShortURL(tt.shortURL))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantState, record.State)
+		})
+	}
+}
+
+func TestGetRedirect_LinkStates(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Active", UserID: "test", State: models.LinkActive},
+		{OriginalURL: "https://go.dev/doc/", ShortURL: "Paused", UserID: "test", State: models.LinkPaused},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Banned", UserID: "test", State: models.LinkBlocked},
+		{OriginalURL: "https://go.dev/play/", ShortURL: "Expired", UserID: "test", ExpiresAt: &expired},
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	tests := []struct {
+		shortURL   string
+		statusCode int
+	}{
+		{shortURL: "Active", statusCode: http.StatusTemporaryRedirect},
+		{shortURL: "Paused", statusCode: http.StatusServiceUnavailable},
+		{shortURL: "Banned", statusCode: http.StatusUnavailableForLegalReasons},
+		{shortURL: "Expired", statusCode: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shortURL, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.shortURL, http.NoBody)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.GetRedirect(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+		})
+	}
+}
+
+// blockingStates blocks the link before the state of the owner is set,
+// as if the moderators blocked it between the read and the write.
+type blockingStates struct {
+	*memstore.URLRepository
+}
+
+func (s blockingStates) SetLinkState(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	if err := s.URLRepository.SetLinkState(ctx, userID, shortURL, from, models.LinkBlocked); err != nil {
+		return err
+	}
+	return s.URLRepository.SetLinkState(ctx, userID, shortURL, from, to)
+}
+
+func TestPostResume_BlockedMeanwhile(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test", State: models.LinkPaused},
+	}))
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithLinkStates(blockingStates{store}))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/urls/{shortURL}/resume", http.NoBody)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", "YBbxJEcQ9vq")
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	r = r.WithContext(user.NewContext(ctx, &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.PostResume(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	record, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkBlocked, record.State, "the blocked link is not resumed")
+}
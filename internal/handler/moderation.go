@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	domainBanRequestPayload struct {
+		Domain string `json:"domain"`
+		Reason string `json:"reason"`
+	}
+
+	domainBanResponsePayload struct {
+		*models.DomainBan
+		// Blocked is the number of the existing links blocked by the ban.
+		Blocked int `json:"blocked"`
+	}
+)
+
+// GetAnyUserURLs returns the full records of the URLs of any user,
+// including the deleted ones, ordered by their short URLs. The limit
+// and offset query parameters select the page of the URLs.
+//
+// Request:
+//
+//	GET /api/admin/users/{userID}/urls?limit=20&offset=40
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	[
+//		{
+//			"id": "8f0e5a2c-...",
+//			"short_url": "YBbxJEcQ9vq",
+//			"original_url": "https://go.dev/",
+//			"user_id": "2a5c1d63-...",
+//			"is_deleted": false,
+//			"state": "active"
+//		},
+//		...
+//	]
+func (h *Handler) GetAnyUserURLs(w http.ResponseWriter, r *http.Request) {
+	userID, err := user.ParseID(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	owned, err := h.store.GetAllByUserID(r.Context(), userID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		h.jsonError(w, r, "failed to get URLs", err, http.StatusInternalServerError)
+		return
+	}
+
+	// the records of the user are partial in some storages,
+	// the full ones are retrieved by their short URLs
+	shortURLs := make([]models.ShortURL, len(owned))
+	for i, u := range owned {
+		shortURLs[i] = u.ShortURL
+	}
+	URLs, err := h.store.GetMany(r.Context(), shortURLs)
+	if err != nil {
+		h.jsonError(w, r, "failed to get URLs", err, http.StatusInternalServerError)
+		return
+	}
+	slices.SortFunc(URLs, func(a, b *models.URL) int {
+		return cmp.Compare(a.ShortURL, b.ShortURL)
+	})
+
+	URLs, page, err := paginate(r, URLs)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodePage(w, r, URLs, page); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteAnyURL deletes the short URL of any user at once, e.g. on
+// a legal demand. Unlike the deletions of the owners, it is not queued.
+//
+// Request:
+//
+//	DELETE /api/admin/urls/{shortURL}
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) DeleteAnyURL(w http.ResponseWriter, r *http.Request) {
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.store.Get(consistency.WithPrimary(r.Context()), shortURL)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusNotFound)
+			return
+		}
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = h.store.DeleteURLs(r.Context(), &models.URL{ShortURL: shortURL, UserID: record.UserID})
+	if err != nil {
+		h.jsonError(w, r, "failed to delete url", err, http.StatusInternalServerError)
+		return
+	}
+	h.logger.Infof("url %s of user %s deleted by the administrator", shortURL, record.UserID)
+	h.publishEvents(events.TypeDeleted, record)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostDomainBan bans the destination domain and its subdomains. The URLs
+// to the domain can't be shortened any more and the existing ones are
+// blocked, so that they are listed among the flagged URLs.
+//
+// Request:
+//
+//	POST /api/admin/domain-bans
+//	Content-Type: application/json
+//	{ "domain": "example.com", "reason": "Phishing" }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"domain": "example.com",
+//		"reason": "Phishing",
+//		"created_at": "2024-06-01T12:00:00Z",
+//		"blocked": 3
+//	}
+//
+// The banned domain responds with 409 Conflict.
+func (h *Handler) PostDomainBan(w http.ResponseWriter, r *http.Request) {
+	if h.moderation == nil {
+		h.jsonError(w, r, "moderation is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	var payload domainBanRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	domain, err := hostpolicy.ParseDomain(payload.Domain)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	ban := &models.DomainBan{Domain: domain, Reason: payload.Reason, CreatedAt: time.Now().UTC()}
+	if err = h.moderation.BanDomain(r.Context(), ban); err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "domain is already banned", err, http.StatusConflict)
+			return
+		}
+		h.jsonError(w, r, "failed to ban domain", err, http.StatusInternalServerError)
+		return
+	}
+
+	blocked, err := h.blockDomain(r.Context(), domain)
+	if err != nil {
+		// the ban holds, even if some of the existing links are not blocked
+		h.logger.Errorf("block links of banned domain %s: %s", domain, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err = h.encodeData(w, r, domainBanResponsePayload{DomainBan: ban, Blocked: blocked}); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// blockDomain blocks the existing links to the domain and its subdomains
+// and returns the number of the blocked ones. The links are not blocked
+// if the storage can't enumerate them or keep their states.
+func (h *Handler) blockDomain(ctx context.Context, domain string) (int, error) {
+	if h.scanner == nil || h.linkStates == nil {
+		return 0, nil
+	}
+
+	// the links are blocked once the scan is over,
+	// so that the storage is not written while it is read
+	var matched []*models.URL
+	err := h.scanner.ScanURLs(ctx, func(u *models.URL) error {
+		if u.IsDeleted || u.State == models.LinkBlocked {
+			return nil
+		}
+		if slices.Contains(hostpolicy.Domains(hostpolicy.Host(string(u.OriginalURL))), domain) {
+			matched = append(matched, &models.URL{ShortURL: u.ShortURL, UserID: u.UserID, State: u.State})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("scan urls: %w", err)
+	}
+
+	blocked := 0
+	for _, u := range matched {
+		err = h.blockLink(ctx, u)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return blocked, fmt.Errorf("block %s: %w", u.ShortURL, err)
+		}
+		if err == nil {
+			h.publishEvents(events.TypeUpdated, u)
+			blocked++
+		}
+	}
+
+	return blocked, nil
+}
+
+// maxBlockAttempts is the number of the attempts to block the link
+// whose state is changed by its owner meanwhile.
+const maxBlockAttempts = 3
+
+// blockLink blocks the link from the state it is kept in. The state
+// changed meanwhile, e.g. the link paused by its owner, is read again,
+// so that the link is blocked whatever the state it is in.
+func (h *Handler) blockLink(ctx context.Context, u *models.URL) error {
+	from := u.StoredState()
+	for attempt := 1; ; attempt++ {
+		err := h.linkStates.SetLinkState(ctx, u.UserID, u.ShortURL, from, models.LinkBlocked)
+		if !errors.Is(err, errs.ErrConflict) || attempt == maxBlockAttempts {
+			return err
+		}
+
+		record, err := h.store.Get(consistency.WithPrimary(ctx), u.ShortURL)
+		if err != nil {
+			return err
+		}
+		if record.State == models.LinkBlocked {
+			return nil
+		}
+		from = record.StoredState()
+	}
+}
+
+// GetDomainBans returns the banned domains in the order they were banned.
+//
+// Request:
+//
+//	GET /api/admin/domain-bans
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[
+//		{
+//			"domain": "example.com",
+//			"reason": "Phishing",
+//			"created_at": "2024-06-01T12:00:00Z"
+//		}
+//	]
+func (h *Handler) GetDomainBans(w http.ResponseWriter, r *http.Request) {
+	if h.moderation == nil {
+		h.jsonError(w, r, "moderation is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	bans, err := h.moderation.ListDomainBans(r.Context())
+	if err != nil {
+		h.jsonError(w, r, "failed to list domain bans", err, http.StatusInternalServerError)
+		return
+	}
+
+	bans, page, err := paginate(r, bans)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodePage(w, r, bans, page); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetFlaggedURLs returns the full records of the URLs blocked
+// by the moderators, excluding the deleted ones.
+//
+// Request:
+//
+//	GET /api/admin/flagged?limit=20
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[
+//		{
+//			"id": "8f0e5a2c-...",
+//			"short_url": "YBbxJEcQ9vq",
+//			"original_url": "https://example.com/",
+//			"user_id": "2a5c1d63-...",
+//			"is_deleted": false,
+//			"state": "blocked"
+//		}
+//	]
+func (h *Handler) GetFlaggedURLs(w http.ResponseWriter, r *http.Request) {
+	if h.moderation == nil {
+		h.jsonError(w, r, "moderation is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	URLs, err := h.moderation.GetFlaggedURLs(r.Context())
+	if err != nil {
+		h.jsonError(w, r, "failed to get flagged URLs", err, http.StatusInternalServerError)
+		return
+	}
+
+	URLs, page, err := paginate(r, URLs)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodePage(w, r, URLs, page); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModeration(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{ID: "1", ShortURL: "YBbxJEcQ9vq", OriginalURL: "https://go.dev/", UserID: "owner"},
+		{ID: "2", ShortURL: "RTfd56hn", OriginalURL: "https://evil.example.com/", UserID: "owner"},
+		{ID: "3", ShortURL: "Hq3Wz7kP", OriginalURL: "https://example.com/", UserID: "other"},
+	}))
+	handler, err := New(store, c, l, WithModeration(store), WithLinkStates(store), WithExport(store, "test"))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// token returns the cookie of the token of the user with the role
+	token := func(role user.Role) *http.Cookie {
+		s, err := jwt.BuildUserJWTString(&user.User{ID: user.NewID(), Token: user.TokenService, Role: role},
+			c.JWT.SigningKey, time.Hour)
+		require.NoError(t, err)
+		return &http.Cookie{Name: "Authorization", Value: s}
+	}
+	admin := token(user.RoleAdmin)
+	// serve returns the recorded response to the request with the cookie
+	serve := func(method, target, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/admin/users/owner/urls", "", token(""))
+	assert.Equal(t, http.StatusForbidden, w.Code, "regular users can't moderate")
+
+	w = serve(http.MethodGet, "/api/admin/users/owner/urls", "", admin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var URLs []models.URL
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&URLs))
+	require.Len(t, URLs, 2)
+	assert.Equal(t, models.ShortURL("RTfd56hn"), URLs[0].ShortURL)
+	assert.Equal(t, user.ID("owner"), URLs[0].UserID)
+
+	// the ban blocks the links to the domain and its subdomains
+	w = serve(http.MethodPost, "/api/admin/domain-bans", `{"domain":"Example.com","reason":"Phishing"}`, admin)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var ban domainBanResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&ban))
+	assert.Equal(t, "example.com", ban.Domain)
+	assert.Equal(t, 2, ban.Blocked)
+
+	w = serve(http.MethodPost, "/api/admin/domain-bans", `{"domain":"example.com"}`, admin)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serve(http.MethodPost, "/api/admin/domain-bans", `{"domain":"*.example.org"}`, admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodGet, "/api/admin/domain-bans", "", admin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"reason":"Phishing"`)
+
+	w = serve(http.MethodGet, "/api/admin/flagged", "", admin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var flagged []models.URL
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&flagged))
+	require.Len(t, flagged, 2)
+	assert.Equal(t, models.LinkBlocked, flagged[0].State)
+
+	// the banned domain can't be shortened
+	w = serve(http.MethodPost, "/api/shorten", `{"url":"https://www.example.com/"}`, token(""))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"error":"host_banned"`)
+
+	w = serve(http.MethodDelete, "/api/admin/urls/Hq3Wz7kP", "", admin)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	record, err := store.Get(context.TODO(), "Hq3Wz7kP")
+	require.NoError(t, err)
+	assert.True(t, record.IsDeleted)
+
+	w = serve(http.MethodDelete, "/api/admin/urls/Hq3Wz7kQ", "", admin)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestModeration_Disabled(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/flagged", http.NoBody)
+	w := httptest.NewRecorder()
+	handler.GetFlaggedURLs(w, r)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
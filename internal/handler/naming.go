@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+)
+
+// acceptProfile is the header selecting the JSON naming of the request,
+// e.g. "Accept-Profile: camelCase". The configured naming is used
+// if the header is not set or unknown.
+const acceptProfile = "Accept-Profile"
+
+// jsonNaming returns the JSON naming of the request.
+func (h *Handler) jsonNaming(r *http.Request) config.JSONNaming {
+	var naming config.JSONNaming
+	if err := naming.Set(r.Header.Get(acceptProfile)); err != nil {
+		return h.config.JSONNaming
+	}
+	return naming
+}
+
+// encodeJSON writes v as JSON in the naming of the request.
+// The payloads are declared in snake_case, so they are written
+// as is unless camelCase is requested. The timestamps are written
+// in the time zone of the request.
+func (h *Handler) encodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if h.jsonNaming(r).IsCamelCase() {
+		b = renameKeys(b, snakeToCamel)
+	}
+	b = append(formatTimestamps(b, location(r)), '\n')
+	_, err = w.Write(b)
+	return err
+}
+
+// decodeJSON reads the request body into v. In camelCase naming
+// the snake_case fields are also accepted. The body over the configured
+// limits is reported as errTooLarge.
+func (h *Handler) decodeJSON(r *http.Request, v any) error {
+	dec, err := h.jsonDecoder(r)
+	if err != nil {
+		return err
+	}
+	return limitError(dec.Decode(v))
+}
+
+// renameKeys renames the keys of all the objects in the JSON
+// keeping the order of the fields.
+func renameKeys(b []byte, rename func(string) string) []byte {
+	res := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != '"' {
+			res = append(res, b[i])
+			continue
+		}
+
+		// find the closing quote of the string
+		end := i + 1
+		for ; end < len(b) && b[end] != '"'; end++ {
+			if b[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(b) {
+			// invalid JSON is left for the decoder to report
+			return append(res, b[i:]...)
+		}
+
+		// the string is the key if followed by the colon
+		next := end + 1
+		for next < len(b) && isSpace(b[next]) {
+			next++
+		}
+		if next < len(b) && b[next] == ':' {
+			res = append(res, '"')
+			res = append(res, rename(string(b[i+1:end]))...)
+			res = append(res, '"')
+		} else {
+			res = append(res, b[i:end+1]...)
+		}
+		i = end
+	}
+	return res
+}
+
+// isSpace reports whether the byte is the JSON whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// snakeToCamel converts short_url to shortUrl.
+func snakeToCamel(s string) string {
+	var sb strings.Builder
+	upper := false
+	for _, c := range s {
+		switch {
+		case c == '_':
+			upper = sb.Len() > 0
+		case upper:
+			sb.WriteRune(unicode.ToUpper(c))
+			upper = false
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// camelToSnake converts shortUrl and shortURL to short_url.
+func camelToSnake(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameKeys(t *testing.T) {
+	snake := `[{"short_url": "a_b", "metadata": {"creator_ip": "x:y", "note": "\"k_v\": 1"}}]`
+	camel := `[{"shortUrl": "a_b", "metadata": {"creatorIp": "x:y", "note": "\"k_v\": 1"}}]`
+
+	assert.Equal(t, camel, string(renameKeys([]byte(snake), snakeToCamel)))
+	assert.Equal(t, snake, string(renameKeys([]byte(camel), camelToSnake)))
+	assert.Equal(t, "last_accessed_at", camelToSnake("lastAccessedAt"))
+	assert.Equal(t, "short_url", camelToSnake("shortURL"))
+	assert.Equal(t, "url_details", camelToSnake("URLDetails"))
+	assert.Equal(t, "lastAccessedAt", snakeToCamel("last_accessed_at"))
+}
+
+func TestPostShortenBatch_JSONNaming(t *testing.T) {
+	tests := []struct {
+		name    string
+		naming  config.JSONNaming
+		profile string
+		payload string
+		want    string
+	}{
+		{
+			name:    "configured snake_case",
+			naming:  config.JSONNamingSnakeCase,
+			payload: `[{"correlation_id":"1","original_url":"https://go.dev/"}]`,
+			want:    `[{"correlation_id":"1","short_url":"http://0.0.0.0:8080/YBbxJEcQ9vq"}]`,
+		},
+		{
+			name:    "requested camelCase",
+			naming:  config.JSONNamingSnakeCase,
+			profile: "camelCase",
+			payload: `[{"correlationId":"1","originalUrl":"https://go.dev/"}]`,
+			want:    `[{"correlationId":"1","shortUrl":"http://0.0.0.0:8080/YBbxJEcQ9vq"}]`,
+		},
+		{
+			name:    "configured camelCase accepts snake_case",
+			naming:  config.JSONNamingCamelCase,
+			payload: `[{"correlation_id":"1","original_url":"https://go.dev/"}]`,
+			want:    `[{"correlationId":"1","shortUrl":"http://0.0.0.0:8080/YBbxJEcQ9vq"}]`,
+		},
+		{
+			name:    "requested snake_case",
+			naming:  config.JSONNamingCamelCase,
+			profile: "snake_case",
+			payload: `[{"correlation_id":"1","original_url":"https://go.dev/"}]`,
+			want:    `[{"correlation_id":"1","short_url":"http://0.0.0.0:8080/YBbxJEcQ9vq"}]`,
+		},
+		{
+			name:    "unknown profile",
+			naming:  config.JSONNamingSnakeCase,
+			profile: "kebab-case",
+			payload: `[{"correlation_id":"1","original_url":"https://go.dev/"}]`,
+			want:    `[{"correlation_id":"1","short_url":"http://0.0.0.0:8080/YBbxJEcQ9vq"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(tt.payload))
+			r.Header.Set(contentType, applicationJSON)
+			if tt.profile != "" {
+				r.Header.Set(acceptProfile, tt.profile)
+			}
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+			w := httptest.NewRecorder()
+
+			l, _ := logger.NewForTest()
+			c := config.NewForTest()
+			c.JSONNaming = tt.naming
+			handler, err := New(memstore.NewURLRepository(), c, l)
+			require.NoError(t, err, "new handler error")
+
+			handler.PostShortenBatch(w, r)
+
+			res := w.Result()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close(), "failed close body")
+
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+			assert.JSONEq(t, tt.want, string(body))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// noteQueryParam is the query parameter of the list endpoint
+// selecting the URLs by their notes.
+const noteQueryParam = "q"
+
+type noteRequestPayload struct {
+	Note string `json:"note"`
+}
+
+// PutNote sets the free-text note of the short URL owned by the user,
+// e.g. the campaign or the placement the link is printed at. Empty note
+// removes it. Short URLs of other users are reported as not found.
+//
+// Request:
+//
+//	PUT /api/user/urls/{shortURL}/note
+//	Content-Type: application/json
+//	{ "note": "Spring campaign, poster at the main entrance" }
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) PutNote(w http.ResponseWriter, r *http.Request) {
+	if h.notes == nil {
+		h.jsonError(w, r, "notes are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL := chi.URLParam(r, "shortURL")
+
+	// check if shortened URL is valid
+	if _, err := h.parseShortURL(shortURL); err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	var payload noteRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	if err := models.ValidateNote(payload.Note); err != nil {
+		h.jsonError(w, r, "invalid note", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+			http.StatusBadRequest)
+		return
+	}
+
+	err := h.notes.SetNote(r.Context(), user.ID, models.ShortURL(shortURL), payload.Note)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, models.ShortURL(shortURL), user.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to set note", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// filterByNote returns the URLs with the notes containing the query,
+// case-insensitively. All the URLs are returned if the query is empty.
+func filterByNote(URLs []*models.URL, query string) []*models.URL {
+	if query == "" {
+		return URLs
+	}
+
+	query = strings.ToLower(query)
+	filtered := make([]*models.URL, 0, len(URLs))
+	for _, u := range URLs {
+		if strings.Contains(strings.ToLower(u.Note), query) {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
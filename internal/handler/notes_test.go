@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutNote(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Foreign", UserID: "other"},
+	}))
+
+	tests := []struct {
+		name       string
+		shortURL   string
+		body       string
+		user       *user.User
+		disabled   bool
+		statusCode int
+		wantNote   string
+	}{
+		{
+			name:       "positive test",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"note": "Spring campaign"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNoContent,
+			wantNote:   "Spring campaign",
+		},
+		{
+			name:       "note removed",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"note": ""}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "too long note",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"note": "` + strings.Repeat("a", models.MaxNoteLength+1) + `"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "URL of another user",
+			shortURL:   "Foreign",
+			body:       `{"note": "mine"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "no user",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"note": "Spring campaign"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "notes disabled",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"note": "Spring campaign"}`,
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithNotes(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodPut, "/api/user/urls/{shortURL}/note",
+				strings.NewReader(tt.body))
+			r.Header.Set(contentType, applicationJSON)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.user != nil {
+				ctx = user.NewContext(ctx, tt.user)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.PutNote(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusNoContent {
+				return
+			}
+			record, err := store.Get(context.TODO(), models.ShortURL(tt.shortURL))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNote, record.Note)
+		})
+	}
+}
+
+func TestGetAllByUserID_NoteSearch(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test", Note: "Spring campaign"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "test", Note: "Summer sale"},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Wxyz9876", UserID: "test"},
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	list := func(query string) []getAllByUserIDResponsePayload {
+		r := httptest.NewRequest(http.MethodGet, "/api/user/urls"+query, http.NoBody)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		handler.GetAllByUserID(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got []getAllByUserIDResponsePayload
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		return got
+	}
+
+	assert.Len(t, list(""), 3)
+
+	got := list("?q=SPRING")
+	require.Len(t, got, 1, "search is case-insensitive")
+	assert.Equal(t, models.OriginalURL("https://go.dev/"), got[0].OriginalURL)
+	assert.Equal(t, "Spring campaign", got[0].Note)
+
+	assert.Empty(t, list("?q=winter"))
+}
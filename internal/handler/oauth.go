@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/oauth"
+)
+
+type oauthTokenResponsePayload struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// GetAuthorize is the authorization endpoint of the OAuth authorization
+// code flow with PKCE. The user of the cookie grants the client access
+// to the requested scopes, "shorten" by default, and is redirected back
+// to the client with the authorization code.
+//
+// Request:
+//
+//	GET /oauth/authorize?response_type=code&client_id=cli
+//		&redirect_uri=http://127.0.0.1:53682/callback&scope=shorten+read
+//		&code_challenge=E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM
+//		&code_challenge_method=S256&state=af0ifjsldkj
+//
+// Response:
+//
+//	HTTP/1.1 302 Found
+//	Location: http://127.0.0.1:53682/callback?code=SplxlOBeZQQYbYS6WxSbIA&state=af0ifjsldkj
+func (h *Handler) GetAuthorize(w http.ResponseWriter, r *http.Request) {
+	if h.oauth == nil {
+		h.jsonError(w, r, "OAuth is disabled", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	req := oauth.AuthorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+
+	// never redirect to the URI not registered for the client
+	if err := h.oauth.CheckClient(req.ClientID, req.RedirectURI); err != nil {
+		h.jsonError(w, r, "invalid client", err, http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		h.jsonError(w, r, "invalid redirect_uri", errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	if state := q.Get("state"); state != "" {
+		params.Set("state", state)
+	}
+
+	code, err := h.oauth.Authorize(req, user.ID)
+	if err != nil {
+		var oauthErr *oauth.Error
+		if !errors.As(err, &oauthErr) {
+			h.jsonError(w, r, "failed to authorize", err, http.StatusInternalServerError)
+			return
+		}
+		h.logger.Infof("authorization denied: %s", err)
+		params.Set("error", oauthErr.Code)
+		params.Set("error_description", oauthErr.Description)
+	} else {
+		params.Set("code", code)
+	}
+
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// PostToken is the token endpoint of the OAuth authorization code flow
+// with PKCE. It exchanges the authorization code for the access token
+// limited to the granted scopes. The token is passed in the
+// "Authorization: Bearer <token>" header.
+//
+// Request:
+//
+//	POST /oauth/token
+//	Content-Type: application/x-www-form-urlencoded
+//
+//	grant_type=authorization_code&code=SplxlOBeZQQYbYS6WxSbIA&client_id=cli
+//	&redirect_uri=http://127.0.0.1:53682/callback
+//	&code_verifier=dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	Cache-Control: no-store
+//	{
+//		"access_token": "eyJhbGciOi...",
+//		"token_type": "Bearer",
+//		"expires_in": 2592000,
+//		"scope": "shorten read"
+//	}
+func (h *Handler) PostToken(w http.ResponseWriter, r *http.Request) {
+	if h.oauth == nil {
+		h.jsonError(w, r, "OAuth is disabled", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.oauthError(w, &oauth.Error{Code: oauth.ErrInvalidRequest, Description: "invalid form"})
+		return
+	}
+
+	grant, err := h.oauth.Exchange(oauth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		ClientID:     r.PostForm.Get("client_id"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	})
+	if err != nil {
+		var oauthErr *oauth.Error
+		if !errors.As(err, &oauthErr) {
+			h.jsonError(w, r, "failed to exchange code", err, http.StatusInternalServerError)
+			return
+		}
+		h.logger.Infof("token denied: %s", err)
+		h.oauthError(w, oauthErr)
+		return
+	}
+
+	exp := h.config.JWT.ExpirationOf(user.TokenOAuth)
+	token, err := jwt.BuildJWTString(grant.UserID, user.TokenOAuth, h.config.JWT.SigningKey, exp,
+		grant.Scopes...)
+	if err != nil {
+		h.jsonError(w, r, "failed to build JWT token", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeOAuth(w, http.StatusOK, oauthTokenResponsePayload{
+		// the header scheme is added by the client
+		AccessToken: token[len("Bearer "):],
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(exp.Seconds()),
+		Scope:       user.FormatScopes(grant.Scopes),
+	})
+}
+
+// oauthError writes the OAuth error response of the token endpoint.
+func (h *Handler) oauthError(w http.ResponseWriter, err *oauth.Error) {
+	code := http.StatusBadRequest
+	if err.Code == oauth.ErrInvalidClient {
+		code = http.StatusUnauthorized
+	}
+	h.writeOAuth(w, code, err)
+}
+
+// writeOAuth writes the JSON response of the token endpoint.
+// The field names are defined by the OAuth specification,
+// so the naming of the request is not applied.
+func (h *Handler) writeOAuth(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth(t *testing.T) {
+	c := config.NewForTest()
+	c.OAuth.Clients = map[string]string{"cli": "http://127.0.0.1/callback"}
+	l, _ := logger.NewForTest()
+	store := memstore.NewURLRepository()
+	handler, err := New(store, c, l, WithAPIKeys(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	redirectURI := "http://127.0.0.1:53682/callback"
+
+	// the user of the cookie grants the client the shorten scope
+	cookie, err := jwt.BuildJWTString(user.NewID(), user.TokenAnonymous, c.JWT.SigningKey, c.JWT.Expiration)
+	require.NoError(t, err)
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"cli"},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {"xyz"},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+q.Encode(), http.NoBody)
+	r.AddCookie(&http.Cookie{Name: "Authorization", Value: cookie})
+	w := serve(r)
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:53682", location.Host)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// the code is exchanged for the token
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {"cli"},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	exchange := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		r.Header.Set(contentType, "application/x-www-form-urlencoded")
+		return serve(r)
+	}
+	w = exchange()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var token oauthTokenResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&token))
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, "shorten", token.Scope)
+
+	// the code is single use
+	w = exchange()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_grant")
+
+	withBearer := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		return serve(r)
+	}
+
+	// the token is limited to the granted scope
+	w = withBearer(http.MethodPost, "/api/shorten", `{"url":"https://go.dev/"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = withBearer(http.MethodGet, "/api/user/urls", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = withBearer(http.MethodPost, "/api/user/keys", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestOAuth_Disabled(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", http.NoBody)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
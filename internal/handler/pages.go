@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/i18n"
+)
+
+// templates contains the HTML pages shown to the browsers.
+//
+//go:embed templates/*.html
+var templates embed.FS
+
+// pages are the parsed HTML templates.
+var pages = template.Must(template.ParseFS(templates, "templates/*.html"))
+
+// errorPage is the data of the error page template.
+type errorPage struct {
+	Lang  string
+	Title string
+	Text  string
+	Home  string
+}
+
+// wantsHTML reports whether the client prefers an HTML page
+// to the plain text response, as browsers do.
+func wantsHTML(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsJSON reports whether the client accepts JSON, as API clients do.
+func wantsJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
+// errorPage writes the error page with the given status code localized
+// according to the Accept-Language header. The page key is the prefix
+// of its title and text messages, which are formatted with args.
+func (h *Handler) errorPage(w http.ResponseWriter, r *http.Request, code int, page string, args ...any) {
+	l := h.i18n.Localizer(r.Header.Get("Accept-Language"))
+
+	var buf bytes.Buffer
+	err := pages.ExecuteTemplate(&buf, "error.html", errorPage{
+		Lang:  l.Language().String(),
+		Title: l.T(i18n.Key(page + ".title")),
+		Text:  l.T(i18n.Key(page+".text"), args...),
+		Home:  l.T("page.home"),
+	})
+	if err != nil {
+		h.logger.Errorf("failed to render %s page: %s", page, err)
+		http.Error(w, l.T(i18n.ErrInternal), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", l.Language().String())
+	w.Header().Add("Vary", "Accept, Accept-Language")
+	w.WriteHeader(code)
+	if _, err = buf.WriteTo(w); err != nil {
+		h.logger.Errorf("failed to write response: %s", err)
+	}
+}
@@ -15,17 +15,16 @@ import (
 func (h *Handler) GetPingDB(w http.ResponseWriter, r *http.Request) {
 	// check request method
 	if r.Method != http.MethodGet {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	if err := h.store.Ping(r.Context()); err != nil {
 		if errors.Is(err, errs.ErrDBNotConnected) {
-			h.textError(w, "DB not connected", err, http.StatusInternalServerError)
+			h.jsonError(w, r, "DB not connected", err, http.StatusInternalServerError)
 			return
 		}
-		h.textError(w, "connection error", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "connection error", err, http.StatusInternalServerError)
 		return
 	}
 }
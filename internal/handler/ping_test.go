@@ -8,10 +8,11 @@ import (
 	"testing"
 
 	"github.com/KretovDmitry/shortener/internal/config"
-	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/repository"
 	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,7 +29,7 @@ func TestGetPingDB(t *testing.T) {
 	path := "/ping"
 
 	type want struct {
-		response   string
+		response   *envelope.Error
 		statusCode int
 	}
 
@@ -42,7 +43,6 @@ func TestGetPingDB(t *testing.T) {
 			store: &connectedStore{},
 			want: want{
 				statusCode: http.StatusOK,
-				response:   "",
 			},
 		},
 		{
@@ -50,9 +50,7 @@ func TestGetPingDB(t *testing.T) {
 			store: memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response: fmt.Sprintf(
-					"%s: DB not connected", errs.ErrDBNotConnected,
-				),
+				response:   &envelope.Error{Code: "internal", Message: "DB not connected"},
 			},
 		},
 		{
@@ -60,9 +58,7 @@ func TestGetPingDB(t *testing.T) {
 			store: &brokenStore{},
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response: fmt.Sprintf(
-					"%s: connection error", errIntentionallyNotWorkingMethod,
-				),
+				response:   &envelope.Error{Code: "internal", Message: "connection error"},
 			},
 		},
 	}
@@ -82,11 +78,12 @@ func TestGetPingDB(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, tt.want.statusCode, res.StatusCode)
-			assert.Equal(t, tt.want.response, response)
+			if tt.want.response == nil {
+				assert.Empty(t, getResponseTextPayload(t, res))
+				return
+			}
+			assert.Equal(t, *tt.want.response, getResponseErrorPayload(t, res))
 		})
 	}
 }
@@ -123,13 +120,76 @@ func TestGetPing_Method(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-			assert.Equal(t, textPlain, res.Header.Get(contentType))
-			assert.Equal(t, fmt.Sprintf("%s: %s",
-				errs.ErrInvalidRequest, tt.method), response)
+			assert.Equal(t, envelope.Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("method %s is not allowed", tt.method),
+			}, getResponseErrorPayload(t, res))
+		})
+	}
+}
+
+func TestGetPing_MethodStrict(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/ping", http.NoBody)
+	w := httptest.NewRecorder()
+
+	l, _ := logger.NewForTest()
+	c := config.NewForTest()
+	c.StrictHTTPSemantics = true
+
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "failed to init new handler")
+
+	handler.GetPingDB(w, r)
+
+	res := w.Result()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, http.MethodGet, res.Header.Get("Allow"))
+	assert.Equal(t, envelope.Error{
+		Code:    "method_not_allowed",
+		Message: "method POST is not allowed",
+	}, getResponseErrorPayload(t, res))
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		strict     bool
+		statusCode int
+		code       string
+		allow      string
+	}{
+		{"default", http.MethodPut, "/ping", false, http.StatusBadRequest, "invalid_request", ""},
+		{"strict", http.MethodPut, "/ping", true, http.StatusMethodNotAllowed, "method_not_allowed", "GET"},
+		{
+			"strict route of the group", http.MethodGet, "/api/shorten/batch", true,
+			http.StatusMethodNotAllowed, "method_not_allowed", "POST",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			c := config.NewForTest()
+			c.StrictHTTPSemantics = tt.strict
+
+			handler, err := New(memstore.NewURLRepository(), c, l)
+			require.NoError(t, err, "failed to init new handler")
+			router := handler.Register(chi.NewRouter(), c, l)
+
+			r := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			res := w.Result()
+
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			assert.Equal(t, tt.allow, res.Header.Get("Allow"))
+			payload := getResponseErrorPayload(t, res)
+			assert.Equal(t, tt.code, payload.Code)
+			assert.Equal(t, fmt.Sprintf("method %s is not allowed", tt.method), payload.Message)
 		})
 	}
 }
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// poolMetrics are the stats of the pool of the database connections.
+type poolMetrics struct {
+	// MaxOpenConnections is the configured limit, zero if unlimited.
+	MaxOpenConnections int `json:"max_open_connections"`
+	OpenConnections    int `json:"open_connections"`
+	InUse              int `json:"in_use"`
+	Idle               int `json:"idle"`
+	// WaitCount is the number of the connections waited for.
+	WaitCount int64 `json:"wait_count"`
+	// WaitDurationMS is the total time waited for the connections.
+	WaitDurationMS int64 `json:"wait_duration_ms"`
+	// MaxIdleClosed, MaxIdleTimeClosed and MaxLifetimeClosed are the
+	// numbers of the connections closed by the limits of the pool.
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// GetPoolMetrics returns the stats of the pool of the database
+// connections, so that its limits are tuned.
+//
+// Request:
+//
+//	GET /api/internal/db/metrics
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"max_open_connections": 25,
+//		"open_connections": 6,
+//		"in_use": 2,
+//		"idle": 4,
+//		"wait_count": 0,
+//		"wait_duration_ms": 0,
+//		"max_idle_closed": 12,
+//		"max_idle_time_closed": 0,
+//		"max_lifetime_closed": 3
+//	}
+func (h *Handler) GetPoolMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.pool == nil {
+		h.jsonError(w, r, "storage has no connection pool",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	stats := h.pool.PoolStats()
+	metrics := poolMetrics{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.encodeData(w, r, metrics); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
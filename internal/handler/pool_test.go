@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/KretovDmitry/shortener/internal/repository/sqlitestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPoolMetrics(t *testing.T) {
+	l, _ := logger.NewForTest()
+	store, err := sqlitestore.New(sqlitestore.Scheme+filepath.Join(t.TempDir(), "shortener.db"), l)
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler, err := New(store, config.NewForTest(), l, WithPoolStats(store))
+	require.NoError(t, err, "new handler error")
+
+	w := httptest.NewRecorder()
+	handler.GetPoolMetrics(w, httptest.NewRequest(http.MethodGet, "/api/internal/db/metrics", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got poolMetrics
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Positive(t, got.OpenConnections, "migrations open a connection")
+}
+
+func TestGetPoolMetrics_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	w := httptest.NewRecorder()
+	handler.GetPoolMetrics(w, httptest.NewRequest(http.MethodGet, "/api/internal/db/metrics", http.NoBody))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
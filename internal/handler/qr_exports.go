@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/qrexport"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	qrExportRequestPayload struct {
+		// Format is zip or pdf, zip by default.
+		Format qrexport.Format `json:"format"`
+		// ShortURLs are the codes of the links to export.
+		ShortURLs []string `json:"short_urls"`
+		// Campaign exports the links of the feeds of the campaign instead.
+		Campaign string `json:"campaign"`
+	}
+
+	qrExportResponsePayload struct {
+		qrexport.Export
+		DownloadURL string `json:"download_url,omitempty"`
+	}
+)
+
+// PostQRExport queues the export of the QR codes of the user's links:
+// either the listed short URLs or all the links of the feeds of the
+// campaign. The codes are rendered in the background as a ZIP of PNG
+// images or a printable PDF sheet. The progress can be polled with
+// GetQRExport, which returns the download URL once the file is ready.
+//
+// Request:
+//
+//	POST /api/user/qr-exports
+//	Content-Type: application/json
+//	{ "format": "pdf", "short_urls": [ "6qxTVvsy", "RTfd56hn" ] }
+//
+// Response:
+//
+//	HTTP/1.1 202 Accepted
+//	Content-Type: application/json
+//	{ "id": "9c1e4f5a-...", "format": "pdf", "status": "queued", "links": 2, ... }
+func (h *Handler) PostQRExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.qrExportsUser(w, r)
+	if !ok {
+		return
+	}
+
+	var payload qrExportRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	if payload.Format == "" {
+		payload.Format = qrexport.FormatZIP
+	}
+	if !payload.Format.Valid() {
+		h.jsonError(w, r, fmt.Sprintf("unsupported format %q", payload.Format),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if (len(payload.ShortURLs) == 0) == (payload.Campaign == "") {
+		h.jsonError(w, r, "either short URLs or campaign must be provided",
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if payload.Campaign != "" && h.feeds == nil {
+		h.jsonError(w, r, "campaigns are not supported", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return
+	}
+
+	var links []qrexport.Link
+	var err error
+	if payload.Campaign != "" {
+		links, err = h.campaignQRLinks(user.ID, payload.Campaign)
+	} else {
+		links, err = h.userQRLinks(r.Context(), user.ID, payload.ShortURLs)
+	}
+	if err != nil {
+		h.qrExportError(w, r, "failed to export", err)
+		return
+	}
+
+	if maxLinks := h.config.QRExport.MaxLinks; maxLinks > 0 && len(links) > maxLinks {
+		h.jsonError(w, r, fmt.Sprintf("more than %d links", maxLinks),
+			errs.ErrInvalidRequest, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	exp, err := h.qrExports.Create(user.ID, payload.Format, links)
+	if err != nil {
+		h.qrExportError(w, r, "failed to create QR export", err)
+		return
+	}
+
+	h.writeQRExport(w, r, http.StatusAccepted, exp)
+}
+
+// GetQRExport returns the status of the QR export
+// with the download URL once it is done.
+//
+// Request:
+//
+//	GET /api/user/qr-exports/{id}
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	{
+//		"id": "9c1e4f5a-...",
+//		"status": "done",
+//		"download_url": "http://config.AddrToReturn/api/user/qr-exports/9c1e4f5a-.../download",
+//		...
+//	}
+func (h *Handler) GetQRExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.qrExportsUser(w, r)
+	if !ok {
+		return
+	}
+
+	exp, err := h.qrExports.Get(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		h.qrExportError(w, r, "failed to get QR export", err)
+		return
+	}
+
+	h.writeQRExport(w, r, http.StatusOK, exp)
+}
+
+// GetQRExportDownload streams the rendered file of the QR export.
+// The export that is not done yet is a conflict.
+//
+// Request:
+//
+//	GET /api/user/qr-exports/{id}/download
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/pdf
+//	Content-Disposition: attachment; filename="qr-codes-9c1e4f5a-....pdf"
+//
+//	<PDF file>
+func (h *Handler) GetQRExportDownload(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.qrExportsUser(w, r)
+	if !ok {
+		return
+	}
+
+	f, exp, err := h.qrExports.Open(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		h.qrExportError(w, r, "failed to download QR export", err)
+		return
+	}
+	defer func() {
+		if err = f.Close(); err != nil {
+			h.logger.Errorf("close QR export file: %v", err)
+		}
+	}()
+
+	name := fmt.Sprintf("qr-codes-%s.%s", exp.ID, exp.Format)
+	w.Header().Set("Content-Type", exp.Format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, exp.UpdatedAt, f)
+}
+
+// userQRLinks returns the links of the user's short URLs
+// in the given order. Deleted and unknown ones are not found.
+func (h *Handler) userQRLinks(ctx context.Context, userID user.ID, shortURLs []string) ([]qrexport.Link, error) {
+	for _, shortURL := range shortURLs {
+		if _, err := h.parseShortURL(shortURL); err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := h.store.GetAllByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("get user urls: %w", err)
+	}
+	// owned maps the short URLs of the user to their domains
+	owned := make(map[string]string, len(records))
+	for _, record := range records {
+		if !record.IsDeleted {
+			owned[string(record.ShortURL)] = record.Domain
+		}
+	}
+
+	links := make([]qrexport.Link, 0, len(shortURLs))
+	seen := make(map[string]bool, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		domain, ok := owned[shortURL]
+		if !ok {
+			return nil, fmt.Errorf("short URL %s: %w", shortURL, errs.ErrNotFound)
+		}
+		if seen[shortURL] {
+			continue
+		}
+		seen[shortURL] = true
+		links = append(links, qrexport.Link{
+			Code: shortURL,
+			URL:  h.absoluteURL(domain, models.ShortURL(shortURL)),
+		})
+	}
+
+	return links, nil
+}
+
+// campaignQRLinks returns the links of the items of the user's feeds
+// of the campaign, oldest first.
+func (h *Handler) campaignQRLinks(userID user.ID, campaign string) ([]qrexport.Link, error) {
+	found := false
+	var links []qrexport.Link
+	seen := make(map[string]bool)
+	for _, feed := range h.feeds.List(userID) {
+		if feed.Campaign != campaign {
+			continue
+		}
+		found = true
+		for _, item := range feed.Items {
+			u, err := url.Parse(item.ShortURL)
+			if err != nil || seen[item.ShortURL] {
+				continue
+			}
+			seen[item.ShortURL] = true
+			links = append(links, qrexport.Link{Code: path.Base(u.Path), URL: item.ShortURL})
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("campaign %s: %w", campaign, errs.ErrNotFound)
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("%w: campaign %s has no links yet", errs.ErrInvalidRequest, campaign)
+	}
+	return links, nil
+}
+
+// qrExportsUser checks that QR exports are enabled and returns the user
+// of the request. It writes the error response otherwise.
+func (h *Handler) qrExportsUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	if h.qrExports == nil {
+		h.jsonError(w, r, "QR exports are not supported", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return nil, false
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// qrExportError writes the error response of the QR exports.
+func (h *Handler) qrExportError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, errs.ErrInvalidRequest):
+		h.jsonError(w, r, message, err, http.StatusBadRequest)
+	case errors.Is(err, errs.ErrNotFound):
+		h.jsonError(w, r, message, err, http.StatusNotFound)
+	case errors.Is(err, errs.ErrConflict):
+		h.jsonError(w, r, message, err, http.StatusConflict)
+	default:
+		h.jsonError(w, r, message, err, http.StatusInternalServerError)
+	}
+}
+
+// writeQRExport writes the QR export as the JSON response in the naming of the request.
+func (h *Handler) writeQRExport(w http.ResponseWriter, r *http.Request, code int, exp qrexport.Export) {
+	payload := qrExportResponsePayload{Export: exp}
+	if exp.Status == qrexport.StatusDone {
+		payload.DownloadURL = h.baseURL("") + "/api/user/qr-exports/" + exp.ID + "/download"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := h.encodeData(w, r, payload); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/qrexport"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRExports(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "owner"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "owner"},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Wxyz9876", UserID: "owner"},
+		{OriginalURL: "https://example.com/", ShortURL: "Qkfd67ds", UserID: "other"},
+	}))
+
+	cfg := config.NewForTest()
+	cfg.QRExport.Dir = t.TempDir()
+	cfg.QRExport.Retention = time.Hour
+	cfg.QRExport.MaxLinks = 2
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, cfg, l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/api/user/qr-exports", handler.PostQRExport)
+	router.Get("/api/user/qr-exports/{id}", handler.GetQRExport)
+	router.Get("/api/user/qr-exports/{id}/download", handler.GetQRExportDownload)
+
+	do := func(userID user.ID, method, path, body string) (*http.Response, []byte) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: userID}))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, b
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "no links", body: `{}`, want: http.StatusBadRequest},
+		{name: "both links and campaign", body: `{"short_urls": ["RTfd56hn"], "campaign": "go"}`,
+			want: http.StatusBadRequest},
+		{name: "unsupported format", body: `{"format": "svg", "short_urls": ["RTfd56hn"]}`,
+			want: http.StatusBadRequest},
+		{name: "link of another user", body: `{"short_urls": ["Qkfd67ds"]}`, want: http.StatusNotFound},
+		{name: "too many links", body: `{"short_urls": ["RTfd56hn", "YBbxJEcQ9vq", "Wxyz9876"]}`,
+			want: http.StatusRequestEntityTooLarge},
+		{name: "feeds are disabled", body: `{"campaign": "go"}`, want: http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := do("owner", http.MethodPost, "/api/user/qr-exports", tt.body)
+			assert.Equal(t, tt.want, res.StatusCode, string(body))
+		})
+	}
+
+	res, body := do("owner", http.MethodPost, "/api/user/qr-exports",
+		`{"short_urls": ["RTfd56hn", "YBbxJEcQ9vq", "RTfd56hn"]}`)
+	require.Equal(t, http.StatusAccepted, res.StatusCode, string(body))
+	var created qrExportResponsePayload
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, qrexport.FormatZIP, created.Format)
+	assert.Equal(t, 2, created.Links, "duplicates are exported once")
+
+	path := "/api/user/qr-exports/" + created.ID
+	res, _ = do("other", http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "exports of the other users are not disclosed")
+
+	var got qrExportResponsePayload
+	require.Eventually(t, func() bool {
+		res, body = do("owner", http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, json.Unmarshal(body, &got))
+		return got.Status == qrexport.StatusDone
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, strings.HasSuffix(got.DownloadURL, path+"/download"))
+
+	res, body = do("owner", http.MethodGet, path+"/download", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/zip", res.Header.Get(contentType))
+	assert.Contains(t, res.Header.Get("Content-Disposition"), created.ID+".zip")
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"RTfd56hn.png", "YBbxJEcQ9vq.png"}, names)
+}
+
+func TestQRExports_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+	defer handler.Stop()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/qr-exports", strings.NewReader(`{}`))
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "owner"}))
+	w := httptest.NewRecorder()
+
+	handler.PostQRExport(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
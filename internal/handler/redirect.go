@@ -2,17 +2,41 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
-	"regexp"
+	"net/url"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-// Base58Regexp is a regular expression that matches a valid Base58-encoded string.
-// It is used to validate the format of shortened URLs.
-var Base58Regexp = regexp.MustCompile(`^[A-HJ-NP-Za-km-z1-9]+$`)
+// parseShortURL parses the short URL code accepted from the client: either
+// base58, as the aliases and the codes generated by default, or of the
+// configured alphabet, and not longer than the configured maximum length.
+// The error wraps both ErrInvalidShortURL and ErrInvalidRequest.
+func (h *Handler) parseShortURL(s string) (models.ShortURL, error) {
+	shortURL, err := h.shortURLs.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	return shortURL, nil
+}
+
+// staleWarning is the Warning header value of the redirects served
+// while the storage is degraded.
+const staleWarning = `110 - "Response is Stale"`
+
+// notFoundCodeParam is the query parameter of the not found redirect
+// carrying the attempted short code.
+const notFoundCodeParam = "code"
+
+// degradable is implemented by the storages serving possibly stale
+// records while the database is unavailable.
+type degradable interface {
+	Degraded() bool
+}
 
 // GetRedirect serves a redirect to the original URL based on the shortened URL.
 //
@@ -24,35 +48,94 @@ var Base58Regexp = regexp.MustCompile(`^[A-HJ-NP-Za-km-z1-9]+$`)
 //
 //	HTTP/1.1 307 Temporary Redirect
 //	Header "Location" contains original url
+//
+// The short URLs of the configured domains redirect only from their hosts.
+// Deleted and expired URLs respond with 410 Gone, the ones paused by their
+// owners with 503 Service Unavailable and the blocked ones with 451
+// Unavailable For Legal Reasons. Redirects over the limit set by the owner
+// respond with 429 Too Many Requests. While the storage is degraded,
+// the redirects are served from the cache and have the Warning header set.
+// Browsers get a localized HTML page instead of the plain text error
+// if the URL is invalid, not found, not active or over the limit.
+// If the interstitial page is enabled, browsers get the page confirming
+// they leave for the untrusted destination with 200 OK instead of the redirect.
+// If the not found redirect is configured, the invalid and unknown
+// short URLs redirect to it with 302 Found instead, except for the API
+// clients accepting JSON, which get 404 Not Found.
 func (h *Handler) GetRedirect(w http.ResponseWriter, r *http.Request) {
 	// check request method
 	if r.Method != http.MethodGet {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	shortURL := chi.URLParam(r, "shortURL")
 
 	// check if shortened URL is valid
-	if !Base58Regexp.MatchString(shortURL) {
-		h.textError(w, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+	if _, err := h.parseShortURL(shortURL); err != nil {
+		if h.notFoundFallback(w, r, shortURL) {
+			return
+		}
+		if wantsHTML(r) {
+			h.errorPage(w, r, http.StatusBadRequest, "page.not_found", shortURL)
+			return
+		}
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
 		return
 	}
 
 	// get original URL
 	record, err := h.store.Get(r.Context(), models.ShortURL(shortURL))
+	// the short URL of another domain is not found on this one
+	if err == nil && !h.servesDomain(r, record.Domain) {
+		err = fmt.Errorf("%s on %s: %w", shortURL, r.Host, errs.ErrNotFound)
+	}
 	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
-			h.textError(w, "no such URL", errs.ErrNotFound, http.StatusBadRequest)
+			if h.notFoundFallback(w, r, shortURL) {
+				return
+			}
+			if wantsHTML(r) {
+				h.errorPage(w, r, http.StatusBadRequest, "page.not_found", shortURL)
+				return
+			}
+			h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusBadRequest)
 			return
 		}
-		h.textError(w, "failed to retrieve url", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
 		return
 	}
 
-	if record.IsDeleted {
-		w.WriteHeader(http.StatusGone)
+	// only the active links redirect
+	switch record.Lifecycle(time.Now()) {
+	case models.LinkDeleted, models.LinkExpired:
+		h.unavailable(w, r, shortURL, http.StatusGone, "page.gone")
+		return
+	case models.LinkBlocked:
+		h.unavailable(w, r, shortURL, http.StatusUnavailableForLegalReasons, "page.blocked")
+		return
+	case models.LinkPaused:
+		h.unavailable(w, r, shortURL, http.StatusServiceUnavailable, "page.paused")
+		return
+	}
+
+	// respect the redirect limit set by the owner
+	if retryAfter := h.allowRedirect(r, record); retryAfter > 0 {
+		h.redirectLimited(w, r, shortURL, retryAfter)
+		return
+	}
+
+	// update last access time asynchronously
+	h.recordAccess(r, record.ShortURL)
+
+	// warn that the record may be stale
+	if h.degraded() {
+		w.Header().Set("Warning", staleWarning)
+	}
+
+	// browsers confirm leaving for the untrusted destinations
+	if wantsHTML(r) && h.needsInterstitial(record.OriginalURL) {
+		h.interstitial(w, r, shortURL, record.OriginalURL)
 		return
 	}
 
@@ -61,3 +144,40 @@ func (h *Handler) GetRedirect(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Location", string(record.OriginalURL))
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
+
+// unavailable responds to the short URL not redirecting in its state
+// with the status code, and with the error page to the browsers.
+func (h *Handler) unavailable(w http.ResponseWriter, r *http.Request, shortURL string, code int, page string) {
+	if wantsHTML(r) {
+		h.errorPage(w, r, code, page, shortURL)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+// notFoundFallback responds to the unknown short URL according to
+// the not found redirect configuration. It reports false if the redirect
+// is not configured and the response is not written.
+func (h *Handler) notFoundFallback(w http.ResponseWriter, r *http.Request, shortURL string) bool {
+	if h.config.NotFoundRedirect == "" {
+		return false
+	}
+
+	if wantsJSON(r) {
+		h.jsonError(w, r, "no such URL", fmt.Errorf("%w: %s", errs.ErrNotFound, shortURL), http.StatusNotFound)
+		return true
+	}
+
+	// the URL is validated on load
+	target, err := url.Parse(h.config.NotFoundRedirect)
+	if err != nil {
+		h.jsonError(w, r, "invalid not found redirect", err, http.StatusInternalServerError)
+		return true
+	}
+	q := target.Query()
+	q.Set(notFoundCodeParam, shortURL)
+	target.RawQuery = q.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+	return true
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// redirectLimitPeriod is the period the redirect limits are given for.
+const redirectLimitPeriod = time.Minute
+
+type redirectLimitRequestPayload struct {
+	Limit int `json:"limit"`
+}
+
+// PutRedirectLimit caps the redirects per minute of the short URL
+// owned by the user, e.g. for the bandwidth-sensitive destinations.
+// Zero limit removes the cap. Short URLs of other users are reported
+// as not found.
+//
+// Request:
+//
+//	PUT /api/user/urls/{shortURL}/redirect-limit
+//	Content-Type: application/json
+//	{ "limit": 60 }
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) PutRedirectLimit(w http.ResponseWriter, r *http.Request) {
+	if h.redirectLimits == nil {
+		h.jsonError(w, r, "redirect limits are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL := chi.URLParam(r, "shortURL")
+
+	// check if shortened URL is valid
+	if _, err := h.parseShortURL(shortURL); err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	var payload redirectLimitRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	if err := models.ValidateRedirectLimit(payload.Limit); err != nil {
+		h.jsonError(w, r, "invalid limit", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+			http.StatusBadRequest)
+		return
+	}
+
+	err := h.redirectLimits.SetRedirectLimit(r.Context(), user.ID, models.ShortURL(shortURL), payload.Limit)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, models.ShortURL(shortURL), user.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to set redirect limit", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowRedirect takes a token from the redirect bucket of the URL
+// if its owner capped the redirects. It returns the time until the next
+// redirect is allowed, zero if the redirect is allowed now. Redirects
+// are let through if the limiter fails.
+func (h *Handler) allowRedirect(r *http.Request, record *models.URL) time.Duration {
+	if record.RedirectLimit <= 0 {
+		return 0
+	}
+
+	key := "redirect:" + string(record.ShortURL)
+	res, err := h.limiter.Allow(r.Context(), key, record.RedirectLimit, redirectLimitPeriod)
+	if err != nil {
+		h.logger.Errorf("rate limit %s: %s", key, err)
+		return 0
+	}
+	if res.Allowed {
+		return 0
+	}
+
+	return max(res.RetryAfter, time.Second)
+}
+
+// redirectLimited responds to the redirect over the limit with
+// 429 Too Many Requests. Browsers get a localized HTML page asking
+// to come back later.
+func (h *Handler) redirectLimited(w http.ResponseWriter, r *http.Request, shortURL string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	if wantsHTML(r) {
+		h.errorPage(w, r, http.StatusTooManyRequests, "page.busy", shortURL, seconds)
+		return
+	}
+	h.jsonError(w, r, "too many redirects, try again later", errs.ErrInvalidRequest, http.StatusTooManyRequests)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutRedirectLimit(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Foreign", UserID: "other"},
+	}))
+
+	tests := []struct {
+		name       string
+		shortURL   string
+		body       string
+		user       *user.User
+		disabled   bool
+		statusCode int
+		wantLimit  int
+	}{
+		{
+			name:       "positive test",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": 60}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNoContent,
+			wantLimit:  60,
+		},
+		{
+			name:       "limit removed",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": 0}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNoContent,
+			wantLimit:  0,
+		},
+		{
+			name:       "negative limit",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": -1}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid body",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": "many"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "URL of another user",
+			shortURL:   "Foreign",
+			body:       `{"limit": 60}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "no user",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": 60}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "redirect limits disabled",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"limit": 60}`,
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithRedirectLimits(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodPut, "/api/user/urls/{shortURL}/redirect-limit",
+				strings.NewReader(tt.body))
+			r.Header.Set(contentType, applicationJSON)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.user != nil {
+				ctx = user.NewContext(ctx, tt.user)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.PutRedirectLimit(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusNoContent {
+				return
+			}
+			record, err := store.Get(context.TODO(), models.ShortURL(tt.shortURL))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLimit, record.RedirectLimit)
+		})
+	}
+}
+
+func TestGetRedirect_RedirectLimit(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test", RedirectLimit: 2},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Free", UserID: "test"},
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	redirect := func(shortURL string, html bool) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+		if html {
+			r.Header.Set("Accept", "text/html")
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("shortURL", shortURL)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetRedirect(w, r)
+		res := w.Result()
+		require.NoError(t, res.Body.Close())
+		return res
+	}
+
+	// the burst of limit redirects is allowed
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusTemporaryRedirect, redirect("YBbxJEcQ9vq", false).StatusCode)
+	}
+
+	res := redirect("YBbxJEcQ9vq", false)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "30", res.Header.Get("Retry-After"))
+	assert.Empty(t, res.Header.Get("Location"))
+
+	// browsers get the page asking to come back later
+	res = redirect("YBbxJEcQ9vq", true)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Contains(t, res.Header.Get(contentType), "text/html")
+
+	// other links are not limited
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusTemporaryRedirect, redirect("Free", false).StatusCode)
+	}
+}
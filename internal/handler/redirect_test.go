@@ -6,8 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/KretovDmitry/shortener/internal/clickexport"
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
@@ -37,6 +40,7 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+				assert.Equal(t, textPlain, res.Header.Get(contentType))
 				assert.Equal(t, "https://e.mail.ru/inbox/", res.Header.Get("Location"))
 			},
 		},
@@ -51,6 +55,7 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+				assert.Equal(t, textPlain, res.Header.Get(contentType))
 				assert.Equal(t, "https://go.dev/", res.Header.Get("Location"))
 			},
 		},
@@ -62,9 +67,10 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				assert.Equal(t,
-					fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPost),
-					getResponseTextPayload(t, res))
+				assert.Equal(t, envelope.Error{
+					Code:    "invalid_request",
+					Message: fmt.Sprintf("method %s is not allowed", http.MethodPost),
+				}, getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -75,9 +81,10 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				assert.Equal(t,
-					fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPut),
-					getResponseTextPayload(t, res))
+				assert.Equal(t, envelope.Error{
+					Code:    "invalid_request",
+					Message: fmt.Sprintf("method %s is not allowed", http.MethodPut),
+				}, getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -88,9 +95,10 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				assert.Equal(t,
-					fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPatch),
-					getResponseTextPayload(t, res))
+				assert.Equal(t, envelope.Error{
+					Code:    "invalid_request",
+					Message: fmt.Sprintf("method %s is not allowed", http.MethodPatch),
+				}, getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -101,9 +109,10 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				assert.Equal(t,
-					fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodDelete),
-					getResponseTextPayload(t, res))
+				assert.Equal(t, envelope.Error{
+					Code:    "invalid_request",
+					Message: fmt.Sprintf("method %s is not allowed", http.MethodDelete),
+				}, getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -114,8 +123,12 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				resBody := getResponseTextPayload(t, res)
-				assert.Equal(t, fmt.Sprintf("%s: invalid URL", errs.ErrInvalidRequest), resBody)
+				assert.Equal(t, envelope.Error{
+					Code:    "invalid_request",
+					Message: "invalid URL",
+					Details: fmt.Sprintf("%s: %s: \"O0Il0O\" has unexpected characters",
+						errs.ErrInvalidRequest, models.ErrInvalidShortURL),
+				}, getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -126,8 +139,8 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-				resBody := getResponseTextPayload(t, res)
-				assert.Equal(t, fmt.Sprintf("%s: no such URL", errs.ErrNotFound), resBody)
+				assert.Equal(t, envelope.Error{Code: "invalid_request", Message: "no such URL"},
+					getResponseErrorPayload(t, res))
 			},
 		},
 		{
@@ -138,8 +151,8 @@ func TestGetRedirect(t *testing.T) {
 			assertResponse: func(res *http.Response) {
 				require.NoError(t, res.Body.Close(), "failed close body")
 				assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
-				resBody := getResponseTextPayload(t, res)
-				assert.Equal(t, fmt.Sprintf("%s: failed to retrieve url", errIntentionallyNotWorkingMethod), resBody)
+				assert.Equal(t, envelope.Error{Code: "internal", Message: "failed to retrieve url"},
+					getResponseErrorPayload(t, res))
 			},
 		},
 	}
@@ -171,8 +184,264 @@ func TestGetRedirect(t *testing.T) {
 			require.NoError(t, res.Body.Close(), "failed close body")
 
 			// assert wanted data
-			assert.Equal(t, textPlain, res.Header.Get(contentType))
 			tt.assertResponse(res)
 		})
 	}
 }
+
+func TestGetRedirect_LastAccessed(t *testing.T) {
+	shortURL := models.ShortURL("YBbxJEcQ9vq")
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    shortURL,
+	})
+
+	l, _ := logger.NewForTest()
+	c := config.NewForTest()
+
+	handler, err := New(store, c, l)
+	require.NoError(t, err, "new handler context error")
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", string(shortURL))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	before := time.Now().UTC()
+	handler.GetRedirect(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+
+	// Stop flushes the buffered last access updates.
+	handler.Stop()
+
+	record, err := store.Get(context.TODO(), shortURL)
+	require.NoError(t, err)
+	require.NotNil(t, record.LastAccessedAt, "last access time is not set")
+	assert.False(t, record.LastAccessedAt.Before(before))
+}
+
+func TestGetRedirect_HTMLPages(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Removed", IsDeleted: true},
+	}))
+
+	tests := []struct {
+		name           string
+		shortURL       string
+		acceptLanguage string
+		statusCode     int
+		wantLanguage   string
+		wantText       string
+	}{
+		{
+			name:           "not found in english",
+			shortURL:       "YBbxJEcQ9vq",
+			acceptLanguage: "en-US,en;q=0.9",
+			statusCode:     http.StatusBadRequest,
+			wantLanguage:   "en",
+			wantText:       "Link not found",
+		},
+		{
+			name:           "not found in russian",
+			shortURL:       "YBbxJEcQ9vq",
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			statusCode:     http.StatusBadRequest,
+			wantLanguage:   "ru",
+			wantText:       "Ссылка не найдена",
+		},
+		{
+			name:           "deleted with unsupported language",
+			shortURL:       "Removed",
+			acceptLanguage: "de",
+			statusCode:     http.StatusGone,
+			wantLanguage:   "en",
+			wantText:       "Link deleted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+			r.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.GetRedirect(w, r)
+
+			res := w.Result()
+			body := getResponseTextPayload(t, res)
+
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+			assert.Equal(t, tt.wantLanguage, res.Header.Get("Content-Language"))
+			assert.Contains(t, body, tt.wantText)
+			assert.Contains(t, body, tt.shortURL)
+		})
+	}
+}
+
+// degradedStore is the storage serving stale records.
+type degradedStore struct {
+	*memstore.URLRepository
+}
+
+func (s *degradedStore) Degraded() bool { return true }
+
+func TestGetRedirect_Degraded(t *testing.T) {
+	store := &degradedStore{initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+	})}
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", "YBbxJEcQ9vq")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.GetRedirect(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	assert.Equal(t, staleWarning, res.Header.Get("Warning"))
+}
+
+// exporterFunc exports the clicks with the function.
+type exporterFunc func(context.Context, []*models.Click) error
+
+func (f exporterFunc) Name() string { return "func" }
+
+func (f exporterFunc) Export(ctx context.Context, clicks []*models.Click) error {
+	return f(ctx, clicks)
+}
+
+func TestGetRedirect_ExportsClicks(t *testing.T) {
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+	})
+
+	var exported []*models.Click
+	exporter := exporterFunc(func(_ context.Context, clicks []*models.Click) error {
+		exported = append(exported, clicks...)
+		return nil
+	})
+
+	l, _ := logger.NewForTest()
+	c := config.NewForTest()
+	handler, err := New(store, c, l, WithClickExporter(
+		clickexport.NewDispatcher([]clickexport.Exporter{exporter}, c.ClickExport, l)))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	r.Header.Set("Referer", "https://go.dev/blog/")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", "YBbxJEcQ9vq")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.GetRedirect(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+
+	// Stop exports the buffered clicks.
+	handler.Stop()
+
+	require.Len(t, exported, 1)
+	assert.Equal(t, models.ShortURL("YBbxJEcQ9vq"), exported[0].ShortURL)
+	assert.Equal(t, "https://go.dev/blog/", exported[0].Referrer)
+}
+
+func TestGetRedirect_NotFoundRedirect(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "Removed", IsDeleted: true},
+	}))
+
+	tests := []struct {
+		name         string
+		shortURL     string
+		accept       string
+		statusCode   int
+		wantLocation string
+		wantJSON     string
+	}{
+		{
+			name:         "browser",
+			shortURL:     "YBbxJEcQ9vq",
+			accept:       "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8",
+			statusCode:   http.StatusFound,
+			wantLocation: "https://example.com/search?code=YBbxJEcQ9vq&q=links",
+		},
+		{
+			name:         "any client",
+			shortURL:     "YBbxJEcQ9vq",
+			statusCode:   http.StatusFound,
+			wantLocation: "https://example.com/search?code=YBbxJEcQ9vq&q=links",
+		},
+		{
+			name:         "invalid short URL",
+			shortURL:     "0OIl",
+			statusCode:   http.StatusFound,
+			wantLocation: "https://example.com/search?code=0OIl&q=links",
+		},
+		{
+			name:       "API client",
+			shortURL:   "YBbxJEcQ9vq",
+			accept:     "application/json",
+			statusCode: http.StatusNotFound,
+			wantJSON:   `{"code": "not_found", "message": "no such URL", "details": "not found: YBbxJEcQ9vq"}`,
+		},
+		{
+			name:       "deleted URL",
+			shortURL:   "Removed",
+			statusCode: http.StatusGone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewForTest()
+			cfg.NotFoundRedirect = "https://example.com/search?q=links"
+			l, _ := logger.NewForTest()
+			handler, err := New(store, cfg, l)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+			r.Header.Set("Accept", tt.accept)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.GetRedirect(w, r)
+
+			res := w.Result()
+			body := getResponseTextPayload(t, res)
+
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			assert.Equal(t, tt.wantLocation, res.Header.Get("Location"))
+			if tt.wantJSON != "" {
+				assert.Equal(t, applicationJSON, res.Header.Get(contentType))
+				assert.JSONEq(t, tt.wantJSON, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxReservationCount is the maximum number of codes
+	// that can be reserved in a single request.
+	maxReservationCount = 1000
+	// maxGenerateAttempts is the number of alternative short URLs tried
+	// before giving up when the generated ones are reserved or taken.
+	maxGenerateAttempts = 10
+)
+
+type (
+	reservationsRequestPayload struct {
+		UserID string   `json:"user_id"`
+		Codes  []string `json:"codes"`
+		Count  int      `json:"count"`
+	}
+
+	reservationsResponsePayload struct {
+		Codes []models.ShortURL `json:"codes"`
+	}
+)
+
+// PostReservations handles the reservation of short codes.
+// Reserved codes are never assigned by the generator and can be bound
+// to destinations only by the reservation owner via the custom alias.
+// Either the list of codes or the number of random codes to reserve
+// should be provided.
+//
+// Request:
+//
+//	POST /api/admin/reservations
+//	Content-Type: application/json
+//	{ "user_id": "campaign-owner", "codes": ["Spring24", "Summer24"] }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{ "codes": ["Spring24", "Summer24"] }
+func (h *Handler) PostReservations(w http.ResponseWriter, r *http.Request) {
+	if h.reservations == nil {
+		h.jsonError(w, r, "reservations are not supported", errs.ErrInvalidRequest, http.StatusNotImplemented)
+		return
+	}
+
+	// check content type
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// decode the request body
+	var payload reservationsRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	userID, err := user.ParseID(payload.UserID)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.reservationCodes(payload)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	reservations := make([]*models.Reservation, len(codes))
+	for i, code := range codes {
+		reservations[i] = &models.Reservation{ShortURL: code, UserID: userID}
+	}
+
+	err = h.reservations.Reserve(r.Context(), reservations...)
+	if err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			h.jsonError(w, r, "code is already taken", err, http.StatusConflict)
+			return
+		}
+		h.jsonError(w, r, "failed to save to database", err, http.StatusInternalServerError)
+		return
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	// encode the response body
+	if err = h.encodeData(w, r, reservationsResponsePayload{Codes: codes}); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// reservationCodes validates the requested codes or generates
+// the requested number of random ones.
+func (h *Handler) reservationCodes(payload reservationsRequestPayload) ([]models.ShortURL, error) {
+	switch {
+	case len(payload.Codes) > 0 && payload.Count > 0:
+		return nil, errors.New("either codes or count should be provided")
+	case len(payload.Codes) > maxReservationCount || payload.Count > maxReservationCount:
+		return nil, fmt.Errorf("no more than %d codes can be reserved at once", maxReservationCount)
+	case payload.Count < 0:
+		return nil, errors.New("count should be positive")
+	}
+
+	if payload.Count > 0 {
+		codes := make([]models.ShortURL, payload.Count)
+		seen := make(map[string]struct{}, payload.Count)
+		for i := range codes {
+			code, err := h.codes.Random()
+			if err != nil {
+				return nil, fmt.Errorf("generate code: %w", err)
+			}
+			if _, ok := seen[code]; ok {
+				return nil, errors.New("generated codes collided, try again")
+			}
+			seen[code] = struct{}{}
+			codes[i] = models.ShortURL(code)
+		}
+		return codes, nil
+	}
+
+	if len(payload.Codes) == 0 {
+		return nil, errors.New("codes are not provided")
+	}
+
+	codes := make([]models.ShortURL, len(payload.Codes))
+	seen := make(map[string]struct{}, len(payload.Codes))
+	for i, code := range payload.Codes {
+		if _, err := h.parseShortURL(code); err != nil {
+			return nil, fmt.Errorf("invalid code: %w", err)
+		}
+		if _, ok := seen[code]; ok {
+			return nil, fmt.Errorf("duplicate code: %q", code)
+		}
+		seen[code] = struct{}{}
+		codes[i] = models.ShortURL(code)
+	}
+
+	return codes, nil
+}
+
+// errNoFreeCode is returned when all the generated short URLs
+// are reserved or taken.
+var errNoFreeCode = errors.New("all generated codes are reserved or taken")
+
+// generateShortURL produces a short URL for the original one skipping
+// the reserved codes and the ones taken by other original URLs.
+// It returns errNoFreeCode if no free code was found.
+func (h *Handler) generateShortURL(ctx context.Context, originalURL string) (string, error) {
+	shortURL, _, err := h.nextShortURL(ctx, originalURL, 0)
+	return shortURL, err
+}
+
+// nextShortURL produces the first free short URL for the original one
+// trying the salts starting with the given one. It returns the short URL
+// and its salt.
+func (h *Handler) nextShortURL(ctx context.Context, originalURL string, salt uint64) (string, uint64, error) {
+	if h.reservations == nil && !h.codes.FixedLength() {
+		return h.codes.GenerateSalted(originalURL, salt), salt, nil
+	}
+
+	for ; salt < maxGenerateAttempts; salt++ {
+		shortURL := h.codes.GenerateSalted(originalURL, salt)
+
+		free, err := h.codeFree(ctx, shortURL, originalURL)
+		if err != nil {
+			return "", 0, err
+		}
+		if free {
+			return shortURL, salt, nil
+		}
+	}
+
+	return "", 0, errNoFreeCode
+}
+
+// saveGenerated generates the short URL of the record and saves it.
+// If the short URL turns out to be taken by another original URL, e.g.
+// on the hash collision or by the concurrent request, the record is saved
+// with the next salted one instead of reporting the misleading conflict.
+// ErrConflict is returned only if the original URL is already shortened.
+func (h *Handler) saveGenerated(ctx context.Context, record *models.URL) error {
+	originalURL := string(record.OriginalURL)
+
+	for salt := uint64(0); salt < maxGenerateAttempts; salt++ {
+		shortURL, next, err := h.nextShortURL(ctx, originalURL, salt)
+		if err != nil {
+			return fmt.Errorf("generate short URL: %w", err)
+		}
+		salt = next
+		record.ShortURL = models.ShortURL(shortURL)
+
+		err = h.store.Save(ctx, record)
+		if !errors.Is(err, errs.ErrConflict) {
+			return err
+		}
+
+		// the conflict is on the original URL if the short URL is free
+		existing, getErr := h.store.Get(ctx, record.ShortURL)
+		if errors.Is(getErr, errs.ErrNotFound) || getErr == nil && existing.OriginalURL == record.OriginalURL {
+			return err
+		}
+		if getErr != nil {
+			return fmt.Errorf("check collision: %w", getErr)
+		}
+		h.logger.Infof("short URL %s is taken by another URL, regenerating", shortURL)
+	}
+
+	return fmt.Errorf("generate short URL: %w", errNoFreeCode)
+}
+
+// useExisting sets the short URL and the domain of the conflicting record
+// to the ones its original URL is already saved under, so that the conflict
+// reports the short URL redirecting to it even if it is not the generated
+// one, e.g. an alias. The record is kept if the lookup is not supported.
+func (h *Handler) useExisting(ctx context.Context, record *models.URL) {
+	if h.originals == nil {
+		return
+	}
+
+	existing, err := h.originals.GetByOriginalURL(ctx, record.OriginalURL)
+	if err != nil {
+		if !errors.Is(err, errs.ErrNotFound) {
+			h.logger.Errorf("failed to get the existing short URL: %s", err)
+		}
+		return
+	}
+	record.ShortURL = existing.ShortURL
+	record.Domain = existing.Domain
+}
+
+// codeFree reports whether the generated code can be used for the original
+// URL: it is not reserved and not taken by another original URL. Only the
+// codes of the configured length are checked against the stored ones,
+// as the natural length ones hardly ever collide. The code taken by the same
+// original URL is free, so that the shortening reports the conflict.
+func (h *Handler) codeFree(ctx context.Context, shortURL, originalURL string) (bool, error) {
+	if h.reservations != nil {
+		_, err := h.reservations.GetReservation(ctx, models.ShortURL(shortURL))
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, errs.ErrNotFound) {
+			return false, fmt.Errorf("check reservation: %w", err)
+		}
+	}
+
+	if !h.codes.FixedLength() {
+		return true, nil
+	}
+
+	record, err := h.store.Get(ctx, models.ShortURL(shortURL))
+	if errors.Is(err, errs.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check collision: %w", err)
+	}
+
+	return record.OriginalURL == models.OriginalURL(originalURL), nil
+}
+
+// checkAlias checks that the custom alias is a valid code that
+// the user is allowed to bind: either not reserved or reserved by the user.
+func (h *Handler) checkAlias(ctx context.Context, alias string, userID user.ID) error {
+	if _, err := h.parseShortURL(alias); err != nil {
+		return fmt.Errorf("invalid alias: %w", err)
+	}
+
+	if h.reservations == nil {
+		return nil
+	}
+
+	reservation, err := h.reservations.GetReservation(ctx, models.ShortURL(alias))
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check reservation: %w", err)
+	}
+	if reservation.UserID != userID {
+		return fmt.Errorf("alias is reserved: %w", errs.ErrConflict)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/KretovDmitry/shortener/internal/repository/sqlitestore"
+	"github.com/KretovDmitry/shortener/internal/shorturl"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostReservations(t *testing.T) {
+	path := "/api/admin/reservations"
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantCodes  []models.ShortURL
+		wantCount  int
+		statusCode int
+	}{
+		{
+			name:       "positive test #1: codes",
+			payload:    `{"user_id":"owner","codes":["Spring24","Summer24"]}`,
+			wantCodes:  []models.ShortURL{"Spring24", "Summer24"},
+			wantCount:  2,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "positive test #2: count",
+			payload:    `{"user_id":"owner","count":5}`,
+			wantCount:  5,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "conflict: code is already reserved",
+			payload:    `{"user_id":"owner","codes":["Taken"]}`,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "conflict: code is used by URL",
+			payload:    `{"user_id":"owner","codes":["YBbxJEcQ9vq"]}`,
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "invalid code",
+			payload:    `{"user_id":"owner","codes":["O0Il"]}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "both codes and count",
+			payload:    `{"user_id":"owner","codes":["Spring24"],"count":1}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "too many codes",
+			payload:    `{"user_id":"owner","count":1001}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "nothing to reserve",
+			payload:    `{"user_id":"owner"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid user ID",
+			payload:    `{"codes":["Spring24"]}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := initMockStore(&models.URL{
+				OriginalURL: "https://go.dev/",
+				ShortURL:    "YBbxJEcQ9vq",
+				UserID:      "test",
+			})
+			require.NoError(t, store.Reserve(context.TODO(),
+				&models.Reservation{ShortURL: "Taken", UserID: "owner"}))
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.payload))
+			r.Header.Set(contentType, applicationJSON)
+			w := httptest.NewRecorder()
+
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, WithReservations(store))
+			require.NoError(t, err, "new handler error")
+
+			handler.PostReservations(w, r)
+
+			res := w.Result()
+			defer func() { _ = res.Body.Close() }()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusCreated {
+				return
+			}
+
+			var got reservationsResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.Len(t, got.Codes, tt.wantCount)
+			if tt.wantCodes != nil {
+				assert.Equal(t, tt.wantCodes, got.Codes)
+			}
+			for _, code := range got.Codes {
+				reservation, err := store.GetReservation(context.TODO(), code)
+				require.NoError(t, err)
+				assert.Equal(t, user.ID("owner"), reservation.UserID)
+			}
+		})
+	}
+}
+
+func TestPostReservations_NotSupported(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/reservations",
+		strings.NewReader(`{"user_id":"owner","count":1}`))
+	r.Header.Set(contentType, applicationJSON)
+	w := httptest.NewRecorder()
+
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	handler.PostReservations(w, r)
+
+	res := w.Result()
+	require.NoError(t, res.Body.Close(), "failed close body")
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+}
+
+func TestPostShorten_SkipsReservedCodes(t *testing.T) {
+	originalURL := "https://go.dev/"
+	reserved := models.ShortURL(shorturl.Generate(originalURL))
+
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.Reserve(context.TODO(),
+		&models.Reservation{ShortURL: reserved, UserID: "owner"}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithReservations(store))
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL))
+	r.Header.Set(contentType, textPlain)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.PostShortenText(w, r)
+
+	res := w.Result()
+	got := models.ShortURL(getShortURL(getResponseTextPayload(t, res)))
+
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.NotEqual(t, reserved, got)
+	assert.Equal(t, models.ShortURL(shorturl.GenerateSalted(originalURL, 1)), got)
+}
+
+func TestPostShorten_SkipsCollidingCodes(t *testing.T) {
+	originalURL := "https://go.dev/"
+	c := config.NewForTest()
+	c.ShortURL.Length = 4
+	c.ShortURL.Alphabet = "0123456789-_"
+	codes, err := shorturl.New(shorturl.WithLength(4), shorturl.WithAlphabet(c.ShortURL.Alphabet))
+	require.NoError(t, err)
+	taken := models.ShortURL(codes.Generate(originalURL))
+
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.Save(context.TODO(), models.NewRecord(string(taken), "https://pkg.go.dev/", "other")))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, c, l)
+	require.NoError(t, err, "new handler error")
+
+	shorten := func() (int, models.ShortURL) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL))
+		r.Header.Set(contentType, textPlain)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+		handler.PostShortenText(w, r)
+		res := w.Result()
+		return res.StatusCode, models.ShortURL(getShortURL(getResponseTextPayload(t, res)))
+	}
+
+	status, got := shorten()
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, models.ShortURL(codes.GenerateSalted(originalURL, 1)), got)
+
+	// the same original URL gets its code again
+	status, again := shorten()
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, got, again)
+
+	// the codes of the configured alphabet redirect
+	r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shortURL", string(got))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	handler.GetRedirect(w, r)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, originalURL, w.Header().Get("Location"))
+}
+
+func TestPostShortenJSON_RegeneratesOnSaveCollision(t *testing.T) {
+	originalURL := "https://go.dev/"
+	taken := models.ShortURL(shorturl.Generate(originalURL))
+
+	// the natural length codes are not checked before saving
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.Save(context.TODO(), models.NewRecord(string(taken), "https://pkg.go.dev/", "other")))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	shorten := func() (int, string) {
+		body := fmt.Sprintf(`{"url": %q}`, originalURL)
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+		handler.PostShortenJSON(w, r)
+
+		var payload shortenJSONResponsePayload
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&payload))
+		return w.Code, payload.Result
+	}
+
+	want := fmt.Sprintf("http://%s/%s", handler.config.HTTPServer.ReturnAddress,
+		shorturl.GenerateSalted(originalURL, 1))
+
+	status, got := shorten()
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, want, got)
+
+	// the same original URL is the conflict
+	status, got = shorten()
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, want, got)
+}
+
+func TestPostShortenText_OriginalURLUnderOtherCode(t *testing.T) {
+	originalURL := "https://go.dev/"
+
+	// the original URLs are unique in the database, as in postgres
+	l, _ := logger.NewForTest()
+	store, err := sqlitestore.New(sqlitestore.Scheme+filepath.Join(t.TempDir(), "shortener.db"), l)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Save(context.TODO(), models.NewRecord("GoDev", originalURL, "other")))
+
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL))
+	r.Header.Set(contentType, textPlain)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+	handler.PostShortenText(w, r)
+
+	// the generated code is free, so the conflict is on the original URL
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestPostShortenJSON_Alias(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     user.ID
+		alias      string
+		statusCode int
+	}{
+		{
+			name:       "free alias",
+			userID:     "test",
+			alias:      "Free",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "reserved alias bound by owner",
+			userID:     "owner",
+			alias:      "Spring24",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "reserved alias used by another user",
+			userID:     "test",
+			alias:      "Spring24",
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "invalid alias",
+			userID:     "test",
+			alias:      "O0Il",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewURLRepository()
+			require.NoError(t, store.Reserve(context.TODO(),
+				&models.Reservation{ShortURL: "Spring24", UserID: "owner"}))
+
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, WithReservations(store))
+			require.NoError(t, err, "new handler error")
+
+			payload := `{"url":"https://go.dev/","alias":"` + tt.alias + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(payload))
+			r.Header.Set(contentType, applicationJSON)
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: tt.userID}))
+			w := httptest.NewRecorder()
+
+			handler.PostShortenJSON(w, r)
+
+			res := w.Result()
+			response := getShortenJSONResponsePayload(t, res)
+
+			require.Equal(t, tt.statusCode, res.StatusCode, response.Message)
+			if tt.statusCode == http.StatusCreated {
+				assert.Equal(t, tt.alias, getShortURL(response.Result))
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+type transferRequestPayload struct {
+	// To is the ID of the new owner.
+	To string `json:"to"`
+}
+
+// PostTransfer hands the short URL owned by the user over to another user.
+// The shares of the URL are dropped and it is removed from the campaigns
+// of the user. Short URLs of other users are reported as not found.
+//
+// Request:
+//
+//	POST /api/user/urls/{shortURL}/transfer
+//	Content-Type: application/json
+//	{ "to": "2a5c1d63-..." }
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) PostTransfer(w http.ResponseWriter, r *http.Request) {
+	if h.shares == nil {
+		h.jsonError(w, r, "sharing is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	owner, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	var payload transferRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err = h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	to, err := h.parseRecipient(owner.ID, payload.To)
+	if err != nil {
+		h.jsonError(w, r, "invalid new owner", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.shares.TransferURL(r.Context(), owner.ID, shortURL, to); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, owner.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to transfer url", err, http.StatusInternalServerError)
+		return
+	}
+	h.logger.Infof("url %s of user %s transferred to user %s", shortURL, owner.ID, to)
+	h.publishEvents(events.TypeUpdated, &models.URL{ShortURL: shortURL, UserID: to})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutShare grants the user the read access to the short URL owned
+// by the user of the request: the URL is listed among the URLs of the user
+// it is shared with, but can't be edited, deleted or transferred by them.
+// Sharing the URL again keeps the original share.
+//
+// Request:
+//
+//	PUT /api/user/urls/{shortURL}/shares/{userID}
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+func (h *Handler) PutShare(w http.ResponseWriter, r *http.Request) {
+	if h.shares == nil {
+		h.jsonError(w, r, "sharing is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	owner, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+	with, err := h.parseRecipient(owner.ID, chi.URLParam(r, "userID"))
+	if err != nil {
+		h.jsonError(w, r, "invalid user", err, http.StatusBadRequest)
+		return
+	}
+
+	share := &models.URLShare{ShortURL: shortURL, UserID: with, CreatedAt: time.Now().UTC()}
+	if err = h.shares.ShareURL(r.Context(), owner.ID, share); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, owner.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to share url", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteShare revokes the read access of the user to the short URL
+// owned by the user of the request.
+//
+// Request:
+//
+//	DELETE /api/user/urls/{shortURL}/shares/{userID}
+//
+// Response:
+//
+//	HTTP/1.1 204 No Content
+//
+// The URLs not shared with the user respond with 404 Not Found.
+func (h *Handler) DeleteShare(w http.ResponseWriter, r *http.Request) {
+	if h.shares == nil {
+		h.jsonError(w, r, "sharing is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	owner, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+	with, err := user.ParseID(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.shares.UnshareURL(r.Context(), owner.ID, shortURL, with); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.jsonError(w, r, "no such share", errs.ErrNotFound, http.StatusNotFound)
+			return
+		}
+		h.jsonError(w, r, "failed to unshare url", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetShares returns the users the short URL owned by the user of
+// the request is shared with, in the order it was shared.
+//
+// Request:
+//
+//	GET /api/user/urls/{shortURL}/shares
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	[
+//		{
+//			"short_url": "6qxTVvsy",
+//			"user_id": "2a5c1d63-...",
+//			"created_at": "2024-06-01T12:00:00Z"
+//		}
+//	]
+func (h *Handler) GetShares(w http.ResponseWriter, r *http.Request) {
+	if h.shares == nil {
+		h.jsonError(w, r, "sharing is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	owner, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	shares, err := h.shares.GetShares(r.Context(), owner.ID, shortURL)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, owner.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to get shares", err, http.StatusInternalServerError)
+		return
+	}
+
+	shares, page, err := paginate(r, shares)
+	if err != nil {
+		h.jsonError(w, r, "invalid page", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodePage(w, r, shares, page); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseRecipient returns the ID of the user the URL of the owner
+// is transferred to or shared with. The owner can't be the recipient.
+func (h *Handler) parseRecipient(owner user.ID, s string) (user.ID, error) {
+	id, err := user.ParseID(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	if id == owner {
+		return "", fmt.Errorf("%w: the user is the owner", errs.ErrInvalidRequest)
+	}
+	return id, nil
+}
+
+// sharedWith reports whether the URL is shared with the user by its owner.
+func (h *Handler) sharedWith(ctx context.Context, record *models.URL, userID user.ID) (bool, error) {
+	if h.shares == nil {
+		return false, nil
+	}
+
+	shares, err := h.shares.GetShares(ctx, record.UserID, record.ShortURL)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, s := range shares {
+		if s.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
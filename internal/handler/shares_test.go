@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShares(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	store := memstore.NewURLRepository()
+	owner, reader, heir := user.NewID(), user.NewID(), user.NewID()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{ID: "1", ShortURL: "YBbxJEcQ9vq", OriginalURL: "https://go.dev/", UserID: owner,
+			Metadata: models.Metadata{CreatorIP: "192.0.2.1"}},
+		{ID: "2", ShortURL: "RTfd56hn", OriginalURL: "https://pkg.go.dev/", UserID: reader},
+	}))
+	_, err := store.AddToCampaign(context.TODO(), owner, "launch", []models.ShortURL{"YBbxJEcQ9vq"})
+	require.NoError(t, err)
+	handler, err := New(store, c, l, WithShares(store), WithURLUpdates(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// serve returns the recorded response to the request of the user
+	serve := func(method, target, body string, id user.ID) *httptest.ResponseRecorder {
+		s, err := jwt.BuildUserJWTString(&user.User{ID: id, Token: user.TokenRegistered}, c.JWT.SigningKey, time.Hour)
+		require.NoError(t, err)
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set(contentType, applicationJSON)
+		r.AddCookie(&http.Cookie{Name: "Authorization", Value: s})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	// list returns the URLs listed to the user
+	list := func(id user.ID) []getAllByUserIDResponsePayload {
+		w := serve(http.MethodGet, "/api/user/urls", "", id)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var got []getAllByUserIDResponsePayload
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		return got
+	}
+
+	w := serve(http.MethodPut, "/api/user/urls/YBbxJEcQ9vq/shares/"+string(heir), "", reader)
+	assert.Equal(t, http.StatusNotFound, w.Code, "only the owner shares")
+	w = serve(http.MethodPut, "/api/user/urls/YBbxJEcQ9vq/shares/"+string(owner), "", owner)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "the owner can't be the reader")
+	for i := 0; i < 2; i++ {
+		w = serve(http.MethodPut, "/api/user/urls/YBbxJEcQ9vq/shares/"+string(reader), "", owner)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	}
+
+	w = serve(http.MethodGet, "/api/user/urls/YBbxJEcQ9vq/shares", "", owner)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var shares []models.URLShare
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&shares))
+	require.Len(t, shares, 1, "sharing again keeps the share")
+	assert.Equal(t, reader, shares[0].UserID)
+
+	got := list(reader)
+	require.Len(t, got, 2)
+	assert.False(t, got[0].Shared)
+	assert.True(t, got[1].Shared)
+	assert.Equal(t, models.OriginalURL("https://go.dev/"), got[1].OriginalURL)
+	assert.Nil(t, got[1].Metadata, "the metadata is private to the owner")
+
+	// the shared URL is read-only
+	w = serve(http.MethodPatch, "/api/user/urls/YBbxJEcQ9vq", `{"url":"https://go.dev/blog/"}`, reader)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = serve(http.MethodDelete, "/api/user/urls?sync=1", `["YBbxJEcQ9vq"]`, reader)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"forbidden"`)
+	w = serve(http.MethodPost, "/api/user/urls/YBbxJEcQ9vq/transfer", `{"to":"`+string(heir)+`"}`, reader)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodPost, "/api/user/urls/YBbxJEcQ9vq/transfer", `{"to":"`+string(heir)+`"}`, owner)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	record, err := store.Get(context.TODO(), "YBbxJEcQ9vq")
+	require.NoError(t, err)
+	assert.Equal(t, heir, record.UserID)
+	assert.Len(t, list(reader), 1, "the transfer drops the shares")
+	campaign, err := store.GetCampaignURLs(context.TODO(), owner, "launch")
+	require.NoError(t, err)
+	assert.Empty(t, campaign, "the transfer drops the campaigns")
+
+	w = serve(http.MethodPatch, "/api/user/urls/YBbxJEcQ9vq", `{"url":"https://go.dev/blog/"}`, heir)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serve(http.MethodDelete, "/api/user/urls/YBbxJEcQ9vq/shares/"+string(reader), "", heir)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShares_Disabled(t *testing.T) {
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPut, "/api/user/urls/YBbxJEcQ9vq/shares/reader", http.NoBody)
+	w := httptest.NewRecorder()
+	handler.PutShare(w, r)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -1,14 +1,13 @@
 package handler
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
-	"github.com/KretovDmitry/shortener/internal/shorturl"
 	"github.com/asaskevich/govalidator"
 	"go.uber.org/zap"
 )
@@ -17,6 +16,8 @@ type (
 	shortenBatchRequestPayload struct {
 		CorrelationID string `json:"correlation_id"`
 		OriginalURL   string `json:"original_url"`
+		TTL           int64  `json:"ttl,omitempty"`
+		Domain        string `json:"domain,omitempty"`
 	}
 
 	shortenBatchResponsePayload struct {
@@ -26,6 +27,10 @@ type (
 )
 
 // PostShortenBatch handles requests to shorten multiple URLs in a single request.
+// Every URL can have the optional TTL in seconds and the optional
+// configured domain the short URL redirects from. The batch with a URL
+// of the host blocked by the configured host lists responds with 422
+// Unprocessable Entity naming the correlation ID of the item.
 //
 // Request:
 //
@@ -39,7 +44,8 @@ type (
 //		},
 //		{
 //			"correlation_id": "229d9603-8540-4925-83f6-5cb1f239a72b",
-//			"original_url": "http://..."
+//			"original_url": "http://...",
+//			"ttl": 86400
 //		},
 //		...
 //	 ]
@@ -64,26 +70,26 @@ type (
 func (h *Handler) PostShortenBatch(w http.ResponseWriter, r *http.Request) {
 	// check the request method
 	if r.Method != http.MethodPost {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
 	// check content type
 	if !h.IsApplicationJSONContentType(r) {
-		h.textError(w, r.Header.Get("Content-Type"), errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	// decode the request body
-	var payload []shortenBatchRequestPayload
+	// decode the request body item by item
 	defer func() {
 		if err := r.Body.Close(); err != nil {
 			h.logger.Errorf("close body: %v", err)
 		}
 	}()
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		h.textError(w, err.Error(), errs.ErrInvalidRequest, http.StatusInternalServerError)
+	payload, err := decodeJSONArray[shortenBatchRequestPayload](h, r)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, decodeStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -93,42 +99,73 @@ func (h *Handler) PostShortenBatch(w http.ResponseWriter, r *http.Request) {
 
 	user, ok := user.FromContext(r.Context())
 	if !ok {
-		h.textError(w, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 
+	metadata := requestMetadata(r, models.OriginAPI)
+
 	for i, p := range payload {
 		// check if URL is provided
 		if len(p.OriginalURL) == 0 {
-			h.textError(w, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
+			h.jsonError(w, r, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
 			return
 		}
 
 		// check if URL is a valid URL
 		if !govalidator.IsURL(p.OriginalURL) {
-			h.textError(w, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+			h.jsonError(w, r, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+
+		// check if the destination host can be shortened
+		if err := h.checkHost(r.Context(), p.OriginalURL); err != nil {
+			h.hostBlockedError(w, r, err, p.CorrelationID)
+			return
+		}
+
+		expiresAt, err := h.expiresAt(p.TTL)
+		if err != nil {
+			h.jsonError(w, r, "invalid TTL", err, http.StatusBadRequest)
+			return
+		}
+
+		domain, err := h.resolveDomain(p.Domain)
+		if err != nil {
+			h.jsonError(w, r, "invalid domain", err, http.StatusBadRequest)
 			return
 		}
 
 		// generate short URL
-		shortURL := shorturl.Generate(p.OriginalURL)
+		shortURL, err := h.generateShortURL(r.Context(), p.OriginalURL)
+		if err != nil {
+			h.jsonError(w, r, "failed to generate short URL", err, http.StatusInternalServerError)
+			return
+		}
 		recordsToSave[i] = models.NewRecord(shortURL, p.OriginalURL, user.ID)
-		shortURL = fmt.Sprintf("http://%s/%s", h.config.HTTPServer.ReturnAddress, shortURL)
-		result[i] = shortenBatchResponsePayload{p.CorrelationID, models.ShortURL(shortURL)}
+		recordsToSave[i].Metadata = metadata
+		recordsToSave[i].ExpiresAt = expiresAt
+		recordsToSave[i].Domain = domain
+		result[i] = shortenBatchResponsePayload{
+			p.CorrelationID, models.ShortURL(h.absoluteURL(domain, recordsToSave[i].ShortURL)),
+		}
 	}
 
 	// save the records
 	if err := h.store.SaveAll(r.Context(), recordsToSave); err != nil {
-		h.textError(w, "failed to save to database", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to save to database", err, http.StatusInternalServerError)
 		return
 	}
+	// the storage skips the URLs shortened already silently,
+	// so their created events are published again
+	h.publishEvents(events.TypeCreated, recordsToSave...)
 
 	// set the response headers and status code
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
 	// encode the response body
-	if err := json.NewEncoder(w).Encode(result); err != nil {
+	if err := h.encodeData(w, r, result); err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
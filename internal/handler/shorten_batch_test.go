@@ -10,7 +10,7 @@ import (
 	"testing"
 
 	"github.com/KretovDmitry/shortener/internal/config"
-	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
@@ -90,7 +90,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodGet),
+				response:   fmt.Sprintf(`{"code":"invalid_request","message":"method %s is not allowed"}`, http.MethodGet),
 			},
 		},
 		{
@@ -101,7 +101,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPut),
+				response:   fmt.Sprintf(`{"code":"invalid_request","message":"method %s is not allowed"}`, http.MethodPut),
 			},
 		},
 		{
@@ -112,7 +112,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPatch),
+				response:   fmt.Sprintf(`{"code":"invalid_request","message":"method %s is not allowed"}`, http.MethodPatch),
 			},
 		},
 		{
@@ -123,7 +123,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodDelete),
+				response:   fmt.Sprintf(`{"code":"invalid_request","message":"method %s is not allowed"}`, http.MethodDelete),
 			},
 		},
 		{
@@ -134,7 +134,8 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, textPlain),
+				response: fmt.Sprintf(`{"code":"invalid_request","message":%q}`,
+					fmt.Sprintf("unsupported content type %q", textPlain)),
 			},
 		},
 		{
@@ -145,7 +146,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response:   errs.ErrInvalidRequest.Error(),
+				response:   `"code":"internal"`,
 			},
 			wantErr: true,
 		},
@@ -157,7 +158,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response:   errs.ErrInvalidRequest.Error(),
+				response:   `"code":"internal"`,
 			},
 			wantErr: true,
 		},
@@ -169,7 +170,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: URL is not provided", errs.ErrInvalidRequest),
+				response:   `{"code":"invalid_request","message":"URL is not provided"}`,
 			},
 		},
 		{
@@ -180,7 +181,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: invalid URL", errs.ErrInvalidRequest),
+				response:   `{"code":"invalid_request","message":"invalid URL"}`,
 			},
 		},
 		{
@@ -191,7 +192,7 @@ func TestPostShortenBatch(t *testing.T) {
 			store:       &brokenStore{},
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response:   fmt.Sprintf("%s: failed to save to database", errIntentionallyNotWorkingMethod),
+				response:   `{"code":"internal","message":"failed to save to database"}`,
 			},
 		},
 	}
@@ -256,10 +257,7 @@ func TestShortenBatch_WithoutUserInContext(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed close body")
-
 	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "status code mismatch")
-	assert.Equal(t, fmt.Sprintf("%s: no user found", errs.ErrUnauthorized),
-		response, "response message mismatch")
+	assert.Equal(t, envelope.Error{Code: "unauthorized", Message: "no user found"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
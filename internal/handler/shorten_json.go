@@ -1,23 +1,29 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
-	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/events"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
-	"github.com/KretovDmitry/shortener/internal/shorturl"
 	"github.com/asaskevich/govalidator"
 )
 
 type (
 	shortenJSONRequestPayload struct {
-		URL string `json:"url"`
+		URL   string `json:"url"`
+		Alias string `json:"alias,omitempty"`
+		TTL   int64  `json:"ttl,omitempty"`
+		// RedirectLimit caps the redirects per minute, zero if not limited.
+		RedirectLimit int `json:"redirect_limit,omitempty"`
+		// Note is the free-text annotation of the link.
+		Note string `json:"note,omitempty"`
+		// Domain is the configured domain of the short URL,
+		// the return address if empty.
+		Domain string `json:"domain,omitempty"`
 	}
 
 	shortenJSONResponsePayload struct {
@@ -25,17 +31,32 @@ type (
 		Message string `json:"message"`
 		Success bool   `json:"success"`
 	}
+
+	// shortenJSONResult is the data of the enveloped response,
+	// which reports the success and the message by itself.
+	shortenJSONResult struct {
+		Result string `json:"result"`
+	}
 )
 
 // PostShortenJSON handles the shortening of a long URL.
-// The message field should be set to an error message if the shortening failed.
-// Otherwise, success should be set to true and the result field should contain the shortened URL.
+// On success, success is set to true and the result field contains the shortened URL,
+// the failures are reported with the JSON error.
+// The optional alias is used as the short URL instead of the generated one.
+// Reserved aliases can be used only by the reservation owner.
+// The optional TTL is the number of seconds the short URL redirects for.
+// The optional redirect limit caps the redirects per minute.
+// The optional note annotates the link for its owner.
+// The optional domain selects one of the configured domains
+// the short URL redirects from.
+// URLs of the hosts blocked by the configured host lists respond
+// with 422 Unprocessable Entity.
 //
 // Request:
 //
 //	POST /api/shorten
 //	Content-Type: application/json
-//	{ "url": "https://example.com" }
+//	{ "url": "https://example.com", "ttl": 86400 }
 //
 // Response:
 //
@@ -46,17 +67,21 @@ type (
 //		"success": true
 //		"message": "OK"
 //	}
+//
+// If the JSON envelope is configured, the short URL is in the data field:
+//
+//	{ "data": { "result": "http://config.AddrToReturn/Base58" } }
 func (h *Handler) PostShortenJSON(w http.ResponseWriter, r *http.Request) {
 	// check request method
 	if r.Method != http.MethodPost {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.shortenJSONError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
 	// check content type
 	if !h.IsApplicationJSONContentType(r) {
-		h.shortenJSONError(w, r.Header.Get("Content-Type"), errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
@@ -67,48 +92,110 @@ func (h *Handler) PostShortenJSON(w http.ResponseWriter, r *http.Request) {
 			h.logger.Errorf("close body: %v", err)
 		}
 	}()
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		h.shortenJSONError(w, "failed to decode request", err, http.StatusInternalServerError)
+	if err := h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", err, decodeStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	// check if URL is provided
 	if len(payload.URL) == 0 {
-		h.shortenJSONError(w, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
 	// check if URL is a valid URL
 	if !govalidator.IsURL(payload.URL) {
-		h.shortenJSONError(w, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	// generate short URL
-	shortURL := shorturl.Generate(payload.URL)
+	// check if the destination host can be shortened
+	if err := h.checkHost(r.Context(), payload.URL); err != nil {
+		h.hostBlockedError(w, r, err, "")
+		return
+	}
+
+	expiresAt, err := h.expiresAt(payload.TTL)
+	if err != nil {
+		h.jsonError(w, r, "invalid TTL", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = models.ValidateRedirectLimit(payload.RedirectLimit); err != nil {
+		h.jsonError(w, r, "invalid redirect limit",
+			fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err), http.StatusBadRequest)
+		return
+	}
+
+	if err = models.ValidateNote(payload.Note); err != nil {
+		h.jsonError(w, r, "invalid note",
+			fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err), http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.resolveDomain(payload.Domain)
+	if err != nil {
+		h.jsonError(w, r, "invalid domain", err, http.StatusBadRequest)
+		return
+	}
 
 	user, ok := user.FromContext(r.Context())
 	if !ok {
-		h.shortenJSONError(w, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 
+	// use custom alias or generate short URL on save
+	var shortURL string
+	if payload.Alias != "" {
+		err = h.checkAlias(r.Context(), payload.Alias, user.ID)
+		switch {
+		case errors.Is(err, errs.ErrInvalidRequest):
+			h.jsonError(w, r, "invalid alias", err, http.StatusBadRequest)
+			return
+		case errors.Is(err, errs.ErrConflict):
+			h.jsonError(w, r, "alias is reserved", err, http.StatusConflict)
+			return
+		case err != nil:
+			h.jsonError(w, r, "failed to check alias", err, http.StatusInternalServerError)
+			return
+		}
+		shortURL = payload.Alias
+	}
+
 	newRecord := models.NewRecord(shortURL, payload.URL, user.ID)
+	newRecord.Metadata = requestMetadata(r, models.OriginAPI)
+	newRecord.ExpiresAt = expiresAt
+	newRecord.RedirectLimit = payload.RedirectLimit
+	newRecord.Note = payload.Note
+	newRecord.Domain = domain
 
 	// Build the JWT authentication token.
-	authToken, err := jwt.BuildJWTString(user.ID,
-		h.config.JWT.SigningKey, h.config.JWT.Expiration)
+	authCookie, err := h.authCookie(user)
 	if err != nil {
-		h.shortenJSONError(w, "failed to build JWT token", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to build JWT token", err, http.StatusInternalServerError)
 		return
 	}
 
 	// save URL to database
-	err = h.store.Save(r.Context(), newRecord)
+	if payload.Alias != "" {
+		err = h.store.Save(r.Context(), newRecord)
+	} else {
+		err = h.saveGenerated(r.Context(), newRecord)
+	}
 	if err != nil && !errors.Is(err, errs.ErrConflict) {
-		h.shortenJSONError(w, "failed to save to database", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to save to database", err, http.StatusInternalServerError)
 		return
 	}
+	if err == nil {
+		h.publishEvents(events.TypeCreated, newRecord)
+	} else {
+		h.useExisting(r.Context(), newRecord)
+	}
+
+	// Set the "Authorization" cookie with the JWT authentication token.
+	// Headers must be set before the status code.
+	http.SetCookie(w, authCookie)
 
 	// Set the response headers and status code
 	w.Header().Set("Content-Type", "application/json")
@@ -119,43 +206,18 @@ func (h *Handler) PostShortenJSON(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 	}
 
-	// Set the "Authorization" cookie with the JWT authentication token.
-	http.SetCookie(w, &http.Cookie{
-		Name:     "Authorization",
-		Value:    authToken,
-		Expires:  time.Now().Add(h.config.JWT.Expiration),
-		HttpOnly: true,
-	})
-
 	// create response payload
-	s := fmt.Sprintf("http://%s/%s", h.config.HTTPServer.ReturnAddress, shortURL)
-	result := shortenJSONResponsePayload{Result: s, Success: true, Message: "OK"}
+	var result any = shortenJSONResponsePayload{
+		Result: h.absoluteURL(newRecord.Domain, newRecord.ShortURL), Success: true, Message: "OK",
+	}
+	if h.config.JSONEnvelope {
+		result = shortenJSONResult{Result: h.absoluteURL(newRecord.Domain, newRecord.ShortURL)}
+	}
 
 	// encode response body
-	if err = json.NewEncoder(w).Encode(result); err != nil {
+	if err = h.encodeData(w, r, result); err != nil {
 		h.logger.Errorf("failed to encode response: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
-
-// shortenJSONError is a helper function that sets the appropriate response
-// headers and status code for errors returned by the ShortenJSON endpoint.
-func (h *Handler) shortenJSONError(w http.ResponseWriter, message string, err error, code int) {
-	logger := h.logger.SkipCaller(1)
-	if code >= http.StatusInternalServerError {
-		logger.Errorf("%s: %s", message, err)
-	} else {
-		logger.Infof("%s: %s", message, err)
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	err = json.NewEncoder(w).Encode(shortenJSONResponsePayload{
-		Success: false,
-		Message: fmt.Sprintf("%s: %s", err, message),
-	})
-	if err != nil {
-		h.logger.Errorf("failed to encode response: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-}
@@ -10,7 +10,7 @@ import (
 	"testing"
 
 	"github.com/KretovDmitry/shortener/internal/config"
-	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
@@ -84,7 +84,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodGet),
+				response:   fmt.Sprintf("method %s is not allowed", http.MethodGet),
 			},
 			wantErr: true,
 		},
@@ -96,7 +96,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPut),
+				response:   fmt.Sprintf("method %s is not allowed", http.MethodPut),
 			},
 			wantErr: true,
 		},
@@ -108,7 +108,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodPatch),
+				response:   fmt.Sprintf("method %s is not allowed", http.MethodPatch),
 			},
 			wantErr: true,
 		},
@@ -120,7 +120,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, http.MethodDelete),
+				response:   fmt.Sprintf("method %s is not allowed", http.MethodDelete),
 			},
 			wantErr: true,
 		},
@@ -132,7 +132,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, textPlain),
+				response:   fmt.Sprintf("unsupported content type %q", textPlain),
 			},
 			wantErr: true,
 		},
@@ -156,7 +156,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: URL is not provided", errs.ErrInvalidRequest),
+				response:   "URL is not provided",
 			},
 			wantErr: true,
 		},
@@ -168,7 +168,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       memstore.NewURLRepository(),
 			want: want{
 				statusCode: http.StatusBadRequest,
-				response:   fmt.Sprintf("%s: invalid URL", errs.ErrInvalidRequest),
+				response:   "invalid URL",
 			},
 			wantErr: true,
 		},
@@ -180,7 +180,7 @@ func TestPostShortenJSON(t *testing.T) {
 			store:       &brokenStore{},
 			want: want{
 				statusCode: http.StatusInternalServerError,
-				response:   fmt.Sprintf("%s: failed to save to database", errIntentionallyNotWorkingMethod),
+				response:   "failed to save to database",
 			},
 			wantErr: true,
 		},
@@ -203,16 +203,14 @@ func TestPostShortenJSON(t *testing.T) {
 
 			res := w.Result()
 
-			response := getShortenJSONResponsePayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, tt.want.statusCode, res.StatusCode)
 			switch {
 			case tt.wantErr:
-				assert.Equal(t, tt.wantErr, !response.Success)
-				assert.True(t, strings.Contains(response.Message, tt.want.response))
+				response := getResponseErrorPayload(t, res)
+				assert.Equal(t, tt.want.response, response.Message)
 			case !tt.wantErr:
-				assert.Equal(t, !tt.wantErr, response.Success)
+				response := getShortenJSONResponsePayload(t, res)
+				assert.True(t, response.Success)
 				assert.Equal(t, tt.want.response, getShortURL(response.Result))
 			}
 		})
@@ -238,13 +236,9 @@ func TestShortenJSON_WithoutUserInContext(t *testing.T) {
 
 	res := w.Result()
 
-	response := getShortenJSONResponsePayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed close body")
-
 	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "status code mismatch")
-	assert.Equal(t, fmt.Sprintf("%s: no user found", errs.ErrUnauthorized),
-		response.Message, "response message mismatch")
-	assert.False(t, response.Success)
+	assert.Equal(t, envelope.Error{Code: "unauthorized", Message: "no user found"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
 
 func getShortenJSONResponsePayload(t *testing.T, r *http.Response) shortenJSONResponsePayload {
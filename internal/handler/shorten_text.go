@@ -5,28 +5,31 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
-	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/events"
 	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
-	"github.com/KretovDmitry/shortener/internal/shorturl"
 	"github.com/asaskevich/govalidator"
 )
 
 // PostShortenText handles the shortening of a long URL.
+// The optional "ttl" query parameter is the number of seconds
+// the short URL redirects for. URLs of the hosts blocked by
+// the configured host lists respond with 422 Unprocessable Entity.
+// The optional "domain" query parameter selects one of the configured
+// domains the short URL redirects from.
 func (h *Handler) PostShortenText(w http.ResponseWriter, r *http.Request) {
 	// check the request method
 	if r.Method != http.MethodPost {
-		// Yandex Practicum requires 400 Bad Request instead of 405 Method Not Allowed.
-		h.textError(w, r.Method, errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.methodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
 	// Check the content type.
 	if r.Header.Get("Content-Encoding") == "" && !isTextPlainContentType(r) {
-		h.textError(w, r.Header.Get("Content-Type"), errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
@@ -38,13 +41,14 @@ func (h *Handler) PostShortenText(w http.ResponseWriter, r *http.Request) {
 	}()
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.textError(w, "failed to read request body", err, http.StatusInternalServerError)
+		err = limitError(err)
+		h.jsonError(w, r, "failed to read request body", err, decodeStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	// Check if the URL is provided.
 	if len(body) == 0 {
-		h.textError(w, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, "URL is not provided", errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
@@ -53,39 +57,67 @@ func (h *Handler) PostShortenText(w http.ResponseWriter, r *http.Request) {
 
 	// Check if the URL is a valid URL.
 	if !govalidator.IsURL(originalURL) {
-		h.textError(w, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+		h.jsonError(w, r, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	// Generate the shortened URL.
-	generatedShortURL := shorturl.Generate(originalURL)
+	// Check if the destination host can be shortened.
+	if err = h.checkHost(r.Context(), originalURL); err != nil {
+		h.hostBlockedError(w, r, err, "")
+		return
+	}
+
+	// Parse the optional TTL.
+	expiresAt, err := h.parseTTL(r.URL.Query().Get("ttl"))
+	if err != nil {
+		h.jsonError(w, r, "invalid TTL", err, http.StatusBadRequest)
+		return
+	}
+
+	// Resolve the optional domain.
+	domain, err := h.resolveDomain(r.URL.Query().Get(domainQueryParam))
+	if err != nil {
+		h.jsonError(w, r, "invalid domain", err, http.StatusBadRequest)
+		return
+	}
 
 	// Extract the user ID from the request context.
 	user, ok := user.FromContext(r.Context())
 	if !ok {
-		h.textError(w, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 
-	// Create a new record with the generated short URL, original URL, and user ID.
-	newRecord := models.NewRecord(generatedShortURL, originalURL, user.ID)
+	// Create a new record with the original URL and user ID.
+	newRecord := models.NewRecord("", originalURL, user.ID)
+	newRecord.Metadata = requestMetadata(r, models.OriginText)
+	newRecord.ExpiresAt = expiresAt
+	newRecord.Domain = domain
 
-	// Save the record to the database.
-	storeErr := h.store.Save(r.Context(), newRecord)
+	// Save the record to the database with the generated short URL.
+	storeErr := h.saveGenerated(r.Context(), newRecord)
 	if storeErr != nil && !errors.Is(storeErr, errs.ErrConflict) {
-		h.textError(w, "failed to save to database",
+		h.jsonError(w, r, "failed to save to database",
 			storeErr, http.StatusInternalServerError)
 		return
 	}
+	if storeErr == nil {
+		h.publishEvents(events.TypeCreated, newRecord)
+	} else {
+		h.useExisting(r.Context(), newRecord)
+	}
 
 	// Build the JWT authentication token.
-	authToken, err := jwt.BuildJWTString(user.ID,
-		h.config.JWT.SigningKey, time.Duration(h.config.JWT.Expiration))
+	authCookie, err := h.authCookie(user)
 	if err != nil {
-		h.textError(w, "failed to build JWT token", err, http.StatusInternalServerError)
+		h.jsonError(w, r, "failed to build JWT token", err, http.StatusInternalServerError)
 		return
 	}
 
+	// Set the "Authorization" cookie with the JWT authentication token.
+	// Headers must be set before the status code.
+	http.SetCookie(w, authCookie)
+
 	// Set the response headers and status code.
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	switch {
@@ -95,16 +127,8 @@ func (h *Handler) PostShortenText(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 	}
 
-	// Set the "Authorization" cookie with the JWT authentication token.
-	http.SetCookie(w, &http.Cookie{
-		Name:     "Authorization",
-		Value:    authToken,
-		Expires:  time.Now().Add(time.Duration(h.config.JWT.Expiration)),
-		HttpOnly: true,
-	})
-
 	// Write the response body.
-	_, err = fmt.Fprintf(w, "http://%s/%s", h.config.HTTPServer.ReturnAddress, generatedShortURL)
+	_, err = fmt.Fprint(w, h.absoluteURL(newRecord.Domain, newRecord.ShortURL))
 	if err != nil {
 		h.logger.Errorf("failed to write response: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
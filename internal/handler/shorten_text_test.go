@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +9,10 @@ import (
 	"testing"
 
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
 	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/KretovDmitry/shortener/internal/repository/memstore"
 	"github.com/KretovDmitry/shortener/mocks"
@@ -24,7 +27,7 @@ func TestPostShortenText_NewRecord(t *testing.T) {
 
 	m := mocks.NewMockURLStorage(ctrl)
 
-	userID := "test"
+	userID := user.ID("test")
 
 	testcases := []string{
 		"http://foo.bar#com",
@@ -122,7 +125,7 @@ func TestPostShortenText_RepeatedRecord(t *testing.T) {
 
 	m := mocks.NewMockURLStorage(ctrl)
 
-	userID := "test"
+	userID := user.ID("test")
 
 	testcases := []string{
 		"http://foo.bar#com",
@@ -197,6 +200,10 @@ func TestPostShortenText_RepeatedRecord(t *testing.T) {
 				Save(gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(errs.ErrConflict)
+			m.EXPECT().
+				Get(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(models.NewRecord("", tc, userID), nil)
 
 			l, _ := logger.NewForTest()
 			c := config.NewForTest()
@@ -215,6 +222,47 @@ func TestPostShortenText_RepeatedRecord(t *testing.T) {
 	}
 }
 
+func TestPostShortenText_ExistingShortURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	m := mocks.NewMockURLStorage(ctrl)
+
+	originalURL := "https://go.dev/doc/"
+	userID := user.ID("test")
+
+	// the original URL is saved under the alias
+	originals := memstore.NewURLRepository()
+	require.NoError(t, originals.Save(context.TODO(), models.NewRecord("GoDocs", originalURL, userID)))
+
+	m.EXPECT().
+		Save(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(errs.ErrConflict)
+	m.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, errs.ErrNotFound)
+
+	l, _ := logger.NewForTest()
+	c := config.NewForTest()
+
+	handler, err := New(m, c, l, WithOriginalURLs(originals))
+	require.NoError(t, err, "failed to init handler")
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(originalURL))
+	r.Header.Set(contentType, textPlain)
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: userID}))
+	w := httptest.NewRecorder()
+
+	handler.PostShortenText(w, r)
+
+	res := w.Result()
+
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "GoDocs", getShortURL(getResponseTextPayload(t, res)))
+}
+
 func TestPostShortenText_BadMethods(t *testing.T) {
 	t.Parallel()
 	path := "/"
@@ -250,15 +298,11 @@ func TestPostShortenText_BadMethods(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-			assert.Equal(t, textPlain, res.Header.Get(contentType))
-			assert.Equal(t,
-				fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, tt.method),
-				response,
-			)
+			assert.Equal(t, envelope.Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("method %s is not allowed", tt.method),
+			}, getResponseErrorPayload(t, res))
 		})
 	}
 }
@@ -342,15 +386,11 @@ func TestPostShortenText_BadContentTypes(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed to close body")
-
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-			assert.Equal(t, textPlain, res.Header.Get(contentType))
-			assert.Equal(t,
-				fmt.Sprintf("%s: %s", errs.ErrInvalidRequest, ct),
-				response,
-			)
+			assert.Equal(t, envelope.Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("unsupported content type %q", ct),
+			}, getResponseErrorPayload(t, res))
 		})
 	}
 }
@@ -379,15 +419,10 @@ func TestPostShortenText_BadReader(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed to close body")
-
 	assert.Equal(t, http.StatusInternalServerError, res.StatusCode,
 		"status code mismatch")
-	assert.Equal(t, textPlain, res.Header.Get(contentType))
-	assert.Equal(t, fmt.Sprintf(
-		"%s: failed to read request body", errIntentionallyNotWorkingMethod,
-	), response, "response message mismatch")
+	assert.Equal(t, envelope.Error{Code: "internal", Message: "failed to read request body"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
 
 func TestPostShortenText_BadPayload(t *testing.T) {
@@ -453,16 +488,10 @@ func TestPostShortenText_BadPayload(t *testing.T) {
 
 			res := w.Result()
 
-			response := getResponseTextPayload(t, res)
-			require.NoError(t, res.Body.Close(), "failed close body")
-
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode,
 				"status code mismatch")
-			assert.Equal(t, textPlain, res.Header.Get(contentType),
-				"content type mismatch")
-			assert.True(t,
-				strings.Contains(response, errs.ErrInvalidRequest.Error()),
-				"response message mismatch")
+			assert.Equal(t, "invalid_request", getResponseErrorPayload(t, res).Code,
+				"response code mismatch")
 		})
 	}
 }
@@ -486,12 +515,9 @@ func TestPostShortenText_WithoutUserInContext(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed to close body")
-
 	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "status code mismatch")
-	assert.Equal(t, fmt.Sprintf("%s: no user found", errs.ErrUnauthorized),
-		response, "response message mismatch")
+	assert.Equal(t, envelope.Error{Code: "unauthorized", Message: "no user found"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
 
 func TestPostShortenText_BadStore(t *testing.T) {
@@ -520,13 +546,8 @@ func TestPostShortenText_BadStore(t *testing.T) {
 
 	res := w.Result()
 
-	response := getResponseTextPayload(t, res)
-	require.NoError(t, res.Body.Close(), "failed to close body")
-
 	assert.Equal(t, http.StatusInternalServerError, res.StatusCode,
 		"status code mismatch")
-	assert.Equal(t, textPlain, res.Header.Get(contentType))
-	assert.Equal(t, fmt.Sprintf(
-		"%s: failed to save to database", errIntentionallyNotWorkingMethod,
-	), response, "response message mismatch")
+	assert.Equal(t, envelope.Error{Code: "internal", Message: "failed to save to database"},
+		getResponseErrorPayload(t, res), "response message mismatch")
 }
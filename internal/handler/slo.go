@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// redirectPattern is the route pattern of the redirects.
+const redirectPattern = "/{shortURL}"
+
+// measureSLO records the status of every request and the latency
+// of the redirects for the service level objectives.
+func (h *Handler) measureSLO(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		// the route is matched once the request is served
+		redirect := false
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			redirect = r.Method == http.MethodGet && rctx.RoutePattern() == redirectPattern
+		}
+		h.slo.Observe(status, redirect, time.Since(start))
+	})
+}
+
+// GetSLO returns the availability and the redirect latency over the
+// rolling windows with the burn rates of the error budgets, and the
+// counters growing since the start.
+//
+// Request:
+//
+//	GET /api/internal/slo
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"objectives": {
+//			"availability": 0.999,
+//			"redirect_latency_ms": 100,
+//			"redirect_latency_target": 0.99
+//		},
+//		"counters": {
+//			"requests_total": 1520,
+//			"errors_total": 2,
+//			"redirects_total": 1210,
+//			"slow_redirects_total": 4
+//		},
+//		"windows": [
+//			{
+//				"window": "5m0s",
+//				"requests": 120,
+//				"errors": 0,
+//				"availability": 1,
+//				"availability_burn_rate": 0,
+//				"redirects": 98,
+//				"slow_redirects": 1,
+//				"redirect_p99_ms": 50,
+//				"latency_burn_rate": 1.02
+//			},
+//			...
+//		]
+//	}
+func (h *Handler) GetSLO(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.encodeData(w, r, h.slo.Report()); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
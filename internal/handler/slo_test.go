@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/KretovDmitry/shortener/internal/slo"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSLO(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.Save(context.TODO(),
+		&models.URL{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"}))
+
+	c := config.NewForTest()
+	c.TrustedSubnet = "192.0.2.0/24"
+	l, _ := logger.NewForTest()
+	handler, err := New(store, c, l)
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	for _, path := range []string{"/YBbxJEcQ9vq", "/YBbxJEcQ9vq", "/healthz"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		require.Less(t, w.Code, http.StatusInternalServerError, path)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/internal/slo", http.NoBody)
+	r.Header.Set("X-Real-IP", "192.0.2.1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got slo.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, slo.Counters{Requests: 3, Redirects: 2}, got.Counters,
+		"the report itself is counted once served")
+	require.Len(t, got.Windows, len(slo.Windows))
+	assert.Equal(t, int64(2), got.Windows[0].Redirects)
+	assert.Equal(t, float64(1), got.Windows[0].Availability)
+	assert.Equal(t, slo.DefaultAvailability, got.Objectives.Availability)
+}
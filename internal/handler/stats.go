@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository"
+)
+
+// Number of the most clicked links of the statistics.
+const (
+	defaultTopLinks = 10
+	maxTopLinks     = 100
+)
+
+// statsResponsePayload are the numbers of the service.
+type statsResponsePayload struct {
+	// URLs is the number of the short URLs not deleted.
+	URLs int `json:"urls"`
+	// Users is the number of the users owning the URLs.
+	Users int `json:"users"`
+	// Approximate is set if the numbers are estimated.
+	Approximate bool `json:"approximate,omitempty"`
+	// ActiveUsers is the number of the users who created URLs
+	// during the days, nil if the extended statistics are disabled.
+	ActiveUsers *int `json:"active_users,omitempty"`
+	// TopLinks are the links most clicked during the days.
+	TopLinks []models.TopLink `json:"top_links,omitempty"`
+	// Daily are the numbers of the URLs created on each of the days.
+	Daily []models.DailyTrend `json:"daily,omitempty"`
+}
+
+// GetStats returns the numbers of the short URLs and of the users.
+//
+// If the storage supports them, the statistics are extended with the
+// number of the users who created URLs, the most clicked links and the
+// daily numbers of the created URLs for the last days in UTC, today
+// included. The optional days query parameter is the number of the days,
+// 30 by default and 366 at most, and top is the number of the most
+// clicked links, 10 by default and 100 at most.
+//
+// Request:
+//
+//	GET /api/internal/stats?days=30&top=10
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"urls": 1520,
+//		"users": 87,
+//		"active_users": 14,
+//		"top_links": [
+//			{ "short_url": "6qxTVvsy", "clicks": 420 },
+//			...
+//		],
+//		"daily": [
+//			{ "date": "2024-06-01", "created_urls": 120, "active_users": 14 },
+//			...
+//		]
+//	}
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		h.jsonError(w, r, "stats are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	days, since, err := parseTrendDays(r)
+	if err != nil {
+		h.jsonError(w, r, fmt.Sprintf("days must be from 1 to %d", maxTrendDays),
+			err, http.StatusBadRequest)
+		return
+	}
+	top := defaultTopLinks
+	if s := r.URL.Query().Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxTopLinks {
+			h.jsonError(w, r, fmt.Sprintf("top must be from 1 to %d", maxTopLinks),
+				errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	urls, err := h.stats.CountShortURLs(r.Context())
+	if err != nil {
+		h.jsonError(w, r, "failed to count URLs", err, http.StatusInternalServerError)
+		return
+	}
+	users, err := h.stats.CountUsers(r.Context())
+	if err != nil {
+		h.jsonError(w, r, "failed to count users", err, http.StatusInternalServerError)
+		return
+	}
+
+	payload := statsResponsePayload{
+		URLs:        urls,
+		Users:       users,
+		Approximate: repository.IsApproximate(h.stats),
+	}
+
+	if h.extendedStats != nil {
+		active, err := h.extendedStats.CountActiveUsers(r.Context(), since)
+		if err != nil {
+			h.jsonError(w, r, "failed to count active users", err, http.StatusInternalServerError)
+			return
+		}
+		payload.ActiveUsers = &active
+
+		payload.TopLinks, err = h.extendedStats.TopLinks(r.Context(), since, top)
+		if err != nil {
+			h.jsonError(w, r, "failed to get top links", err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if h.trends != nil {
+		trends, err := h.trends.GetTrends(r.Context(), since)
+		if err != nil {
+			h.jsonError(w, r, "failed to get trends", err, http.StatusInternalServerError)
+			return
+		}
+		payload.Daily = fillTrends(trends, since, days)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, payload); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStats(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "first"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "first"},
+		{OriginalURL: "https://go.dev/blog/", ShortURL: "Wxyz9876", UserID: "second"},
+	}))
+
+	l, _ := logger.NewForTest()
+	cfg := config.NewForTest()
+	cfg.TrustedSubnet = "10.0.0.0/8"
+	cfg.TrustedProxies = []string{"192.0.2.0/24"}
+	handler, err := New(store, cfg, l, WithStats(store))
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), cfg, l)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/internal/stats", http.NoBody)
+	r.Header.Set("X-Real-IP", "10.1.2.3")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got statsResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, statsResponsePayload{URLs: 3, Users: 2}, got)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/internal/stats", http.NoBody)
+	r.Header.Set("X-Real-IP", "192.168.1.1")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code, "untrusted subnet")
+}
+
+func TestGetStats_Extended(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "first"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "second"},
+	}))
+	now := time.Now()
+	require.NoError(t, store.SaveClicks(context.TODO(),
+		&models.Click{ShortURL: "RTfd56hn", ClickedAt: now},
+		&models.Click{ShortURL: "RTfd56hn", ClickedAt: now},
+		&models.Click{ShortURL: "YBbxJEcQ9vq", ClickedAt: now},
+		&models.Click{ShortURL: "YBbxJEcQ9vq", ClickedAt: now.AddDate(0, 0, -10)},
+	))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l,
+		WithStats(store), WithExtendedStats(store), WithTrends(store))
+	require.NoError(t, err, "new handler error")
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		top    []models.TopLink
+	}{
+		{
+			name:   "defaults",
+			target: "/api/internal/stats",
+			status: http.StatusOK,
+			top:    []models.TopLink{{ShortURL: "RTfd56hn", Clicks: 2}, {ShortURL: "YBbxJEcQ9vq", Clicks: 2}},
+		},
+		{
+			name:   "last days",
+			target: "/api/internal/stats?days=7&top=1",
+			status: http.StatusOK,
+			top:    []models.TopLink{{ShortURL: "RTfd56hn", Clicks: 2}},
+		},
+		{name: "invalid days", target: "/api/internal/stats?days=0", status: http.StatusBadRequest},
+		{name: "invalid top", target: "/api/internal/stats?top=101", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetStats(w, httptest.NewRequest(http.MethodGet, tt.target, http.NoBody))
+			require.Equal(t, tt.status, w.Code)
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got statsResponsePayload
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			require.NotNil(t, got.ActiveUsers)
+			assert.Equal(t, 2, *got.ActiveUsers)
+			assert.Equal(t, tt.top, got.TopLinks)
+			require.NotEmpty(t, got.Daily)
+			assert.Equal(t, 2, got.Daily[len(got.Daily)-1].CreatedURLs, "created today")
+		})
+	}
+}
+
+func TestGetStats_Disabled(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	w := httptest.NewRecorder()
+	handler.GetStats(w, httptest.NewRequest(http.MethodGet, "/api/internal/stats", http.NoBody))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	// the zones are known on the hosts without the tz database
+	_ "time/tzdata"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+)
+
+// tzParam is the query parameter selecting the time zone of the
+// timestamps of the user endpoints, e.g. ?tz=Europe/Berlin.
+const tzParam = "tz"
+
+// locationKey is the context key of the time zone of the request.
+type locationKey struct{}
+
+// timezone selects the time zone of the timestamps of the responses
+// by the tz query parameter. The timestamps are in UTC if it is not set.
+func (h *Handler) timezone(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tz := r.URL.Query().Get(tzParam)
+		if tz == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Local depends on the host of the server
+		loc, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			h.jsonError(w, r, "invalid time zone",
+				fmt.Errorf("%w: unknown time zone %q", errs.ErrInvalidRequest, tz),
+				http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), locationKey{}, loc)))
+	})
+}
+
+// location returns the time zone of the timestamps of the request.
+func location(r *http.Request) *time.Location {
+	if loc, ok := r.Context().Value(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// formatTimestamps rewrites the timestamps in the string values of
+// the JSON as RFC 3339 in the time zone, without the fractions of the
+// seconds, so that the responses don't depend on the zone of the server
+// or the database. The keys are left as is.
+func formatTimestamps(b []byte, loc *time.Location) []byte {
+	res := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != '"' {
+			res = append(res, b[i])
+			continue
+		}
+
+		// find the closing quote of the string
+		end := i + 1
+		escaped := false
+		for ; end < len(b) && b[end] != '"'; end++ {
+			if b[end] == '\\' {
+				escaped = true
+				end++
+			}
+		}
+		if end >= len(b) {
+			// invalid JSON is left for the decoder to report
+			return append(res, b[i:]...)
+		}
+
+		next := end + 1
+		for next < len(b) && isSpace(b[next]) {
+			next++
+		}
+		isKey := next < len(b) && b[next] == ':'
+
+		if t, ok := parseTimestamp(b[i+1 : end]); ok && !isKey && !escaped {
+			res = append(res, '"')
+			res = t.In(loc).AppendFormat(res, time.RFC3339)
+			res = append(res, '"')
+		} else {
+			res = append(res, b[i:end+1]...)
+		}
+		i = end
+	}
+	return res
+}
+
+// parseTimestamp parses the RFC 3339 timestamp as encoded by time.Time,
+// e.g. 2006-01-02T15:04:05.999999999+07:00.
+func parseTimestamp(s []byte) (time.Time, bool) {
+	const minLen, maxLen = len("2006-01-02T15:04:05Z"), len("2006-01-02T15:04:05.999999999+07:00")
+	if len(s) < minLen || len(s) > maxLen || s[4] != '-' || s[10] != 'T' {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(s))
+	return t, err == nil
+}
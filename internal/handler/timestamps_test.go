@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTimestamps(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	in := `{"expires_at": "2024-03-01T15:04:05.123+03:00", "2024-03-01T12:00:00Z": 1, ` +
+		`"note": "2024-03-01", "daily": {"2024-03-01": 2}, "created_at":"2024-03-01T12:00:00Z"}`
+
+	assert.Equal(t, `{"expires_at": "2024-03-01T12:04:05Z", "2024-03-01T12:00:00Z": 1, `+
+		`"note": "2024-03-01", "daily": {"2024-03-01": 2}, "created_at":"2024-03-01T12:00:00Z"}`,
+		string(formatTimestamps([]byte(in), time.UTC)), "keys and dates are left as is")
+	assert.Equal(t, `{"expires_at": "2024-03-01T13:04:05+01:00", "2024-03-01T12:00:00Z": 1, `+
+		`"note": "2024-03-01", "daily": {"2024-03-01": 2}, "created_at":"2024-03-01T13:00:00+01:00"}`,
+		string(formatTimestamps([]byte(in), berlin)))
+	assert.Equal(t, `["2024-03-01T12:00:00Z", "\"2024-03-01T12:00:00Z"]`,
+		string(formatTimestamps([]byte(`["2024-03-01T14:00:00+02:00", "\"2024-03-01T12:00:00Z"]`), time.UTC)))
+}
+
+func TestTimezone(t *testing.T) {
+	expires := time.Date(2024, 3, 1, 15, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.Save(context.TODO(), &models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    "YBbxJEcQ9vq",
+		UserID:      "test",
+		ExpiresAt:   &expires,
+	}))
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+	h := handler.timezone(http.HandlerFunc(handler.GetAllByUserID))
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		want   string
+	}{
+		{name: "UTC by default", target: "/api/user/urls", status: http.StatusOK, want: "2024-03-01T12:00:00Z"},
+		{name: "time zone", target: "/api/user/urls?tz=Asia/Tokyo", status: http.StatusOK, want: "2024-03-01T21:00:00+09:00"},
+		{name: "unknown time zone", target: "/api/user/urls?tz=Mars/Olympus", status: http.StatusBadRequest},
+		{name: "server time zone", target: "/api/user/urls?tz=Local", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+			require.Equal(t, tt.status, res.StatusCode)
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got []map[string]any
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0]["expires_at"])
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+type (
+	serviceTokenRequestPayload struct {
+		UserID string `json:"user_id"`
+		Role   string `json:"role"`
+	}
+
+	serviceTokenResponsePayload struct {
+		UserID    user.ID        `json:"user_id"`
+		Token     string         `json:"token"`
+		TokenType user.TokenType `json:"token_type"`
+		Role      user.Role      `json:"role"`
+		ExpiresAt time.Time      `json:"expires_at"`
+	}
+)
+
+// PostServiceToken issues the service token of the user. The token is
+// passed in the "Authorization" cookie and expires as configured for
+// the service tokens, so that the services are not bound to the lifetime
+// of the user tokens. A new user is created if the user ID is omitted.
+// The token is of the service role unless another one is requested,
+// e.g. the admin one moderating the URLs of all the users. Only the admins
+// mint the admin tokens and the tokens of the given users, so that the
+// trusted subnet alone doesn't give the access of any user, see
+// 'shortenerctl token' for the first admin token.
+//
+// Request:
+//
+//	POST /api/admin/tokens
+//	Content-Type: application/json
+//	{ "user_id": "2a5c1d63-...", "role": "admin" }
+//
+// Response:
+//
+//	HTTP/1.1 201 Created
+//	Content-Type: application/json
+//	{
+//		"user_id": "2a5c1d63-...",
+//		"token": "Bearer eyJhbGciOi...",
+//		"token_type": "service",
+//		"role": "admin",
+//		"expires_at": "2025-06-01T12:00:00Z"
+//	}
+func (h *Handler) PostServiceToken(w http.ResponseWriter, r *http.Request) {
+	// decode the request body, the empty one is allowed
+	var payload serviceTokenRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err := h.decodeJSON(r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	userID := user.NewID()
+	if payload.UserID != "" {
+		var err error
+		if userID, err = user.ParseID(payload.UserID); err != nil {
+			h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		if !h.config.UserIDFormat.IsOpaque() && !userID.IsUUID() {
+			h.jsonError(w, r, fmt.Sprintf("%s user ID expected", h.config.UserIDFormat),
+				errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+	}
+
+	role, err := user.ParseRole(payload.Role)
+	if err != nil {
+		h.jsonError(w, r, err.Error(), errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	if role == "" {
+		role = user.DefaultRole(user.TokenService)
+	}
+
+	if payload.UserID != "" || role == user.RoleAdmin {
+		if caller, ok := user.FromContext(r.Context()); !ok || !caller.HasRole(user.RoleAdmin) {
+			h.jsonError(w, r, "admin token required", errs.ErrUnauthorized, http.StatusForbidden)
+			return
+		}
+	}
+
+	exp := h.config.JWT.ExpirationOf(user.TokenService)
+	token, err := jwt.BuildUserJWTString(&user.User{ID: userID, Token: user.TokenService, Role: role},
+		h.config.JWT.SigningKey, exp)
+	if err != nil {
+		h.jsonError(w, r, "failed to build JWT token", err, http.StatusInternalServerError)
+		return
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	// encode the response body
+	err = h.encodeData(w, r, serviceTokenResponsePayload{
+		UserID:    userID,
+		Token:     token,
+		TokenType: user.TokenService,
+		Role:      role,
+		ExpiresAt: time.Now().Add(exp).UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenExpirations(t *testing.T) {
+	c := config.NewForTest()
+	c.JWT.AnonymousExpiration = time.Hour
+	c.JWT.ServiceExpiration = 365 * 24 * time.Hour
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// shorten returns the cookie minted for the user of the token
+	shorten := func(token string) *http.Cookie {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://go.dev/"}`))
+		r.Header.Set(contentType, applicationJSON)
+		if token != "" {
+			r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Contains(t, []int{http.StatusCreated, http.StatusConflict}, w.Code, w.Body.String())
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		return cookies[0]
+	}
+	assertCookie := func(cookie *http.Cookie, tokenType user.TokenType, exp time.Duration) *user.User {
+		u, err := jwt.GetUser(cookie.Value, c.JWT.SigningKey)
+		require.NoError(t, err)
+		assert.Equal(t, tokenType, u.Token)
+		assert.WithinDuration(t, time.Now().Add(exp), cookie.Expires, time.Minute)
+		return u
+	}
+
+	// new users get the anonymous token
+	anonymous := shorten("")
+	assertCookie(anonymous, user.TokenAnonymous, c.JWT.AnonymousExpiration)
+
+	// the service token is issued by the administrator
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", http.NoBody)
+	w := httptest.NewRecorder()
+	handler.PostServiceToken(w, r)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var issued serviceTokenResponsePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&issued))
+	assert.Equal(t, user.TokenService, issued.TokenType)
+	assert.WithinDuration(t, time.Now().Add(c.JWT.ServiceExpiration), issued.ExpiresAt, time.Minute)
+
+	// the refreshed token keeps its type and lifetime
+	u := assertCookie(shorten(issued.Token), user.TokenService, c.JWT.ServiceExpiration)
+	assert.Equal(t, issued.UserID, u.ID)
+
+	// tokens without the type were minted for the anonymous users,
+	// the registered ones fall back to the default expiration
+	legacy, err := jwt.BuildJWTString(user.NewID(), "", c.JWT.SigningKey, time.Hour)
+	require.NoError(t, err)
+	assertCookie(shorten(legacy), user.TokenAnonymous, c.JWT.AnonymousExpiration)
+	registered, err := jwt.BuildJWTString(user.NewID(), user.TokenRegistered, c.JWT.SigningKey, time.Hour)
+	require.NoError(t, err)
+	assertCookie(shorten(registered), user.TokenRegistered, c.JWT.Expiration)
+}
+
+func TestPostServiceToken_InvalidUserID(t *testing.T) {
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", strings.NewReader(`{"user_id":"oidc|42"}`))
+	w := httptest.NewRecorder()
+
+	handler.PostServiceToken(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "opaque user ID is not accepted")
+}
+
+func TestServiceTokenRoles(t *testing.T) {
+	c := config.NewForTest()
+	c.TrustedSubnet = "192.168.1.0/24"
+	c.TrustedProxies = []string{"192.0.2.0/24"}
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// mint requests the token from the trusted subnet
+	mint := func(body, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", strings.NewReader(body))
+		r.Header.Set("X-Real-IP", "192.168.1.15")
+		if token != "" {
+			r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	// bootstrap is the first admin token minted with the signing key
+	bootstrap, err := jwt.BuildUserJWTString(&user.User{ID: user.NewID(), Token: user.TokenService, Role: user.RoleAdmin},
+		c.JWT.SigningKey, time.Hour)
+	require.NoError(t, err)
+	// issue returns the token of the role issued by the admin to the trusted subnet
+	issue := func(body string) serviceTokenResponsePayload {
+		w := mint(body, bootstrap)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var issued serviceTokenResponsePayload
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&issued))
+		return issued
+	}
+	// serve returns the status of the request from outside the trusted subnet
+	serve := func(target, token string) int {
+		r := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		r.Header.Set("X-Real-IP", "10.0.0.1")
+		if token != "" {
+			r.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	service := issue("")
+	assert.Equal(t, user.RoleService, service.Role, "service tokens are of the service role by default")
+	admin := issue(`{"role":"admin"}`)
+	assert.Equal(t, user.RoleAdmin, admin.Role)
+
+	assert.Equal(t, http.StatusForbidden, serve("/api/internal/slo", ""))
+	assert.Equal(t, http.StatusOK, serve("/api/internal/slo", service.Token))
+	assert.Equal(t, http.StatusOK, serve("/api/internal/slo", admin.Token), "admin includes service")
+
+	assert.Equal(t, http.StatusForbidden, serve("/api/admin/urls/YBbxJEcQ9vq", service.Token))
+	assert.Equal(t, http.StatusNotFound, serve("/api/admin/urls/YBbxJEcQ9vq", admin.Token))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", http.NoBody)
+	r.Header.Set("X-Real-IP", "10.0.0.1")
+	r.AddCookie(&http.Cookie{Name: "Authorization", Value: admin.Token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code, "tokens are issued to the trusted subnet only")
+
+	// only the admins pick the user and the admin role
+	assert.Equal(t, http.StatusForbidden, mint(`{"role":"admin"}`, "").Code)
+	assert.Equal(t, http.StatusForbidden, mint(`{"role":"admin"}`, service.Token).Code)
+	assert.Equal(t, http.StatusForbidden, mint(`{"user_id":"`+service.UserID.String()+`"}`, "").Code)
+	assert.Equal(t, http.StatusCreated, mint(`{"user_id":"`+service.UserID.String()+`"}`, admin.Token).Code)
+	assert.Equal(t, http.StatusCreated, mint("", "").Code, "anyone in the trusted subnet mints the service tokens")
+}
+
+func TestPostServiceToken_SpoofedAddress(t *testing.T) {
+	c := config.NewForTest()
+	c.TrustedSubnet = "192.168.1.0/24"
+	l, _ := logger.NewForTest()
+	handler, err := New(memstore.NewURLRepository(), c, l)
+	require.NoError(t, err, "new handler error")
+	router := handler.Register(chi.NewRouter(), c, l)
+
+	// the peer is not a trusted proxy, so its header is ignored
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", strings.NewReader(`{"role":"admin"}`))
+	r.Header.Set("X-Real-IP", "192.168.1.15")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+}
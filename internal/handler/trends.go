@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// Number of the days of the creation trends.
+const (
+	defaultTrendDays = 30
+	maxTrendDays     = 366
+)
+
+// GetStatsTrends returns the daily numbers of the created URLs and
+// of the users who created them for the last days in UTC, today
+// included, oldest first. Days without created URLs have zero counts.
+// The optional days query parameter is the number of the days,
+// 30 by default and 366 at most.
+//
+// Request:
+//
+//	GET /api/internal/stats/trends?days=7
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	[
+//		{ "date": "2024-06-01", "created_urls": 120, "active_users": 14 },
+//		{ "date": "2024-06-02", "created_urls": 0, "active_users": 0 },
+//		...
+//	]
+func (h *Handler) GetStatsTrends(w http.ResponseWriter, r *http.Request) {
+	if h.trends == nil {
+		h.jsonError(w, r, "trends are disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	days, since, err := parseTrendDays(r)
+	if err != nil {
+		h.jsonError(w, r, fmt.Sprintf("days must be from 1 to %d", maxTrendDays),
+			err, http.StatusBadRequest)
+		return
+	}
+
+	trends, err := h.trends.GetTrends(r.Context(), since)
+	if err != nil {
+		h.jsonError(w, r, "failed to get trends", err, http.StatusInternalServerError)
+		return
+	}
+
+	response := fillTrends(trends, since, days)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, response); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseTrendDays returns the number of the days of the days query
+// parameter, defaultTrendDays if it is empty, and the first of the days
+// in UTC, today being the last one.
+func parseTrendDays(r *http.Request) (int, time.Time, error) {
+	days := defaultTrendDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxTrendDays {
+			return 0, time.Time{}, fmt.Errorf("%w: days %q", errs.ErrInvalidRequest, s)
+		}
+		days = n
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return days, today.AddDate(0, 0, 1-days), nil
+}
+
+// fillTrends returns the trends of the days since the given one,
+// the days missing from the stored trends having zero counts.
+func fillTrends(trends []models.DailyTrend, since time.Time, days int) []models.DailyTrend {
+	stored := make(map[string]models.DailyTrend, len(trends))
+	for _, t := range trends {
+		stored[t.Date] = t
+	}
+
+	filled := make([]models.DailyTrend, days)
+	for i := range filled {
+		date := since.AddDate(0, 0, i).Format(models.DateLayout)
+		filled[i] = stored[date]
+		filled[i].Date = date
+	}
+	return filled
+}
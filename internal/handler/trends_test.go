@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatsTrends(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "RTfd56hn", UserID: "test"},
+		{OriginalURL: "https://example.com/", ShortURL: "Qkfd67ds", UserID: "other"},
+	}))
+
+	tests := []struct {
+		name       string
+		query      string
+		disabled   bool
+		statusCode int
+		wantDays   int
+	}{
+		{name: "default days", statusCode: http.StatusOK, wantDays: defaultTrendDays},
+		{name: "a week", query: "?days=7", statusCode: http.StatusOK, wantDays: 7},
+		{name: "too many days", query: "?days=367", statusCode: http.StatusBadRequest},
+		{name: "invalid days", query: "?days=week", statusCode: http.StatusBadRequest},
+		{name: "trends disabled", disabled: true, statusCode: http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithTrends(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/api/internal/stats/trends"+tt.query, http.NoBody)
+			w := httptest.NewRecorder()
+
+			handler.GetStatsTrends(w, r)
+
+			res := w.Result()
+			defer func() { _ = res.Body.Close() }()
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var got []models.DailyTrend
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.Len(t, got, tt.wantDays)
+			assert.Equal(t, models.DailyTrend{
+				Date:        time.Now().UTC().Format(models.DateLayout),
+				CreatedURLs: 3,
+				ActiveUsers: 2,
+			}, got[len(got)-1], "today is the last day")
+			assert.Zero(t, got[0].CreatedURLs, "days without URLs are filled")
+			assert.NotEmpty(t, got[0].Date)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/asaskevich/govalidator"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// updateURLRequestPayload is the edit of the URL,
+	// the omitted fields are left unchanged.
+	updateURLRequestPayload struct {
+		URL *string `json:"url"`
+		// TTL is the new lifetime in seconds counted from now,
+		// zero removes the expiration.
+		TTL *int64 `json:"ttl"`
+		// Version is the version of the URL the edit is based on,
+		// the current one if omitted.
+		Version *int `json:"version"`
+	}
+
+	updateURLResponsePayload struct {
+		ShortURL    models.ShortURL    `json:"short_url"`
+		OriginalURL models.OriginalURL `json:"original_url"`
+		ExpiresAt   *time.Time         `json:"expires_at,omitempty"`
+		Version     int                `json:"version"`
+	}
+)
+
+// PatchURL changes the destination or the expiration of the short URL
+// owned by the user. The edit is applied to the version of the URL it is
+// based on only, so that the concurrent edits are not lost: the version
+// is returned by the list of the URLs of the user and by the previous
+// edit. Short URLs of other users are reported as not found, the ones
+// shared with the user respond with 403 Forbidden.
+//
+// Request:
+//
+//	PATCH /api/user/urls/{shortURL}
+//	Content-Type: application/json
+//	{ "url": "https://go.dev/blog/", "ttl": 86400, "version": 2 }
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"short_url": "http://localhost:8080/6qxTVvsy",
+//		"original_url": "https://go.dev/blog/",
+//		"expires_at": "2024-06-02T12:00:00Z",
+//		"version": 3
+//	}
+//
+// The URLs edited since the version respond with 409 Conflict,
+// as do the destinations already shortened.
+func (h *Handler) PatchURL(w http.ResponseWriter, r *http.Request) {
+	if h.updates == nil {
+		h.jsonError(w, r, "editing is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	if !h.IsApplicationJSONContentType(r) {
+		h.jsonError(w, r, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")),
+			errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL, err := h.parseShortURL(chi.URLParam(r, "shortURL"))
+	if err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	var payload updateURLRequestPayload
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if err = h.decodeJSON(r, &payload); err != nil {
+		h.jsonError(w, r, "failed to decode request", errs.ErrInvalidRequest, decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+	if payload.URL == nil && payload.TTL == nil {
+		h.jsonError(w, r, "nothing to update", errs.ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// the edit is applied to the latest version
+	record, err := h.store.Get(consistency.WithPrimary(r.Context()), shortURL)
+	if err == nil && record.UserID != user.ID && !record.IsDeleted {
+		// the URLs shared with the user are read-only
+		shared, sharedErr := h.sharedWith(r.Context(), record, user.ID)
+		if sharedErr != nil {
+			h.jsonError(w, r, "failed to get shares", sharedErr, http.StatusInternalServerError)
+			return
+		}
+		if shared {
+			h.jsonError(w, r, "URL is shared read-only", errs.ErrUnauthorized, http.StatusForbidden)
+			return
+		}
+	}
+	if err == nil && (record.UserID != user.ID || record.IsDeleted) {
+		err = fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.urlNotFound(w, r, shortURL, user.ID)
+			return
+		}
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
+		return
+	}
+
+	update := &models.URLUpdate{
+		OriginalURL: record.OriginalURL,
+		ExpiresAt:   record.ExpiresAt,
+		Version:     record.Version,
+	}
+	if payload.Version != nil {
+		update.Version = *payload.Version
+	}
+	if payload.URL != nil {
+		if !govalidator.IsURL(*payload.URL) {
+			h.jsonError(w, r, "invalid URL", errs.ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		// check if the new destination host can be shortened
+		if err = h.checkHost(r.Context(), *payload.URL); err != nil {
+			h.hostBlockedError(w, r, err, "")
+			return
+		}
+		update.OriginalURL = models.OriginalURL(*payload.URL)
+	}
+	if payload.TTL != nil {
+		if update.ExpiresAt, err = h.expiresAt(*payload.TTL); err != nil {
+			h.jsonError(w, r, "invalid TTL", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.updates.UpdateURL(r.Context(), user.ID, shortURL, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			h.urlNotFound(w, r, shortURL, user.ID)
+		case errors.Is(err, models.ErrStaleVersion):
+			h.jsonError(w, r, "URL was edited, retry with the current version", err, http.StatusConflict)
+		case errors.Is(err, errs.ErrConflict):
+			h.jsonError(w, r, "destination is already shortened", err, http.StatusConflict)
+		default:
+			h.jsonError(w, r, "failed to update url", err, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.publishEvents(events.TypeUpdated,
+		&models.URL{ShortURL: shortURL, OriginalURL: update.OriginalURL, UserID: user.ID})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := updateURLResponsePayload{
+		ShortURL:    models.ShortURL(h.absoluteURL(record.Domain, shortURL)),
+		OriginalURL: update.OriginalURL,
+		ExpiresAt:   update.ExpiresAt,
+		Version:     update.Version + 1,
+	}
+	if err = h.encodeData(w, r, response); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
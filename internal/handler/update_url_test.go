@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		shortURL    string
+		body        string
+		user        *user.User
+		disabled    bool
+		strict      bool
+		statusCode  int
+		wantURL     models.OriginalURL
+		wantExpires bool
+		wantVersion int
+	}{
+		{
+			name:        "destination changed",
+			shortURL:    "YBbxJEcQ9vq",
+			body:        `{"url": "https://go.dev/blog/", "version": 1}`,
+			user:        &user.User{ID: "test"},
+			statusCode:  http.StatusOK,
+			wantURL:     "https://go.dev/blog/",
+			wantExpires: true,
+			wantVersion: 2,
+		},
+		{
+			name:        "expiration removed at the current version",
+			shortURL:    "YBbxJEcQ9vq",
+			body:        `{"ttl": 0}`,
+			user:        &user.User{ID: "test"},
+			statusCode:  http.StatusOK,
+			wantURL:     "https://go.dev/",
+			wantVersion: 2,
+		},
+		{
+			name:       "stale version",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"url": "https://go.dev/blog/", "version": 0}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "nothing to update",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"version": 1}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid URL",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"url": "go dev"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "negative TTL",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"ttl": -1}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "URL of another user",
+			shortURL:   "Foreign",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "deleted URL",
+			shortURL:   "Removed",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "deleted URL with strict HTTP semantics",
+			shortURL:   "Removed",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			user:       &user.User{ID: "test"},
+			strict:     true,
+			statusCode: http.StatusGone,
+		},
+		{
+			name:       "URL of another user with strict HTTP semantics",
+			shortURL:   "Foreign",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			user:       &user.User{ID: "test"},
+			strict:     true,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "no user",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "editing disabled",
+			shortURL:   "YBbxJEcQ9vq",
+			body:       `{"url": "https://go.dev/blog/"}`,
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := time.Now().Add(time.Hour)
+			store := memstore.NewURLRepository()
+			require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+				{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test", ExpiresAt: &expiresAt},
+				{OriginalURL: "https://pkg.go.dev/", ShortURL: "Foreign", UserID: "other"},
+				{OriginalURL: "https://go.dev/doc/", ShortURL: "Removed", UserID: "test", IsDeleted: true},
+			}))
+			// the URL is edited once already
+			require.NoError(t, store.UpdateURL(context.TODO(), "test", "YBbxJEcQ9vq",
+				&models.URLUpdate{OriginalURL: "https://go.dev/", ExpiresAt: &expiresAt}))
+
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithURLUpdates(store))
+			}
+			cfg := config.NewForTest()
+			cfg.StrictHTTPSemantics = tt.strict
+			l, _ := logger.NewForTest()
+			handler, err := New(store, cfg, l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodPatch, "/api/user/urls/{shortURL}",
+				strings.NewReader(tt.body))
+			r.Header.Set(contentType, applicationJSON)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.user != nil {
+				ctx = user.NewContext(ctx, tt.user)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.PatchURL(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			var got updateURLResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.Equal(t, tt.wantURL, got.OriginalURL)
+			assert.Equal(t, tt.wantExpires, got.ExpiresAt != nil)
+			assert.Equal(t, tt.wantVersion, got.Version)
+
+			record, err := store.Get(context.TODO(), models.ShortURL(tt.shortURL))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantURL, record.OriginalURL)
+			assert.Equal(t, tt.wantVersion, record.Version)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetURLDetails returns the full record of the short URL including
+// its owner and creation metadata. It is intended for administrators
+// investigating abuse.
+//
+// Request:
+//
+//	GET /api/admin/urls/{shortURL}
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"id": "8f0e5a2c-...",
+//		"short_url": "YBbxJEcQ9vq",
+//		"original_url": "https://go.dev/",
+//		"user_id": "2a5c1d63-...",
+//		"is_deleted": false,
+//		"metadata": {
+//			"creator_ip": "192.0.2.1",
+//			"user_agent": "curl/8.5.0",
+//			"origin": "api"
+//		}
+//	}
+func (h *Handler) GetURLDetails(w http.ResponseWriter, r *http.Request) {
+	shortURL := chi.URLParam(r, "shortURL")
+
+	// check if shortened URL is valid
+	if _, err := h.parseShortURL(shortURL); err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.store.Get(r.Context(), models.ShortURL(shortURL))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusNotFound)
+			return
+		}
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
+		return
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// encode the response body
+	if err = h.encodeData(w, r, record); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetURLDetails_Metadata(t *testing.T) {
+	store := memstore.NewURLRepository()
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l)
+	require.NoError(t, err, "new handler error")
+
+	// shorten the URL on behalf of the client
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://go.dev/"}`))
+	r.Header.Set(contentType, applicationJSON)
+	r.Header.Set("X-Real-IP", "192.0.2.1")
+	r.Header.Set("User-Agent", "curl/8.5.0")
+	r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+	w := httptest.NewRecorder()
+
+	handler.PostShortenJSON(w, r)
+
+	res := w.Result()
+	shortURL := getShortURL(getShortenJSONResponsePayload(t, res).Result)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	want := models.Metadata{CreatorIP: "192.0.2.1", UserAgent: "curl/8.5.0", Origin: models.OriginAPI}
+
+	t.Run("admin", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/urls/{shortURL}", http.NoBody)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("shortURL", shortURL)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		handler.GetURLDetails(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got models.URL
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		assert.Equal(t, user.ID("test"), got.UserID)
+		assert.Equal(t, want, got.Metadata)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody)
+		r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: "test"}))
+		w := httptest.NewRecorder()
+
+		handler.GetAllByUserID(w, r)
+
+		res := w.Result()
+		defer func() { _ = res.Body.Close() }()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var got []getAllByUserIDResponsePayload
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got, 1)
+		require.NotNil(t, got[0].Metadata)
+		assert.Equal(t, want, *got[0].Metadata)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/urls/{shortURL}", http.NoBody)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("shortURL", "Unknown")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		handler.GetURLDetails(w, r)
+
+		res := w.Result()
+		require.NoError(t, res.Body.Close(), "failed close body")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
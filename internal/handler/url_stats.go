@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetURLStats returns the click statistics of the short URL owned by the user.
+// Short URLs of other users are reported as not found.
+// Clicks are saved in the background, so the latest redirects
+// may be missing from the statistics for a few seconds.
+// The fields query parameter selects the returned fields.
+//
+// Request:
+//
+//	GET /api/user/urls/{shortURL}/stats?fields=total_clicks,daily
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"short_url": "YBbxJEcQ9vq",
+//		"total_clicks": 3,
+//		"daily": [
+//			{"date": "2024-06-01", "clicks": 1},
+//			{"date": "2024-06-02", "clicks": 2}
+//		],
+//		"last_accessed_at": "2024-06-02T12:00:00Z",
+//		"devices": [
+//			{"name": "mobile", "clicks": 2},
+//			{"name": "desktop", "clicks": 1}
+//		],
+//		"os": [
+//			{"name": "Android", "clicks": 2},
+//			{"name": "Windows", "clicks": 1}
+//		],
+//		"browsers": [
+//			{"name": "Chrome", "clicks": 3}
+//		]
+//	}
+func (h *Handler) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	if h.clicks == nil {
+		h.jsonError(w, r, "click analytics is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	shortURL := chi.URLParam(r, "shortURL")
+
+	// check if shortened URL is valid
+	if _, err := h.parseShortURL(shortURL); err != nil {
+		h.jsonError(w, r, "invalid URL", err, http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseFields(r, reflect.TypeOf(models.ClickStats{}))
+	if err != nil {
+		h.jsonError(w, r, "invalid fields", err, http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.store.Get(r.Context(), models.ShortURL(shortURL))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusNotFound)
+			return
+		}
+		h.jsonError(w, r, "failed to retrieve url", err, http.StatusInternalServerError)
+		return
+	}
+	if record.UserID != user.ID {
+		h.jsonError(w, r, "no such URL", errs.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	stats, err := h.clicks.GetClickStats(r.Context(), record.ShortURL)
+	if err != nil {
+		h.jsonError(w, r, "failed to get click stats", err, http.StatusInternalServerError)
+		return
+	}
+
+	selected, err := selectFields(stats, fields)
+	if err != nil {
+		h.jsonError(w, r, "failed to select fields", err, http.StatusInternalServerError)
+		return
+	}
+
+	// set the response headers and status code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// encode the response body
+	if err = h.encodeData(w, r, selected); err != nil {
+		h.logger.Errorf("failed to encode response: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
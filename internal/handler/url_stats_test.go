@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetURLStats(t *testing.T) {
+	store := memstore.NewURLRepository()
+	require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+		{OriginalURL: "https://go.dev/", ShortURL: "YBbxJEcQ9vq", UserID: "test"},
+		{OriginalURL: "https://pkg.go.dev/", ShortURL: "Foreign", UserID: "other"},
+	}))
+
+	first := time.Date(2024, 6, 1, 23, 59, 0, 0, time.UTC)
+	last := time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SaveClicks(context.TODO(),
+		models.NewClick("YBbxJEcQ9vq", last, "192.0.2.1", "", "curl/8.5.0"),
+		models.NewClick("YBbxJEcQ9vq", first, "192.0.2.1", "https://go.dev/", ""),
+		models.NewClick("YBbxJEcQ9vq", last.Add(-time.Hour), "192.0.2.2", "", ""),
+		models.NewClick("Foreign", last, "192.0.2.1", "", ""),
+	))
+
+	tests := []struct {
+		name       string
+		shortURL   string
+		query      string
+		user       *user.User
+		disabled   bool
+		statusCode int
+		want       *models.ClickStats
+		wantJSON   string
+	}{
+		{
+			name:       "positive test",
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusOK,
+			want: &models.ClickStats{
+				ShortURL:    "YBbxJEcQ9vq",
+				TotalClicks: 3,
+				Daily: []models.DailyClicks{
+					{Date: "2024-06-01", Clicks: 1},
+					{Date: "2024-06-02", Clicks: 2},
+				},
+				LastAccessedAt: &last,
+				Devices:        []models.Share{{Name: "unknown", Clicks: 2}, {Name: "bot", Clicks: 1}},
+				OS:             []models.Share{{Name: "unknown", Clicks: 3}},
+				Browsers:       []models.Share{{Name: "unknown", Clicks: 3}},
+			},
+		},
+		{
+			name:       "selected fields",
+			shortURL:   "YBbxJEcQ9vq",
+			query:      "?fields=total_clicks",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusOK,
+			wantJSON:   `{"total_clicks": 3}`,
+		},
+		{
+			name:       "unknown field",
+			shortURL:   "YBbxJEcQ9vq",
+			query:      "?fields=total_clicks,clicks",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "URL of another user",
+			shortURL:   "Foreign",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "unknown URL",
+			shortURL:   "Unknown",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "invalid URL",
+			shortURL:   "0OIl",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no user",
+			shortURL:   "YBbxJEcQ9vq",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "click analytics disabled",
+			shortURL:   "YBbxJEcQ9vq",
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithClicks(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls/{shortURL}/stats"+tt.query, http.NoBody)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("shortURL", tt.shortURL)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.user != nil {
+				ctx = user.NewContext(ctx, tt.user)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.GetURLStats(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.wantJSON != "" {
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, tt.wantJSON, string(body))
+				return
+			}
+			if tt.want == nil {
+				return
+			}
+			assert.Equal(t, applicationJSON, res.Header.Get(contentType))
+			var got models.ClickStats
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.NotNil(t, got.LastAccessedAt)
+			assert.True(t, tt.want.LastAccessedAt.Equal(*got.LastAccessedAt))
+			got.LastAccessedAt = tt.want.LastAccessedAt
+			assert.Equal(t, *tt.want, got)
+		})
+	}
+}
+
+func TestGetRedirect_RecordsClicks(t *testing.T) {
+	shortURL := models.ShortURL("YBbxJEcQ9vq")
+	store := initMockStore(&models.URL{
+		OriginalURL: "https://go.dev/",
+		ShortURL:    shortURL,
+	})
+
+	l, _ := logger.NewForTest()
+	handler, err := New(store, config.NewForTest(), l, WithClicks(store))
+	require.NoError(t, err, "new handler error")
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/{shortURL}", http.NoBody)
+		r.Header.Set("Referer", "https://go.dev/blog/")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("shortURL", string(shortURL))
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		handler.GetRedirect(w, r)
+
+		res := w.Result()
+		require.NoError(t, res.Body.Close(), "failed close body")
+		require.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	}
+
+	// Stop flushes the buffered clicks.
+	handler.Stop()
+
+	stats, err := store.GetClickStats(context.TODO(), shortURL)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalClicks)
+	assert.NotNil(t, stats.LastAccessedAt)
+}
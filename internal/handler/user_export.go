@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Formats of the export of the URLs of the user.
+const (
+	userExportJSON = "json"
+	userExportCSV  = "csv"
+)
+
+// userExportFlushRows is the number of the exported URLs
+// sent to the client at once.
+const userExportFlushRows = 100
+
+// userExportColumns is the header of the CSV export,
+// in the order of the fields of userExportRecord.
+var userExportColumns = []string{
+	"short_url", "original_url", "created_at", "expires_at", "last_accessed_at",
+	"is_deleted", "state", "note", "clicks",
+}
+
+// userExportRecord is the URL of the user in the export. The timestamps
+// are RFC 3339 in the time zone of the request, empty if not set.
+type userExportRecord struct {
+	ShortURL       models.ShortURL    `json:"short_url"`
+	OriginalURL    models.OriginalURL `json:"original_url"`
+	CreatedAt      string             `json:"created_at,omitempty"`
+	ExpiresAt      string             `json:"expires_at,omitempty"`
+	LastAccessedAt string             `json:"last_accessed_at,omitempty"`
+	IsDeleted      bool               `json:"is_deleted"`
+	State          models.LinkState   `json:"state"`
+	Note           string             `json:"note,omitempty"`
+	Clicks         int                `json:"clicks"`
+}
+
+// csv returns the record as the row of the CSV export.
+func (rec *userExportRecord) csv() []string {
+	return []string{
+		string(rec.ShortURL), string(rec.OriginalURL), rec.CreatedAt, rec.ExpiresAt,
+		rec.LastAccessedAt, strconv.FormatBool(rec.IsDeleted), string(rec.State),
+		rec.Note, strconv.Itoa(rec.Clicks),
+	}
+}
+
+// GetUserURLsExport streams all the URLs of the user, including
+// the deleted ones, ordered by their short URLs, as a JSON array or
+// a CSV file with the header. The URLs are read with a cursor and sent
+// in batches, so that the export of any size is not held in memory.
+// The format query parameter selects the format, json by default.
+// The URLs created before the creation times were kept have no created_at.
+//
+// Request:
+//
+//	GET /api/user/urls/export?format=csv
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: text/csv; charset=utf-8
+//	Content-Disposition: attachment; filename="urls-20240601T120000Z.csv"
+//
+//	short_url,original_url,created_at,expires_at,last_accessed_at,is_deleted,state,note,clicks
+//	http://localhost:8080/6qxTVvsy,https://go.dev/,2024-05-01T12:00:00Z,,,false,active,,42
+//
+// The failure after the first batch is sent truncates the export.
+func (h *Handler) GetUserURLsExport(w http.ResponseWriter, r *http.Request) {
+	if h.userScanner == nil {
+		h.jsonError(w, r, "export is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = userExportJSON
+	}
+	var (
+		enc         userExportEncoder
+		contentType string
+	)
+	// the records are scanned before the first batch is sent,
+	// so the failed scan is reported with the status code
+	sw := &startedWriter{ResponseWriter: w}
+	switch format {
+	case userExportJSON:
+		enc, contentType = &userExportJSONEncoder{w: bufio.NewWriter(sw)}, "application/json"
+	case userExportCSV:
+		enc, contentType = &userExportCSVEncoder{w: csv.NewWriter(sw)}, "text/csv; charset=utf-8"
+	default:
+		h.jsonError(w, r, "invalid format",
+			fmt.Errorf("%w: unknown format %q, want json or csv", errs.ErrInvalidRequest, format),
+			http.StatusBadRequest)
+		return
+	}
+	sw.Header().Set("Content-Type", contentType)
+	sw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		"urls-"+time.Now().UTC().Format("20060102T150405Z")+"."+format))
+
+	var (
+		loc  = location(r)
+		now  = time.Now()
+		rows = 0
+	)
+	err := h.userScanner.ScanUserURLs(r.Context(), user.ID, func(u *models.URL, clicks int) error {
+		rec := &userExportRecord{
+			ShortURL:       models.ShortURL(h.absoluteURL(u.Domain, u.ShortURL)),
+			OriginalURL:    u.OriginalURL,
+			CreatedAt:      formatExportTime(u.CreatedAt, loc),
+			ExpiresAt:      formatExportTime(u.ExpiresAt, loc),
+			LastAccessedAt: formatExportTime(u.LastAccessedAt, loc),
+			IsDeleted:      u.IsDeleted,
+			State:          u.Lifecycle(now),
+			Note:           u.Note,
+			Clicks:         clicks,
+		}
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("encode %s: %w", u.ShortURL, err)
+		}
+		if rows++; rows%userExportFlushRows == 0 {
+			return flushExport(w, enc)
+		}
+		return nil
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		if !sw.started {
+			sw.Header().Del("Content-Disposition")
+			h.jsonError(w, r, "failed to export", err, http.StatusInternalServerError)
+			return
+		}
+		h.logger.Errorf("failed to write export of user %s: %s", user.ID, err)
+		return
+	}
+}
+
+// formatExportTime returns the time as RFC 3339 in the time zone,
+// empty if it is nil.
+func formatExportTime(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// flushExport sends the encoded records to the client.
+func flushExport(w http.ResponseWriter, enc userExportEncoder) error {
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	// the writers not supporting the flushes send the export at the end
+	if err := http.NewResponseController(w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// userExportEncoder writes the records of the export in its format.
+type userExportEncoder interface {
+	// Encode writes the record, possibly buffered.
+	Encode(rec *userExportRecord) error
+	// Flush writes the buffered records.
+	Flush() error
+	// Close completes the export and writes the buffered records.
+	Close() error
+}
+
+// userExportJSONEncoder writes the records as the elements of the JSON array.
+type userExportJSONEncoder struct {
+	w    *bufio.Writer
+	rows int
+}
+
+// Encode writes the record as the next element of the array.
+func (e *userExportJSONEncoder) Encode(rec *userExportRecord) error {
+	sep := ","
+	if e.rows == 0 {
+		sep = "["
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	e.rows++
+	_, err = fmt.Fprintf(e.w, "%s%s\n", sep, b)
+	return err
+}
+
+// Flush writes the buffered elements.
+func (e *userExportJSONEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Close closes the array, empty if there are no records.
+func (e *userExportJSONEncoder) Close() error {
+	end := "]\n"
+	if e.rows == 0 {
+		end = "[]\n"
+	}
+	if _, err := e.w.WriteString(end); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// userExportCSVEncoder writes the records as the rows of the CSV file
+// following the header.
+type userExportCSVEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+// Encode writes the record as the row, the header first.
+func (e *userExportCSVEncoder) Encode(rec *userExportRecord) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.w.Write(rec.csv())
+}
+
+// Flush writes the buffered rows.
+func (e *userExportCSVEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// Close writes the header if there are no records and the buffered rows.
+func (e *userExportCSVEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// writeHeader writes the header once.
+func (e *userExportCSVEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(userExportColumns)
+}
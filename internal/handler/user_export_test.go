@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserURLsExport(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		user            *user.User
+		disabled        bool
+		statusCode      int
+		wantContentType string
+	}{
+		{
+			name:            "json by default",
+			user:            &user.User{ID: "test"},
+			statusCode:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "csv",
+			query:           "?format=csv",
+			user:            &user.User{ID: "test"},
+			statusCode:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:       "unknown format",
+			query:      "?format=xml",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no user",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "export disabled",
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewURLRepository()
+			require.NoError(t, store.SaveAll(context.TODO(), []*models.URL{
+				{OriginalURL: "https://pkg.go.dev/", ShortURL: "Deleted", UserID: "test"},
+				{OriginalURL: "https://go.dev/", ShortURL: "Active", UserID: "test", Note: "docs, spring"},
+				{OriginalURL: "https://go.dev/blog/", ShortURL: "Foreign", UserID: "other"},
+			}))
+			require.NoError(t, store.DeleteURLs(context.TODO(),
+				&models.URL{ShortURL: "Deleted", UserID: "test"}))
+			require.NoError(t, store.SaveClicks(context.TODO(),
+				&models.Click{ShortURL: "Active", ClickedAt: time.Now()},
+				&models.Click{ShortURL: "Active", ClickedAt: time.Now()}))
+
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithUserExport(store))
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, config.NewForTest(), l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls/export"+tt.query, nil)
+			if tt.user != nil {
+				r = r.WithContext(user.NewContext(r.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			handler.GetUserURLsExport(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantContentType, res.Header.Get("Content-Type"))
+			assert.Contains(t, res.Header.Get("Content-Disposition"), "attachment")
+
+			var got []userExportRecord
+			if tt.query == "" {
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			} else {
+				rows, err := csv.NewReader(res.Body).ReadAll()
+				require.NoError(t, err)
+				require.NotEmpty(t, rows)
+				assert.Equal(t, userExportColumns, rows[0])
+				for _, row := range rows[1:] {
+					clicks, err := strconv.Atoi(row[8])
+					require.NoError(t, err)
+					got = append(got, userExportRecord{
+						ShortURL:  models.ShortURL(row[0]),
+						CreatedAt: row[2],
+						IsDeleted: row[5] == "true",
+						Note:      row[7],
+						Clicks:    clicks,
+					})
+				}
+			}
+
+			// the URLs of the user only, ordered by the short URLs
+			require.Len(t, got, 2)
+			assert.Contains(t, string(got[0].ShortURL), "Active")
+			assert.Equal(t, "docs, spring", got[0].Note)
+			assert.Equal(t, 2, got[0].Clicks)
+			assert.False(t, got[0].IsDeleted)
+			assert.NotEmpty(t, got[0].CreatedAt)
+			assert.Contains(t, string(got[1].ShortURL), "Deleted")
+			assert.True(t, got[1].IsDeleted)
+			assert.Zero(t, got[1].Clicks)
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/events"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/asaskevich/govalidator"
+	"go.uber.org/zap"
+)
+
+// userImportFile is the name of the form field of the imported file.
+const userImportFile = "file"
+
+// userImportInvalid is the outcome of the row of the import
+// failing the validation. It is not saved.
+const userImportInvalid models.SaveStatus = "invalid"
+
+// userImportItem is the row of the imported file.
+type userImportItem struct {
+	OriginalURL string `json:"original_url"`
+	Alias       string `json:"alias,omitempty"`
+}
+
+type (
+	userImportResponsePayload struct {
+		Created int             `json:"created"`
+		Exists  int             `json:"exists"`
+		Invalid int             `json:"invalid"`
+		Rows    []userImportRow `json:"rows"`
+	}
+
+	// userImportRow is the outcome of the row of the imported file.
+	userImportRow struct {
+		// Row is the number of the row from 1, not counting the CSV header.
+		Row         int               `json:"row"`
+		OriginalURL string            `json:"original_url"`
+		Alias       string            `json:"alias,omitempty"`
+		ShortURL    models.ShortURL   `json:"short_url,omitempty"`
+		Status      models.SaveStatus `json:"status"`
+		Error       string            `json:"error,omitempty"`
+	}
+)
+
+// PostUserURLsImport shortens the URLs of the file uploaded as the file
+// field of the multipart form. The file is either CSV of the original URLs
+// and the optional aliases, with the optional original_url,alias header,
+// or JSON array of the objects with the same fields. The format is told by
+// the extension of the file name or its content type.
+//
+// Every row is validated as the URL shortened alone, the invalid rows are
+// skipped. The valid ones are saved in batches, the ones conflicting with
+// the stored URLs are reported as existing. The file is limited as the JSON
+// requests are: its size by the maximum body size and the number of its rows
+// by the maximum number of the batch items.
+//
+// Request:
+//
+//	POST /api/user/urls/import
+//	Content-Type: multipart/form-data; boundary=...
+//
+//	--...
+//	Content-Disposition: form-data; name="file"; filename="urls.csv"
+//
+//	original_url,alias
+//	https://go.dev/,go-dev
+//	https://pkg.go.dev/,
+//	--...--
+//
+// Response:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"created": 1,
+//		"exists": 0,
+//		"invalid": 1,
+//		"rows": [
+//			{
+//				"row": 1,
+//				"original_url": "https://go.dev/",
+//				"alias": "go-dev",
+//				"status": "invalid",
+//				"error": "invalid alias: ..."
+//			},
+//			{
+//				"row": 2,
+//				"original_url": "https://pkg.go.dev/",
+//				"short_url": "http://config.AddrToReturn/Base58",
+//				"status": "created"
+//			}
+//		]
+//	}
+func (h *Handler) PostUserURLsImport(w http.ResponseWriter, r *http.Request) {
+	if h.saveResults == nil {
+		h.jsonError(w, r, "import is disabled",
+			fmt.Errorf("%w: not supported by the storage", errs.ErrInvalidRequest),
+			http.StatusNotImplemented)
+		return
+	}
+
+	user, ok := user.FromContext(r.Context())
+	if !ok {
+		h.jsonError(w, r, "no user found", errs.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			h.logger.Errorf("close body: %v", err)
+		}
+	}()
+	if maxSize := h.config.JSON.MaxBodySize; maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+	items, err := h.readUserImport(r)
+	if err != nil {
+		h.jsonError(w, r, "failed to read file", fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err),
+			decodeStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	rows, err := h.importUserURLs(r.Context(), user.ID, requestMetadata(r, models.OriginImport), items)
+	if err != nil {
+		h.jsonError(w, r, "failed to import", err, http.StatusInternalServerError)
+		return
+	}
+
+	result := userImportResponsePayload{Rows: rows}
+	for _, row := range rows {
+		switch row.Status {
+		case models.SaveStatusCreated:
+			result.Created++
+		case models.SaveStatusExists:
+			result.Exists++
+		default:
+			result.Invalid++
+		}
+	}
+	h.logger.Infof("user %s imported %d URLs, %d existing, %d invalid",
+		user.ID, result.Created, result.Exists, result.Invalid)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = h.encodeData(w, r, result); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// importUserURLs validates the rows and saves the valid ones in batches,
+// returning the outcome of every row in the order of the file.
+func (h *Handler) importUserURLs(
+	ctx context.Context, userID user.ID, metadata models.Metadata, items []userImportItem,
+) ([]userImportRow, error) {
+	rows := make([]userImportRow, len(items))
+	// the rows of the records in the batch
+	batch := make([]*models.URL, 0, importBatchSize)
+	batchRows := make([]int, 0, importBatchSize)
+	aliases := make(map[string]int)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := h.saveResults.SaveAllResults(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("save urls: %w", err)
+		}
+		for i, res := range results {
+			rows[batchRows[i]].Status = res.Status
+			if res.Status == models.SaveStatusCreated {
+				h.publishEvents(events.TypeCreated, batch[i])
+			}
+		}
+		batch, batchRows = batch[:0], batchRows[:0]
+		return nil
+	}
+
+	for i, item := range items {
+		rows[i] = userImportRow{Row: i + 1, OriginalURL: item.OriginalURL, Alias: item.Alias}
+
+		if err := h.checkUserImportItem(ctx, userID, item); err != nil {
+			if !errors.Is(err, errs.ErrInvalidRequest) {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+			rows[i].Status, rows[i].Error = userImportInvalid, err.Error()
+			continue
+		}
+		if item.Alias != "" {
+			if first, ok := aliases[item.Alias]; ok {
+				rows[i].Status = userImportInvalid
+				rows[i].Error = fmt.Sprintf("%s: alias is repeated from row %d", errs.ErrInvalidRequest, first)
+				continue
+			}
+			aliases[item.Alias] = i + 1
+		}
+
+		shortURL := item.Alias
+		if shortURL == "" {
+			var err error
+			shortURL, err = h.generateShortURL(ctx, item.OriginalURL)
+			if err != nil {
+				return nil, fmt.Errorf("generate short URL: %w", err)
+			}
+		}
+		record := models.NewRecord(shortURL, item.OriginalURL, userID)
+		record.Metadata = metadata
+		rows[i].ShortURL = models.ShortURL(h.absoluteURL("", record.ShortURL))
+		batch = append(batch, record)
+		batchRows = append(batchRows, i)
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// checkUserImportItem checks the row as the URL shortened alone.
+// The invalid rows are reported with ErrInvalidRequest.
+func (h *Handler) checkUserImportItem(ctx context.Context, userID user.ID, item userImportItem) error {
+	if item.OriginalURL == "" {
+		return fmt.Errorf("%w: URL is not provided", errs.ErrInvalidRequest)
+	}
+	if !govalidator.IsURL(item.OriginalURL) {
+		return fmt.Errorf("%w: invalid URL", errs.ErrInvalidRequest)
+	}
+	if err := h.checkHost(ctx, item.OriginalURL); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	if item.Alias == "" {
+		return nil
+	}
+
+	err := h.checkAlias(ctx, item.Alias, userID)
+	if errors.Is(err, errs.ErrConflict) {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	return err
+}
+
+// readUserImport reads the rows of the file of the import request.
+func (h *Handler) readUserImport(r *http.Request) ([]userImportItem, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("no %s field", userImportFile)
+		}
+		if err != nil {
+			return nil, limitError(err)
+		}
+		if part.FormName() != userImportFile {
+			continue
+		}
+
+		switch format := userImportFormat(part); format {
+		case userExportCSV:
+			return h.readUserImportCSV(part)
+		case userExportJSON:
+			return h.readUserImportJSON(part)
+		default:
+			return nil, fmt.Errorf("unsupported file %q, want csv or json", part.FileName())
+		}
+	}
+}
+
+// userImportFormat returns the format of the file told by the extension
+// of its name or by its content type, empty if it is unknown.
+func userImportFormat(part *multipart.Part) string {
+	switch strings.ToLower(path.Ext(part.FileName())) {
+	case ".csv":
+		return userExportCSV
+	case ".json":
+		return userExportJSON
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return userExportCSV
+	case "application/json":
+		return userExportJSON
+	}
+	return ""
+}
+
+// readUserImportCSV reads the rows of the CSV file. The columns are
+// the original URL and the optional alias, or the ones named by the header.
+func (h *Handler) readUserImportCSV(file io.Reader) ([]userImportItem, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	urlColumn, aliasColumn := 0, 1
+	var items []userImportItem
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, limitError(err)
+		}
+
+		if line == 0 && strings.TrimSpace(record[0]) == "original_url" {
+			urlColumn, aliasColumn = 0, -1
+			for i, name := range record {
+				if strings.TrimSpace(name) == "alias" {
+					aliasColumn = i
+				}
+			}
+			continue
+		}
+
+		if maxItems := h.config.JSON.MaxItems; maxItems > 0 && len(items) == maxItems {
+			return nil, fmt.Errorf("%w: more than %d rows", errTooLarge, maxItems)
+		}
+		item := userImportItem{OriginalURL: strings.TrimSpace(record[urlColumn])}
+		if aliasColumn >= 0 && aliasColumn < len(record) {
+			item.Alias = strings.TrimSpace(record[aliasColumn])
+		}
+		items = append(items, item)
+	}
+}
+
+// readUserImportJSON reads the rows of the JSON array item by item.
+func (h *Handler) readUserImportJSON(file io.Reader) ([]userImportItem, error) {
+	dec := json.NewDecoder(file)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, limitError(err)
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("array expected, got %v", tok)
+	}
+
+	var items []userImportItem
+	for dec.More() {
+		if maxItems := h.config.JSON.MaxItems; maxItems > 0 && len(items) == maxItems {
+			return nil, fmt.Errorf("%w: more than %d rows", errTooLarge, maxItems)
+		}
+		var item userImportItem
+		if err = dec.Decode(&item); err != nil {
+			return nil, limitError(err)
+		}
+		items = append(items, item)
+	}
+	if _, err = dec.Token(); err != nil {
+		return nil, limitError(err)
+	}
+
+	return items, nil
+}
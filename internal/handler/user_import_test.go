@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostUserURLsImport(t *testing.T) {
+	const csvFile = "original_url,alias\n" +
+		"https://go.dev/,GoDev\n" +
+		"https://pkg.go.dev/,\n" +
+		"go dev,\n" +
+		"https://go.dev/blog/,Taken\n" +
+		"https://go.dev/doc/,GoDev\n"
+
+	tests := []struct {
+		name        string
+		field       string
+		filename    string
+		file        string
+		user        *user.User
+		disabled    bool
+		maxItems    int
+		statusCode  int
+		wantStatus  []models.SaveStatus
+		wantCreated int
+	}{
+		{
+			name:     "csv with the header",
+			field:    "file",
+			filename: "urls.csv",
+			file:     csvFile,
+			user:     &user.User{ID: "test"},
+			wantStatus: []models.SaveStatus{
+				models.SaveStatusCreated, models.SaveStatusCreated, userImportInvalid,
+				models.SaveStatusExists, userImportInvalid,
+			},
+			wantCreated: 2,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:     "csv without the header",
+			field:    "file",
+			filename: "urls.csv",
+			file:     "https://go.dev/\nhttps://go.dev/\n",
+			user:     &user.User{ID: "test"},
+			wantStatus: []models.SaveStatus{
+				models.SaveStatusCreated, models.SaveStatusExists,
+			},
+			wantCreated: 1,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:     "json",
+			field:    "file",
+			filename: "urls.json",
+			file:     `[{"original_url": "https://go.dev/", "alias": "GoDev"}, {"original_url": ""}]`,
+			user:     &user.User{ID: "test"},
+			wantStatus: []models.SaveStatus{
+				models.SaveStatusCreated, userImportInvalid,
+			},
+			wantCreated: 1,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:       "unsupported format",
+			field:      "file",
+			filename:   "urls.txt",
+			file:       "https://go.dev/",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "no file",
+			field:      "urls",
+			filename:   "urls.csv",
+			file:       "https://go.dev/",
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed json",
+			field:      "file",
+			filename:   "urls.json",
+			file:       `{"original_url": "https://go.dev/"}`,
+			user:       &user.User{ID: "test"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "too many rows",
+			field:      "file",
+			filename:   "urls.csv",
+			file:       csvFile,
+			user:       &user.User{ID: "test"},
+			maxItems:   2,
+			statusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "no user",
+			field:      "file",
+			filename:   "urls.csv",
+			file:       csvFile,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "import disabled",
+			field:      "file",
+			filename:   "urls.csv",
+			file:       csvFile,
+			user:       &user.User{ID: "test"},
+			disabled:   true,
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewURLRepository()
+			require.NoError(t, store.Save(context.TODO(),
+				&models.URL{OriginalURL: "https://go.dev/ref/", ShortURL: "Taken", UserID: "other"}))
+
+			var opts []Option
+			if !tt.disabled {
+				opts = append(opts, WithUserImport(store))
+			}
+			c := config.NewForTest()
+			if tt.maxItems > 0 {
+				c.JSON.MaxItems = tt.maxItems
+			}
+			l, _ := logger.NewForTest()
+			handler, err := New(store, c, l, opts...)
+			require.NoError(t, err, "new handler error")
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, err := mw.CreateFormFile(tt.field, tt.filename)
+			require.NoError(t, err)
+			_, err = fw.Write([]byte(tt.file))
+			require.NoError(t, err)
+			require.NoError(t, mw.Close())
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/urls/import", &body)
+			r.Header.Set(contentType, mw.FormDataContentType())
+			if tt.user != nil {
+				r = r.WithContext(user.NewContext(r.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			handler.PostUserURLsImport(w, r)
+
+			res := w.Result()
+			defer func() {
+				require.NoError(t, res.Body.Close(), "failed close body")
+			}()
+
+			require.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			var got userImportResponsePayload
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			require.Len(t, got.Rows, len(tt.wantStatus))
+			for i, row := range got.Rows {
+				assert.Equal(t, i+1, row.Row)
+				assert.Equal(t, tt.wantStatus[i], row.Status, "row %d: %s", row.Row, row.Error)
+				assert.Equal(t, row.Status == userImportInvalid, row.Error != "")
+			}
+			assert.Equal(t, tt.wantCreated, got.Created)
+
+			URLs, err := store.GetAllByUserID(context.TODO(), "test")
+			require.NoError(t, err)
+			assert.Len(t, URLs, tt.wantCreated)
+			for _, u := range URLs {
+				assert.Equal(t, models.OriginImport, u.Metadata.Origin)
+			}
+		})
+	}
+}
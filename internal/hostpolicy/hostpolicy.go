@@ -0,0 +1,184 @@
+// Package hostpolicy decides which destination hosts can be shortened
+// according to the configured allowlist and denylist.
+//
+// Patterns are the host names, e.g. example.com, matching the host
+// itself, or the wildcards, e.g. *.example.com, matching its subdomains
+// at any depth. The single * matches every host. Hosts are matched
+// case-insensitively.
+package hostpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrBlocked is returned for the hosts the policy blocks.
+var ErrBlocked = errors.New("host is blocked")
+
+// Reasons the host is blocked.
+const (
+	// ReasonDenied is the reason of the hosts matching the denylist.
+	ReasonDenied = "host_denied"
+	// ReasonNotAllowed is the reason of the hosts missing from the allowlist.
+	ReasonNotAllowed = "host_not_allowed"
+	// ReasonBanned is the reason of the hosts of the domains banned
+	// by the moderators.
+	ReasonBanned = "host_banned"
+)
+
+// BlockedError is the error of the blocked host. It matches ErrBlocked.
+type BlockedError struct {
+	// Host is the blocked host.
+	Host string
+	// Reason is the machine-readable reason, ReasonDenied, ReasonNotAllowed
+	// or ReasonBanned.
+	Reason string
+}
+
+// Error implements error.
+func (e *BlockedError) Error() string {
+	switch e.Reason {
+	case ReasonDenied:
+		return fmt.Sprintf("%s: %s is denied", ErrBlocked, e.Host)
+	case ReasonBanned:
+		return fmt.Sprintf("%s: %s is banned", ErrBlocked, e.Host)
+	default:
+		return fmt.Sprintf("%s: %s is not allowed", ErrBlocked, e.Host)
+	}
+}
+
+// Is reports whether the target is ErrBlocked.
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Policy checks the destination hosts. The zero value allows every host.
+type Policy struct {
+	allow, deny []pattern
+}
+
+// New returns the policy allowing only the hosts matching the allow
+// patterns, every host if there are none, except for the hosts matching
+// the deny patterns.
+func New(allow, deny []string) (*Policy, error) {
+	p := new(Policy)
+
+	var err error
+	if p.allow, err = parsePatterns(allow); err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	if p.deny, err = parsePatterns(deny); err != nil {
+		return nil, fmt.Errorf("denylist: %w", err)
+	}
+
+	return p, nil
+}
+
+// Check returns *BlockedError if the host of the URL is blocked.
+// URLs without the scheme are treated as http ones.
+func (p *Policy) Check(rawURL string) error {
+	if p == nil || (len(p.allow) == 0 && len(p.deny) == 0) {
+		return nil
+	}
+
+	host := Host(rawURL)
+	switch {
+	case matchAny(p.deny, host):
+		return &BlockedError{Host: host, Reason: ReasonDenied}
+	case len(p.allow) > 0 && !matchAny(p.allow, host):
+		return &BlockedError{Host: host, Reason: ReasonNotAllowed}
+	default:
+		return nil
+	}
+}
+
+// Host returns the lower-case host of the URL without the port.
+// URLs without the scheme are treated as http ones.
+func Host(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// ParseDomain returns the normalized domain name, e.g. of the domain
+// banned by the moderators. Unlike the patterns, it can't be a wildcard.
+func ParseDomain(s string) (string, error) {
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+	if domain == "" || strings.ContainsAny(domain, "*/:@ ") || strings.HasPrefix(domain, ".") {
+		return "", fmt.Errorf("invalid domain %q", s)
+	}
+	return domain, nil
+}
+
+// Domains returns the host and the domains it is a subdomain of,
+// e.g. a.example.com, example.com and com for a.example.com,
+// so that the host is matched against the domains by the lookup.
+func Domains(host string) []string {
+	domains := []string{host}
+	for i := strings.IndexByte(host, '.'); i >= 0; i = strings.IndexByte(host, '.') {
+		host = host[i+1:]
+		if host == "" {
+			break
+		}
+		domains = append(domains, host)
+	}
+	return domains
+}
+
+// pattern is the parsed host pattern.
+type pattern struct {
+	// host is the host or the parent domain of the wildcard.
+	host string
+	// wildcard matches the subdomains of host, or every host if it is empty.
+	wildcard bool
+}
+
+// parsePatterns parses the host patterns skipping the blank ones.
+func parsePatterns(patterns []string) ([]pattern, error) {
+	parsed := make([]pattern, 0, len(patterns))
+	for _, s := range patterns {
+		s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+		switch {
+		case s == "":
+			continue
+		case s == "*":
+			parsed = append(parsed, pattern{wildcard: true})
+			continue
+		case strings.HasPrefix(s, "*."):
+			parsed = append(parsed, pattern{host: s[2:], wildcard: true})
+		default:
+			parsed = append(parsed, pattern{host: s})
+		}
+
+		host := parsed[len(parsed)-1].host
+		if host == "" || strings.ContainsAny(host, "*/:@ ") || strings.HasPrefix(host, ".") {
+			return nil, fmt.Errorf("invalid host pattern %q", s)
+		}
+	}
+	return parsed, nil
+}
+
+// matchAny reports whether the host matches any of the patterns.
+func matchAny(patterns []pattern, host string) bool {
+	for _, p := range patterns {
+		if p.match(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the host matches the pattern.
+func (p pattern) match(host string) bool {
+	if !p.wildcard {
+		return host == p.host
+	}
+	return p.host == "" || strings.HasSuffix(host, "."+p.host)
+}
@@ -0,0 +1,78 @@
+package hostpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		allow  []string
+		deny   []string
+		url    string
+		reason string
+	}{
+		{name: "no lists", url: "https://example.com/"},
+		{name: "denied host", deny: []string{"example.com"}, url: "https://Example.COM:8443/path",
+			reason: ReasonDenied},
+		{name: "subdomain of denied host", deny: []string{"example.com"}, url: "https://www.example.com/"},
+		{name: "denied wildcard", deny: []string{"*.example.com"}, url: "https://a.b.example.com/",
+			reason: ReasonDenied},
+		{name: "wildcard does not match the domain", deny: []string{"*.example.com"}, url: "https://example.com/"},
+		{name: "wildcard does not match the suffix", deny: []string{"*.example.com"}, url: "https://badexample.com/"},
+		{name: "allowed host", allow: []string{"go.dev", "*.go.dev"}, url: "https://pkg.go.dev/"},
+		{name: "host not allowed", allow: []string{"go.dev"}, url: "https://example.com/",
+			reason: ReasonNotAllowed},
+		{name: "denied over allowed", allow: []string{"*"}, deny: []string{"evil.go.dev"},
+			url: "https://evil.go.dev/", reason: ReasonDenied},
+		{name: "URL without scheme", deny: []string{"example.com"}, url: "example.com/path",
+			reason: ReasonDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.allow, tt.deny)
+			require.NoError(t, err)
+
+			err = p.Check(tt.url)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrBlocked)
+			var blocked *BlockedError
+			require.ErrorAs(t, err, &blocked)
+			assert.Equal(t, tt.reason, blocked.Reason)
+		})
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"*.*", "a*.example.com", "example.com/path", "..example.com"} {
+		_, err := New([]string{pattern}, nil)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+	assert.NoError(t, p.Check("https://example.com/"))
+}
+
+func TestParseDomain(t *testing.T) {
+	domain, err := ParseDomain(" Example.COM. ")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", domain)
+
+	for _, s := range []string{"", "*.example.com", "example.com/path", ".example.com", "user@example.com"} {
+		_, err = ParseDomain(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDomains(t *testing.T) {
+	assert.Equal(t, []string{"a.b.example.com", "b.example.com", "example.com", "com"}, Domains("a.b.example.com"))
+	assert.Equal(t, []string{"localhost"}, Domains("localhost"))
+}
@@ -0,0 +1,150 @@
+// Package i18n provides the translations of the user facing messages
+// and the negotiation of the language preferred by the client.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"golang.org/x/text/language"
+)
+
+// Key identifies a message in the catalog.
+type Key string
+
+// Keys of the messages describing the application errors.
+// They are shared by the API and the UI.
+const (
+	ErrInvalidRequest Key = "error.invalid_request"
+	ErrNotFound       Key = "error.not_found"
+	ErrConflict       Key = "error.conflict"
+	ErrUnauthorized   Key = "error.unauthorized"
+	ErrInternal       Key = "error.internal"
+)
+
+// DefaultLanguage is used when none of the client languages is supported.
+var DefaultLanguage = language.English
+
+// locales contains the translations, one JSON file per language
+// named by its BCP 47 tag, e.g. en.json.
+//
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog is the set of translations of all the supported languages.
+// It is safe for concurrent use.
+type Catalog struct {
+	// tags are the supported languages, the default one goes first.
+	tags []language.Tag
+	// matcher picks the best supported language.
+	matcher language.Matcher
+	// messages are the translations by the index of the language in tags.
+	messages []map[Key]string
+}
+
+// New returns the catalog of the embedded translations.
+func New() (*Catalog, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("read locales: %w", err)
+	}
+
+	c := &Catalog{
+		tags:     []language.Tag{DefaultLanguage},
+		messages: []map[Key]string{nil},
+	}
+
+	for _, f := range files {
+		tag, err := language.Parse(strings.TrimSuffix(f.Name(), path.Ext(f.Name())))
+		if err != nil {
+			return nil, fmt.Errorf("locale %s: %w", f.Name(), err)
+		}
+
+		data, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read locale %s: %w", f.Name(), err)
+		}
+
+		var messages map[Key]string
+		if err = json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("decode locale %s: %w", f.Name(), err)
+		}
+
+		if tag == DefaultLanguage {
+			c.messages[0] = messages
+			continue
+		}
+		c.tags = append(c.tags, tag)
+		c.messages = append(c.messages, messages)
+	}
+
+	if c.messages[0] == nil {
+		return nil, fmt.Errorf("no translations for the default language %s", DefaultLanguage)
+	}
+
+	c.matcher = language.NewMatcher(c.tags)
+
+	return c, nil
+}
+
+// Localizer translates messages into a single language.
+type Localizer struct {
+	catalog *Catalog
+	// index is the index of the language in the catalog.
+	index int
+}
+
+// Localizer returns the localizer for the best supported language
+// of the given Accept-Language header value.
+func (c *Catalog) Localizer(acceptLanguage string) Localizer {
+	// malformed header values are treated as absent
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		index = 0
+	}
+	return Localizer{catalog: c, index: index}
+}
+
+// Language returns the language of the localizer.
+func (l Localizer) Language() language.Tag {
+	return l.catalog.tags[l.index]
+}
+
+// T returns the message translated into the language of the localizer
+// and formatted with the given arguments. Messages missing in the
+// language fall back to the default one, unknown keys are returned as is.
+func (l Localizer) T(key Key, args ...any) string {
+	msg, ok := l.catalog.messages[l.index][key]
+	if !ok {
+		msg, ok = l.catalog.messages[0][key]
+	}
+	if !ok {
+		return string(key)
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// ErrorKey returns the key of the message describing the error.
+func ErrorKey(err error) Key {
+	switch {
+	case errors.Is(err, errs.ErrInvalidRequest):
+		return ErrInvalidRequest
+	case errors.Is(err, errs.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, errs.ErrConflict):
+		return ErrConflict
+	case errors.Is(err, errs.ErrUnauthorized):
+		return ErrUnauthorized
+	default:
+		return ErrInternal
+	}
+}
@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog_Localizer(t *testing.T) {
+	c, err := New()
+	require.NoError(t, err)
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"ru", "ru"},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8", "ru"},
+		{"de-DE,en;q=0.5", "en"},
+		{"fr", "en"},
+		{"en;q=0.1,ru;q=0.9", "ru"},
+		{"!!malformed", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.Localizer(tt.acceptLanguage).Language().String())
+		})
+	}
+}
+
+func TestLocalizer_T(t *testing.T) {
+	c, err := New()
+	require.NoError(t, err)
+
+	en, ru := c.Localizer("en"), c.Localizer("ru")
+
+	assert.Equal(t, "Link not found", en.T("page.not_found.title"))
+	assert.Equal(t, "Ссылка не найдена", ru.T("page.not_found.title"))
+	assert.Contains(t, ru.T("page.gone.text", "abc"), "abc")
+	assert.Equal(t, "unknown.key", en.T("unknown.key"))
+}
+
+func TestErrorKey(t *testing.T) {
+	assert.Equal(t, ErrNotFound, ErrorKey(fmt.Errorf("wrapped: %w", errs.ErrNotFound)))
+	assert.Equal(t, ErrConflict, ErrorKey(errs.ErrConflict))
+	assert.Equal(t, ErrInternal, ErrorKey(errs.ErrDBNotConnected))
+}
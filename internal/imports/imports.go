@@ -0,0 +1,281 @@
+// Package imports manages bulk imports of URLs from uploaded files.
+//
+// An import is created empty, the file is uploaded to it in chunks,
+// and once the upload is completed the import is queued and processed
+// in the background. Uploaded files are kept in the configured directory
+// until processed. The imports state is kept in memory and is lost
+// on restart.
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// ErrTooLarge is returned when the uploaded file exceeds the size limit.
+var ErrTooLarge = errors.New("import file is too large")
+
+// Status is the state of the import.
+type Status string
+
+const (
+	// StatusUploading means the file is being uploaded.
+	StatusUploading Status = "uploading"
+	// StatusQueued means the upload is completed and
+	// the import waits to be processed.
+	StatusQueued Status = "queued"
+	// StatusProcessing means the URLs are being imported.
+	StatusProcessing Status = "processing"
+	// StatusDone means all the URLs of the file are processed.
+	StatusDone Status = "done"
+	// StatusFailed means the import was aborted, see the error.
+	StatusFailed Status = "failed"
+)
+
+// queueLen is the number of completed imports waiting to be processed.
+const queueLen = 100
+
+// Import is a single bulk import.
+type Import struct {
+	ID     string  `json:"id"`
+	UserID user.ID `json:"-"`
+	Status Status  `json:"status"`
+	// Size is the number of bytes uploaded.
+	Size int64 `json:"size"`
+	// Imported and Failed are the numbers of processed lines.
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+	// Error is the reason of the import failure.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Processor imports the URLs read from the file of the import.
+// It reports the number of imported and failed lines with progress.
+type Processor func(ctx context.Context, imp Import, file io.Reader,
+	progress func(imported, failed int)) error
+
+// Manager keeps track of the imports and processes them one by one.
+// It is safe for concurrent use.
+type Manager struct {
+	// dir is the directory of the uploaded files.
+	dir string
+	// maxSize is the maximum size of the uploaded file.
+	maxSize int64
+	// mu protects imports.
+	mu      sync.Mutex
+	imports map[string]*Import
+	// queue is the IDs of the completed imports.
+	queue chan string
+}
+
+// NewManager returns the imports manager storing files in dir.
+func NewManager(dir string, maxSize int64) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create imports directory: %w", err)
+	}
+	return &Manager{
+		dir:     dir,
+		maxSize: maxSize,
+		imports: make(map[string]*Import),
+		queue:   make(chan string, queueLen),
+	}, nil
+}
+
+// Create starts a new import of the user.
+func (m *Manager) Create(userID user.ID) (Import, error) {
+	now := time.Now().UTC()
+	imp := &Import{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Status:    StatusUploading,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	f, err := os.OpenFile(m.path(imp.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Import{}, fmt.Errorf("create import file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return Import{}, fmt.Errorf("close import file: %w", err)
+	}
+
+	m.mu.Lock()
+	m.imports[imp.ID] = imp
+	m.mu.Unlock()
+
+	return *imp, nil
+}
+
+// Get returns the import of the user.
+// If there is no such import, ErrNotFound is returned.
+func (m *Manager) Get(id string, userID user.ID) (Import, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp, err := m.get(id, userID)
+	if err != nil {
+		return Import{}, err
+	}
+	return *imp, nil
+}
+
+// Append appends the chunk to the file of the import at the given offset
+// and returns the new size of the file. The offset must be equal to the
+// current size, otherwise ErrConflict is returned, so that lost or repeated
+// chunks are detected. Chunks of the same import must not be sent
+// concurrently.
+func (m *Manager) Append(id string, userID user.ID, offset int64, chunk io.Reader) (int64, error) {
+	m.mu.Lock()
+	imp, err := m.get(id, userID)
+	if err == nil && imp.Status != StatusUploading {
+		err = fmt.Errorf("import is %s: %w", imp.Status, errs.ErrConflict)
+	}
+	if err == nil && imp.Size != offset {
+		err = fmt.Errorf("offset %d, expected %d: %w", offset, imp.Size, errs.ErrConflict)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(m.path(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("open import file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// drop a partially written chunk, so that it can be retried
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek import file: %w", err)
+	}
+
+	// read one byte more than allowed to detect the excess
+	n, err := io.Copy(f, io.LimitReader(chunk, m.maxSize-offset+1))
+	if err == nil && offset+n > m.maxSize {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		_ = f.Truncate(offset)
+		return 0, fmt.Errorf("write import file: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp.Size = offset + n
+	imp.UpdatedAt = time.Now().UTC()
+
+	return imp.Size, nil
+}
+
+// Complete finishes the upload and queues the import for processing.
+// If the queue is full, the import can be completed later.
+func (m *Manager) Complete(id string, userID user.ID) (Import, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp, err := m.get(id, userID)
+	if err != nil {
+		return Import{}, err
+	}
+	if imp.Status != StatusUploading {
+		return Import{}, fmt.Errorf("import is %s: %w", imp.Status, errs.ErrConflict)
+	}
+
+	select {
+	case m.queue <- id:
+	default:
+		return Import{}, errors.New("too many imports are queued, try again later")
+	}
+
+	imp.Status = StatusQueued
+	imp.UpdatedAt = time.Now().UTC()
+
+	return *imp, nil
+}
+
+// Run processes the queued imports with process until done is closed.
+// The import in progress is canceled on stop and marked as failed.
+func (m *Manager) Run(done <-chan struct{}, process Processor) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case id := <-m.queue:
+			m.process(ctx, id, process)
+		}
+	}
+}
+
+// process runs the single import and removes its file.
+func (m *Manager) process(ctx context.Context, id string, process Processor) {
+	defer func() { _ = os.Remove(m.path(id)) }()
+
+	imp := m.update(id, func(imp *Import) { imp.Status = StatusProcessing })
+
+	f, err := os.Open(m.path(id))
+	if err == nil {
+		err = process(ctx, imp, f, func(imported, failed int) {
+			m.update(id, func(imp *Import) {
+				imp.Imported += imported
+				imp.Failed += failed
+			})
+		})
+		_ = f.Close()
+	}
+
+	m.update(id, func(imp *Import) {
+		imp.Status = StatusDone
+		if err != nil {
+			imp.Status = StatusFailed
+			imp.Error = err.Error()
+		}
+	})
+}
+
+// update applies the change to the import and returns its copy.
+func (m *Manager) update(id string, change func(imp *Import)) Import {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp := m.imports[id]
+	change(imp)
+	imp.UpdatedAt = time.Now().UTC()
+
+	return *imp
+}
+
+// get returns the import of the user. It must be called with mu held.
+func (m *Manager) get(id string, userID user.ID) (*Import, error) {
+	imp, ok := m.imports[id]
+	// don't disclose imports of the other users
+	if !ok || imp.UserID != userID {
+		return nil, fmt.Errorf("import %s: %w", id, errs.ErrNotFound)
+	}
+	return imp, nil
+}
+
+// path returns the path of the import file.
+func (m *Manager) path(id string) string {
+	return filepath.Join(m.dir, id+".txt")
+}
@@ -0,0 +1,44 @@
+// Package ipallow matches the client addresses against the allowlists
+// of the subnets in CIDR notation, e.g. 10.0.0.0/8 or fd00::/8.
+// It is shared by the transports restricting the clients by address.
+package ipallow
+
+import (
+	"fmt"
+	"net"
+)
+
+// List is the allowlist of the subnets. The empty list allows everyone.
+type List []*net.IPNet
+
+// Parse returns the allowlist of the given subnets in CIDR notation.
+func Parse(cidrs []string) (List, error) {
+	list := make(List, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet: %w", err)
+		}
+		list = append(list, subnet)
+	}
+	return list, nil
+}
+
+// Allows reports whether the address, e.g. 10.0.0.1, belongs to any of
+// the subnets. The empty list allows every address, the invalid address
+// is allowed by the empty list only.
+func (l List) Allows(addr string) bool {
+	if len(l) == 0 {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, subnet := range l {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
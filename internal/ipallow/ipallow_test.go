@@ -0,0 +1,37 @@
+package ipallow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList_Allows(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		addr  string
+		want  bool
+	}{
+		{"empty list", nil, "203.0.113.1", true},
+		{"empty list, invalid address", nil, "", true},
+		{"inside", []string{"10.0.0.0/8"}, "10.1.2.3", true},
+		{"outside", []string{"10.0.0.0/8"}, "203.0.113.1", false},
+		{"any of the subnets", []string{"10.0.0.0/8", "192.168.0.0/16"}, "192.168.1.1", true},
+		{"ipv6", []string{"fd00::/8"}, "fd12::1", true},
+		{"invalid address", []string{"10.0.0.0/8"}, "localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Parse(tt.cidrs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, list.Allows(tt.addr))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]string{"10.0.0.0/8", "10.0.0.1"})
+	require.Error(t, err)
+}
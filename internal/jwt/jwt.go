@@ -6,16 +6,40 @@ import (
 	"time"
 
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
-// BuildJWTString creates a JWT string for the given user ID and token expiration time.
-func BuildJWTString(userID, secret string, tokenExp time.Duration) (string, error) {
+// BuildJWTString creates a JWT string of the given type for the given user ID
+// and token expiration time. The token is limited to the scopes if any.
+// Every token gets the unique ID it can be revoked by.
+func BuildJWTString(
+	userID user.ID, tokenType user.TokenType, secret string, tokenExp time.Duration, scopes ...user.Scope,
+) (string, error) {
+	return BuildUserJWTString(&user.User{ID: userID, Token: tokenType, Scopes: scopes}, secret, tokenExp)
+}
+
+// BuildUserJWTString is like BuildJWTString but the token keeps
+// the type, the scopes and the role of the given user. The user without
+// the role gets the default one of the token type.
+func BuildUserJWTString(u *user.User, secret string, tokenExp time.Duration) (string, error) {
+	role := u.Role
+	if role == "" {
+		role = user.DefaultRole(u.Token)
+	}
+
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, models.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp)),
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenExp)),
 		},
-		UserID: userID,
+		UserID:    u.ID,
+		TokenType: u.Token,
+		Scope:     user.FormatScopes(u.Scopes),
+		Role:      role,
 	})
 
 	tokenString, err := token.SignedString([]byte(secret))
@@ -26,8 +50,16 @@ func BuildJWTString(userID, secret string, tokenExp time.Duration) (string, erro
 	return fmt.Sprintf("Bearer %s", tokenString), nil
 }
 
-// GetUserID extracts the user ID from a JWT token.
-func GetUserID(tokenString, secret string) (string, error) {
+// GetUser extracts the user ID and the token type from a JWT token.
+// If the token has no user ID claim, the standard subject claim is used,
+// so that the tokens minted with the signing key by the trusted issuers,
+// e.g. the gateway in front of the service, are supported. Only the HMAC
+// tokens are verified, the ones signed by the external identity providers
+// with their own keys, e.g. OIDC RS256 tokens, are rejected. The tokens
+// with the subject are of the registered type, the untyped ones minted
+// by the service are of the anonymous type. The tokens without the role
+// are of the default one of their type.
+func GetUser(tokenString, secret string) (*user.User, error) {
 	claims := new(models.Claims)
 
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
@@ -44,14 +76,47 @@ func GetUserID(tokenString, secret string) (string, error) {
 
 	// Check for errors
 	if err != nil {
-		return "", fmt.Errorf("error parsing token: %w", err)
+		return nil, fmt.Errorf("error parsing token: %w", err)
 	}
 
 	// Check if the token is valid
 	if !token.Valid {
-		return "", fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	// Fall back to the subject of externally issued tokens.
+	rawID, tokenType := string(claims.UserID), claims.TokenType
+	if rawID == "" {
+		rawID = claims.Subject
+		if tokenType == "" {
+			tokenType = user.TokenRegistered
+		}
+	}
+	if tokenType == "" {
+		tokenType = user.TokenAnonymous
 	}
 
-	// Return the user ID
-	return claims.UserID, nil
+	id, err := user.ParseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := user.ParseScopes(claims.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	role, err := user.ParseRole(string(claims.Role))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if role == "" {
+		role = user.DefaultRole(tokenType)
+	}
+
+	u := &user.User{ID: id, Token: tokenType, Scopes: scopes, Role: role, TokenID: claims.ID}
+	if claims.ExpiresAt != nil {
+		u.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return u, nil
 }
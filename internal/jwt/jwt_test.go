@@ -0,0 +1,44 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-signing-key"
+
+func TestGetUser_Subject(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "auth0|42",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	s, err := token.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	u, err := GetUser(s, testSecret)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID("auth0|42"), u.ID)
+	assert.Equal(t, user.TokenRegistered, u.Token)
+}
+
+func TestGetUser_RejectsExternallySigned(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Subject:   "auth0|42",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	_, err = GetUser(s, testSecret)
+	assert.Error(t, err, "the tokens signed with the keys of the identity providers aren't verified")
+}
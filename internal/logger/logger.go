@@ -59,6 +59,20 @@ type Log struct {
 // Interface implementation check.
 var _ Logger = (*Log)(nil)
 
+// logLevel is the level of the root logger, changed at runtime by SetLevel.
+var logLevel = zap.NewAtomicLevel()
+
+// SetLevel changes the level of the loggers made by New, e.g. once
+// the configuration is reloaded.
+func SetLevel(level string) error {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid level: %w", err)
+	}
+	logLevel.SetLevel(l)
+	return nil
+}
+
 type contextKey int
 
 const (
@@ -67,6 +81,8 @@ const (
 )
 
 // Get creates a new logger using the default configuration.
+// The console logs are colored text in the development environment
+// and JSON in the production one.
 func New(config *config.Config) *Log {
 	sync.OnceFunc(func() {
 		stdout := zapcore.AddSync(os.Stdout)
@@ -86,7 +102,7 @@ func New(config *config.Config) *Log {
 			)
 		}
 
-		logLevel := zap.NewAtomicLevelAt(configLevel)
+		logLevel.SetLevel(configLevel)
 
 		productionCfg := zap.NewProductionEncoderConfig()
 		productionCfg.TimeKey = "timestamp"
@@ -95,7 +111,11 @@ func New(config *config.Config) *Log {
 		developmentCfg := zap.NewDevelopmentEncoderConfig()
 		developmentCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
 
+		// the console is collected as JSON outside the local development
 		consoleEncoder := zapcore.NewConsoleEncoder(developmentCfg)
+		if config.Logger.Format.IsJSON() {
+			consoleEncoder = zapcore.NewJSONEncoder(productionCfg)
+		}
 		fileEncoder := zapcore.NewJSONEncoder(productionCfg)
 
 		var gitRevision string
@@ -214,6 +234,13 @@ func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	return ctx
 }
 
+// RequestIDFromContext returns the request ID recorded with WithRequest,
+// empty if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
 // getCorrelationID extracts the correlation ID from the HTTP request.
 func getCorrelationID(req *http.Request) string {
 	return req.Header.Get("X-Correlation-ID")
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+// apiKeyScheme is the authorization scheme of the API keys.
+const apiKeyScheme = "ApiKey"
+
+// apiKeyCtxKey marks the context of the request authenticated by the API key.
+type apiKeyCtxKey struct{}
+
+// APIKey is a middleware function that authenticates the request by the API key
+// passed in the "Authorization: ApiKey <key>" header, so that programmatic
+// clients don't need the cookie. The user of the key limited to its scopes
+// is added to the request context and the cookie is not checked. Every
+// request is counted for the audit of the key usage. Requests without
+// the API key pass through as is, requests with an unknown one are rejected.
+func APIKey(
+	config *config.Config, keys repository.APIKeyStorage, logger logger.Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			scheme, key, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, apiKeyScheme) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hash := models.HashAPIKey(strings.TrimSpace(key))
+			apiKey, err := keys.GetAPIKey(r.Context(), hash)
+			if err != nil {
+				if errors.Is(err, errs.ErrNotFound) {
+					logger.Debug("unknown API key")
+					jsonError(w, r, config, "invalid API key", errs.ErrUnauthorized, http.StatusUnauthorized)
+					return
+				}
+				logger.Errorf("get API key: %s", err)
+				jsonError(w, r, config, "failed to check API key", err, http.StatusInternalServerError)
+				return
+			}
+
+			// the usage is only audited, so the request is served anyway
+			if err = keys.RecordAPIKeyUse(r.Context(), hash, time.Now().UTC()); err != nil {
+				logger.Errorf("record API key use: %s", err)
+			}
+
+			logger.Debug("API key of the user", zap.Stringer("id", apiKey.UserID))
+			ctx := user.NewContext(r.Context(), &user.User{ID: apiKey.UserID, Scopes: apiKey.Scopes})
+			ctx = context.WithValue(ctx, apiKeyCtxKey{}, true)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// byAPIKey reports whether the request is authenticated by the API key.
+func byAPIKey(ctx context.Context) bool {
+	_, ok := ctx.Value(apiKeyCtxKey{}).(bool)
+	return ok
+}
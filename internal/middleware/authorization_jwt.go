@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/jwt"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models/user"
-	"github.com/google/uuid"
+	"github.com/KretovDmitry/shortener/internal/repository"
 	"go.uber.org/zap"
 )
 
@@ -18,25 +21,34 @@ import (
 func OnlyWithToken(config *config.Config, logger logger.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		f := func(w http.ResponseWriter, r *http.Request) {
-			authCookie, err := r.Cookie("Authorization")
+			// the user is already authenticated by the API key
+			if byAPIKey(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := authToken(r)
 			if err != nil {
 				if err == http.ErrNoCookie {
-					http.Error(w, "Authorization cookie not found", http.StatusUnauthorized)
+					jsonError(w, r, config, "Authorization cookie not found", errs.ErrUnauthorized,
+						http.StatusUnauthorized)
 					logger.Debug("Authorization cookie not found")
 					return
 				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				jsonError(w, r, config, "failed to read token", err, http.StatusInternalServerError)
 				return
 			}
 
-			id, err := jwt.GetUserID(authCookie.Value, config.JWT.SigningKey)
+			u, err := userFromToken(token, config)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				logger.Debug("invalid token", zap.Error(err))
+				jsonError(w, r, config, "invalid token", err, http.StatusUnauthorized)
 				return
 			}
 
-			logger.Debug("JWT token contains user ID", zap.String("id", id))
-			ctx := user.NewContext(r.Context(), &user.User{ID: id})
+			logger.Debug("JWT token contains user ID", zap.Stringer("id", u.ID),
+				zap.String("token_type", string(u.Token)))
+			ctx := user.NewContext(r.Context(), u)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -52,27 +64,36 @@ func OnlyWithToken(config *config.Config, logger logger.Logger) func(next http.H
 func Authorization(config *config.Config, logger logger.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		f := func(w http.ResponseWriter, r *http.Request) {
-			authCookie, err := r.Cookie("Authorization")
+			// the user is already authenticated by the API key
+			if byAPIKey(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := authToken(r)
 			if err != nil {
 				if err == http.ErrNoCookie {
 					logger.Debug("Authorization cookie not found")
-					ctx := user.NewContext(r.Context(), &user.User{ID: uuid.NewString()})
+					ctx := user.NewContext(r.Context(),
+						&user.User{ID: user.NewID(), Token: user.TokenAnonymous})
 
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				jsonError(w, r, config, "failed to read token", err, http.StatusInternalServerError)
 				return
 			}
 
-			id, err := jwt.GetUserID(authCookie.Value, config.JWT.SigningKey)
+			u, err := userFromToken(token, config)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				logger.Debug("invalid token", zap.Error(err))
+				jsonError(w, r, config, "invalid token", err, http.StatusUnauthorized)
 				return
 			}
 
-			logger.Debug("JWT token contains user ID", zap.String("id", id))
-			ctx := user.NewContext(r.Context(), &user.User{ID: id})
+			logger.Debug("JWT token contains user ID", zap.Stringer("id", u.ID),
+				zap.String("token_type", string(u.Token)))
+			ctx := user.NewContext(r.Context(), u)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -80,3 +101,120 @@ func Authorization(config *config.Config, logger logger.Logger) func(next http.H
 		return http.HandlerFunc(f)
 	}
 }
+
+// authToken returns the JWT token of the "Authorization" cookie, falling
+// back to the "Authorization: Bearer <token>" header of the clients without
+// the cookie, e.g. the OAuth ones. It returns http.ErrNoCookie if there is
+// neither.
+func authToken(r *http.Request) (string, error) {
+	cookie, err := r.Cookie("Authorization")
+	if err == nil {
+		return cookie.Value, nil
+	}
+
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if strings.EqualFold(scheme, "Bearer") && token != "" {
+		return token, nil
+	}
+
+	return "", err
+}
+
+// RequireScope is a middleware function that lets the request pass through
+// only if the token of the user gives access to the scope. Tokens without
+// scopes give access to everything. The empty scope admits them only, e.g.
+// to keep the scoped tokens from minting the unscoped credentials.
+func RequireScope(config *config.Config, scope user.Scope, logger logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			if u, ok := user.FromContext(r.Context()); ok && !u.Allows(scope) {
+				logger.Debug("insufficient scope", zap.Stringer("id", u.ID),
+					zap.String("scope", string(scope)))
+				jsonError(w, r, config, "insufficient scope", errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// RequireRole is a middleware function that lets the request pass through
+// only if the user the request is authenticated with has the access of
+// the role, see user.HasRole. It goes after OnlyWithToken.
+func RequireRole(config *config.Config, role user.Role, logger logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			u, ok := user.FromContext(r.Context())
+			if !ok || !u.HasRole(role) {
+				if ok {
+					logger.Debug("insufficient role", zap.Stringer("id", u.ID),
+						zap.String("role", string(role)))
+				}
+				jsonError(w, r, config, "insufficient role", errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// NotRevoked is a middleware function that rejects the requests authenticated
+// with the revoked tokens. It goes after Authorization. The tokens without
+// the ID can't be revoked and pass through, as the requests without a token do.
+func NotRevoked(
+	config *config.Config, revocations repository.RevocationStorage, logger logger.Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			u, ok := user.FromContext(r.Context())
+			if !ok || u.TokenID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			revoked, err := revocations.IsTokenRevoked(r.Context(), u.TokenID)
+			if err != nil {
+				logger.Errorf("check token revocation: %s", err)
+				jsonError(w, r, config, "failed to check token", err, http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				logger.Debug("revoked token", zap.Stringer("id", u.ID),
+					zap.String("token_id", u.TokenID))
+				jsonError(w, r, config, "token is revoked", errs.ErrUnauthorized, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// userFromToken extracts the user from the JWT token and checks its ID
+// against the configured user ID format. Every failure is the fault
+// of the token, e.g. it is expired, its signature is bad or its ID can't
+// be accepted, so that the clients know to refresh it. The refresh tokens
+// are not accepted, they only get the new tokens.
+func userFromToken(token string, config *config.Config) (*user.User, error) {
+	u, err := jwt.GetUser(token, config.JWT.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrUnauthorized, err)
+	}
+	if u.Token == user.TokenRefresh {
+		return nil, fmt.Errorf("%w: refresh token can't authorize requests", errs.ErrUnauthorized)
+	}
+
+	if !config.UserIDFormat.IsOpaque() && !u.ID.IsUUID() {
+		return nil, fmt.Errorf("%w: %w: %s format expected", errs.ErrUnauthorized, user.ErrInvalidID, config.UserIDFormat)
+	}
+
+	return u, nil
+}
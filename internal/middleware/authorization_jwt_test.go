@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/jwt"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorization_InvalidToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+
+	valid, err := jwt.BuildJWTString(user.NewID(), user.TokenRegistered, c.JWT.SigningKey, time.Hour)
+	require.NoError(t, err)
+	expired, err := jwt.BuildJWTString(user.NewID(), user.TokenRegistered, c.JWT.SigningKey, -time.Minute)
+	require.NoError(t, err)
+	forged, err := jwt.BuildJWTString(user.NewID(), user.TokenRegistered, "another-signing-key", time.Hour)
+	require.NoError(t, err)
+	refresh, err := jwt.BuildJWTString(user.NewID(), user.TokenRefresh, c.JWT.SigningKey, time.Hour)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		token      string
+		statusCode int
+	}{
+		{"valid token", valid, http.StatusOK},
+		{"expired token", expired, http.StatusUnauthorized},
+		{"bad signature", forged, http.StatusUnauthorized},
+		{"malformed token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"refresh token", refresh, http.StatusUnauthorized},
+	}
+	middlewares := map[string]func(next http.Handler) http.Handler{
+		"Authorization": Authorization(c, l),
+		"OnlyWithToken": OnlyWithToken(c, l),
+	}
+	for name, mw := range middlewares {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+				r.AddCookie(&http.Cookie{Name: "Authorization", Value: tt.token})
+				w := httptest.NewRecorder()
+
+				mw(next).ServeHTTP(w, r)
+
+				require.Equal(t, tt.statusCode, w.Code, w.Body.String())
+				if tt.statusCode == http.StatusOK {
+					return
+				}
+				var got envelope.Error
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+				assert.Equal(t, "unauthorized", got.Code, "the clients refresh the tokens on 401")
+			})
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+)
+
+// corsMaxAge is the time in seconds the browsers cache the preflight response.
+const corsMaxAge = 600
+
+// CORS is a middleware function that allows the browsers to call the API
+// from the configured origins. The listed origins are echoed back with
+// the credentials allowed, since they are sent in the cookies. The wildcard
+// lets any other origin call the API with "*" and without the credentials,
+// so that no website makes the cookie-authenticated requests of its visitors.
+// The preflight requests from the allowed origins are answered without
+// calling the next handler.
+func CORS(config *config.Config) func(next http.Handler) http.Handler {
+	origins := config.CORS.AllowedOrigins
+	anyOrigin := slices.Contains(origins, "*")
+
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || len(origins) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			switch {
+			case slices.Contains(origins, origin):
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			case anyOrigin:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{
+				http.MethodGet, http.MethodPost, http.MethodPut,
+				http.MethodPatch, http.MethodDelete,
+			}, ", "))
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
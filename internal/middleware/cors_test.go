@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		origins     []string
+		method      string
+		origin      string
+		statusCode  int
+		allowOrigin string
+		credentials bool
+	}{
+		{"not configured", nil, http.MethodGet, "https://app.example", http.StatusOK, "", false},
+		{"same origin", []string{"*"}, http.MethodGet, "", http.StatusOK, "", false},
+		{"any origin", []string{"*"}, http.MethodGet, "https://app.example", http.StatusOK, "*", false},
+		{
+			"listed origin along with any", []string{"*", "https://app.example"}, http.MethodGet,
+			"https://app.example", http.StatusOK, "https://app.example", true,
+		},
+		{
+			"allowed origin", []string{"https://app.example"}, http.MethodPost,
+			"https://app.example", http.StatusOK, "https://app.example", true,
+		},
+		{"unknown origin", []string{"https://app.example"}, http.MethodGet, "https://evil.example", http.StatusOK, "", false},
+		{
+			"preflight", []string{"https://app.example"}, http.MethodOptions,
+			"https://app.example", http.StatusNoContent, "https://app.example", true,
+		},
+		{"any origin preflight", []string{"*"}, http.MethodOptions, "https://evil.example", http.StatusNoContent, "*", false},
+		{
+			"unknown origin preflight", []string{"https://app.example"}, http.MethodOptions,
+			"https://evil.example", http.StatusOK, "", false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewForTest()
+			c.CORS.AllowedOrigins = tt.origins
+
+			r := httptest.NewRequest(tt.method, "/api/shorten", http.NoBody)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+			}
+			w := httptest.NewRecorder()
+
+			CORS(c)(next).ServeHTTP(w, r)
+
+			res := w.Result()
+			require.NoError(t, res.Body.Close(), "failed close body")
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			assert.Equal(t, tt.allowOrigin, res.Header.Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.credentials, res.Header.Get("Access-Control-Allow-Credentials") == "true")
+			if tt.statusCode == http.StatusNoContent {
+				assert.Equal(t, "Content-Type", res.Header.Get("Access-Control-Allow-Headers"))
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"go.uber.org/zap"
 )
@@ -49,16 +51,32 @@ func (c *compressReader) Close() error {
 }
 
 // Unzip decides whether or not to decompress request judging by content encoding.
-func Unzip(logger logger.Logger) func(next http.Handler) http.Handler {
+// The body over the configured size is rejected with 413 Request Entity
+// Too Large before it is inflated: at once if its Content-Length is known,
+// or once the limit is read otherwise. The inflated body is limited as well,
+// so that the handlers get the *http.MaxBytesError reading past the limit.
+func Unzip(config *config.Config, logger logger.Logger) func(next http.Handler) http.Handler {
+	maxSize := config.HTTPServer.MaxBodySize
+	maxInflatedSize := config.HTTPServer.MaxInflatedSize
+
 	return func(next http.Handler) http.Handler {
 		f := func(w http.ResponseWriter, r *http.Request) {
+			if maxSize > 0 {
+				if r.ContentLength > maxSize {
+					jsonError(w, r, config, fmt.Sprintf("request body exceeds %d bytes", maxSize),
+						errs.ErrInvalidRequest, http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			}
+
 			contentEncoding := r.Header.Get("Content-Encoding")
 			sendsGzip := strings.Contains(contentEncoding, "gzip")
 			if sendsGzip {
 				cr, err := newCompressReader(r.Body)
 				if err != nil {
 					logger.Error("new compress reader", zap.Error(err))
-					w.WriteHeader(http.StatusInternalServerError)
+					jsonError(w, r, config, "failed to decompress body", err, http.StatusInternalServerError)
 					return
 				}
 				r.Body = cr
@@ -67,6 +85,9 @@ func Unzip(logger logger.Logger) func(next http.Handler) http.Handler {
 						logger.Errorf("close compress reader: %v", err)
 					}
 				}()
+				if maxInflatedSize > 0 {
+					r.Body = http.MaxBytesReader(w, r.Body, maxInflatedSize)
+				}
 			}
 
 			next.ServeHTTP(w, r)
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/KretovDmitry/shortener/internal/config"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,7 +50,7 @@ func TestUnzip(t *testing.T) {
 
 			l, _ := logger.NewForTest()
 
-			unzipper := Unzip(l)
+			unzipper := Unzip(config.NewForTest(), l)
 			handler = unzipper(handler)
 
 			handler.ServeHTTP(w, r)
@@ -78,3 +79,45 @@ func compress(data []byte) []byte {
 	}
 	return b.Bytes()
 }
+
+func TestUnzip_Limits(t *testing.T) {
+	var readErr error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c := config.NewForTest()
+	c.HTTPServer.MaxBodySize = 64
+	c.HTTPServer.MaxInflatedSize = 1024
+	l, _ := logger.NewForTest()
+	unzipped := Unzip(c, l)(handler)
+
+	// the body is rejected before it is read
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 65)))
+	w := httptest.NewRecorder()
+	unzipped.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// the body of unknown length is cut at the limit
+	readErr = nil
+	r = httptest.NewRequest(http.MethodPost, "/", io.NopCloser(bytes.NewReader(make([]byte, 65))))
+	r.ContentLength = -1
+	unzipped.ServeHTTP(httptest.NewRecorder(), r)
+	var maxBytesErr *http.MaxBytesError
+	require.ErrorAs(t, readErr, &maxBytesErr)
+	assert.EqualValues(t, 64, maxBytesErr.Limit)
+
+	// the small compressed body can't be inflated past the limit
+	bomb := compress(make([]byte, 1<<20))
+	require.Less(t, len(bomb), 1<<20/100, "compressed body expected to be small")
+	c.HTTPServer.MaxBodySize = int64(len(bomb))
+	unzipped = Unzip(c, l)(handler)
+
+	readErr = nil
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+	r.Header.Set("Content-Encoding", "gzip")
+	unzipped.ServeHTTP(httptest.NewRecorder(), r)
+	require.ErrorAs(t, readErr, &maxBytesErr)
+	assert.EqualValues(t, 1024, maxBytesErr.Limit)
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/logger"
+)
+
+// jsonError rejects the request with the JSON error the handlers respond
+// with, in the envelope if it is configured, see envelope.NewError.
+func jsonError(w http.ResponseWriter, r *http.Request, config *config.Config, message string, err error, code int) {
+	e := envelope.NewError(message, err, code, logger.RequestIDFromContext(r.Context()))
+	// the client is gone if the error can't be written
+	_ = envelope.WriteError(w, code, e, config.JSONEnvelope)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+)
+
+// Latency is a middleware function that delays every request by the latency
+// of the sandbox, so that the clients developed against the sandbox see
+// their loading states. The request canceled while it waits is dropped.
+// It does nothing outside the sandbox or if the latency is zero.
+func Latency(config *config.Config, _ logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			latency := config.Sandbox.Latency
+			if !config.Sandbox.Enabled || latency <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			timer := time.NewTimer(latency)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				next.ServeHTTP(w, r)
+			case <-r.Context().Done():
+			}
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
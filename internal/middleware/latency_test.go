@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatency(t *testing.T) {
+	var served bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		served = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c := config.NewForTest()
+	c.Sandbox.Latency = 50 * time.Millisecond
+	l, _ := logger.NewForTest()
+	h := Latency(c, l)(next)
+
+	// serve returns how long the request took
+	serve := func(ctx context.Context) time.Duration {
+		served = false
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody).WithContext(ctx)
+		start := time.Now()
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return time.Since(start)
+	}
+
+	assert.Less(t, serve(context.Background()), c.Sandbox.Latency, "not delayed outside the sandbox")
+	assert.True(t, served)
+
+	c.Sandbox.Enabled = true
+	assert.GreaterOrEqual(t, serve(context.Background()), c.Sandbox.Latency)
+	assert.True(t, served)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	serve(ctx)
+	assert.False(t, served, "canceled request is dropped")
+}
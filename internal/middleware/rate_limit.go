@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/ipallow"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// Headers of the rate limit state.
+const (
+	headerRateLimit          = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRetryAfter         = "Retry-After"
+)
+
+// RateLimit is a middleware function that limits the requests of every user
+// and every client IP, responding with 429 Too Many Requests when any of the
+// limits is exceeded. Users without the token get a new ID on every request,
+// so the client IP limit is the one stopping them. The client IP is the
+// remote address, or the "X-Real-IP" header set by the trusted proxies.
+//
+// The limit and the remaining number of the requests of the most restrictive
+// bucket are returned in the X-RateLimit-Limit and X-RateLimit-Remaining
+// headers. The requests are let through if the limiter fails. The limits
+// reloaded at runtime apply to the next requests.
+func RateLimit(config *config.Config, logger logger.Logger, limiter ratelimit.Limiter) func(next http.Handler) http.Handler {
+	proxies := trustedProxies(config, logger)
+
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			cfg := config.Live().RateLimit
+			if cfg.UserLimit <= 0 && cfg.IPLimit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			type bucket struct {
+				key   string
+				limit int
+			}
+			var buckets []bucket
+			if cfg.IPLimit > 0 {
+				buckets = append(buckets, bucket{"ip:" + clientIP(r, proxies), cfg.IPLimit})
+			}
+			if u, ok := user.FromContext(r.Context()); ok && cfg.UserLimit > 0 {
+				buckets = append(buckets, bucket{"user:" + u.ID.String(), cfg.UserLimit})
+			}
+
+			limit, remaining := 0, math.MaxInt
+			for _, b := range buckets {
+				res, err := limiter.Allow(r.Context(), b.key, b.limit, cfg.Period)
+				if err != nil {
+					logger.Errorf("rate limit %s: %s", b.key, err)
+					continue
+				}
+
+				if res.Remaining < remaining {
+					limit, remaining = b.limit, res.Remaining
+				}
+				if !res.Allowed {
+					logger.Debug("rate limit exceeded", zap.String("key", b.key))
+					retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+					w.Header().Set(headerRateLimit, strconv.Itoa(b.limit))
+					w.Header().Set(headerRateLimitRemaining, "0")
+					w.Header().Set(headerRetryAfter, strconv.Itoa(retryAfter))
+					jsonError(w, r, config, "too many requests", errs.ErrInvalidRequest, http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			if limit > 0 {
+				w.Header().Set(headerRateLimit, strconv.Itoa(limit))
+				w.Header().Set(headerRateLimitRemaining, strconv.Itoa(remaining))
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// clientIP returns the IP of the client that sent the request: the remote
+// address, or the "X-Real-IP" header if the request comes from any of
+// the trusted proxies.
+func clientIP(r *http.Request, proxies ipallow.List) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	// the empty list allows everyone, but no proxy is trusted then
+	if len(proxies) == 0 || !proxies.Allows(host) {
+		return host
+	}
+	if ip := r.Header.Get("X-Real-IP"); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return host
+}
+
+// trustedProxies returns the subnets of the trusted proxies.
+// No proxy is trusted if they are invalid.
+func trustedProxies(config *config.Config, logger logger.Logger) ipallow.List {
+	proxies, err := ipallow.Parse(config.TrustedProxies)
+	if err != nil {
+		logger.Errorf("invalid trusted proxies, X-Real-IP ignored: %s", err)
+		return nil
+	}
+	return proxies
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenLimiter fails every request.
+type brokenLimiter struct{}
+
+func (brokenLimiter) Allow(context.Context, string, int, time.Duration) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	type request struct {
+		ip         string
+		user       user.ID
+		statusCode int
+		remaining  string
+	}
+	tests := []struct {
+		name      string
+		userLimit int
+		ipLimit   int
+		limiter   ratelimit.Limiter
+		// proxies are the trusted proxies the requests come through.
+		proxies  []string
+		requests []request
+	}{
+		{
+			name:      "limited by user",
+			userLimit: 2,
+			ipLimit:   10,
+			requests: []request{
+				{"192.0.2.1", "a", http.StatusOK, "1"},
+				{"192.0.2.2", "a", http.StatusOK, "0"},
+				{"192.0.2.3", "a", http.StatusTooManyRequests, "0"},
+				{"192.0.2.1", "b", http.StatusOK, "1"},
+			},
+		},
+		{
+			name:      "limited by IP",
+			userLimit: 10,
+			ipLimit:   2,
+			requests: []request{
+				{"192.0.2.1", "a", http.StatusOK, "1"},
+				{"192.0.2.1", "b", http.StatusOK, "0"},
+				{"192.0.2.1", "c", http.StatusTooManyRequests, "0"},
+				{"192.0.2.2", "c", http.StatusOK, "1"},
+			},
+		},
+		{
+			name:      "limited by IP behind the trusted proxy",
+			userLimit: 10,
+			ipLimit:   2,
+			proxies:   []string{"10.0.0.0/8"},
+			requests: []request{
+				{"192.0.2.1", "a", http.StatusOK, "1"},
+				{"192.0.2.1", "b", http.StatusOK, "0"},
+				{"192.0.2.1", "c", http.StatusTooManyRequests, "0"},
+				{"192.0.2.2", "c", http.StatusOK, "1"},
+			},
+		},
+		{
+			name: "not limited",
+			requests: []request{
+				{"192.0.2.1", "a", http.StatusOK, ""},
+				{"192.0.2.1", "a", http.StatusOK, ""},
+			},
+		},
+		{
+			name:      "limiter failure",
+			userLimit: 1,
+			ipLimit:   1,
+			limiter:   brokenLimiter{},
+			requests: []request{
+				{"192.0.2.1", "a", http.StatusOK, ""},
+				{"192.0.2.1", "a", http.StatusOK, ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewForTest()
+			c.RateLimit.UserLimit = tt.userLimit
+			c.RateLimit.IPLimit = tt.ipLimit
+			c.RateLimit.Period = time.Hour
+			c.TrustedProxies = tt.proxies
+			l, _ := logger.NewForTest()
+			limiter := tt.limiter
+			if limiter == nil {
+				limiter = ratelimit.NewMemory()
+			}
+			h := RateLimit(c, l, limiter)(next)
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+				if tt.proxies != nil {
+					r.RemoteAddr = "10.0.0.1:1234"
+					r.Header.Set("X-Real-IP", req.ip)
+				} else {
+					// the clients can't dodge the limit spoofing the header
+					r.RemoteAddr = req.ip + ":1234"
+					r.Header.Set("X-Real-IP", fmt.Sprintf("198.51.100.%d", i))
+				}
+				r = r.WithContext(user.NewContext(r.Context(), &user.User{ID: req.user}))
+				w := httptest.NewRecorder()
+
+				h.ServeHTTP(w, r)
+
+				res := w.Result()
+				require.NoError(t, res.Body.Close(), "failed close body")
+				assert.Equal(t, req.statusCode, res.StatusCode, "request %d", i)
+				assert.Equal(t, req.remaining, res.Header.Get("X-RateLimit-Remaining"), "request %d", i)
+				if req.statusCode == http.StatusTooManyRequests {
+					assert.Equal(t, "1800", res.Header.Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/logger"
+)
+
+// WriteVersionCookie and WriteVersionHeader carry the time of the last
+// write of the client in Unix milliseconds. The header is for the clients
+// not keeping the cookies, they pass it back with the following requests.
+const (
+	WriteVersionCookie = "Write-Version"
+	WriteVersionHeader = "X-Write-Version"
+)
+
+// ReadYourWrites is a middleware function that lets the clients read their
+// own writes. Every write request gets the write version, which the client
+// passes with the following requests in the cookie or in the header. The
+// requests within the configured window after the write are marked to read
+// from the primary storage, bypassing the cache and the replicas. It does
+// nothing if the window is zero.
+func ReadYourWrites(config *config.Config, _ logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			window := config.Consistency.ReadYourWritesWindow
+			if window <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			if isWrite(r.Method) {
+				// set before the handler writes the headers; the failed
+				// write only sends the reads to the primary for a while
+				version := strconv.FormatInt(now.UnixMilli(), 10)
+				w.Header().Set(WriteVersionHeader, version)
+				http.SetCookie(w, &http.Cookie{
+					Name:     WriteVersionCookie,
+					Value:    version,
+					Path:     "/",
+					MaxAge:   int(window.Seconds()) + 1,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+				r = r.WithContext(consistency.WithPrimary(r.Context()))
+			} else if written, ok := writeVersion(r); ok && now.Sub(written) < window {
+				r = r.WithContext(consistency.WithPrimary(r.Context()))
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// writeVersion returns the time of the last write passed by the client.
+func writeVersion(r *http.Request) (time.Time, bool) {
+	value := r.Header.Get(WriteVersionHeader)
+	if value == "" {
+		cookie, err := r.Cookie(WriteVersionCookie)
+		if err != nil {
+			return time.Time{}, false
+		}
+		value = cookie.Value
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// isWrite reports whether the requests of the method change the links.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
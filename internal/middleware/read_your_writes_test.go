@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	var primary bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary = consistency.Primary(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c := config.NewForTest()
+	l, _ := logger.NewForTest()
+	h := ReadYourWrites(c, l)(next)
+
+	serve := func(r *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	// disabled
+	res := serve(httptest.NewRequest(http.MethodPost, "/api/shorten", http.NoBody))
+	defer res.Body.Close()
+	assert.False(t, primary)
+	assert.Empty(t, res.Header.Get(WriteVersionHeader))
+
+	c.Consistency.ReadYourWritesWindow = time.Minute
+
+	res = serve(httptest.NewRequest(http.MethodPost, "/api/shorten", http.NoBody))
+	defer res.Body.Close()
+	assert.True(t, primary, "writes read from the primary")
+	version := res.Header.Get(WriteVersionHeader)
+	require.NotEmpty(t, version)
+	require.Len(t, res.Cookies(), 1)
+	assert.Equal(t, version, res.Cookies()[0].Value)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody)
+	r.AddCookie(res.Cookies()[0])
+	res = serve(r)
+	defer res.Body.Close()
+	assert.True(t, primary, "read after the write")
+
+	r = httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody)
+	r.Header.Set(WriteVersionHeader, version)
+	res = serve(r)
+	defer res.Body.Close()
+	assert.True(t, primary, "write version in the header")
+
+	r = httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody)
+	r.Header.Set(WriteVersionHeader, strconv.FormatInt(time.Now().Add(-time.Hour).UnixMilli(), 10))
+	res = serve(r)
+	defer res.Body.Close()
+	assert.False(t, primary, "write out of the window")
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/user/urls", http.NoBody))
+	defer res.Body.Close()
+	assert.False(t, primary, "no writes")
+}
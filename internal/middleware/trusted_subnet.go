@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/ipallow"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"go.uber.org/zap"
+)
+
+// OnlyTrustedSubnet is a middleware function that lets the request pass
+// through only if the client IP belongs to the trusted subnet. The client
+// IP is taken from the "X-Real-IP" header only if the request comes from
+// one of the trusted proxies, see clientIP. Access is denied to everyone if the trusted
+// subnet is not configured. The subnet reloaded at runtime applies
+// to the next requests. Everyone is trusted in the sandbox.
+func OnlyTrustedSubnet(config *config.Config, logger logger.Logger) func(next http.Handler) http.Handler {
+	proxies := trustedProxies(config, logger)
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			if reason := untrusted(config, r, proxies, logger); reason != "" {
+				jsonError(w, r, config, reason, errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// TrustedSubnetOrRole is like OnlyTrustedSubnet but also lets the request
+// pass through if it is authenticated with the token of the user having
+// the access of the role, see user.HasRole, so that the clients outside
+// the trusted subnet are admitted by their tokens.
+func TrustedSubnetOrRole(config *config.Config, role user.Role, logger logger.Logger) func(next http.Handler) http.Handler {
+	proxies := trustedProxies(config, logger)
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			// the users created for the requests without a token
+			// are of the user role only
+			if u, ok := user.FromContext(r.Context()); ok && u.HasRole(role) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if reason := untrusted(config, r, proxies, logger); reason != "" {
+				jsonError(w, r, config, reason, errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
+
+// untrusted returns the reason the request is not from the trusted subnet,
+// empty if it is.
+func untrusted(config *config.Config, r *http.Request, proxies ipallow.List, logger logger.Logger) string {
+	if config.Sandbox.Enabled {
+		return ""
+	}
+
+	trusted := config.Live().TrustedSubnet
+	if trusted == "" {
+		return "trusted subnet is not configured"
+	}
+	subnet, err := ipallow.Parse([]string{trusted})
+	if err != nil {
+		logger.Errorf("invalid trusted subnet, access denied: %s", err)
+		return "trusted subnet is not configured"
+	}
+
+	if ip := clientIP(r, proxies); !subnet.Allows(ip) {
+		logger.Debug("request from untrusted address", zap.String("ip", ip))
+		return "untrusted address"
+	}
+
+	return ""
+}
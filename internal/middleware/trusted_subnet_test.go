@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/envelope"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProxies trusts the address of the requests of httptest.NewRequest
+// to set the "X-Real-IP" header.
+var testProxies = []string{"192.0.2.0/24"}
+
+func TestOnlyTrustedSubnet(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		subnet     string
+		proxies    []string
+		realIP     string
+		sandbox    bool
+		statusCode int
+	}{
+		{"trusted address", "192.168.1.0/24", testProxies, "192.168.1.15", false, http.StatusOK},
+		{"untrusted address", "192.168.1.0/24", testProxies, "10.0.0.1", false, http.StatusForbidden},
+		{"missing header", "192.168.1.0/24", testProxies, "", false, http.StatusForbidden},
+		{"subnet is not configured", "", testProxies, "192.168.1.15", false, http.StatusForbidden},
+		{"spoofed header", "192.168.1.0/24", nil, "192.168.1.15", false, http.StatusForbidden},
+		{"trusted peer", "192.0.2.0/24", nil, "", false, http.StatusOK},
+		{"sandbox", "", nil, "", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewForTest()
+			c.TrustedSubnet = tt.subnet
+			c.TrustedProxies = tt.proxies
+			c.Sandbox.Enabled = tt.sandbox
+			l, _ := logger.NewForTest()
+
+			r := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			w := httptest.NewRecorder()
+
+			OnlyTrustedSubnet(c, l)(next).ServeHTTP(w, r)
+
+			res := w.Result()
+			require.NoError(t, res.Body.Close(), "failed close body")
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestOnlyTrustedSubnet_Reload(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	c := config.NewForTest()
+	c.TrustedSubnet = "192.168.1.0/24"
+	c.TrustedProxies = testProxies
+	l, _ := logger.NewForTest()
+	h := OnlyTrustedSubnet(c, l)(next)
+
+	serve := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+		r.Header.Set("X-Real-IP", "10.0.0.1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve())
+
+	live := c.Live()
+	live.TrustedSubnet = "10.0.0.0/8"
+	c.Apply(live)
+	assert.Equal(t, http.StatusOK, serve(), "the reloaded subnet applies to the next requests")
+}
+
+func TestOnlyTrustedSubnet_JSONError(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, enveloped := range []bool{false, true} {
+		c := config.NewForTest()
+		c.TrustedSubnet = "192.168.1.0/24"
+		c.JSONEnvelope = enveloped
+		l, _ := logger.NewForTest()
+
+		r := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+		r.Header.Set("X-Real-IP", "10.0.0.1")
+		w := httptest.NewRecorder()
+
+		OnlyTrustedSubnet(c, l)(next).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var got envelope.Error
+		if enveloped {
+			var res envelope.Response
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			require.NotNil(t, res.Error, "the failure is in the envelope")
+			got = *res.Error
+		} else {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		}
+		assert.Equal(t, "forbidden", got.Code)
+		assert.NotEmpty(t, got.Message)
+	}
+}
+
+func TestTrustedSubnetOrRole(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		proxies    []string
+		realIP     string
+		user       *user.User
+		statusCode int
+	}{
+		{"trusted address", testProxies, "192.168.1.15", nil, http.StatusOK},
+		{"untrusted address", testProxies, "10.0.0.1", nil, http.StatusForbidden},
+		{"role", testProxies, "10.0.0.1", &user.User{ID: "admin", Role: user.RoleAdmin}, http.StatusOK},
+		{"default role", testProxies, "10.0.0.1", &user.User{ID: "service", Token: user.TokenService}, http.StatusOK},
+		{
+			"insufficient role", testProxies, "10.0.0.1",
+			&user.User{ID: "user", Token: user.TokenRegistered}, http.StatusForbidden,
+		},
+		{"spoofed header", nil, "192.168.1.15", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewForTest()
+			c.TrustedSubnet = "192.168.1.0/24"
+			c.TrustedProxies = tt.proxies
+			l, _ := logger.NewForTest()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set("X-Real-IP", tt.realIP)
+			if tt.user != nil {
+				r = r.WithContext(user.NewContext(r.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			TrustedSubnetOrRole(c, user.RoleService, l)(next).ServeHTTP(w, r)
+
+			res := w.Result()
+			require.NoError(t, res.Body.Close(), "failed close body")
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+		})
+	}
+}
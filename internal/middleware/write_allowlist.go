@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/ipallow"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+// OnlyAllowedWriters is a middleware function that lets the requests
+// creating and deleting the links pass through only if the client IP
+// belongs to any of the subnets of the write allowlist, e.g. to create
+// the links from the internal network only while the redirects are public.
+// Everyone is allowed if the allowlist is empty. The client IP is the
+// remote address, or the "X-Real-IP" header set by the trusted proxies.
+func OnlyAllowedWriters(config *config.Config, logger logger.Logger) func(next http.Handler) http.Handler {
+	allowlist, err := ipallow.Parse(config.WriteAllowlist)
+	if err != nil {
+		logger.Errorf("invalid write allowlist, writes denied: %s", err)
+	}
+	proxies := trustedProxies(config, logger)
+
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			if err != nil {
+				jsonError(w, r, config, "write allowlist is invalid", errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			if ip := clientIP(r, proxies); !allowlist.Allows(ip) {
+				logger.Debug("write from not allowed address", zap.String("ip", ip))
+				jsonError(w, r, config, "address is not allowed to write", errs.ErrUnauthorized, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(f)
+	}
+}
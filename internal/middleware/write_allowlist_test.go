@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnlyAllowedWriters(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		allowlist  []string
+		proxies    []string
+		realIP     string
+		remoteAddr string
+		statusCode int
+	}{
+		{"not configured", nil, nil, "203.0.113.1", "", http.StatusOK},
+		{"allowed address", []string{"10.0.0.0/8", "192.168.0.0/16"}, nil, "", "192.168.1.15:1234", http.StatusOK},
+		{"not allowed address", []string{"10.0.0.0/8"}, nil, "", "203.0.113.1:1234", http.StatusForbidden},
+		{"spoofed header", []string{"10.0.0.0/8"}, nil, "10.0.0.1", "203.0.113.1:1234", http.StatusForbidden},
+		{
+			"spoofed header of the untrusted proxy", []string{"10.0.0.0/8"}, []string{"192.0.2.0/24"},
+			"10.0.0.1", "203.0.113.1:1234", http.StatusForbidden,
+		},
+		{
+			"allowed address behind the trusted proxy", []string{"10.0.0.0/8"}, []string{"192.0.2.0/24"},
+			"10.0.0.1", "192.0.2.1:1234", http.StatusOK,
+		},
+		{
+			"not allowed address behind the trusted proxy", []string{"10.0.0.0/8"}, []string{"192.0.2.0/24"},
+			"203.0.113.1", "192.0.2.1:1234", http.StatusForbidden,
+		},
+		{"invalid allowlist", []string{"10.0.0.1"}, nil, "", "10.0.0.1:1234", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewForTest()
+			c.WriteAllowlist = tt.allowlist
+			c.TrustedProxies = tt.proxies
+			l, _ := logger.NewForTest()
+
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", http.NoBody)
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+
+			OnlyAllowedWriters(c, l)(next).ServeHTTP(w, r)
+
+			res := w.Result()
+			require.NoError(t, res.Body.Close(), "failed close body")
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Limits of the credentials of the accounts.
+const (
+	// MaxLoginLength is the maximum length of the login in bytes.
+	MaxLoginLength = 255
+	// MinPasswordLength is the minimum length of the password in bytes.
+	MinPasswordLength = 8
+	// MaxPasswordLength is the maximum length of the password in bytes,
+	// the longer ones are truncated by bcrypt.
+	MaxPasswordLength = 72
+)
+
+// ErrInvalidCredentials is returned if the login or the password is wrong.
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+// Account is the user registered with the login and the password.
+// Only the bcrypt hash of the password is stored.
+type Account struct {
+	UserID       user.ID   `json:"user_id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewAccount returns the account of the user with the login
+// and the hash of the password.
+func NewAccount(userID user.ID, login, password string) (*Account, error) {
+	login = NormalizeLogin(login)
+	if err := ValidateLogin(login); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	return &Account{
+		UserID:       userID,
+		Login:        login,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// NormalizeLogin returns the login as it is stored:
+// trimmed and in lower case.
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// ValidateLogin checks the normalized login.
+func ValidateLogin(login string) error {
+	if login == "" {
+		return errors.New("empty login")
+	}
+	if len(login) > MaxLoginLength {
+		return fmt.Errorf("login is longer than %d characters", MaxLoginLength)
+	}
+	for _, r := range login {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return fmt.Errorf("unexpected character %q in login", r)
+		}
+	}
+	return nil
+}
+
+// ValidatePassword checks the length of the password.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password is shorter than %d characters", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password is longer than %d characters", MaxPasswordLength)
+	}
+	return nil
+}
+
+// CheckPassword returns ErrInvalidCredentials if the password
+// doesn't match the account. The nil account never matches, but
+// takes as long to check, so that the unknown logins can't be told
+// from the wrong passwords by the response time.
+func (a *Account) CheckPassword(password string) error {
+	hash := unknownAccountHash()
+	if a != nil {
+		hash = []byte(a.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || a == nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// unknownAccountHash is the hash checked for the unknown logins.
+var unknownAccountHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("unknown account"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
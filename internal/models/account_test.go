@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		want     string
+		wantErr  bool
+	}{
+		{name: "valid", login: "gopher", password: "correct horse", want: "gopher"},
+		{name: "normalized", login: "  Gopher@Go.dev ", password: "correct horse", want: "gopher@go.dev"},
+		{name: "empty login", login: "  ", password: "correct horse", wantErr: true},
+		{name: "login with spaces", login: "go pher", password: "correct horse", wantErr: true},
+		{name: "long login", login: strings.Repeat("a", MaxLoginLength+1), password: "correct horse", wantErr: true},
+		{name: "short password", login: "gopher", password: "short", wantErr: true},
+		{name: "long password", login: "gopher", password: strings.Repeat("a", MaxPasswordLength+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAccount("user", tt.login, tt.password)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a.Login)
+			assert.NotContains(t, a.PasswordHash, tt.password)
+		})
+	}
+}
+
+func TestAccount_CheckPassword(t *testing.T) {
+	a, err := NewAccount("user", "gopher", "correct horse")
+	require.NoError(t, err)
+
+	assert.NoError(t, a.CheckPassword("correct horse"))
+	assert.ErrorIs(t, a.CheckPassword("wrong horse"), ErrInvalidCredentials)
+
+	var unknown *Account
+	assert.ErrorIs(t, unknown.CheckPassword("unknown account"), ErrInvalidCredentials,
+		"the unknown account never matches")
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, so that leaked keys are easy to spot.
+const APIKeyPrefix = "sk_"
+
+// apiKeySize is the number of random bytes of the API key.
+const apiKeySize = 32
+
+// APIKey authenticates the programmatic clients of the user instead of
+// the JWT cookie. Only the hash of the key is stored, the key itself
+// is shown to the user once when it is minted or rotated.
+type APIKey struct {
+	ID     string  `json:"id"`
+	UserID user.ID `json:"user_id"`
+	Name   string  `json:"name"`
+	// Scopes limit the access of the key, nil if it is not limited.
+	Scopes    []user.Scope `json:"scopes"`
+	Hash      string       `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+	// LastUsedAt is the time of the last request with the key,
+	// nil if the key is not used yet.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Requests is the number of the requests with the key.
+	Requests int64 `json:"requests"`
+}
+
+// NewAPIKey mints a new API key of the user limited to the scopes.
+// It returns the key to show to the user and the record to store.
+func NewAPIKey(userID user.ID, name string, scopes []user.Scope) (string, *APIKey, error) {
+	key, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return key, &APIKey{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      HashAPIKey(key),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Rotate replaces the key keeping the record and its usage.
+// It returns the new key to show to the user.
+func (k *APIKey) Rotate() (string, error) {
+	key, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	k.Hash = HashAPIKey(key)
+	return key, nil
+}
+
+// generateAPIKey returns the random API key.
+func generateAPIKey() (string, error) {
+	b := make([]byte, apiKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate API key: %w", err)
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hash the API key is stored by.
+// The keys are random, so a fast unsalted hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxCampaignLength is the maximum length of the campaign name in bytes.
+const MaxCampaignLength = 64
+
+// ValidateCampaign checks the name of the campaign the owner
+// groups the links with.
+func ValidateCampaign(name string) error {
+	if name == "" {
+		return errors.New("campaign is empty")
+	}
+	if len(name) > MaxCampaignLength {
+		return fmt.Errorf("campaign is longer than %d bytes", MaxCampaignLength)
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("campaign is not valid UTF-8")
+	}
+	return nil
+}
+
+// CampaignTransfer is the outcome of copying or moving the links
+// of one campaign to another. The links are deduplicated by their
+// destinations: the ones already in the target campaign are skipped.
+type CampaignTransfer struct {
+	// Copied is the number of the links added to the target campaign.
+	Copied int `json:"copied"`
+	// Skipped is the number of the links whose destinations
+	// were already in the target campaign.
+	Skipped int `json:"skipped"`
+	// Removed is the number of the links removed from the source
+	// campaign, zero unless they are moved.
+	Removed int `json:"removed"`
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/useragent"
+)
+
+// DateLayout is the layout of the days in the click statistics.
+const DateLayout = "2006-01-02"
+
+// Click is a single redirect of the short URL.
+// The client IP is stored only as a hash, so that the unique visitors
+// can be counted without keeping the addresses.
+type Click struct {
+	ShortURL  ShortURL  `json:"short_url" db:"short_url"`
+	ClickedAt time.Time `json:"clicked_at" db:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty" db:"referrer"`
+	UserAgent string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPHash    string    `json:"ip_hash,omitempty" db:"ip_hash"`
+}
+
+// NewClick returns the click of the short URL at the given time
+// with the IP hashed and the referrer and user agent truncated
+// to MaxUserAgentLength.
+func NewClick(shortURL ShortURL, clickedAt time.Time, ip, referrer, userAgent string) *Click {
+	if len(referrer) > MaxUserAgentLength {
+		referrer = referrer[:MaxUserAgentLength]
+	}
+	if len(userAgent) > MaxUserAgentLength {
+		userAgent = userAgent[:MaxUserAgentLength]
+	}
+	return &Click{
+		ShortURL:  shortURL,
+		ClickedAt: clickedAt,
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IPHash:    HashIP(ip),
+	}
+}
+
+// HashIP returns the hex encoded SHA-256 hash of the IP,
+// or an empty string if the IP is empty.
+func HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClickStats is the click statistics of the short URL.
+type ClickStats struct {
+	ShortURL       ShortURL      `json:"short_url"`
+	TotalClicks    int           `json:"total_clicks"`
+	Daily          []DailyClicks `json:"daily"`
+	LastAccessedAt *time.Time    `json:"last_accessed_at,omitempty"`
+	// Devices, OS and Browsers break the clicks down by the classes
+	// of the user agents, see useragent.Agent. Most clicked go first.
+	Devices  []Share `json:"devices,omitempty"`
+	OS       []Share `json:"os,omitempty"`
+	Browsers []Share `json:"browsers,omitempty"`
+}
+
+// Share is the number of clicks of the device class, the OS or the browser.
+type Share struct {
+	Name   string `json:"name"`
+	Clicks int    `json:"clicks"`
+}
+
+// AddUserAgents fills the breakdowns of the clicks from the number
+// of clicks by the user agent.
+func (s *ClickStats) AddUserAgents(clicks map[string]int) {
+	devices := make(map[string]int)
+	os := make(map[string]int)
+	browsers := make(map[string]int)
+	for ua, n := range clicks {
+		agent := useragent.Parse(ua)
+		devices[agent.Device] += n
+		os[agent.OS] += n
+		browsers[agent.Browser] += n
+	}
+	s.Devices = newShares(devices)
+	s.OS = newShares(os)
+	s.Browsers = newShares(browsers)
+}
+
+// newShares returns the shares sorted by clicks in descending order
+// and then by name, nil if there are none.
+func newShares(clicks map[string]int) []Share {
+	if len(clicks) == 0 {
+		return nil
+	}
+	shares := make([]Share, 0, len(clicks))
+	for name, n := range clicks {
+		shares = append(shares, Share{Name: name, Clicks: n})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Clicks != shares[j].Clicks {
+			return shares[i].Clicks > shares[j].Clicks
+		}
+		return shares[i].Name < shares[j].Name
+	})
+	return shares
+}
+
+// DailyClicks is the number of clicks during the day in UTC.
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+// NewClickStats aggregates the clicks of the short URL.
+// Days are sorted in ascending order, days without clicks are omitted.
+// The clicks are broken down by their user agents.
+func NewClickStats(shortURL ShortURL, clicks []Click) *ClickStats {
+	stats := &ClickStats{ShortURL: shortURL, TotalClicks: len(clicks)}
+
+	daily := make(map[string]int)
+	agents := make(map[string]int)
+	for _, c := range clicks {
+		daily[c.ClickedAt.UTC().Format(DateLayout)]++
+		agents[c.UserAgent]++
+		if stats.LastAccessedAt == nil || c.ClickedAt.After(*stats.LastAccessedAt) {
+			at := c.ClickedAt
+			stats.LastAccessedAt = &at
+		}
+	}
+
+	stats.Daily = make([]DailyClicks, 0, len(daily))
+	for date, n := range daily {
+		stats.Daily = append(stats.Daily, DailyClicks{Date: date, Clicks: n})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+	stats.AddUserAgents(agents)
+
+	return stats
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DomainBan is the destination domain banned by the moderators. The URLs
+// to the domain and its subdomains can't be shortened, the existing ones
+// are blocked.
+type DomainBan struct {
+	// Domain is the normalized domain name, see hostpolicy.ParseDomain.
+	Domain string `json:"domain"`
+	// Reason is the note of the moderator, e.g. the legal demand.
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
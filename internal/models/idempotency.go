@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// MaxIdempotencyKeyLength is the maximum length of the Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotentResponse is the response to the request with the Idempotency-Key
+// header, replayed for the retries of the request. The keys are chosen by
+// the clients, so they are scoped to the user.
+type IdempotentResponse struct {
+	UserID user.ID `json:"user_id"`
+	Key    string  `json:"key"`
+	// RequestHash is the hash of the request the response is given for,
+	// so that the key reused for another request is detected.
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
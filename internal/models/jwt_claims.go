@@ -2,6 +2,7 @@
 package models
 
 import (
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -10,8 +11,17 @@ import (
 //
 // Fields:
 //   - jwt.RegisteredClaims: Standard claims fields defined by the JWT specification.
-//   - UserID string: A unique identifier for the user associated with the token.
+//   - UserID user.ID: A unique identifier for the user associated with the token.
+//   - TokenType user.TokenType: The type of the token, empty in the tokens
+//     minted before the types were introduced and in the external ones.
+//   - Scope string: The space separated scopes of the token, empty if
+//     the token is not limited.
+//   - Role user.Role: The role of the user, empty in the tokens minted
+//     before the roles were introduced and in the external ones.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID string
+	UserID    user.ID
+	TokenType user.TokenType `json:"token_type,omitempty"`
+	Scope     string         `json:"scope,omitempty"`
+	Role      user.Role      `json:"role,omitempty"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Reservation is a short code reserved in advance, e.g. for a print campaign.
+// Reserved codes are never assigned by generation. They can only be bound
+// to a destination by the owner of the reservation via custom alias.
+type Reservation struct {
+	ShortURL  ShortURL  `json:"short_url"`
+	UserID    user.ID   `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
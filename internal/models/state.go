@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LinkState is the state of the short URL in its lifecycle.
+//
+// Active, paused and blocked are set explicitly and kept in the State
+// field of the record, empty if it has never been set. Expired follows
+// from the expiration time and deleted from the IsDeleted flag, which
+// take precedence over the kept state, so that the deletions and the
+// expirations stay as they are.
+type LinkState string
+
+// States of the short URLs.
+const (
+	// LinkActive redirects to the original URL.
+	LinkActive LinkState = "active"
+	// LinkPaused is stopped by the owner for a while and can be resumed.
+	LinkPaused LinkState = "paused"
+	// LinkExpired has passed its expiration time.
+	LinkExpired LinkState = "expired"
+	// LinkDeleted is deleted by the owner, for good.
+	LinkDeleted LinkState = "deleted"
+	// LinkBlocked is blocked by the moderators, e.g. on a legal demand.
+	LinkBlocked LinkState = "blocked"
+)
+
+// ErrInvalidTransition is returned if the short URL can't change
+// from its state to the requested one.
+var ErrInvalidTransition = errors.New("invalid state transition")
+
+// transitions are the states every state changes to.
+var transitions = map[LinkState][]LinkState{
+	LinkActive:  {LinkPaused, LinkBlocked, LinkExpired, LinkDeleted},
+	LinkPaused:  {LinkActive, LinkBlocked, LinkExpired, LinkDeleted},
+	LinkExpired: {LinkBlocked, LinkDeleted},
+	LinkBlocked: {LinkActive, LinkDeleted},
+	LinkDeleted: nil,
+}
+
+// CanTransition reports whether the state changes to the given one.
+func (s LinkState) CanTransition(to LinkState) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStored reports whether the state is kept in the State field,
+// i.e. it is set explicitly rather than follows from other fields.
+func (s LinkState) IsStored() bool {
+	return s == LinkActive || s == LinkPaused || s == LinkBlocked
+}
+
+// ValidateStoredState checks the state set to the State field.
+func ValidateStoredState(s LinkState) error {
+	if !s.IsStored() {
+		return fmt.Errorf("state %q is not one of %q, %q, %q", s, LinkActive, LinkPaused, LinkBlocked)
+	}
+	return nil
+}
+
+// StoredState returns the state kept in the State field of the URL,
+// active if it has never been set.
+func (u *URL) StoredState() LinkState {
+	if u.State == "" {
+		return LinkActive
+	}
+	return u.State
+}
+
+// Lifecycle returns the state of the URL at the given time.
+func (u *URL) Lifecycle(now time.Time) LinkState {
+	switch {
+	case u.IsDeleted:
+		return LinkDeleted
+	case u.State == LinkBlocked:
+		return LinkBlocked
+	case u.IsExpired(now):
+		return LinkExpired
+	case u.State == LinkPaused:
+		return LinkPaused
+	default:
+		return LinkActive
+	}
+}
+
+// Transition changes the state of the URL kept in the State field
+// to the given one if the current state at the time allows it.
+// ErrInvalidTransition is returned otherwise.
+func (u *URL) Transition(to LinkState, now time.Time) error {
+	from := u.Lifecycle(now)
+	if !to.IsStored() || !from.CanTransition(to) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidTransition, from, to)
+	}
+	u.State = to
+	return nil
+}
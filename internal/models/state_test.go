@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURL_Lifecycle(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		url  URL
+		want LinkState
+	}{
+		{name: "never set", want: LinkActive},
+		{name: "active", url: URL{State: LinkActive}, want: LinkActive},
+		{name: "paused", url: URL{State: LinkPaused}, want: LinkPaused},
+		{name: "expired", url: URL{ExpiresAt: &past}, want: LinkExpired},
+		{name: "paused and expired", url: URL{State: LinkPaused, ExpiresAt: &past}, want: LinkExpired},
+		{name: "blocked and expired", url: URL{State: LinkBlocked, ExpiresAt: &past}, want: LinkBlocked},
+		{name: "blocked and deleted", url: URL{State: LinkBlocked, IsDeleted: true}, want: LinkDeleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.url.Lifecycle(now))
+		})
+	}
+}
+
+func TestURL_Transition(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	u := &URL{}
+	require.NoError(t, u.Transition(LinkPaused, now))
+	require.NoError(t, u.Transition(LinkActive, now))
+	require.NoError(t, u.Transition(LinkBlocked, now))
+	require.ErrorIs(t, u.Transition(LinkPaused, now), ErrInvalidTransition)
+	assert.Equal(t, LinkBlocked, u.State, "unchanged on the invalid transition")
+
+	expired := &URL{ExpiresAt: &past}
+	require.ErrorIs(t, expired.Transition(LinkActive, now), ErrInvalidTransition)
+	require.NoError(t, expired.Transition(LinkBlocked, now))
+
+	deleted := &URL{IsDeleted: true}
+	require.ErrorIs(t, deleted.Transition(LinkActive, now), ErrInvalidTransition)
+
+	require.ErrorIs(t, (&URL{}).Transition(LinkDeleted, now), ErrInvalidTransition,
+		"deleted is not kept in the state")
+}
@@ -0,0 +1,16 @@
+package models
+
+// DailyTrend is the number of the URLs created during the day in UTC
+// and of the users who created them.
+type DailyTrend struct {
+	Date        string `json:"date"`
+	CreatedURLs int    `json:"created_urls"`
+	ActiveUsers int    `json:"active_users"`
+}
+
+// TopLink is the short URL with the number of its clicks
+// in the top of the most clicked ones.
+type TopLink struct {
+	ShortURL ShortURL `json:"short_url"`
+	Clicks   int      `json:"clicks"`
+}
@@ -2,12 +2,70 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/google/uuid"
 )
 
 // ShortURL is a string that represents a shortened URL.
 type ShortURL string
 
+// ErrInvalidShortURL is returned when the short URL code is malformed.
+var ErrInvalidShortURL = errors.New("invalid short URL")
+
+// MaxShortURLLength is the maximum length of the stored short URL code.
+const MaxShortURLLength = 255
+
+// ShortURLParser parses the short URL codes accepted from the clients.
+type ShortURLParser struct {
+	// MaxLength is the maximum length of the code.
+	// MaxShortURLLength is used if it is zero or greater.
+	MaxLength int
+	// Alphabets are the accepted alphabets. The code consists
+	// of the characters of any one of them.
+	Alphabets []string
+}
+
+// Parse returns the short URL code if it is not empty, is not longer than
+// the maximum length and consists of the characters of any of the alphabets.
+// ErrInvalidShortURL is returned otherwise.
+func (p ShortURLParser) Parse(s string) (ShortURL, error) {
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > MaxShortURLLength {
+		maxLength = MaxShortURLLength
+	}
+	if s == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidShortURL)
+	}
+	if len(s) > maxLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidShortURL, maxLength)
+	}
+	for _, alphabet := range p.Alphabets {
+		if consistsOf(s, alphabet) {
+			return ShortURL(s), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q has unexpected characters", ErrInvalidShortURL, s)
+}
+
+// consistsOf reports whether s consists of the characters of the alphabet.
+func consistsOf(s, alphabet string) bool {
+	if alphabet == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // OriginalURL is a string that represents the original URL.
 type OriginalURL string
 
@@ -18,16 +76,101 @@ type OriginalURL string
 //   - OriginalURL: the original URL.
 //   - UserID: the ID of the user who created the URL record.
 //   - IsDeleted: a boolean flag that indicates whether the URL record has been deleted.
+//   - LastAccessedAt: the time of the last redirect, nil if never accessed.
+//   - ExpiresAt: the time the URL stops redirecting, nil if it never expires.
+//   - RedirectLimit: the redirects allowed per minute, zero if not limited.
+//   - Note: the free-text note of the owner, at most MaxNoteLength bytes.
+//   - Domain: the host of the short URL, empty for the default one.
+//   - State: the state set explicitly, empty if active, see Lifecycle.
+//   - Version: the number of the edits of the owner, see URLUpdate.
+//   - CreatedAt: the time the record was saved, nil if saved before it was kept.
+//   - Metadata: the details of the client that created the record.
 type URL struct {
-	ID          string      `json:"id"`
-	ShortURL    ShortURL    `json:"short_url"`
-	OriginalURL OriginalURL `json:"original_url"`
-	UserID      string      `json:"user_id"`
-	IsDeleted   bool        `json:"is_deleted" db:"is_deleted"`
+	ID             string      `json:"id"`
+	ShortURL       ShortURL    `json:"short_url"`
+	OriginalURL    OriginalURL `json:"original_url"`
+	UserID         user.ID     `json:"user_id"`
+	IsDeleted      bool        `json:"is_deleted" db:"is_deleted"`
+	LastAccessedAt *time.Time  `json:"last_accessed_at,omitempty" db:"last_accessed_at"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty" db:"expires_at"`
+	RedirectLimit  int         `json:"redirect_limit,omitempty" db:"redirect_limit"`
+	Note           string      `json:"note,omitempty" db:"note"`
+	Domain         string      `json:"domain,omitempty" db:"domain"`
+	State          LinkState   `json:"state,omitempty" db:"state"`
+	Version        int         `json:"version" db:"version"`
+	CreatedAt      *time.Time  `json:"created_at,omitempty" db:"created_at"`
+	Metadata       Metadata    `json:"metadata"`
+}
+
+// CreationTime returns the time the URL was created, now if it is not set,
+// e.g. unless the URL is restored from the archive.
+func (u *URL) CreationTime(now time.Time) time.Time {
+	if u.CreatedAt != nil {
+		return *u.CreatedAt
+	}
+	return now
+}
+
+// IsExpired reports whether the URL has expired by the given time.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(now)
+}
+
+// Origin is the kind of the client interface the URL was created with.
+type Origin string
+
+const (
+	// OriginAPI is the JSON API.
+	OriginAPI Origin = "api"
+	// OriginText is the plain text endpoint used by simple clients and forms.
+	OriginText Origin = "text"
+	// OriginImport is the bulk import of the uploaded file.
+	OriginImport Origin = "import"
+	// OriginFeed is the item of the polled RSS or Atom feed.
+	OriginFeed Origin = "feed"
+)
+
+// SaveStatus is the outcome of saving a single URL of the batch.
+type SaveStatus string
+
+const (
+	// SaveStatusCreated is the URL saved by the batch.
+	SaveStatusCreated SaveStatus = "created"
+	// SaveStatusExists is the URL skipped since it conflicts
+	// with the one already stored.
+	SaveStatusExists SaveStatus = "exists"
+)
+
+// SaveResult is the outcome of saving the URL of the batch.
+type SaveResult struct {
+	ShortURL ShortURL
+	Status   SaveStatus
+}
+
+// MaxUserAgentLength is the maximum length of the stored user agent.
+// Longer values are truncated.
+const MaxUserAgentLength = 512
+
+// Metadata describes the client that created the URL record.
+// It is used to investigate abuse and is visible only
+// to the owner of the URL and the administrators.
+type Metadata struct {
+	CreatorIP string `json:"creator_ip,omitempty" db:"creator_ip"`
+	UserAgent string `json:"user_agent,omitempty" db:"user_agent"`
+	Origin    Origin `json:"origin,omitempty" db:"origin"`
+}
+
+// NewMetadata returns the metadata with the user agent
+// truncated to MaxUserAgentLength.
+func NewMetadata(creatorIP, userAgent string, origin Origin) Metadata {
+	if len(userAgent) > MaxUserAgentLength {
+		userAgent = userAgent[:MaxUserAgentLength]
+	}
+	return Metadata{CreatorIP: creatorIP, UserAgent: userAgent, Origin: origin}
 }
 
 // NewRecord is a function that creates a new URL record.
-func NewRecord(shortURL, originalURL, userID string) *URL {
+func NewRecord(shortURL, originalURL string, userID user.ID) *URL {
 	return &URL{
 		ID:          uuid.NewString(),
 		ShortURL:    ShortURL(shortURL),
@@ -35,3 +178,38 @@ func NewRecord(shortURL, originalURL, userID string) *URL {
 		UserID:      userID,
 	}
 }
+
+// Validate checks the constraints of the URL record
+// which must be enforced by all the storages.
+func (u *URL) Validate() error {
+	if len(u.UserID) > user.MaxIDLength {
+		return fmt.Errorf("%w: longer than %d characters",
+			user.ErrInvalidID, user.MaxIDLength)
+	}
+	if err := ValidateRedirectLimit(u.RedirectLimit); err != nil {
+		return err
+	}
+	return ValidateNote(u.Note)
+}
+
+// ValidateRedirectLimit checks the redirects allowed per minute.
+func ValidateRedirectLimit(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("redirect limit %d is negative", limit)
+	}
+	return nil
+}
+
+// MaxNoteLength is the maximum length of the note in bytes.
+const MaxNoteLength = 1024
+
+// ValidateNote checks the note of the owner.
+func ValidateNote(note string) error {
+	if len(note) > MaxNoteLength {
+		return fmt.Errorf("note is longer than %d bytes", MaxNoteLength)
+	}
+	if !utf8.ValidString(note) {
+		return errors.New("note is not valid UTF-8")
+	}
+	return nil
+}
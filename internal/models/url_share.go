@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// URLShare is the read access to the link granted by its owner to another
+// user. The shared links are listed among the URLs of the user, but only
+// the owner edits, deletes or transfers them. The shares are dropped once
+// the link is transferred to another owner.
+type URLShare struct {
+	ShortURL ShortURL `json:"short_url"`
+	// UserID is the user the link is shared with.
+	UserID    user.ID   `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
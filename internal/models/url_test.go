@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortURLParser_Parse(t *testing.T) {
+	const base58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	parser := ShortURLParser{MaxLength: 16, Alphabets: []string{base58, "0123456789abcdef"}}
+
+	tests := []struct {
+		name    string
+		parser  ShortURLParser
+		code    string
+		wantErr bool
+	}{
+		{name: "base58", parser: parser, code: "YBbxJEcQ9vq"},
+		{name: "second alphabet", parser: parser, code: "00ff"},
+		{name: "max length", parser: parser, code: strings.Repeat("a", 16)},
+		{name: "empty", parser: parser, code: "", wantErr: true},
+		{name: "too long", parser: parser, code: strings.Repeat("a", 17), wantErr: true},
+		{name: "mixed alphabets", parser: parser, code: "0Z", wantErr: true},
+		{name: "unexpected characters", parser: parser, code: "O0Il", wantErr: true},
+		{name: "path", parser: parser, code: "abc/def", wantErr: true},
+		{name: "default max length", parser: ShortURLParser{Alphabets: []string{"a"}},
+			code: strings.Repeat("a", MaxShortURLLength)},
+		{name: "over default max length", parser: ShortURLParser{Alphabets: []string{"a"}},
+			code: strings.Repeat("a", MaxShortURLLength+1), wantErr: true},
+		{name: "no alphabets", code: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Parse(tt.code)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidShortURL)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, ShortURL(tt.code), got)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStaleVersion is returned if the URL was edited since the version
+// the update is based on.
+var ErrStaleVersion = errors.New("stale version")
+
+// URLUpdate is the edit of the URL by its owner. The update is applied
+// only to the version of the URL it is based on, so that the concurrent
+// edits are not lost: the one applied later is rejected with
+// ErrStaleVersion. The applied update increments the version.
+type URLUpdate struct {
+	// OriginalURL is the new destination of the URL.
+	OriginalURL OriginalURL
+	// ExpiresAt is the new expiration of the URL, nil if it never expires.
+	ExpiresAt *time.Time
+	// Version is the version of the URL the update is based on.
+	Version int
+}
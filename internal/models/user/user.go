@@ -1,11 +1,245 @@
 // Package user provides functions to manage user data in the context.
 package user
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxIDLength is the maximum length of the user ID in bytes.
+// It matches the OpenID Connect limit for subject identifiers
+// and is enforced by the repositories and the database schema.
+const MaxIDLength = 255
+
+// ErrInvalidID is returned when the user ID is malformed.
+var ErrInvalidID = errors.New("invalid user ID")
+
+// ID is a unique identifier of the user. It is either a UUID minted by
+// the service or an externally supplied identifier, e.g. the subject
+// of the token minted with the signing key by a gateway.
+type ID string
+
+// NewID returns a new random user ID.
+func NewID() ID {
+	return ID(uuid.NewString())
+}
+
+// ParseID parses and validates the user ID.
+func ParseID(s string) (ID, error) {
+	id := ID(s)
+	if err := id.Validate(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Validate checks that the ID is not empty, does not exceed
+// MaxIDLength and consists of printable ASCII characters only.
+func (id ID) Validate() error {
+	if id == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidID)
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidID, MaxIDLength)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return fmt.Errorf("%w: unexpected character at position %d", ErrInvalidID, i)
+		}
+	}
+	return nil
+}
+
+// IsUUID reports whether the ID is a UUID.
+func (id ID) IsUUID() bool {
+	return uuid.Validate(string(id)) == nil
+}
+
+// String returns the string representation of the ID.
+func (id ID) String() string {
+	return string(id)
+}
+
+// TokenType is the type of the token the user is authenticated with.
+// Every type has its own lifetime.
+type TokenType string
+
+const (
+	// TokenAnonymous is minted for the new users without a token.
+	TokenAnonymous TokenType = "anonymous"
+	// TokenRegistered is issued to the users logged in with their accounts
+	// or minted for them with the signing key by a trusted issuer.
+	TokenRegistered TokenType = "registered"
+	// TokenService is issued by the administrators to the services.
+	TokenService TokenType = "service"
+	// TokenOAuth is issued to the OAuth clients, e.g. the browser extension.
+	TokenOAuth TokenType = "oauth"
+	// TokenRefresh is issued along with the registered tokens to get
+	// the new ones. It can't authenticate the requests itself.
+	TokenRefresh TokenType = "refresh"
+)
+
+// Role is the role of the user the token is issued to. Unlike the scopes
+// limiting the access of the token, the role gives access beyond the URLs
+// of the user. The roles are ordered, each one has the access of the ones
+// before it.
+type Role string
+
+// Roles of the users.
+const (
+	// RoleUser shortens and manages the URLs of the user.
+	RoleUser Role = "user"
+	// RoleService also reads the service statistics and metrics.
+	RoleService Role = "service"
+	// RoleAdmin also moderates the URLs of all the users.
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks orders the roles.
+var roleRanks = map[Role]int{RoleUser: 1, RoleService: 2, RoleAdmin: 3}
+
+// ErrInvalidRole is returned when the role is unknown.
+var ErrInvalidRole = errors.New("invalid role")
+
+// ParseRole parses the role. The empty one is left for DefaultRole.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok && role != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRole, s)
+	}
+	return role, nil
+}
+
+// DefaultRole returns the role of the tokens of the type issued without
+// one, e.g. before the roles were: the service tokens are of the service
+// role, the others are of the user one.
+func DefaultRole(tokenType TokenType) Role {
+	if tokenType == TokenService {
+		return RoleService
+	}
+	return RoleUser
+}
+
+// Scope is the part of the API the token gives access to.
+type Scope string
+
+const (
+	// ScopeShorten allows to shorten URLs.
+	ScopeShorten Scope = "shorten"
+	// ScopeRead allows to list the URLs of the user.
+	ScopeRead Scope = "read"
+	// ScopeDelete allows to delete the URLs of the user.
+	ScopeDelete Scope = "delete"
+	// ScopeStats allows to read the click statistics of the URLs.
+	ScopeStats Scope = "stats"
+	// ScopeWrite allows to shorten URLs, to import them and to subscribe
+	// to the feeds. It includes ScopeShorten.
+	ScopeWrite Scope = "write"
+	// ScopeAdmin allows to mint, list and rotate the API keys of the user
+	// within the scopes of the token.
+	ScopeAdmin Scope = "admin"
+)
+
+// ErrInvalidScope is returned when the scope is unknown.
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ParseScope parses the scope.
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(s)
+	switch scope {
+	case ScopeShorten, ScopeRead, ScopeDelete, ScopeStats, ScopeWrite, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidScope, s)
+	}
+}
+
+// ParseScopes parses the space separated scopes.
+// Repeated scopes are reported once.
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for _, f := range strings.Fields(s) {
+		scope, err := ParseScope(f)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// FormatScopes returns the space separated scopes.
+func FormatScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, " ")
+}
 
 // User struct represents a user.
 type User struct {
-	ID string
+	ID ID
+	// Token is the type of the token the user is authenticated with,
+	// empty if the user is not authenticated with a token.
+	Token TokenType
+	// Scopes limit the access of the token, nil if it is not limited.
+	Scopes []Scope
+	// Role is the role of the user, see DefaultRole if it is empty.
+	Role Role
+	// TokenID identifies the token to revoke it, empty if the token
+	// has no ID, e.g. the tokens issued before the IDs were.
+	TokenID string
+	// ExpiresAt is the expiration of the token, zero if unknown.
+	ExpiresAt time.Time
+}
+
+// HasRole reports whether the user has the access of the role,
+// i.e. the role of the user is the same or comes after it.
+func (u *User) HasRole(role Role) bool {
+	own := u.Role
+	if own == "" {
+		own = DefaultRole(u.Token)
+	}
+	return roleRanks[own] >= roleRanks[role]
+}
+
+// Allows reports whether the user has access to the scope.
+// The empty scope is allowed to the users without limits only.
+func (u *User) Allows(scope Scope) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	if scope == ScopeShorten && slices.Contains(u.Scopes, ScopeWrite) {
+		return true
+	}
+	return scope != "" && slices.Contains(u.Scopes, scope)
+}
+
+// AllowsAll reports whether the user has access to all the scopes,
+// so that the tokens limited to them can be issued on behalf of the user.
+// Nil scopes are allowed to the users without limits only.
+func (u *User) AllowsAll(scopes []Scope) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	if scopes == nil {
+		return false
+	}
+	for _, scope := range scopes {
+		if !u.Allows(scope) {
+			return false
+		}
+	}
+	return true
 }
 
 // key is an unexported type for keys defined in this package.
@@ -1,6 +1,104 @@
 package user
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestUser(t *testing.T) {
+	u := &User{ID: NewID()}
+	ctx := NewContext(context.Background(), u)
+
+	got, ok := FromContext(ctx)
+	require.True(t, ok, "user not found in context")
+	assert.Equal(t, u, got)
+	assert.True(t, got.ID.IsUUID())
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		isUUID  bool
+		wantErr bool
+	}{
+		{"uuid", "2a5c1d63-5b3e-4a6b-8d6e-6c9e5c5a3f11", true, false},
+		{"oidc subject", "auth0|5f7c8ec7c33c6c004bbafe82", false, false},
+		{"max length", strings.Repeat("a", MaxIDLength), false, false},
+		{"empty", "", false, true},
+		{"too long", strings.Repeat("a", MaxIDLength+1), false, true},
+		{"space", "john doe", false, true},
+		{"control character", "john\ndoe", false, true},
+		{"non ascii", "иван", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseID(tt.id)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidID)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.isUUID, id.IsUUID())
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("read  shorten read")
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeRead, ScopeShorten}, scopes)
+	assert.Equal(t, "read shorten", FormatScopes(scopes))
+
+	_, err = ParseScopes("shorten owner")
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	scoped := &User{ID: NewID(), Scopes: scopes}
+	assert.True(t, scoped.Allows(ScopeShorten))
+	assert.False(t, scoped.Allows(ScopeDelete))
+	assert.False(t, scoped.Allows(""), "only unscoped users are allowed the empty scope")
+
+	unscoped := &User{ID: NewID()}
+	assert.True(t, unscoped.Allows(ScopeDelete))
+	assert.True(t, unscoped.Allows(""))
+
+	writer := &User{ID: NewID(), Scopes: []Scope{ScopeWrite, ScopeAdmin}}
+	assert.True(t, writer.Allows(ScopeShorten), "write includes shorten")
+	assert.True(t, writer.AllowsAll([]Scope{ScopeShorten, ScopeAdmin}))
+	assert.False(t, writer.AllowsAll([]Scope{ScopeShorten, ScopeRead}))
+	assert.False(t, writer.AllowsAll(nil), "only unscoped users are allowed nil scopes")
+	assert.True(t, unscoped.AllowsAll(nil))
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("admin")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	role, err = ParseRole("")
+	require.NoError(t, err)
+	assert.Empty(t, role, "the empty role is left for the default one")
+
+	_, err = ParseRole("root")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestUser_HasRole(t *testing.T) {
+	admin := &User{ID: NewID(), Role: RoleAdmin}
+	assert.True(t, admin.HasRole(RoleAdmin))
+	assert.True(t, admin.HasRole(RoleService), "admin includes service")
+	assert.True(t, admin.HasRole(RoleUser))
+
+	service := &User{ID: NewID(), Token: TokenService}
+	assert.Equal(t, RoleService, DefaultRole(service.Token))
+	assert.True(t, service.HasRole(RoleService), "service tokens are of the service role by default")
+	assert.False(t, service.HasRole(RoleAdmin))
+
+	anonymous := &User{ID: NewID(), Token: TokenAnonymous}
+	assert.True(t, anonymous.HasRole(RoleUser))
+	assert.False(t, anonymous.HasRole(RoleService))
 }
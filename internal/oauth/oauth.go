@@ -0,0 +1,252 @@
+// Package oauth implements the minimal OAuth 2.0 authorization server
+// of the authorization code flow with PKCE (RFC 7636), so that public
+// clients such as the browser extension and the CLI obtain the scoped
+// tokens of the user without handling the cookie or the passwords.
+//
+// The user is the one of the cookie sent to the authorization endpoint,
+// the request is approved without the consent screen. Only the S256 code
+// challenge method is supported. The codes are single use, expire in
+// a minute and are kept in memory.
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Error codes of RFC 6749.
+const (
+	ErrInvalidRequest          = "invalid_request"
+	ErrUnauthorizedClient      = "unauthorized_client"
+	ErrInvalidClient           = "invalid_client"
+	ErrInvalidGrant            = "invalid_grant"
+	ErrInvalidScope            = "invalid_scope"
+	ErrUnsupportedResponseType = "unsupported_response_type"
+	ErrUnsupportedGrantType    = "unsupported_grant_type"
+)
+
+const (
+	// codeTTL is the lifetime of the authorization code.
+	codeTTL = time.Minute
+	// codeSize is the number of random bytes of the authorization code.
+	codeSize = 32
+)
+
+// DefaultScopes are granted if the client requests none.
+var DefaultScopes = []user.Scope{user.ScopeShorten}
+
+// verifierRegexp matches the code verifier and the S256 code challenge.
+var verifierRegexp = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// Error is the OAuth error returned to the client.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// newError returns the OAuth error.
+func newError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Description: fmt.Sprintf(format, args...)}
+}
+
+type (
+	// AuthorizeRequest is the request to the authorization endpoint.
+	AuthorizeRequest struct {
+		ResponseType        string
+		ClientID            string
+		RedirectURI         string
+		Scope               string
+		CodeChallenge       string
+		CodeChallengeMethod string
+	}
+
+	// TokenRequest is the request to the token endpoint.
+	TokenRequest struct {
+		GrantType    string
+		Code         string
+		ClientID     string
+		RedirectURI  string
+		CodeVerifier string
+	}
+
+	// Grant is the access granted by the user to the client.
+	Grant struct {
+		UserID user.ID
+		Scopes []user.Scope
+	}
+
+	// code is the issued authorization code.
+	code struct {
+		Grant
+		clientID    string
+		redirectURI string
+		challenge   string
+		expiresAt   time.Time
+	}
+)
+
+// Server issues and exchanges the authorization codes.
+// It is safe for concurrent use.
+type Server struct {
+	// clients maps the client IDs to their redirect URIs.
+	clients map[string]string
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+
+	// mu protects codes.
+	mu    sync.Mutex
+	codes map[string]*code
+}
+
+// NewServer returns the authorization server of the given clients.
+func NewServer(clients map[string]string) *Server {
+	return &Server{
+		clients: clients,
+		now:     time.Now,
+		codes:   make(map[string]*code),
+	}
+}
+
+// CheckClient checks that the redirect URI is registered for the client.
+// The errors of the check must not be sent to the redirect URI.
+func (s *Server) CheckClient(clientID, redirectURI string) error {
+	registered, ok := s.clients[clientID]
+	if !ok {
+		return newError(ErrInvalidClient, "unknown client %q", clientID)
+	}
+	if !matchRedirectURI(registered, redirectURI) {
+		return newError(ErrInvalidRequest, "redirect_uri is not registered")
+	}
+	return nil
+}
+
+// Authorize issues the authorization code of the user to the client.
+// The client must be checked with CheckClient first.
+func (s *Server) Authorize(req AuthorizeRequest, userID user.ID) (string, error) {
+	if req.ResponseType != "code" {
+		return "", newError(ErrUnsupportedResponseType, "only code is supported")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return "", newError(ErrInvalidRequest, "code_challenge_method S256 is required")
+	}
+	if !verifierRegexp.MatchString(req.CodeChallenge) {
+		return "", newError(ErrInvalidRequest, "invalid code_challenge")
+	}
+
+	scopes, err := user.ParseScopes(req.Scope)
+	if err != nil {
+		return "", newError(ErrInvalidScope, "%s", err)
+	}
+	// the clients can't manage the API keys, as the keys outlive the tokens
+	if slices.Contains(scopes, user.ScopeAdmin) {
+		return "", newError(ErrInvalidScope, "%s can't be granted to the clients", user.ScopeAdmin)
+	}
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	b := make([]byte, codeSize)
+	if _, err = rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate code: %w", err)
+	}
+	value := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	s.codes[value] = &code{
+		Grant:       Grant{UserID: userID, Scopes: scopes},
+		clientID:    req.ClientID,
+		redirectURI: req.RedirectURI,
+		challenge:   req.CodeChallenge,
+		expiresAt:   s.now().Add(codeTTL),
+	}
+
+	return value, nil
+}
+
+// Exchange exchanges the authorization code for the grant.
+// The code is spent even if the exchange fails.
+func (s *Server) Exchange(req TokenRequest) (*Grant, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, newError(ErrUnsupportedGrantType, "only authorization_code is supported")
+	}
+
+	s.mu.Lock()
+	c, ok := s.codes[req.Code]
+	delete(s.codes, req.Code)
+	s.mu.Unlock()
+
+	switch {
+	case !ok || !s.now().Before(c.expiresAt):
+		return nil, newError(ErrInvalidGrant, "invalid or expired code")
+	case c.clientID != req.ClientID:
+		return nil, newError(ErrInvalidGrant, "code was issued to another client")
+	case c.redirectURI != req.RedirectURI:
+		return nil, newError(ErrInvalidGrant, "redirect_uri does not match")
+	case !verifierRegexp.MatchString(req.CodeVerifier):
+		return nil, newError(ErrInvalidRequest, "invalid code_verifier")
+	}
+
+	sum := sha256.Sum256([]byte(req.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(c.challenge)) != 1 {
+		return nil, newError(ErrInvalidGrant, "code_verifier does not match")
+	}
+
+	return &c.Grant, nil
+}
+
+// sweep removes the expired codes. It must be called with mu held.
+func (s *Server) sweep() {
+	now := s.now()
+	for value, c := range s.codes {
+		if !now.Before(c.expiresAt) {
+			delete(s.codes, value)
+		}
+	}
+}
+
+// matchRedirectURI reports whether the redirect URI matches the registered
+// one. Loopback URIs match any port, as native clients listen on the port
+// available at the time of the request (RFC 8252, section 7.3).
+func matchRedirectURI(registered, redirectURI string) bool {
+	if registered == redirectURI {
+		return true
+	}
+
+	r, err := url.Parse(registered)
+	if err != nil || r.Scheme != "http" || !isLoopback(r.Hostname()) {
+		return false
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme == r.Scheme && u.Hostname() == r.Hostname() &&
+		u.Path == r.Path && u.RawQuery == r.RawQuery && u.Fragment == ""
+}
+
+// isLoopback reports whether the host is the loopback IP address.
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,128 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestServer(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := NewServer(map[string]string{
+		"cli":       "http://127.0.0.1/callback",
+		"extension": "https://abcdef.chromiumapp.org/",
+	})
+	s.now = func() time.Time { return now }
+
+	authorize := func(clientID, redirectURI, scope string) string {
+		t.Helper()
+		require.NoError(t, s.CheckClient(clientID, redirectURI))
+		code, err := s.Authorize(AuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            clientID,
+			RedirectURI:         redirectURI,
+			Scope:               scope,
+			CodeChallenge:       challenge(verifier),
+			CodeChallengeMethod: "S256",
+		}, "owner")
+		require.NoError(t, err)
+		return code
+	}
+	exchange := func(code, clientID, redirectURI, verifier string) (*Grant, error) {
+		return s.Exchange(TokenRequest{
+			GrantType:    "authorization_code",
+			Code:         code,
+			ClientID:     clientID,
+			RedirectURI:  redirectURI,
+			CodeVerifier: verifier,
+		})
+	}
+	assertError := func(t *testing.T, err error, code string) {
+		t.Helper()
+		var oauthErr *Error
+		require.ErrorAs(t, err, &oauthErr)
+		assert.Equal(t, code, oauthErr.Code)
+	}
+
+	t.Run("loopback redirect with any port", func(t *testing.T) {
+		redirectURI := "http://127.0.0.1:53682/callback"
+		code := authorize("cli", redirectURI, "shorten read")
+
+		grant, err := exchange(code, "cli", redirectURI, verifier)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID("owner"), grant.UserID)
+		assert.Equal(t, []user.Scope{user.ScopeShorten, user.ScopeRead}, grant.Scopes)
+
+		_, err = exchange(code, "cli", redirectURI, verifier)
+		assertError(t, err, ErrInvalidGrant)
+	})
+
+	t.Run("default scopes", func(t *testing.T) {
+		redirectURI := "https://abcdef.chromiumapp.org/"
+		grant, err := exchange(authorize("extension", redirectURI, ""), "extension", redirectURI, verifier)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultScopes, grant.Scopes)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		redirectURI := "https://abcdef.chromiumapp.org/"
+		code := authorize("extension", redirectURI, "")
+		_, err := exchange(code, "extension", redirectURI, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+		assertError(t, err, ErrInvalidGrant)
+	})
+
+	t.Run("another client", func(t *testing.T) {
+		redirectURI := "https://abcdef.chromiumapp.org/"
+		code := authorize("extension", redirectURI, "")
+		_, err := exchange(code, "cli", redirectURI, verifier)
+		assertError(t, err, ErrInvalidGrant)
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		redirectURI := "https://abcdef.chromiumapp.org/"
+		code := authorize("extension", redirectURI, "")
+		now = now.Add(codeTTL)
+		_, err := exchange(code, "extension", redirectURI, verifier)
+		assertError(t, err, ErrInvalidGrant)
+	})
+
+	t.Run("unregistered redirect", func(t *testing.T) {
+		assertError(t, s.CheckClient("cli", "http://127.0.0.1:53682/other"), ErrInvalidRequest)
+		assertError(t, s.CheckClient("extension", "https://abcdef.chromiumapp.org:8443/"), ErrInvalidRequest)
+		assertError(t, s.CheckClient("unknown", "http://127.0.0.1/callback"), ErrInvalidClient)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		req := AuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            "cli",
+			RedirectURI:         "http://127.0.0.1/callback",
+			CodeChallenge:       challenge(verifier),
+			CodeChallengeMethod: "plain",
+		}
+		_, err := s.Authorize(req, "owner")
+		assertError(t, err, ErrInvalidRequest)
+
+		req.CodeChallengeMethod = "S256"
+		req.Scope = "admin"
+		_, err = s.Authorize(req, "owner")
+		assertError(t, err, ErrInvalidScope)
+
+		req.Scope = ""
+		req.ResponseType = "token"
+		_, err = s.Authorize(req, "owner")
+		assertError(t, err, ErrUnsupportedResponseType)
+	})
+}
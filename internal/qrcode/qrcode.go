@@ -0,0 +1,486 @@
+// Package qrcode encodes short URLs as QR codes.
+//
+// Only what the short URLs need is implemented: the byte mode,
+// the medium error correction level and the versions up to 10,
+// which hold up to 213 bytes. The mask is chosen by the penalty
+// rules of ISO/IEC 18004, so the codes are the same as the ones
+// of the other encoders.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// ErrTooLong is returned when the text doesn't fit the largest version.
+var ErrTooLong = errors.New("text is too long for QR code")
+
+// QuietZone is the width of the light border around the code in modules.
+const QuietZone = 4
+
+// version is the size and the error correction layout of the QR code
+// of the medium error correction level.
+type version struct {
+	// ecLen is the number of error correction codewords per block.
+	ecLen int
+	// blocks are the numbers of data codewords of the blocks.
+	blocks []int
+	// align are the centers of the alignment patterns.
+	align []int
+	// remainder is the number of bits left after the codewords.
+	remainder int
+}
+
+// versions are indexed by the version number minus one.
+var versions = []version{
+	{ecLen: 10, blocks: []int{16}},
+	{ecLen: 16, blocks: []int{28}, align: []int{6, 18}, remainder: 7},
+	{ecLen: 26, blocks: []int{44}, align: []int{6, 22}, remainder: 7},
+	{ecLen: 18, blocks: []int{32, 32}, align: []int{6, 26}, remainder: 7},
+	{ecLen: 24, blocks: []int{43, 43}, align: []int{6, 30}, remainder: 7},
+	{ecLen: 16, blocks: []int{27, 27, 27, 27}, align: []int{6, 34}, remainder: 7},
+	{ecLen: 18, blocks: []int{31, 31, 31, 31}, align: []int{6, 22, 38}},
+	{ecLen: 22, blocks: []int{38, 38, 39, 39}, align: []int{6, 24, 42}},
+	{ecLen: 22, blocks: []int{36, 36, 36, 37, 37}, align: []int{6, 26, 46}},
+	{ecLen: 26, blocks: []int{43, 43, 43, 43, 44}, align: []int{6, 28, 50}},
+}
+
+// dataLen returns the number of data codewords of the version.
+func (v version) dataLen() int {
+	n := 0
+	for _, b := range v.blocks {
+		n += b
+	}
+	return n
+}
+
+// Code is the encoded QR code.
+type Code struct {
+	// Version is the version number from 1 to 10.
+	Version int
+	// Size is the number of modules on a side.
+	Size int
+	// Mask is the mask pattern applied to the data from 0 to 7.
+	Mask int
+	// modules are the dark modules by row.
+	modules [][]bool
+	// function marks the modules of the function patterns.
+	function [][]bool
+}
+
+// Dark reports whether the module in the column x and the row y is dark.
+// The modules out of the code are light.
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x]
+}
+
+// Encode encodes the text in the smallest version it fits.
+func Encode(text string) (*Code, error) {
+	for i, v := range versions {
+		// mode and character count indicators
+		headerBits := 4 + 8
+		if i+1 > 9 {
+			headerBits = 4 + 16
+		}
+		if headerBits+8*len(text) > 8*v.dataLen() {
+			continue
+		}
+
+		c := newCode(i + 1)
+		c.drawFunctionPatterns()
+		c.drawCodewords(c.codewords(encodeData(text, headerBits, v.dataLen())))
+		c.applyBestMask()
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(text))
+}
+
+// newCode returns the empty code of the version.
+func newCode(ver int) *Code {
+	size := 17 + 4*ver
+	c := &Code{Version: ver, Size: size}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	return c
+}
+
+// encodeData returns the data codewords of the text in the byte mode
+// padded to the capacity.
+func encodeData(text string, headerBits, capacity int) []byte {
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(uint32(len(text)), headerBits-4)
+	for i := 0; i < len(text); i++ {
+		bb.append(uint32(text[i]), 8)
+	}
+
+	// terminator up to four bits and the padding to the byte
+	bb.append(0, min(4, 8*capacity-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+
+	data := bb.bytes()
+	for pad := byte(0xEC); len(data) < capacity; pad ^= 0xEC ^ 0x11 {
+		data = append(data, pad)
+	}
+	return data
+}
+
+// codewords splits the data into the blocks, appends the error correction
+// codewords to them and interleaves the result.
+func (c *Code) codewords(data []byte) []byte {
+	v := versions[c.Version-1]
+	divisor := rsDivisor(v.ecLen)
+
+	var blocks, ecBlocks [][]byte
+	for _, n := range v.blocks {
+		blocks = append(blocks, data[:n])
+		ecBlocks = append(ecBlocks, rsRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+
+	var result []byte
+	for i := 0; i < v.blocks[len(v.blocks)-1]; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				result = append(result, b[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecLen; i++ {
+		for _, b := range ecBlocks {
+			result = append(result, b[i])
+		}
+	}
+	return result
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns
+// and reserves the format and version information areas.
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	align := versions[c.Version-1].align
+	last := len(align) - 1
+	for i, x := range align {
+		for j, y := range align {
+			// skip the corners of the finders
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// the format bits are drawn with the mask
+	c.drawFormat(0)
+	c.drawVersion()
+}
+
+// drawFinder draws the finder pattern with the separator around the center.
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws the alignment pattern around the center.
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information
+// of the medium error correction level and the mask.
+func (c *Code) drawFormat(mask int) {
+	// the medium level is 00
+	data := uint32(mask)
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information
+// of the versions 7 and above.
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+
+	rem := uint32(c.Version)
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := uint32(c.Version)<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order
+// from the bottom right corner skipping the function patterns.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// the vertical timing pattern
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y][x] || i >= 8*len(codewords) {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		// the mask is its own inverse
+		c.applyMask(mask)
+	}
+
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// applyMask flips the data modules selected by the mask pattern.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.function[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != flip
+		}
+	}
+}
+
+// Penalty weights of the mask evaluation rules.
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// penalty evaluates the code by the rules of the mask selection:
+// runs of the same color, 2x2 blocks, finder-like patterns
+// and the balance of dark and light modules.
+func (c *Code) penalty() int {
+	result := 0
+	for i := 0; i < c.Size; i++ {
+		row := func(j int) bool { return c.modules[i][j] }
+		col := func(j int) bool { return c.modules[j][i] }
+		result += c.linePenalty(row) + c.linePenalty(col)
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				m := c.modules[y][x]
+				if m == c.modules[y][x-1] && m == c.modules[y-1][x] && m == c.modules[y-1][x-1] {
+					result += penaltyBlock
+				}
+			}
+		}
+	}
+
+	// 10 points for every 5% of deviation from the half
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyBalance
+
+	return result
+}
+
+// finderLike is the 1:1:3:1:1 pattern with four light modules on a side.
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty evaluates the runs and the finder-like patterns of the line.
+func (c *Code) linePenalty(at func(int) bool) int {
+	result := 0
+
+	run := 1
+	for j := 1; j <= c.Size; j++ {
+		if j < c.Size && at(j) == at(j-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	for j := 0; j+len(finderLike[0]) <= c.Size; j++ {
+		for _, pattern := range finderLike {
+			matches := true
+			for k, dark := range pattern {
+				if at(j+k) != dark {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				result += penaltyFinder
+			}
+		}
+	}
+
+	return result
+}
+
+// setFunction sets the module of the function pattern.
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// Image returns the code with the quiet zone, scale pixels per module.
+func (c *Code) Image(scale int) image.Image {
+	scale = max(scale, 1)
+	side := (c.Size + 2*QuietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side),
+		color.Palette{color.White, color.Black})
+
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			if c.Dark(x/scale-QuietZone, y/scale-QuietZone) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the code as the PNG image, scale pixels per module.
+func (c *Code) WritePNG(w io.Writer, scale int) error {
+	if err := png.Encode(w, c.Image(scale)); err != nil {
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return nil
+}
+
+// bitBuffer is the sequence of bits, one per byte.
+type bitBuffer []byte
+
+// append appends the n low bits of the value, most significant first.
+func (bb *bitBuffer) append(value uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, byte(value>>i&1))
+	}
+}
+
+// bytes packs the bits into bytes. The length must be a multiple of 8.
+func (bb bitBuffer) bytes() []byte {
+	result := make([]byte, len(bb)/8)
+	for i, b := range bb {
+		result[i/8] |= b << (7 - i%8)
+	}
+	return result
+}
+
+// bit reports whether the i-th bit of the value is set.
+func bit(value uint32, i int) bool {
+	return value>>i&1 == 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
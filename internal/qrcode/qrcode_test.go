@@ -0,0 +1,183 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" of the version 1-M in the alphanumeric mode
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	assert.Equal(t, want, rsRemainder(data, rsDivisor(10)))
+}
+
+func TestEncode_FormatAndVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		version int
+	}{
+		{name: "version 1", text: "https://go.dev", version: 1},
+		{name: "short URL", text: "http://localhost:8080/YBbxJEcQ9vq", version: 3},
+		{name: "version 7", text: strings.Repeat("a", 120), version: 7},
+		{name: "version 10", text: strings.Repeat("a", 211), version: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, c.Version)
+			assert.Equal(t, 17+4*tt.version, c.Size)
+
+			// the first copy of the format bits, most significant first
+			var format uint32
+			for i := 0; i <= 5; i++ {
+				format = format<<1 | b2u(c.Dark(i, 8))
+			}
+			format = format<<1 | b2u(c.Dark(7, 8))
+			format = format<<1 | b2u(c.Dark(8, 8))
+			format = format<<1 | b2u(c.Dark(8, 7))
+			for i := 5; i >= 0; i-- {
+				format = format<<1 | b2u(c.Dark(8, i))
+			}
+			assert.Equal(t, formatBits[c.Mask], format, "format bits of mask %d", c.Mask)
+
+			if tt.version >= 7 {
+				var ver uint32
+				for i := 17; i >= 0; i-- {
+					ver = ver<<1 | b2u(c.Dark(c.Size-11+i%3, i/3))
+				}
+				assert.Equal(t, versionBits[tt.version], ver)
+			}
+
+			assert.Equal(t, tt.text, string(readData(t, c)))
+		})
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	_, err := Encode(strings.Repeat("a", 214))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestWritePNG(t *testing.T) {
+	c, err := Encode("http://localhost:8080/YBbxJEcQ9vq")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.WritePNG(&buf, 4))
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	side := (c.Size + 2*QuietZone) * 4
+	assert.Equal(t, side, img.Bounds().Dx())
+	assert.Equal(t, side, img.Bounds().Dy())
+
+	// the quiet zone is light, the top left finder corner is dark
+	r, _, _, _ := img.At(0, 0).RGBA()
+	assert.NotZero(t, r)
+	r, _, _, _ = img.At(QuietZone*4, QuietZone*4).RGBA()
+	assert.Zero(t, r)
+}
+
+// formatBits are the format information of the medium level by mask
+// from the table of ISO/IEC 18004.
+var formatBits = []uint32{
+	0b101010000010010,
+	0b101000100100101,
+	0b101111001111100,
+	0b101101101001011,
+	0b100010111111001,
+	0b100000011001110,
+	0b100111110010111,
+	0b100101010100000,
+}
+
+// versionBits are the version information from the table of ISO/IEC 18004.
+var versionBits = map[int]uint32{
+	7:  0x07C94,
+	8:  0x085BC,
+	9:  0x09A99,
+	10: 0x0A4D3,
+}
+
+// readData reads the byte mode data back from the code.
+func readData(t *testing.T, c *Code) []byte {
+	t.Helper()
+
+	c.applyMask(c.Mask)
+	defer c.applyMask(c.Mask)
+
+	var bits []bool
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				if !c.function[y][right-j] {
+					bits = append(bits, c.modules[y][right-j])
+				}
+			}
+		}
+	}
+
+	// deinterleave the data codewords
+	v := versions[c.Version-1]
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for _, b := range bits[8*i : 8*i+8] {
+			codewords[i] = codewords[i]<<1 | byte(b2u(b))
+		}
+	}
+	blocks := make([][]byte, len(v.blocks))
+	i := 0
+	for k := 0; k < v.blocks[len(v.blocks)-1]; k++ {
+		for j, n := range v.blocks {
+			if k < n {
+				blocks[j] = append(blocks[j], codewords[i])
+				i++
+			}
+		}
+	}
+	for j, b := range blocks {
+		ec := codewords[v.dataLen()+j:]
+		for k := range rsDivisor(v.ecLen) {
+			assert.Equal(t, rsRemainder(b, rsDivisor(v.ecLen))[k], ec[k*len(blocks)], "ec codeword %d", k)
+		}
+	}
+	data := bytes.Join(blocks, nil)
+
+	require.Equal(t, byte(0b0100), data[0]>>4, "byte mode")
+	var bb bitBuffer
+	for _, b := range data {
+		bb.append(uint32(b), 8)
+	}
+	countBits := 8
+	if c.Version >= 10 {
+		countBits = 16
+	}
+	n := 0
+	for _, b := range bb[4 : 4+countBits] {
+		n = n<<1 | int(b)
+	}
+	return bb[4+countBits : 4+countBits+8*n].bytes()
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,317 @@
+// Package qrexport renders the QR codes of the short URLs in bulk.
+//
+// An export is created with the links to render and queued; the queued
+// exports are rendered one by one in the background into the configured
+// directory as a ZIP of PNG images or a printable PDF sheet. The rendered
+// files are kept until the exports expire. The exports state is kept
+// in memory and is lost on restart.
+package qrexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// Format is the format of the rendered file.
+type Format string
+
+const (
+	// FormatZIP is the ZIP archive with a PNG image per link.
+	FormatZIP Format = "zip"
+	// FormatPDF is the printable PDF with a grid of codes per page.
+	FormatPDF Format = "pdf"
+)
+
+// ContentType returns the media type of the files of the format.
+func (f Format) ContentType() string {
+	if f == FormatPDF {
+		return "application/pdf"
+	}
+	return "application/zip"
+}
+
+// Valid reports whether the format is supported.
+func (f Format) Valid() bool {
+	return f == FormatZIP || f == FormatPDF
+}
+
+// Status is the state of the export.
+type Status string
+
+const (
+	// StatusQueued means the export waits to be rendered.
+	StatusQueued Status = "queued"
+	// StatusProcessing means the codes are being rendered.
+	StatusProcessing Status = "processing"
+	// StatusDone means the file is ready to be downloaded.
+	StatusDone Status = "done"
+	// StatusFailed means the rendering was aborted, see the error.
+	StatusFailed Status = "failed"
+)
+
+const (
+	// queueLen is the number of exports waiting to be rendered.
+	queueLen = 100
+	// cleanupInterval is how often the expired exports are removed.
+	cleanupInterval = time.Minute
+)
+
+// Link is the short URL to render the QR code of.
+type Link struct {
+	// Code is the short code used as the file name and the label.
+	Code string
+	// URL is the full short URL encoded in the QR code.
+	URL string
+}
+
+// Export is a single bulk export.
+type Export struct {
+	ID     string  `json:"id"`
+	UserID user.ID `json:"-"`
+	Format Format  `json:"format"`
+	Status Status  `json:"status"`
+	// Links is the number of the rendered codes.
+	Links int `json:"links"`
+	// Error is the reason of the export failure.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ExpiresAt is the time the export and its file are removed.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Manager keeps track of the exports and renders them one by one.
+// It is safe for concurrent use.
+type Manager struct {
+	// dir is the directory of the rendered files.
+	dir string
+	// retention is how long the exports are kept.
+	retention time.Duration
+	// mu protects exports and links.
+	mu      sync.Mutex
+	exports map[string]*Export
+	// links are the links of the queued exports.
+	links map[string][]Link
+	// queue is the IDs of the queued exports.
+	queue chan string
+}
+
+// NewManager returns the exports manager storing files in dir.
+// The files left by the previous run are removed, as their exports are lost.
+func NewManager(dir string, retention time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create QR exports directory: %w", err)
+	}
+	for _, f := range []Format{FormatZIP, FormatPDF} {
+		stale, err := filepath.Glob(filepath.Join(dir, "*."+string(f)))
+		if err != nil {
+			return nil, fmt.Errorf("list QR exports directory: %w", err)
+		}
+		for _, path := range stale {
+			_ = os.Remove(path)
+		}
+	}
+
+	return &Manager{
+		dir:       dir,
+		retention: retention,
+		exports:   make(map[string]*Export),
+		links:     make(map[string][]Link),
+		queue:     make(chan string, queueLen),
+	}, nil
+}
+
+// Create queues the export of the links of the user.
+func (m *Manager) Create(userID user.ID, format Format, links []Link) (Export, error) {
+	if !format.Valid() {
+		return Export{}, fmt.Errorf("%w: unsupported format %q", errs.ErrInvalidRequest, format)
+	}
+	if len(links) == 0 {
+		return Export{}, fmt.Errorf("%w: no links to export", errs.ErrInvalidRequest)
+	}
+
+	now := time.Now().UTC()
+	exp := &Export{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Format:    format,
+		Status:    StatusQueued,
+		Links:     len(links),
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(m.retention),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- exp.ID:
+	default:
+		return Export{}, errors.New("too many QR exports are queued, try again later")
+	}
+
+	m.exports[exp.ID] = exp
+	m.links[exp.ID] = links
+
+	return *exp, nil
+}
+
+// Get returns the export of the user.
+// If there is no such export, ErrNotFound is returned.
+func (m *Manager) Get(id string, userID user.ID) (Export, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exp, err := m.get(id, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	return *exp, nil
+}
+
+// Open opens the rendered file of the export. The caller must close it.
+// If the export is not done yet, ErrConflict is returned.
+func (m *Manager) Open(id string, userID user.ID) (*os.File, Export, error) {
+	exp, err := m.Get(id, userID)
+	if err != nil {
+		return nil, Export{}, err
+	}
+	if exp.Status != StatusDone {
+		return nil, Export{}, fmt.Errorf("export is %s: %w", exp.Status, errs.ErrConflict)
+	}
+
+	f, err := os.Open(m.path(exp))
+	if err != nil {
+		return nil, Export{}, fmt.Errorf("open export file: %w", err)
+	}
+	return f, exp, nil
+}
+
+// Run renders the queued exports and removes the expired ones
+// until done is closed. The export in progress is marked as failed
+// on stop.
+func (m *Manager) Run(done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			m.removeExpired(time.Now())
+		case id := <-m.queue:
+			m.process(ctx, id)
+		}
+	}
+}
+
+// process renders the single export into its file.
+func (m *Manager) process(ctx context.Context, id string) {
+	m.mu.Lock()
+	exp, ok := m.exports[id]
+	links := m.links[id]
+	delete(m.links, id)
+	var snapshot Export
+	if ok {
+		exp.Status = StatusProcessing
+		exp.UpdatedAt = time.Now().UTC()
+		snapshot = *exp
+	}
+	m.mu.Unlock()
+	// expired before its turn
+	if !ok {
+		return
+	}
+
+	err := m.render(ctx, snapshot, links)
+
+	m.update(id, func(exp *Export) {
+		exp.Status = StatusDone
+		if err != nil {
+			exp.Status = StatusFailed
+			exp.Error = err.Error()
+		}
+	})
+}
+
+// render writes the file of the export. The partial file is removed on error.
+func (m *Manager) render(ctx context.Context, exp Export, links []Link) (err error) {
+	f, err := os.OpenFile(m.path(exp), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create export file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("close export file: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(m.path(exp))
+		}
+	}()
+
+	if exp.Format == FormatPDF {
+		return WritePDF(ctx, f, links)
+	}
+	return WriteZIP(ctx, f, links)
+}
+
+// removeExpired removes the exports expired by now with their files.
+func (m *Manager) removeExpired(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, exp := range m.exports {
+		// the export in progress is removed once rendered
+		if exp.ExpiresAt.After(now) || exp.Status == StatusProcessing {
+			continue
+		}
+		_ = os.Remove(m.path(*exp))
+		delete(m.exports, id)
+		delete(m.links, id)
+	}
+}
+
+// update applies the change to the export and returns its copy.
+func (m *Manager) update(id string, change func(exp *Export)) Export {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exp := m.exports[id]
+	change(exp)
+	exp.UpdatedAt = time.Now().UTC()
+
+	return *exp
+}
+
+// get returns the export of the user. It must be called with mu held.
+func (m *Manager) get(id string, userID user.ID) (*Export, error) {
+	exp, ok := m.exports[id]
+	// don't disclose exports of the other users
+	if !ok || exp.UserID != userID {
+		return nil, fmt.Errorf("QR export %s: %w", id, errs.ErrNotFound)
+	}
+	return exp, nil
+}
+
+// path returns the path of the export file.
+func (m *Manager) path(exp Export) string {
+	return filepath.Join(m.dir, exp.ID+"."+string(exp.Format))
+}
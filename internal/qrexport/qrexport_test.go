@@ -0,0 +1,114 @@
+package qrexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLinks = []Link{
+	{Code: "YBbxJEcQ9vq", URL: "http://localhost:8080/YBbxJEcQ9vq"},
+	{Code: "Spring24", URL: "http://localhost:8080/Spring24"},
+}
+
+func TestManager(t *testing.T) {
+	m, err := NewManager(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	defer close(done)
+	go m.Run(done)
+
+	_, err = m.Create("test", "svg", testLinks)
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+	_, err = m.Create("test", FormatZIP, nil)
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	exp, err := m.Create("test", FormatZIP, testLinks)
+	require.NoError(t, err)
+	assert.Equal(t, 2, exp.Links)
+
+	_, err = m.Get(exp.ID, "other")
+	assert.ErrorIs(t, err, errs.ErrNotFound, "exports of the other users are not disclosed")
+
+	require.Eventually(t, func() bool {
+		exp, err = m.Get(exp.ID, "test")
+		return err == nil && exp.Status == StatusDone
+	}, time.Second, 10*time.Millisecond)
+
+	f, _, err := m.Open(exp.ID, "test")
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "YBbxJEcQ9vq.png", zr.File[0].Name)
+	rc, err := zr.File[1].Open()
+	require.NoError(t, err)
+	_, err = png.Decode(rc)
+	assert.NoError(t, err)
+
+	// expired exports are removed with their files
+	m.removeExpired(time.Now().Add(2 * time.Hour))
+	_, _, err = m.Open(exp.ID, "test")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.NoFileExists(t, m.path(exp))
+}
+
+func TestManager_OpenNotDone(t *testing.T) {
+	m, err := NewManager(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	// not running
+	exp, err := m.Create("test", FormatPDF, testLinks)
+	require.NoError(t, err)
+
+	_, _, err = m.Open(exp.ID, "test")
+	assert.ErrorIs(t, err, errs.ErrConflict)
+}
+
+func TestWritePDF(t *testing.T) {
+	// two pages
+	links := make([]Link, 0, 13)
+	for i := 0; i < 13; i++ {
+		links = append(links, Link{Code: strconv.Itoa(i), URL: "http://localhost:8080/" + strconv.Itoa(i)})
+	}
+	links = append(links, Link{Code: "paren", URL: "http://localhost:8080/(paren)"})
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(context.Background(), &buf, links))
+	b := buf.Bytes()
+
+	assert.True(t, bytes.HasPrefix(b, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(b, []byte("%%EOF\n")))
+	assert.Contains(t, buf.String(), "/Count 2")
+	assert.Contains(t, buf.String(), `(http://localhost:8080/\(paren\)) Tj`)
+
+	// the cross-reference table points at the objects
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(b)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(b[xref:], []byte("xref\n0 8\n")), "xref offset")
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(b[xref:], -1)
+	require.Len(t, offsets, 7)
+	for i, o := range offsets {
+		offset, err := strconv.Atoi(string(o[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(b[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
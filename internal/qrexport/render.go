@@ -0,0 +1,212 @@
+package qrexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KretovDmitry/shortener/internal/qrcode"
+)
+
+// pngScale is the number of pixels per module of the PNG images.
+const pngScale = 8
+
+// WriteZIP writes the ZIP archive with the PNG image of every link
+// named by its code.
+func WriteZIP(ctx context.Context, w io.Writer, links []Link) error {
+	zw := zip.NewWriter(w)
+
+	for _, link := range links {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		code, err := qrcode.Encode(link.URL)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", link.Code, err)
+		}
+		// PNG is compressed already
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: link.Code + ".png", Method: zip.Store})
+		if err != nil {
+			return fmt.Errorf("create zip entry: %w", err)
+		}
+		if err = code.WritePNG(f, pngScale); err != nil {
+			return fmt.Errorf("write %s: %w", link.Code, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+	return nil
+}
+
+// Layout of the PDF sheets in points: A4 pages with a grid of codes
+// labeled with their short URLs.
+const (
+	pageWidth   = 595
+	pageHeight  = 842
+	pageMargin  = 36
+	pageColumns = 3
+	pageRows    = 4
+	// codeSide is the side of the code with its quiet zone.
+	codeSide = 144
+	// fontSize is the size of the labels under the codes.
+	fontSize = 7
+	// maxLabelLen is the number of characters of the label fitting the cell.
+	maxLabelLen = 44
+)
+
+// WritePDF writes the printable PDF sheets with the codes of the links
+// in a grid, every code labeled with its short URL.
+func WritePDF(ctx context.Context, w io.Writer, links []Link) error {
+	perPage := pageColumns * pageRows
+	pages := (len(links) + perPage - 1) / perPage
+
+	// objects: 1 catalog, 2 pages, 3 font, then a page and its content
+	pdf := newPDFWriter(w)
+	pdf.header()
+
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	pdf.object("<< /Type /Catalog /Pages 2 0 R >>")
+	pdf.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	pdf.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	cellWidth := float64(pageWidth-2*pageMargin) / pageColumns
+	cellHeight := float64(pageHeight-2*pageMargin) / pageRows
+
+	for p := 0; p < pages; p++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var content strings.Builder
+		for i, link := range links[p*perPage : min((p+1)*perPage, len(links))] {
+			code, err := qrcode.Encode(link.URL)
+			if err != nil {
+				return fmt.Errorf("encode %s: %w", link.Code, err)
+			}
+			// cells from the top left, the origin of the page is bottom left
+			x := pageMargin + float64(i%pageColumns)*cellWidth + (cellWidth-codeSide)/2
+			y := pageHeight - pageMargin - float64(i/pageColumns)*cellHeight - codeSide
+			drawCode(&content, code, x, y)
+			fmt.Fprintf(&content, "BT /F1 %d Tf %.2f %.2f Td (%s) Tj ET\n",
+				fontSize, x, y-fontSize, pdfString(link.URL))
+		}
+
+		pdf.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*p))
+		pdf.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	return pdf.close()
+}
+
+// drawCode draws the dark modules of the code as the filled rectangles,
+// a rectangle per horizontal run, with the bottom left corner at x, y.
+func drawCode(w *strings.Builder, code *qrcode.Code, x, y float64) {
+	module := float64(codeSide) / float64(code.Size+2*qrcode.QuietZone)
+	top := y + codeSide - qrcode.QuietZone*module
+
+	w.WriteString("0 g\n")
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; {
+			if !code.Dark(col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < code.Size && code.Dark(col, row) {
+				col++
+			}
+			fmt.Fprintf(w, "%.2f %.2f %.2f %.2f re\n",
+				x+float64(qrcode.QuietZone+start)*module, top-float64(row+1)*module,
+				float64(col-start)*module, module)
+		}
+	}
+	w.WriteString("f\n")
+}
+
+// pdfString escapes the text as the PDF literal string truncated
+// to the label length. Non-ASCII characters are replaced, as the
+// standard fonts can't render them.
+func pdfString(text string) string {
+	if len(text) > maxLabelLen {
+		text = text[:maxLabelLen-3] + "..."
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7E:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// pdfWriter writes the numbered objects of the PDF file
+// and the cross-reference table of their offsets.
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int
+	offsets []int
+	err     error
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{w: bufio.NewWriter(w)}
+}
+
+// write writes the text keeping the offset and the first error.
+func (p *pdfWriter) write(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.offset += n
+	p.err = err
+}
+
+// header writes the file header. The comment with the binary characters
+// marks the file as binary for the transfer tools.
+func (p *pdfWriter) header() {
+	p.write("%%PDF-1.4\n%%\xE2\xE3\xCF\xD3\n")
+}
+
+// object writes the next object numbered from one.
+func (p *pdfWriter) object(body string) {
+	p.offsets = append(p.offsets, p.offset)
+	p.write("%d 0 obj\n%s\nendobj\n", len(p.offsets), body)
+}
+
+// close writes the cross-reference table and the trailer and flushes the file.
+func (p *pdfWriter) close() error {
+	xref := p.offset
+	p.write("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		p.write("%010d 00000 n \n", offset)
+	}
+	p.write("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, xref)
+
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+	if p.err != nil {
+		return fmt.Errorf("write pdf: %w", p.err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package ratelimit implements token bucket rate limiting.
+//
+// Every key, e.g. the user ID or the client IP, has its own bucket holding
+// up to limit tokens. The bucket is refilled evenly, limit tokens per period,
+// and every allowed request takes one token from it. So the clients may send
+// limit requests at once and then limit requests per period.
+//
+// The buckets are kept in memory or in Redis to be shared between
+// the instances of the service.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter decides whether the request is allowed.
+type Limiter interface {
+	// Allow takes a token from the bucket of the key if it has one.
+	Allow(ctx context.Context, key string, limit int, period time.Duration) (Result, error)
+}
+
+// Result of taking a token from the bucket.
+type Result struct {
+	// Allowed reports whether the token is taken.
+	Allowed bool
+	// Remaining is the number of the tokens left in the bucket.
+	Remaining int
+	// RetryAfter is the time until the next token is added
+	// if the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// New returns the limiter selected by the configuration.
+// The limits are kept in memory unless Redis is enabled.
+func New(config *config.Config) (Limiter, error) {
+	if !config.RateLimit.Redis {
+		return NewMemory(), nil
+	}
+	if config.Redis.DSN == "" {
+		return nil, errors.New("redis DSN is not provided")
+	}
+
+	opts, err := redis.ParseURL(config.Redis.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis DSN: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+
+	// Check connectivity and DSN correctness.
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return NewRedis(client, config.Redis.KeyPrefix), nil
+}
+
+// Interface implementation check.
+var _ Limiter = (*Memory)(nil)
+
+// Memory keeps the buckets in memory. It is safe for concurrent use.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// sweptAt is the last time the full buckets were removed.
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+// bucket is the state of the token bucket.
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	period    time.Duration
+}
+
+// NewMemory returns the empty in-memory limiter.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow implements Limiter.
+func (m *Memory) Allow(_ context.Context, key string, limit int, period time.Duration) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now, period)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), updatedAt: now}
+		m.buckets[key] = b
+	}
+	b.period = period
+
+	// refill the bucket with the tokens added since the last request
+	rate := float64(limit) / float64(period)
+	b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.updatedAt))*rate)
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: time.Duration(math.Ceil((1 - b.tokens) / rate))}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// sweep removes the buckets refilled completely, as they are
+// the same as the new ones. It runs at most once per period.
+func (m *Memory) sweep(now time.Time, period time.Duration) {
+	if now.Sub(m.sweptAt) < period {
+		return
+	}
+	m.sweptAt = now
+	for key, b := range m.buckets {
+		if now.Sub(b.updatedAt) >= b.period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	allow := func(key string) Result {
+		res, err := m.Allow(context.Background(), key, 3, time.Minute)
+		require.NoError(t, err)
+		return res
+	}
+
+	// the burst of limit requests is allowed
+	for want := 2; want >= 0; want-- {
+		res := allow("ip:192.0.2.1")
+		require.True(t, res.Allowed)
+		assert.Equal(t, want, res.Remaining)
+	}
+	res := allow("ip:192.0.2.1")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 20*time.Second, res.RetryAfter)
+
+	// other keys have their own buckets
+	assert.True(t, allow("ip:192.0.2.2").Allowed)
+
+	// the token is added in a third of the period
+	now = now.Add(20 * time.Second)
+	res = allow("ip:192.0.2.1")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.False(t, allow("ip:192.0.2.1").Allowed)
+
+	// the refilled buckets are removed
+	now = now.Add(time.Hour)
+	assert.True(t, allow("ip:192.0.2.1").Allowed)
+	assert.Len(t, m.buckets, 1)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Interface implementation check.
+var _ Limiter = (*Redis)(nil)
+
+// allowScript refills the bucket and takes a token from it if there is one.
+// The Redis clock is used, so the instances of the service agree on it.
+// The bucket expires when it is refilled completely. It returns 1 if the token
+// is taken, 0 otherwise, and the number of the tokens left as a string,
+// since Lua numbers are truncated to integers in the replies.
+//
+// KEYS: bucket key.
+// ARGV: limit, period in microseconds.
+var allowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1]) or limit
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - updated) * limit / period)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(period / 1000))
+return {allowed, tostring(tokens)}
+`)
+
+// Redis keeps the buckets in Redis, so that they are shared between
+// the instances of the service. Every bucket is the hash stored under
+// the "ratelimit:<key>" key.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis returns the limiter prefixing all the keys with the prefix.
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix + "ratelimit:"}
+}
+
+// Allow implements Limiter.
+func (r *Redis) Allow(ctx context.Context, key string, limit int, period time.Duration) (Result, error) {
+	res, err := allowScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit, period.Microseconds()).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("take token: %w", err)
+	}
+	if len(res) != 2 {
+		return Result{}, fmt.Errorf("take token: unexpected reply %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	s, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("take token: parse tokens: %w", err)
+	}
+
+	if allowed == 1 {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	rate := float64(limit) / float64(period)
+	return Result{RetryAfter: time.Duration((1 - tokens) / rate)}, nil
+}
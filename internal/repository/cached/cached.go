@@ -0,0 +1,309 @@
+// Package cached provides the storage decorator caching the URL records
+// in memory, so that the redirects of the hot links don't hit the database.
+//
+// The cache holds the configured number of the least recently used records
+// for at most the configured TTL. The records saved one by one are cached at
+// once, the deleted and batch saved ones are dropped from the cache, so that
+// they are read again. The last access times and the expirations are applied
+// to the cached records. Other changes made bypassing the store, e.g. by
+// another instance of the service, are visible after the TTL, or at once
+// to the reads marked by consistency.WithPrimary.
+package cached
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Storage is the interface of the decorated URL storage.
+type Storage interface {
+	Save(ctx context.Context, url *models.URL) error
+	SaveAll(ctx context.Context, urls []*models.URL) error
+	Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error)
+	GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error)
+	GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error)
+	DeleteURLs(ctx context.Context, urls ...*models.URL) error
+	UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	Ping(ctx context.Context) error
+}
+
+// Interface implementation check.
+var _ Storage = (*Store)(nil)
+
+// Store caches the records read from the decorated storage.
+// It is safe for concurrent use.
+type Store struct {
+	// next is the decorated storage.
+	next Storage
+	// size is the maximum number of the cached records.
+	size int
+	// ttl is how long the records are cached for.
+	ttl time.Duration
+
+	mu sync.Mutex
+	// lru is the list of the cached entries, the most recently used first.
+	lru *list.List
+	// entries is the list elements by short URL.
+	entries map[models.ShortURL]*list.Element
+	now     func() time.Time
+}
+
+// entry is the cached record.
+type entry struct {
+	url models.URL
+	// expiresAt is the time the record is read again.
+	expiresAt time.Time
+}
+
+// New returns the store decorating next with the cache
+// of the configured size and TTL.
+func New(next Storage, cfg config.Cache) (*Store, error) {
+	if next == nil {
+		return nil, fmt.Errorf("%w: storage", errs.ErrNilDependency)
+	}
+	if cfg.Size <= 0 {
+		return nil, fmt.Errorf("cache size %d is not positive", cfg.Size)
+	}
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("cache TTL %s is not positive", cfg.TTL)
+	}
+
+	return &Store{
+		next:    next,
+		size:    cfg.Size,
+		ttl:     cfg.TTL,
+		lru:     list.New(),
+		entries: make(map[models.ShortURL]*list.Element),
+		now:     time.Now,
+	}, nil
+}
+
+// Unwrap returns the decorated storage.
+func (s *Store) Unwrap() Storage {
+	return s.next
+}
+
+// Save saves the record and caches it.
+func (s *Store) Save(ctx context.Context, url *models.URL) error {
+	if err := s.next.Save(ctx, url); err != nil {
+		return err
+	}
+	s.put(url)
+	return nil
+}
+
+// SaveAll saves the records and drops them from the cache, since
+// the conflicting records are skipped and the saved ones are not known.
+func (s *Store) SaveAll(ctx context.Context, urls []*models.URL) error {
+	defer s.Invalidate(shortURLs(urls)...)
+	return s.next.SaveAll(ctx, urls)
+}
+
+// Get returns the cached record or reads it and caches it.
+// The reads which have to see the recent writes bypass the cache.
+func (s *Store) Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error) {
+	if u, ok := s.get(shortURL); ok && !consistency.Primary(ctx) {
+		return u, nil
+	}
+
+	u, err := s.next.Get(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	s.put(u)
+
+	return u, nil
+}
+
+// GetMany returns the cached records and reads the missing ones
+// in a single round trip caching them. The reads which have to see
+// the recent writes bypass the cache.
+func (s *Store) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	if consistency.Primary(ctx) {
+		read, err := s.next.GetMany(ctx, sURLs)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range read {
+			s.put(u)
+		}
+		return read, nil
+	}
+
+	all := make([]*models.URL, 0, len(sURLs))
+	missing := make([]models.ShortURL, 0, len(sURLs))
+	seen := make(map[models.ShortURL]struct{}, len(sURLs))
+	for _, sURL := range sURLs {
+		if _, ok := seen[sURL]; ok {
+			continue
+		}
+		seen[sURL] = struct{}{}
+		if u, ok := s.get(sURL); ok {
+			all = append(all, u)
+			continue
+		}
+		missing = append(missing, sURL)
+	}
+	if len(missing) == 0 {
+		return all, nil
+	}
+
+	read, err := s.next.GetMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range read {
+		s.put(u)
+	}
+
+	return append(all, read...), nil
+}
+
+// GetAllByUserID reads the records of the user bypassing the cache,
+// as the cache may hold only some of them.
+func (s *Store) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	return s.next.GetAllByUserID(ctx, userID)
+}
+
+// DeleteURLs deletes the records and drops them from the cache.
+func (s *Store) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	defer s.Invalidate(shortURLs(urls)...)
+	return s.next.DeleteURLs(ctx, urls...)
+}
+
+// UpdateLastAccessed sets the last access time of the records
+// and of the cached ones. Older times never overwrite newer ones.
+func (s *Store) UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error {
+	if err := s.next.UpdateLastAccessed(ctx, accessed); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sURL, at := range accessed {
+		elem, ok := s.entries[sURL]
+		if !ok {
+			continue
+		}
+		e := elem.Value.(*entry)
+		if e.url.LastAccessedAt != nil && e.url.LastAccessedAt.After(at) {
+			continue
+		}
+		at := at // for Go versions below 1.22
+		e.url.LastAccessedAt = &at
+	}
+
+	return nil
+}
+
+// DeleteExpired marks the records expired by now as deleted
+// in the storage and in the cache.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	n, err := s.next.DeleteExpired(ctx, now)
+	if err != nil {
+		return n, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, elem := range s.entries {
+		e := elem.Value.(*entry)
+		if e.url.IsExpired(now) {
+			e.url.IsDeleted = true
+		}
+	}
+
+	return n, nil
+}
+
+// Ping checks the health of the decorated storage.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+// Close closes the decorated storage if it needs closing.
+func (s *Store) Close() error {
+	if closer, ok := s.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Invalidate drops the records from the cache, so that they are read
+// again, e.g. after they are changed bypassing the store.
+func (s *Store) Invalidate(sURLs ...models.ShortURL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sURL := range sURLs {
+		if elem, ok := s.entries[sURL]; ok {
+			s.lru.Remove(elem)
+			delete(s.entries, sURL)
+		}
+	}
+}
+
+// get returns the copy of the unexpired cached record of the short URL
+// and marks it as the most recently used.
+func (s *Store) get(sURL models.ShortURL) (*models.URL, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[sURL]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !s.now().Before(e.expiresAt) {
+		s.lru.Remove(elem)
+		delete(s.entries, sURL)
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+
+	u := e.url
+	return &u, true
+}
+
+// put caches the copy of the record replacing the cached one
+// and evicts the least recently used record if the cache is full.
+func (s *Store) put(u *models.URL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &entry{url: *u, expiresAt: s.now().Add(s.ttl)}
+	if elem, ok := s.entries[u.ShortURL]; ok {
+		elem.Value = e
+		s.lru.MoveToFront(elem)
+		return
+	}
+
+	s.entries[u.ShortURL] = s.lru.PushFront(e)
+	if s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry).url.ShortURL)
+	}
+}
+
+// shortURLs returns the short URLs of the records.
+func shortURLs(urls []*models.URL) []models.ShortURL {
+	all := make([]models.ShortURL, len(urls))
+	for i, u := range urls {
+		all[i] = u.ShortURL
+	}
+	return all
+}
@@ -0,0 +1,219 @@
+package cached
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore is the in-memory storage counting the reads.
+type countingStore struct {
+	*memstore.URLRepository
+	reads atomic.Int64
+}
+
+func (s *countingStore) Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error) {
+	s.reads.Add(1)
+	return s.URLRepository.Get(ctx, shortURL)
+}
+
+func (s *countingStore) GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error) {
+	s.reads.Add(1)
+	return s.URLRepository.GetMany(ctx, shortURLs)
+}
+
+func newTestStore(t *testing.T, size int) (*Store, *countingStore) {
+	t.Helper()
+	next := &countingStore{URLRepository: memstore.NewURLRepository()}
+	s, err := New(next, config.Cache{Size: size, TTL: time.Minute})
+	require.NoError(t, err)
+	return s, next
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, config.Cache{Size: 1, TTL: time.Minute})
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+
+	next := memstore.NewURLRepository()
+	_, err = New(next, config.Cache{TTL: time.Minute})
+	require.Error(t, err)
+	_, err = New(next, config.Cache{Size: 1})
+	require.Error(t, err)
+}
+
+func TestStore_Get(t *testing.T) {
+	ctx := context.Background()
+	s, next := newTestStore(t, 2)
+
+	require.NoError(t, next.SaveAll(ctx, []*models.URL{
+		{ShortURL: "First", OriginalURL: "https://go.dev/", UserID: "user"},
+		{ShortURL: "Second", OriginalURL: "https://pkg.go.dev/", UserID: "user"},
+		{ShortURL: "Third", OriginalURL: "https://go.dev/blog/", UserID: "user"},
+	}))
+
+	for i := 0; i < 3; i++ {
+		got, err := s.Get(ctx, "First")
+		require.NoError(t, err)
+		assert.Equal(t, "https://go.dev/", string(got.OriginalURL))
+	}
+	assert.EqualValues(t, 1, next.reads.Load(), "hot link expected to be read once")
+
+	// the cached records are copies
+	got, err := s.Get(ctx, "First")
+	require.NoError(t, err)
+	got.IsDeleted = true
+	got, err = s.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted)
+
+	// misses are not cached
+	_, err = s.Get(ctx, "Missing")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+	_, err = s.Get(ctx, "Missing")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+	assert.EqualValues(t, 3, next.reads.Load())
+
+	// the least recently used record is evicted
+	_, err = s.Get(ctx, "Second")
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "First")
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "Third")
+	require.NoError(t, err)
+	next.reads.Store(0)
+	_, err = s.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.Zero(t, next.reads.Load())
+	_, err = s.Get(ctx, "Second")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, next.reads.Load())
+
+	// the records are read again after the TTL
+	now := time.Now()
+	s.now = func() time.Time { return now.Add(time.Minute) }
+	next.reads.Store(0)
+	_, err = s.Get(ctx, "Second")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, next.reads.Load())
+}
+
+func TestStore_GetMany(t *testing.T) {
+	ctx := context.Background()
+	s, next := newTestStore(t, 10)
+
+	require.NoError(t, s.Save(ctx, &models.URL{ShortURL: "Saved", OriginalURL: "https://go.dev/", UserID: "user"}))
+	require.NoError(t, next.Save(ctx, &models.URL{ShortURL: "Stored", OriginalURL: "https://pkg.go.dev/", UserID: "user"}))
+
+	all, err := s.GetMany(ctx, []models.ShortURL{"Saved", "Saved"})
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.Zero(t, next.reads.Load(), "saved records expected to be cached")
+
+	all, err = s.GetMany(ctx, []models.ShortURL{"Saved", "Stored", "Missing"})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.EqualValues(t, 1, next.reads.Load())
+
+	_, err = s.Get(ctx, "Stored")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, next.reads.Load(), "read records expected to be cached")
+}
+
+func TestStore_Writes(t *testing.T) {
+	ctx := context.Background()
+	s, next := newTestStore(t, 10)
+
+	u := &models.URL{ShortURL: "Link", OriginalURL: "https://go.dev/", UserID: "user"}
+	require.NoError(t, s.Save(ctx, u))
+	require.ErrorIs(t, s.Save(ctx, &models.URL{ShortURL: "Link", OriginalURL: "https://go.dev/", UserID: "other"}),
+		errs.ErrConflict)
+
+	// the last access times are applied to the cached records
+	now := time.Now()
+	require.NoError(t, s.UpdateLastAccessed(ctx, map[models.ShortURL]time.Time{"Link": now}))
+	require.NoError(t, s.UpdateLastAccessed(ctx, map[models.ShortURL]time.Time{"Link": now.Add(-time.Minute)}))
+	got, err := s.Get(ctx, "Link")
+	require.NoError(t, err)
+	require.NotNil(t, got.LastAccessedAt)
+	assert.True(t, now.Equal(*got.LastAccessedAt))
+
+	// the deleted records are read again
+	require.NoError(t, s.DeleteURLs(ctx, u))
+	got, err = s.Get(ctx, "Link")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+	assert.EqualValues(t, 1, next.reads.Load())
+
+	// as well as the invalidated ones
+	s.Invalidate("Link")
+	_, err = s.Get(ctx, "Link")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, next.reads.Load())
+
+	// the batch saved records are read from the storage
+	require.NoError(t, s.SaveAll(ctx, []*models.URL{
+		{ShortURL: "Batch", OriginalURL: "https://pkg.go.dev/", UserID: "user"},
+	}))
+	_, err = s.Get(ctx, "Batch")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, next.reads.Load())
+}
+
+func TestStore_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, 10)
+
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, s.Save(ctx, &models.URL{
+		ShortURL: "Expiring", OriginalURL: "https://go.dev/", UserID: "user", ExpiresAt: &expiresAt,
+	}))
+	require.NoError(t, s.Save(ctx, &models.URL{ShortURL: "Forever", OriginalURL: "https://pkg.go.dev/", UserID: "user"}))
+
+	n, err := s.DeleteExpired(ctx, expiresAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err := s.Get(ctx, "Expiring")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+	got, err = s.Get(ctx, "Forever")
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted)
+}
+
+func TestStore_Primary(t *testing.T) {
+	ctx := context.Background()
+	s, next := newTestStore(t, 2)
+
+	require.NoError(t, s.Save(ctx, &models.URL{ShortURL: "First", OriginalURL: "https://go.dev/", UserID: "user"}))
+
+	// another instance changes the record bypassing the cache
+	require.NoError(t, next.SetNote(ctx, "user", "First", "changed"))
+
+	got, err := s.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.Empty(t, got.Note, "cached record expected")
+
+	primary := consistency.WithPrimary(ctx)
+	got, err = s.Get(primary, "First")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", got.Note, "primary read bypasses the cache")
+
+	all, err := s.GetMany(primary, []models.ShortURL{"First"})
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.EqualValues(t, 2, next.reads.Load())
+
+	got, err = s.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", got.Note, "primary read refreshes the cache")
+}
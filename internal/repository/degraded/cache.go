@@ -0,0 +1,145 @@
+package degraded
+
+import (
+	"sync"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// cache holds the recently used URL records.
+// It is safe for concurrent use.
+type cache struct {
+	mu sync.RWMutex
+	// records is the cached records by short URL.
+	records map[models.ShortURL]models.URL
+	// size is the maximum number of the records read from the database.
+	// Records written in the degraded mode are always cached.
+	size int
+}
+
+// newCache returns the cache holding at most size records read from the database.
+func newCache(size int) *cache {
+	return &cache{records: make(map[models.ShortURL]models.URL), size: size}
+}
+
+// put caches the record read from the database replacing the cached one.
+// New records are skipped if the cache is full.
+func (c *cache) put(u *models.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.records[u.ShortURL]; !ok && len(c.records) >= c.size {
+		return
+	}
+	c.records[u.ShortURL] = *u
+}
+
+// add caches the records unless their short URLs are already cached
+// and returns the added ones.
+func (c *cache) add(urls ...*models.URL) []*models.URL {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	added := make([]*models.URL, 0, len(urls))
+	for _, u := range urls {
+		if _, ok := c.records[u.ShortURL]; ok {
+			continue
+		}
+		c.records[u.ShortURL] = *u
+		added = append(added, u)
+	}
+	return added
+}
+
+// get returns the cached record of the short URL.
+func (c *cache) get(sURL models.ShortURL) (*models.URL, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	u, ok := c.records[sURL]
+	return &u, ok
+}
+
+// getMany returns the cached records of the short URLs skipping
+// the missing and repeated ones.
+func (c *cache) getMany(sURLs []models.ShortURL) []*models.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	all := make([]*models.URL, 0, len(sURLs))
+	seen := make(map[models.ShortURL]struct{}, len(sURLs))
+	for _, sURL := range sURLs {
+		if _, ok := seen[sURL]; ok {
+			continue
+		}
+		seen[sURL] = struct{}{}
+		if u, ok := c.records[sURL]; ok {
+			all = append(all, &u)
+		}
+	}
+	return all
+}
+
+// byUser returns the cached records of the user.
+func (c *cache) byUser(userID user.ID) []*models.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	all := make([]*models.URL, 0)
+	for _, u := range c.records {
+		u := u // for Go versions below 1.22
+		if u.UserID == userID {
+			all = append(all, &u)
+		}
+	}
+	return all
+}
+
+// delete marks the cached records as deleted if they belong
+// to the user given in the URL.
+func (c *cache) delete(urls ...*models.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, u := range urls {
+		record, ok := c.records[u.ShortURL]
+		if !ok || record.UserID != u.UserID {
+			continue
+		}
+		record.IsDeleted = true
+		c.records[u.ShortURL] = record
+	}
+}
+
+// touch sets the last access time of the cached records.
+// Older times never overwrite newer ones.
+func (c *cache) touch(accessed map[models.ShortURL]time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for sURL, at := range accessed {
+		record, ok := c.records[sURL]
+		if !ok || (record.LastAccessedAt != nil && record.LastAccessedAt.After(at)) {
+			continue
+		}
+		at := at // for Go versions below 1.22
+		record.LastAccessedAt = &at
+		c.records[sURL] = record
+	}
+}
+
+// expire marks the cached records expired by now as deleted.
+func (c *cache) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for sURL, record := range c.records {
+		if record.IsDeleted || !record.IsExpired(now) {
+			continue
+		}
+		record.IsDeleted = true
+		c.records[sURL] = record
+	}
+}
@@ -0,0 +1,374 @@
+// Package degraded provides the storage decorator keeping the redirects
+// available while the database is down.
+//
+// The decorated storage is pinged periodically. After the configured number
+// of failed pings in a row the store switches to the degraded mode: reads are
+// served from the cache of the recently used records, which may be stale,
+// and writes are appended to the durable spool and applied to the cache.
+// When the database responds again, the spool is replayed and the store
+// switches back. The spool left by the previous run is replayed as soon
+// as the database is available.
+package degraded
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Default values used if the config values are not set.
+const (
+	defaultCheckInterval    = 5 * time.Second
+	defaultFailureThreshold = 3
+	defaultCacheSize        = 100000
+)
+
+// Storage is the interface of the decorated URL storage.
+type Storage interface {
+	Save(ctx context.Context, url *models.URL) error
+	SaveAll(ctx context.Context, urls []*models.URL) error
+	Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error)
+	GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error)
+	GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error)
+	DeleteURLs(ctx context.Context, urls ...*models.URL) error
+	UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	Ping(ctx context.Context) error
+}
+
+// Interface implementation check.
+var _ Storage = (*Store)(nil)
+
+// Store switches to the cache and the spool while the decorated
+// storage is unavailable. It is safe for concurrent use.
+type Store struct {
+	// next is the decorated storage.
+	next   Storage
+	logger logger.Logger
+	// interval is how often the decorated storage is pinged.
+	interval time.Duration
+	// threshold is the number of failed pings in a row
+	// switching to the degraded mode.
+	threshold int
+	// failures is the number of failed pings in a row.
+	// It is accessed only by the monitoring goroutine.
+	failures int
+	// degraded is set while the decorated storage is unavailable.
+	degraded atomic.Bool
+	// cache is the recently used records.
+	cache *cache
+	// mu serializes the spooled writes with the replay.
+	mu    sync.Mutex
+	spool *spool
+	// done stops the monitoring goroutine.
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New returns the store decorating next and starts monitoring it.
+// The store must be closed to stop monitoring and close the spool.
+func New(next Storage, cfg config.Degraded, logger logger.Logger) (*Store, error) {
+	if next == nil {
+		return nil, fmt.Errorf("%w: storage", errs.ErrNilDependency)
+	}
+
+	spool, err := openSpool(cfg.SpoolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		next:      next,
+		logger:    logger,
+		interval:  cfg.CheckInterval,
+		threshold: cfg.FailureThreshold,
+		cache:     newCache(cfg.CacheSize),
+		spool:     spool,
+		done:      make(chan struct{}),
+	}
+	if s.interval <= 0 {
+		s.interval = defaultCheckInterval
+	}
+	if s.threshold <= 0 {
+		s.threshold = defaultFailureThreshold
+	}
+	if s.cache.size <= 0 {
+		s.cache.size = defaultCacheSize
+	}
+
+	// replay the spool of the previous run before serving any writes
+	s.check()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.monitor()
+	}()
+
+	return s, nil
+}
+
+// Degraded reports whether the store serves from the cache and the spool.
+func (s *Store) Degraded() bool {
+	return s.degraded.Load()
+}
+
+// Unwrap returns the decorated storage.
+func (s *Store) Unwrap() Storage {
+	return s.next
+}
+
+// Close stops monitoring and closes the spool.
+// It is safe to call more than once.
+func (s *Store) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err = s.spool.Close()
+	})
+	return err
+}
+
+// monitor pings the decorated storage until the store is closed.
+func (s *Store) monitor() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check pings the decorated storage and switches the mode.
+// The spool is replayed before switching back, so that the writes
+// are applied in order. The store stays degraded if the replay fails.
+func (s *Store) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+
+	if err := s.next.Ping(ctx); err != nil {
+		s.failures++
+		if s.failures >= s.threshold && !s.degraded.Swap(true) {
+			s.logger.Errorf("storage is unavailable, switching to degraded mode: %s", err)
+		}
+		return
+	}
+	s.failures = 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	empty, err := s.spool.empty()
+	if err != nil {
+		s.logger.Errorf("check spool: %s", err)
+		return
+	}
+	if !empty {
+		n, err := s.spool.replay(func(e *entry) error { return s.apply(context.TODO(), e) })
+		if err != nil {
+			s.logger.Errorf("replay spool: %s", err)
+			return
+		}
+		s.logger.Infof("%d spooled writes replayed", n)
+	}
+
+	if s.degraded.Swap(false) {
+		s.logger.Info("storage is available, degraded mode is off")
+	}
+}
+
+// apply applies the spooled write to the decorated storage.
+// Saving is idempotent, as already existing records are skipped.
+func (s *Store) apply(ctx context.Context, e *entry) error {
+	switch e.Op {
+	case opSave:
+		return s.next.SaveAll(ctx, e.URLs)
+	case opDelete:
+		return s.next.DeleteURLs(ctx, e.URLs...)
+	case opTouch:
+		return s.next.UpdateLastAccessed(ctx, e.Accessed)
+	default:
+		return fmt.Errorf("unknown operation %q", e.Op)
+	}
+}
+
+// spoolWrite appends the write to the spool and applies it to the cache.
+// It reports false if the store is not degraded anymore,
+// so that the write must go to the decorated storage.
+func (s *Store) spoolWrite(e *entry, apply func()) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.degraded.Load() {
+		return false, nil
+	}
+	if err := s.spool.append(e); err != nil {
+		return true, err
+	}
+	apply()
+	return true, nil
+}
+
+// Save saves the record. In the degraded mode the conflicts are detected
+// only with the cached records.
+func (s *Store) Save(ctx context.Context, u *models.URL) error {
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	if s.degraded.Load() {
+		if _, ok := s.cache.get(u.ShortURL); ok {
+			return errs.ErrConflict
+		}
+		spooled, err := s.spoolWrite(&entry{Op: opSave, URLs: []*models.URL{u}},
+			func() { s.cache.add(u) })
+		if spooled {
+			return err
+		}
+	}
+
+	if err := s.next.Save(ctx, u); err != nil {
+		return err
+	}
+	s.cache.put(u)
+	return nil
+}
+
+// SaveAll saves the records. Records that already exist are skipped.
+func (s *Store) SaveAll(ctx context.Context, urls []*models.URL) error {
+	for _, u := range urls {
+		if err := u.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	if s.degraded.Load() {
+		spooled, err := s.spoolWrite(&entry{Op: opSave, URLs: urls},
+			func() { s.cache.add(urls...) })
+		if spooled {
+			return err
+		}
+	}
+
+	if err := s.next.SaveAll(ctx, urls); err != nil {
+		return err
+	}
+	for _, u := range urls {
+		s.cache.put(u)
+	}
+	return nil
+}
+
+// Get retrieves the record by its short URL.
+// In the degraded mode only the cached records are found.
+func (s *Store) Get(ctx context.Context, sURL models.ShortURL) (*models.URL, error) {
+	if s.degraded.Load() {
+		u, ok := s.cache.get(sURL)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", sURL, errs.ErrNotFound)
+		}
+		return u, nil
+	}
+
+	u, err := s.next.Get(ctx, sURL)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(u)
+	return u, nil
+}
+
+// GetMany retrieves the records by their short URLs.
+// In the degraded mode only the cached records are found.
+func (s *Store) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	if s.degraded.Load() {
+		return s.cache.getMany(sURLs), nil
+	}
+	return s.next.GetMany(ctx, sURLs)
+}
+
+// GetAllByUserID retrieves the records of the user.
+// In the degraded mode only the cached records are found.
+func (s *Store) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	if s.degraded.Load() {
+		all := s.cache.byUser(userID)
+		if len(all) == 0 {
+			return nil, errs.ErrNotFound
+		}
+		return all, nil
+	}
+	return s.next.GetAllByUserID(ctx, userID)
+}
+
+// DeleteURLs marks the records as deleted.
+func (s *Store) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	if s.degraded.Load() {
+		spooled, err := s.spoolWrite(&entry{Op: opDelete, URLs: urls},
+			func() { s.cache.delete(urls...) })
+		if spooled {
+			return err
+		}
+	}
+
+	if err := s.next.DeleteURLs(ctx, urls...); err != nil {
+		return err
+	}
+	s.cache.delete(urls...)
+	return nil
+}
+
+// UpdateLastAccessed sets the last access time of the short URLs.
+func (s *Store) UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error {
+	if s.degraded.Load() {
+		spooled, err := s.spoolWrite(&entry{Op: opTouch, Accessed: accessed},
+			func() { s.cache.touch(accessed) })
+		if spooled {
+			return err
+		}
+	}
+
+	if err := s.next.UpdateLastAccessed(ctx, accessed); err != nil {
+		return err
+	}
+	s.cache.touch(accessed)
+	return nil
+}
+
+// DeleteExpired marks the records expired by now as deleted.
+// Nothing is deleted in the degraded mode, as expired records
+// are not redirected anyway and are deleted after the recovery.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	if s.degraded.Load() {
+		return 0, nil
+	}
+
+	n, err := s.next.DeleteExpired(ctx, now)
+	if err != nil {
+		return n, err
+	}
+	s.cache.expire(now)
+	return n, nil
+}
+
+// Ping checks the decorated storage.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
@@ -0,0 +1,131 @@
+package degraded
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("database is down")
+
+// flakyStore is the in-memory storage which can be switched off.
+type flakyStore struct {
+	*memstore.URLRepository
+	down atomic.Bool
+}
+
+func (s *flakyStore) Ping(context.Context) error {
+	if s.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+// newTestStore returns the store checked only manually.
+func newTestStore(t *testing.T, next Storage, spoolPath string) *Store {
+	t.Helper()
+	l, _ := logger.NewForTest()
+	s, err := New(next, config.Degraded{
+		SpoolPath:        spoolPath,
+		CheckInterval:    time.Hour,
+		FailureThreshold: 2,
+	}, l)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	next := &flakyStore{URLRepository: memstore.NewURLRepository()}
+	spoolPath := filepath.Join(t.TempDir(), "spool.jsonl")
+	s := newTestStore(t, next, spoolPath)
+
+	cached := &models.URL{ShortURL: "Cached", OriginalURL: "https://go.dev/", UserID: "user"}
+	require.NoError(t, s.Save(ctx, cached))
+
+	next.down.Store(true)
+	s.check()
+	assert.False(t, s.Degraded(), "single failure must not switch the mode")
+	s.check()
+	require.True(t, s.Degraded())
+
+	// reads are served from the cache
+	got, err := s.Get(ctx, "Cached")
+	require.NoError(t, err)
+	assert.Equal(t, cached.OriginalURL, got.OriginalURL)
+	_, err = s.Get(ctx, "Unknown")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	// writes are spooled and applied to the cache
+	spooled := &models.URL{ShortURL: "Spooled", OriginalURL: "https://pkg.go.dev/", UserID: "other"}
+	require.NoError(t, s.Save(ctx, spooled))
+	assert.ErrorIs(t, s.Save(ctx, spooled), errs.ErrConflict)
+	require.NoError(t, s.DeleteURLs(ctx, &models.URL{ShortURL: "Cached", UserID: "user"}))
+
+	got, err = s.Get(ctx, "Spooled")
+	require.NoError(t, err)
+	assert.Equal(t, spooled.OriginalURL, got.OriginalURL)
+	got, err = s.Get(ctx, "Cached")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+
+	_, err = next.Get(ctx, "Spooled")
+	assert.ErrorIs(t, err, errs.ErrNotFound, "spooled write applied too early")
+
+	// the spool survives the restart
+	require.NoError(t, s.Close())
+	s = newTestStore(t, next, spoolPath)
+
+	next.down.Store(false)
+	s.check()
+	assert.False(t, s.Degraded())
+
+	got, err = next.Get(ctx, "Spooled")
+	require.NoError(t, err)
+	assert.Equal(t, spooled.OriginalURL, got.OriginalURL)
+	got, err = next.Get(ctx, "Cached")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+
+	info, err := os.Stat(spoolPath)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "spool must be truncated after replay")
+}
+
+func TestSpool_IncompleteLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.jsonl")
+	sp, err := openSpool(path)
+	require.NoError(t, err)
+	defer func() { _ = sp.Close() }()
+
+	require.NoError(t, sp.append(&entry{Op: opSave, URLs: []*models.URL{{ShortURL: "First"}}}))
+	_, err = sp.file.WriteString(`{"op":"sa`)
+	require.NoError(t, err)
+
+	var applied []*entry
+	n, err := sp.replay(func(e *entry) error {
+		applied = append(applied, e)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, applied, 1)
+	assert.Equal(t, models.ShortURL("First"), applied[0].URLs[0].ShortURL)
+
+	empty, err := sp.empty()
+	require.NoError(t, err)
+	assert.True(t, empty)
+}
@@ -0,0 +1,110 @@
+package degraded
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models"
+)
+
+// Kinds of the spooled writes.
+const (
+	opSave   = "save"
+	opDelete = "delete"
+	opTouch  = "touch"
+)
+
+// entry is a single spooled write.
+type entry struct {
+	Op       string                        `json:"op"`
+	URLs     []*models.URL                 `json:"urls,omitempty"`
+	Accessed map[models.ShortURL]time.Time `json:"accessed,omitempty"`
+}
+
+// spool is the append only file of the writes made while the database
+// is unavailable. Every entry is synced to the disk before the write
+// is acknowledged, so that it survives the restart. It is not safe
+// for concurrent use.
+type spool struct {
+	file *os.File
+}
+
+// openSpool opens or creates the spool file at the given path.
+func openSpool(path string) (*spool, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open spool: %w", err)
+	}
+	return &spool{file: file}, nil
+}
+
+// append writes the entry to the end of the spool and syncs it to the disk.
+func (s *spool) append(e *entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode spool entry: %w", err)
+	}
+	if _, err = s.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write spool: %w", err)
+	}
+	if err = s.file.Sync(); err != nil {
+		return fmt.Errorf("sync spool: %w", err)
+	}
+	return nil
+}
+
+// empty reports whether the spool has no entries.
+func (s *spool) empty() (bool, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("stat spool: %w", err)
+	}
+	return info.Size() == 0, nil
+}
+
+// replay applies the entries in the order they were written and
+// truncates the spool if all of them are applied. On error the spool
+// is kept as is, so the entries must be safe to apply more than once.
+// An incomplete last line, e.g. left by the crash during the write,
+// is skipped.
+func (s *spool) replay(apply func(*entry) error) (int, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek spool: %w", err)
+	}
+
+	n := 0
+	r := bufio.NewReader(s.file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("read spool: %w", err)
+		}
+
+		var e entry
+		if err = json.Unmarshal(line, &e); err != nil {
+			return n, fmt.Errorf("decode spool entry %d: %w", n+1, err)
+		}
+		if err = apply(&e); err != nil {
+			return n, fmt.Errorf("apply spool entry %d: %w", n+1, err)
+		}
+		n++
+	}
+
+	if err := s.file.Truncate(0); err != nil {
+		return n, fmt.Errorf("truncate spool: %w", err)
+	}
+	return n, nil
+}
+
+// Close closes the spool file.
+func (s *spool) Close() error {
+	return s.file.Close()
+}
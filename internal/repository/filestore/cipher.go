@@ -0,0 +1,125 @@
+package filestore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnknownKey is returned when a record is encrypted with a key
+// which is not known to the cipher.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Cipher encrypts and decrypts file storage records with AES-GCM.
+// It supports key rotation: new records are always encrypted with the
+// active key, while records encrypted with any of the previous keys
+// can still be decrypted.
+type Cipher struct {
+	// active is the ID of the key used to encrypt new records.
+	active string
+	// aeads holds AEAD instances by key ID.
+	aeads map[string]cipher.AEAD
+}
+
+// NewCipher creates a new Cipher from the hex encoded active key and
+// previously used keys. The key length must be 16, 24 or 32 bytes
+// to select AES-128, AES-192 or AES-256 respectively.
+func NewCipher(key string, oldKeys ...string) (*Cipher, error) {
+	c := &Cipher{aeads: make(map[string]cipher.AEAD, len(oldKeys)+1)}
+
+	id, err := c.addKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("active key: %w", err)
+	}
+	c.active = id
+
+	for i, k := range oldKeys {
+		if _, err = c.addKey(k); err != nil {
+			return nil, fmt.Errorf("decryption key #%d: %w", i+1, err)
+		}
+	}
+
+	return c, nil
+}
+
+// NewDecrypter creates a new Cipher from the hex encoded keys which is
+// only able to decrypt records, e.g. to decrypt the file storage with
+// the keys that are no longer active.
+func NewDecrypter(keys ...string) (*Cipher, error) {
+	c := &Cipher{aeads: make(map[string]cipher.AEAD, len(keys))}
+
+	for i, k := range keys {
+		if _, err := c.addKey(k); err != nil {
+			return nil, fmt.Errorf("decryption key #%d: %w", i+1, err)
+		}
+	}
+
+	return c, nil
+}
+
+// Encrypt seals the plaintext with the active key. It returns the ID of
+// the key used and the nonce prepended to the ciphertext.
+func (c *Cipher) Encrypt(plaintext []byte) (string, []byte, error) {
+	aead, ok := c.aeads[c.active]
+	if !ok {
+		return "", nil, errors.New("no active key")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	return c.active, aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens the data sealed with the key identified by keyID.
+func (c *Cipher) Decrypt(keyID string, data []byte) ([]byte, error) {
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// addKey decodes the hex encoded key and registers it in the cipher.
+// It returns the ID of the key.
+func (c *Cipher) addKey(key string) (string, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("decode key: %w", err)
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return "", fmt.Errorf("new cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("new gcm: %w", err)
+	}
+
+	id := keyID(raw)
+	c.aeads[id] = aead
+
+	return id, nil
+}
+
+// keyID returns first 8 hex digits of the key hash.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,105 @@
+package filestore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return hex.EncodeToString(key)
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher(newTestKey(t))
+	require.NoError(t, err)
+
+	kid, data, err := c.Encrypt([]byte(`{"short_url":"abc"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "abc")
+
+	got, err := c.Decrypt(kid, data)
+	require.NoError(t, err)
+	assert.Equal(t, `{"short_url":"abc"}`, string(got))
+
+	data[len(data)-1] ^= 1
+	_, err = c.Decrypt(kid, data)
+	require.Error(t, err, "the ciphertext is authenticated")
+
+	_, err = c.Decrypt(kid, data[:4])
+	require.Error(t, err, "the ciphertext is too short")
+}
+
+func TestNewCipher_InvalidKey(t *testing.T) {
+	for name, key := range map[string]string{
+		"not hex":      "not-a-hex-key",
+		"wrong length": "abcdef",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewCipher(key)
+			require.Error(t, err)
+			_, err = NewCipher(newTestKey(t), key)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCipher_Rotation(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+
+	old, err := NewCipher(oldKey)
+	require.NoError(t, err)
+	oldKID, data, err := old.Encrypt([]byte("record"))
+	require.NoError(t, err)
+
+	rotated, err := NewCipher(newKey, oldKey)
+	require.NoError(t, err)
+	got, err := rotated.Decrypt(oldKID, data)
+	require.NoError(t, err, "the records encrypted with the old key are readable")
+	assert.Equal(t, "record", string(got))
+
+	newKID, _, err := rotated.Encrypt([]byte("record"))
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKID, newKID, "the new records are encrypted with the active key")
+
+	dropped, err := NewCipher(newKey)
+	require.NoError(t, err)
+	_, err = dropped.Decrypt(oldKID, data)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestCipher_UnknownKey(t *testing.T) {
+	c, err := NewCipher(newTestKey(t))
+	require.NoError(t, err)
+
+	require.NotPanics(t, func() {
+		_, err = c.Decrypt("deadbeef", []byte("data"))
+	})
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestNewDecrypter(t *testing.T) {
+	key := newTestKey(t)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+	kid, data, err := c.Encrypt([]byte("record"))
+	require.NoError(t, err)
+
+	d, err := NewDecrypter(key)
+	require.NoError(t, err)
+	got, err := d.Decrypt(kid, data)
+	require.NoError(t, err)
+	assert.Equal(t, "record", string(got))
+
+	require.NotPanics(t, func() {
+		_, _, err = d.Encrypt([]byte("record"))
+	})
+	require.Error(t, err, "there is no active key")
+}
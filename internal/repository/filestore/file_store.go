@@ -6,39 +6,116 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/config"
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/KretovDmitry/shortener/internal/repository/memstore"
 )
 
+// entry is a single line of the storage file holding an encrypted or
+// chained record. Plain text records are stored as is.
+type entry struct {
+	// KeyID is the ID of the key the record is encrypted with.
+	KeyID string `json:"kid,omitempty"`
+	// Data is the nonce prepended to the encrypted record.
+	Data []byte `json:"data,omitempty"`
+	// Record is the plain text record if the entry is not encrypted.
+	Record json.RawMessage `json:"record,omitempty"`
+	// Prev is the hash of the previous line in the integrity mode.
+	Prev string `json:"prev,omitempty"`
+	// MAC authenticates the entry in the integrity mode.
+	MAC string `json:"mac,omitempty"`
+}
+
 // Producer is a struct that represents a producer for writing URL records to a file.
 type Producer struct {
 	// file is the underlying file handle for writing records.
 	file *os.File
-	// encoder is the JSON encoder used to write records to the file.
-	encoder *json.Encoder
+	// cipher encrypts records before writing. Records are written
+	// in plain text if cipher is nil.
+	cipher *Cipher
+	// chain links every written record with the previous one.
+	// Records are not chained if it is nil.
+	chain *chain
 }
 
 // NewProducer creates a new Producer instance for writing URL records to a file.
-// It takes a filepath as input and returns a Producer instance
-// along with any encountered errors.
-func NewProducer(fileName string) (*Producer, error) {
+// It takes a filepath and an optional cipher as input and returns
+// a Producer instance along with any encountered errors.
+func NewProducer(fileName string, cipher *Cipher) (*Producer, error) {
 	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
 	if err != nil {
 		return nil, err
 	}
 	return &Producer{
-		file:    file,
-		encoder: json.NewEncoder(file),
+		file:   file,
+		cipher: cipher,
 	}, nil
 }
 
-// WriteRecord writes a URL record to the file using the JSON encoder.
+// enableChain turns on the integrity mode. Every subsequent record will
+// contain the hash of the previous one and will be signed with the key.
+// The last hash and count describe the records already present in the file.
+// The checkpoint is written at once, so that it catches up after a crash.
+func (p *Producer) enableChain(key []byte, last string, count int) error {
+	p.chain = &chain{
+		key:      key,
+		headPath: headPath(p.file.Name()),
+		last:     last,
+		count:    count,
+	}
+	return p.chain.checkpoint()
+}
+
+// WriteRecord writes a URL record to the file as a single JSON line.
+// The record is encrypted if the producer has a cipher
+// and chained if the integrity mode is enabled.
 func (p *Producer) WriteRecord(record *models.URL) error {
-	return p.encoder.Encode(record)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal record: %w", err)
+	}
+
+	var e entry
+	if p.cipher != nil || p.chain != nil {
+		if p.cipher != nil {
+			if e.KeyID, e.Data, err = p.cipher.Encrypt(line); err != nil {
+				return fmt.Errorf("encrypt record: %w", err)
+			}
+		} else {
+			e.Record = line
+		}
+		if p.chain != nil {
+			if err = p.chain.seal(&e); err != nil {
+				return fmt.Errorf("seal entry: %w", err)
+			}
+		}
+		if line, err = json.Marshal(e); err != nil {
+			return fmt.Errorf("marshal entry: %w", err)
+		}
+	}
+
+	if _, err = p.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if p.chain != nil {
+		if err = p.chain.advance(e); err != nil {
+			return fmt.Errorf("advance chain: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the underlying file.
+func (p *Producer) Close() error {
+	return p.file.Close()
 }
 
 // Consumer is a struct that represents a consumer for reading URL records from a file.
@@ -47,12 +124,14 @@ type Consumer struct {
 	file *os.File
 	// decoder is the JSON decoder used to read records from the file.
 	decoder *json.Decoder
+	// cipher decrypts encrypted records.
+	cipher *Cipher
 }
 
 // NewConsumer creates a new Consumer instance for reading URL records from a file.
-// It takes a filepath as input and returns a Consumer instance
-// along with any encountered errors.
-func NewConsumer(fileName string) (*Consumer, error) {
+// It takes a filepath and an optional cipher as input and returns
+// a Consumer instance along with any encountered errors.
+func NewConsumer(fileName string, cipher *Cipher) (*Consumer, error) {
 	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0o644)
 	if err != nil {
 		return nil, err
@@ -61,19 +140,127 @@ func NewConsumer(fileName string) (*Consumer, error) {
 	return &Consumer{
 		file:    file,
 		decoder: json.NewDecoder(file),
+		cipher:  cipher,
 	}, nil
 }
 
 // ReadRecord reads a URL record from the file using the JSON decoder.
+// Plain text, encrypted and chained records are supported.
 func (c *Consumer) ReadRecord() (*models.URL, error) {
+	var line json.RawMessage
+	if err := c.decoder.Decode(&line); err != nil {
+		return nil, err
+	}
+
+	var e entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.KeyID != "":
+		if c.cipher == nil {
+			return nil, fmt.Errorf("%w: %s: encryption is not configured",
+				ErrUnknownKey, e.KeyID)
+		}
+		plaintext, err := c.cipher.Decrypt(e.KeyID, e.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt record: %w", err)
+		}
+		line = plaintext
+	case e.Record != nil:
+		line = e.Record
+	}
+
 	record := new(models.URL)
-	if err := c.decoder.Decode(record); err != nil {
+	if err := json.Unmarshal(line, record); err != nil {
 		return nil, err
 	}
 
 	return record, nil
 }
 
+// Close closes the underlying file.
+func (c *Consumer) Close() error {
+	return c.file.Close()
+}
+
+// Migrate rewrites all the records of the file. Records are read with
+// the from cipher and written with the to cipher, so it can be used to
+// encrypt, decrypt or re-encrypt the file with a new key. Any of the
+// ciphers may be nil which means plain text. If the file is protected
+// by the hash chain, it is verified and rebuilt with the integrity key.
+// The file is replaced atomically. It returns the number of records migrated.
+func Migrate(fileName string, from, to *Cipher, integrityKey []byte) (int, error) {
+	consumer, err := NewConsumer(fileName, from)
+	if err != nil {
+		return 0, fmt.Errorf("new consumer: %w", err)
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	tmpName := fileName + ".tmp"
+	producer, err := NewProducer(tmpName, to)
+	if err != nil {
+		return 0, fmt.Errorf("new producer: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpName)
+		_ = os.Remove(headPath(tmpName))
+	}()
+
+	// Keep the integrity mode if the file is chained.
+	_, err = os.Stat(headPath(fileName))
+	chained := err == nil
+	if chained {
+		if _, err = Verify(fileName, integrityKey); err != nil {
+			_ = producer.Close()
+			return 0, err
+		}
+		if err = producer.enableChain(integrityKey, genesisHash, 0); err != nil {
+			_ = producer.Close()
+			return 0, fmt.Errorf("enable chain: %w", err)
+		}
+	}
+
+	n := 0
+	for {
+		record, err := consumer.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = producer.Close()
+			return 0, fmt.Errorf("read record #%d: %w", n+1, err)
+		}
+		if err = producer.WriteRecord(record); err != nil {
+			_ = producer.Close()
+			return 0, fmt.Errorf("write record #%d: %w", n+1, err)
+		}
+		n++
+	}
+
+	if err = producer.file.Sync(); err != nil {
+		_ = producer.Close()
+		return 0, fmt.Errorf("sync file: %w", err)
+	}
+	if err = producer.Close(); err != nil {
+		return 0, fmt.Errorf("close file: %w", err)
+	}
+
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return 0, fmt.Errorf("replace file: %w", err)
+	}
+	if chained {
+		if err = os.Rename(headPath(tmpName), headPath(fileName)); err != nil {
+			return 0, fmt.Errorf("replace head: %w", err)
+		}
+	}
+
+	return n, nil
+}
+
 // FileStore is a struct that represents a file-based storage system for URL records.
 type FileStore struct {
 	// cache is an in memory instance of URL repository
@@ -97,10 +284,41 @@ func NewFileStore(config *config.Config) (*FileStore, error) {
 		config: config,
 	}
 
-	consumer, err := NewConsumer(config.FileStoragePath)
+	// Init cipher if encryption at rest is enabled.
+	var cipher *Cipher
+	if config.FileStorage.EncryptionKey != "" {
+		var err error
+		cipher, err = NewCipher(config.FileStorage.EncryptionKey,
+			config.FileStorage.DecryptionKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("new cipher: %w", err)
+		}
+	}
+
+	// Refuse to start on a tampered file in the integrity mode.
+	integrityKey := []byte(config.FileStorage.IntegrityKey)
+	verified := &VerifyResult{LastHash: genesisHash}
+	if config.FileStorage.Integrity {
+		_, err := os.Stat(config.FileStoragePath)
+		switch {
+		case err == nil:
+			if verified, err = Verify(config.FileStoragePath, integrityKey); err != nil {
+				return nil, fmt.Errorf("verify file: %w", err)
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			if _, err = os.Stat(headPath(config.FileStoragePath)); err == nil {
+				return nil, fmt.Errorf("verify file: %w: file is missing", ErrIntegrity)
+			}
+		}
+	}
+
+	consumer, err := NewConsumer(config.FileStoragePath, cipher)
 	if err != nil {
 		return nil, fmt.Errorf("new consumer: %w", err)
 	}
+	defer func() {
+		_ = consumer.Close()
+	}()
 
 	var record *models.URL
 
@@ -123,11 +341,18 @@ func NewFileStore(config *config.Config) (*FileStore, error) {
 		return fileStore, nil
 	}
 
-	producer, err := NewProducer(config.FileStoragePath)
+	producer, err := NewProducer(config.FileStoragePath, cipher)
 	if err != nil {
 		return nil, fmt.Errorf("new producer: %w", err)
 	}
 
+	if config.FileStorage.Integrity {
+		if err = producer.enableChain(integrityKey, verified.LastHash, verified.Records); err != nil {
+			_ = producer.Close()
+			return nil, fmt.Errorf("enable chain: %w", err)
+		}
+	}
+
 	fileStore.file = producer
 
 	return fileStore, nil
@@ -138,8 +363,18 @@ func (fs *FileStore) Get(ctx context.Context, sURL models.ShortURL) (*models.URL
 	return fs.cache.Get(ctx, sURL)
 }
 
+// GetByOriginalURL retrieves a URL record from the cache by its original URL.
+func (fs *FileStore) GetByOriginalURL(ctx context.Context, originalURL models.OriginalURL) (*models.URL, error) {
+	return fs.cache.GetByOriginalURL(ctx, originalURL)
+}
+
+// GetMany retrieves URL records from the cache by their short URLs.
+func (fs *FileStore) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	return fs.cache.GetMany(ctx, sURLs)
+}
+
 // GetAllByUserID retrieves all URL records belonging to a specific user from the cache.
-func (fs *FileStore) GetAllByUserID(ctx context.Context, userID string) ([]*models.URL, error) {
+func (fs *FileStore) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
 	return fs.cache.GetAllByUserID(ctx, userID)
 }
 
@@ -148,8 +383,72 @@ func (fs *FileStore) DeleteURLs(ctx context.Context, urls ...*models.URL) error
 	return fs.cache.DeleteURLs(ctx, urls...)
 }
 
+// UpdateLastAccessed sets the last access time of the given short URLs in the cache.
+func (fs *FileStore) UpdateLastAccessed(
+	ctx context.Context, accessed map[models.ShortURL]time.Time,
+) error {
+	return fs.cache.UpdateLastAccessed(ctx, accessed)
+}
+
+// SetRedirectLimit sets the redirect limit of the URL in the cache.
+// Like the deletions, the limits set after the URL is saved
+// are not persisted to the file.
+func (fs *FileStore) SetRedirectLimit(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, limit int,
+) error {
+	return fs.cache.SetRedirectLimit(ctx, userID, shortURL, limit)
+}
+
+// SetNote sets the note of the URL in the cache.
+// Like the redirect limits, the notes set after the URL is saved
+// are not persisted to the file.
+func (fs *FileStore) SetNote(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, note string,
+) error {
+	return fs.cache.SetNote(ctx, userID, shortURL, note)
+}
+
+// AddToCampaign adds the URLs to the campaign in the cache.
+// Like the notes, the campaigns are not persisted to the file.
+func (fs *FileStore) AddToCampaign(
+	ctx context.Context, userID user.ID, campaign string, shortURLs []models.ShortURL,
+) (int, error) {
+	return fs.cache.AddToCampaign(ctx, userID, campaign, shortURLs)
+}
+
+// GetCampaignURLs retrieves the URLs of the campaign from the cache.
+func (fs *FileStore) GetCampaignURLs(
+	ctx context.Context, userID user.ID, campaign string,
+) ([]*models.URL, error) {
+	return fs.cache.GetCampaignURLs(ctx, userID, campaign)
+}
+
+// TransferCampaign copies or moves the URLs between the campaigns in the cache.
+func (fs *FileStore) TransferCampaign(
+	ctx context.Context, userID user.ID, from, to string, move bool,
+) (*models.CampaignTransfer, error) {
+	return fs.cache.TransferCampaign(ctx, userID, from, to, move)
+}
+
+// SetLinkState changes the state of the URL in the cache if it is still
+// the given one. Like the notes, the states set after the URL is saved
+// are not persisted to the file.
+func (fs *FileStore) SetLinkState(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	return fs.cache.SetLinkState(ctx, userID, shortURL, from, to)
+}
+
+// DeleteExpired marks the URLs expired by now as deleted in the cache.
+func (fs *FileStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return fs.cache.DeleteExpired(ctx, now)
+}
+
 // Save writes a URL record to the cache and file if required.
 func (fs *FileStore) Save(ctx context.Context, url *models.URL) error {
+	if err := url.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
 	// check if the record already exists in the cache
 	record, err := fs.cache.Get(ctx, url.ShortURL)
 	if err != nil && !errors.Is(err, errs.ErrNotFound) {
@@ -171,6 +470,12 @@ func (fs *FileStore) Save(ctx context.Context, url *models.URL) error {
 
 // SaveAll saves multiple URL records to the cache and file if required.
 func (fs *FileStore) SaveAll(ctx context.Context, urls []*models.URL) error {
+	for _, url := range urls {
+		if err := url.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
 	for _, url := range urls {
 		// check if the record already exists in the cache
 		record, err := fs.cache.Get(ctx, url.ShortURL)
@@ -195,6 +500,47 @@ func (fs *FileStore) SaveAll(ctx context.Context, urls []*models.URL) error {
 	return nil
 }
 
+// SaveClicks saves the clicks in the cache.
+// Clicks are not persisted to the file.
+func (fs *FileStore) SaveClicks(ctx context.Context, clicks ...*models.Click) error {
+	return fs.cache.SaveClicks(ctx, clicks...)
+}
+
+// GetClickStats returns the click statistics of the short URL from the cache.
+func (fs *FileStore) GetClickStats(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.ClickStats, error) {
+	return fs.cache.GetClickStats(ctx, shortURL)
+}
+
+// Reserve saves the reservations in the cache.
+// Reservations are not persisted to the file.
+func (fs *FileStore) Reserve(ctx context.Context, reservations ...*models.Reservation) error {
+	return fs.cache.Reserve(ctx, reservations...)
+}
+
+// GetReservation retrieves the reservation of the short code from the cache.
+func (fs *FileStore) GetReservation(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.Reservation, error) {
+	return fs.cache.GetReservation(ctx, shortURL)
+}
+
+// ScanURLs calls fn for every URL record in the cache.
+func (fs *FileStore) ScanURLs(ctx context.Context, fn func(*models.URL) error) error {
+	return fs.cache.ScanURLs(ctx, fn)
+}
+
+// CountShortURLs returns the number of not deleted short URLs from the cache.
+func (fs *FileStore) CountShortURLs(ctx context.Context) (int, error) {
+	return fs.cache.CountShortURLs(ctx)
+}
+
+// CountUsers returns the number of distinct users from the cache.
+func (fs *FileStore) CountUsers(ctx context.Context) (int, error) {
+	return fs.cache.CountUsers(ctx)
+}
+
 // Ping is a placeholder method that returns an error
 // indicating that the database is not connected [ErrDBNotConnected].
 func (fs *FileStore) Ping(context.Context) error {
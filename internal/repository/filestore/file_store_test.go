@@ -0,0 +1,182 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIntegrityKey = "test-integrity-key"
+
+func newTestStore(t *testing.T, path string, opts ...func(c *config.Config)) (*FileStore, error) {
+	t.Helper()
+	c := config.NewForTest()
+	c.FileStoragePath = path
+	for _, opt := range opts {
+		opt(c)
+	}
+	store, err := NewFileStore(c)
+	if err == nil {
+		t.Cleanup(func() { _ = store.file.Close() })
+	}
+	return store, err
+}
+
+func withIntegrity(c *config.Config) {
+	c.FileStorage.Integrity = true
+	c.FileStorage.IntegrityKey = testIntegrityKey
+}
+
+func withKeys(key string, oldKeys ...string) func(c *config.Config) {
+	return func(c *config.Config) {
+		c.FileStorage.EncryptionKey = key
+		c.FileStorage.DecryptionKeys = oldKeys
+	}
+}
+
+// saveRecords saves the records numbered from..to to the file storage.
+func saveRecords(t *testing.T, store *FileStore, from, to int) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		record := models.NewRecord(fmt.Sprintf("short%d", i),
+			fmt.Sprintf("https://example.com/%d", i), "user")
+		require.NoError(t, store.Save(context.Background(), record))
+	}
+}
+
+// requireRecords checks that the records numbered 1..n are loaded.
+func requireRecords(t *testing.T, store *FileStore, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		got, err := store.Get(context.Background(), models.ShortURL(fmt.Sprintf("short%d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, models.OriginalURL(fmt.Sprintf("https://example.com/%d", i)), got.OriginalURL)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+}
+
+func TestNewFileStore_MixedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	key := newTestKey(t)
+
+	store, err := newTestStore(t, path)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	// the encryption is enabled for the existing file
+	store, err = newTestStore(t, path, withKeys(key))
+	require.NoError(t, err)
+	saveRecords(t, store, 3, 4)
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "example.com", "the existing records stay in plain text")
+	assert.NotContains(t, lines[3], "example.com", "the new records are encrypted")
+
+	store, err = newTestStore(t, path, withKeys(key))
+	require.NoError(t, err)
+	requireRecords(t, store, 4)
+
+	_, err = newTestStore(t, path)
+	require.ErrorIs(t, err, ErrUnknownKey, "the encrypted records are not skipped")
+}
+
+func TestMigrate_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+
+	store, err := newTestStore(t, path, withKeys(oldKey))
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	// the old key is still active, so a record is encrypted with it
+	// after the new one is deployed
+	store, err = newTestStore(t, path, withKeys(newKey, oldKey))
+	require.NoError(t, err)
+	saveRecords(t, store, 3, 3)
+	requireRecords(t, store, 3)
+
+	rotated, err := NewCipher(newKey, oldKey)
+	require.NoError(t, err)
+	n, err := Migrate(path, rotated, rotated, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	// the old key is not needed after the migration
+	store, err = newTestStore(t, path, withKeys(newKey))
+	require.NoError(t, err)
+	requireRecords(t, store, 3)
+
+	decrypter, err := NewDecrypter(newKey)
+	require.NoError(t, err)
+	n, err = Migrate(path, decrypter, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	store, err = newTestStore(t, path)
+	require.NoError(t, err)
+	requireRecords(t, store, 3)
+}
+
+func TestMigrate_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+
+	store, err := newTestStore(t, path, withKeys(newTestKey(t)))
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+	before := readLines(t, path)
+
+	other, err := NewCipher(newTestKey(t))
+	require.NoError(t, err)
+	_, err = Migrate(path, other, nil, nil)
+	require.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, before, readLines(t, path), "the file is left intact")
+
+	_, err = Migrate(path, nil, nil, nil)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestMigrate_Chained(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	key := newTestKey(t)
+
+	store, err := newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+	_, err = Migrate(path, nil, c, nil)
+	require.Error(t, err, "the chain can't be rebuilt without the integrity key")
+
+	n, err := Migrate(path, nil, c, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	res, err := Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Chained)
+
+	store, err = newTestStore(t, path, withIntegrity, withKeys(key))
+	require.NoError(t, err)
+	requireRecords(t, store, 2)
+}
@@ -0,0 +1,267 @@
+package filestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ErrIntegrity is returned when the file storage has been tampered with
+// or truncated.
+var ErrIntegrity = errors.New("integrity violation")
+
+// genesisHash is the previous hash of the first record in a chain.
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+// head is the checkpoint of a hash chain stored next to the file storage.
+// It allows to detect truncation of the file tail.
+type head struct {
+	// Count is the total number of records in the file.
+	Count int `json:"count"`
+	// Hash is the hash of the last record.
+	Hash string `json:"hash"`
+	// MAC authenticates the count and the hash.
+	MAC string `json:"mac"`
+}
+
+// chain keeps the state of the hash chain while appending records.
+type chain struct {
+	// key is the HMAC key of the chain.
+	key []byte
+	// headPath is the path of the checkpoint file.
+	headPath string
+	// last is the hash of the last record.
+	last string
+	// count is the total number of records in the file.
+	count int
+}
+
+// seal links the entry with the last record and signs it.
+func (c *chain) seal(e *entry) error {
+	e.Prev = c.last
+	mac, err := signEntry(c.key, *e)
+	if err != nil {
+		return err
+	}
+	e.MAC = mac
+	return nil
+}
+
+// advance moves the chain forward by the written entry
+// and persists the new checkpoint.
+func (c *chain) advance(e entry) error {
+	c.last = e.MAC
+	c.count++
+	return c.checkpoint()
+}
+
+// checkpoint persists the current state of the chain.
+func (c *chain) checkpoint() error {
+	return writeHead(c.headPath, c.key, c.count, c.last)
+}
+
+// VerifyResult is the report of the file storage integrity check.
+type VerifyResult struct {
+	// Records is the total number of records in the file.
+	Records int
+	// Chained is the number of records protected by the hash chain.
+	Chained int
+	// LastHash is the hash of the last record.
+	LastHash string
+	// Recovered reports that the checkpoint was one record behind, which
+	// happens if the process stopped between writing the last record and
+	// its checkpoint. The record itself is authenticated by its MAC.
+	Recovered bool
+	// prevHash is the hash of the record before the last one
+	// if the last one is chained.
+	prevHash string
+}
+
+// Verify checks the hash chain of the file storage with the given HMAC key.
+// Records written before the integrity mode had been enabled are allowed
+// only at the beginning of the file and are covered by the first chained
+// record or the checkpoint. It returns ErrIntegrity if a record was
+// modified, removed or the file was truncated, including when the chain
+// was stripped along with its checkpoint. The records are not decrypted,
+// so no encryption keys are required to verify the file.
+func Verify(fileName string, key []byte) (*VerifyResult, error) {
+	res, err := scan(fileName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := readHead(headPath(fileName))
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && res.Records == 0:
+		return res, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("%w: checkpoint is missing", ErrIntegrity)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %w", ErrIntegrity, err)
+	}
+	if !hmac.Equal([]byte(h.MAC), []byte(signHead(key, h.Count, h.Hash))) {
+		return nil, fmt.Errorf("%w: checkpoint is modified", ErrIntegrity)
+	}
+
+	switch {
+	case h.Count == res.Records && h.Hash == res.LastHash:
+	case h.Count == res.Records-1 && res.prevHash != "" && h.Hash == res.prevHash:
+		res.Recovered = true
+	default:
+		return nil, fmt.Errorf("%w: file does not match the checkpoint: %d records expected, %d found",
+			ErrIntegrity, h.Count, res.Records)
+	}
+
+	return res, nil
+}
+
+// Protect starts the hash chain of the file storage written without
+// the integrity mode. The records already present are covered by
+// the checkpoint, so the file is not rewritten. It returns the number
+// of the covered records.
+func Protect(fileName string, key []byte) (int, error) {
+	if _, err := os.Stat(headPath(fileName)); err == nil {
+		return 0, fmt.Errorf("%s is already protected", fileName)
+	}
+
+	res, err := scan(fileName, key)
+	if err != nil {
+		return 0, err
+	}
+	if res.Chained > 0 {
+		return 0, fmt.Errorf("%w: checkpoint is missing", ErrIntegrity)
+	}
+
+	if err = writeHead(headPath(fileName), key, res.Records, res.LastHash); err != nil {
+		return 0, err
+	}
+
+	return res.Records, nil
+}
+
+// scan walks the hash chain of the file storage without the checkpoint.
+func scan(fileName string, key []byte) (*VerifyResult, error) {
+	if len(key) == 0 {
+		return nil, errors.New("integrity key is not set")
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	res := &VerifyResult{LastHash: genesisHash}
+	decoder := json.NewDecoder(file)
+
+	for {
+		var line json.RawMessage
+		if err = decoder.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("%w: record #%d: %w", ErrIntegrity, res.Records+1, err)
+		}
+		res.Records++
+
+		var e entry
+		if err = json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%w: record #%d: %w", ErrIntegrity, res.Records, err)
+		}
+
+		if e.Prev == "" {
+			if res.Chained > 0 {
+				return nil, fmt.Errorf("%w: record #%d is not chained",
+					ErrIntegrity, res.Records)
+			}
+			// the records written before the integrity mode are folded
+			// into a single hash, so that any of them is covered
+			res.LastHash = sign(key, append([]byte(res.LastHash), line...))
+			res.prevHash = ""
+			continue
+		}
+
+		if e.Prev != res.LastHash {
+			return nil, fmt.Errorf("%w: record #%d does not match the previous one",
+				ErrIntegrity, res.Records)
+		}
+		var mac string
+		if mac, err = signEntry(key, e); err != nil {
+			return nil, fmt.Errorf("%w: record #%d: %w", ErrIntegrity, res.Records, err)
+		}
+		if !hmac.Equal([]byte(mac), []byte(e.MAC)) {
+			return nil, fmt.Errorf("%w: record #%d is modified", ErrIntegrity, res.Records)
+		}
+		res.Chained++
+		res.LastHash, res.prevHash = e.MAC, res.LastHash
+	}
+
+	return res, nil
+}
+
+// sign returns hex encoded HMAC-SHA256 of the data.
+func sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signEntry returns the MAC of the entry, which covers
+// the record and the hash of the previous one.
+func signEntry(key []byte, e entry) (string, error) {
+	e.MAC = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("marshal entry: %w", err)
+	}
+	return sign(key, b), nil
+}
+
+// signHead returns the MAC of the checkpoint.
+func signHead(key []byte, count int, hash string) string {
+	return sign(key, []byte(fmt.Sprintf("%d:%s", count, hash)))
+}
+
+// headPath returns the path of the checkpoint file for the file storage.
+func headPath(fileName string) string {
+	return fileName + ".head"
+}
+
+// readHead reads the checkpoint file.
+func readHead(path string) (head, error) {
+	var h head
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return h, fmt.Errorf("read head: %w", err)
+	}
+	if err = json.Unmarshal(b, &h); err != nil {
+		return h, fmt.Errorf("decode head: %w", err)
+	}
+
+	return h, nil
+}
+
+// writeHead atomically replaces the checkpoint file.
+func writeHead(path string, key []byte, count int, hash string) error {
+	b, err := json.Marshal(head{Count: count, Hash: hash, MAC: signHead(key, count, hash)})
+	if err != nil {
+		return fmt.Errorf("encode head: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write head: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,204 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 3)
+
+	res, err := Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Records)
+	assert.Equal(t, 3, res.Chained)
+	assert.False(t, res.Recovered)
+
+	_, err = Verify(path, nil)
+	require.Error(t, err, "the key is required")
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, path string, lines []string)
+	}{
+		{
+			name: "modified record",
+			tamper: func(t *testing.T, path string, lines []string) {
+				lines[0] = strings.Replace(lines[0], "example.com", "example.org", 1)
+				writeLines(t, path, lines)
+			},
+		},
+		{
+			name: "removed record",
+			tamper: func(t *testing.T, path string, lines []string) {
+				writeLines(t, path, append(lines[:1], lines[2:]...))
+			},
+		},
+		{
+			name: "truncated file",
+			tamper: func(t *testing.T, path string, lines []string) {
+				writeLines(t, path, lines[:2])
+			},
+		},
+		{
+			name: "truncated file and checkpoint",
+			tamper: func(t *testing.T, path string, lines []string) {
+				var e entry
+				require.NoError(t, json.Unmarshal([]byte(lines[2]), &e))
+				writeLines(t, path, lines[:2])
+				require.NoError(t, writeHead(headPath(path), []byte("another-key"), 2, e.Prev))
+			},
+		},
+		{
+			name: "appended record",
+			tamper: func(t *testing.T, path string, lines []string) {
+				var e entry
+				require.NoError(t, json.Unmarshal([]byte(lines[2]), &e))
+				forged := entry{
+					Record: json.RawMessage(`{"short_url":"forged","original_url":"https://example.org"}`),
+					Prev:   e.MAC,
+					MAC:    e.MAC,
+				}
+				b, err := json.Marshal(forged)
+				require.NoError(t, err)
+				writeLines(t, path, append(lines, string(b)))
+			},
+		},
+		{
+			name: "stripped chain",
+			tamper: func(t *testing.T, path string, lines []string) {
+				for i, line := range lines {
+					var e entry
+					require.NoError(t, json.Unmarshal([]byte(line), &e))
+					lines[i] = string(e.Record)
+				}
+				writeLines(t, path, lines)
+				require.NoError(t, os.Remove(headPath(path)))
+			},
+		},
+		{
+			name: "removed checkpoint",
+			tamper: func(t *testing.T, path string, _ []string) {
+				require.NoError(t, os.Remove(headPath(path)))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.json")
+			store, err := newTestStore(t, path, withIntegrity)
+			require.NoError(t, err)
+			saveRecords(t, store, 1, 3)
+
+			tt.tamper(t, path, readLines(t, path))
+
+			_, err = Verify(path, []byte(testIntegrityKey))
+			require.ErrorIs(t, err, ErrIntegrity)
+
+			_, err = newTestStore(t, path, withIntegrity)
+			require.ErrorIs(t, err, ErrIntegrity, "the server refuses to start")
+		})
+	}
+}
+
+func TestVerify_WrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	_, err = Verify(path, []byte("another-key"))
+	require.ErrorIs(t, err, ErrIntegrity)
+}
+
+func TestVerify_CrashRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	// the process stops after the record is written, but before its checkpoint
+	checkpoint, err := os.ReadFile(headPath(path))
+	require.NoError(t, err)
+	saveRecords(t, store, 3, 3)
+	require.NoError(t, os.WriteFile(headPath(path), checkpoint, 0o644))
+
+	res, err := Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Records)
+	assert.True(t, res.Recovered)
+
+	// the checkpoint catches up on the start
+	store, err = newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	_, err = store.Get(context.Background(), "short3")
+	require.NoError(t, err)
+
+	res, err = Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.False(t, res.Recovered)
+
+	saveRecords(t, store, 4, 4)
+	res, err = Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 4, res.Records)
+}
+
+func TestProtect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := newTestStore(t, path)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 2)
+
+	// the mode can't be enabled on the file written without it,
+	// since it's not distinguishable from the stripped chain
+	_, err = newTestStore(t, path, withIntegrity)
+	require.ErrorIs(t, err, ErrIntegrity)
+
+	n, err := Protect(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = Protect(path, []byte(testIntegrityKey))
+	require.Error(t, err, "already protected")
+
+	store, err = newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 3, 3)
+
+	res, err := Verify(path, []byte(testIntegrityKey))
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Records)
+	assert.Equal(t, 1, res.Chained)
+
+	// the records written before the mode are covered as well
+	lines := readLines(t, path)
+	lines[0] = strings.Replace(lines[0], "example.com", "example.org", 1)
+	writeLines(t, path, lines)
+	_, err = Verify(path, []byte(testIntegrityKey))
+	require.ErrorIs(t, err, ErrIntegrity)
+}
+
+func TestNewFileStore_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := newTestStore(t, path, withIntegrity)
+	require.NoError(t, err)
+	saveRecords(t, store, 1, 1)
+
+	require.NoError(t, os.Remove(path))
+
+	_, err = newTestStore(t, path, withIntegrity)
+	require.ErrorIs(t, err, ErrIntegrity)
+}
@@ -3,10 +3,14 @@ package memstore
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 )
 
 // URLRepository is an in-memory implementation of the URLStorage interface.
@@ -15,6 +19,28 @@ import (
 type URLRepository struct {
 	// store is a map that stores the URLs.
 	store map[models.ShortURL]models.URL
+	// reservations is a map that stores the reserved short codes.
+	reservations map[models.ShortURL]models.Reservation
+	// clicks is a map that stores the redirects of the short URLs.
+	clicks map[models.ShortURL][]models.Click
+	// apiKeys is a map that stores the API keys by their hashes.
+	apiKeys map[string]models.APIKey
+	// accounts is a map that stores the accounts by their logins.
+	accounts map[string]models.Account
+	// revoked is a map that stores the expirations of the revoked tokens
+	// by their IDs.
+	revoked map[string]time.Time
+	// bans is a map that stores the banned domains.
+	bans map[string]models.DomainBan
+	// idempotent is a map that stores the idempotent responses.
+	idempotent map[idempotencyKey]models.IdempotentResponse
+	// trends is a map that stores the creation rollups by their days.
+	trends map[string]*trend
+	// campaigns is a map that stores the short URLs of the campaigns.
+	campaigns map[campaignKey]map[models.ShortURL]struct{}
+	// shares is a map that stores the shares of the short URLs
+	// in the order they were granted.
+	shares map[models.ShortURL][]models.URLShare
 	// mu is a mutex that protects the store map from concurrent access.
 	mu sync.RWMutex
 }
@@ -22,7 +48,19 @@ type URLRepository struct {
 // NewInMemoryStore creates a new instance of the InMemoryStore.
 // It initializes an empty map to store the URLs.
 func NewURLRepository() *URLRepository {
-	return &URLRepository{store: make(map[models.ShortURL]models.URL)}
+	return &URLRepository{
+		store:        make(map[models.ShortURL]models.URL),
+		reservations: make(map[models.ShortURL]models.Reservation),
+		clicks:       make(map[models.ShortURL][]models.Click),
+		apiKeys:      make(map[string]models.APIKey),
+		accounts:     make(map[string]models.Account),
+		revoked:      make(map[string]time.Time),
+		bans:         make(map[string]models.DomainBan),
+		idempotent:   make(map[idempotencyKey]models.IdempotentResponse),
+		trends:       make(map[string]*trend),
+		campaigns:    make(map[campaignKey]map[models.ShortURL]struct{}),
+		shares:       make(map[models.ShortURL][]models.URLShare),
+	}
 }
 
 // Get retrieves a URL by its short URL.
@@ -39,9 +77,52 @@ func (r *URLRepository) Get(_ context.Context, sURL models.ShortURL) (*models.UR
 	return &record, nil
 }
 
+// GetByOriginalURL retrieves a URL by its original URL. The store does not
+// keep the original URLs unique, so the one with the least short URL is
+// returned if there are several. If the URL is not found, it returns
+// ErrNotFound.
+func (r *URLRepository) GetByOriginalURL(_ context.Context, originalURL models.OriginalURL) (*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *models.URL
+	for _, record := range r.store {
+		record := record // for Go versions below 1.22
+		if record.OriginalURL == originalURL && (found == nil || record.ShortURL < found.ShortURL) {
+			found = &record
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: %w", originalURL, errs.ErrNotFound)
+	}
+
+	return found, nil
+}
+
+// GetMany retrieves the URLs by their short URLs.
+// Short URLs that are not found or repeated are skipped.
+func (r *URLRepository) GetMany(_ context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*models.URL, 0, len(sURLs))
+	seen := make(map[models.ShortURL]struct{}, len(sURLs))
+	for _, sURL := range sURLs {
+		if _, ok := seen[sURL]; ok {
+			continue
+		}
+		seen[sURL] = struct{}{}
+		if record, found := r.store[sURL]; found {
+			all = append(all, &record)
+		}
+	}
+
+	return all, nil
+}
+
 // GetAllByUserID retrieves all URLs belonging to a specific user.
 // If no URLs are found for the specified user, it returns ErrNotFound.
-func (r *URLRepository) GetAllByUserID(_ context.Context, userID string) ([]*models.URL, error) {
+func (r *URLRepository) GetAllByUserID(_ context.Context, userID user.ID) ([]*models.URL, error) {
 	r.mu.RLock()
 
 	all := make([]*models.URL, 0)
@@ -67,49 +148,940 @@ func (r *URLRepository) DeleteURLs(_ context.Context, urls ...*models.URL) error
 	r.mu.Lock()
 
 	for _, url := range urls {
-		for shortURL, record := range r.store {
-			if record.UserID == url.UserID {
-				record.IsDeleted = true
-				r.store[shortURL] = record
-				break
-			}
+		record, ok := r.store[url.ShortURL]
+		if !ok || record.UserID != url.UserID {
+			continue
 		}
+		record.IsDeleted = true
+		r.store[url.ShortURL] = record
 	}
 
 	r.mu.Unlock()
 	return nil
 }
 
+// UpdateLastAccessed sets the last access time of the given short URLs.
+// Older times never overwrite newer ones. Unknown short URLs are ignored.
+func (r *URLRepository) UpdateLastAccessed(
+	_ context.Context, accessed map[models.ShortURL]time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for shortURL, accessedAt := range accessed {
+		record, ok := r.store[shortURL]
+		if !ok {
+			continue
+		}
+		if record.LastAccessedAt != nil && record.LastAccessedAt.After(accessedAt) {
+			continue
+		}
+		accessedAt := accessedAt // for Go versions below 1.22
+		record.LastAccessedAt = &accessedAt
+		r.store[shortURL] = record
+	}
+
+	return nil
+}
+
+// SetRedirectLimit sets the redirects allowed per minute for the URL
+// of the user. If the user has no such URL, it returns ErrNotFound.
+func (r *URLRepository) SetRedirectLimit(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, limit int,
+) error {
+	if err := models.ValidateRedirectLimit(limit); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[shortURL]
+	if !ok || record.UserID != userID {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	record.RedirectLimit = limit
+	r.store[shortURL] = record
+
+	return nil
+}
+
+// SetNote sets the note of the URL of the user.
+// If the user has no such URL, it returns ErrNotFound.
+func (r *URLRepository) SetNote(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, note string,
+) error {
+	if err := models.ValidateNote(note); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[shortURL]
+	if !ok || record.UserID != userID {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	record.Note = note
+	r.store[shortURL] = record
+
+	return nil
+}
+
+// UpdateURL applies the update to the URL of the user
+// and increments its version.
+func (r *URLRepository) UpdateURL(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, update *models.URLUpdate,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[shortURL]
+	if !ok || record.UserID != userID || record.IsDeleted {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if record.Version != update.Version {
+		return fmt.Errorf("%s: %w", shortURL, models.ErrStaleVersion)
+	}
+	record.OriginalURL = update.OriginalURL
+	record.ExpiresAt = update.ExpiresAt
+	record.Version++
+	r.store[shortURL] = record
+
+	return nil
+}
+
+// campaignKey identifies the campaign of the user.
+type campaignKey struct {
+	userID user.ID
+	name   string
+}
+
+// AddToCampaign adds the URLs of the user to the campaign and returns
+// the number of the added ones. Short URLs of other users, deleted
+// or already in the campaign are skipped.
+func (r *URLRepository) AddToCampaign(
+	_ context.Context, userID user.ID, campaign string, shortURLs []models.ShortURL,
+) (int, error) {
+	if err := models.ValidateCampaign(campaign); err != nil {
+		return 0, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := campaignKey{userID: userID, name: campaign}
+	set, ok := r.campaigns[k]
+	if !ok {
+		set = make(map[models.ShortURL]struct{})
+	}
+	n := 0
+	for _, shortURL := range shortURLs {
+		record, ok := r.store[shortURL]
+		if !ok || record.UserID != userID || record.IsDeleted {
+			continue
+		}
+		if _, ok = set[shortURL]; ok {
+			continue
+		}
+		set[shortURL] = struct{}{}
+		n++
+	}
+	if len(set) > 0 {
+		r.campaigns[k] = set
+	}
+
+	return n, nil
+}
+
+// GetCampaignURLs retrieves the URLs of the user in the campaign,
+// excluding the deleted ones.
+func (r *URLRepository) GetCampaignURLs(
+	_ context.Context, userID user.ID, campaign string,
+) ([]*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*models.URL, 0)
+	for shortURL := range r.campaigns[campaignKey{userID: userID, name: campaign}] {
+		record, ok := r.store[shortURL]
+		if !ok || record.IsDeleted {
+			continue
+		}
+		all = append(all, &record)
+	}
+
+	return all, nil
+}
+
+// TransferCampaign copies the URLs of the user in the campaign from
+// to the campaign to, skipping the ones whose destinations are already
+// in it. If move is set, the URLs are removed from the campaign from.
+func (r *URLRepository) TransferCampaign(
+	_ context.Context, userID user.ID, from, to string, move bool,
+) (*models.CampaignTransfer, error) {
+	for _, campaign := range []string{from, to} {
+		if err := models.ValidateCampaign(campaign); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source := r.campaigns[campaignKey{userID: userID, name: from}]
+	targetKey := campaignKey{userID: userID, name: to}
+	target, ok := r.campaigns[targetKey]
+	if !ok {
+		target = make(map[models.ShortURL]struct{})
+	}
+
+	destinations := make(map[models.OriginalURL]struct{}, len(target))
+	for shortURL := range target {
+		destinations[r.store[shortURL].OriginalURL] = struct{}{}
+	}
+
+	var res models.CampaignTransfer
+	for shortURL := range source {
+		record, ok := r.store[shortURL]
+		if !ok || record.IsDeleted {
+			continue
+		}
+		if _, ok = destinations[record.OriginalURL]; ok {
+			res.Skipped++
+			continue
+		}
+		destinations[record.OriginalURL] = struct{}{}
+		target[shortURL] = struct{}{}
+		res.Copied++
+	}
+	if len(target) > 0 {
+		r.campaigns[targetKey] = target
+	}
+
+	if move && from != to {
+		res.Removed = len(source)
+		delete(r.campaigns, campaignKey{userID: userID, name: from})
+	}
+
+	return &res, nil
+}
+
+// TransferURL hands the URL of the user over to the new owner,
+// dropping its shares and removing it from the campaigns of the user.
+// If the user has no such URL or it is deleted, it returns ErrNotFound.
+func (r *URLRepository) TransferURL(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, to user.ID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[shortURL]
+	if !ok || record.UserID != userID || record.IsDeleted {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	record.UserID = to
+	r.store[shortURL] = record
+
+	delete(r.shares, shortURL)
+	for k, set := range r.campaigns {
+		if k.userID != userID {
+			continue
+		}
+		delete(set, shortURL)
+		if len(set) == 0 {
+			delete(r.campaigns, k)
+		}
+	}
+
+	return nil
+}
+
+// ShareURL grants the read access to the URL of the user, keeping
+// the original share if it is shared already. If the user has no such URL
+// or it is deleted, it returns ErrNotFound.
+func (r *URLRepository) ShareURL(_ context.Context, userID user.ID, share *models.URLShare) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[share.ShortURL]
+	if !ok || record.UserID != userID || record.IsDeleted {
+		return fmt.Errorf("%s: %w", share.ShortURL, errs.ErrNotFound)
+	}
+	for _, s := range r.shares[share.ShortURL] {
+		if s.UserID == share.UserID {
+			return nil
+		}
+	}
+	r.shares[share.ShortURL] = append(r.shares[share.ShortURL], *share)
+
+	return nil
+}
+
+// UnshareURL revokes the read access to the URL of the user.
+// If the URL is not shared with the user, it returns ErrNotFound.
+func (r *URLRepository) UnshareURL(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, with user.ID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.store[shortURL]; ok && record.UserID == userID {
+		shares := r.shares[shortURL]
+		for i, s := range shares {
+			if s.UserID != with {
+				continue
+			}
+			if shares = append(shares[:i], shares[i+1:]...); len(shares) == 0 {
+				delete(r.shares, shortURL)
+			} else {
+				r.shares[shortURL] = shares
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s shared with %s: %w", shortURL, with, errs.ErrNotFound)
+}
+
+// GetShares returns the shares of the URL of the user in the order
+// they were granted. If the user has no such URL, it returns ErrNotFound.
+func (r *URLRepository) GetShares(
+	_ context.Context, userID user.ID, shortURL models.ShortURL,
+) ([]*models.URLShare, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if record, ok := r.store[shortURL]; !ok || record.UserID != userID {
+		return nil, fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	all := make([]*models.URLShare, 0, len(r.shares[shortURL]))
+	for _, s := range r.shares[shortURL] {
+		s := s // for Go versions below 1.22
+		all = append(all, &s)
+	}
+
+	return all, nil
+}
+
+// GetSharedURLs retrieves the URLs shared with the user,
+// excluding the deleted ones.
+func (r *URLRepository) GetSharedURLs(_ context.Context, userID user.ID) ([]*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*models.URL, 0)
+	for shortURL, shares := range r.shares {
+		for _, s := range shares {
+			if s.UserID != userID {
+				continue
+			}
+			if record, ok := r.store[shortURL]; ok && !record.IsDeleted {
+				all = append(all, &record)
+			}
+			break
+		}
+	}
+
+	return all, nil
+}
+
+// SetLinkState changes the state of the URL of the user if it is still
+// the given one. If the user has no such URL, it returns ErrNotFound,
+// if its state has changed, ErrConflict.
+func (r *URLRepository) SetLinkState(
+	_ context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	for _, state := range []models.LinkState{from, to} {
+		if err := models.ValidateStoredState(state); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.store[shortURL]
+	if !ok || record.UserID != userID {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if record.StoredState() != from {
+		return fmt.Errorf("%s: state is not %s: %w", shortURL, from, errs.ErrConflict)
+	}
+	record.State = to
+	r.store[shortURL] = record
+
+	return nil
+}
+
+// DeleteExpired marks the URLs expired by now as deleted
+// and returns their number.
+func (r *URLRepository) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for shortURL, record := range r.store {
+		if record.IsDeleted || !record.IsExpired(now) {
+			continue
+		}
+		record.IsDeleted = true
+		r.store[shortURL] = record
+		n++
+	}
+
+	return n, nil
+}
+
 // Save saves a URL to the store.
 // If a URL with the same short URL already exists in the store, it returns ErrConflict.
 func (r *URLRepository) Save(_ context.Context, u *models.URL) error {
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
 	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, ok := r.store[u.ShortURL]; ok {
 		return errs.ErrConflict
 	}
-	r.store[u.ShortURL] = *u
-	r.mu.Unlock()
+	now := time.Now()
+	r.store[u.ShortURL] = created(*u, now)
+	r.recordCreated(u, now)
 
 	return nil
 }
 
 // SaveAll saves multiple URLs to the store.
-// If a URL with the same short URL already exists in the store, it returns ErrConflict.
-func (r *URLRepository) SaveAll(_ context.Context, u []*models.URL) error {
-	r.mu.Lock()
+// URLs with the short URLs already in the store are skipped.
+func (r *URLRepository) SaveAll(ctx context.Context, u []*models.URL) error {
+	_, err := r.SaveAllResults(ctx, u)
+	return err
+}
+
+// SaveAllResults saves multiple URLs to the store and returns the outcome
+// of every URL in the batch order. URLs with the short URLs already in the
+// store, including the ones repeated in the batch, are skipped.
+// Nothing is saved if any of the URLs is invalid.
+func (r *URLRepository) SaveAllResults(_ context.Context, u []*models.URL) ([]models.SaveResult, error) {
 	for _, u := range u {
+		if err := u.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]models.SaveResult, len(u))
+	for i, u := range u {
+		results[i] = models.SaveResult{ShortURL: u.ShortURL, Status: models.SaveStatusExists}
 		if _, ok := r.store[u.ShortURL]; ok {
-			return errs.ErrConflict
+			continue
 		}
-		r.store[u.ShortURL] = *u
+		now := time.Now()
+		r.store[u.ShortURL] = created(*u, now)
+		r.recordCreated(u, now)
+		results[i].Status = models.SaveStatusCreated
+	}
+
+	return results, nil
+}
+
+// Reserve saves the reservations. If any of the codes is already reserved
+// or used by a URL, ErrConflict is returned and nothing is saved.
+func (r *URLRepository) Reserve(_ context.Context, reservations ...*models.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, res := range reservations {
+		if _, ok := r.store[res.ShortURL]; ok {
+			return fmt.Errorf("%s: %w", res.ShortURL, errs.ErrConflict)
+		}
+		if _, ok := r.reservations[res.ShortURL]; ok {
+			return fmt.Errorf("%s: %w", res.ShortURL, errs.ErrConflict)
+		}
+	}
+
+	for _, res := range reservations {
+		res := *res
+		if res.CreatedAt.IsZero() {
+			res.CreatedAt = time.Now().UTC()
+		}
+		r.reservations[res.ShortURL] = res
+	}
+
+	return nil
+}
+
+// GetReservation retrieves the reservation of the short code.
+// If the code is not reserved, ErrNotFound is returned.
+func (r *URLRepository) GetReservation(
+	_ context.Context, shortURL models.ShortURL,
+) (*models.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res, ok := r.reservations[shortURL]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return &res, nil
+}
+
+// SaveClicks saves the clicks.
+func (r *URLRepository) SaveClicks(_ context.Context, clicks ...*models.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range clicks {
+		r.clicks[c.ShortURL] = append(r.clicks[c.ShortURL], *c)
 	}
-	r.mu.Unlock()
 
 	return nil
 }
 
+// GetClickStats returns the click statistics of the short URL.
+func (r *URLRepository) GetClickStats(
+	_ context.Context, shortURL models.ShortURL,
+) (*models.ClickStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return models.NewClickStats(shortURL, r.clicks[shortURL]), nil
+}
+
+// CountShortURLs returns the number of not deleted short URLs.
+func (r *URLRepository) CountShortURLs(_ context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for _, record := range r.store {
+		if !record.IsDeleted {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// CountUsers returns the number of distinct users owning at least one URL.
+func (r *URLRepository) CountUsers(_ context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make(map[user.ID]struct{})
+	for _, record := range r.store {
+		if !record.IsDeleted {
+			users[record.UserID] = struct{}{}
+		}
+	}
+
+	return len(users), nil
+}
+
 // Ping is a placeholder method that returns an error
 // indicating that the database is not connected [ErrDBNotConnected].
 func (r *URLRepository) Ping(_ context.Context) error {
 	return errs.ErrDBNotConnected
 }
+
+// CreateAccount saves the account. If the login is taken or the user
+// already has an account, ErrConflict is returned.
+func (r *URLRepository) CreateAccount(_ context.Context, account *models.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[account.Login]; ok {
+		return fmt.Errorf("login %s: %w", account.Login, errs.ErrConflict)
+	}
+	for _, a := range r.accounts {
+		if a.UserID == account.UserID {
+			return fmt.Errorf("user %s: %w", account.UserID, errs.ErrConflict)
+		}
+	}
+	r.accounts[account.Login] = *account
+
+	return nil
+}
+
+// GetAccount retrieves the account by its login.
+// If the login is unknown, ErrNotFound is returned.
+func (r *URLRepository) GetAccount(_ context.Context, login string) (*models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[login]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return &account, nil
+}
+
+// RevokeToken revokes the token with the ID until it expires. If the token
+// is already revoked, ErrConflict is returned. The expired tokens are
+// forgotten.
+func (r *URLRepository) RevokeToken(_ context.Context, tokenID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range r.revoked {
+		if exp.Before(now) {
+			delete(r.revoked, id)
+		}
+	}
+	if _, ok := r.revoked[tokenID]; ok {
+		return fmt.Errorf("token %s: %w", tokenID, errs.ErrConflict)
+	}
+	r.revoked[tokenID] = expiresAt
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token with the ID is revoked.
+func (r *URLRepository) IsTokenRevoked(_ context.Context, tokenID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.revoked[tokenID]
+	return ok, nil
+}
+
+// BanDomain bans the domain. If the domain is already banned,
+// ErrConflict is returned.
+func (r *URLRepository) BanDomain(_ context.Context, ban *models.DomainBan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.bans[ban.Domain]; ok {
+		return fmt.Errorf("domain %s: %w", ban.Domain, errs.ErrConflict)
+	}
+	r.bans[ban.Domain] = *ban
+
+	return nil
+}
+
+// GetDomainBan retrieves the ban of the host or of its parent domain.
+// If the host is not banned, it returns ErrNotFound.
+func (r *URLRepository) GetDomainBan(_ context.Context, host string) (*models.DomainBan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, domain := range hostpolicy.Domains(host) {
+		if ban, ok := r.bans[domain]; ok {
+			return &ban, nil
+		}
+	}
+
+	return nil, fmt.Errorf("host %s: %w", host, errs.ErrNotFound)
+}
+
+// ListDomainBans returns the banned domains in the order they were banned.
+func (r *URLRepository) ListDomainBans(_ context.Context) ([]*models.DomainBan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bans := make([]*models.DomainBan, 0, len(r.bans))
+	for _, ban := range r.bans {
+		ban := ban // for Go versions below 1.22
+		bans = append(bans, &ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].CreatedAt.Equal(bans[j].CreatedAt) {
+			return bans[i].Domain < bans[j].Domain
+		}
+		return bans[i].CreatedAt.Before(bans[j].CreatedAt)
+	})
+
+	return bans, nil
+}
+
+// GetFlaggedURLs retrieves the blocked URLs, excluding the deleted ones,
+// ordered by their short URLs.
+func (r *URLRepository) GetFlaggedURLs(_ context.Context) ([]*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	flagged := make([]*models.URL, 0)
+	for _, record := range r.store {
+		record := record // for Go versions below 1.22
+		if record.State == models.LinkBlocked && !record.IsDeleted {
+			flagged = append(flagged, &record)
+		}
+	}
+	sort.Slice(flagged, func(i, j int) bool {
+		return flagged[i].ShortURL < flagged[j].ShortURL
+	})
+
+	return flagged, nil
+}
+
+// SaveAPIKey saves the API key.
+func (r *URLRepository) SaveAPIKey(_ context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.apiKeys[key.Hash] = *key
+
+	return nil
+}
+
+// GetAPIKey retrieves the API key by its hash.
+// If the key is unknown, ErrNotFound is returned.
+func (r *URLRepository) GetAPIKey(_ context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.apiKeys[hash]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns the API keys of the user in the order they were minted.
+func (r *URLRepository) ListAPIKeys(_ context.Context, userID user.ID) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*models.APIKey
+	for _, key := range r.apiKeys {
+		if key.UserID == userID {
+			key := key
+			keys = append(keys, &key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+// RotateAPIKey replaces the hash of the API key of the user.
+// If the user has no key with the ID, ErrNotFound is returned.
+func (r *URLRepository) RotateAPIKey(_ context.Context, userID user.ID, id, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for old, key := range r.apiKeys {
+		if key.ID == id && key.UserID == userID {
+			delete(r.apiKeys, old)
+			key.Hash = hash
+			r.apiKeys[hash] = key
+			return nil
+		}
+	}
+
+	return errs.ErrNotFound
+}
+
+// RecordAPIKeyUse counts the request with the API key
+// and sets the time of its last use. Unknown keys are skipped.
+func (r *URLRepository) RecordAPIKeyUse(_ context.Context, hash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[hash]
+	if !ok {
+		return nil
+	}
+	key.Requests++
+	if key.LastUsedAt == nil || at.After(*key.LastUsedAt) {
+		key.LastUsedAt = &at
+	}
+	r.apiKeys[hash] = key
+
+	return nil
+}
+
+// idempotencyKey identifies the idempotent response.
+type idempotencyKey struct {
+	userID user.ID
+	key    string
+}
+
+// SaveIdempotentResponse saves the response until it expires.
+// If the unexpired response of the user with the same key is already
+// saved, ErrConflict is returned. Expired responses are overwritten.
+func (r *URLRepository) SaveIdempotentResponse(_ context.Context, resp *models.IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := idempotencyKey{userID: resp.UserID, key: resp.Key}
+	if saved, ok := r.idempotent[k]; ok && time.Now().Before(saved.ExpiresAt) {
+		return errs.ErrConflict
+	}
+
+	r.idempotent[k] = *resp
+
+	return nil
+}
+
+// GetIdempotentResponse retrieves the unexpired response of the user
+// by the key. If there is none, ErrNotFound is returned.
+func (r *URLRepository) GetIdempotentResponse(
+	_ context.Context, userID user.ID, key string,
+) (*models.IdempotentResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := idempotencyKey{userID: userID, key: key}
+	resp, ok := r.idempotent[k]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	if !time.Now().Before(resp.ExpiresAt) {
+		delete(r.idempotent, k)
+		return nil, errs.ErrNotFound
+	}
+
+	return &resp, nil
+}
+
+// ScanURLs calls fn for every URL record, including the deleted ones.
+// The records are copied first, so fn may use the repository.
+func (r *URLRepository) ScanURLs(_ context.Context, fn func(*models.URL) error) error {
+	r.mu.RLock()
+	all := make([]models.URL, 0, len(r.store))
+	for _, u := range r.store {
+		all = append(all, u)
+	}
+	r.mu.RUnlock()
+
+	for i := range all {
+		if err := fn(&all[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ScanUserURLs calls fn for every URL of the user, including the deleted
+// ones, ordered by their short URLs, with the number of their clicks.
+// The URLs are copied first, so fn may access the store.
+func (r *URLRepository) ScanUserURLs(
+	_ context.Context, userID user.ID, fn func(u *models.URL, clicks int) error,
+) error {
+	r.mu.RLock()
+	all := make([]models.URL, 0)
+	clicks := make(map[models.ShortURL]int)
+	for _, u := range r.store {
+		if u.UserID == userID {
+			all = append(all, u)
+			clicks[u.ShortURL] = len(r.clicks[u.ShortURL])
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].ShortURL < all[j].ShortURL })
+	for i := range all {
+		if err := fn(&all[i], clicks[all[i].ShortURL]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trend is the creation rollup of the day.
+type trend struct {
+	created int
+	users   map[user.ID]struct{}
+}
+
+// recordCreated adds the URL to the rollup of the day it is created.
+// created returns the record stamped with the time it is saved,
+// unless the creation time is set already.
+func created(u models.URL, now time.Time) models.URL {
+	at := u.CreationTime(now.UTC())
+	u.CreatedAt = &at
+	return u
+}
+
+// The caller must hold the lock.
+func (r *URLRepository) recordCreated(u *models.URL, now time.Time) {
+	day := now.UTC().Format(models.DateLayout)
+	t, ok := r.trends[day]
+	if !ok {
+		t = &trend{users: make(map[user.ID]struct{})}
+		r.trends[day] = t
+	}
+	t.created++
+	t.users[u.UserID] = struct{}{}
+}
+
+// GetTrends returns the daily trends since the given day in UTC,
+// oldest first. Days without created URLs are omitted.
+func (r *URLRepository) GetTrends(_ context.Context, since time.Time) ([]models.DailyTrend, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from := since.UTC().Format(models.DateLayout)
+	trends := make([]models.DailyTrend, 0, len(r.trends))
+	for day, t := range r.trends {
+		if day < from {
+			continue
+		}
+		trends = append(trends, models.DailyTrend{Date: day, CreatedURLs: t.created, ActiveUsers: len(t.users)})
+	}
+	sort.Slice(trends, func(i, j int) bool { return trends[i].Date < trends[j].Date })
+
+	return trends, nil
+}
+
+// TopLinks returns at most limit short URLs most clicked since
+// the given time, most clicked first.
+func (r *URLRepository) TopLinks(_ context.Context, since time.Time, limit int) ([]models.TopLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	top := make([]models.TopLink, 0, len(r.clicks))
+	for shortURL, clicks := range r.clicks {
+		n := 0
+		for _, c := range clicks {
+			if !c.ClickedAt.Before(since) {
+				n++
+			}
+		}
+		if n > 0 {
+			top = append(top, models.TopLink{ShortURL: shortURL, Clicks: n})
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Clicks != top[j].Clicks {
+			return top[i].Clicks > top[j].Clicks
+		}
+		return top[i].ShortURL < top[j].ShortURL
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+
+	return top, nil
+}
+
+// CountActiveUsers returns the number of the users who created
+// URLs since the given day in UTC.
+func (r *URLRepository) CountActiveUsers(_ context.Context, since time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from := since.UTC().Format(models.DateLayout)
+	users := make(map[user.ID]struct{})
+	for day, t := range r.trends {
+		if day < from {
+			continue
+		}
+		for id := range t.users {
+			users[id] = struct{}{}
+		}
+	}
+
+	return len(users), nil
+}
@@ -0,0 +1,105 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLRepository_SaveAllResults(t *testing.T) {
+	ctx := context.Background()
+	store := NewURLRepository()
+
+	require.NoError(t, store.Save(ctx, models.NewRecord("abc", "https://example.com/1", "user")))
+
+	results, err := store.SaveAllResults(ctx, []*models.URL{
+		models.NewRecord("abc", "https://example.com/2", "user"),
+		models.NewRecord("def", "https://example.com/3", "user"),
+		models.NewRecord("def", "https://example.com/4", "user"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SaveResult{
+		{ShortURL: "abc", Status: models.SaveStatusExists},
+		{ShortURL: "def", Status: models.SaveStatusCreated},
+		{ShortURL: "def", Status: models.SaveStatusExists},
+	}, results)
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginalURL("https://example.com/1"), got.OriginalURL, "existing URL is kept")
+
+	// the store is not locked by the batch
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{models.NewRecord("ghi", "https://example.com/5", "user")}))
+
+	// nothing is saved if any of the URLs is invalid
+	invalid := models.NewRecord("npq", "https://example.com/7", "user")
+	invalid.Note = strings.Repeat("n", models.MaxNoteLength+1)
+	_, err = store.SaveAllResults(ctx, []*models.URL{
+		models.NewRecord("jkm", "https://example.com/6", "user"),
+		invalid,
+	})
+	require.ErrorIs(t, err, errs.ErrInvalidRequest)
+	_, err = store.Get(ctx, "jkm")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestURLRepository_GetByOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	store := NewURLRepository()
+
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		models.NewRecord("def", "https://example.com/1", "user"),
+		models.NewRecord("abc", "https://example.com/1", "user"),
+		models.NewRecord("ghi", "https://example.com/2", "user"),
+	}))
+
+	got, err := store.GetByOriginalURL(ctx, "https://example.com/1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ShortURL("abc"), got.ShortURL, "least short URL of the same original one")
+
+	_, err = store.GetByOriginalURL(ctx, "https://example.com/3")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestURLRepository_SetLinkState_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewURLRepository()
+	require.NoError(t, store.Save(ctx, models.NewRecord("abc", "https://example.com/", "user")))
+	require.NoError(t, store.SetLinkState(ctx, "user", "abc", models.LinkActive, models.LinkPaused))
+
+	// the owner resumes the paused link while the moderator blocks it,
+	// both read it paused, so that only one of them changes it
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	for i, to := range []models.LinkState{models.LinkActive, models.LinkBlocked} {
+		wg.Add(1)
+		go func(i int, to models.LinkState) {
+			defer wg.Done()
+			results[i] = store.SetLinkState(ctx, "user", "abc", models.LinkPaused, to)
+		}(i, to)
+	}
+	wg.Wait()
+
+	var conflicts int
+	for _, err := range results {
+		if errors.Is(err, errs.ErrConflict) {
+			conflicts++
+			continue
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, conflicts, "the state changed meanwhile is not overwritten")
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Contains(t, []models.LinkState{models.LinkActive, models.LinkBlocked}, got.State)
+
+	assert.ErrorIs(t, store.SetLinkState(ctx, "other", "abc", got.State, models.LinkPaused), errs.ErrNotFound)
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
 	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -36,13 +38,20 @@ func NewURLRepository(db *sql.DB, logger logger.Logger,
 func (ur *URLRepository) Save(ctx context.Context, u *models.URL) error {
 	const q = `
 		INSERT INTO url
-			(id, short_url, original_url, user_id)
+			(id, short_url, original_url, user_id, creator_ip, user_agent, origin, expires_at,
+			redirect_limit, note, domain, created_at)
 		VALUES
-			($1, $2, $3, $4)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
 	// query the database to insert the URL record
-	_, err := ur.db.ExecContext(ctx, q, u.ID, u.ShortURL, u.OriginalURL, u.UserID)
+	_, err := ur.db.ExecContext(ctx, q, u.ID, u.ShortURL, u.OriginalURL, u.UserID,
+		u.Metadata.CreatorIP, u.Metadata.UserAgent, u.Metadata.Origin, u.ExpiresAt, u.RedirectLimit,
+		u.Note, u.Domain, u.CreationTime(time.Now()))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -65,16 +74,28 @@ func (ur *URLRepository) Save(ctx context.Context, u *models.URL) error {
 // SaveAll saves multiple URL records to the database in a single transaction.
 // If a URL record already exists, the record is not inserted.
 func (ur *URLRepository) SaveAll(ctx context.Context, urls []*models.URL) error {
-	const q = `
-		INSERT INTO url 
-			(id, short_url, original_url, user_id)
-		VALUES
-			($1, $2, $3, $4)
-	`
+	_, err := ur.SaveAllResults(ctx, urls)
+	return err
+}
+
+// saveBatchSize is the number of the URL records inserted by a single
+// statement, so that the parameters stay within the postgres limit.
+const saveBatchSize = 1000
+
+// SaveAllResults saves multiple URL records to the database in a single
+// transaction and returns the outcome of every record in the batch order.
+// The records are inserted by the multi-row statements of saveBatchSize
+// records. If a URL record already exists, the record is not inserted.
+func (ur *URLRepository) SaveAllResults(ctx context.Context, urls []*models.URL) ([]models.SaveResult, error) {
+	for _, url := range urls {
+		if err := url.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
 
 	tx, err := ur.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return nil, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer func() {
 		if err = tx.Rollback(); err != nil {
@@ -84,38 +105,99 @@ func (ur *URLRepository) SaveAll(ctx context.Context, urls []*models.URL) error
 		}
 	}()
 
-	stmt, err := tx.PrepareContext(ctx, q)
+	// ids of the inserted records, the rest conflict with the stored ones
+	inserted := make(map[string]struct{}, len(urls))
+	for batch := urls; len(batch) > 0; {
+		chunk := batch[:min(len(batch), saveBatchSize)]
+		batch = batch[len(chunk):]
+
+		if err = ur.insertURLs(ctx, tx, chunk, inserted); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	results := make([]models.SaveResult, len(urls))
+	for i, url := range urls {
+		results[i] = models.SaveResult{ShortURL: url.ShortURL, Status: models.SaveStatusCreated}
+		if _, ok := inserted[url.ID]; !ok {
+			results[i].Status = models.SaveStatusExists
+		}
+		// the duplicate of the batch is not inserted twice
+		delete(inserted, url.ID)
+	}
+
+	return results, nil
+}
+
+// insertURLs inserts the URL records by a single statement in the
+// transaction and adds the ids of the inserted ones to the set.
+func (ur *URLRepository) insertURLs(
+	ctx context.Context, tx *sql.Tx, urls []*models.URL, inserted map[string]struct{},
+) error {
+	// The unique violation aborts the transaction,
+	// so the existing records are skipped by the insert itself.
+	const (
+		q = `
+			INSERT INTO url
+				(id, short_url, original_url, user_id, creator_ip, user_agent, origin, expires_at,
+				redirect_limit, note, domain, created_at)
+			VALUES
+				%s
+			ON CONFLICT DO NOTHING
+			RETURNING id
+		`
+		columns = 12
+	)
+
+	now := time.Now()
+	values := make([]string, len(urls))
+	args := make([]any, 0, len(urls)*columns)
+	for i, url := range urls {
+		placeholders := make([]string, columns)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*columns+j+1)
+		}
+		values[i] = "(" + strings.Join(placeholders, ", ") + ")"
+		args = append(args, url.ID, url.ShortURL, url.OriginalURL, url.UserID,
+			url.Metadata.CreatorIP, url.Metadata.UserAgent, url.Metadata.Origin, url.ExpiresAt,
+			url.RedirectLimit, url.Note, url.Domain, url.CreationTime(now))
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(q, strings.Join(values, ", ")), args...)
 	if err != nil {
-		return fmt.Errorf("prepare statement: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			// create a new error with additional context
+			return fmt.Errorf("save urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+
+		return fmt.Errorf("save urls with query (%s): %w", formatQuery(q), err)
 	}
 	defer func() {
-		if err = stmt.Close(); err != nil {
-			if !errors.Is(err, sql.ErrTxDone) {
-				ur.logger.Errorf("close prepared statement: %v", err)
-			}
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
 		}
 	}()
 
-	for _, url := range urls {
-		_, err = stmt.ExecContext(ctx, url.ID, url.ShortURL, url.OriginalURL, url.UserID)
-		if err != nil {
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) {
-				// continue if the record already exists
-				if pgErr.Code == pgerrcode.UniqueViolation {
-					continue
-				}
-				// create a new error with additional context
-				return fmt.Errorf("save url with query (%s): %w",
-					formatQuery(q), formatPgError(pgErr),
-				)
-			}
-
-			return fmt.Errorf("save url with query (%s): %w", formatQuery(q), err)
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return fmt.Errorf("save urls with query (%s): %w", formatQuery(q), err)
 		}
+		inserted[id] = struct{}{}
 	}
 
-	return tx.Commit()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("save urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
 }
 
 // Get retrieves a URL record from the database based on its short URL.
@@ -123,7 +205,8 @@ func (ur *URLRepository) SaveAll(ctx context.Context, urls []*models.URL) error
 func (ur *URLRepository) Get(ctx context.Context, sURL models.ShortURL) (*models.URL, error) {
 	const q = `
 		SELECT
-			id, short_url, original_url, is_deleted
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
 		FROM
 			url
 		WHERE
@@ -135,10 +218,71 @@ func (ur *URLRepository) Get(ctx context.Context, sURL models.ShortURL) (*models
 		&u.ID,
 		&u.ShortURL,
 		&u.OriginalURL,
+		&u.UserID,
+		&u.IsDeleted,
+		&u.LastAccessedAt,
+		&u.ExpiresAt,
+		&u.Metadata.CreatorIP,
+		&u.Metadata.UserAgent,
+		&u.Metadata.Origin,
+		&u.RedirectLimit,
+		&u.Note,
+		&u.Domain,
+		&u.State,
+		&u.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			// Create a new error with additional context.
+			return nil, fmt.Errorf("retrieve url with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+
+		return nil, fmt.Errorf("retrieve url with query (%s): %w", formatQuery(q), err)
+	}
+
+	return u, nil
+}
+
+// GetByOriginalURL retrieves a URL record from the database based on its
+// original URL, e.g. to report the short URL the conflicting one is saved
+// under. If the URL record does not exist, ErrNotFound is returned.
+func (ur *URLRepository) GetByOriginalURL(ctx context.Context, originalURL models.OriginalURL) (*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			original_url = $1
+	`
+
+	u := new(models.URL)
+	err := ur.db.QueryRowContext(ctx, q, originalURL).Scan(
+		&u.ID,
+		&u.ShortURL,
+		&u.OriginalURL,
+		&u.UserID,
 		&u.IsDeleted,
+		&u.LastAccessedAt,
+		&u.ExpiresAt,
+		&u.Metadata.CreatorIP,
+		&u.Metadata.UserAgent,
+		&u.Metadata.Origin,
+		&u.RedirectLimit,
+		&u.Note,
+		&u.Domain,
+		&u.State,
+		&u.Version,
 	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errs.ErrNotFound
 		}
 		var pgErr *pgconn.PgError
@@ -155,13 +299,88 @@ func (ur *URLRepository) Get(ctx context.Context, sURL models.ShortURL) (*models
 	return u, nil
 }
 
+// GetMany retrieves URL records from the database based on their short URLs.
+// Short URLs that are not found are skipped.
+func (ur *URLRepository) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			short_url = ANY($1)
+	`
+
+	if len(sURLs) == 0 {
+		return []*models.URL{}, nil
+	}
+
+	keys := make([]string, len(sURLs))
+	for i, s := range sURLs {
+		keys[i] = string(s)
+	}
+
+	rows, err := ur.db.QueryContext(ctx, q, keys)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+
+		return nil, fmt.Errorf("retrieve urls with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	all := make([]*models.URL, 0, len(sURLs))
+	for rows.Next() {
+		u := new(models.URL)
+		err = rows.Scan(
+			&u.ID,
+			&u.ShortURL,
+			&u.OriginalURL,
+			&u.UserID,
+			&u.IsDeleted,
+			&u.LastAccessedAt,
+			&u.ExpiresAt,
+			&u.Metadata.CreatorIP,
+			&u.Metadata.UserAgent,
+			&u.Metadata.Origin,
+			&u.RedirectLimit,
+			&u.Note,
+			&u.Domain,
+			&u.State,
+			&u.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"retrieve urls with query (%s): %w", formatQuery(q), err,
+			)
+		}
+		all = append(all, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
 // GetAllByUserID retrieves all URL records from the database associated with a specific user.
 // It returns a slice of URL pointers and an error if any occurred.
 // If no URL records are found for the given user, it returns nil and ErrNotFound.
-func (ur *URLRepository) GetAllByUserID(ctx context.Context, userID string) ([]*models.URL, error) {
+func (ur *URLRepository) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
 	const q = `
 		SELECT
-			short_url, original_url
+			short_url, original_url, last_accessed_at, expires_at,
+			creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
 		FROM
 			url
 		WHERE
@@ -193,7 +412,9 @@ func (ur *URLRepository) GetAllByUserID(ctx context.Context, userID string) ([]*
 		u := new(models.URL) // Create a new URL pointer.
 
 		// Scan the current row into the URL pointer.
-		err = rows.Scan(&u.ShortURL, &u.OriginalURL)
+		err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.LastAccessedAt, &u.ExpiresAt,
+			&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit,
+			&u.Note, &u.Domain, &u.State, &u.Version)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"retrieve url with query (%s): %w", formatQuery(q), err,
@@ -218,16 +439,59 @@ func (ur *URLRepository) GetAllByUserID(ctx context.Context, userID string) ([]*
 	return all, nil
 }
 
-// DeleteURLs deletes the specified URLs from the database.
-// It takes a context and a slice of URL pointers as parameters.
-// It returns an error if any occurs during the deletion process.
+// DeleteURLs marks the URLs of their users as deleted by a single
+// statement, the short URLs and the user IDs passed as the arrays.
 // If no URLs are provided, it returns nil.
 func (ur *URLRepository) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
 	if len(urls) == 0 {
 		return nil
 	}
 
-	const q = "UPDATE url SET is_deleted = TRUE WHERE short_url = $1;"
+	const q = `
+		UPDATE url SET
+			is_deleted = TRUE
+		FROM
+			unnest($1::text[], $2::text[]) AS d(short_url, user_id)
+		WHERE
+			url.short_url = d.short_url AND url.user_id = d.user_id
+	`
+
+	shortURLs := make([]string, len(urls))
+	userIDs := make([]string, len(urls))
+	for i, url := range urls {
+		shortURLs[i] = string(url.ShortURL)
+		userIDs[i] = string(url.UserID)
+	}
+
+	_, err := ur.db.ExecContext(ctx, q, shortURLs, userIDs)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("delete urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("delete urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// UpdateLastAccessed sets the last access time of the given short URLs
+// in a single transaction. Older times never overwrite newer ones.
+func (ur *URLRepository) UpdateLastAccessed(
+	ctx context.Context, accessed map[models.ShortURL]time.Time,
+) error {
+	if len(accessed) == 0 {
+		return nil
+	}
+
+	const q = `
+		UPDATE url SET
+			last_accessed_at = GREATEST(last_accessed_at, $2)
+		WHERE
+			short_url = $1
+	`
 
 	tx, err := ur.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -253,16 +517,16 @@ func (ur *URLRepository) DeleteURLs(ctx context.Context, urls ...*models.URL) er
 		}
 	}()
 
-	for _, url := range urls {
-		_, err = stmt.ExecContext(ctx, url.ShortURL)
+	for shortURL, accessedAt := range accessed {
+		_, err = stmt.ExecContext(ctx, shortURL, accessedAt)
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
-				return fmt.Errorf("delete url with query (%s): %w",
+				return fmt.Errorf("update last access with query (%s): %w",
 					formatQuery(q), formatPgError(pgErr),
 				)
 			}
-			return fmt.Errorf("delete url with query (%s): %w",
+			return fmt.Errorf("update last access with query (%s): %w",
 				formatQuery(q), err)
 		}
 	}
@@ -270,6 +534,2002 @@ func (ur *URLRepository) DeleteURLs(ctx context.Context, urls ...*models.URL) er
 	return tx.Commit()
 }
 
+// SetRedirectLimit sets the redirects allowed per minute for the URL
+// of the user. If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) SetRedirectLimit(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, limit int,
+) error {
+	const q = `
+		UPDATE url SET
+			redirect_limit = $3
+		WHERE
+			short_url = $1 AND user_id = $2
+	`
+
+	if err := models.ValidateRedirectLimit(limit); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, limit)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("set redirect limit with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("set redirect limit with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set redirect limit: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetNote sets the note of the URL of the user.
+// If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) SetNote(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, note string,
+) error {
+	const q = `
+		UPDATE url SET
+			note = $3
+		WHERE
+			short_url = $1 AND user_id = $2
+	`
+
+	if err := models.ValidateNote(note); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, note)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("set note with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("set note with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateURL applies the update to the URL of the user and increments
+// its version. The version is checked by the update itself, so that
+// the concurrent updates of the same version are applied once.
+func (ur *URLRepository) UpdateURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, update *models.URLUpdate,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				original_url = $4, expires_at = $5, version = version + 1
+			WHERE
+				short_url = $1 AND user_id = $2 AND version = $3 AND NOT is_deleted
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = $1 AND user_id = $2 AND NOT is_deleted)
+		`
+	)
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, update.Version,
+		update.OriginalURL, update.ExpiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			// return ErrConflict if the destination is taken
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return fmt.Errorf("%s: %w", update.OriginalURL, errs.ErrConflict)
+			}
+			return fmt.Errorf("update url with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("update url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update url: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is updated, either the URL is missing or its version is stale
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("update url with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return fmt.Errorf("%s: %w", shortURL, models.ErrStaleVersion)
+}
+
+// TransferURL hands the URL of the user over to the new owner in a single
+// transaction, dropping its shares and removing it from the campaigns
+// of the user. If the user has no such URL or it is deleted, ErrNotFound
+// is returned.
+func (ur *URLRepository) TransferURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, to user.ID,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				user_id = $3
+			WHERE
+				short_url = $1 AND user_id = $2 AND NOT is_deleted
+		`
+		sharesQuery = `
+			DELETE FROM url_share
+			WHERE
+				short_url = $1
+		`
+		campaignsQuery = `
+			DELETE FROM url_campaign
+			WHERE
+				short_url = $1 AND user_id = $2
+		`
+	)
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	n, err := execRowsAffected(ctx, tx, q, shortURL, userID, to)
+	if err != nil {
+		return fmt.Errorf("transfer url with query (%s): %w", formatQuery(q), err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if _, err = execRowsAffected(ctx, tx, sharesQuery, shortURL); err != nil {
+		return fmt.Errorf("drop shares with query (%s): %w", formatQuery(sharesQuery), err)
+	}
+	if _, err = execRowsAffected(ctx, tx, campaignsQuery, shortURL, userID); err != nil {
+		return fmt.Errorf("drop campaigns with query (%s): %w", formatQuery(campaignsQuery), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ShareURL grants the read access to the URL of the user, keeping
+// the original share if it is shared already. If the user has no such URL
+// or it is deleted, ErrNotFound is returned.
+func (ur *URLRepository) ShareURL(ctx context.Context, userID user.ID, share *models.URLShare) error {
+	const (
+		q = `
+			INSERT INTO url_share
+				(short_url, user_id, created_at)
+			SELECT
+				short_url, $3, $4
+			FROM
+				url
+			WHERE
+				short_url = $1 AND user_id = $2 AND NOT is_deleted
+			ON CONFLICT DO NOTHING
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = $1 AND user_id = $2 AND NOT is_deleted)
+		`
+	)
+
+	res, err := ur.db.ExecContext(ctx, q, share.ShortURL, userID, share.UserID, share.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("share url with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("share url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("share url: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is inserted, either the URL is missing or it is shared already
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, share.ShortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("share url with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", share.ShortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UnshareURL revokes the read access to the URL of the user.
+// If the URL is not shared with the user, ErrNotFound is returned.
+func (ur *URLRepository) UnshareURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, with user.ID,
+) error {
+	const q = `
+		DELETE FROM url_share
+		WHERE
+			short_url = $1 AND user_id = $3
+			AND EXISTS (SELECT 1 FROM url WHERE short_url = $1 AND user_id = $2)
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, with)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("unshare url with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("unshare url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unshare url: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s shared with %s: %w", shortURL, with, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// GetShares returns the shares of the URL of the user in the order
+// they were granted. If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) GetShares(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL,
+) ([]*models.URLShare, error) {
+	const (
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = $1 AND user_id = $2)
+		`
+		q = `
+			SELECT
+				short_url, user_id, created_at
+			FROM
+				url_share
+			WHERE
+				short_url = $1
+			ORDER BY
+				created_at, user_id
+		`
+	)
+
+	var exists bool
+	if err := ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	rows, err := ur.db.QueryContext(ctx, q, shortURL)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve shares with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	shares := make([]*models.URLShare, 0)
+	for rows.Next() {
+		share := new(models.URLShare)
+		if err = rows.Scan(&share.ShortURL, &share.UserID, &share.CreatedAt); err != nil {
+			return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(q), err)
+		}
+		shares = append(shares, share)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(q), err)
+	}
+
+	return shares, nil
+}
+
+// GetSharedURLs retrieves the URLs shared with the user, excluding
+// the deleted ones, ordered by their short URLs.
+func (ur *URLRepository) GetSharedURLs(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			u.id, u.short_url, u.original_url, u.user_id, u.is_deleted, u.last_accessed_at,
+			u.expires_at, u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note,
+			u.domain, u.state, u.version
+		FROM
+			url_share s
+			JOIN url u ON u.short_url = s.short_url
+		WHERE
+			s.user_id = $1 AND NOT u.is_deleted
+		ORDER BY
+			u.short_url
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve shared urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve shared urls with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	all := make([]*models.URL, 0)
+	for rows.Next() {
+		u := new(models.URL)
+		err = rows.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+			&u.LastAccessedAt, &u.ExpiresAt, &u.Metadata.CreatorIP, &u.Metadata.UserAgent,
+			&u.Metadata.Origin, &u.RedirectLimit, &u.Note, &u.Domain, &u.State, &u.Version)
+		if err != nil {
+			return nil, fmt.Errorf("retrieve shared urls with query (%s): %w", formatQuery(q), err)
+		}
+		all = append(all, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve shared urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// SetLinkState changes the state of the URL of the user if it is still
+// the given one. The state is checked by the update itself, so that the
+// concurrent changes are not overwritten. If the user has no such URL,
+// ErrNotFound is returned, if its state has changed, ErrConflict.
+func (ur *URLRepository) SetLinkState(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				state = $4
+			WHERE
+				short_url = $1 AND user_id = $2 AND COALESCE(NULLIF(state, ''), 'active') = $3
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = $1 AND user_id = $2)
+		`
+	)
+
+	for _, state := range []models.LinkState{from, to} {
+		if err := models.ValidateStoredState(state); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, from, to)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("set link state with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("set link state with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set link state: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is updated, either the URL is missing or its state has changed
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("set link state with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return fmt.Errorf("%s: state is not %s: %w", shortURL, from, errs.ErrConflict)
+}
+
+// AddToCampaign adds the URLs of the user to the campaign in a single
+// statement and returns the number of the added ones. Short URLs of other
+// users, deleted or already in the campaign are skipped.
+func (ur *URLRepository) AddToCampaign(
+	ctx context.Context, userID user.ID, campaign string, shortURLs []models.ShortURL,
+) (int, error) {
+	const q = `
+		INSERT INTO url_campaign
+			(user_id, campaign, short_url)
+		SELECT
+			user_id, $2, short_url
+		FROM
+			url
+		WHERE
+			user_id = $1 AND short_url = ANY($3) AND NOT is_deleted
+		ON CONFLICT DO NOTHING
+	`
+
+	if err := models.ValidateCampaign(campaign); err != nil {
+		return 0, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	if len(shortURLs) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(shortURLs))
+	for i, s := range shortURLs {
+		keys[i] = string(s)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, userID, campaign, keys)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return 0, fmt.Errorf("add to campaign with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return 0, fmt.Errorf("add to campaign with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("add to campaign: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// GetCampaignURLs retrieves the URLs of the user in the campaign,
+// excluding the deleted ones.
+func (ur *URLRepository) GetCampaignURLs(
+	ctx context.Context, userID user.ID, campaign string,
+) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			u.short_url, u.original_url, u.last_accessed_at, u.expires_at,
+			u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note, u.domain, u.state, u.version
+		FROM
+			url_campaign c
+			JOIN url u ON u.short_url = c.short_url
+		WHERE
+			c.user_id = $1 AND c.campaign = $2 AND NOT u.is_deleted
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, userID, campaign)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve campaign with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve campaign with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	all := make([]*models.URL, 0)
+	for rows.Next() {
+		u := &models.URL{UserID: userID}
+		err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.LastAccessedAt, &u.ExpiresAt,
+			&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit,
+			&u.Note, &u.Domain, &u.State, &u.Version)
+		if err != nil {
+			return nil, fmt.Errorf("retrieve campaign with query (%s): %w", formatQuery(q), err)
+		}
+		all = append(all, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve campaign with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// TransferCampaign copies the URLs of the user in the campaign from
+// to the campaign to in a single transaction, skipping the ones whose
+// destinations are already in it. If move is set, the URLs are removed
+// from the campaign from.
+func (ur *URLRepository) TransferCampaign(
+	ctx context.Context, userID user.ID, from, to string, move bool,
+) (*models.CampaignTransfer, error) {
+	const (
+		countQuery = `
+			SELECT
+				count(*)
+			FROM
+				url_campaign c
+				JOIN url u ON u.short_url = c.short_url
+			WHERE
+				c.user_id = $1 AND c.campaign = $2 AND NOT u.is_deleted
+		`
+		copyQuery = `
+			INSERT INTO url_campaign
+				(user_id, campaign, short_url)
+			SELECT
+				c.user_id, $3, c.short_url
+			FROM
+				url_campaign c
+				JOIN url u ON u.short_url = c.short_url
+			WHERE
+				c.user_id = $1 AND c.campaign = $2 AND NOT u.is_deleted
+				AND NOT EXISTS (
+					SELECT 1
+					FROM
+						url_campaign t
+						JOIN url d ON d.short_url = t.short_url
+					WHERE
+						t.user_id = $1 AND t.campaign = $3
+						AND d.original_url = u.original_url
+				)
+			ON CONFLICT DO NOTHING
+		`
+		removeQuery = `
+			DELETE FROM url_campaign
+			WHERE
+				user_id = $1 AND campaign = $2
+		`
+	)
+
+	for _, campaign := range []string{from, to} {
+		if err := models.ValidateCampaign(campaign); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	var total int
+	if err = tx.QueryRowContext(ctx, countQuery, userID, from).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count campaign with query (%s): %w", formatQuery(countQuery), err)
+	}
+
+	res := new(models.CampaignTransfer)
+	res.Copied, err = execRowsAffected(ctx, tx, copyQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy campaign with query (%s): %w", formatQuery(copyQuery), err)
+	}
+	res.Skipped = total - res.Copied
+
+	if move && from != to {
+		res.Removed, err = execRowsAffected(ctx, tx, removeQuery, userID, from)
+		if err != nil {
+			return nil, fmt.Errorf("remove campaign with query (%s): %w", formatQuery(removeQuery), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return res, nil
+}
+
+// execRowsAffected executes the query in the transaction
+// and returns the number of the affected rows.
+func execRowsAffected(ctx context.Context, tx *sql.Tx, q string, args ...any) (int, error) {
+	res, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return 0, formatPgError(pgErr)
+		}
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// DeleteExpired marks the URLs expired by now as deleted
+// and returns their number.
+func (ur *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const q = `
+		UPDATE url SET
+			is_deleted = TRUE
+		WHERE
+			expires_at <= $1 AND NOT is_deleted
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, now)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return 0, fmt.Errorf("delete expired urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return 0, fmt.Errorf("delete expired urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired urls: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// Reserve saves the reservations in a single transaction.
+// If any of the codes is already reserved or used by a URL,
+// ErrConflict is returned and nothing is saved.
+func (ur *URLRepository) Reserve(ctx context.Context, reservations ...*models.Reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	const q = `
+		INSERT INTO reservation
+			(short_url, user_id)
+		SELECT
+			$1, $2
+		WHERE
+			NOT EXISTS (SELECT 1 FROM url WHERE short_url = $1)
+	`
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer func() {
+		if err = stmt.Close(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("close prepared statement: %v", err)
+			}
+		}
+	}()
+
+	for _, r := range reservations {
+		res, err := stmt.ExecContext(ctx, r.ShortURL, r.UserID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				// return ErrConflict if the code is already reserved
+				if pgErr.Code == pgerrcode.UniqueViolation {
+					return fmt.Errorf("%s: %w", r.ShortURL, errs.ErrConflict)
+				}
+				return fmt.Errorf("reserve with query (%s): %w",
+					formatQuery(q), formatPgError(pgErr),
+				)
+			}
+			return fmt.Errorf("reserve with query (%s): %w", formatQuery(q), err)
+		}
+		// nothing inserted means the code is used by a URL
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("%s: %w", r.ShortURL, errs.ErrConflict)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetReservation retrieves the reservation of the short code.
+// If the code is not reserved, ErrNotFound is returned.
+func (ur *URLRepository) GetReservation(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.Reservation, error) {
+	const q = `
+		SELECT
+			short_url, user_id, created_at
+		FROM
+			reservation
+		WHERE
+			short_url = $1
+	`
+
+	r := new(models.Reservation)
+	err := ur.db.QueryRowContext(ctx, q, shortURL).Scan(
+		&r.ShortURL,
+		&r.UserID,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve reservation with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve reservation with query (%s): %w", formatQuery(q), err)
+	}
+
+	return r, nil
+}
+
+// SaveClicks saves the clicks in a single transaction.
+func (ur *URLRepository) SaveClicks(ctx context.Context, clicks ...*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	const q = `
+		INSERT INTO click
+			(short_url, clicked_at, referrer, user_agent, ip_hash)
+		VALUES
+			($1, $2, $3, $4, $5)
+	`
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer func() {
+		if err = stmt.Close(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("close prepared statement: %v", err)
+			}
+		}
+	}()
+
+	for _, c := range clicks {
+		_, err = stmt.ExecContext(ctx, c.ShortURL, c.ClickedAt, c.Referrer, c.UserAgent, c.IPHash)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				return fmt.Errorf("save click with query (%s): %w",
+					formatQuery(q), formatPgError(pgErr),
+				)
+			}
+			return fmt.Errorf("save click with query (%s): %w", formatQuery(q), err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetClickStats returns the click statistics of the short URL
+// aggregated by days in UTC.
+func (ur *URLRepository) GetClickStats(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.ClickStats, error) {
+	const q = `
+		SELECT
+			to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+			count(*),
+			max(clicked_at)
+		FROM
+			click
+		WHERE
+			short_url = $1
+		GROUP BY
+			day
+		ORDER BY
+			day
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, shortURL)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve click stats with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve click stats with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	stats := &models.ClickStats{ShortURL: shortURL, Daily: make([]models.DailyClicks, 0)}
+	for rows.Next() {
+		var (
+			day  models.DailyClicks
+			last time.Time
+		)
+		if err = rows.Scan(&day.Date, &day.Clicks, &last); err != nil {
+			return nil, fmt.Errorf("scan click stats: %w", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+		stats.TotalClicks += day.Clicks
+		if stats.LastAccessedAt == nil || last.After(*stats.LastAccessedAt) {
+			stats.LastAccessedAt = &last
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate click stats: %w", err)
+	}
+
+	agents, err := ur.getUserAgentClicks(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	stats.AddUserAgents(agents)
+
+	return stats, nil
+}
+
+// getUserAgentClicks returns the number of clicks
+// of the short URL by the user agent.
+func (ur *URLRepository) getUserAgentClicks(
+	ctx context.Context, shortURL models.ShortURL,
+) (map[string]int, error) {
+	const q = `
+		SELECT
+			user_agent,
+			count(*)
+		FROM
+			click
+		WHERE
+			short_url = $1
+		GROUP BY
+			user_agent
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, shortURL)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve user agent clicks with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve user agent clicks with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	agents := make(map[string]int)
+	for rows.Next() {
+		var (
+			ua string
+			n  int
+		)
+		if err = rows.Scan(&ua, &n); err != nil {
+			return nil, fmt.Errorf("scan user agent clicks: %w", err)
+		}
+		agents[ua] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate user agent clicks: %w", err)
+	}
+
+	return agents, nil
+}
+
+// GetTrends returns the daily trends since the given day in UTC,
+// oldest first, from the rollups the trigger on the url table updates.
+func (ur *URLRepository) GetTrends(ctx context.Context, since time.Time) ([]models.DailyTrend, error) {
+	const q = `
+		SELECT
+			to_char(d.day, 'YYYY-MM-DD'),
+			d.created,
+			(SELECT count(*) FROM url_daily_user u WHERE u.day = d.day)
+		FROM
+			url_daily d
+		WHERE
+			d.day >= $1::date
+		ORDER BY
+			d.day
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, since.UTC().Format(models.DateLayout))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve trends with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve trends with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	trends := make([]models.DailyTrend, 0)
+	for rows.Next() {
+		var t models.DailyTrend
+		if err = rows.Scan(&t.Date, &t.CreatedURLs, &t.ActiveUsers); err != nil {
+			return nil, fmt.Errorf("scan trends: %w", err)
+		}
+		trends = append(trends, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trends: %w", err)
+	}
+
+	return trends, nil
+}
+
+// SavePendingDeletions saves the scheduled deletions in a single transaction.
+// Deletions already in the queue are skipped.
+func (ur *URLRepository) SavePendingDeletions(ctx context.Context, urls ...*models.URL) error {
+	const q = `
+		INSERT INTO pending_delete
+			(short_url, user_id)
+		VALUES
+			($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	return ur.execForEach(ctx, q, "save pending deletion", urls)
+}
+
+// GetPendingDeletions returns all the pending deletions
+// in the order they were saved.
+func (ur *URLRepository) GetPendingDeletions(ctx context.Context) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			short_url, user_id
+		FROM
+			pending_delete
+		ORDER BY
+			created_at
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve pending deletions with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve pending deletions with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	all := make([]*models.URL, 0)
+	for rows.Next() {
+		u := new(models.URL)
+		if err = rows.Scan(&u.ShortURL, &u.UserID); err != nil {
+			return nil, fmt.Errorf("scan pending deletion: %w", err)
+		}
+		all = append(all, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pending deletions: %w", err)
+	}
+
+	return all, nil
+}
+
+// RemovePendingDeletions removes the applied deletions
+// from the queue in a single transaction.
+func (ur *URLRepository) RemovePendingDeletions(ctx context.Context, urls ...*models.URL) error {
+	const q = `
+		DELETE FROM
+			pending_delete
+		WHERE
+			short_url = $1 AND user_id = $2
+	`
+
+	return ur.execForEach(ctx, q, "remove pending deletion", urls)
+}
+
+// execForEach executes the query with the short URL and user ID
+// of every URL in a single transaction.
+func (ur *URLRepository) execForEach(ctx context.Context, q, op string, urls []*models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer func() {
+		if err = stmt.Close(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("close prepared statement: %v", err)
+			}
+		}
+	}()
+
+	for _, u := range urls {
+		if _, err = stmt.ExecContext(ctx, u.ShortURL, u.UserID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				return fmt.Errorf("%s with query (%s): %w",
+					op, formatQuery(q), formatPgError(pgErr),
+				)
+			}
+			return fmt.Errorf("%s with query (%s): %w", op, formatQuery(q), err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountShortURLs returns the number of not deleted short URLs.
+func (ur *URLRepository) CountShortURLs(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			COUNT(*)
+		FROM
+			url
+		WHERE
+			NOT is_deleted
+	`
+
+	return ur.count(ctx, q)
+}
+
+// CountUsers returns the number of distinct users owning at least one URL.
+func (ur *URLRepository) CountUsers(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			COUNT(DISTINCT user_id)
+		FROM
+			url
+		WHERE
+			NOT is_deleted
+	`
+
+	return ur.count(ctx, q)
+}
+
+// CountActiveUsers returns the number of the users who created
+// URLs since the given day in UTC, from the daily rollups.
+func (ur *URLRepository) CountActiveUsers(ctx context.Context, since time.Time) (int, error) {
+	const q = `
+		SELECT
+			COUNT(DISTINCT user_id)
+		FROM
+			url_daily_user
+		WHERE
+			day >= $1::date
+	`
+
+	return ur.count(ctx, q, since.UTC().Format(models.DateLayout))
+}
+
+// TopLinks returns at most limit short URLs most clicked since
+// the given time, most clicked first.
+func (ur *URLRepository) TopLinks(ctx context.Context, since time.Time, limit int) ([]models.TopLink, error) {
+	const q = `
+		SELECT
+			short_url, count(*) AS clicks
+		FROM
+			click
+		WHERE
+			clicked_at >= $1
+		GROUP BY
+			short_url
+		ORDER BY
+			clicks DESC, short_url
+		LIMIT $2
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, since, limit)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve top links with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve top links with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	top := make([]models.TopLink, 0, limit)
+	for rows.Next() {
+		var t models.TopLink
+		if err = rows.Scan(&t.ShortURL, &t.Clicks); err != nil {
+			return nil, fmt.Errorf("scan top links: %w", err)
+		}
+		top = append(top, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate top links: %w", err)
+	}
+
+	return top, nil
+}
+
+// count executes the query returning a single number.
+func (ur *URLRepository) count(ctx context.Context, q string, args ...any) (int, error) {
+	var n int
+	if err := ur.db.QueryRowContext(ctx, q, args...).Scan(&n); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return 0, fmt.Errorf("count with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return 0, fmt.Errorf("count with query (%s): %w", formatQuery(q), err)
+	}
+
+	return n, nil
+}
+
+// ScanURLs calls fn for every URL record, including the deleted ones.
+// The records are streamed, so fn must not block for long.
+func (ur *URLRepository) ScanURLs(ctx context.Context, fn func(*models.URL) error) error {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted,
+			last_accessed_at, expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("scan urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("scan urls with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		u := new(models.URL)
+		err = rows.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+			&u.LastAccessedAt, &u.ExpiresAt,
+			&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit,
+			&u.Note, &u.Domain, &u.State, &u.Version)
+		if err != nil {
+			return fmt.Errorf("scan urls with query (%s): %w", formatQuery(q), err)
+		}
+		if err = fn(u); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("scan urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// ScanUserURLs calls fn for every URL of the user, including the deleted
+// ones, ordered by their short URLs, with the number of their clicks.
+// The records are streamed, so fn must not block for long.
+func (ur *URLRepository) ScanUserURLs(
+	ctx context.Context, userID user.ID, fn func(u *models.URL, clicks int) error,
+) error {
+	const q = `
+		SELECT
+			u.id, u.short_url, u.original_url, u.user_id, u.is_deleted, u.last_accessed_at,
+			u.expires_at, u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note,
+			u.domain, u.state, u.version, u.created_at,
+			(SELECT count(*) FROM click c WHERE c.short_url = u.short_url)
+		FROM
+			url u
+		WHERE
+			u.user_id = $1
+		ORDER BY
+			u.short_url
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("scan user urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("scan user urls with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			u      = new(models.URL)
+			clicks int
+		)
+		err = rows.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+			&u.LastAccessedAt, &u.ExpiresAt,
+			&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit,
+			&u.Note, &u.Domain, &u.State, &u.Version, &u.CreatedAt, &clicks)
+		if err != nil {
+			return fmt.Errorf("scan user urls with query (%s): %w", formatQuery(q), err)
+		}
+		if err = fn(u, clicks); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("scan user urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// CreateAccount saves the account. If the login is taken or the user
+// already has an account, ErrConflict is returned.
+func (ur *URLRepository) CreateAccount(ctx context.Context, account *models.Account) error {
+	const q = `
+		INSERT INTO users
+			(user_id, login, password_hash, created_at)
+		VALUES
+			($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := ur.db.ExecContext(ctx, q,
+		account.UserID, account.Login, account.PasswordHash, account.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("create account with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("create account with query (%s): %w", formatQuery(q), err)
+	}
+
+	// nothing inserted means the login or the user is taken
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("login %s: %w", account.Login, errs.ErrConflict)
+	}
+
+	return nil
+}
+
+// GetAccount retrieves the account by its login.
+// If the login is unknown, ErrNotFound is returned.
+func (ur *URLRepository) GetAccount(ctx context.Context, login string) (*models.Account, error) {
+	const q = `
+		SELECT
+			user_id, login, password_hash, created_at
+		FROM
+			users
+		WHERE
+			login = $1
+	`
+
+	var a models.Account
+	err := ur.db.QueryRowContext(ctx, q, login).Scan(&a.UserID, &a.Login, &a.PasswordHash, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("get account with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("get account with query (%s): %w", formatQuery(q), err)
+	}
+
+	return &a, nil
+}
+
+// RevokeToken revokes the token with the ID until it expires. If the token
+// is already revoked, ErrConflict is returned. The expired tokens are
+// forgotten.
+func (ur *URLRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	const purge = `
+		DELETE FROM
+			revoked_token
+		WHERE
+			expires_at < now()
+	`
+	const q = `
+		INSERT INTO revoked_token
+			(token_id, expires_at)
+		VALUES
+			($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	// the tokens revoked once are never revoked again, so the failed
+	// purge only keeps them a bit longer
+	if _, err := ur.db.ExecContext(ctx, purge); err != nil {
+		ur.logger.Errorf("purge revoked tokens with query (%s): %s", formatQuery(purge), err)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, tokenID, expiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("revoke token with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("revoke token with query (%s): %w", formatQuery(q), err)
+	}
+
+	// nothing inserted means the token is already revoked
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("token %s: %w", tokenID, errs.ErrConflict)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token with the ID is revoked.
+func (ur *URLRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	const q = `
+		SELECT
+			EXISTS (SELECT 1 FROM revoked_token WHERE token_id = $1)
+	`
+
+	var revoked bool
+	if err := ur.db.QueryRowContext(ctx, q, tokenID).Scan(&revoked); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return false, fmt.Errorf("check token with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return false, fmt.Errorf("check token with query (%s): %w", formatQuery(q), err)
+	}
+
+	return revoked, nil
+}
+
+// BanDomain bans the domain. If the domain is already banned,
+// ErrConflict is returned.
+func (ur *URLRepository) BanDomain(ctx context.Context, ban *models.DomainBan) error {
+	const q = `
+		INSERT INTO banned_domain
+			(domain, reason, created_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, ban.Domain, ban.Reason, ban.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("ban domain with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("ban domain with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ban domain: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("domain %s: %w", ban.Domain, errs.ErrConflict)
+	}
+
+	return nil
+}
+
+// GetDomainBan retrieves the ban of the host or of its parent domain,
+// the most specific one first. If the host is not banned, ErrNotFound
+// is returned.
+func (ur *URLRepository) GetDomainBan(ctx context.Context, host string) (*models.DomainBan, error) {
+	const q = `
+		SELECT
+			domain, reason, created_at
+		FROM
+			banned_domain
+		WHERE
+			domain = ANY($1)
+		ORDER BY
+			length(domain) DESC
+		LIMIT 1
+	`
+
+	ban := new(models.DomainBan)
+	err := ur.db.QueryRowContext(ctx, q, hostpolicy.Domains(host)).
+		Scan(&ban.Domain, &ban.Reason, &ban.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("host %s: %w", host, errs.ErrNotFound)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve domain ban with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve domain ban with query (%s): %w", formatQuery(q), err)
+	}
+
+	return ban, nil
+}
+
+// ListDomainBans returns the banned domains in the order they were banned.
+func (ur *URLRepository) ListDomainBans(ctx context.Context) ([]*models.DomainBan, error) {
+	const q = `
+		SELECT
+			domain, reason, created_at
+		FROM
+			banned_domain
+		ORDER BY
+			created_at, domain
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("list domain bans with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("list domain bans with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	bans := make([]*models.DomainBan, 0)
+	for rows.Next() {
+		ban := new(models.DomainBan)
+		if err = rows.Scan(&ban.Domain, &ban.Reason, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list domain bans with query (%s): %w", formatQuery(q), err)
+		}
+		bans = append(bans, ban)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list domain bans with query (%s): %w", formatQuery(q), err)
+	}
+
+	return bans, nil
+}
+
+// GetFlaggedURLs retrieves the blocked URLs, excluding the deleted ones,
+// ordered by their short URLs.
+func (ur *URLRepository) GetFlaggedURLs(ctx context.Context) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			state = 'blocked' AND NOT is_deleted
+		ORDER BY
+			short_url
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve flagged urls with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve flagged urls with query (%s): %w", formatQuery(q), err)
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	all := make([]*models.URL, 0)
+	for rows.Next() {
+		u := new(models.URL)
+		err = rows.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+			&u.LastAccessedAt, &u.ExpiresAt, &u.Metadata.CreatorIP, &u.Metadata.UserAgent,
+			&u.Metadata.Origin, &u.RedirectLimit, &u.Note, &u.Domain, &u.State, &u.Version)
+		if err != nil {
+			return nil, fmt.Errorf("retrieve flagged urls with query (%s): %w", formatQuery(q), err)
+		}
+		all = append(all, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve flagged urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// SaveAPIKey saves the API key.
+func (ur *URLRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	const q = `
+		INSERT INTO api_key
+			(id, user_id, name, scopes, hash, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := ur.db.ExecContext(ctx, q,
+		key.ID, key.UserID, key.Name, encodeScopes(key.Scopes), key.Hash, key.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("save API key with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("save API key with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// GetAPIKey retrieves the API key by its hash.
+// If the key is unknown, ErrNotFound is returned.
+func (ur *URLRepository) GetAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	const q = `
+		SELECT
+			id, user_id, name, scopes, hash, created_at, last_used_at, requests
+		FROM
+			api_key
+		WHERE
+			hash = $1
+	`
+
+	key, err := scanAPIKey(ur.db.QueryRowContext(ctx, q, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve API key with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve API key with query (%s): %w", formatQuery(q), err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns the API keys of the user in the order they were minted.
+func (ur *URLRepository) ListAPIKeys(ctx context.Context, userID user.ID) ([]*models.APIKey, error) {
+	const q = `
+		SELECT
+			id, user_id, name, scopes, hash, created_at, last_used_at, requests
+		FROM
+			api_key
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at
+	`
+
+	rows, err := ur.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("list API keys with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("list API keys with query (%s): %w", formatQuery(q), err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list API keys with query (%s): %w", formatQuery(q), err)
+	}
+
+	return keys, nil
+}
+
+// RotateAPIKey replaces the hash of the API key of the user.
+// If the user has no key with the ID, ErrNotFound is returned.
+func (ur *URLRepository) RotateAPIKey(ctx context.Context, userID user.ID, id, hash string) error {
+	const q = `
+		UPDATE api_key SET
+			hash = $3
+		WHERE
+			id = $1 AND user_id = $2
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, id, userID, hash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("rotate API key with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("rotate API key with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rotate API key: %w", err)
+	}
+	if n == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordAPIKeyUse counts the request with the API key
+// and sets the time of its last use.
+func (ur *URLRepository) RecordAPIKeyUse(ctx context.Context, hash string, at time.Time) error {
+	const q = `
+		UPDATE api_key SET
+			requests = requests + 1,
+			last_used_at = GREATEST(last_used_at, $2)
+		WHERE
+			hash = $1
+	`
+
+	if _, err := ur.db.ExecContext(ctx, q, hash, at); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("record API key use with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("record API key use with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by both sql.Row and sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAPIKey scans the API key selected with its usage.
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var scopes *string
+	key := new(models.APIKey)
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&scopes,
+		&key.Hash,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.Requests,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if key.Scopes, err = decodeScopes(scopes); err != nil {
+		return nil, fmt.Errorf("decode API key scopes: %w", err)
+	}
+	return key, nil
+}
+
+// encodeScopes returns the space separated scopes, NULL if not limited.
+func encodeScopes(scopes []user.Scope) *string {
+	if scopes == nil {
+		return nil
+	}
+	s := user.FormatScopes(scopes)
+	return &s
+}
+
+// decodeScopes parses the scopes encoded by encodeScopes.
+func decodeScopes(s *string) ([]user.Scope, error) {
+	if s == nil {
+		return nil, nil
+	}
+	scopes, err := user.ParseScopes(*s)
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = []user.Scope{}
+	}
+	return scopes, nil
+}
+
+// SaveIdempotentResponse saves the response until it expires.
+// If the unexpired response of the user with the same key is already
+// saved, ErrConflict is returned. The expired responses are removed.
+func (ur *URLRepository) SaveIdempotentResponse(ctx context.Context, resp *models.IdempotentResponse) error {
+	const purge = `
+		DELETE FROM idempotency_key
+		WHERE
+			expires_at <= now()
+	`
+
+	if _, err := ur.db.ExecContext(ctx, purge); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("purge idempotent responses with query (%s): %w",
+				formatQuery(purge), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("purge idempotent responses with query (%s): %w", formatQuery(purge), err)
+	}
+
+	const q = `
+		INSERT INTO idempotency_key
+			(user_id, key, request_hash, status_code, content_type, body, expires_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`
+
+	res, err := ur.db.ExecContext(ctx, q,
+		resp.UserID,
+		resp.Key,
+		resp.RequestHash,
+		resp.StatusCode,
+		resp.ContentType,
+		resp.Body,
+		resp.ExpiresAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("save idempotent response with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("save idempotent response with query (%s): %w", formatQuery(q), err)
+	}
+
+	// nothing inserted means the response is already saved
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errs.ErrConflict
+	}
+
+	return nil
+}
+
+// GetIdempotentResponse retrieves the unexpired response of the user
+// by the key. If there is none, ErrNotFound is returned.
+func (ur *URLRepository) GetIdempotentResponse(
+	ctx context.Context, userID user.ID, key string,
+) (*models.IdempotentResponse, error) {
+	const q = `
+		SELECT
+			request_hash, status_code, content_type, body, expires_at
+		FROM
+			idempotency_key
+		WHERE
+			user_id = $1 AND key = $2 AND expires_at > now()
+	`
+
+	resp := &models.IdempotentResponse{UserID: userID, Key: key}
+	err := ur.db.QueryRowContext(ctx, q, userID, key).Scan(
+		&resp.RequestHash,
+		&resp.StatusCode,
+		&resp.ContentType,
+		&resp.Body,
+		&resp.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve idempotent response with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve idempotent response with query (%s): %w", formatQuery(q), err)
+	}
+
+	return resp, nil
+}
+
+// GetCertificateCache retrieves the ACME certificate cache entry by the key.
+// If there is none, ErrNotFound is returned.
+func (ur *URLRepository) GetCertificateCache(ctx context.Context, key string) ([]byte, error) {
+	const q = `
+		SELECT
+			data
+		FROM
+			certificate_cache
+		WHERE
+			key = $1
+	`
+
+	var data []byte
+	if err := ur.db.QueryRowContext(ctx, q, key).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return nil, fmt.Errorf("retrieve certificate cache with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return nil, fmt.Errorf("retrieve certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return data, nil
+}
+
+// PutCertificateCache saves the ACME certificate cache entry,
+// replacing the one with the same key.
+func (ur *URLRepository) PutCertificateCache(ctx context.Context, key string, data []byte) error {
+	const q = `
+		INSERT INTO certificate_cache
+			(key, data, updated_at)
+		VALUES
+			($1, $2, now())
+		ON CONFLICT (key) DO UPDATE SET
+			data = EXCLUDED.data,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := ur.db.ExecContext(ctx, q, key, data); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("save certificate cache with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("save certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// DeleteCertificateCache removes the ACME certificate cache entry.
+// Removing the missing entry is not an error.
+func (ur *URLRepository) DeleteCertificateCache(ctx context.Context, key string) error {
+	const q = `
+		DELETE FROM certificate_cache
+		WHERE
+			key = $1
+	`
+
+	if _, err := ur.db.ExecContext(ctx, q, key); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return fmt.Errorf("delete certificate cache with query (%s): %w",
+				formatQuery(q), formatPgError(pgErr),
+			)
+		}
+		return fmt.Errorf("delete certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// PoolStats returns the stats of the pool of the database connections.
+func (ur *URLRepository) PoolStats() sql.DBStats {
+	return ur.db.Stats()
+}
+
 // Ping verifies the connection to the database is alive.
 func (ur *URLRepository) Ping(ctx context.Context) error {
 	return ur.db.PingContext(ctx)
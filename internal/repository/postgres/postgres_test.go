@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyDriver is the database/sql driver answering every query with no rows,
+// as the pgx driver does on the miss.
+type emptyDriver struct{}
+
+func (emptyDriver) Open(string) (driver.Conn, error) { return emptyConn{}, nil }
+
+type emptyConn struct{}
+
+func (emptyConn) Prepare(string) (driver.Stmt, error) { return emptyStmt{}, nil }
+func (emptyConn) Close() error                        { return nil }
+func (emptyConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type emptyStmt struct{}
+
+func (emptyStmt) Close() error                               { return nil }
+func (emptyStmt) NumInput() int                              { return -1 }
+func (emptyStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (emptyStmt) Query([]driver.Value) (driver.Rows, error)  { return emptyRows{}, nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("postgres-empty", emptyDriver{})
+}
+
+func newEmptyStore(t *testing.T) *URLRepository {
+	t.Helper()
+	db, err := sql.Open("postgres-empty", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	l, _ := logger.NewForTest()
+	store, err := NewURLRepository(db, l)
+	require.NoError(t, err)
+	return store
+}
+
+func TestURLRepository_Get_NotFound(t *testing.T) {
+	_, err := newEmptyStore(t).Get(context.Background(), "abc")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestURLRepository_GetByOriginalURL_NotFound(t *testing.T) {
+	_, err := newEmptyStore(t).GetByOriginalURL(context.Background(), "https://go.dev/")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
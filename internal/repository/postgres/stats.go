@@ -0,0 +1,106 @@
+package postgres
+
+import "context"
+
+// EstimatedStats counts the URLs and their users approximately
+// from the statistics of the planner instead of scanning the url table.
+// The counts are as of the last analyze of the table, which autovacuum
+// keeps reasonably fresh.
+type EstimatedStats struct {
+	ur *URLRepository
+}
+
+// EstimatedStats returns the statistics storage of the planner estimates.
+func (ur *URLRepository) EstimatedStats() *EstimatedStats {
+	return &EstimatedStats{ur: ur}
+}
+
+// CountShortURLs returns the estimated number of not deleted short URLs:
+// the estimated rows of the table times the frequency of not deleted ones.
+func (s *EstimatedStats) CountShortURLs(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			(GREATEST(c.reltuples, 0) * COALESCE((
+				SELECT
+					COALESCE(s.most_common_freqs[
+						array_position(s.most_common_vals::text::boolean[], FALSE)
+					], 0)
+				FROM
+					pg_stats s
+				WHERE
+					s.schemaname = 'public' AND s.tablename = 'url' AND s.attname = 'is_deleted'
+			), 1))::bigint
+		FROM
+			pg_class c
+		WHERE
+			c.oid = 'public.url'::regclass
+	`
+
+	return s.ur.count(ctx, q)
+}
+
+// CountUsers returns the estimated number of distinct users,
+// deleted URLs included.
+func (s *EstimatedStats) CountUsers(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			COALESCE((
+				SELECT
+					CASE
+						WHEN s.n_distinct >= 0 THEN s.n_distinct
+						ELSE -s.n_distinct * GREATEST(c.reltuples, 0)
+					END
+				FROM
+					pg_stats s
+				WHERE
+					s.schemaname = 'public' AND s.tablename = 'url' AND s.attname = 'user_id'
+			), 0)::bigint
+		FROM
+			pg_class c
+		WHERE
+			c.oid = 'public.url'::regclass
+	`
+
+	return s.ur.count(ctx, q)
+}
+
+// Approximate reports that the counts are estimated.
+func (s *EstimatedStats) Approximate() bool {
+	return true
+}
+
+// CounterStats reads the numbers of the URLs and their users
+// from the counters the triggers on the url table maintain.
+// They are exact, as they are updated in the writing transactions.
+type CounterStats struct {
+	ur *URLRepository
+}
+
+// CounterStats returns the statistics storage of the maintained counters.
+func (ur *URLRepository) CounterStats() *CounterStats {
+	return &CounterStats{ur: ur}
+}
+
+// CountShortURLs returns the counter of not deleted short URLs.
+func (s *CounterStats) CountShortURLs(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			short_urls
+		FROM
+			url_count
+	`
+
+	return s.ur.count(ctx, q)
+}
+
+// CountUsers returns the counter of users owning at least one URL.
+func (s *CounterStats) CountUsers(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			users
+		FROM
+			url_count
+	`
+
+	return s.ur.count(ctx, q)
+}
@@ -0,0 +1,1080 @@
+// Package redisstore implements the URL storage on top of Redis.
+//
+// Every URL record is a hash stored under the "url:<short URL>" key.
+// The "orig:<original URL>" keys index the short URLs by the original ones,
+// so that the same URL can't be shortened twice, and the "user:<user ID>"
+// sets hold the short URLs of every user. If the TTL is set, all the keys
+// of the record expire after it passes since creation. The "expiry" sorted
+// set holds the short URLs with the expiration time, scored by it, so that
+// the expired ones can be found without scanning.
+//
+// Redirects of every short URL are appended to the "clicks:<short URL>"
+// stream and counted by days in the "daily:<short URL>" hash, so that
+// the statistics are read without aggregating the stream. The click keys
+// expire with the record if the TTL is set, which requires Redis 7.
+//
+// The "pending" list is the queue of the scheduled deletions.
+//
+// API keys are hashes stored under the "apikey:<key hash>" keys.
+// The "apikeys:<user ID>" sets hold the key hashes of every user.
+//
+// Idempotent responses are JSON strings stored under the
+// "idempotency:<user ID>:<key hash>" keys expiring with the responses.
+//
+// All the keys of a record are modified by Lua scripts atomically,
+// so the storage requires a single Redis node or a replicated setup
+// without sharding.
+package redisstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/redis/go-redis/v9"
+)
+
+// Hash fields of the URL record.
+const (
+	fieldID             = "id"
+	fieldShortURL       = "short_url"
+	fieldOriginalURL    = "original_url"
+	fieldUserID         = "user_id"
+	fieldIsDeleted      = "is_deleted"
+	fieldLastAccessedAt = "last_accessed_at"
+	fieldExpiresAt      = "expires_at"
+	fieldCreatorIP      = "creator_ip"
+	fieldUserAgent      = "user_agent"
+	fieldOrigin         = "origin"
+	fieldRedirectLimit  = "redirect_limit"
+	fieldNote           = "note"
+	fieldDomain         = "domain"
+	fieldState          = "state"
+)
+
+// saveScript saves the record unless its short or original URL
+// already exists. It returns 1 if the record is saved, 0 otherwise.
+//
+// KEYS: url key, original URL key, user key, expiry key.
+// ARGV: id, short URL, original URL, user ID, creator IP,
+// user agent, origin, TTL in milliseconds,
+// expiration time in Unix microseconds or empty string, redirect limit, note,
+// domain.
+var saveScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1],
+	'id', ARGV[1], 'short_url', ARGV[2], 'original_url', ARGV[3],
+	'user_id', ARGV[4], 'is_deleted', '0',
+	'creator_ip', ARGV[5], 'user_agent', ARGV[6], 'origin', ARGV[7],
+	'redirect_limit', ARGV[10], 'note', ARGV[11], 'domain', ARGV[12])
+redis.call('SET', KEYS[2], ARGV[2])
+redis.call('SADD', KEYS[3], ARGV[2])
+if ARGV[9] ~= '' then
+	redis.call('HSET', KEYS[1], 'expires_at', ARGV[9])
+	redis.call('ZADD', KEYS[4], ARGV[9], ARGV[2])
+end
+local ttl = tonumber(ARGV[8])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+	redis.call('PEXPIRE', KEYS[3], ttl)
+end
+return 1
+`)
+
+// deleteScript marks the record as deleted if it belongs to the user.
+//
+// KEYS: url key.
+// ARGV: user ID.
+var deleteScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'is_deleted', '1')
+	return 1
+end
+return 0
+`)
+
+// redirectLimitScript sets the redirect limit of the record
+// if it belongs to the user. It returns 1 if the limit is set, 0 otherwise.
+//
+// KEYS: url key.
+// ARGV: user ID, redirect limit.
+var redirectLimitScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'redirect_limit', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// noteScript sets the note of the record if it belongs to the user.
+// It returns 1 if the note is set, 0 otherwise.
+//
+// KEYS: url key.
+// ARGV: user ID, note.
+var noteScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'note', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// stateScript changes the state of the record if it belongs to the user
+// and its state, active if it has never been set, is still the given one.
+// It returns 1 if the state is set, 0 if the record is missing and -1
+// if its state has changed.
+//
+// KEYS: url key.
+// ARGV: user ID, from state, to state.
+var stateScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') ~= ARGV[1] then
+	return 0
+end
+local state = redis.call('HGET', KEYS[1], 'state')
+if not state or state == '' then
+	state = 'active'
+end
+if state ~= ARGV[2] then
+	return -1
+end
+redis.call('HSET', KEYS[1], 'state', ARGV[3])
+return 1
+`)
+
+// touchScript sets the last access time of the record
+// unless it already has a later one.
+//
+// KEYS: url key.
+// ARGV: access time in Unix microseconds.
+var touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local last = tonumber(redis.call('HGET', KEYS[1], 'last_accessed_at') or '0')
+if tonumber(ARGV[1]) > last then
+	redis.call('HSET', KEYS[1], 'last_accessed_at', ARGV[1])
+end
+return 1
+`)
+
+// expireScript marks the records expired by the given time as deleted
+// and removes them from the expiry set. It processes at most the given
+// number of records and returns the number of removed and deleted ones.
+//
+// KEYS: expiry key.
+// ARGV: time in Unix microseconds, limit, url key prefix.
+var expireScript = redis.NewScript(`
+local codes = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local deleted = 0
+for _, code in ipairs(codes) do
+	local key = ARGV[3] .. code
+	if redis.call('HGET', key, 'is_deleted') == '0' then
+		redis.call('HSET', key, 'is_deleted', '1')
+		deleted = deleted + 1
+	end
+end
+if #codes > 0 then
+	redis.call('ZREM', KEYS[1], unpack(codes))
+end
+return {#codes, deleted}
+`)
+
+// expireBatchSize is the number of records expireScript processes at once.
+const expireBatchSize = 1000
+
+// Stream fields of the click.
+const (
+	fieldClickedAt = "clicked_at"
+	fieldReferrer  = "referrer"
+	fieldIPHash    = "ip_hash"
+)
+
+// URLRepository is the Redis URL storage.
+type URLRepository struct {
+	client *redis.Client
+	// prefix is prepended to all the keys.
+	prefix string
+	// ttl is the lifetime of the records, zero means forever.
+	ttl time.Duration
+}
+
+// NewURLRepository returns the Redis URL storage.
+// Records expire after the given TTL unless it is zero.
+func NewURLRepository(client *redis.Client, prefix string, ttl time.Duration) (*URLRepository, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%w: redis client", errs.ErrNilDependency)
+	}
+	return &URLRepository{client: client, prefix: prefix, ttl: ttl}, nil
+}
+
+// Save saves a new URL record. If the short or original URL
+// already exists, ErrConflict is returned.
+func (r *URLRepository) Save(ctx context.Context, u *models.URL) error {
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	saved, err := r.save(ctx, r.client, u).Int()
+	if err != nil {
+		return fmt.Errorf("save url: %w", err)
+	}
+	if saved == 0 {
+		return errs.ErrConflict
+	}
+
+	return nil
+}
+
+// SaveAll saves multiple URL records in a single transaction.
+// If a URL record already exists, the record is not saved.
+func (r *URLRepository) SaveAll(ctx context.Context, urls []*models.URL) error {
+	for _, u := range urls {
+		if err := u.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, u := range urls {
+			r.save(ctx, pipe, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save urls: %w", err)
+	}
+
+	return nil
+}
+
+// save runs the save script for the record.
+func (r *URLRepository) save(ctx context.Context, c redis.Scripter, u *models.URL) *redis.Cmd {
+	keys := []string{
+		r.urlKey(u.ShortURL),
+		r.key("orig:", string(u.OriginalURL)),
+		r.key("user:", string(u.UserID)),
+		r.key("expiry", ""),
+	}
+	expiresAt := ""
+	if u.ExpiresAt != nil {
+		expiresAt = strconv.FormatInt(u.ExpiresAt.UnixMicro(), 10)
+	}
+	args := []any{
+		u.ID, string(u.ShortURL), string(u.OriginalURL), string(u.UserID),
+		u.Metadata.CreatorIP, u.Metadata.UserAgent, string(u.Metadata.Origin),
+		r.ttl.Milliseconds(), expiresAt, u.RedirectLimit, u.Note, u.Domain,
+	}
+	return saveScript.Eval(ctx, c, keys, args...)
+}
+
+// Get retrieves the URL record by its short URL.
+// If the record does not exist, ErrNotFound is returned.
+func (r *URLRepository) Get(ctx context.Context, sURL models.ShortURL) (*models.URL, error) {
+	fields, err := r.client.HGetAll(ctx, r.urlKey(sURL)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve url: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return decode(fields)
+}
+
+// GetByOriginalURL retrieves the URL record by its original URL
+// indexed by the "orig:" keys.
+func (r *URLRepository) GetByOriginalURL(ctx context.Context, originalURL models.OriginalURL) (*models.URL, error) {
+	sURL, err := r.client.Get(ctx, r.key("orig:", string(originalURL))).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve short url: %w", err)
+	}
+
+	return r.Get(ctx, models.ShortURL(sURL))
+}
+
+// GetMany retrieves the URL records by their short URLs in a single
+// round trip. Short URLs that are not found are skipped.
+func (r *URLRepository) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	cmds := make([]*redis.MapStringStringCmd, 0, len(sURLs))
+	seen := make(map[models.ShortURL]struct{}, len(sURLs))
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, sURL := range sURLs {
+			if _, ok := seen[sURL]; ok {
+				continue
+			}
+			seen[sURL] = struct{}{}
+			cmds = append(cmds, pipe.HGetAll(ctx, r.urlKey(sURL)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve urls: %w", err)
+	}
+
+	return decodeAll(cmds)
+}
+
+// GetAllByUserID retrieves all the URL records of the user.
+// If there are none, ErrNotFound is returned.
+func (r *URLRepository) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	userKey := r.key("user:", string(userID))
+
+	members, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve user urls: %w", err)
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(members))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, m := range members {
+			cmds[i] = pipe.HGetAll(ctx, r.urlKey(models.ShortURL(m)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve user urls: %w", err)
+	}
+
+	// forget the expired records
+	var expired []any
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			expired = append(expired, members[i])
+		}
+	}
+	if len(expired) > 0 {
+		if err = r.client.SRem(ctx, userKey, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("remove expired user urls: %w", err)
+		}
+	}
+
+	all, err := decodeAll(cmds)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return all, nil
+}
+
+// DeleteURLs marks the URL records as deleted in a single transaction.
+// Records that don't belong to the user given in the URL are skipped.
+func (r *URLRepository) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, u := range urls {
+			deleteScript.Eval(ctx, pipe, []string{r.urlKey(u.ShortURL)}, string(u.UserID))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete urls: %w", err)
+	}
+
+	return nil
+}
+
+// SetRedirectLimit sets the redirects allowed per minute for the URL
+// of the user. If the user has no such URL, ErrNotFound is returned.
+func (r *URLRepository) SetRedirectLimit(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, limit int,
+) error {
+	if err := models.ValidateRedirectLimit(limit); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	set, err := redirectLimitScript.Run(ctx, r.client,
+		[]string{r.urlKey(shortURL)}, string(userID), limit).Int()
+	if err != nil {
+		return fmt.Errorf("set redirect limit: %w", err)
+	}
+	if set == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetNote sets the note of the URL of the user.
+// If the user has no such URL, ErrNotFound is returned.
+func (r *URLRepository) SetNote(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, note string,
+) error {
+	if err := models.ValidateNote(note); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	set, err := noteScript.Run(ctx, r.client,
+		[]string{r.urlKey(shortURL)}, string(userID), note).Int()
+	if err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+	if set == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetLinkState changes the state of the URL of the user if it is still
+// the given one. The state is checked by the script atomically, so that
+// the concurrent changes are not overwritten. If the user has no such URL,
+// ErrNotFound is returned, if its state has changed, ErrConflict.
+func (r *URLRepository) SetLinkState(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	for _, state := range []models.LinkState{from, to} {
+		if err := models.ValidateStoredState(state); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	set, err := stateScript.Run(ctx, r.client,
+		[]string{r.urlKey(shortURL)}, string(userID), string(from), string(to)).Int()
+	if err != nil {
+		return fmt.Errorf("set link state: %w", err)
+	}
+	switch set {
+	case 0:
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	case -1:
+		return fmt.Errorf("%s: state is not %s: %w", shortURL, from, errs.ErrConflict)
+	}
+
+	return nil
+}
+
+// UpdateLastAccessed sets the last access time of the short URLs.
+// Older times never overwrite newer ones.
+func (r *URLRepository) UpdateLastAccessed(
+	ctx context.Context, accessed map[models.ShortURL]time.Time,
+) error {
+	if len(accessed) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for sURL, at := range accessed {
+			touchScript.Eval(ctx, pipe, []string{r.urlKey(sURL)}, at.UnixMicro())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update last access time: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired marks the records expired by now as deleted
+// and returns their number.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		res, err := expireScript.Run(ctx, r.client, []string{r.key("expiry", "")},
+			now.UnixMicro(), expireBatchSize, r.key("url:", "")).Int64Slice()
+		if err != nil {
+			return total, fmt.Errorf("delete expired urls: %w", err)
+		}
+		total += int(res[1])
+		if res[0] < expireBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// SaveClicks appends the clicks to the streams of the short URLs
+// and updates the daily counters in a single transaction.
+func (r *URLRepository) SaveClicks(ctx context.Context, clicks ...*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, c := range clicks {
+			stream, daily, agents := r.clickKeys(c.ShortURL)
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: stream,
+				Values: []any{
+					fieldClickedAt, c.ClickedAt.UnixMicro(),
+					fieldReferrer, c.Referrer,
+					fieldUserAgent, c.UserAgent,
+					fieldIPHash, c.IPHash,
+				},
+			})
+			pipe.HIncrBy(ctx, daily, c.ClickedAt.UTC().Format(models.DateLayout), 1)
+			pipe.HIncrBy(ctx, agents, c.UserAgent, 1)
+			if r.ttl > 0 {
+				pipe.ExpireNX(ctx, stream, r.ttl)
+				pipe.ExpireNX(ctx, daily, r.ttl)
+				pipe.ExpireNX(ctx, agents, r.ttl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save clicks: %w", err)
+	}
+
+	return nil
+}
+
+// GetClickStats returns the click statistics of the short URL
+// from the daily counters and the last click of the stream.
+func (r *URLRepository) GetClickStats(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.ClickStats, error) {
+	stream, daily, agents := r.clickKeys(shortURL)
+
+	var (
+		dailyCmd  *redis.MapStringStringCmd
+		agentsCmd *redis.MapStringStringCmd
+		lastCmd   *redis.XMessageSliceCmd
+	)
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		dailyCmd = pipe.HGetAll(ctx, daily)
+		agentsCmd = pipe.HGetAll(ctx, agents)
+		lastCmd = pipe.XRevRangeN(ctx, stream, "+", "-", 1)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("retrieve click stats: %w", err)
+	}
+
+	stats := &models.ClickStats{ShortURL: shortURL, Daily: make([]models.DailyClicks, 0)}
+	for date, v := range dailyCmd.Val() {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("decode clicks of %s on %s: %w", shortURL, date, err)
+		}
+		stats.Daily = append(stats.Daily, models.DailyClicks{Date: date, Clicks: n})
+		stats.TotalClicks += n
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+
+	userAgents := make(map[string]int, len(agentsCmd.Val()))
+	for ua, v := range agentsCmd.Val() {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("decode clicks of %s by user agent: %w", shortURL, err)
+		}
+		userAgents[ua] = n
+	}
+	stats.AddUserAgents(userAgents)
+
+	if last := lastCmd.Val(); len(last) > 0 {
+		fields := make(map[string]string, len(last[0].Values))
+		for k, v := range last[0].Values {
+			fields[k], _ = v.(string)
+		}
+		if stats.LastAccessedAt, err = decodeTime(fields, fieldClickedAt); err != nil {
+			return nil, fmt.Errorf("decode %s of %s: %w", fieldClickedAt, shortURL, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// SavePendingDeletions appends the scheduled deletions to the queue.
+// Deletions already in the queue are skipped.
+func (r *URLRepository) SavePendingDeletions(ctx context.Context, urls ...*models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	key := r.key("pending", "")
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, u := range urls {
+			member, err := encodePending(u)
+			if err != nil {
+				return err
+			}
+			pipe.LRem(ctx, key, 0, member)
+			pipe.RPush(ctx, key, member)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save pending deletions: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingDeletions returns all the deletions in the queue
+// in the order they were saved.
+func (r *URLRepository) GetPendingDeletions(ctx context.Context) ([]*models.URL, error) {
+	members, err := r.client.LRange(ctx, r.key("pending", ""), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve pending deletions: %w", err)
+	}
+
+	all := make([]*models.URL, 0, len(members))
+	for _, m := range members {
+		var pair [2]string
+		if err = json.Unmarshal([]byte(m), &pair); err != nil {
+			return nil, fmt.Errorf("decode pending deletion %q: %w", m, err)
+		}
+		all = append(all, &models.URL{ShortURL: models.ShortURL(pair[0]), UserID: user.ID(pair[1])})
+	}
+
+	return all, nil
+}
+
+// RemovePendingDeletions removes the applied deletions from the queue.
+func (r *URLRepository) RemovePendingDeletions(ctx context.Context, urls ...*models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	key := r.key("pending", "")
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, u := range urls {
+			member, err := encodePending(u)
+			if err != nil {
+				return err
+			}
+			pipe.LRem(ctx, key, 0, member)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("remove pending deletions: %w", err)
+	}
+
+	return nil
+}
+
+// encodePending encodes the deletion as the queue member.
+func encodePending(u *models.URL) (string, error) {
+	b, err := json.Marshal([2]string{string(u.ShortURL), string(u.UserID)})
+	if err != nil {
+		return "", fmt.Errorf("encode pending deletion: %w", err)
+	}
+	return string(b), nil
+}
+
+// scanBatchSize is the number of the keys requested by a single SCAN.
+const scanBatchSize = 500
+
+// ScanURLs calls fn for every URL record, including the deleted ones.
+// The records are iterated with SCAN, so the ones saved or deleted
+// during the iteration may be missed.
+func (r *URLRepository) ScanURLs(ctx context.Context, fn func(*models.URL) error) error {
+	iter := r.client.Scan(ctx, 0, r.urlKey("*"), scanBatchSize).Iterator()
+	keys := make([]string, 0, scanBatchSize)
+
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		cmds := make([]*redis.MapStringStringCmd, len(keys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.HGetAll(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("scan urls: %w", err)
+		}
+		keys = keys[:0]
+
+		all, err := decodeAll(cmds)
+		if err != nil {
+			return err
+		}
+		for _, u := range all {
+			if err = fn(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < scanBatchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("scan urls: %w", err)
+	}
+
+	return flush()
+}
+
+// CountShortURLs returns the number of not deleted short URLs.
+// The records are scanned, so the counts are better cached.
+func (r *URLRepository) CountShortURLs(ctx context.Context) (int, error) {
+	urls, _, err := r.count(ctx)
+	return urls, err
+}
+
+// CountUsers returns the number of distinct users owning at least one URL.
+// The records are scanned, so the counts are better cached.
+func (r *URLRepository) CountUsers(ctx context.Context) (int, error) {
+	_, users, err := r.count(ctx)
+	return users, err
+}
+
+// count returns the numbers of not deleted short URLs and of their users.
+func (r *URLRepository) count(ctx context.Context) (urls, users int, err error) {
+	owners := make(map[user.ID]struct{})
+	err = r.ScanURLs(ctx, func(u *models.URL) error {
+		if !u.IsDeleted {
+			urls++
+			owners[u.UserID] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("count urls: %w", err)
+	}
+	return urls, len(owners), nil
+}
+
+// SaveAPIKey saves the API key as the hash stored by the key hash
+// and adds it to the API keys of the user.
+func (r *URLRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	fields := []any{
+		"id", key.ID,
+		"user_id", string(key.UserID),
+		"name", key.Name,
+		"created_at", key.CreatedAt.Format(time.RFC3339Nano),
+	}
+	// the missing field stands for the key without limits
+	if key.Scopes != nil {
+		fields = append(fields, "scopes", user.FormatScopes(key.Scopes))
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.key("apikey:", key.Hash), fields...)
+		pipe.SAdd(ctx, r.key("apikeys:", string(key.UserID)), key.Hash)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKey retrieves the API key by its hash.
+// If the key is unknown, ErrNotFound is returned.
+func (r *URLRepository) GetAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	fields, err := r.client.HGetAll(ctx, r.key("apikey:", hash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve API key: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return decodeAPIKey(hash, fields)
+}
+
+// ListAPIKeys returns the API keys of the user in the order they were minted.
+// The keys minted before the index of the user keys was introduced are
+// listed after they are used.
+func (r *URLRepository) ListAPIKeys(ctx context.Context, userID user.ID) ([]*models.APIKey, error) {
+	hashes, err := r.client.SMembers(ctx, r.key("apikeys:", string(userID))).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(hashes))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, hash := range hashes {
+			cmds[i] = pipe.HGetAll(ctx, r.key("apikey:", hash))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+
+	keys := make([]*models.APIKey, 0, len(hashes))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		// skip the keys rotated after the index is read
+		if len(fields) == 0 {
+			continue
+		}
+		key, err := decodeAPIKey(hashes[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+// RotateAPIKey moves the API key of the user to the new hash.
+// If the user has no key with the ID, ErrNotFound is returned.
+func (r *URLRepository) RotateAPIKey(ctx context.Context, userID user.ID, id, hash string) error {
+	keys, err := r.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.ID != id {
+			continue
+		}
+		_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Rename(ctx, r.key("apikey:", key.Hash), r.key("apikey:", hash))
+			pipe.SRem(ctx, r.key("apikeys:", string(userID)), key.Hash)
+			pipe.SAdd(ctx, r.key("apikeys:", string(userID)), hash)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("rotate API key: %w", err)
+		}
+		return nil
+	}
+
+	return errs.ErrNotFound
+}
+
+// RecordAPIKeyUse counts the request with the API key
+// and sets the time of its last use.
+func (r *URLRepository) RecordAPIKeyUse(ctx context.Context, hash string, at time.Time) error {
+	key, err := r.GetAPIKey(ctx, hash)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		k := r.key("apikey:", hash)
+		pipe.HIncrBy(ctx, k, "requests", 1)
+		if key.LastUsedAt == nil || at.After(*key.LastUsedAt) {
+			pipe.HSet(ctx, k, "last_used_at", at.Format(time.RFC3339Nano))
+		}
+		pipe.SAdd(ctx, r.key("apikeys:", string(key.UserID)), hash)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record API key use: %w", err)
+	}
+
+	return nil
+}
+
+// decodeAPIKey decodes the API key stored as the hash.
+func decodeAPIKey(hash string, fields map[string]string) (*models.APIKey, error) {
+	key := &models.APIKey{
+		ID:     fields["id"],
+		UserID: user.ID(fields["user_id"]),
+		Name:   fields["name"],
+		Hash:   hash,
+	}
+
+	var err error
+	if key.CreatedAt, err = time.Parse(time.RFC3339Nano, fields["created_at"]); err != nil {
+		return nil, fmt.Errorf("decode API key created at: %w", err)
+	}
+	if s, ok := fields["scopes"]; ok {
+		if key.Scopes, err = user.ParseScopes(s); err != nil {
+			return nil, fmt.Errorf("decode API key scopes: %w", err)
+		}
+		if key.Scopes == nil {
+			key.Scopes = []user.Scope{}
+		}
+	}
+	if s, ok := fields["last_used_at"]; ok {
+		at, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("decode API key last used at: %w", err)
+		}
+		key.LastUsedAt = &at
+	}
+	if s, ok := fields["requests"]; ok {
+		if key.Requests, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("decode API key requests: %w", err)
+		}
+	}
+
+	return key, nil
+}
+
+// SaveIdempotentResponse saves the response until it expires.
+// If the unexpired response of the user with the same key is already
+// saved, ErrConflict is returned.
+func (r *URLRepository) SaveIdempotentResponse(ctx context.Context, resp *models.IdempotentResponse) error {
+	ttl := time.Until(resp.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encode idempotent response: %w", err)
+	}
+
+	ok, err := r.client.SetNX(ctx, r.idempotencyKey(resp.UserID, resp.Key), b, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("save idempotent response: %w", err)
+	}
+	if !ok {
+		return errs.ErrConflict
+	}
+
+	return nil
+}
+
+// GetIdempotentResponse retrieves the unexpired response of the user
+// by the key. If there is none, ErrNotFound is returned.
+func (r *URLRepository) GetIdempotentResponse(
+	ctx context.Context, userID user.ID, key string,
+) (*models.IdempotentResponse, error) {
+	b, err := r.client.Get(ctx, r.idempotencyKey(userID, key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve idempotent response: %w", err)
+	}
+
+	resp := new(models.IdempotentResponse)
+	if err = json.Unmarshal(b, resp); err != nil {
+		return nil, fmt.Errorf("decode idempotent response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Ping checks the connection to Redis.
+func (r *URLRepository) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrDBNotConnected, err)
+	}
+	return nil
+}
+
+// key returns the prefixed key of the given kind.
+func (r *URLRepository) key(kind, id string) string {
+	return r.prefix + kind + id
+}
+
+// urlKey returns the key of the URL record.
+func (r *URLRepository) urlKey(sURL models.ShortURL) string {
+	return r.key("url:", string(sURL))
+}
+
+// idempotencyKey returns the key of the idempotent response. The key
+// chosen by the client is hashed, so that it can't collide with the user ID.
+func (r *URLRepository) idempotencyKey(userID user.ID, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return r.key("idempotency:", string(userID)+":"+hex.EncodeToString(sum[:]))
+}
+
+// clickKeys returns the stream, daily counters and user agent counters
+// keys of the short URL clicks.
+func (r *URLRepository) clickKeys(sURL models.ShortURL) (stream, daily, agents string) {
+	return r.key("clicks:", string(sURL)), r.key("daily:", string(sURL)),
+		r.key("agents:", string(sURL))
+}
+
+// decodeAll decodes the results of the pipelined HGETALL commands
+// skipping the missing records.
+func decodeAll(cmds []*redis.MapStringStringCmd) ([]*models.URL, error) {
+	all := make([]*models.URL, 0, len(cmds))
+	for _, cmd := range cmds {
+		fields, err := cmd.Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("retrieve url: %w", err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		u, err := decode(fields)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, u)
+	}
+	return all, nil
+}
+
+// decode converts the hash fields to the URL record.
+func decode(fields map[string]string) (*models.URL, error) {
+	u := &models.URL{
+		ID:          fields[fieldID],
+		ShortURL:    models.ShortURL(fields[fieldShortURL]),
+		OriginalURL: models.OriginalURL(fields[fieldOriginalURL]),
+		UserID:      user.ID(fields[fieldUserID]),
+		IsDeleted:   fields[fieldIsDeleted] == "1",
+		Note:        fields[fieldNote],
+		Domain:      fields[fieldDomain],
+		State:       models.LinkState(fields[fieldState]),
+		Metadata: models.Metadata{
+			CreatorIP: fields[fieldCreatorIP],
+			UserAgent: fields[fieldUserAgent],
+			Origin:    models.Origin(fields[fieldOrigin]),
+		},
+	}
+
+	var err error
+	if u.LastAccessedAt, err = decodeTime(fields, fieldLastAccessedAt); err != nil {
+		return nil, fmt.Errorf("decode %s of %s: %w", fieldLastAccessedAt, u.ShortURL, err)
+	}
+	if u.ExpiresAt, err = decodeTime(fields, fieldExpiresAt); err != nil {
+		return nil, fmt.Errorf("decode %s of %s: %w", fieldExpiresAt, u.ShortURL, err)
+	}
+	// the records saved before the limits were introduced have no field
+	if v, ok := fields[fieldRedirectLimit]; ok {
+		if u.RedirectLimit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("decode %s of %s: %w", fieldRedirectLimit, u.ShortURL, err)
+		}
+	}
+
+	return u, nil
+}
+
+// decodeTime decodes the time stored in Unix microseconds.
+// It returns nil if the field is not set.
+func decodeTime(fields map[string]string, field string) (*time.Time, error) {
+	v, ok := fields[field]
+	if !ok {
+		return nil, nil
+	}
+	micro, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	at := time.UnixMicro(micro).UTC()
+	return &at, nil
+}
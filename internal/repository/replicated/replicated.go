@@ -0,0 +1,165 @@
+// Package replicated provides the storage decorator sending the reads
+// of the URL records to the read-only replica of the database, so that
+// the redirects don't load the primary.
+//
+// The writes go to the primary. The records not found on the replica
+// are read from the primary, since the replica may lag behind it, e.g.
+// right after the link is created. The reads fall back to the primary
+// if the replica fails too. The reads marked by consistency.WithPrimary
+// go to the primary at once.
+package replicated
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Storage is the interface of the decorated URL storage.
+type Storage interface {
+	Save(ctx context.Context, url *models.URL) error
+	SaveAll(ctx context.Context, urls []*models.URL) error
+	Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error)
+	GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error)
+	GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error)
+	DeleteURLs(ctx context.Context, urls ...*models.URL) error
+	UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	Ping(ctx context.Context) error
+}
+
+// Interface implementation check.
+var _ Storage = (*Store)(nil)
+
+// Store routes the reads to the replica and the writes to the primary.
+// It is safe for concurrent use if the decorated storages are.
+type Store struct {
+	primary Storage
+	replica Storage
+}
+
+// New returns the store reading from the replica and writing to the primary.
+func New(primary, replica Storage) (*Store, error) {
+	if primary == nil {
+		return nil, fmt.Errorf("%w: primary storage", errs.ErrNilDependency)
+	}
+	if replica == nil {
+		return nil, fmt.Errorf("%w: replica storage", errs.ErrNilDependency)
+	}
+	return &Store{primary: primary, replica: replica}, nil
+}
+
+// Unwrap returns the primary storage.
+func (s *Store) Unwrap() Storage {
+	return s.primary
+}
+
+// Replica returns the replica storage.
+func (s *Store) Replica() Storage {
+	return s.replica
+}
+
+// Save saves the record to the primary.
+func (s *Store) Save(ctx context.Context, url *models.URL) error {
+	return s.primary.Save(ctx, url)
+}
+
+// SaveAll saves the records to the primary.
+func (s *Store) SaveAll(ctx context.Context, urls []*models.URL) error {
+	return s.primary.SaveAll(ctx, urls)
+}
+
+// Get retrieves the record from the replica, or from the primary
+// if the replica doesn't have it.
+func (s *Store) Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error) {
+	if !s.fromReplica(ctx) {
+		return s.primary.Get(ctx, shortURL)
+	}
+
+	url, err := s.replica.Get(ctx, shortURL)
+	if err == nil || ctx.Err() != nil {
+		return url, err
+	}
+	return s.primary.Get(ctx, shortURL)
+}
+
+// GetMany retrieves the records from the replica, the ones
+// the replica doesn't have from the primary.
+func (s *Store) GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error) {
+	if !s.fromReplica(ctx) {
+		return s.primary.GetMany(ctx, shortURLs)
+	}
+
+	urls, err := s.replica.GetMany(ctx, shortURLs)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return s.primary.GetMany(ctx, shortURLs)
+	}
+	if len(urls) == len(shortURLs) {
+		return urls, nil
+	}
+
+	found := make(map[models.ShortURL]struct{}, len(urls))
+	for _, u := range urls {
+		found[u.ShortURL] = struct{}{}
+	}
+	missing := make([]models.ShortURL, 0, len(shortURLs)-len(urls))
+	for _, shortURL := range shortURLs {
+		if _, ok := found[shortURL]; !ok {
+			missing = append(missing, shortURL)
+		}
+	}
+
+	lagging, err := s.primary.GetMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	return append(urls, lagging...), nil
+}
+
+// GetAllByUserID retrieves the records of the user from the replica,
+// or from the primary if the replica has none.
+func (s *Store) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	if !s.fromReplica(ctx) {
+		return s.primary.GetAllByUserID(ctx, userID)
+	}
+
+	urls, err := s.replica.GetAllByUserID(ctx, userID)
+	if err == nil || ctx.Err() != nil {
+		return urls, err
+	}
+	return s.primary.GetAllByUserID(ctx, userID)
+}
+
+// DeleteURLs deletes the records on the primary.
+func (s *Store) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	return s.primary.DeleteURLs(ctx, urls...)
+}
+
+// UpdateLastAccessed sets the last access times on the primary.
+func (s *Store) UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error {
+	return s.primary.UpdateLastAccessed(ctx, accessed)
+}
+
+// DeleteExpired deletes the expired records on the primary.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return s.primary.DeleteExpired(ctx, now)
+}
+
+// Ping checks the health of the primary. The replica failing
+// is not fatal, since the reads fall back to the primary.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.primary.Ping(ctx)
+}
+
+// fromReplica reports whether the reads of the request go to the replica.
+func (s *Store) fromReplica(ctx context.Context) bool {
+	return !consistency.Primary(ctx)
+}
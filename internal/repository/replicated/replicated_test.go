@@ -0,0 +1,67 @@
+package replicated
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/consistency"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, memstore.NewURLRepository())
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+	_, err = New(memstore.NewURLRepository(), nil)
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := memstore.NewURLRepository(), memstore.NewURLRepository()
+	s, err := New(primary, replica)
+	require.NoError(t, err)
+
+	replicated := &models.URL{ShortURL: "First", OriginalURL: "https://go.dev/", UserID: "user"}
+	require.NoError(t, s.Save(ctx, replicated))
+	// the replica has caught up with the first save only
+	require.NoError(t, replica.Save(ctx, &models.URL{
+		ShortURL: "First", OriginalURL: "https://go.dev/", UserID: "user", Note: "replica",
+	}))
+	require.NoError(t, s.Save(ctx, &models.URL{ShortURL: "Second", OriginalURL: "https://pkg.go.dev/", UserID: "user"}))
+
+	got, err := s.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.Equal(t, "replica", got.Note, "read from the replica")
+
+	got, err = s.Get(consistency.WithPrimary(ctx), "First")
+	require.NoError(t, err)
+	assert.Empty(t, got.Note, "marked read from the primary")
+
+	got, err = s.Get(ctx, "Second")
+	require.NoError(t, err, "lagging replica falls back to the primary")
+	assert.EqualValues(t, "https://pkg.go.dev/", got.OriginalURL)
+
+	_, err = s.Get(ctx, "Unknown")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	all, err := s.GetMany(ctx, []models.ShortURL{"First", "Second", "Unknown"})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	urls, err := s.GetAllByUserID(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, urls, 1, "listed from the replica")
+
+	urls, err = s.GetAllByUserID(consistency.WithPrimary(ctx), "user")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	require.NoError(t, s.DeleteURLs(ctx, replicated))
+	got, err = primary.Get(ctx, "First")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted, "deleted on the primary")
+}
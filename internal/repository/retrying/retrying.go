@@ -0,0 +1,203 @@
+// Package retrying provides the storage decorator retrying the operations
+// failed with the transient errors of the database, e.g. the serialization
+// failures, the deadlocks or the connection resets.
+//
+// The retries are delayed with the exponential backoff and the full jitter,
+// so that the instances failed at once don't retry at once. The retries
+// stop when the context is canceled. The saves applied by the database
+// just before the connection was lost are reported as conflicts when they
+// are retried, the same as the saves of the existing URLs. The pings are
+// never retried, so that the health checks see the failures at once.
+package retrying
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+)
+
+// Defaults of the backoff used if they are not set.
+const (
+	defaultRetryBackoff    = 50 * time.Millisecond
+	defaultMaxRetryBackoff = time.Second
+)
+
+// Storage is the interface of the decorated URL storage.
+type Storage interface {
+	Save(ctx context.Context, url *models.URL) error
+	SaveAll(ctx context.Context, urls []*models.URL) error
+	Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error)
+	GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error)
+	GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error)
+	DeleteURLs(ctx context.Context, urls ...*models.URL) error
+	UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	Ping(ctx context.Context) error
+}
+
+// Interface implementation check.
+var _ Storage = (*Store)(nil)
+
+// Store retries the operations of the decorated storage.
+// It is safe for concurrent use.
+type Store struct {
+	// next is the decorated storage.
+	next Storage
+	// transient reports whether the error is worth retrying.
+	transient func(error) bool
+	// retries is the maximum number of the retries of the operation.
+	retries int
+	// backoff is the delay before the first retry.
+	backoff time.Duration
+	// maxBackoff caps the doubled delays.
+	maxBackoff time.Duration
+	// sleep waits for the delay or the context, replaced in the tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns the store decorating next with the retries of the operations
+// failed with the errors reported as transient by the function.
+func New(next Storage, cfg config.Postgres, transient func(error) bool) (*Store, error) {
+	if next == nil {
+		return nil, fmt.Errorf("%w: storage", errs.ErrNilDependency)
+	}
+	if transient == nil {
+		return nil, fmt.Errorf("%w: transient errors classifier", errs.ErrNilDependency)
+	}
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("max retries %d is negative", cfg.MaxRetries)
+	}
+
+	s := &Store{
+		next:       next,
+		transient:  transient,
+		retries:    cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		maxBackoff: cfg.MaxRetryBackoff,
+		sleep:      sleep,
+	}
+	if s.backoff <= 0 {
+		s.backoff = defaultRetryBackoff
+	}
+	if s.maxBackoff < s.backoff {
+		s.maxBackoff = max(defaultMaxRetryBackoff, s.backoff)
+	}
+
+	return s, nil
+}
+
+// Unwrap returns the decorated storage.
+func (s *Store) Unwrap() Storage {
+	return s.next
+}
+
+// Save saves the record.
+func (s *Store) Save(ctx context.Context, url *models.URL) error {
+	return s.do(ctx, func() error {
+		return s.next.Save(ctx, url)
+	})
+}
+
+// SaveAll saves the records.
+func (s *Store) SaveAll(ctx context.Context, urls []*models.URL) error {
+	return s.do(ctx, func() error {
+		return s.next.SaveAll(ctx, urls)
+	})
+}
+
+// Get retrieves the record.
+func (s *Store) Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error) {
+	var url *models.URL
+	err := s.do(ctx, func() (err error) {
+		url, err = s.next.Get(ctx, shortURL)
+		return err
+	})
+	return url, err
+}
+
+// GetMany retrieves the records.
+func (s *Store) GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error) {
+	var urls []*models.URL
+	err := s.do(ctx, func() (err error) {
+		urls, err = s.next.GetMany(ctx, shortURLs)
+		return err
+	})
+	return urls, err
+}
+
+// GetAllByUserID retrieves the records of the user.
+func (s *Store) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	var urls []*models.URL
+	err := s.do(ctx, func() (err error) {
+		urls, err = s.next.GetAllByUserID(ctx, userID)
+		return err
+	})
+	return urls, err
+}
+
+// DeleteURLs deletes the records.
+func (s *Store) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	return s.do(ctx, func() error {
+		return s.next.DeleteURLs(ctx, urls...)
+	})
+}
+
+// UpdateLastAccessed sets the last access times.
+func (s *Store) UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error {
+	return s.do(ctx, func() error {
+		return s.next.UpdateLastAccessed(ctx, accessed)
+	})
+}
+
+// DeleteExpired deletes the expired records.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	var n int
+	err := s.do(ctx, func() (err error) {
+		n, err = s.next.DeleteExpired(ctx, now)
+		return err
+	})
+	return n, err
+}
+
+// Ping checks the health of the decorated storage without the retries.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+// do calls fn retrying the transient errors. If the context is canceled
+// while waiting for the retry, both the context error and the last error
+// of fn are returned.
+func (s *Store) do(ctx context.Context, fn func() error) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.retries || !s.transient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		// full jitter: a random delay up to the backoff
+		if ctxErr := s.sleep(ctx, time.Duration(rand.Int63n(int64(backoff)+1))); ctxErr != nil {
+			return fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// sleep waits for the delay unless the context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package retrying
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTransient = errors.New("serialization failure")
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+// flakyStore is the in-memory storage failing the reads
+// with the error until it is used up.
+type flakyStore struct {
+	*memstore.URLRepository
+	err      error
+	failures int
+	calls    int
+}
+
+func (s *flakyStore) Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error) {
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return nil, s.err
+	}
+	return s.URLRepository.Get(ctx, shortURL)
+}
+
+func newTestStore(t *testing.T, retries int) (*Store, *flakyStore, *[]time.Duration) {
+	t.Helper()
+	next := &flakyStore{URLRepository: memstore.NewURLRepository(), err: errTransient}
+	require.NoError(t, next.Save(context.Background(),
+		&models.URL{ShortURL: "First", OriginalURL: "https://go.dev/", UserID: "user"}))
+
+	s, err := New(next, config.Postgres{
+		MaxRetries:      retries,
+		RetryBackoff:    10 * time.Millisecond,
+		MaxRetryBackoff: 20 * time.Millisecond,
+	}, isTransient)
+	require.NoError(t, err)
+
+	var delays []time.Duration
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return s, next, &delays
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, config.Postgres{}, isTransient)
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+
+	next := memstore.NewURLRepository()
+	_, err = New(next, config.Postgres{}, nil)
+	require.ErrorIs(t, err, errs.ErrNilDependency)
+	_, err = New(next, config.Postgres{MaxRetries: -1}, isTransient)
+	require.Error(t, err)
+}
+
+func TestStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retried until success", func(t *testing.T) {
+		s, next, delays := newTestStore(t, 3)
+		next.failures = 3
+
+		got, err := s.Get(ctx, "First")
+		require.NoError(t, err)
+		assert.EqualValues(t, "https://go.dev/", got.OriginalURL)
+		assert.Equal(t, 4, next.calls)
+
+		require.Len(t, *delays, 3)
+		for i, limit := range []time.Duration{10, 20, 20} {
+			assert.LessOrEqual(t, (*delays)[i], limit*time.Millisecond, "jittered backoff capped")
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		s, next, _ := newTestStore(t, 2)
+		next.failures = 5
+
+		_, err := s.Get(ctx, "First")
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, next.calls)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		s, next, _ := newTestStore(t, 3)
+
+		_, err := s.Get(ctx, "Unknown")
+		require.ErrorIs(t, err, errs.ErrNotFound)
+		assert.Equal(t, 1, next.calls)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		s, next, _ := newTestStore(t, 3)
+		next.failures = 5
+
+		ctx, cancel := context.WithCancel(ctx)
+		s.sleep = func(ctx context.Context, _ time.Duration) error {
+			cancel()
+			return ctx.Err()
+		}
+
+		_, err := s.Get(ctx, "First")
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorIs(t, err, errTransient, "last error kept")
+		assert.Equal(t, 1, next.calls)
+	})
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS url (
+    id text PRIMARY KEY,
+    short_url text NOT NULL UNIQUE,
+    original_url text NOT NULL UNIQUE,
+    user_id text NOT NULL,
+    is_deleted integer NOT NULL DEFAULT 0,
+    last_accessed_at integer,
+    expires_at integer,
+    creator_ip text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    origin text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS url_user_id ON url (user_id);
+CREATE INDEX IF NOT EXISTS url_expires_at ON url (expires_at)
+    WHERE expires_at IS NOT NULL AND NOT is_deleted;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS reservation (
+    short_url text PRIMARY KEY,
+    user_id text NOT NULL,
+    created_at integer NOT NULL
+);
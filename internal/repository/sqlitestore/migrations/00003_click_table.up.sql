@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS click (
+    short_url text NOT NULL,
+    clicked_at integer NOT NULL,
+    referrer text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    ip_hash text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS click_short_url_clicked_at ON click (short_url, clicked_at);
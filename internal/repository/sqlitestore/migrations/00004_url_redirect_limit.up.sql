@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN redirect_limit integer NOT NULL DEFAULT 0;
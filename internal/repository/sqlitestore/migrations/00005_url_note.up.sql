@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN note text NOT NULL DEFAULT '';
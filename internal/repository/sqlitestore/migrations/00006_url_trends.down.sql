@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS url_daily;
+DROP TABLE IF EXISTS url_daily_user;
+DROP TABLE IF EXISTS url_daily;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS url_daily (
+    day text PRIMARY KEY,
+    created integer NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS url_daily_user (
+    day text NOT NULL,
+    user_id text NOT NULL,
+    PRIMARY KEY (day, user_id)
+);
+CREATE TRIGGER IF NOT EXISTS url_daily AFTER INSERT ON url
+BEGIN
+    INSERT INTO url_daily (day, created) VALUES (date('now'), 1)
+        ON CONFLICT (day) DO UPDATE SET created = created + 1;
+    INSERT OR IGNORE INTO url_daily_user (day, user_id) VALUES (date('now'), NEW.user_id);
+END;
@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN domain text NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS certificate_cache (
+    key text PRIMARY KEY,
+    data blob NOT NULL,
+    updated_at integer NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS url_campaign (
+    user_id text NOT NULL,
+    campaign text NOT NULL,
+    short_url text NOT NULL,
+    PRIMARY KEY (user_id, campaign, short_url)
+);
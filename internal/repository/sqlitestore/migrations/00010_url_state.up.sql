@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN state text NOT NULL DEFAULT 'active';
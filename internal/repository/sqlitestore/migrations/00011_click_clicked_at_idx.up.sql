@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS click_clicked_at ON click (clicked_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id text PRIMARY KEY,
+    login text NOT NULL UNIQUE,
+    password_hash text NOT NULL,
+    created_at integer NOT NULL
+);
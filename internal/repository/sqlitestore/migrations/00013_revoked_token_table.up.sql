@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS revoked_token (
+    token_id text PRIMARY KEY,
+    expires_at integer NOT NULL
+);
+CREATE INDEX IF NOT EXISTS revoked_token_expires_at ON revoked_token (expires_at);
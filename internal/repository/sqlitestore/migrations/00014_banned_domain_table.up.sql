@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS banned_domain (
+    domain text PRIMARY KEY,
+    reason text NOT NULL DEFAULT '',
+    created_at integer NOT NULL
+);
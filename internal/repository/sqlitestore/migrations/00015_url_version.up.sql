@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN version integer NOT NULL DEFAULT 0;
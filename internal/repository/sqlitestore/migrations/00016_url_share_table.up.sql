@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS url_share (
+    short_url text NOT NULL,
+    user_id text NOT NULL,
+    created_at integer NOT NULL,
+    PRIMARY KEY (short_url, user_id)
+);
+CREATE INDEX IF NOT EXISTS url_share_user_id ON url_share (user_id);
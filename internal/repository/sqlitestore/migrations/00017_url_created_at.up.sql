@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN created_at integer;
@@ -0,0 +1,1771 @@
+// Package sqlitestore implements the URL storage on top of SQLite,
+// so that a single binary with a local database file is enough to run
+// the service without a database server.
+//
+// The store is selected by the DSN with the sqlite:// scheme followed by
+// the path of the database file, e.g. sqlite:///var/lib/shortener/db.sqlite
+// or sqlite://shortener.db for the path relative to the working directory.
+// The migrations are embedded in the binary and applied on start.
+//
+// Besides URLStorage, the store implements StatsRepository,
+// ReservationStorage, ClickStorage and URLScanner. The API keys,
+// the idempotency keys and the durable deletion queue are not supported.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/hostpolicy"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	sqldblogger "github.com/simukti/sqldb-logger"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Scheme is the scheme of the DSN selecting the SQLite storage.
+const Scheme = "sqlite://"
+
+// maxParams is the number of short URLs queried at once by GetMany,
+// well below the limit of the host parameters of SQLite.
+const maxParams = 500
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// URLRepository implements URLStorage interface.
+type URLRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// New opens the database file of the DSN, applies the migrations
+// and returns the store. The database file is created if it does not
+// exist. The store must be closed to release the file.
+func New(dsn string, logger logger.Logger) (*URLRepository, error) {
+	source, err := dataSource(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the database: %w", err)
+	}
+
+	// Log every query to the database.
+	db = sqldblogger.OpenDriver(source, db.Driver(), logger)
+
+	// Check the database file is accessible.
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to open the database: %w", err)
+	}
+
+	if err = migrateUp(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate DB: %w", err)
+	}
+
+	return &URLRepository{db: db, logger: logger}, nil
+}
+
+// CheckDSN checks that the DSN is valid and the directory of the database
+// file exists, without creating the file.
+func CheckDSN(dsn string) error {
+	if _, err := dataSource(dsn); err != nil {
+		return err
+	}
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, Scheme), "?")
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("database directory: %w", err)
+	}
+	return nil
+}
+
+// dataSource converts the DSN to the data source name of the driver.
+func dataSource(dsn string) (string, error) {
+	path, ok := strings.CutPrefix(dsn, Scheme)
+	if !ok {
+		return "", fmt.Errorf("%w: DSN has no %s scheme", errs.ErrInvalidRequest, Scheme)
+	}
+
+	path, query, _ := strings.Cut(path, "?")
+	if path == "" {
+		return "", fmt.Errorf("%w: DSN has no database path", errs.ErrInvalidRequest)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", fmt.Errorf("%w: DSN query: %w", errs.ErrInvalidRequest, err)
+	}
+	// SQLite allows a single writer, the busy timeout and the immediate
+	// transactions make the concurrent writers wait instead of failing.
+	if !params.Has("_pragma") {
+		params.Add("_pragma", "busy_timeout(5000)")
+		params.Add("_pragma", "journal_mode(WAL)")
+		params.Add("_pragma", "foreign_keys(1)")
+	}
+	if !params.Has("_txlock") {
+		params.Set("_txlock", "immediate")
+	}
+
+	return "file:" + path + "?" + params.Encode(), nil
+}
+
+// migrateUp applies the embedded migrations.
+func migrateUp(db *sql.DB) error {
+	d, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to return an iofs driver: %w", err)
+	}
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to get sqlite driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", d, "sqlite", driver)
+	if err != nil {
+		return fmt.Errorf("failed to init migrate instance: %w", err)
+	}
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Save saves a new URL record to the database.
+// If a URL record already exists, ErrConflict is returned.
+func (ur *URLRepository) Save(ctx context.Context, u *models.URL) error {
+	const q = `
+		INSERT INTO url
+			(id, short_url, original_url, user_id, creator_ip, user_agent, origin, expires_at,
+			redirect_limit, note, domain, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	_, err := ur.db.ExecContext(ctx, q, u.ID, u.ShortURL, u.OriginalURL, u.UserID,
+		u.Metadata.CreatorIP, u.Metadata.UserAgent, u.Metadata.Origin, encodeTime(u.ExpiresAt),
+		u.RedirectLimit, u.Note, u.Domain, u.CreationTime(time.Now()).UnixNano())
+	if err != nil {
+		// return ErrConflict if the record already exists
+		if isConstraintViolation(err) {
+			return errs.ErrConflict
+		}
+		return fmt.Errorf("save url with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// SaveAll saves multiple URL records to the database in a single transaction.
+// If a URL record already exists, the record is not inserted.
+func (ur *URLRepository) SaveAll(ctx context.Context, urls []*models.URL) error {
+	_, err := ur.SaveAllResults(ctx, urls)
+	return err
+}
+
+// SaveAllResults saves multiple URL records to the database in a single
+// transaction and returns the outcome of every record in the batch order.
+// If a URL record already exists, the record is not inserted.
+func (ur *URLRepository) SaveAllResults(ctx context.Context, urls []*models.URL) ([]models.SaveResult, error) {
+	const q = `
+		INSERT INTO url
+			(id, short_url, original_url, user_id, creator_ip, user_agent, origin, expires_at,
+			redirect_limit, note, domain, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	for _, url := range urls {
+		if err := url.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	results := make([]models.SaveResult, len(urls))
+	now := time.Now()
+	err := ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for i, url := range urls {
+			results[i] = models.SaveResult{ShortURL: url.ShortURL, Status: models.SaveStatusCreated}
+			_, err := stmt.ExecContext(ctx, url.ID, url.ShortURL, url.OriginalURL, url.UserID,
+				url.Metadata.CreatorIP, url.Metadata.UserAgent, url.Metadata.Origin,
+				encodeTime(url.ExpiresAt), url.RedirectLimit, url.Note, url.Domain,
+				url.CreationTime(now).UnixNano())
+			if err != nil {
+				// continue if the record already exists
+				if isConstraintViolation(err) {
+					results[i].Status = models.SaveStatusExists
+					continue
+				}
+				return fmt.Errorf("save url with query (%s): %w", formatQuery(q), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Get retrieves a URL record from the database based on its short URL.
+// If the URL record does not exist, ErrNotFound is returned.
+func (ur *URLRepository) Get(ctx context.Context, sURL models.ShortURL) (*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			short_url = ?
+	`
+
+	u, err := scanURL(ur.db.QueryRowContext(ctx, q, sURL))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve url with query (%s): %w", formatQuery(q), err)
+	}
+
+	return u, nil
+}
+
+// GetByOriginalURL retrieves a URL record from the database based on its
+// original URL. If the URL record does not exist, ErrNotFound is returned.
+func (ur *URLRepository) GetByOriginalURL(ctx context.Context, originalURL models.OriginalURL) (*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			original_url = ?
+	`
+
+	u, err := scanURL(ur.db.QueryRowContext(ctx, q, originalURL))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve url with query (%s): %w", formatQuery(q), err)
+	}
+
+	return u, nil
+}
+
+// GetMany retrieves URL records from the database based on their short URLs.
+// Short URLs that are not found are skipped.
+func (ur *URLRepository) GetMany(ctx context.Context, sURLs []models.ShortURL) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			short_url IN (%s)
+	`
+
+	all := make([]*models.URL, 0, len(sURLs))
+	for len(sURLs) > 0 {
+		chunk := sURLs[:min(len(sURLs), maxParams)]
+		sURLs = sURLs[len(chunk):]
+
+		args := make([]any, len(chunk))
+		for i, s := range chunk {
+			args[i] = s
+		}
+		query := fmt.Sprintf(q, strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", "))
+
+		err := ur.query(ctx, query, args, func(rows *sql.Rows) error {
+			u, err := scanURL(rows)
+			if err != nil {
+				return err
+			}
+			all = append(all, u)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("retrieve urls with query (%s): %w", formatQuery(q), err)
+		}
+	}
+
+	return all, nil
+}
+
+// GetAllByUserID retrieves all URL records from the database associated with a specific user.
+// If no URL records are found for the given user, it returns nil and ErrNotFound.
+func (ur *URLRepository) GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			user_id = ?
+	`
+
+	all := make([]*models.URL, 0)
+	err := ur.query(ctx, q, []any{userID}, func(rows *sql.Rows) error {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		all = append(all, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve url with query (%s): %w", formatQuery(q), err)
+	}
+
+	if len(all) == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	return all, nil
+}
+
+// DeleteURLs marks the URLs of their users as deleted in a single transaction.
+func (ur *URLRepository) DeleteURLs(ctx context.Context, urls ...*models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	const q = "UPDATE url SET is_deleted = 1 WHERE short_url = ? AND user_id = ?"
+
+	return ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for _, url := range urls {
+			if _, err := stmt.ExecContext(ctx, url.ShortURL, url.UserID); err != nil {
+				return fmt.Errorf("delete url with query (%s): %w", formatQuery(q), err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateLastAccessed sets the last access time of the given short URLs
+// in a single transaction. Older times never overwrite newer ones.
+func (ur *URLRepository) UpdateLastAccessed(
+	ctx context.Context, accessed map[models.ShortURL]time.Time,
+) error {
+	if len(accessed) == 0 {
+		return nil
+	}
+
+	const q = `
+		UPDATE url SET
+			last_accessed_at = max(coalesce(last_accessed_at, ?2), ?2)
+		WHERE
+			short_url = ?1
+	`
+
+	return ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for sURL, at := range accessed {
+			if _, err := stmt.ExecContext(ctx, sURL, at.UnixNano()); err != nil {
+				return fmt.Errorf("update last accessed with query (%s): %w", formatQuery(q), err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetRedirectLimit sets the redirects allowed per minute for the URL
+// of the user. If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) SetRedirectLimit(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, limit int,
+) error {
+	const q = `
+		UPDATE url SET
+			redirect_limit = ?
+		WHERE
+			short_url = ? AND user_id = ?
+	`
+
+	if err := models.ValidateRedirectLimit(limit); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, limit, shortURL, userID)
+	if err != nil {
+		return fmt.Errorf("set redirect limit with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set redirect limit: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetNote sets the note of the URL of the user.
+// If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) SetNote(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, note string,
+) error {
+	const q = `
+		UPDATE url SET
+			note = ?
+		WHERE
+			short_url = ? AND user_id = ?
+	`
+
+	if err := models.ValidateNote(note); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, note, shortURL, userID)
+	if err != nil {
+		return fmt.Errorf("set note with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateURL applies the update to the URL of the user and increments
+// its version. The version is checked by the update itself, so that
+// the concurrent updates of the same version are applied once.
+func (ur *URLRepository) UpdateURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, update *models.URLUpdate,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				original_url = ?, expires_at = ?, version = version + 1
+			WHERE
+				short_url = ? AND user_id = ? AND version = ? AND NOT is_deleted
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = ? AND user_id = ? AND NOT is_deleted)
+		`
+	)
+
+	res, err := ur.db.ExecContext(ctx, q, update.OriginalURL, encodeTime(update.ExpiresAt),
+		shortURL, userID, update.Version)
+	if err != nil {
+		// return ErrConflict if the destination is taken
+		if isConstraintViolation(err) {
+			return fmt.Errorf("%s: %w", update.OriginalURL, errs.ErrConflict)
+		}
+		return fmt.Errorf("update url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update url: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is updated, either the URL is missing or its version is stale
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("update url with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return fmt.Errorf("%s: %w", shortURL, models.ErrStaleVersion)
+}
+
+// SetLinkState changes the state of the URL of the user if it is still
+// the given one. The state is checked by the update itself, so that the
+// concurrent changes are not overwritten. If the user has no such URL,
+// ErrNotFound is returned, if its state has changed, ErrConflict.
+func (ur *URLRepository) SetLinkState(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				state = ?
+			WHERE
+				short_url = ? AND user_id = ? AND COALESCE(NULLIF(state, ''), 'active') = ?
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = ? AND user_id = ?)
+		`
+	)
+
+	for _, state := range []models.LinkState{from, to} {
+		if err := models.ValidateStoredState(state); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	res, err := ur.db.ExecContext(ctx, q, to, shortURL, userID, from)
+	if err != nil {
+		return fmt.Errorf("set link state with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set link state: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is updated, either the URL is missing or its state has changed
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("set link state with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	return fmt.Errorf("%s: state is not %s: %w", shortURL, from, errs.ErrConflict)
+}
+
+// TransferURL hands the URL of the user over to the new owner in a single
+// transaction, dropping its shares and removing it from the campaigns
+// of the user. If the user has no such URL or it is deleted, ErrNotFound
+// is returned.
+func (ur *URLRepository) TransferURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, to user.ID,
+) error {
+	const (
+		q = `
+			UPDATE url SET
+				user_id = ?3
+			WHERE
+				short_url = ?1 AND user_id = ?2 AND NOT is_deleted
+		`
+		sharesQuery = `
+			DELETE FROM url_share
+			WHERE
+				short_url = ?1
+		`
+		campaignsQuery = `
+			DELETE FROM url_campaign
+			WHERE
+				short_url = ?1 AND user_id = ?2
+		`
+	)
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	n, err := execRowsAffected(ctx, tx, q, shortURL, userID, to)
+	if err != nil {
+		return fmt.Errorf("transfer url with query (%s): %w", formatQuery(q), err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+	if _, err = tx.ExecContext(ctx, sharesQuery, shortURL); err != nil {
+		return fmt.Errorf("drop shares with query (%s): %w", formatQuery(sharesQuery), err)
+	}
+	if _, err = tx.ExecContext(ctx, campaignsQuery, shortURL, userID); err != nil {
+		return fmt.Errorf("drop campaigns with query (%s): %w", formatQuery(campaignsQuery), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ShareURL grants the read access to the URL of the user, keeping
+// the original share if it is shared already. If the user has no such URL
+// or it is deleted, ErrNotFound is returned.
+func (ur *URLRepository) ShareURL(ctx context.Context, userID user.ID, share *models.URLShare) error {
+	const (
+		q = `
+			INSERT INTO url_share
+				(short_url, user_id, created_at)
+			SELECT
+				short_url, ?3, ?4
+			FROM
+				url
+			WHERE
+				short_url = ?1 AND user_id = ?2 AND NOT is_deleted
+			ON CONFLICT DO NOTHING
+		`
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = ? AND user_id = ? AND NOT is_deleted)
+		`
+	)
+
+	res, err := ur.db.ExecContext(ctx, q, share.ShortURL, userID, share.UserID, share.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("share url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("share url: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// nothing is inserted, either the URL is missing or it is shared already
+	var exists bool
+	if err = ur.db.QueryRowContext(ctx, existsQuery, share.ShortURL, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("share url with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", share.ShortURL, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UnshareURL revokes the read access to the URL of the user.
+// If the URL is not shared with the user, ErrNotFound is returned.
+func (ur *URLRepository) UnshareURL(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL, with user.ID,
+) error {
+	const q = `
+		DELETE FROM url_share
+		WHERE
+			short_url = ?1 AND user_id = ?3
+			AND EXISTS (SELECT 1 FROM url WHERE short_url = ?1 AND user_id = ?2)
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, shortURL, userID, with)
+	if err != nil {
+		return fmt.Errorf("unshare url with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unshare url: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s shared with %s: %w", shortURL, with, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// GetShares returns the shares of the URL of the user in the order
+// they were granted. If the user has no such URL, ErrNotFound is returned.
+func (ur *URLRepository) GetShares(
+	ctx context.Context, userID user.ID, shortURL models.ShortURL,
+) ([]*models.URLShare, error) {
+	const (
+		existsQuery = `
+			SELECT
+				EXISTS (SELECT 1 FROM url WHERE short_url = ? AND user_id = ?)
+		`
+		q = `
+			SELECT
+				short_url, user_id, created_at
+			FROM
+				url_share
+			WHERE
+				short_url = ?
+			ORDER BY
+				created_at, user_id
+		`
+	)
+
+	var exists bool
+	if err := ur.db.QueryRowContext(ctx, existsQuery, shortURL, userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(existsQuery), err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", shortURL, errs.ErrNotFound)
+	}
+
+	shares := make([]*models.URLShare, 0)
+	err := ur.query(ctx, q, []any{shortURL}, func(rows *sql.Rows) error {
+		var (
+			share     = new(models.URLShare)
+			createdAt int64
+		)
+		if err := rows.Scan(&share.ShortURL, &share.UserID, &createdAt); err != nil {
+			return err
+		}
+		share.CreatedAt = time.Unix(0, createdAt).UTC()
+		shares = append(shares, share)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve shares with query (%s): %w", formatQuery(q), err)
+	}
+
+	return shares, nil
+}
+
+// GetSharedURLs retrieves the URLs shared with the user, excluding
+// the deleted ones, ordered by their short URLs.
+func (ur *URLRepository) GetSharedURLs(ctx context.Context, userID user.ID) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			u.id, u.short_url, u.original_url, u.user_id, u.is_deleted, u.last_accessed_at,
+			u.expires_at, u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note,
+			u.domain, u.state, u.version
+		FROM
+			url_share s
+			JOIN url u ON u.short_url = s.short_url
+		WHERE
+			s.user_id = ? AND NOT u.is_deleted
+		ORDER BY
+			u.short_url
+	`
+
+	all := make([]*models.URL, 0)
+	err := ur.query(ctx, q, []any{userID}, func(rows *sql.Rows) error {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		all = append(all, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve shared urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// AddToCampaign adds the URLs of the user to the campaign in a single
+// transaction and returns the number of the added ones. Short URLs of
+// other users, deleted or already in the campaign are skipped.
+func (ur *URLRepository) AddToCampaign(
+	ctx context.Context, userID user.ID, campaign string, shortURLs []models.ShortURL,
+) (int, error) {
+	const q = `
+		INSERT INTO url_campaign
+			(user_id, campaign, short_url)
+		SELECT
+			user_id, ?2, short_url
+		FROM
+			url
+		WHERE
+			user_id = ?1 AND short_url = ?3 AND NOT is_deleted
+		ON CONFLICT DO NOTHING
+	`
+
+	if err := models.ValidateCampaign(campaign); err != nil {
+		return 0, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+	}
+	if len(shortURLs) == 0 {
+		return 0, nil
+	}
+
+	added := 0
+	err := ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for _, shortURL := range shortURLs {
+			res, err := stmt.ExecContext(ctx, userID, campaign, shortURL)
+			if err != nil {
+				return fmt.Errorf("add to campaign with query (%s): %w", formatQuery(q), err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("add to campaign: %w", err)
+			}
+			added += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// GetCampaignURLs retrieves the URLs of the user in the campaign,
+// excluding the deleted ones.
+func (ur *URLRepository) GetCampaignURLs(
+	ctx context.Context, userID user.ID, campaign string,
+) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			u.id, u.short_url, u.original_url, u.user_id, u.is_deleted, u.last_accessed_at,
+			u.expires_at, u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note, u.domain, u.state, u.version
+		FROM
+			url_campaign c
+			JOIN url u ON u.short_url = c.short_url
+		WHERE
+			c.user_id = ? AND c.campaign = ? AND NOT u.is_deleted
+	`
+
+	all := make([]*models.URL, 0)
+	err := ur.query(ctx, q, []any{userID, campaign}, func(rows *sql.Rows) error {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		all = append(all, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve campaign with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// TransferCampaign copies the URLs of the user in the campaign from
+// to the campaign to in a single transaction, skipping the ones whose
+// destinations are already in it. If move is set, the URLs are removed
+// from the campaign from.
+func (ur *URLRepository) TransferCampaign(
+	ctx context.Context, userID user.ID, from, to string, move bool,
+) (*models.CampaignTransfer, error) {
+	const (
+		countQuery = `
+			SELECT
+				count(*)
+			FROM
+				url_campaign c
+				JOIN url u ON u.short_url = c.short_url
+			WHERE
+				c.user_id = ?1 AND c.campaign = ?2 AND NOT u.is_deleted
+		`
+		copyQuery = `
+			INSERT INTO url_campaign
+				(user_id, campaign, short_url)
+			SELECT
+				c.user_id, ?3, c.short_url
+			FROM
+				url_campaign c
+				JOIN url u ON u.short_url = c.short_url
+			WHERE
+				c.user_id = ?1 AND c.campaign = ?2 AND NOT u.is_deleted
+				AND NOT EXISTS (
+					SELECT 1
+					FROM
+						url_campaign t
+						JOIN url d ON d.short_url = t.short_url
+					WHERE
+						t.user_id = ?1 AND t.campaign = ?3
+						AND d.original_url = u.original_url
+				)
+			ON CONFLICT DO NOTHING
+		`
+		removeQuery = `
+			DELETE FROM url_campaign
+			WHERE
+				user_id = ?1 AND campaign = ?2
+		`
+	)
+
+	for _, campaign := range []string{from, to} {
+		if err := models.ValidateCampaign(campaign); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidRequest, err)
+		}
+	}
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	var total int
+	if err = tx.QueryRowContext(ctx, countQuery, userID, from).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count campaign with query (%s): %w", formatQuery(countQuery), err)
+	}
+
+	res := new(models.CampaignTransfer)
+	res.Copied, err = execRowsAffected(ctx, tx, copyQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("copy campaign with query (%s): %w", formatQuery(copyQuery), err)
+	}
+	res.Skipped = total - res.Copied
+
+	if move && from != to {
+		res.Removed, err = execRowsAffected(ctx, tx, removeQuery, userID, from)
+		if err != nil {
+			return nil, fmt.Errorf("remove campaign with query (%s): %w", formatQuery(removeQuery), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return res, nil
+}
+
+// DeleteExpired marks the URLs expired by now as deleted
+// and returns their number.
+func (ur *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const q = `
+		UPDATE url SET
+			is_deleted = 1
+		WHERE
+			expires_at <= ? AND NOT is_deleted
+	`
+
+	res, err := ur.db.ExecContext(ctx, q, now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired urls: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// Reserve saves the reservations in a single transaction.
+// If any of the codes is already reserved or used by a URL,
+// ErrConflict is returned and nothing is saved.
+func (ur *URLRepository) Reserve(ctx context.Context, reservations ...*models.Reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	const q = `
+		INSERT INTO reservation
+			(short_url, user_id, created_at)
+		SELECT
+			?1, ?2, ?3
+		WHERE
+			NOT EXISTS (SELECT 1 FROM url WHERE short_url = ?1)
+	`
+
+	now := time.Now().UnixNano()
+
+	return ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for _, r := range reservations {
+			res, err := stmt.ExecContext(ctx, r.ShortURL, r.UserID, now)
+			if err != nil {
+				// return ErrConflict if the code is already reserved
+				if isConstraintViolation(err) {
+					return fmt.Errorf("%s: %w", r.ShortURL, errs.ErrConflict)
+				}
+				return fmt.Errorf("reserve with query (%s): %w", formatQuery(q), err)
+			}
+			// nothing inserted means the code is used by a URL
+			if n, err := res.RowsAffected(); err == nil && n == 0 {
+				return fmt.Errorf("%s: %w", r.ShortURL, errs.ErrConflict)
+			}
+		}
+		return nil
+	})
+}
+
+// GetReservation retrieves the reservation of the short code.
+// If the code is not reserved, ErrNotFound is returned.
+func (ur *URLRepository) GetReservation(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.Reservation, error) {
+	const q = `
+		SELECT
+			short_url, user_id, created_at
+		FROM
+			reservation
+		WHERE
+			short_url = ?
+	`
+
+	var (
+		r         = new(models.Reservation)
+		createdAt int64
+	)
+	err := ur.db.QueryRowContext(ctx, q, shortURL).Scan(&r.ShortURL, &r.UserID, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve reservation with query (%s): %w", formatQuery(q), err)
+	}
+	r.CreatedAt = time.Unix(0, createdAt)
+
+	return r, nil
+}
+
+// CreateAccount saves the account. If the login is taken or the user
+// already has an account, ErrConflict is returned.
+func (ur *URLRepository) CreateAccount(ctx context.Context, account *models.Account) error {
+	const q = `
+		INSERT INTO users
+			(user_id, login, password_hash, created_at)
+		VALUES
+			(?, ?, ?, ?)
+	`
+
+	_, err := ur.db.ExecContext(ctx, q,
+		account.UserID, account.Login, account.PasswordHash, account.CreatedAt.UnixNano())
+	if err != nil {
+		// return ErrConflict if the login or the user is taken
+		if isConstraintViolation(err) {
+			return fmt.Errorf("login %s: %w", account.Login, errs.ErrConflict)
+		}
+		return fmt.Errorf("create account with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// GetAccount retrieves the account by its login.
+// If the login is unknown, ErrNotFound is returned.
+func (ur *URLRepository) GetAccount(ctx context.Context, login string) (*models.Account, error) {
+	const q = `
+		SELECT
+			user_id, login, password_hash, created_at
+		FROM
+			users
+		WHERE
+			login = ?
+	`
+
+	var (
+		a         = new(models.Account)
+		createdAt int64
+	)
+	err := ur.db.QueryRowContext(ctx, q, login).Scan(&a.UserID, &a.Login, &a.PasswordHash, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve account with query (%s): %w", formatQuery(q), err)
+	}
+	a.CreatedAt = time.Unix(0, createdAt).UTC()
+
+	return a, nil
+}
+
+// RevokeToken revokes the token with the ID until it expires. If the token
+// is already revoked, ErrConflict is returned. The expired tokens are
+// forgotten.
+func (ur *URLRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	const purge = `
+		DELETE FROM
+			revoked_token
+		WHERE
+			expires_at < ?
+	`
+	const q = `
+		INSERT INTO revoked_token
+			(token_id, expires_at)
+		VALUES
+			(?, ?)
+	`
+
+	// the tokens revoked once are never revoked again, so the failed
+	// purge only keeps them a bit longer
+	if _, err := ur.db.ExecContext(ctx, purge, time.Now().UnixNano()); err != nil {
+		ur.logger.Errorf("purge revoked tokens with query (%s): %s", formatQuery(purge), err)
+	}
+
+	_, err := ur.db.ExecContext(ctx, q, tokenID, expiresAt.UnixNano())
+	if err != nil {
+		// return ErrConflict if the token is already revoked
+		if isConstraintViolation(err) {
+			return fmt.Errorf("token %s: %w", tokenID, errs.ErrConflict)
+		}
+		return fmt.Errorf("revoke token with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token with the ID is revoked.
+func (ur *URLRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	const q = `
+		SELECT
+			EXISTS (SELECT 1 FROM revoked_token WHERE token_id = ?)
+	`
+
+	var revoked bool
+	if err := ur.db.QueryRowContext(ctx, q, tokenID).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("check token with query (%s): %w", formatQuery(q), err)
+	}
+
+	return revoked, nil
+}
+
+// BanDomain bans the domain. If the domain is already banned,
+// ErrConflict is returned.
+func (ur *URLRepository) BanDomain(ctx context.Context, ban *models.DomainBan) error {
+	const q = `
+		INSERT INTO banned_domain
+			(domain, reason, created_at)
+		VALUES
+			(?, ?, ?)
+	`
+
+	_, err := ur.db.ExecContext(ctx, q, ban.Domain, ban.Reason, ban.CreatedAt.UnixNano())
+	if err != nil {
+		// return ErrConflict if the domain is already banned
+		if isConstraintViolation(err) {
+			return fmt.Errorf("domain %s: %w", ban.Domain, errs.ErrConflict)
+		}
+		return fmt.Errorf("ban domain with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// GetDomainBan retrieves the ban of the host or of its parent domain,
+// the most specific one first. If the host is not banned, ErrNotFound
+// is returned.
+func (ur *URLRepository) GetDomainBan(ctx context.Context, host string) (*models.DomainBan, error) {
+	const q = `
+		SELECT
+			domain, reason, created_at
+		FROM
+			banned_domain
+		WHERE
+			domain IN (%s)
+		ORDER BY
+			length(domain) DESC
+		LIMIT 1
+	`
+
+	domains := hostpolicy.Domains(host)
+	args := make([]any, len(domains))
+	for i, d := range domains {
+		args[i] = d
+	}
+	query := fmt.Sprintf(q, strings.TrimSuffix(strings.Repeat("?, ", len(domains)), ", "))
+
+	var (
+		ban       = new(models.DomainBan)
+		createdAt int64
+	)
+	err := ur.db.QueryRowContext(ctx, query, args...).Scan(&ban.Domain, &ban.Reason, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("host %s: %w", host, errs.ErrNotFound)
+		}
+		return nil, fmt.Errorf("retrieve domain ban with query (%s): %w", formatQuery(query), err)
+	}
+	ban.CreatedAt = time.Unix(0, createdAt).UTC()
+
+	return ban, nil
+}
+
+// ListDomainBans returns the banned domains in the order they were banned.
+func (ur *URLRepository) ListDomainBans(ctx context.Context) ([]*models.DomainBan, error) {
+	const q = `
+		SELECT
+			domain, reason, created_at
+		FROM
+			banned_domain
+		ORDER BY
+			created_at, domain
+	`
+
+	bans := make([]*models.DomainBan, 0)
+	err := ur.query(ctx, q, nil, func(rows *sql.Rows) error {
+		var (
+			ban       = new(models.DomainBan)
+			createdAt int64
+		)
+		if err := rows.Scan(&ban.Domain, &ban.Reason, &createdAt); err != nil {
+			return err
+		}
+		ban.CreatedAt = time.Unix(0, createdAt).UTC()
+		bans = append(bans, ban)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list domain bans with query (%s): %w", formatQuery(q), err)
+	}
+
+	return bans, nil
+}
+
+// GetFlaggedURLs retrieves the blocked URLs, excluding the deleted ones,
+// ordered by their short URLs.
+func (ur *URLRepository) GetFlaggedURLs(ctx context.Context) ([]*models.URL, error) {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+		WHERE
+			state = 'blocked' AND NOT is_deleted
+		ORDER BY
+			short_url
+	`
+
+	all := make([]*models.URL, 0)
+	err := ur.query(ctx, q, nil, func(rows *sql.Rows) error {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		all = append(all, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve flagged urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return all, nil
+}
+
+// SaveClicks saves the clicks in a single transaction.
+func (ur *URLRepository) SaveClicks(ctx context.Context, clicks ...*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	const q = `
+		INSERT INTO click
+			(short_url, clicked_at, referrer, user_agent, ip_hash)
+		VALUES
+			(?, ?, ?, ?, ?)
+	`
+
+	return ur.inTx(ctx, q, func(stmt *sql.Stmt) error {
+		for _, c := range clicks {
+			_, err := stmt.ExecContext(ctx, c.ShortURL, c.ClickedAt.UnixNano(),
+				c.Referrer, c.UserAgent, c.IPHash)
+			if err != nil {
+				return fmt.Errorf("save click with query (%s): %w", formatQuery(q), err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetClickStats returns the click statistics of the short URL
+// aggregated by days in UTC.
+func (ur *URLRepository) GetClickStats(
+	ctx context.Context, shortURL models.ShortURL,
+) (*models.ClickStats, error) {
+	const q = `
+		SELECT
+			strftime('%Y-%m-%d', clicked_at / 1000000000, 'unixepoch') AS day,
+			count(*),
+			max(clicked_at)
+		FROM
+			click
+		WHERE
+			short_url = ?
+		GROUP BY
+			day
+		ORDER BY
+			day
+	`
+
+	stats := &models.ClickStats{ShortURL: shortURL, Daily: make([]models.DailyClicks, 0)}
+	err := ur.query(ctx, q, []any{shortURL}, func(rows *sql.Rows) error {
+		var (
+			day  models.DailyClicks
+			last int64
+		)
+		if err := rows.Scan(&day.Date, &day.Clicks, &last); err != nil {
+			return err
+		}
+		stats.Daily = append(stats.Daily, day)
+		stats.TotalClicks += day.Clicks
+		if at := time.Unix(0, last); stats.LastAccessedAt == nil || at.After(*stats.LastAccessedAt) {
+			stats.LastAccessedAt = &at
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve click stats with query (%s): %w", formatQuery(q), err)
+	}
+
+	agents, err := ur.getUserAgentClicks(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	stats.AddUserAgents(agents)
+
+	return stats, nil
+}
+
+// getUserAgentClicks returns the number of clicks
+// of the short URL by the user agent.
+func (ur *URLRepository) getUserAgentClicks(
+	ctx context.Context, shortURL models.ShortURL,
+) (map[string]int, error) {
+	const q = `
+		SELECT
+			user_agent,
+			count(*)
+		FROM
+			click
+		WHERE
+			short_url = ?
+		GROUP BY
+			user_agent
+	`
+
+	agents := make(map[string]int)
+	err := ur.query(ctx, q, []any{shortURL}, func(rows *sql.Rows) error {
+		var (
+			ua string
+			n  int
+		)
+		if err := rows.Scan(&ua, &n); err != nil {
+			return err
+		}
+		agents[ua] = n
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve user agent clicks with query (%s): %w", formatQuery(q), err)
+	}
+
+	return agents, nil
+}
+
+// GetTrends returns the daily trends since the given day in UTC,
+// oldest first, from the rollups the trigger on the url table updates.
+func (ur *URLRepository) GetTrends(ctx context.Context, since time.Time) ([]models.DailyTrend, error) {
+	const q = `
+		SELECT
+			d.day,
+			d.created,
+			(SELECT count(*) FROM url_daily_user u WHERE u.day = d.day)
+		FROM
+			url_daily d
+		WHERE
+			d.day >= ?
+		ORDER BY
+			d.day
+	`
+
+	trends := make([]models.DailyTrend, 0)
+	err := ur.query(ctx, q, []any{since.UTC().Format(models.DateLayout)}, func(rows *sql.Rows) error {
+		var t models.DailyTrend
+		if err := rows.Scan(&t.Date, &t.CreatedURLs, &t.ActiveUsers); err != nil {
+			return err
+		}
+		trends = append(trends, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve trends with query (%s): %w", formatQuery(q), err)
+	}
+
+	return trends, nil
+}
+
+// CountShortURLs returns the number of not deleted short URLs.
+func (ur *URLRepository) CountShortURLs(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			COUNT(*)
+		FROM
+			url
+		WHERE
+			NOT is_deleted
+	`
+
+	return ur.count(ctx, q)
+}
+
+// CountUsers returns the number of distinct users owning at least one URL.
+func (ur *URLRepository) CountUsers(ctx context.Context) (int, error) {
+	const q = `
+		SELECT
+			COUNT(DISTINCT user_id)
+		FROM
+			url
+		WHERE
+			NOT is_deleted
+	`
+
+	return ur.count(ctx, q)
+}
+
+// CountActiveUsers returns the number of the users who created
+// URLs since the given day in UTC, from the daily rollups.
+func (ur *URLRepository) CountActiveUsers(ctx context.Context, since time.Time) (int, error) {
+	const q = `
+		SELECT
+			COUNT(DISTINCT user_id)
+		FROM
+			url_daily_user
+		WHERE
+			day >= ?
+	`
+
+	return ur.count(ctx, q, since.UTC().Format(models.DateLayout))
+}
+
+// TopLinks returns at most limit short URLs most clicked since
+// the given time, most clicked first.
+func (ur *URLRepository) TopLinks(ctx context.Context, since time.Time, limit int) ([]models.TopLink, error) {
+	const q = `
+		SELECT
+			short_url, count(*) AS clicks
+		FROM
+			click
+		WHERE
+			clicked_at >= ?
+		GROUP BY
+			short_url
+		ORDER BY
+			clicks DESC, short_url
+		LIMIT ?
+	`
+
+	top := make([]models.TopLink, 0, limit)
+	err := ur.query(ctx, q, []any{since.UnixNano(), limit}, func(rows *sql.Rows) error {
+		var t models.TopLink
+		if err := rows.Scan(&t.ShortURL, &t.Clicks); err != nil {
+			return err
+		}
+		top = append(top, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieve top links with query (%s): %w", formatQuery(q), err)
+	}
+
+	return top, nil
+}
+
+// count executes the query returning a single number.
+func (ur *URLRepository) count(ctx context.Context, q string, args ...any) (int, error) {
+	var n int
+	if err := ur.db.QueryRowContext(ctx, q, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count with query (%s): %w", formatQuery(q), err)
+	}
+
+	return n, nil
+}
+
+// ScanURLs calls fn for every URL record, including the deleted ones.
+// The records are streamed, so fn must not block for long.
+func (ur *URLRepository) ScanURLs(ctx context.Context, fn func(*models.URL) error) error {
+	const q = `
+		SELECT
+			id, short_url, original_url, user_id, is_deleted, last_accessed_at,
+			expires_at, creator_ip, user_agent, origin, redirect_limit, note, domain, state, version
+		FROM
+			url
+	`
+
+	var fnErr error
+	err := ur.query(ctx, q, nil, func(rows *sql.Rows) error {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		fnErr = fn(u)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("scan urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// ScanUserURLs calls fn for every URL of the user, including the deleted
+// ones, ordered by their short URLs, with the number of their clicks.
+// The records are streamed, so fn must not block for long.
+func (ur *URLRepository) ScanUserURLs(
+	ctx context.Context, userID user.ID, fn func(u *models.URL, clicks int) error,
+) error {
+	const q = `
+		SELECT
+			u.id, u.short_url, u.original_url, u.user_id, u.is_deleted, u.last_accessed_at,
+			u.expires_at, u.creator_ip, u.user_agent, u.origin, u.redirect_limit, u.note,
+			u.domain, u.state, u.version, u.created_at,
+			(SELECT count(*) FROM click c WHERE c.short_url = u.short_url)
+		FROM
+			url u
+		WHERE
+			u.user_id = ?
+		ORDER BY
+			u.short_url
+	`
+
+	var fnErr error
+	err := ur.query(ctx, q, []any{userID}, func(rows *sql.Rows) error {
+		var (
+			u                                    = new(models.URL)
+			lastAccessedAt, expiresAt, createdAt sql.NullInt64
+			clicks                               int
+		)
+		err := rows.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+			&lastAccessedAt, &expiresAt,
+			&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit, &u.Note,
+			&u.Domain, &u.State, &u.Version, &createdAt, &clicks)
+		if err != nil {
+			return err
+		}
+		u.LastAccessedAt = decodeTime(lastAccessedAt)
+		u.ExpiresAt = decodeTime(expiresAt)
+		u.CreatedAt = decodeTime(createdAt)
+		fnErr = fn(u, clicks)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("scan user urls with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// GetCertificateCache retrieves the ACME certificate cache entry by the key.
+// If there is none, ErrNotFound is returned.
+func (ur *URLRepository) GetCertificateCache(ctx context.Context, key string) ([]byte, error) {
+	const q = `
+		SELECT
+			data
+		FROM
+			certificate_cache
+		WHERE
+			key = ?
+	`
+
+	var data []byte
+	if err := ur.db.QueryRowContext(ctx, q, key).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("retrieve certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return data, nil
+}
+
+// PutCertificateCache saves the ACME certificate cache entry,
+// replacing the one with the same key.
+func (ur *URLRepository) PutCertificateCache(ctx context.Context, key string, data []byte) error {
+	const q = `
+		INSERT INTO certificate_cache
+			(key, data, updated_at)
+		VALUES
+			(?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`
+
+	if _, err := ur.db.ExecContext(ctx, q, key, data, time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("save certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// DeleteCertificateCache removes the ACME certificate cache entry.
+// Removing the missing entry is not an error.
+func (ur *URLRepository) DeleteCertificateCache(ctx context.Context, key string) error {
+	const q = `
+		DELETE FROM certificate_cache
+		WHERE
+			key = ?
+	`
+
+	if _, err := ur.db.ExecContext(ctx, q, key); err != nil {
+		return fmt.Errorf("delete certificate cache with query (%s): %w", formatQuery(q), err)
+	}
+
+	return nil
+}
+
+// PoolStats returns the stats of the pool of the database connections.
+func (ur *URLRepository) PoolStats() sql.DBStats {
+	return ur.db.Stats()
+}
+
+// Ping verifies the connection to the database is alive.
+func (ur *URLRepository) Ping(ctx context.Context) error {
+	return ur.db.PingContext(ctx)
+}
+
+// Close closes the database.
+func (ur *URLRepository) Close() error {
+	return ur.db.Close()
+}
+
+// inTx executes fn with the statement prepared in a new transaction
+// and commits the transaction if fn succeeds.
+func (ur *URLRepository) inTx(ctx context.Context, q string, fn func(stmt *sql.Stmt) error) error {
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err = tx.Rollback(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("rollback: %v", err)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer func() {
+		if err = stmt.Close(); err != nil {
+			if !errors.Is(err, sql.ErrTxDone) {
+				ur.logger.Errorf("close prepared statement: %v", err)
+			}
+		}
+	}()
+
+	if err = fn(stmt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// query executes the query and calls fn for every row.
+func (ur *URLRepository) query(ctx context.Context, q string, args []any, fn func(rows *sql.Rows) error) error {
+	rows, err := ur.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			ur.logger.Errorf("close rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		if err = fn(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// execRowsAffected executes the query in the transaction
+// and returns the number of the affected rows.
+func execRowsAffected(ctx context.Context, tx *sql.Tx, q string, args ...any) (int, error) {
+	res, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanURL scans the URL record selected with all its columns.
+func scanURL(row rowScanner) (*models.URL, error) {
+	var (
+		u                         = new(models.URL)
+		lastAccessedAt, expiresAt sql.NullInt64
+	)
+	err := row.Scan(&u.ID, &u.ShortURL, &u.OriginalURL, &u.UserID, &u.IsDeleted,
+		&lastAccessedAt, &expiresAt,
+		&u.Metadata.CreatorIP, &u.Metadata.UserAgent, &u.Metadata.Origin, &u.RedirectLimit, &u.Note,
+		&u.Domain, &u.State, &u.Version)
+	if err != nil {
+		return nil, err
+	}
+	u.LastAccessedAt = decodeTime(lastAccessedAt)
+	u.ExpiresAt = decodeTime(expiresAt)
+
+	return u, nil
+}
+
+// encodeTime returns the time in nanoseconds since the epoch,
+// so that the times are compared as numbers, or nil.
+func encodeTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+// decodeTime reverses encodeTime.
+func decodeTime(n sql.NullInt64) *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := time.Unix(0, n.Int64)
+	return &t
+}
+
+// isConstraintViolation reports whether the error is caused by
+// the unique or the primary key constraint.
+func isConstraintViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// formatQuery removes tabs and replaces newlines with spaces in the given query string.
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
@@ -0,0 +1,545 @@
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/errs"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, path string) *URLRepository {
+	t.Helper()
+	l, _ := logger.NewForTest()
+	store, err := New(Scheme+path, l)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestDataSource(t *testing.T) {
+	_, err := dataSource("postgres://localhost/db")
+	require.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	_, err = dataSource(Scheme)
+	require.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	source, err := dataSource(Scheme + "/tmp/db.sqlite")
+	require.NoError(t, err)
+	assert.Contains(t, source, "file:/tmp/db.sqlite?")
+	assert.Contains(t, source, "_txlock=immediate")
+
+	source, err = dataSource(Scheme + "db.sqlite?_pragma=busy_timeout(100)")
+	require.NoError(t, err)
+	assert.Contains(t, source, "busy_timeout%28100%29")
+	assert.NotContains(t, source, "journal_mode")
+}
+
+func TestURLRepository(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	store := newTestStore(t, path)
+
+	expiresAt := time.Now().Add(time.Hour)
+	u := models.NewRecord("abc", "https://example.com", "user")
+	u.ExpiresAt = &expiresAt
+	require.NoError(t, store.Save(ctx, u))
+	require.ErrorIs(t, store.Save(ctx, models.NewRecord("abc", "https://other.com", "user")),
+		errs.ErrConflict)
+
+	// conflicts are skipped
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		models.NewRecord("abc", "https://example.com", "user"),
+		models.NewRecord("def", "https://example.org", "user"),
+	}))
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, u.OriginalURL, got.OriginalURL)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*got.ExpiresAt))
+	assert.Nil(t, got.LastAccessedAt)
+
+	_, err = store.Get(ctx, "missing")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	many, err := store.GetMany(ctx, []models.ShortURL{"abc", "def", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, many, 2)
+
+	all, err := store.GetAllByUserID(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	_, err = store.GetAllByUserID(ctx, "nobody")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	// older times never overwrite newer ones
+	now := time.Now()
+	require.NoError(t, store.UpdateLastAccessed(ctx, map[models.ShortURL]time.Time{"abc": now}))
+	require.NoError(t, store.UpdateLastAccessed(ctx,
+		map[models.ShortURL]time.Time{"abc": now.Add(-time.Minute)}))
+	got, err = store.Get(ctx, "abc")
+	require.NoError(t, err)
+	require.NotNil(t, got.LastAccessedAt)
+	assert.True(t, now.Equal(*got.LastAccessedAt))
+
+	require.NoError(t, store.SetRedirectLimit(ctx, "user", "abc", 60))
+	require.ErrorIs(t, store.SetRedirectLimit(ctx, "other", "abc", 1), errs.ErrNotFound)
+	got, err = store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, 60, got.RedirectLimit)
+
+	require.NoError(t, store.SetNote(ctx, "user", "abc", "spring campaign"))
+	require.ErrorIs(t, store.SetNote(ctx, "other", "abc", "mine"), errs.ErrNotFound)
+	require.ErrorIs(t, store.SetNote(ctx, "user", "abc", strings.Repeat("a", models.MaxNoteLength+1)),
+		errs.ErrInvalidRequest)
+	got, err = store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "spring campaign", got.Note)
+
+	require.NoError(t, store.SetLinkState(ctx, "user", "abc", models.LinkActive, models.LinkPaused))
+	require.ErrorIs(t, store.SetLinkState(ctx, "other", "abc", models.LinkPaused, models.LinkActive), errs.ErrNotFound)
+	require.ErrorIs(t, store.SetLinkState(ctx, "user", "abc", models.LinkPaused, models.LinkDeleted),
+		errs.ErrInvalidRequest)
+	require.ErrorIs(t, store.SetLinkState(ctx, "user", "abc", models.LinkActive, models.LinkBlocked), errs.ErrConflict,
+		"the state changed since it was read is not overwritten")
+	got, err = store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkPaused, got.State)
+
+	// other users can't delete the URL
+	require.NoError(t, store.DeleteURLs(ctx, &models.URL{ShortURL: "def", UserID: "other"}))
+	got, err = store.Get(ctx, "def")
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted)
+	require.NoError(t, store.DeleteURLs(ctx, &models.URL{ShortURL: "def", UserID: "user"}))
+	got, err = store.Get(ctx, "def")
+	require.NoError(t, err)
+	assert.True(t, got.IsDeleted)
+
+	n, err := store.DeleteExpired(ctx, expiresAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	count, err := store.CountShortURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	var scanned int
+	require.NoError(t, store.ScanURLs(ctx, func(*models.URL) error {
+		scanned++
+		return nil
+	}))
+	assert.Equal(t, 2, scanned)
+
+	// the data survives the restart
+	require.NoError(t, store.Close())
+	store = newTestStore(t, path)
+	_, err = store.Get(ctx, "abc")
+	require.NoError(t, err)
+}
+
+func TestURLRepository_GetByOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.Save(ctx, models.NewRecord("GoDev", "https://go.dev/", "user")))
+	require.ErrorIs(t, store.Save(ctx, models.NewRecord("other", "https://go.dev/", "user")), errs.ErrConflict)
+
+	got, err := store.GetByOriginalURL(ctx, "https://go.dev/")
+	require.NoError(t, err)
+	assert.Equal(t, models.ShortURL("GoDev"), got.ShortURL, "short URL of the conflicting save")
+
+	_, err = store.GetByOriginalURL(ctx, "https://pkg.go.dev/")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestURLRepository_Reservations(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.Save(ctx, models.NewRecord("used", "https://example.com", "user")))
+
+	require.NoError(t, store.Reserve(ctx, &models.Reservation{ShortURL: "free", UserID: "user"}))
+	require.ErrorIs(t, store.Reserve(ctx, &models.Reservation{ShortURL: "free", UserID: "other"}),
+		errs.ErrConflict)
+	require.ErrorIs(t, store.Reserve(ctx,
+		&models.Reservation{ShortURL: "another", UserID: "user"},
+		&models.Reservation{ShortURL: "used", UserID: "user"},
+	), errs.ErrConflict)
+
+	r, err := store.GetReservation(ctx, "free")
+	require.NoError(t, err)
+	assert.EqualValues(t, "user", r.UserID)
+
+	_, err = store.GetReservation(ctx, "another")
+	require.ErrorIs(t, err, errs.ErrNotFound, "nothing expected to be saved on conflict")
+}
+
+func TestURLRepository_Accounts(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	account, err := models.NewAccount("user", "gopher", "correct horse")
+	require.NoError(t, err)
+	require.NoError(t, store.CreateAccount(ctx, account))
+
+	taken, err := models.NewAccount("other", "gopher", "correct horse")
+	require.NoError(t, err)
+	require.ErrorIs(t, store.CreateAccount(ctx, taken), errs.ErrConflict)
+	registered, err := models.NewAccount("user", "another", "correct horse")
+	require.NoError(t, err)
+	require.ErrorIs(t, store.CreateAccount(ctx, registered), errs.ErrConflict)
+
+	got, err := store.GetAccount(ctx, "gopher")
+	require.NoError(t, err)
+	assert.EqualValues(t, "user", got.UserID)
+	assert.NoError(t, got.CheckPassword("correct horse"))
+	assert.WithinDuration(t, account.CreatedAt, got.CreatedAt, time.Millisecond)
+
+	_, err = store.GetAccount(ctx, "another")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestURLRepository_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.RevokeToken(ctx, "expired", time.Now().Add(-time.Minute)))
+	require.NoError(t, store.RevokeToken(ctx, "token", time.Now().Add(time.Hour)))
+	require.ErrorIs(t, store.RevokeToken(ctx, "token", time.Now().Add(time.Hour)), errs.ErrConflict)
+
+	revoked, err := store.IsTokenRevoked(ctx, "token")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsTokenRevoked(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, revoked, "expired tokens are forgotten")
+
+	revoked, err = store.IsTokenRevoked(ctx, "unknown")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestURLRepository_Moderation(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.BanDomain(ctx, &models.DomainBan{Domain: "example.com", CreatedAt: now}))
+	require.NoError(t, store.BanDomain(ctx, &models.DomainBan{Domain: "a.example.com", CreatedAt: now.Add(time.Second)}))
+	require.ErrorIs(t, store.BanDomain(ctx, &models.DomainBan{Domain: "example.com", CreatedAt: now}),
+		errs.ErrConflict)
+
+	ban, err := store.GetDomainBan(ctx, "b.a.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "a.example.com", ban.Domain, "the most specific ban is returned")
+
+	_, err = store.GetDomainBan(ctx, "example.org")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	bans, err := store.ListDomainBans(ctx)
+	require.NoError(t, err)
+	require.Len(t, bans, 2)
+	assert.Equal(t, "example.com", bans[0].Domain)
+	assert.Equal(t, now, bans[0].CreatedAt)
+
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		{ID: "1", ShortURL: "abc", OriginalURL: "https://example.com/", UserID: "user"},
+		{ID: "2", ShortURL: "def", OriginalURL: "https://example.com/a", UserID: "user"},
+	}))
+	require.NoError(t, store.SetLinkState(ctx, "user", "abc", models.LinkActive, models.LinkBlocked))
+
+	flagged, err := store.GetFlaggedURLs(ctx)
+	require.NoError(t, err)
+	require.Len(t, flagged, 1)
+	assert.Equal(t, models.ShortURL("abc"), flagged[0].ShortURL)
+}
+
+func TestURLRepository_UpdateURL(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		{ID: "1", ShortURL: "abc", OriginalURL: "https://example.com/", UserID: "user"},
+		{ID: "2", ShortURL: "def", OriginalURL: "https://example.org/", UserID: "user"},
+	}))
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	update := &models.URLUpdate{OriginalURL: "https://example.net/", ExpiresAt: &expiresAt}
+	require.NoError(t, store.UpdateURL(ctx, "user", "abc", update))
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginalURL("https://example.net/"), got.OriginalURL)
+	assert.Equal(t, expiresAt, got.ExpiresAt.UTC())
+	assert.Equal(t, 1, got.Version)
+
+	require.ErrorIs(t, store.UpdateURL(ctx, "user", "abc", update), models.ErrStaleVersion)
+	require.ErrorIs(t, store.UpdateURL(ctx, "other", "abc", update), errs.ErrNotFound)
+	require.ErrorIs(t, store.UpdateURL(ctx, "user", "xyz", update), errs.ErrNotFound)
+	require.ErrorIs(t, store.UpdateURL(ctx, "user", "def", &models.URLUpdate{OriginalURL: "https://example.net/"}),
+		errs.ErrConflict, "the destination is shortened already")
+}
+
+func TestURLRepository_Shares(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		{ID: "1", ShortURL: "abc", OriginalURL: "https://example.com/", UserID: "owner"},
+		{ID: "2", ShortURL: "def", OriginalURL: "https://example.org/", UserID: "owner"},
+	}))
+	_, err := store.AddToCampaign(ctx, "owner", "launch", []models.ShortURL{"abc"})
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	share := &models.URLShare{ShortURL: "abc", UserID: "reader", CreatedAt: now}
+	require.NoError(t, store.ShareURL(ctx, "owner", share))
+	require.NoError(t, store.ShareURL(ctx, "owner", share), "sharing again keeps the share")
+	require.NoError(t, store.ShareURL(ctx, "owner", &models.URLShare{ShortURL: "def", UserID: "reader", CreatedAt: now}))
+	require.ErrorIs(t, store.ShareURL(ctx, "reader", &models.URLShare{ShortURL: "abc", UserID: "other"}),
+		errs.ErrNotFound)
+
+	shares, err := store.GetShares(ctx, "owner", "abc")
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, *share, *shares[0])
+	_, err = store.GetShares(ctx, "reader", "abc")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	shared, err := store.GetSharedURLs(ctx, "reader")
+	require.NoError(t, err)
+	require.Len(t, shared, 2)
+	assert.EqualValues(t, "owner", shared[0].UserID)
+
+	require.NoError(t, store.UnshareURL(ctx, "owner", "def", "reader"))
+	require.ErrorIs(t, store.UnshareURL(ctx, "owner", "def", "reader"), errs.ErrNotFound)
+
+	require.NoError(t, store.TransferURL(ctx, "owner", "abc", "heir"))
+	require.ErrorIs(t, store.TransferURL(ctx, "owner", "abc", "heir"), errs.ErrNotFound)
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.EqualValues(t, "heir", got.UserID)
+
+	shared, err = store.GetSharedURLs(ctx, "reader")
+	require.NoError(t, err)
+	assert.Empty(t, shared, "the transfer drops the shares")
+	campaign, err := store.GetCampaignURLs(ctx, "owner", "launch")
+	require.NoError(t, err)
+	assert.Empty(t, campaign, "the transfer drops the campaigns")
+}
+
+func TestURLRepository_Clicks(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	day := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	const firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:126.0) Gecko/20100101 Firefox/126.0"
+	clicks := []models.Click{
+		{ShortURL: "abc", ClickedAt: day, UserAgent: firefox},
+		{ShortURL: "abc", ClickedAt: day.Add(30 * time.Minute), UserAgent: firefox},
+		{ShortURL: "abc", ClickedAt: day.Add(2 * time.Hour), UserAgent: "curl/8.5.0"},
+		{ShortURL: "def", ClickedAt: day},
+	}
+	for i := range clicks {
+		require.NoError(t, store.SaveClicks(ctx, &clicks[i]))
+	}
+
+	stats, err := store.GetClickStats(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, models.NewClickStats("abc", clicks[:3]).Daily, stats.Daily)
+	assert.Equal(t, 3, stats.TotalClicks)
+	require.NotNil(t, stats.LastAccessedAt)
+	assert.True(t, day.Add(2*time.Hour).Equal(*stats.LastAccessedAt))
+	assert.Equal(t, []models.Share{{Name: "desktop", Clicks: 2}, {Name: "bot", Clicks: 1}}, stats.Devices)
+	assert.Equal(t, []models.Share{{Name: "Firefox", Clicks: 2}, {Name: "unknown", Clicks: 1}}, stats.Browsers)
+
+	stats, err = store.GetClickStats(ctx, "missing")
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Empty(t, stats.Daily)
+
+	top, err := store.TopLinks(ctx, day, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TopLink{{ShortURL: "abc", Clicks: 3}, {ShortURL: "def", Clicks: 1}}, top)
+	top, err = store.TopLinks(ctx, day.Add(time.Minute), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TopLink{{ShortURL: "abc", Clicks: 2}}, top, "earlier clicks are not counted")
+}
+
+func TestURLRepository_Trends(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.Save(ctx, models.NewRecord("abc", "https://example.com/1", "user")))
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		models.NewRecord("def", "https://example.com/2", "user"),
+		models.NewRecord("ghi", "https://example.com/3", "other"),
+	}))
+	require.ErrorIs(t, store.Save(ctx, models.NewRecord("abc", "https://example.com/4", "third")),
+		errs.ErrConflict)
+
+	now := time.Now().UTC()
+	trends, err := store.GetTrends(ctx, now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, []models.DailyTrend{
+		{Date: now.Format(models.DateLayout), CreatedURLs: 3, ActiveUsers: 2},
+	}, trends, "conflicting URLs are not counted")
+
+	trends, err = store.GetTrends(ctx, now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, trends)
+
+	active, err := store.CountActiveUsers(ctx, now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, 2, active)
+	active, err = store.CountActiveUsers(ctx, now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Zero(t, active)
+}
+
+func TestURLRepository_SaveAllResults(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.Save(ctx, models.NewRecord("abc", "https://example.com/1", "user")))
+
+	results, err := store.SaveAllResults(ctx, []*models.URL{
+		models.NewRecord("abc", "https://example.com/2", "user"),
+		models.NewRecord("def", "https://example.com/3", "user"),
+		models.NewRecord("def", "https://example.com/4", "user"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SaveResult{
+		{ShortURL: "abc", Status: models.SaveStatusExists},
+		{ShortURL: "def", Status: models.SaveStatusCreated},
+		{ShortURL: "def", Status: models.SaveStatusExists},
+	}, results)
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginalURL("https://example.com/1"), got.OriginalURL, "existing URL is kept")
+}
+
+func TestURLRepository_ScanUserURLs(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	createdAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		{ID: "1", ShortURL: "def", OriginalURL: "https://example.org/", UserID: "user"},
+		{ID: "2", ShortURL: "abc", OriginalURL: "https://example.com/", UserID: "user", CreatedAt: &createdAt},
+		{ID: "3", ShortURL: "xyz", OriginalURL: "https://example.net/", UserID: "other"},
+	}))
+	require.NoError(t, store.SaveClicks(ctx,
+		&models.Click{ShortURL: "def", ClickedAt: time.Now()},
+		&models.Click{ShortURL: "def", ClickedAt: time.Now()}))
+
+	var (
+		got    []models.ShortURL
+		clicks []int
+	)
+	require.NoError(t, store.ScanUserURLs(ctx, "user", func(u *models.URL, n int) error {
+		require.NotNil(t, u.CreatedAt)
+		if u.ShortURL == "abc" {
+			assert.Equal(t, createdAt, u.CreatedAt.UTC(), "the creation time is kept")
+		}
+		got = append(got, u.ShortURL)
+		clicks = append(clicks, n)
+		return nil
+	}))
+	assert.Equal(t, []models.ShortURL{"abc", "def"}, got)
+	assert.Equal(t, []int{0, 2}, clicks)
+
+	require.ErrorIs(t, store.ScanUserURLs(ctx, "user", func(*models.URL, int) error {
+		return errs.ErrConflict
+	}), errs.ErrConflict, "the error of fn is returned")
+}
+
+func TestURLRepository_Domain(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	record := models.NewRecord("abc", "https://example.com/1", "user")
+	record.Domain = "acme.link"
+	require.NoError(t, store.Save(ctx, record))
+
+	got, err := store.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "acme.link", got.Domain)
+
+	all, err := store.GetAllByUserID(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "acme.link", all[0].Domain)
+}
+
+func TestURLRepository_Campaigns(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+
+	require.NoError(t, store.SaveAll(ctx, []*models.URL{
+		models.NewRecord("a", "https://example.com/1", "user"),
+		models.NewRecord("b", "https://example.com/2", "user"),
+		models.NewRecord("c", "https://example.com/3", "user"),
+		models.NewRecord("d", "https://example.com/4", "other"),
+	}))
+
+	added, err := store.AddToCampaign(ctx, "user", "spring", []models.ShortURL{"a", "b", "d", "x"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added, "URLs of other users and unknown ones are skipped")
+	_, err = store.AddToCampaign(ctx, "user", "autumn", []models.ShortURL{"a", "c"})
+	require.NoError(t, err)
+
+	res, err := store.TransferCampaign(ctx, "user", "spring", "autumn", false)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignTransfer{Copied: 1, Skipped: 1}, *res)
+
+	res, err = store.TransferCampaign(ctx, "user", "autumn", "summer", true)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignTransfer{Copied: 3, Removed: 3}, *res)
+
+	got, err := store.GetCampaignURLs(ctx, "user", "summer")
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+	got, err = store.GetCampaignURLs(ctx, "user", "autumn")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = store.TransferCampaign(ctx, "user", "summer", "", false)
+	require.ErrorIs(t, err, errs.ErrInvalidRequest)
+}
+
+func TestCertificateCache(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, filepath.Join(t.TempDir(), "db.sqlite"))
+
+	_, err := store.GetCertificateCache(ctx, "example.com")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	require.NoError(t, store.PutCertificateCache(ctx, "example.com", []byte("old")))
+	require.NoError(t, store.PutCertificateCache(ctx, "example.com", []byte("new")))
+	data, err := store.GetCertificateCache(ctx, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), data, "the renewed certificate replaces the old one")
+
+	require.NoError(t, store.DeleteCertificateCache(ctx, "example.com"))
+	require.NoError(t, store.DeleteCertificateCache(ctx, "example.com"))
+	_, err = store.GetCertificateCache(ctx, "example.com")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestCheckDSN(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, CheckDSN(Scheme+filepath.Join(dir, "db.sqlite")))
+	require.Error(t, CheckDSN(Scheme+filepath.Join(dir, "missing", "db.sqlite")))
+	require.ErrorIs(t, CheckDSN(Scheme), errs.ErrInvalidRequest)
+}
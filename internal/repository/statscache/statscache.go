@@ -0,0 +1,89 @@
+// Package statscache provides a caching decorator for the statistics storage.
+package statscache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats is the interface of the decorated statistics storage.
+type Stats interface {
+	// CountShortURLs returns the number of shortened URLs in the storage.
+	CountShortURLs(ctx context.Context) (int, error)
+
+	// CountUsers returns the number of users owning at least one URL.
+	CountUsers(ctx context.Context) (int, error)
+}
+
+// Interface implementation check.
+var _ Stats = (*Cache)(nil)
+
+// Cache caches statistics counters for the given TTL.
+// Stale values are refreshed on the first request after expiration.
+// It is safe for concurrent use.
+type Cache struct {
+	// next is the decorated storage.
+	next Stats
+	// ttl is how long the counters are valid.
+	ttl time.Duration
+	// shortURLs and users are the cached counters.
+	shortURLs, users counter
+}
+
+// counter is a single cached value.
+type counter struct {
+	// mu serializes refreshes of the value.
+	mu sync.Mutex
+	// value is the last fetched value.
+	value int
+	// expires is the moment the value becomes stale.
+	expires time.Time
+}
+
+// New returns statistics storage caching counters of next for ttl.
+func New(next Stats, ttl time.Duration) *Cache {
+	return &Cache{next: next, ttl: ttl}
+}
+
+// CountShortURLs returns cached number of shortened URLs.
+func (c *Cache) CountShortURLs(ctx context.Context) (int, error) {
+	return c.shortURLs.get(ctx, c.ttl, c.next.CountShortURLs)
+}
+
+// CountUsers returns cached number of users.
+func (c *Cache) CountUsers(ctx context.Context) (int, error) {
+	return c.users.get(ctx, c.ttl, c.next.CountUsers)
+}
+
+// Approximate reports whether the decorated storage counts approximately.
+func (c *Cache) Approximate() bool {
+	a, ok := c.next.(interface{ Approximate() bool })
+	return ok && a.Approximate()
+}
+
+// get returns the cached value or refreshes it with fetch if it is stale.
+// Concurrent callers wait for a single refresh instead of hitting
+// the storage simultaneously.
+func (c *counter) get(
+	ctx context.Context,
+	ttl time.Duration,
+	fetch func(context.Context) (int, error),
+) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expires) {
+		return c.value, nil
+	}
+
+	v, err := fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	c.value = v
+	c.expires = time.Now().Add(ttl)
+
+	return v, nil
+}
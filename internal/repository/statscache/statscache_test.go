@@ -0,0 +1,77 @@
+package statscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStats counts calls to the underlying storage.
+type countingStats struct {
+	calls int
+	err   error
+}
+
+func (s *countingStats) CountShortURLs(context.Context) (int, error) {
+	s.calls++
+	return s.calls, s.err
+}
+
+func (s *countingStats) CountUsers(context.Context) (int, error) {
+	s.calls++
+	return s.calls, s.err
+}
+
+func TestCache(t *testing.T) {
+	next := &countingStats{}
+	c := New(next, 50*time.Millisecond)
+
+	n, err := c.CountShortURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = c.CountShortURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "cached value expected")
+
+	n, err = c.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "counters expected to be cached separately")
+
+	time.Sleep(60 * time.Millisecond)
+
+	n, err = c.CountShortURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "stale value expected to be refreshed")
+}
+
+func TestCache_Error(t *testing.T) {
+	errBroken := errors.New("broken")
+	next := &countingStats{err: errBroken}
+	c := New(next, time.Minute)
+
+	_, err := c.CountUsers(context.Background())
+	require.ErrorIs(t, err, errBroken)
+
+	_, err = c.CountUsers(context.Background())
+	require.ErrorIs(t, err, errBroken)
+	assert.Equal(t, 2, next.calls, "errors must not be cached")
+}
+
+// estimatedStats counts approximately.
+type estimatedStats struct {
+	countingStats
+}
+
+func (s *estimatedStats) Approximate() bool {
+	return true
+}
+
+func TestCache_Approximate(t *testing.T) {
+	assert.False(t, New(&countingStats{}, time.Minute).Approximate())
+	assert.True(t, New(&estimatedStats{}, time.Minute).Approximate())
+}
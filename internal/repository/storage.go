@@ -5,14 +5,29 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/KretovDmitry/shortener/internal/config"
 	"github.com/KretovDmitry/shortener/internal/errs"
 	"github.com/KretovDmitry/shortener/internal/logger"
 	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository/cached"
+	"github.com/KretovDmitry/shortener/internal/repository/degraded"
 	"github.com/KretovDmitry/shortener/internal/repository/filestore"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
 	"github.com/KretovDmitry/shortener/internal/repository/postgres"
+	"github.com/KretovDmitry/shortener/internal/repository/redisstore"
+	"github.com/KretovDmitry/shortener/internal/repository/replicated"
+	"github.com/KretovDmitry/shortener/internal/repository/retrying"
+	"github.com/KretovDmitry/shortener/internal/repository/sqlitestore"
+	"github.com/KretovDmitry/shortener/internal/repository/statscache"
 	"github.com/KretovDmitry/shortener/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
 	sqldblogger "github.com/simukti/sqldb-logger"
 )
 
@@ -27,35 +42,367 @@ type URLStorage interface {
 	// Get retrieves a URL from the storage by its short URL.
 	Get(ctx context.Context, shortURL models.ShortURL) (*models.URL, error)
 
+	// GetMany retrieves URLs from the storage by their short URLs in a single
+	// round trip. Short URLs that are not found are skipped, the order of the
+	// result is not specified.
+	GetMany(ctx context.Context, shortURLs []models.ShortURL) ([]*models.URL, error)
+
 	// GetAllByUserID retrieves all URLs for a specific user from the storage.
-	GetAllByUserID(ctx context.Context, userID string) ([]*models.URL, error)
+	GetAllByUserID(ctx context.Context, userID user.ID) ([]*models.URL, error)
 
 	// DeleteURLs deletes one or more URLs from the storage.
 	DeleteURLs(ctx context.Context, urls ...*models.URL) error
 
+	// UpdateLastAccessed sets the last access time of the short URLs.
+	// Older times never overwrite newer ones.
+	UpdateLastAccessed(ctx context.Context, accessed map[models.ShortURL]time.Time) error
+
+	// DeleteExpired marks the URLs expired by the given time as deleted
+	// and returns their number.
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+
 	// Ping checks the health of the storage.
 	Ping(ctx context.Context) error
 }
 
+// Interface of the service statistics storage. It is kept apart from
+// URLStorage, so that statistics queries can be served by optimized
+// implementations without bloating the core storage interface.
+type StatsRepository interface {
+	// CountShortURLs returns the number of shortened URLs in the storage.
+	CountShortURLs(ctx context.Context) (int, error)
+
+	// CountUsers returns the number of users owning at least one URL.
+	CountUsers(ctx context.Context) (int, error)
+}
+
+// Interface of the statistics storage which may count approximately,
+// e.g. from the estimates of the database planner. The storages not
+// implementing it count exactly.
+type ApproximateStats interface {
+	// Approximate reports whether the counts are approximate.
+	Approximate() bool
+}
+
+// IsApproximate reports whether the statistics storage counts approximately.
+func IsApproximate(stats StatsRepository) bool {
+	a, ok := stats.(ApproximateStats)
+	return ok && a.Approximate()
+}
+
+// Interface of the reserved short codes storage.
+type ReservationStorage interface {
+	// Reserve saves the reservations in a single transaction. If any of
+	// the codes is already reserved or used by a URL, ErrConflict is
+	// returned and nothing is saved.
+	Reserve(ctx context.Context, reservations ...*models.Reservation) error
+
+	// GetReservation retrieves the reservation of the short code.
+	// If the code is not reserved, ErrNotFound is returned.
+	GetReservation(ctx context.Context, shortURL models.ShortURL) (*models.Reservation, error)
+}
+
+// Interface of the redirects storage used for the click analytics.
+type ClickStorage interface {
+	// SaveClicks saves the clicks in a single round trip.
+	SaveClicks(ctx context.Context, clicks ...*models.Click) error
+
+	// GetClickStats returns the click statistics of the short URL.
+	// Short URLs without clicks have empty statistics.
+	GetClickStats(ctx context.Context, shortURL models.ShortURL) (*models.ClickStats, error)
+}
+
+// Interface of the storage reporting the outcome of every URL of the batch.
+type SaveResultStorage interface {
+	// SaveAllResults saves a slice of URLs to the storage, skipping the ones
+	// conflicting with the stored URLs, and returns the outcome of every URL
+	// in the batch order.
+	SaveAllResults(ctx context.Context, urls []*models.URL) ([]models.SaveResult, error)
+}
+
+// Interface of the durable queue of the scheduled deletions, so that
+// the deletions accepted before the crash are applied after the restart.
+type DeletionQueue interface {
+	// SavePendingDeletions saves the scheduled deletions.
+	// Deletions already in the queue are skipped.
+	SavePendingDeletions(ctx context.Context, urls ...*models.URL) error
+
+	// GetPendingDeletions returns all the deletions in the queue
+	// in the order they were saved.
+	GetPendingDeletions(ctx context.Context) ([]*models.URL, error)
+
+	// RemovePendingDeletions removes the applied deletions from the queue.
+	RemovePendingDeletions(ctx context.Context, urls ...*models.URL) error
+}
+
+// Interface of the storage enumerating all the URL records,
+// e.g. to export the instance.
+type URLScanner interface {
+	// ScanURLs calls fn for every URL record, including the deleted ones,
+	// in no particular order. It stops at the first error returned by fn.
+	ScanURLs(ctx context.Context, fn func(*models.URL) error) error
+}
+
+// Interface of the storage streaming the URLs of the user with a cursor,
+// e.g. to export them without loading them all into memory.
+type UserURLScanner interface {
+	// ScanUserURLs calls fn for every URL of the user, including the deleted
+	// ones, ordered by their short URLs, with the number of their clicks.
+	// It stops at the first error returned by fn.
+	ScanUserURLs(ctx context.Context, userID user.ID, fn func(u *models.URL, clicks int) error) error
+}
+
+// Interface of the storage looking the URLs up by their original URLs,
+// e.g. to report the short URL the conflicting one is already saved under.
+type OriginalURLStorage interface {
+	// GetByOriginalURL retrieves the URL by its original URL. If there
+	// is no such URL, ErrNotFound is returned.
+	GetByOriginalURL(ctx context.Context, originalURL models.OriginalURL) (*models.URL, error)
+}
+
+// Interface of the API keys storage.
+type APIKeyStorage interface {
+	// SaveAPIKey saves the API key.
+	SaveAPIKey(ctx context.Context, key *models.APIKey) error
+
+	// GetAPIKey retrieves the API key by its hash.
+	// If the key is unknown, ErrNotFound is returned.
+	GetAPIKey(ctx context.Context, hash string) (*models.APIKey, error)
+
+	// ListAPIKeys returns the API keys of the user in the order
+	// they were minted.
+	ListAPIKeys(ctx context.Context, userID user.ID) ([]*models.APIKey, error)
+
+	// RotateAPIKey replaces the hash of the API key of the user, so that
+	// the old key is rejected at once. If the user has no key with the ID,
+	// ErrNotFound is returned.
+	RotateAPIKey(ctx context.Context, userID user.ID, id, hash string) error
+
+	// RecordAPIKeyUse counts the request with the API key
+	// and sets the time of its last use.
+	RecordAPIKeyUse(ctx context.Context, hash string, at time.Time) error
+}
+
+// Interface of the storage of the accounts the users register
+// with the login and the password.
+type AccountStorage interface {
+	// CreateAccount saves the account. If the login is taken or the user
+	// already has an account, ErrConflict is returned and nothing is saved.
+	CreateAccount(ctx context.Context, account *models.Account) error
+
+	// GetAccount retrieves the account by its normalized login.
+	// If the login is unknown, ErrNotFound is returned.
+	GetAccount(ctx context.Context, login string) (*models.Account, error)
+}
+
+// Interface of the storage of the revoked tokens.
+type RevocationStorage interface {
+	// RevokeToken revokes the token with the ID until it expires, then
+	// it may be forgotten. If the token is already revoked, ErrConflict
+	// is returned, so that the token is revoked once.
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// IsTokenRevoked reports whether the token with the ID is revoked.
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Interface of the storage of the moderation of the URLs of all the users.
+type ModerationStorage interface {
+	// BanDomain bans the destination domain. If the domain is already
+	// banned, ErrConflict is returned and nothing is saved.
+	BanDomain(ctx context.Context, ban *models.DomainBan) error
+
+	// GetDomainBan retrieves the ban of the host or of the domain it is
+	// a subdomain of, see hostpolicy.Domains. If the host is not banned,
+	// ErrNotFound is returned.
+	GetDomainBan(ctx context.Context, host string) (*models.DomainBan, error)
+
+	// ListDomainBans returns the banned domains in the order they were banned.
+	ListDomainBans(ctx context.Context) ([]*models.DomainBan, error)
+
+	// GetFlaggedURLs retrieves the URLs blocked by the moderators,
+	// excluding the deleted ones.
+	GetFlaggedURLs(ctx context.Context) ([]*models.URL, error)
+}
+
+// Interface of the storage of the per-link redirect limits.
+type RedirectLimitStorage interface {
+	// SetRedirectLimit sets the redirects allowed per minute for the URL
+	// of the user, zero removes the limit. If the user has no URL with
+	// the short URL, ErrNotFound is returned.
+	SetRedirectLimit(ctx context.Context, userID user.ID, shortURL models.ShortURL, limit int) error
+}
+
+// Interface of the storage of the edits of the links by their owners.
+type URLUpdateStorage interface {
+	// UpdateURL applies the update to the URL of the user, see
+	// models.URLUpdate. If the URL was edited since the version of the
+	// update, models.ErrStaleVersion is returned. If the storage keeps
+	// the destinations unique and the destination is taken by another URL,
+	// ErrConflict is returned. If the user has no URL with the short URL
+	// or it is deleted, ErrNotFound is returned. Nothing is changed
+	// in either case.
+	UpdateURL(ctx context.Context, userID user.ID, shortURL models.ShortURL, update *models.URLUpdate) error
+}
+
+// Interface of the storage of the owners of the links and of the users
+// the owners share them with, see models.URLShare.
+type ShareStorage interface {
+	// TransferURL hands the URL of the user over to the new owner in
+	// a single transaction, dropping its shares and removing it from
+	// the campaigns of the user. If the user has no URL with the short URL
+	// or it is deleted, ErrNotFound is returned.
+	TransferURL(ctx context.Context, userID user.ID, shortURL models.ShortURL, to user.ID) error
+
+	// ShareURL grants the read access to the URL of the user. Sharing
+	// the URL again keeps the original share. If the user has no URL
+	// with the short URL or it is deleted, ErrNotFound is returned.
+	ShareURL(ctx context.Context, userID user.ID, share *models.URLShare) error
+
+	// UnshareURL revokes the read access to the URL of the user.
+	// If the URL is not shared with the user, ErrNotFound is returned.
+	UnshareURL(ctx context.Context, userID user.ID, shortURL models.ShortURL, with user.ID) error
+
+	// GetShares returns the shares of the URL of the user in the order
+	// they were granted. If the user has no URL with the short URL,
+	// ErrNotFound is returned.
+	GetShares(ctx context.Context, userID user.ID, shortURL models.ShortURL) ([]*models.URLShare, error)
+
+	// GetSharedURLs retrieves the URLs shared with the user,
+	// excluding the deleted ones.
+	GetSharedURLs(ctx context.Context, userID user.ID) ([]*models.URL, error)
+}
+
+// Interface of the storage of the notes the owners annotate their links with.
+type NoteStorage interface {
+	// SetNote sets the note of the URL of the user, empty removes it.
+	// If the user has no URL with the short URL, ErrNotFound is returned.
+	SetNote(ctx context.Context, userID user.ID, shortURL models.ShortURL, note string) error
+}
+
+// Interface of the storage of the states of the links in their lifecycle.
+type LinkStateStorage interface {
+	// SetLinkState changes the state of the URL of the user kept in
+	// the record from the given one to the other, see models.LinkState
+	// and models.URL.StoredState. Only the states kept in the records
+	// are accepted, the transitions are checked by the callers. The state
+	// is changed only if it is still the given one, so that the concurrent
+	// changes, e.g. the blocking by the moderators, are not overwritten;
+	// ErrConflict is returned otherwise. If the user has no URL with
+	// the short URL, ErrNotFound is returned.
+	SetLinkState(ctx context.Context, userID user.ID, shortURL models.ShortURL, from, to models.LinkState) error
+}
+
+// Interface of the storage of the campaigns the owners group their links
+// with. A link may belong to any number of the campaigns of its owner.
+type CampaignStorage interface {
+	// AddToCampaign adds the URLs of the user to the campaign in a single
+	// transaction and returns the number of the added ones. Short URLs of
+	// other users, deleted or already in the campaign are skipped.
+	AddToCampaign(ctx context.Context, userID user.ID, campaign string, shortURLs []models.ShortURL) (int, error)
+
+	// GetCampaignURLs retrieves the URLs of the user in the campaign,
+	// excluding the deleted ones.
+	GetCampaignURLs(ctx context.Context, userID user.ID, campaign string) ([]*models.URL, error)
+
+	// TransferCampaign copies the URLs of the user in the campaign from
+	// to the campaign to in a single transaction, skipping the ones whose
+	// destinations are already in it. If move is set, the URLs are removed
+	// from the campaign from.
+	TransferCampaign(ctx context.Context, userID user.ID, from, to string, move bool) (*models.CampaignTransfer, error)
+}
+
+// Interface of the storage of the daily creation trends. The trends
+// are kept in the rollups updated as the URLs are saved, so that
+// they are read without scanning the URLs.
+type TrendStorage interface {
+	// GetTrends returns the daily trends since the given day in UTC,
+	// oldest first. Days without created URLs are omitted.
+	GetTrends(ctx context.Context, since time.Time) ([]models.DailyTrend, error)
+}
+
+// Interface of the storage of the aggregates of the extended statistics,
+// read from the clicks and the creation rollups.
+type ExtendedStatsStorage interface {
+	// TopLinks returns at most limit short URLs most clicked since
+	// the given time, most clicked first.
+	TopLinks(ctx context.Context, since time.Time, limit int) ([]models.TopLink, error)
+	// CountActiveUsers returns the number of the users who created
+	// URLs since the given day in UTC.
+	CountActiveUsers(ctx context.Context, since time.Time) (int, error)
+}
+
+// Interface of the storage of the responses to the requests with
+// the Idempotency-Key header, so that the retries are replayed.
+type IdempotencyStorage interface {
+	// SaveIdempotentResponse saves the response until it expires. If the
+	// unexpired response of the user with the same key is already saved,
+	// ErrConflict is returned and nothing is saved.
+	SaveIdempotentResponse(ctx context.Context, resp *models.IdempotentResponse) error
+
+	// GetIdempotentResponse retrieves the unexpired response of the user
+	// by the key. If there is none, ErrNotFound is returned.
+	GetIdempotentResponse(ctx context.Context, userID user.ID, key string) (*models.IdempotentResponse, error)
+}
+
+// CertificateCacheStorage keeps the ACME account key and the certificates
+// issued by Let's Encrypt, so that the replicas share them.
+type CertificateCacheStorage interface {
+	// GetCertificateCache retrieves the cache entry by the key.
+	// If there is none, ErrNotFound is returned.
+	GetCertificateCache(ctx context.Context, key string) ([]byte, error)
+
+	// PutCertificateCache saves the cache entry,
+	// replacing the one with the same key.
+	PutCertificateCache(ctx context.Context, key string, data []byte) error
+
+	// DeleteCertificateCache removes the cache entry.
+	// Removing the missing entry is not an error.
+	DeleteCertificateCache(ctx context.Context, key string) error
+}
+
+// Interface of the storage reporting the stats of its connection pool.
+type PoolStatsStorage interface {
+	// PoolStats returns the stats of the pool of the database connections.
+	PoolStats() sql.DBStats
+}
+
 // NewURLStore returns one of the URLStorage implementations based on
-// the configuration. Could be in memory, file storage or postgres.
+// the configuration. Could be in memory, file storage, redis, sqlite or postgres.
 func NewURLStore(config *config.Config, logger logger.Logger) (URLStorage, error) {
 	// Check for dependencies that can lead to panic.
 	if config == nil {
 		return nil, fmt.Errorf("%w: config", errs.ErrNilDependency)
 	}
 
+	// Keep everything in memory in the sandbox. Unlike the file storage
+	// without the path, the memory one supports all the features.
+	if config.Sandbox.Enabled {
+		logger.Info("sandbox is enabled, initializing in memory storage")
+		return memstore.NewURLRepository(), nil
+	}
+
+	// Init sqlite URL repository if DSN has the sqlite scheme.
+	if strings.HasPrefix(config.DSN, sqlitestore.Scheme) {
+		store, err := sqlitestore.New(config.DSN, logger)
+		if err != nil {
+			return nil, fmt.Errorf("new sqlite repository: %w", err)
+		}
+
+		logger.Infof("sqlite storage initialized at: %q",
+			strings.TrimPrefix(config.DSN, sqlitestore.Scheme))
+
+		return withCache(config, store, logger)
+	}
+
 	// Init postgres URL repository if DSN is provided.
 	if config.DSN != "" {
 		// Connect to the postgres.
-		db, err := sql.Open("pgx", config.DSN)
+		db, err := openPostgres(config.DSN, config, logger)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open the database: %w", err)
+			return nil, err
 		}
 
-		// Log every query to the database.
-		db = sqldblogger.OpenDriver(config.DSN, db.Driver(), logger)
-
 		// Check connectivity and DSN correctness.
 		if err = db.Ping(); err != nil {
 			return nil, fmt.Errorf("failed to connect to the database: %w", err)
@@ -67,7 +414,54 @@ func NewURLStore(config *config.Config, logger logger.Logger) (URLStorage, error
 			return nil, fmt.Errorf("failed to migrate DB: %w", err)
 		}
 
-		return postgres.NewURLRepository(db, logger)
+		pg, err := postgres.NewURLRepository(db, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := withReplica(config, pg, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err = withRetries(config, store, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err = withCache(config, store, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return withDegradedMode(config, store, logger)
+	}
+
+	// Init redis URL repository if redis DSN is provided.
+	if config.Redis.DSN != "" {
+		opts, err := redis.ParseURL(config.Redis.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis DSN: %w", err)
+		}
+
+		client := redis.NewClient(opts)
+
+		// Check connectivity and DSN correctness.
+		if err = client.Ping(context.Background()).Err(); err != nil {
+			return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+
+		rs, err := redisstore.NewURLRepository(client, config.Redis.KeyPrefix, config.Redis.TTL)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := withCache(config, rs, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return withDegradedMode(config, store, logger)
 	}
 
 	logger.Info("DSN is not provided, initializing file storage")
@@ -86,3 +480,591 @@ func NewURLStore(config *config.Config, logger logger.Logger) (URLStorage, error
 
 	return store, nil
 }
+
+// openPostgres opens the pool of the connections to the postgres
+// with the configured limits and statement timeout. Every query
+// to the database is logged.
+func openPostgres(dsn string, config *config.Config, logger logger.Logger) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the DSN: %w", err)
+	}
+	if timeout := config.Postgres.StatementTimeout; timeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	}
+	registered := stdlib.RegisterConnConfig(connConfig)
+
+	db, err := sql.Open("pgx", registered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the database: %w", err)
+	}
+	db = sqldblogger.OpenDriver(registered, db.Driver(), logger)
+
+	db.SetMaxOpenConns(config.Postgres.MaxOpenConns)
+	db.SetMaxIdleConns(config.Postgres.MaxIdleConns)
+	db.SetConnMaxLifetime(config.Postgres.ConnMaxLifetime)
+
+	return db, nil
+}
+
+// checkTimeout limits the time CheckConnections waits for every server.
+const checkTimeout = 5 * time.Second
+
+// CheckConnections adds the errors of the databases of the configuration
+// which can't be reached to the report. It connects without migrating
+// or creating anything, so that the configuration can be checked before
+// the deployment.
+func CheckConnections(ctx context.Context, config *config.Config, report *config.Report) {
+	switch {
+	case strings.HasPrefix(config.DSN, sqlitestore.Scheme):
+		if err := sqlitestore.CheckDSN(config.DSN); err != nil {
+			report.Errorf("dsn", "%s", err)
+		}
+	case config.DSN != "":
+		if err := pingPostgres(ctx, config.DSN); err != nil {
+			report.Errorf("dsn", "%s", err)
+		}
+		if config.Postgres.ReplicaDSN != "" {
+			if err := pingPostgres(ctx, config.Postgres.ReplicaDSN); err != nil {
+				report.Errorf("postgres.replica_dsn", "%s", err)
+			}
+		}
+	}
+
+	if config.Redis.DSN != "" {
+		if err := pingRedis(ctx, config.Redis.DSN); err != nil {
+			report.Errorf("redis.dsn", "%s", err)
+		}
+	}
+}
+
+func pingPostgres(ctx context.Context, dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open the database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	return nil
+}
+
+func pingRedis(ctx context.Context, dsn string) error {
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return fmt.Errorf("failed to parse redis DSN: %w", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err = client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return nil
+}
+
+// withDegradedMode decorates the database storage with the degraded mode
+// store if it is enabled.
+func withDegradedMode(config *config.Config, store URLStorage, logger logger.Logger) (URLStorage, error) {
+	if !config.Degraded.Enabled {
+		return store, nil
+	}
+
+	d, err := degraded.New(store, config.Degraded, logger)
+	if err != nil {
+		return nil, fmt.Errorf("new degraded mode store: %w", err)
+	}
+
+	logger.Infof("degraded mode is enabled, writes are spooled to %q while the database is down",
+		config.Degraded.SpoolPath)
+
+	return d, nil
+}
+
+// withCache decorates the database storage with the in-memory cache
+// of the URL records if its size is set. The cache goes under the degraded
+// mode store, which has to stay the outermost one.
+func withCache(config *config.Config, store URLStorage, logger logger.Logger) (URLStorage, error) {
+	if config.Cache.Size <= 0 {
+		return store, nil
+	}
+
+	c, err := cached.New(store, config.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("new cached store: %w", err)
+	}
+
+	logger.Infof("URL cache is enabled for up to %d records for %s",
+		config.Cache.Size, config.Cache.TTL)
+
+	return c, nil
+}
+
+// withReplica decorates the postgres storage with the routing of the reads
+// to the replica if its DSN is set. The replica is not migrated.
+func withReplica(config *config.Config, pg *postgres.URLRepository, logger logger.Logger) (URLStorage, error) {
+	if config.Postgres.ReplicaDSN == "" {
+		return pg, nil
+	}
+
+	db, err := openPostgres(config.Postgres.ReplicaDSN, config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to the replica: %w", err)
+	}
+
+	replica, err := postgres.NewURLRepository(db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := replicated.New(pg, replica)
+	if err != nil {
+		return nil, fmt.Errorf("new replicated store: %w", err)
+	}
+
+	logger.Info("read replica is enabled, redirects and lists are read from it")
+
+	return r, nil
+}
+
+// withRetries decorates the postgres storage with the retries of the
+// operations failed with the transient errors if they are enabled.
+// The retries go under the cache, so that the cache sees their outcome.
+func withRetries(config *config.Config, store URLStorage, logger logger.Logger) (URLStorage, error) {
+	if config.Postgres.MaxRetries <= 0 {
+		return store, nil
+	}
+
+	r, err := retrying.New(store, config.Postgres, postgres.IsTransient)
+	if err != nil {
+		return nil, fmt.Errorf("new retrying store: %w", err)
+	}
+
+	logger.Infof("transient database errors are retried up to %d times", config.Postgres.MaxRetries)
+
+	return r, nil
+}
+
+// unwrap returns the storage decorated by the degraded mode store, the cache,
+// the retries and the replica, so that its optional capabilities are available.
+func unwrap(store URLStorage) URLStorage {
+	for {
+		switch s := store.(type) {
+		case *degraded.Store:
+			store = s.Unwrap()
+		case *cached.Store:
+			store = s.Unwrap()
+		case *retrying.Store:
+			store = s.Unwrap()
+		case *replicated.Store:
+			store = s.Unwrap()
+		default:
+			return store
+		}
+	}
+}
+
+// readStore returns the storage the reads of the statistics go to:
+// the replica if it is enabled, the unwrapped storage otherwise.
+func readStore(store URLStorage) URLStorage {
+	for {
+		switch s := store.(type) {
+		case *degraded.Store:
+			store = s.Unwrap()
+		case *cached.Store:
+			store = s.Unwrap()
+		case *retrying.Store:
+			store = s.Unwrap()
+		case *replicated.Store:
+			return unwrap(s.Replica())
+		default:
+			return store
+		}
+	}
+}
+
+// cacheOf returns the cache decorating the storage, nil if there is none.
+func cacheOf(store URLStorage) *cached.Store {
+	for {
+		switch s := store.(type) {
+		case *degraded.Store:
+			store = s.Unwrap()
+		case *cached.Store:
+			return s
+		default:
+			return nil
+		}
+	}
+}
+
+// NewStatsStore returns the statistics storage backed by the given URL
+// storage. Postgres counts with the planner estimates or the maintained
+// counters instead of the table scans if configured so. Statistics are
+// cached for the configured TTL if it is set.
+func NewStatsStore(cfg *config.Config, store URLStorage) (StatsRepository, error) {
+	// Check for dependencies that can lead to panic.
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: config", errs.ErrNilDependency)
+	}
+
+	stats, ok := readStore(store).(StatsRepository)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support statistics", store)
+	}
+
+	switch counting := cfg.Stats.Counting; counting {
+	case config.StatsCountingEstimate, config.StatsCountingCounters:
+		pg, ok := readStore(store).(*postgres.URLRepository)
+		if !ok {
+			return nil, fmt.Errorf("%T does not support %s stats counting", store, counting)
+		}
+		if counting == config.StatsCountingEstimate {
+			stats = pg.EstimatedStats()
+		} else {
+			stats = pg.CounterStats()
+		}
+	}
+
+	if cfg.Stats.CacheTTL > 0 {
+		return statscache.New(stats, cfg.Stats.CacheTTL), nil
+	}
+
+	return stats, nil
+}
+
+// NewExtendedStatsStore returns the storage of the extended statistics
+// backed by the given URL storage. The aggregates are read from the
+// replica if the reads are routed to it.
+func NewExtendedStatsStore(store URLStorage) (ExtendedStatsStorage, error) {
+	stats, ok := readStore(store).(ExtendedStatsStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support extended statistics", store)
+	}
+	return stats, nil
+}
+
+// NewReservationStore returns the reserved short codes storage
+// backed by the given URL storage.
+func NewReservationStore(store URLStorage) (ReservationStorage, error) {
+	reservations, ok := unwrap(store).(ReservationStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support reservations", store)
+	}
+	return reservations, nil
+}
+
+// NewClickStore returns the clicks storage backed by the given URL storage.
+func NewClickStore(store URLStorage) (ClickStorage, error) {
+	clicks, ok := unwrap(store).(ClickStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support click analytics", store)
+	}
+	return clicks, nil
+}
+
+// NewDeletionQueue returns the durable queue of the scheduled deletions
+// backed by the given URL storage.
+func NewDeletionQueue(store URLStorage) (DeletionQueue, error) {
+	queue, ok := unwrap(store).(DeletionQueue)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support pending deletions", store)
+	}
+	return queue, nil
+}
+
+// NewAPIKeyStore returns the API keys storage backed by the given URL storage.
+func NewAPIKeyStore(store URLStorage) (APIKeyStorage, error) {
+	keys, ok := unwrap(store).(APIKeyStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support API keys", store)
+	}
+	return keys, nil
+}
+
+// NewAccountStore returns the accounts storage backed by the given URL storage.
+func NewAccountStore(store URLStorage) (AccountStorage, error) {
+	accounts, ok := unwrap(store).(AccountStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support accounts", store)
+	}
+	return accounts, nil
+}
+
+// NewRevocationStore returns the revoked tokens storage backed by the given URL storage.
+func NewRevocationStore(store URLStorage) (RevocationStorage, error) {
+	revocations, ok := unwrap(store).(RevocationStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support token revocation", store)
+	}
+	return revocations, nil
+}
+
+// NewModerationStore returns the moderation storage backed by the given URL storage.
+func NewModerationStore(store URLStorage) (ModerationStorage, error) {
+	moderation, ok := unwrap(store).(ModerationStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support moderation", store)
+	}
+	return moderation, nil
+}
+
+// NewIdempotencyStore returns the storage of the idempotent responses
+// backed by the given URL storage.
+func NewIdempotencyStore(store URLStorage) (IdempotencyStorage, error) {
+	responses, ok := unwrap(store).(IdempotencyStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support idempotency keys", store)
+	}
+	return responses, nil
+}
+
+// NewCertificateCacheStore returns the storage of the ACME certificates
+// backed by the given URL storage.
+func NewCertificateCacheStore(store URLStorage) (CertificateCacheStorage, error) {
+	certs, ok := unwrap(store).(CertificateCacheStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support the certificate cache", store)
+	}
+	return certs, nil
+}
+
+// NewRedirectLimitStore returns the storage of the redirect limits
+// backed by the given URL storage.
+func NewRedirectLimitStore(store URLStorage) (RedirectLimitStorage, error) {
+	limits, ok := unwrap(store).(RedirectLimitStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support redirect limits", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedRedirectLimits{next: limits, cache: c}, nil
+	}
+	return limits, nil
+}
+
+// cachedRedirectLimits drops the short URLs from the cache
+// once their redirect limits are changed.
+type cachedRedirectLimits struct {
+	next  RedirectLimitStorage
+	cache *cached.Store
+}
+
+// SetRedirectLimit sets the redirect limit and drops the short URL from the cache.
+func (l *cachedRedirectLimits) SetRedirectLimit(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, limit int,
+) error {
+	defer l.cache.Invalidate(shortURL)
+	return l.next.SetRedirectLimit(ctx, userID, shortURL, limit)
+}
+
+// NewURLUpdateStore returns the storage of the edits of the links
+// backed by the given URL storage.
+func NewURLUpdateStore(store URLStorage) (URLUpdateStorage, error) {
+	updates, ok := unwrap(store).(URLUpdateStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support editing", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedURLUpdates{next: updates, cache: c}, nil
+	}
+	return updates, nil
+}
+
+// cachedURLUpdates drops the short URLs from the cache
+// once they are edited.
+type cachedURLUpdates struct {
+	next  URLUpdateStorage
+	cache *cached.Store
+}
+
+// UpdateURL applies the update and drops the short URL from the cache.
+func (u *cachedURLUpdates) UpdateURL(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, update *models.URLUpdate,
+) error {
+	defer u.cache.Invalidate(shortURL)
+	return u.next.UpdateURL(ctx, userID, shortURL, update)
+}
+
+// NewShareStore returns the storage of the owners and the shares
+// of the links backed by the given URL storage.
+func NewShareStore(store URLStorage) (ShareStorage, error) {
+	shares, ok := unwrap(store).(ShareStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support sharing", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedShares{ShareStorage: shares, cache: c}, nil
+	}
+	return shares, nil
+}
+
+// cachedShares drops the short URLs from the cache
+// once they are transferred to another owner.
+type cachedShares struct {
+	ShareStorage
+	cache *cached.Store
+}
+
+// TransferURL transfers the URL and drops the short URL from the cache.
+func (s *cachedShares) TransferURL(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, to user.ID,
+) error {
+	defer s.cache.Invalidate(shortURL)
+	return s.ShareStorage.TransferURL(ctx, userID, shortURL, to)
+}
+
+// NewNoteStore returns the storage of the notes
+// backed by the given URL storage.
+func NewNoteStore(store URLStorage) (NoteStorage, error) {
+	notes, ok := unwrap(store).(NoteStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support notes", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedNotes{next: notes, cache: c}, nil
+	}
+	return notes, nil
+}
+
+// cachedNotes drops the short URLs from the cache
+// once their notes are changed.
+type cachedNotes struct {
+	next  NoteStorage
+	cache *cached.Store
+}
+
+// SetNote sets the note and drops the short URL from the cache.
+func (n *cachedNotes) SetNote(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, note string,
+) error {
+	defer n.cache.Invalidate(shortURL)
+	return n.next.SetNote(ctx, userID, shortURL, note)
+}
+
+// NewLinkStateStore returns the storage of the link states
+// backed by the given URL storage.
+func NewLinkStateStore(store URLStorage) (LinkStateStorage, error) {
+	states, ok := unwrap(store).(LinkStateStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support link states", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedLinkStates{next: states, cache: c}, nil
+	}
+	return states, nil
+}
+
+// cachedLinkStates drops the short URLs from the cache
+// once their states are changed.
+type cachedLinkStates struct {
+	next  LinkStateStorage
+	cache *cached.Store
+}
+
+// SetLinkState sets the state and drops the short URL from the cache.
+func (s *cachedLinkStates) SetLinkState(ctx context.Context, userID user.ID,
+	shortURL models.ShortURL, from, to models.LinkState,
+) error {
+	defer s.cache.Invalidate(shortURL)
+	return s.next.SetLinkState(ctx, userID, shortURL, from, to)
+}
+
+// NewTrendStore returns the storage of the daily creation trends
+// backed by the given URL storage.
+func NewTrendStore(store URLStorage) (TrendStorage, error) {
+	trends, ok := unwrap(store).(TrendStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support trends", store)
+	}
+	return trends, nil
+}
+
+// NewPoolStats returns the stats of the connection pool
+// of the given URL storage.
+func NewPoolStats(store URLStorage) (PoolStatsStorage, error) {
+	pool, ok := unwrap(store).(PoolStatsStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T has no connection pool", store)
+	}
+	return pool, nil
+}
+
+// NewCampaignStore returns the storage of the campaigns
+// backed by the given URL storage.
+func NewCampaignStore(store URLStorage) (CampaignStorage, error) {
+	campaigns, ok := unwrap(store).(CampaignStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support campaigns", store)
+	}
+	return campaigns, nil
+}
+
+// NewUserURLScanner returns the scanner of the URLs of the users
+// backed by the given URL storage.
+func NewUserURLScanner(store URLStorage) (UserURLScanner, error) {
+	scanner, ok := unwrap(store).(UserURLScanner)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support scanning the URLs of the users", store)
+	}
+	return scanner, nil
+}
+
+// NewOriginalURLStore returns the storage looking the URLs up
+// by their original URLs backed by the given URL storage.
+func NewOriginalURLStore(store URLStorage) (OriginalURLStorage, error) {
+	originals, ok := unwrap(store).(OriginalURLStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support looking the URLs up by their original URLs", store)
+	}
+	return originals, nil
+}
+
+// NewSaveResultStore returns the storage reporting the outcome
+// of every saved URL backed by the given URL storage.
+func NewSaveResultStore(store URLStorage) (SaveResultStorage, error) {
+	results, ok := unwrap(store).(SaveResultStorage)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support the outcomes of the saved URLs", store)
+	}
+	if c := cacheOf(store); c != nil {
+		return &cachedSaveResults{next: results, cache: c}, nil
+	}
+	return results, nil
+}
+
+// cachedSaveResults drops the short URLs from the cache
+// once they are saved, as SaveAll of the cached store does.
+type cachedSaveResults struct {
+	next  SaveResultStorage
+	cache *cached.Store
+}
+
+// SaveAllResults saves the URLs and drops their short URLs from the cache.
+func (s *cachedSaveResults) SaveAllResults(
+	ctx context.Context, urls []*models.URL,
+) ([]models.SaveResult, error) {
+	defer func() {
+		for _, u := range urls {
+			s.cache.Invalidate(u.ShortURL)
+		}
+	}()
+	return s.next.SaveAllResults(ctx, urls)
+}
+
+// NewURLScanner returns the scanner of all the URL records
+// backed by the given URL storage.
+func NewURLScanner(store URLStorage) (URLScanner, error) {
+	scanner, ok := unwrap(store).(URLScanner)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support scanning", store)
+	}
+	return scanner, nil
+}
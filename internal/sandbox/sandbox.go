@@ -0,0 +1,101 @@
+// Package sandbox seeds the storage of the developer sandbox with the demo
+// data. The data is the same on every start, so that the clients can be
+// developed and tested against the known fixtures: the demo user owning
+// the links in every state, a campaign and the clicks of the links.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/models"
+	"github.com/KretovDmitry/shortener/internal/models/user"
+	"github.com/KretovDmitry/shortener/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Credentials of the demo user.
+const (
+	DemoUserID   user.ID = "5a4d1e2c-9b7f-4e3a-8c61-0d2f5b7a9e14"
+	DemoLogin            = "demo"
+	DemoPassword         = "sandbox-demo"
+)
+
+// DemoCampaign is the campaign of the demo links.
+const DemoCampaign = "launch"
+
+// clickDays is the number of the last days the demo clicks are spread over.
+const clickDays = 7
+
+// Links returns the demo links of the demo user relative to now:
+// the active ones, the paused one and the expired one.
+func Links(now time.Time) []*models.URL {
+	expired := now.Add(-time.Hour)
+	expiring := now.Add(30 * 24 * time.Hour)
+
+	links := []*models.URL{
+		{ShortURL: "GoDev", OriginalURL: "https://go.dev/", Note: "Home page"},
+		{ShortURL: "GoDocs", OriginalURL: "https://go.dev/doc/"},
+		{ShortURL: "GoNews", OriginalURL: "https://go.dev/blog/", ExpiresAt: &expiring},
+		{ShortURL: "GoRun", OriginalURL: "https://go.dev/play/", RedirectLimit: 10},
+		{ShortURL: "GoTour", OriginalURL: "https://go.dev/tour/", State: models.LinkPaused},
+		{ShortURL: "GoWiki", OriginalURL: "https://go.dev/wiki/", ExpiresAt: &expired},
+	}
+	for _, u := range links {
+		// the IDs are derived from the codes to be the same on every start
+		u.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(u.ShortURL)).String()
+		u.UserID = DemoUserID
+	}
+	return links
+}
+
+// Seed saves the demo data to the store. The data of the features the store
+// doesn't support is skipped.
+func Seed(ctx context.Context, store repository.URLStorage, now time.Time) error {
+	links := Links(now)
+	if err := store.SaveAll(ctx, links); err != nil {
+		return fmt.Errorf("save links: %w", err)
+	}
+
+	if accounts, err := repository.NewAccountStore(store); err == nil {
+		account, err := models.NewAccount(DemoUserID, DemoLogin, DemoPassword)
+		if err != nil {
+			return fmt.Errorf("new account: %w", err)
+		}
+		if err = accounts.CreateAccount(ctx, account); err != nil {
+			return fmt.Errorf("create account: %w", err)
+		}
+	}
+
+	if campaigns, err := repository.NewCampaignStore(store); err == nil {
+		codes := []models.ShortURL{links[0].ShortURL, links[1].ShortURL, links[2].ShortURL}
+		if _, err = campaigns.AddToCampaign(ctx, DemoUserID, DemoCampaign, codes); err != nil {
+			return fmt.Errorf("add to campaign: %w", err)
+		}
+	}
+
+	if clicks, err := repository.NewClickStore(store); err == nil {
+		if err = clicks.SaveClicks(ctx, Clicks(links, now)...); err != nil {
+			return fmt.Errorf("save clicks: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Clicks returns the demo clicks of the links for the last days relative
+// to now. The first links are clicked the most.
+func Clicks(links []*models.URL, now time.Time) []*models.Click {
+	var clicks []*models.Click
+	for i, u := range links {
+		for day := 0; day < clickDays; day++ {
+			for n := 0; n < len(links)-i; n++ {
+				at := now.Add(-time.Duration(day)*24*time.Hour - time.Duration(n)*time.Hour)
+				clicks = append(clicks, models.NewClick(u.ShortURL, at,
+					fmt.Sprintf("192.0.2.%d", n+1), "https://example.com/", "Mozilla/5.0"))
+			}
+		}
+	}
+	return clicks
+}
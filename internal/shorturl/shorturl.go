@@ -1,20 +1,254 @@
 package shorturl
 
 import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // the hash is not used for security
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Base58 is the default alphabet of the short links. It leaves out
+// the characters easy to confuse: 0, O, I and l.
+const Base58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
 
-	"github.com/itchyny/base58-go"
+// Hash algorithms of the short links.
+const (
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+	HashSHA1   = "sha1"
+	HashFNV    = "fnv"
 )
 
+// hashes are the hash functions by algorithm name.
+var hashes = map[string]func() hash.Hash{
+	HashSHA256: sha256.New,
+	HashSHA512: sha512.New,
+	HashSHA1:   sha1.New,
+	HashFNV:    func() hash.Hash { return fnv.New64a() },
+}
+
+// defaultGenerator produces the short links for the package functions.
+var defaultGenerator = &Generator{alphabet: Base58, hash: sha256.New, hashBits: sha256.Size * 8}
+
+// Generator produces the short links of the configured length
+// and alphabet. It is safe for concurrent use.
+type Generator struct {
+	// length is the number of characters of the short links.
+	// The links have the natural length of the 64-bit number if zero.
+	length int
+	// alphabet is the characters of the short links.
+	alphabet string
+	// hash is the hash function of the original links.
+	hash func() hash.Hash
+	// hashBits is the size of the hash in bits.
+	hashBits int
+}
+
+// Option configures the generator.
+type Option func(*options)
+
+type options struct {
+	length    int
+	alphabet  string
+	algorithm string
+}
+
+// WithLength sets the length of the short links. Shorter links are more
+// likely to collide. Zero gives the natural length of the 64-bit number,
+// e.g. up to 11 characters in base58.
+func WithLength(length int) Option {
+	return func(o *options) {
+		o.length = length
+	}
+}
+
+// WithAlphabet sets the characters of the short links.
+// Empty alphabet leaves the default base58 one.
+func WithAlphabet(alphabet string) Option {
+	return func(o *options) {
+		if alphabet != "" {
+			o.alphabet = alphabet
+		}
+	}
+}
+
+// WithHash sets the hash algorithm of the original links: sha256, sha512,
+// sha1 or fnv. Empty algorithm leaves the default sha256 one.
+func WithHash(algorithm string) Option {
+	return func(o *options) {
+		if algorithm != "" {
+			o.algorithm = algorithm
+		}
+	}
+}
+
+// New returns the generator configured with the options.
+// It returns an error if the hash is too short for the length.
+func New(opts ...Option) (*Generator, error) {
+	o := options{alphabet: Base58, algorithm: HashSHA256}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := validateAlphabet(o.alphabet); err != nil {
+		return nil, err
+	}
+	newHash, ok := hashes[o.algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q", o.algorithm)
+	}
+	hashBits := newHash().Size() * 8
+	if o.length < 0 {
+		return nil, fmt.Errorf("negative length %d", o.length)
+	}
+	bits := float64(o.length) * math.Log2(float64(len(o.alphabet)))
+	if bits > float64(hashBits) {
+		return nil, fmt.Errorf("%d characters need %.0f bits, %s hash has %d",
+			o.length, math.Ceil(bits), o.algorithm, hashBits)
+	}
+
+	return &Generator{
+		length:   o.length,
+		alphabet: o.alphabet,
+		hash:     newHash,
+		hashBits: hashBits,
+	}, nil
+}
+
+// validateAlphabet checks that the alphabet consists of at least two
+// distinct characters allowed in the URL path unescaped.
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return errors.New("alphabet must have at least 2 characters")
+	}
+	for i, c := range []byte(alphabet) {
+		if !isUnreserved(c) {
+			return fmt.Errorf("alphabet character %q is not allowed in URLs", c)
+		}
+		if strings.IndexByte(alphabet[:i], c) >= 0 {
+			return fmt.Errorf("alphabet character %q is repeated", c)
+		}
+	}
+	return nil
+}
+
+// isUnreserved reports whether the character is unreserved in URLs
+// according to RFC 3986.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
 // Generate produces a short link from the original one.
 // It utilizes base 58 algorithm to reduce confusion in character output
 // (0OIl+/ are not used).
 func Generate(s string) string {
-	sha256 := sha256.New()
-	sha256.Write([]byte(s))
-	urlHashBytes := sha256.Sum(nil)
-	generatedNumber := binary.BigEndian.Uint64(urlHashBytes)
-	encodedBytes := base58.BitcoinEncoding.EncodeUint64(generatedNumber)
-	return string(encodedBytes)
+	return defaultGenerator.Generate(s)
+}
+
+// GenerateSalted produces a short link from the original one mixed with
+// the salt. It is used to get an alternative short link when the one
+// produced by Generate can't be used. Zero salt gives the same result
+// as Generate.
+func GenerateSalted(s string, salt uint64) string {
+	return defaultGenerator.GenerateSalted(s, salt)
+}
+
+// Random produces a random short link not bound to any original one.
+func Random() (string, error) {
+	return defaultGenerator.Random()
+}
+
+// Generate produces a short link from the original one.
+func (g *Generator) Generate(s string) string {
+	return g.GenerateSalted(s, 0)
+}
+
+// GenerateSalted produces a short link from the original one mixed with
+// the salt. Zero salt gives the same result as Generate.
+func (g *Generator) GenerateSalted(s string, salt uint64) string {
+	h := g.hash()
+	h.Write([]byte(s))
+	if salt != 0 {
+		_ = binary.Write(h, binary.BigEndian, salt)
+	}
+	sum := h.Sum(nil)
+
+	if g.length == 0 {
+		return g.encodeUint64(binary.BigEndian.Uint64(sum))
+	}
+	return g.encode(new(big.Int).SetBytes(sum))
+}
+
+// Random produces a random short link not bound to any original one.
+func (g *Generator) Random() (string, error) {
+	if g.length == 0 {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		return g.encodeUint64(binary.BigEndian.Uint64(b[:])), nil
+	}
+
+	b := make([]byte, g.hashBits/8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return g.encode(new(big.Int).SetBytes(b)), nil
+}
+
+// Valid reports whether the short link consists of the alphabet characters.
+func (g *Generator) Valid(shortURL string) bool {
+	if shortURL == "" {
+		return false
+	}
+	for _, c := range []byte(shortURL) {
+		if strings.IndexByte(g.alphabet, c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FixedLength reports whether the generator produces the short links
+// of the configured length, which may collide.
+func (g *Generator) FixedLength() bool {
+	return g.length > 0
+}
+
+// encodeUint64 writes the number in the alphabet without padding.
+func (g *Generator) encodeUint64(n uint64) string {
+	base := uint64(len(g.alphabet))
+	var buf [64]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = g.alphabet[n%base]
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	return string(buf[i:])
+}
+
+// encode writes the lowest digits of the number in the alphabet
+// padded to the configured length.
+func (g *Generator) encode(n *big.Int) string {
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+	buf := make([]byte, g.length)
+	for i := len(buf) - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		buf[i] = g.alphabet[digit.Int64()]
+	}
+	return string(buf)
 }
@@ -7,7 +7,9 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/itchyny/base58-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -74,3 +76,70 @@ func FuzzGenerate(f *testing.F) {
 			"generated string expected to be base58 encoded")
 	})
 }
+
+func TestGenerate_Compatible(t *testing.T) {
+	// the links don't change for the existing records
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		n := r.Uint64()
+		assert.Equal(t, string(base58.BitcoinEncoding.EncodeUint64(n)), defaultGenerator.encodeUint64(n))
+	}
+	assert.Equal(t, string(base58.BitcoinEncoding.EncodeUint64(0)), defaultGenerator.encodeUint64(0))
+
+	g, err := New()
+	require.NoError(t, err)
+	assert.Equal(t, Generate("https://go.dev"), g.Generate("https://go.dev"))
+	assert.Equal(t, GenerateSalted("https://go.dev", 1), g.GenerateSalted("https://go.dev", 1))
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "short base58 links", opts: []Option{WithLength(6)}},
+		{name: "custom alphabet", opts: []Option{WithAlphabet("abc-_"), WithLength(20)}},
+		{name: "fnv hash", opts: []Option{WithHash(HashFNV), WithLength(10)}},
+		{name: "empty values are defaults", opts: []Option{WithAlphabet(""), WithHash("")}},
+		{name: "negative length", opts: []Option{WithLength(-1)}, wantErr: true},
+		{name: "unknown hash", opts: []Option{WithHash("crc32")}, wantErr: true},
+		{name: "hash too short", opts: []Option{WithHash(HashFNV), WithLength(11)}, wantErr: true},
+		{name: "single character", opts: []Option{WithAlphabet("a")}, wantErr: true},
+		{name: "repeated character", opts: []Option{WithAlphabet("abca")}, wantErr: true},
+		{name: "reserved character", opts: []Option{WithAlphabet("ab/")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	g, err := New(WithLength(6), WithAlphabet("0123456789abcdef"), WithHash(HashSHA512))
+	require.NoError(t, err)
+
+	code := g.Generate("https://go.dev")
+	assert.Len(t, code, 6)
+	assert.True(t, g.Valid(code))
+	assert.Equal(t, code, g.Generate("https://go.dev"), "links expected to be stable")
+	assert.NotEqual(t, code, g.GenerateSalted("https://go.dev", 1))
+	assert.Regexp(t, `^[0-9a-f]{6}$`, code)
+
+	random, err := g.Random()
+	require.NoError(t, err)
+	assert.Len(t, random, 6)
+	assert.True(t, g.Valid(random))
+
+	assert.False(t, g.Valid(""))
+	assert.False(t, g.Valid("XYZ"))
+	assert.True(t, g.FixedLength())
+	assert.False(t, defaultGenerator.FixedLength())
+}
@@ -0,0 +1,269 @@
+// Package slo tracks the service level indicators of the shortener:
+// the availability of the requests and the latency of the redirects.
+//
+// The indicators are kept in the per-minute buckets and reported over
+// the rolling windows together with the burn rates of the error budgets,
+// i.e. how many times faster than allowed by the objective the budget is
+// spent, and with the monotonic counters, so that the alerts are wired
+// without the histograms of the raw latencies.
+package slo
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults of the objectives used if they are not set.
+const (
+	DefaultAvailability          = 0.999
+	DefaultRedirectLatency       = 100 * time.Millisecond
+	DefaultRedirectLatencyTarget = 0.99
+)
+
+// Windows are the rolling windows the indicators are reported over.
+var Windows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+
+// buckets is the number of the minutes kept, enough for the longest window.
+const buckets = 6 * 60
+
+// latencyBounds are the upper bounds of the latency histogram buckets.
+// The latencies above the last one fall into the overflow bucket.
+var latencyBounds = [...]time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second,
+}
+
+// Objectives are the service level objectives.
+type Objectives struct {
+	// Availability is the share of the requests served
+	// without the server errors.
+	Availability float64
+	// RedirectLatency is the latency the redirects are served within.
+	RedirectLatency time.Duration
+	// RedirectLatencyTarget is the share of the redirects
+	// served within RedirectLatency.
+	RedirectLatencyTarget float64
+}
+
+// Tracker records the requests and reports the indicators.
+// It is safe for concurrent use.
+type Tracker struct {
+	objectives Objectives
+	now        func() time.Time
+
+	mu       sync.Mutex
+	buckets  [buckets]bucket
+	counters Counters
+}
+
+// bucket holds the requests of a single minute.
+type bucket struct {
+	// minute is the Unix minute of the bucket, the bucket
+	// is stale if it differs from the minute of its slot.
+	minute        int64
+	requests      int64
+	errors        int64
+	redirects     int64
+	slowRedirects int64
+	latencies     histogram
+}
+
+// histogram counts the latencies in the buckets of latencyBounds
+// and in the overflow bucket.
+type histogram [len(latencyBounds) + 1]int64
+
+// Counters are the totals since the start. They only grow,
+// so that the rates are computed over any period.
+type Counters struct {
+	Requests      int64 `json:"requests_total"`
+	Errors        int64 `json:"errors_total"`
+	Redirects     int64 `json:"redirects_total"`
+	SlowRedirects int64 `json:"slow_redirects_total"`
+}
+
+// Report is the state of the indicators.
+type Report struct {
+	Objectives ObjectivesReport `json:"objectives"`
+	Counters   Counters         `json:"counters"`
+	Windows    []WindowReport   `json:"windows"`
+}
+
+// ObjectivesReport are the objectives the burn rates are computed for.
+type ObjectivesReport struct {
+	Availability          float64 `json:"availability"`
+	RedirectLatencyMS     float64 `json:"redirect_latency_ms"`
+	RedirectLatencyTarget float64 `json:"redirect_latency_target"`
+}
+
+// WindowReport are the indicators over the rolling window.
+type WindowReport struct {
+	// Window is the length of the window, e.g. 5m0s.
+	Window   string `json:"window"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	// Availability is the share of the requests without the server
+	// errors, 1 if there were no requests.
+	Availability float64 `json:"availability"`
+	// AvailabilityBurnRate is the share of the errors divided by the
+	// share allowed by the objective. The budget lasts for the period
+	// of the objective if it is 1.
+	AvailabilityBurnRate float64 `json:"availability_burn_rate"`
+	Redirects            int64   `json:"redirects"`
+	SlowRedirects        int64   `json:"slow_redirects"`
+	// RedirectP99MS is the upper bound of the histogram bucket
+	// of the 99th percentile of the redirect latency.
+	RedirectP99MS float64 `json:"redirect_p99_ms"`
+	// LatencyBurnRate is the share of the slow redirects divided
+	// by the share allowed by the objective.
+	LatencyBurnRate float64 `json:"latency_burn_rate"`
+}
+
+// New returns the tracker of the objectives, the defaults used
+// for the ones out of range.
+func New(objectives Objectives) *Tracker {
+	if objectives.Availability <= 0 || objectives.Availability >= 1 {
+		objectives.Availability = DefaultAvailability
+	}
+	if objectives.RedirectLatency <= 0 {
+		objectives.RedirectLatency = DefaultRedirectLatency
+	}
+	if objectives.RedirectLatencyTarget <= 0 || objectives.RedirectLatencyTarget >= 1 {
+		objectives.RedirectLatencyTarget = DefaultRedirectLatencyTarget
+	}
+	return &Tracker{objectives: objectives, now: time.Now}
+}
+
+// Observe records the request served with the status. The latency
+// of the redirects is recorded too.
+func (t *Tracker) Observe(status int, redirect bool, latency time.Duration) {
+	failed := status >= 500
+	slow := latency > t.objectives.RedirectLatency
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucketLocked(t.now())
+	b.requests++
+	t.counters.Requests++
+	if failed {
+		b.errors++
+		t.counters.Errors++
+	}
+	if !redirect {
+		return
+	}
+	b.redirects++
+	t.counters.Redirects++
+	if slow {
+		b.slowRedirects++
+		t.counters.SlowRedirects++
+	}
+	b.latencies[latencyBucket(latency)]++
+}
+
+// Report returns the indicators over the windows.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := &Report{
+		Objectives: ObjectivesReport{
+			Availability:          t.objectives.Availability,
+			RedirectLatencyMS:     float64(t.objectives.RedirectLatency) / float64(time.Millisecond),
+			RedirectLatencyTarget: t.objectives.RedirectLatencyTarget,
+		},
+		Counters: t.counters,
+		Windows:  make([]WindowReport, len(Windows)),
+	}
+
+	now := unixMinute(t.now())
+	for i, window := range Windows {
+		var sum bucket
+		for m := now - int64(window/time.Minute) + 1; m <= now; m++ {
+			b := &t.buckets[slot(m)]
+			if b.minute != m {
+				continue
+			}
+			sum.requests += b.requests
+			sum.errors += b.errors
+			sum.redirects += b.redirects
+			sum.slowRedirects += b.slowRedirects
+			for j, n := range b.latencies {
+				sum.latencies[j] += n
+			}
+		}
+		r.Windows[i] = t.windowReport(window, &sum)
+	}
+
+	return r
+}
+
+// windowReport returns the indicators of the requests of the window.
+func (t *Tracker) windowReport(window time.Duration, sum *bucket) WindowReport {
+	w := WindowReport{
+		Window:        window.String(),
+		Requests:      sum.requests,
+		Errors:        sum.errors,
+		Availability:  1,
+		Redirects:     sum.redirects,
+		SlowRedirects: sum.slowRedirects,
+	}
+	if sum.requests > 0 {
+		errorRate := float64(sum.errors) / float64(sum.requests)
+		w.Availability = 1 - errorRate
+		w.AvailabilityBurnRate = errorRate / (1 - t.objectives.Availability)
+	}
+	if sum.redirects > 0 {
+		slowRate := float64(sum.slowRedirects) / float64(sum.redirects)
+		w.LatencyBurnRate = slowRate / (1 - t.objectives.RedirectLatencyTarget)
+		w.RedirectP99MS = percentile(&sum.latencies, sum.redirects, 0.99)
+	}
+	return w
+}
+
+// bucketLocked returns the bucket of the minute of now,
+// reset if it holds the stale minute.
+func (t *Tracker) bucketLocked(now time.Time) *bucket {
+	m := unixMinute(now)
+	b := &t.buckets[slot(m)]
+	if b.minute != m {
+		*b = bucket{minute: m}
+	}
+	return b
+}
+
+// percentile returns the upper bound in milliseconds of the bucket
+// holding the percentile p of the n latencies. The overflow bucket
+// is reported as the last bound, since +Inf is not valid JSON.
+func percentile(latencies *histogram, n int64, p float64) float64 {
+	rank := int64(math.Ceil(p * float64(n)))
+	var seen int64
+	for i, count := range latencies {
+		seen += count
+		if seen >= rank && i < len(latencyBounds) {
+			return float64(latencyBounds[i]) / float64(time.Millisecond)
+		}
+	}
+	return float64(latencyBounds[len(latencyBounds)-1]) / float64(time.Millisecond)
+}
+
+// latencyBucket returns the index of the histogram bucket of the latency.
+func latencyBucket(latency time.Duration) int {
+	for i, bound := range latencyBounds {
+		if latency <= bound {
+			return i
+		}
+	}
+	return len(latencyBounds)
+}
+
+func unixMinute(t time.Time) int64 {
+	return t.Unix() / 60
+}
+
+func slot(minute int64) int {
+	return int(minute % buckets)
+}
@@ -0,0 +1,70 @@
+package slo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tr := New(Objectives{Availability: 0.99, RedirectLatency: 50 * time.Millisecond, RedirectLatencyTarget: 0.9})
+	tr.now = func() time.Time { return now }
+
+	// an hour ago: outside of the 5 minutes window
+	now = now.Add(-30 * time.Minute)
+	for i := 0; i < 10; i++ {
+		tr.Observe(http.StatusInternalServerError, false, time.Millisecond)
+	}
+	now = now.Add(30 * time.Minute)
+
+	for i := 0; i < 198; i++ {
+		tr.Observe(http.StatusTemporaryRedirect, true, 3*time.Millisecond)
+	}
+	tr.Observe(http.StatusTemporaryRedirect, true, 300*time.Millisecond)
+	tr.Observe(http.StatusServiceUnavailable, false, time.Millisecond)
+
+	r := tr.Report()
+	assert.Equal(t, Counters{Requests: 210, Errors: 11, Redirects: 199, SlowRedirects: 1}, r.Counters)
+	require.Len(t, r.Windows, len(Windows))
+
+	short := r.Windows[0]
+	assert.Equal(t, "5m0s", short.Window)
+	assert.Equal(t, int64(200), short.Requests)
+	assert.Equal(t, int64(1), short.Errors)
+	assert.InDelta(t, 0.995, short.Availability, 1e-9)
+	assert.InDelta(t, 0.5, short.AvailabilityBurnRate, 1e-9, "half of the budget rate is spent")
+	assert.InDelta(t, 5, short.RedirectP99MS, 1e-9, "the slowest redirect is above the percentile")
+	assert.InDelta(t, 1.0/199/0.1, short.LatencyBurnRate, 1e-9)
+
+	hour := r.Windows[1]
+	assert.Equal(t, int64(210), hour.Requests)
+	assert.Equal(t, int64(11), hour.Errors)
+
+	// the buckets of the previous lap are not counted
+	now = now.Add(buckets * time.Minute)
+	r = tr.Report()
+	assert.Zero(t, r.Windows[2].Requests)
+	assert.Equal(t, float64(1), r.Windows[2].Availability)
+}
+
+func TestNew_Defaults(t *testing.T) {
+	tr := New(Objectives{Availability: 1})
+	assert.Equal(t, Objectives{
+		Availability:          DefaultAvailability,
+		RedirectLatency:       DefaultRedirectLatency,
+		RedirectLatencyTarget: DefaultRedirectLatencyTarget,
+	}, tr.objectives)
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies histogram
+	latencies[latencyBucket(time.Millisecond)] = 50
+	latencies[latencyBucket(time.Minute)] = 50
+
+	assert.InDelta(t, 1, percentile(&latencies, 100, 0.5), 1e-9)
+	assert.InDelta(t, 10000, percentile(&latencies, 100, 0.99), 1e-9, "overflow is the last bound")
+}
@@ -0,0 +1,160 @@
+// Package useragent classifies the clients by their User-Agent header:
+// the device class, the operating system and the browser. The patterns
+// are embedded, see regexes.yaml, and the results are cached, so that
+// the repeated user agents are classified without running the patterns.
+package useragent
+
+import (
+	"container/list"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Classes of the devices.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Unknown is reported for the device, the OS or the browser
+// none of the patterns matched.
+const Unknown = "unknown"
+
+// DefaultCacheSize is the number of the user agents cached by Parse.
+const DefaultCacheSize = 4096
+
+// Agent is the classified user agent.
+type Agent struct {
+	// Device is one of the device classes or Unknown.
+	Device string `json:"device"`
+	// OS is the name of the operating system, e.g. Android.
+	OS string `json:"os"`
+	// Browser is the name of the browser, e.g. Firefox.
+	Browser string `json:"browser"`
+}
+
+//go:embed regexes.yaml
+var regexes []byte
+
+// rule names the user agents matching the pattern.
+type rule struct {
+	re   *regexp.Regexp
+	name string
+}
+
+// Parser classifies the user agents. It keeps the results of the
+// most recently parsed ones. It is safe for concurrent use.
+type Parser struct {
+	devices, os, browsers []rule
+
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	cache map[string]*list.Element
+}
+
+// cached is the element of the cache.
+type cached struct {
+	ua    string
+	agent Agent
+}
+
+// New returns the parser of the embedded patterns caching up to
+// size user agents. The results are not cached if size is not positive.
+func New(size int) (*Parser, error) {
+	var file struct {
+		Devices  []struct{ Regex, Name string } `yaml:"devices"`
+		OS       []struct{ Regex, Name string } `yaml:"os"`
+		Browsers []struct{ Regex, Name string } `yaml:"browsers"`
+	}
+	if err := yaml.Unmarshal(regexes, &file); err != nil {
+		return nil, fmt.Errorf("decode patterns: %w", err)
+	}
+
+	p := &Parser{size: size, order: list.New(), cache: make(map[string]*list.Element)}
+	for _, section := range []struct {
+		rules *[]rule
+		defs  []struct{ Regex, Name string }
+	}{
+		{&p.devices, file.Devices},
+		{&p.os, file.OS},
+		{&p.browsers, file.Browsers},
+	} {
+		for _, d := range section.defs {
+			re, err := regexp.Compile(d.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("compile pattern of %s: %w", d.Name, err)
+			}
+			*section.rules = append(*section.rules, rule{re: re, name: d.Name})
+		}
+	}
+
+	return p, nil
+}
+
+// Parse classifies the user agent.
+func (p *Parser) Parse(ua string) Agent {
+	p.mu.Lock()
+	if e, ok := p.cache[ua]; ok {
+		p.order.MoveToFront(e)
+		agent := e.Value.(*cached).agent
+		p.mu.Unlock()
+		return agent
+	}
+	p.mu.Unlock()
+
+	agent := Agent{
+		Device:  match(p.devices, ua),
+		OS:      match(p.os, ua),
+		Browser: match(p.browsers, ua),
+	}
+	if p.size <= 0 {
+		return agent
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.cache[ua]; !ok {
+		p.cache[ua] = p.order.PushFront(&cached{ua: ua, agent: agent})
+		if p.order.Len() > p.size {
+			oldest := p.order.Remove(p.order.Back()).(*cached)
+			delete(p.cache, oldest.ua)
+		}
+	}
+	return agent
+}
+
+// match returns the name of the first rule matching the user agent.
+func match(rules []rule, ua string) string {
+	if ua == "" {
+		return Unknown
+	}
+	for _, r := range rules {
+		if r.re.MatchString(ua) {
+			return r.name
+		}
+	}
+	return Unknown
+}
+
+// defaultParser is the parser of Parse.
+var defaultParser = sync.OnceValue(func() *Parser {
+	p, err := New(DefaultCacheSize)
+	if err != nil {
+		// the patterns are embedded and checked by the tests
+		panic(err)
+	}
+	return p
+})
+
+// Parse classifies the user agent with the shared parser
+// caching up to DefaultCacheSize user agents.
+func Parse(ua string) Agent {
+	return defaultParser().Parse(ua)
+}
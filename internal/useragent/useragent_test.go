@@ -0,0 +1,92 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Agent
+	}{
+		{
+			name: "chrome on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
+			want: Agent{Device: DeviceDesktop, OS: "Windows", Browser: "Chrome"},
+		},
+		{
+			name: "edge on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 Edg/125.0.2535.67",
+			want: Agent{Device: DeviceDesktop, OS: "Windows", Browser: "Edge"},
+		},
+		{
+			name: "safari on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			want: Agent{Device: DeviceMobile, OS: "iOS", Browser: "Safari"},
+		},
+		{
+			name: "safari on ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			want: Agent{Device: DeviceTablet, OS: "iOS", Browser: "Safari"},
+		},
+		{
+			name: "chrome on android phone",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Mobile Safari/537.36",
+			want: Agent{Device: DeviceMobile, OS: "Android", Browser: "Chrome"},
+		},
+		{
+			name: "android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Safari/537.36",
+			want: Agent{Device: DeviceTablet, OS: "Android", Browser: "Samsung Internet"},
+		},
+		{
+			name: "firefox on linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; rv:126.0) Gecko/20100101 Firefox/126.0",
+			want: Agent{Device: DeviceDesktop, OS: "Linux", Browser: "Firefox"},
+		},
+		{
+			name: "safari on macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+			want: Agent{Device: DeviceDesktop, OS: "macOS", Browser: "Safari"},
+		},
+		{
+			name: "crawler",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: Agent{Device: DeviceBot, OS: Unknown, Browser: Unknown},
+		},
+		{
+			name: "curl",
+			ua:   "curl/8.5.0",
+			want: Agent{Device: DeviceBot, OS: Unknown, Browser: Unknown},
+		},
+		{
+			name: "empty",
+			ua:   "",
+			want: Agent{Device: Unknown, OS: Unknown, Browser: Unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.ua))
+		})
+	}
+}
+
+func TestParser_Cache(t *testing.T) {
+	p, err := New(2)
+	require.NoError(t, err)
+
+	first := p.Parse("curl/8.5.0")
+	p.Parse("Wget/1.21")
+	assert.Equal(t, first, p.Parse("curl/8.5.0"), "cached")
+	p.Parse("Go-http-client/1.1")
+
+	// the least recently used one is evicted
+	assert.Equal(t, 2, p.order.Len())
+	assert.Contains(t, p.cache, "curl/8.5.0")
+	assert.NotContains(t, p.cache, "Wget/1.21")
+}
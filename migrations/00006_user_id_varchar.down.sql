@@ -0,0 +1,8 @@
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id DROP DEFAULT;
+
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id TYPE uuid USING user_id::uuid;
+
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id SET DEFAULT gen_random_uuid ();
@@ -0,0 +1,8 @@
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id DROP DEFAULT;
+
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id TYPE varchar(255) USING user_id::text;
+
+ALTER TABLE IF EXISTS public.url
+    ALTER COLUMN user_id SET DEFAULT gen_random_uuid ()::text;
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS url
+    DROP COLUMN IF EXISTS last_accessed_at
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS public.reservation (
+    short_url varchar(255) PRIMARY KEY,
+    user_id varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS url
+    DROP COLUMN IF EXISTS creator_ip,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS origin
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS url
+    ADD COLUMN IF NOT EXISTS creator_ip varchar(45) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent varchar(512) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS origin varchar(16) NOT NULL DEFAULT ''
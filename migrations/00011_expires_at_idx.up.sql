@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS url_expires_at ON url (expires_at)
+    WHERE expires_at IS NOT NULL AND NOT is_deleted
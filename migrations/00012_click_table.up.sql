@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS public.click (
+    id bigserial PRIMARY KEY,
+    short_url varchar(255) NOT NULL,
+    clicked_at timestamptz NOT NULL,
+    referrer varchar(512) NOT NULL DEFAULT '',
+    user_agent varchar(512) NOT NULL DEFAULT '',
+    ip_hash varchar(64) NOT NULL DEFAULT ''
+)
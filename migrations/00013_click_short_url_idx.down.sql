@@ -0,0 +1 @@
+DROP INDEX IF EXISTS click_short_url_clicked_at
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS click_short_url_clicked_at ON click (short_url, clicked_at)
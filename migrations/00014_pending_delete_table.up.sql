@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.pending_delete (
+    short_url varchar(255) NOT NULL,
+    user_id varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (short_url, user_id)
+)
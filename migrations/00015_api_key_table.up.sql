@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS public.api_key (
+    id uuid PRIMARY KEY,
+    user_id varchar(255) NOT NULL,
+    name varchar(255) NOT NULL,
+    hash varchar(64) NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.idempotency_key (
+    user_id varchar(255) NOT NULL,
+    key varchar(255) NOT NULL,
+    request_hash varchar(64) NOT NULL,
+    status_code integer NOT NULL,
+    content_type varchar(255) NOT NULL,
+    body bytea NOT NULL,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON public.idempotency_key (expires_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS api_key_user_id;
+ALTER TABLE IF EXISTS api_key
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS requests;
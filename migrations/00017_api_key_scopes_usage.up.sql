@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS api_key
+    ADD COLUMN IF NOT EXISTS scopes varchar(255),
+    ADD COLUMN IF NOT EXISTS last_used_at timestamptz,
+    ADD COLUMN IF NOT EXISTS requests bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS api_key_user_id ON api_key (user_id, created_at);
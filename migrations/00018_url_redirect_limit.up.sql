@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS url
+    ADD COLUMN IF NOT EXISTS redirect_limit integer NOT NULL DEFAULT 0;
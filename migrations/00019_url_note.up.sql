@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS url
+    ADD COLUMN IF NOT EXISTS note text NOT NULL DEFAULT '';
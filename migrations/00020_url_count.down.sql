@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS url_count ON public.url;
+
+DROP FUNCTION IF EXISTS public.url_count_trigger();
+
+DROP FUNCTION IF EXISTS public.url_count_add(varchar, integer);
+
+DROP TABLE IF EXISTS public.url_count;
+
+DROP TABLE IF EXISTS public.url_user_count;
@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS public.url_user_count (
+    user_id varchar(255) PRIMARY KEY,
+    urls bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS public.url_count (
+    id boolean PRIMARY KEY DEFAULT TRUE CHECK (id),
+    short_urls bigint NOT NULL DEFAULT 0,
+    users bigint NOT NULL DEFAULT 0
+);
+
+INSERT INTO public.url_user_count (user_id, urls)
+SELECT user_id, COUNT(*) FROM public.url
+WHERE NOT is_deleted AND user_id IS NOT NULL GROUP BY user_id;
+
+INSERT INTO public.url_count (short_urls, users)
+SELECT COUNT(*), COUNT(DISTINCT user_id) FROM public.url WHERE NOT is_deleted;
+
+-- url_count_add adds delta to the URLs of the user and to the totals,
+-- counting the user once the first URL is added and until the last is removed.
+-- The URLs without the user are counted in the totals only.
+CREATE OR REPLACE FUNCTION public.url_count_add(uid varchar, delta integer) RETURNS void AS $$
+DECLARE
+    n bigint;
+BEGIN
+    IF uid IS NULL THEN
+        UPDATE public.url_count SET short_urls = short_urls + delta;
+        RETURN;
+    END IF;
+
+    INSERT INTO public.url_user_count AS c (user_id, urls) VALUES (uid, delta)
+    ON CONFLICT (user_id) DO UPDATE SET urls = c.urls + delta
+    RETURNING c.urls INTO n;
+
+    UPDATE public.url_count SET
+        short_urls = short_urls + delta,
+        users = users + CASE
+            WHEN delta > 0 AND n = delta THEN 1
+            WHEN delta < 0 AND n = 0 THEN -1
+            ELSE 0
+        END;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION public.url_count_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND NOT OLD.is_deleted THEN
+        PERFORM public.url_count_add(OLD.user_id, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NOT NEW.is_deleted THEN
+        PERFORM public.url_count_add(NEW.user_id, 1);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER url_count
+    AFTER INSERT OR DELETE OR UPDATE OF is_deleted, user_id ON public.url
+    FOR EACH ROW EXECUTE FUNCTION public.url_count_trigger();
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS url_daily ON public.url;
+
+DROP FUNCTION IF EXISTS public.url_daily_trigger();
+
+DROP TABLE IF EXISTS public.url_daily_user;
+
+DROP TABLE IF EXISTS public.url_daily;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS public.url_daily (
+    day date PRIMARY KEY,
+    created bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS public.url_daily_user (
+    day date NOT NULL,
+    user_id varchar(255) NOT NULL,
+    PRIMARY KEY (day, user_id)
+);
+
+-- url_daily_trigger adds the created URL to the rollups of the day in UTC.
+CREATE OR REPLACE FUNCTION public.url_daily_trigger() RETURNS trigger AS $$
+DECLARE
+    today date := (now() AT TIME ZONE 'UTC')::date;
+BEGIN
+    INSERT INTO public.url_daily AS d (day, created) VALUES (today, 1)
+    ON CONFLICT (day) DO UPDATE SET created = d.created + 1;
+
+    IF NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.url_daily_user (day, user_id) VALUES (today, NEW.user_id)
+        ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER url_daily
+    AFTER INSERT ON public.url
+    FOR EACH ROW EXECUTE FUNCTION public.url_daily_trigger();
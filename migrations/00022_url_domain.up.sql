@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS url
+    ADD COLUMN IF NOT EXISTS domain varchar(255) NOT NULL DEFAULT '';
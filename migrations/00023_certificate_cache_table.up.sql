@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS public.certificate_cache (
+    key varchar(255) PRIMARY KEY,
+    data bytea NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
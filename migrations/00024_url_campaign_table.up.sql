@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.url_campaign (
+    user_id varchar(255) NOT NULL,
+    campaign varchar(64) NOT NULL,
+    short_url varchar(255) NOT NULL,
+    PRIMARY KEY (user_id, campaign, short_url)
+);
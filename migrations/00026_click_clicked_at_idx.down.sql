@@ -0,0 +1 @@
+DROP INDEX IF EXISTS click_clicked_at
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.users (
+    user_id varchar(255) PRIMARY KEY,
+    login varchar(255) NOT NULL UNIQUE,
+    password_hash varchar(60) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS public.revoked_token (
+    token_id varchar(64) PRIMARY KEY,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS revoked_token_expires_at ON public.revoked_token (expires_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.url_blocked;
+DROP TABLE IF EXISTS public.banned_domain;
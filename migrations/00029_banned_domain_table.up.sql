@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.banned_domain (
+    domain varchar(255) PRIMARY KEY,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS url_blocked ON public.url (state) WHERE state = 'blocked';
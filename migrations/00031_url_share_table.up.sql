@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS public.url_share (
+    short_url varchar(255) NOT NULL,
+    user_id varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL,
+    PRIMARY KEY (short_url, user_id)
+);
+CREATE INDEX IF NOT EXISTS url_share_user_id ON public.url_share (user_id);
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS url
+    ADD COLUMN IF NOT EXISTS created_at timestamptz;
+ALTER TABLE IF EXISTS url
+    ALTER COLUMN created_at SET DEFAULT now();
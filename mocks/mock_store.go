@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/KretovDmitry/shortener/internal/db (interfaces: URLStorage)
+// Source: github.com/KretovDmitry/shortener/internal/repository (interfaces: URLStorage)
 //
 // Generated by this command:
 //
-//	mockgen -destination=mocks/mock_store.go -package=mocks github.com/KretovDmitry/shortener/internal/db URLStorage
+//	mockgen -destination=mocks/mock_store.go -package=mocks github.com/KretovDmitry/shortener/internal/repository URLStorage
 //
 
 // Package mocks is a generated GoMock package.
@@ -12,8 +12,10 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/KretovDmitry/shortener/internal/models"
+	user "github.com/KretovDmitry/shortener/internal/models/user"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,6 +42,21 @@ func (m *MockURLStorage) EXPECT() *MockURLStorageMockRecorder {
 	return m.recorder
 }
 
+// DeleteExpired mocks base method.
+func (m *MockURLStorage) DeleteExpired(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockURLStorageMockRecorder) DeleteExpired(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockURLStorage)(nil).DeleteExpired), arg0, arg1)
+}
+
 // DeleteURLs mocks base method.
 func (m *MockURLStorage) DeleteURLs(arg0 context.Context, arg1 ...*models.URL) error {
 	m.ctrl.T.Helper()
@@ -75,7 +92,7 @@ func (mr *MockURLStorageMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 }
 
 // GetAllByUserID mocks base method.
-func (m *MockURLStorage) GetAllByUserID(arg0 context.Context, arg1 string) ([]*models.URL, error) {
+func (m *MockURLStorage) GetAllByUserID(arg0 context.Context, arg1 user.ID) ([]*models.URL, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAllByUserID", arg0, arg1)
 	ret0, _ := ret[0].([]*models.URL)
@@ -89,6 +106,21 @@ func (mr *MockURLStorageMockRecorder) GetAllByUserID(arg0, arg1 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUserID", reflect.TypeOf((*MockURLStorage)(nil).GetAllByUserID), arg0, arg1)
 }
 
+// GetMany mocks base method.
+func (m *MockURLStorage) GetMany(arg0 context.Context, arg1 []models.ShortURL) ([]*models.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", arg0, arg1)
+	ret0, _ := ret[0].([]*models.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockURLStorageMockRecorder) GetMany(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockURLStorage)(nil).GetMany), arg0, arg1)
+}
+
 // Ping mocks base method.
 func (m *MockURLStorage) Ping(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -130,3 +162,17 @@ func (mr *MockURLStorageMockRecorder) SaveAll(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockURLStorage)(nil).SaveAll), arg0, arg1)
 }
+
+// UpdateLastAccessed mocks base method.
+func (m *MockURLStorage) UpdateLastAccessed(arg0 context.Context, arg1 map[models.ShortURL]time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastAccessed", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastAccessed indicates an expected call of UpdateLastAccessed.
+func (mr *MockURLStorageMockRecorder) UpdateLastAccessed(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastAccessed", reflect.TypeOf((*MockURLStorage)(nil).UpdateLastAccessed), arg0, arg1)
+}
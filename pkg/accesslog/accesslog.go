@@ -4,6 +4,7 @@ package accesslog
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -11,16 +12,16 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// sugaredLogFormat is the format the Chi logs will use when
-// a sugared Zap logger is passed. Uses fmt.Printf templating.
-var sugaredLogFormat = "%s %s %s from %s - %s %dB in %s"
-
 // Handler returns a middleware that records an access log message
 // for every HTTP request being processed.
-func Handler(log logger.Logger) func(next http.Handler) http.Handler {
+func Handler(log logger.Logger, opts ...Option) func(next http.Handler) http.Handler {
+	core := New(log, opts...)
+
 	return func(next http.Handler) http.Handler {
 		f := func(w http.ResponseWriter, r *http.Request) {
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			body := &countingReader{ReadCloser: r.Body}
+			r.Body = body
 
 			// associate request ID and session ID with the request context
 			// so that they can be added to the log messages
@@ -29,15 +30,17 @@ func Handler(log logger.Logger) func(next http.Handler) http.Handler {
 
 			// defer function that logs the request details
 			defer func(start time.Time) {
-				log.With(ctx).Infof(sugaredLogFormat,
-					r.Method,                 // Method
-					r.URL.Path,               // Path
-					r.Proto,                  // Protocol
-					r.RemoteAddr,             // RemoteAddr
-					statusLabel(ww.Status()), // "200 OK"
-					ww.BytesWritten(),        // Bytes Written
-					time.Since(start),        // Elapsed
-				)
+				core.Log(ctx, Entry{
+					Method:        r.Method,
+					Path:          r.URL.Path,
+					Proto:         r.Proto,
+					RemoteAddr:    r.RemoteAddr,
+					Status:        statusLabel(ww.Status()),
+					Failed:        ww.Status() >= http.StatusInternalServerError,
+					RequestBytes:  body.n,
+					ResponseBytes: int64(ww.BytesWritten()),
+					Elapsed:       time.Since(start),
+				})
 			}(time.Now())
 
 			next.ServeHTTP(ww, r)
@@ -46,6 +49,19 @@ func Handler(log logger.Logger) func(next http.Handler) http.Handler {
 	}
 }
 
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read reads from the underlying body counting the bytes.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
 func statusLabel(status int) string {
 	switch {
 	case status >= 100 && status < 300:
@@ -0,0 +1,87 @@
+package accesslog
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/logger"
+)
+
+// logFormat is the format of the access log messages
+// shared by all the transports. Uses fmt.Printf templating.
+var logFormat = "%s %s %s from %s - %s %dB in %s (request %dB)"
+
+// Entry is a single access log record. It is transport agnostic,
+// so that every API is logged in the same format.
+type Entry struct {
+	// Method is the HTTP method or the RPC call type.
+	Method string
+	// Path is the HTTP path or the full RPC method name.
+	Path string
+	// Proto is the protocol of the call.
+	Proto string
+	// RemoteAddr is the address of the client.
+	RemoteAddr string
+	// Status is the transport specific status label, e.g. "200 OK".
+	Status string
+	// Failed reports whether the call failed.
+	// Failed calls are never sampled out.
+	Failed bool
+	// RequestBytes and ResponseBytes are the sizes of the payloads.
+	RequestBytes, ResponseBytes int64
+	// Elapsed is the duration of the call.
+	Elapsed time.Duration
+}
+
+// Core writes access log entries of any transport.
+// It is safe for concurrent use.
+type Core struct {
+	log logger.Logger
+	// every is the sampling interval of the successful calls,
+	// one of every calls is logged.
+	every uint64
+	// calls is the number of successful calls seen.
+	calls atomic.Uint64
+}
+
+// Option configures the access log core.
+type Option func(*Core)
+
+// WithSampling logs only the given fraction of the successful calls.
+// Rates out of the (0, 1) range log every call.
+func WithSampling(rate float64) Option {
+	return func(c *Core) {
+		if rate > 0 && rate < 1 {
+			c.every = uint64(math.Round(1 / rate))
+		}
+	}
+}
+
+// New returns the access log core writing to log.
+func New(log logger.Logger, opts ...Option) *Core {
+	c := &Core{log: log, every: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Log writes the entry unless it is sampled out.
+func (c *Core) Log(ctx context.Context, e Entry) {
+	if !e.Failed && c.every > 1 && c.calls.Add(1)%c.every != 0 {
+		return
+	}
+
+	c.log.With(ctx).Infof(logFormat,
+		e.Method,
+		e.Path,
+		e.Proto,
+		e.RemoteAddr,
+		e.Status,
+		e.ResponseBytes,
+		e.Elapsed,
+		e.RequestBytes,
+	)
+}
@@ -0,0 +1,35 @@
+package accesslog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCore_Sampling(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   float64
+		failed bool
+		want   int
+	}{
+		{"no sampling", 0, false, 100},
+		{"every tenth", 0.1, false, 10},
+		{"out of range", 2, false, 100},
+		{"failed calls are never sampled out", 0.1, true, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, logs := logger.NewForTest()
+			core := New(l, WithSampling(tt.rate))
+
+			for i := 0; i < 100; i++ {
+				core.Log(context.Background(), Entry{Method: "GET", Path: "/", Failed: tt.failed})
+			}
+
+			assert.Equal(t, tt.want, logs.Len())
+		})
+	}
+}
@@ -0,0 +1,3716 @@
+// Code generated by apigen from openapi.yaml. DO NOT EDIT.
+
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPDoer sends the HTTP requests, *http.Client implements it.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RequestEditorFn modifies the request before it is sent,
+// e.g. to set the headers or cookies.
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// Client is the API client.
+type Client struct {
+	// Server is the base URL of the API, e.g. "http://localhost:8080".
+	Server string
+	// HTTPClient sends the requests.
+	HTTPClient HTTPDoer
+	// RequestEditors are applied to every request.
+	RequestEditors []RequestEditorFn
+}
+
+// ClientOption configures the client.
+type ClientOption func(*Client) error
+
+// WithHTTPClient sets the client sending the requests. It should not
+// follow redirects, so that the redirect responses are returned.
+func WithHTTPClient(doer HTTPDoer) ClientOption {
+	return func(c *Client) error {
+		c.HTTPClient = doer
+		return nil
+	}
+}
+
+// WithRequestEditorFn adds the editor applied to every request.
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+// NewClient returns the client of the API at the server base URL.
+// By default the requests are sent by the HTTP client that
+// does not follow redirects.
+func NewClient(server string, opts ...ClientOption) (*Client, error) {
+	c := &Client{Server: strings.TrimRight(server, "/")}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return c, nil
+}
+
+// newRequest returns the request to the path of the server.
+func (c *Client) newRequest(
+	ctx context.Context, method, path, contentType string, body io.Reader,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.Server+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// do applies the editors, sends the request and reads the response body.
+func (c *Client) do(
+	ctx context.Context, req *http.Request, reqEditors []RequestEditorFn,
+) (*http.Response, []byte, error) {
+	editors := append(append([]RequestEditorFn(nil), c.RequestEditors...), reqEditors...)
+	for _, edit := range editors {
+		if err := edit(ctx, req); err != nil {
+			return nil, nil, fmt.Errorf("edit request: %w", err)
+		}
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+	return res, body, nil
+}
+
+// jsonBody encodes the request body.
+func jsonBody(v any) (io.Reader, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	return &buf, nil
+}
+
+// isJSON reports whether the response body is JSON.
+func isJSON(res *http.Response) bool {
+	return strings.HasPrefix(res.Header.Get("Content-Type"), "application/json")
+}
+
+// CertificateHealth is the CertificateHealth schema of the API.
+type CertificateHealth struct {
+	// One of: file, acme.
+	Source   string    `json:"source"`
+	Names    []string  `json:"names"`
+	NotAfter time.Time `json:"not_after"`
+	// ExpiresInSeconds is the time left until the expiry, negative once expired.
+	ExpiresInSeconds int64 `json:"expires_in_seconds"`
+	// RenewAt is the time the certificate issued by Let's Encrypt is renewed at.
+	RenewAt *time.Time `json:"renew_at,omitempty"`
+	// One of: ok, expiring, renewal_overdue, expired.
+	Status string `json:"status"`
+}
+
+// DNSMetrics is the DNSMetrics schema of the API.
+type DNSMetrics struct {
+	// Entries is the number of the cached hostnames.
+	Entries int `json:"entries"`
+	// Hits is the lookups answered with the cached addresses.
+	Hits int64 `json:"hits"`
+	// NegativeHits is the lookups answered with the cached failure.
+	NegativeHits int64 `json:"negative_hits"`
+	// Misses is the lookups sent to the resolver.
+	Misses int64 `json:"misses"`
+	// Errors is the failed lookups sent to the resolver.
+	Errors int64 `json:"errors"`
+}
+
+// SLOReport is the SLOReport schema of the API.
+type SLOReport struct {
+	Objectives SLOObjectives `json:"objectives"`
+	Counters   SLOCounters   `json:"counters"`
+	Windows    []SLOWindow   `json:"windows"`
+}
+
+// SLOObjectives is the SLOObjectives schema of the API.
+type SLOObjectives struct {
+	// Availability is the share of the requests served without the server errors.
+	Availability float64 `json:"availability"`
+	// RedirectLatencyMs is the latency the redirects are served within.
+	RedirectLatencyMs float64 `json:"redirect_latency_ms"`
+	// RedirectLatencyTarget is the share of the redirects served within the latency.
+	RedirectLatencyTarget float64 `json:"redirect_latency_target"`
+}
+
+// SLOCounters is the SLOCounters schema of the API.
+type SLOCounters struct {
+	RequestsTotal int64 `json:"requests_total"`
+	// ErrorsTotal is the requests answered with the server errors.
+	ErrorsTotal    int64 `json:"errors_total"`
+	RedirectsTotal int64 `json:"redirects_total"`
+	// SlowRedirectsTotal is the redirects slower than the objective.
+	SlowRedirectsTotal int64 `json:"slow_redirects_total"`
+}
+
+// SLOWindow is the SLOWindow schema of the API.
+type SLOWindow struct {
+	// Window is the length of the window, e.g. 5m0s.
+	Window   string `json:"window"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	// Availability is the share of the requests without the server errors, 1 if there were none.
+	Availability float64 `json:"availability"`
+	// AvailabilityBurnRate is the share of the errors divided by the share allowed by the
+	// objective. The error budget lasts for the period if it is 1.
+	AvailabilityBurnRate float64 `json:"availability_burn_rate"`
+	Redirects            int64   `json:"redirects"`
+	SlowRedirects        int64   `json:"slow_redirects"`
+	// RedirectP99Ms is the upper bound of the histogram bucket of the 99th percentile
+	// of the redirect latency.
+	RedirectP99Ms float64 `json:"redirect_p99_ms"`
+	// LatencyBurnRate is the share of the slow redirects divided by the share allowed by the objective.
+	LatencyBurnRate float64 `json:"latency_burn_rate"`
+}
+
+// PoolMetrics is the PoolMetrics schema of the API.
+type PoolMetrics struct {
+	// MaxOpenConnections is the configured limit, zero if unlimited.
+	MaxOpenConnections int `json:"max_open_connections"`
+	OpenConnections    int `json:"open_connections"`
+	InUse              int `json:"in_use"`
+	Idle               int `json:"idle"`
+	// WaitCount is the number of the connections waited for.
+	WaitCount int64 `json:"wait_count"`
+	// WaitDurationMs is the total time waited for the connections.
+	WaitDurationMs int64 `json:"wait_duration_ms"`
+	// MaxIdleClosed is the connections closed by the idle limit.
+	MaxIdleClosed int64 `json:"max_idle_closed"`
+	// MaxIdleTimeClosed is the connections closed by the idle time.
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	// MaxLifetimeClosed is the connections closed by the lifetime limit.
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// DeletionMetrics is the DeletionMetrics schema of the API.
+type DeletionMetrics struct {
+	// Pending is the number of the deletions waiting for the flush in the buffers of the workers.
+	Pending int `json:"pending"`
+	// Queued is the number of the deletions waiting for the workers.
+	Queued int `json:"queued"`
+	// TargetBatchSize is the batch size flushed without waiting for the delay.
+	TargetBatchSize int `json:"target_batch_size"`
+	// ArrivalRate is the moving average of the deletions per second.
+	ArrivalRate float64 `json:"arrival_rate"`
+	// FlushLatencyMs is the moving average of the flush duration.
+	FlushLatencyMs float64 `json:"flush_latency_ms"`
+	Flushes        int64   `json:"flushes"`
+	FailedFlushes  int64   `json:"failed_flushes"`
+	Deleted        int64   `json:"deleted"`
+	LastBatchSize  int     `json:"last_batch_size"`
+	MinBatchSize   int     `json:"min_batch_size"`
+	MaxBatchSize   int     `json:"max_batch_size"`
+	MaxDelayMs     int64   `json:"max_delay_ms"`
+	// Workers is the number of the workers flushing the batches.
+	Workers int `json:"workers"`
+}
+
+// DeleteURLResult is the DeleteURLResult schema of the API.
+type DeleteURLResult struct {
+	ShortURL string `json:"short_url"`
+	// One of: deleted, not_found, forbidden.
+	Status string `json:"status"`
+}
+
+// DeleteByOriginalResult is the DeleteByOriginalResult schema of the API.
+type DeleteByOriginalResult struct {
+	OriginalURL string `json:"original_url"`
+	ShortURL    string `json:"short_url"`
+}
+
+// ServiceTokenRequest is the ServiceTokenRequest schema of the API.
+type ServiceTokenRequest struct {
+	UserID string `json:"user_id,omitempty"`
+	Role   *Role  `json:"role,omitempty"`
+}
+
+// Role is the role of the token. Each role has the access of the ones before it: the service role also reads the statistics and the metrics under /api/internal, the admin one also moderates the URLs of all the users. The service tokens are of the service role by default.
+type Role string
+
+// Credentials is the Credentials schema of the API.
+type Credentials struct {
+	// Login is compared case-insensitively.
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// AccountToken is the AccountToken schema of the API.
+type AccountToken struct {
+	UserID string `json:"user_id"`
+	// Token is the value of the Authorization cookie.
+	Token string `json:"token"`
+	// One of: registered.
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RefreshToken is the value of the Refresh-Token cookie, omitted if the storage does not support the token revocation.
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+}
+
+// RefreshRequest is the RefreshRequest schema of the API.
+type RefreshRequest struct {
+	// RefreshToken is used if there is no Refresh-Token cookie.
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// ServiceToken is the ServiceToken schema of the API.
+type ServiceToken struct {
+	UserID string `json:"user_id"`
+	// Token is the value of the Authorization cookie.
+	Token string `json:"token"`
+	// One of: anonymous, registered, service.
+	TokenType string    `json:"token_type"`
+	Role      Role      `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// APIKeyRequest is the APIKeyRequest schema of the API.
+type APIKeyRequest struct {
+	// Name is the name telling the keys of the user apart.
+	Name string `json:"name,omitempty"`
+	// Scopes is the scopes the key is limited to.
+	Scopes []Scope `json:"scopes,omitempty"`
+}
+
+// APIKey is the APIKey schema of the API.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Key is the key passed in the Authorization header with the ApiKey scheme.
+	Key string `json:"key"`
+	// Scopes is the scopes the key is limited to, null if not limited.
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKeyUsage is the APIKeyUsage schema of the API.
+type APIKeyUsage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Scopes is the scopes the key is limited to, null if not limited.
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastUsedAt is the time of the last request with the key, null if not used yet.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Requests is the number of the requests with the key.
+	Requests int64 `json:"requests"`
+}
+
+// Scope is the part of the API the key gives access to. The write scope
+// includes shorten, the admin scope allows to manage the API keys.
+type Scope string
+
+// ShortenRequest is the ShortenRequest schema of the API.
+type ShortenRequest struct {
+	// URL is the URL to shorten.
+	URL string `json:"url"`
+	// Alias is the custom short code used instead of the generated one.
+	Alias string `json:"alias,omitempty"`
+	// Ttl is the number of seconds the short URL redirects for.
+	Ttl int64 `json:"ttl,omitempty"`
+	// RedirectLimit is the redirects allowed per minute, not limited if zero.
+	RedirectLimit int `json:"redirect_limit,omitempty"`
+	// Note is the free-text annotation of the link.
+	Note string `json:"note,omitempty"`
+	// Domain is the configured domain of the short URL, the return address if empty.
+	Domain string `json:"domain,omitempty"`
+}
+
+// Error is the Error schema of the API.
+type Error struct {
+	// Code is the machine-readable code: invalid_request, unauthorized,
+	// forbidden, not_found, conflict, gone, too_large, unprocessable,
+	// too_many_requests, internal, not_implemented or unavailable.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details is the cause of the failure, never set for the server errors.
+	Details string `json:"details,omitempty"`
+	// RequestID is the X-Request-ID header of the request or the generated ID.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// HostBlocked is the HostBlocked schema of the API.
+type HostBlocked struct {
+	// Error is the host matches the denylist, is missing from the allowlist
+	// or is of the domain banned by the moderators.
+	// One of: host_denied, host_not_allowed, host_banned.
+	Error   string `json:"error"`
+	Host    string `json:"host"`
+	Message string `json:"message"`
+	// CorrelationID is the batch item of the blocked URL.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// RedirectLimitRequest is the RedirectLimitRequest schema of the API.
+type RedirectLimitRequest struct {
+	// Limit is the redirects allowed per minute, not limited if zero.
+	Limit int `json:"limit"`
+}
+
+// UpdateURLRequest is the UpdateURLRequest schema of the API.
+type UpdateURLRequest struct {
+	// URL is the new destination of the short URL.
+	URL string `json:"url,omitempty"`
+	// Ttl is the new lifetime in seconds counted from now, zero removes the expiration.
+	Ttl int64 `json:"ttl,omitempty"`
+	// Version is the version of the URL the edit is based on.
+	Version int `json:"version,omitempty"`
+}
+
+// UpdatedURL is the UpdatedURL schema of the API.
+type UpdatedURL struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Version is the version of the URL after the edit.
+	Version int `json:"version"`
+}
+
+// NoteRequest is the NoteRequest schema of the API.
+type NoteRequest struct {
+	// Note is the free-text annotation of the link, removed if empty.
+	Note string `json:"note"`
+}
+
+// LinkState is the state of the link: paused by the owner, expired, deleted
+// or blocked links don't redirect.
+type LinkState string
+
+// LinkStateResponse is the LinkStateResponse schema of the API.
+type LinkStateResponse struct {
+	ShortURL string    `json:"short_url"`
+	State    LinkState `json:"state"`
+}
+
+// CampaignURLsResponse is the CampaignURLsResponse schema of the API.
+type CampaignURLsResponse struct {
+	// Added is the number of the URLs added to the campaign.
+	Added int `json:"added"`
+}
+
+// CampaignTransferRequest is the CampaignTransferRequest schema of the API.
+type CampaignTransferRequest struct {
+	// To is the target campaign.
+	To string `json:"to"`
+}
+
+// CampaignTransfer is the CampaignTransfer schema of the API.
+type CampaignTransfer struct {
+	// Copied is the number of the links added to the target campaign.
+	Copied int `json:"copied"`
+	// Skipped is the number of the links whose destinations were already
+	// in the target campaign.
+	Skipped int `json:"skipped"`
+	// Removed is the number of the links removed from the source campaign.
+	Removed int `json:"removed"`
+}
+
+// ShortenResponse is the ShortenResponse schema of the API.
+type ShortenResponse struct {
+	// Result is the short URL.
+	Result string `json:"result"`
+	// Message is the error message if the shortening failed.
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+// ShortenBatchRequestItem is the ShortenBatchRequestItem schema of the API.
+type ShortenBatchRequestItem struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+	// Ttl is the number of seconds the short URL redirects for.
+	Ttl int64 `json:"ttl,omitempty"`
+	// Domain is the configured domain of the short URL, the return address if empty.
+	Domain string `json:"domain,omitempty"`
+}
+
+// ShortenBatchResponseItem is the ShortenBatchResponseItem schema of the API.
+type ShortenBatchResponseItem struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+}
+
+// ExpandBatchResponseItem is the ExpandBatchResponseItem schema of the API.
+type ExpandBatchResponseItem struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
+
+// Metadata is the details of the client that created the URL.
+type Metadata struct {
+	CreatorIP string `json:"creator_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	// One of: api, text, import.
+	Origin string `json:"origin,omitempty"`
+}
+
+// UserURL is the UserURL schema of the API.
+type UserURL struct {
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RedirectLimit  int        `json:"redirect_limit,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	State          LinkState  `json:"state"`
+	Metadata       *Metadata  `json:"metadata,omitempty"`
+	// Version is the number of the edits of the URL, see UpdateURL.
+	Version int `json:"version,omitempty"`
+	// Shared is set for the read-only URLs shared with the user by their owners.
+	Shared bool `json:"shared,omitempty"`
+}
+
+// URLDetails is the URLDetails schema of the API.
+type URLDetails struct {
+	ID             string     `json:"id"`
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url"`
+	UserID         string     `json:"user_id"`
+	IsDeleted      bool       `json:"is_deleted"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RedirectLimit  int        `json:"redirect_limit,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	// Domain is the configured domain of the short URL, empty for the return address.
+	Domain string `json:"domain,omitempty"`
+	// State is the state set to the URL, empty if never set. See LinkState.
+	// One of: active, paused, blocked.
+	State    string   `json:"state,omitempty"`
+	Metadata Metadata `json:"metadata"`
+	Version  int      `json:"version,omitempty"`
+	// CreatedAt is missing for the URLs created before the creation times were kept.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// DomainBanRequest is the DomainBanRequest schema of the API.
+type DomainBanRequest struct {
+	// Domain is the domain name, compared case-insensitively. Wildcards are not allowed.
+	Domain string `json:"domain"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// UserURLExport is the UserURLExport schema of the API.
+type UserURLExport struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	// CreatedAt is missing for the URLs created before the creation times were kept.
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	IsDeleted      bool       `json:"is_deleted"`
+	State          LinkState  `json:"state"`
+	Note           string     `json:"note,omitempty"`
+	Clicks         int        `json:"clicks"`
+}
+
+// UserURLImportItem is the UserURLImportItem schema of the API.
+type UserURLImportItem struct {
+	OriginalURL string `json:"original_url"`
+	// Alias is the short URL used instead of the generated one.
+	Alias string `json:"alias,omitempty"`
+}
+
+// UserURLImport is the UserURLImport schema of the API.
+type UserURLImport struct {
+	Created int                `json:"created"`
+	Exists  int                `json:"exists"`
+	Invalid int                `json:"invalid"`
+	Rows    []UserURLImportRow `json:"rows"`
+}
+
+// UserURLImportRow is the UserURLImportRow schema of the API.
+type UserURLImportRow struct {
+	// Row is the number of the row from 1, not counting the CSV header.
+	Row         int    `json:"row"`
+	OriginalURL string `json:"original_url"`
+	Alias       string `json:"alias,omitempty"`
+	ShortURL    string `json:"short_url,omitempty"`
+	// One of: created, exists, invalid.
+	Status string `json:"status"`
+	// Error is why the invalid row is skipped.
+	Error string `json:"error,omitempty"`
+}
+
+// TransferRequest is the TransferRequest schema of the API.
+type TransferRequest struct {
+	// To is the ID of the new owner.
+	To string `json:"to"`
+}
+
+// URLShare is the URLShare schema of the API.
+type URLShare struct {
+	ShortURL string `json:"short_url"`
+	// UserID is the user the URL is shared with.
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DomainBan is the DomainBan schema of the API.
+type DomainBan struct {
+	Domain    string    `json:"domain"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DomainBanResult is the DomainBanResult schema of the API.
+type DomainBanResult map[string]any
+
+// Stats is the Stats schema of the API.
+type Stats struct {
+	Urls  int `json:"urls"`
+	Users int `json:"users"`
+	// Approximate is set if the numbers are estimated.
+	Approximate bool `json:"approximate,omitempty"`
+	// ActiveUsers is the number of the users who created URLs during the days.
+	ActiveUsers int `json:"active_users,omitempty"`
+	// TopLinks is the links most clicked during the days, most clicked first.
+	TopLinks []TopLink `json:"top_links,omitempty"`
+	// Daily is the numbers of the URLs created on each of the days, oldest first.
+	Daily []DailyTrend `json:"daily,omitempty"`
+}
+
+// TopLink is the TopLink schema of the API.
+type TopLink struct {
+	ShortURL string `json:"short_url"`
+	Clicks   int    `json:"clicks"`
+}
+
+// DailyTrend is the DailyTrend schema of the API.
+type DailyTrend struct {
+	Date        string `json:"date"`
+	CreatedUrls int    `json:"created_urls"`
+	ActiveUsers int    `json:"active_users"`
+}
+
+// ClickStats is the ClickStats schema of the API.
+type ClickStats struct {
+	ShortURL       string        `json:"short_url"`
+	TotalClicks    int           `json:"total_clicks"`
+	Daily          []DailyClicks `json:"daily"`
+	LastAccessedAt *time.Time    `json:"last_accessed_at,omitempty"`
+	// Devices is the clicks by the device class of the user agent: desktop, mobile, tablet, bot or unknown. Most clicked first.
+	Devices []Share `json:"devices,omitempty"`
+	// OS is the clicks by the operating system. Most clicked first.
+	OS []Share `json:"os,omitempty"`
+	// Browsers is the clicks by the browser. Most clicked first.
+	Browsers []Share `json:"browsers,omitempty"`
+}
+
+// Share is the Share schema of the API.
+type Share struct {
+	Name   string `json:"name"`
+	Clicks int    `json:"clicks"`
+}
+
+// DailyClicks is the DailyClicks schema of the API.
+type DailyClicks struct {
+	// Date is the day in UTC formatted as YYYY-MM-DD.
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+// FeedRequest is the FeedRequest schema of the API.
+type FeedRequest struct {
+	// URL is the HTTP or HTTPS URL of the feed.
+	URL string `json:"url"`
+	// Campaign is the name the short URLs are grouped under.
+	Campaign string `json:"campaign,omitempty"`
+}
+
+// Feed is the Feed schema of the API.
+type Feed struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Campaign string `json:"campaign,omitempty"`
+	// Items is the shortened items, latest last.
+	Items []FeedItem `json:"items"`
+	// Error is the reason the last poll failed.
+	Error        string     `json:"error,omitempty"`
+	LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// FeedItem is the FeedItem schema of the API.
+type FeedItem struct {
+	// ID is the GUID of the item, the link if it has none.
+	ID          string     `json:"id"`
+	Title       string     `json:"title,omitempty"`
+	Link        string     `json:"link"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	ShortURL    string     `json:"short_url"`
+	ShortenedAt time.Time  `json:"shortened_at"`
+}
+
+// Import is the Import schema of the API.
+type Import struct {
+	ID string `json:"id"`
+	// One of: uploading, queued, processing, done, failed.
+	Status string `json:"status"`
+	// Size is the number of bytes uploaded.
+	Size     int64 `json:"size"`
+	Imported int   `json:"imported"`
+	Failed   int   `json:"failed"`
+	// Error is the reason of the import failure.
+	Error string `json:"error,omitempty"`
+	// UploadURL is the URL to upload the file to, while the import is uploading.
+	UploadURL string    `json:"upload_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// QRExportRequest is the QRExportRequest schema of the API.
+type QRExportRequest struct {
+	// One of: zip, pdf.
+	Format string `json:"format,omitempty"`
+	// ShortUrls is the codes of the links to export.
+	ShortUrls []string `json:"short_urls,omitempty"`
+	// Campaign is exports the links of the feeds of the campaign instead.
+	Campaign string `json:"campaign,omitempty"`
+}
+
+// QRExport is the QRExport schema of the API.
+type QRExport struct {
+	ID string `json:"id"`
+	// One of: zip, pdf.
+	Format string `json:"format"`
+	// One of: queued, processing, done, failed.
+	Status string `json:"status"`
+	// Links is the number of the rendered codes.
+	Links int `json:"links"`
+	// Error is the reason of the export failure.
+	Error string `json:"error,omitempty"`
+	// DownloadURL is the URL to download the file from, once the export is done.
+	DownloadURL string    `json:"download_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// ExpiresAt is the time the export and its file are removed.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ReservationsRequest is the ReservationsRequest schema of the API.
+type ReservationsRequest struct {
+	UserID string   `json:"user_id"`
+	Codes  []string `json:"codes,omitempty"`
+	Count  int      `json:"count,omitempty"`
+}
+
+// ReservationsResponse is the ReservationsResponse schema of the API.
+type ReservationsResponse struct {
+	Codes []string `json:"codes"`
+}
+
+// AddCampaignURLsResponse is the response of AddCampaignURLs.
+type AddCampaignURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *CampaignURLsResponse
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *AddCampaignURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// AddCampaignURLs adds the URLs of the user to the campaign.
+//
+// The URLs are added in a single transaction. Short URLs of other
+// users, deleted or already in the campaign are skipped. Requires
+// the write scope for the scoped callers.
+//
+//	POST /api/user/campaigns/{campaign}/urls
+func (c *Client) AddCampaignURLs(ctx context.Context, campaign string, body []string, reqEditors ...RequestEditorFn) (*AddCampaignURLsResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/campaigns/"+url.PathEscape(campaign)+"/urls", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AddCampaignURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest CampaignURLsResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// BanDomainResponse is the response of BanDomain.
+type BanDomainResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *DomainBanResult
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *BanDomainResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// BanDomain bans the destination domain and its subdomains.
+//
+// The URLs to the domain can't be shortened any more, they are rejected with the host_banned reason. The existing links to the domain are blocked. Requires the token of the admin role.
+//
+//	POST /api/admin/domain-bans
+func (c *Client) BanDomain(ctx context.Context, body DomainBanRequest, reqEditors ...RequestEditorFn) (*BanDomainResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/admin/domain-bans", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BanDomainResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest DomainBanResult
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// CompleteImportResponse is the response of CompleteImport.
+type CompleteImportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON202 is the decoded body of the 202 response.
+	JSON202 *Import
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CompleteImportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CompleteImport finishes the upload and schedules the import.
+//
+//	POST /api/user/imports/{id}/complete
+func (c *Client) CompleteImport(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*CompleteImportResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "POST", "/api/user/imports/"+url.PathEscape(id)+"/complete", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CompleteImportResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 202:
+		var dest Import
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 202 response: %w", err)
+		}
+		res.JSON202 = &dest
+	}
+
+	return res, nil
+}
+
+// CopyCampaignResponse is the response of CopyCampaign.
+type CopyCampaignResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *CampaignTransfer
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CopyCampaignResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CopyCampaign copies the links of the campaign to the other campaign.
+//
+// The links are copied in a single transaction and deduplicated by
+// their destinations: the ones already in the target campaign are
+// skipped. Requires the write scope for the scoped callers.
+//
+//	POST /api/user/campaigns/{campaign}/copy
+func (c *Client) CopyCampaign(ctx context.Context, campaign string, body CampaignTransferRequest, reqEditors ...RequestEditorFn) (*CopyCampaignResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/campaigns/"+url.PathEscape(campaign)+"/copy", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CopyCampaignResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest CampaignTransfer
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// CreateAPIKeyResponse is the response of CreateAPIKey.
+type CreateAPIKeyResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *APIKey
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CreateAPIKeyResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CreateAPIKey mints a new API key of the user.
+//
+// The key is returned once, only its hash is stored. The key without
+// the scopes has the scopes of the caller, i.e. no limits for the
+// Authorization cookie. Requires the admin scope for the scoped callers,
+// which can't mint the keys with other scopes.
+//
+//	POST /api/user/keys
+func (c *Client) CreateAPIKey(ctx context.Context, body APIKeyRequest, reqEditors ...RequestEditorFn) (*CreateAPIKeyResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/keys", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateAPIKeyResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest APIKey
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// CreateFeedResponse is the response of CreateFeed.
+type CreateFeedResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *Feed
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CreateFeedResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CreateFeed subscribes to RSS or Atom feed.
+//
+// The feed is polled right away and then on schedule. The link of
+// every new item is shortened on behalf of the user.
+//
+//	POST /api/user/feeds
+func (c *Client) CreateFeed(ctx context.Context, body FeedRequest, reqEditors ...RequestEditorFn) (*CreateFeedResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/feeds", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateFeedResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest Feed
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// CreateImportResponse is the response of CreateImport.
+type CreateImportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *Import
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CreateImportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CreateImport starts the bulk import of URLs.
+//
+// The file with one URL per line is uploaded to the returned
+// upload URL in chunks, then the import is completed.
+//
+//	POST /api/user/imports
+func (c *Client) CreateImport(ctx context.Context, reqEditors ...RequestEditorFn) (*CreateImportResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "POST", "/api/user/imports", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateImportResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest Import
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// CreateQRExportResponse is the response of CreateQRExport.
+type CreateQRExportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON202 is the decoded body of the 202 response.
+	JSON202 *QRExport
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CreateQRExportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CreateQRExport queues the bulk export of the QR codes.
+//
+// Renders the QR codes of the listed short URLs or of all the links
+// of the feeds of the campaign in the background, as a ZIP of PNG
+// images or a printable PDF sheet. The status is polled until
+// the download URL is returned.
+//
+//	POST /api/user/qr-exports
+func (c *Client) CreateQRExport(ctx context.Context, body QRExportRequest, reqEditors ...RequestEditorFn) (*CreateQRExportResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/qr-exports", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateQRExportResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 202:
+		var dest QRExport
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 202 response: %w", err)
+		}
+		res.JSON202 = &dest
+	}
+
+	return res, nil
+}
+
+// CreateServiceTokenResponse is the response of CreateServiceToken.
+type CreateServiceTokenResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *ServiceToken
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *CreateServiceTokenResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// CreateServiceToken issues the service token of the user.
+//
+// The token is passed in the Authorization cookie and expires as configured for the service tokens. A new user is created if the user ID is omitted. The token of the admin role moderates the URLs of all the users. Available only from the trusted subnet. The tokens of the given users and of the admin role also require the token of the admin role, the first one is minted with 'shortenerctl token'.
+//
+//	POST /api/admin/tokens
+func (c *Client) CreateServiceToken(ctx context.Context, body ServiceTokenRequest, reqEditors ...RequestEditorFn) (*CreateServiceTokenResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/admin/tokens", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateServiceTokenResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest ServiceToken
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// DeleteAnyURLResponse is the response of DeleteAnyURL.
+type DeleteAnyURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *DeleteAnyURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// DeleteAnyURL deletes the short URL of any user at once.
+//
+// Requires the token of the admin role.
+//
+//	DELETE /api/admin/urls/{shortURL}
+func (c *Client) DeleteAnyURL(ctx context.Context, shortURL string, reqEditors ...RequestEditorFn) (*DeleteAnyURLResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "DELETE", "/api/admin/urls/"+url.PathEscape(shortURL), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DeleteAnyURLResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// DeleteFeedResponse is the response of DeleteFeed.
+type DeleteFeedResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *DeleteFeedResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// DeleteFeed unsubscribes from the feed, the short URLs are kept.
+//
+//	DELETE /api/user/feeds/{id}
+func (c *Client) DeleteFeed(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DeleteFeedResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "DELETE", "/api/user/feeds/"+url.PathEscape(id), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DeleteFeedResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// DeleteUserURLsResponse is the response of DeleteUserURLs.
+type DeleteUserURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]DeleteURLResult
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *DeleteUserURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// DeleteUserURLs schedules the deletion of the URLs of the user.
+//
+//	DELETE /api/user/urls
+func (c *Client) DeleteUserURLs(ctx context.Context, body []string, sync *bool, reqEditors ...RequestEditorFn) (*DeleteUserURLsResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "DELETE", "/api/user/urls", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if sync != nil {
+		query.Set("sync", fmt.Sprint(*sync))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DeleteUserURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []DeleteURLResult
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// DeleteUserURLsByOriginalResponse is the response of DeleteUserURLsByOriginal.
+type DeleteUserURLsByOriginalResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON202 is the decoded body of the 202 response.
+	JSON202 *[]DeleteByOriginalResult
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *DeleteUserURLsByOriginalResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// DeleteUserURLsByOriginal schedules the deletion of the URLs of the user by their original URLs.
+//
+// Every short URL of the user pointing to one of the original URLs
+// is deleted. Original URLs the user has not shortened are skipped.
+//
+//	DELETE /api/user/urls/by-original
+func (c *Client) DeleteUserURLsByOriginal(ctx context.Context, body []string, reqEditors ...RequestEditorFn) (*DeleteUserURLsByOriginalResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "DELETE", "/api/user/urls/by-original", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DeleteUserURLsByOriginalResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 202:
+		var dest []DeleteByOriginalResult
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 202 response: %w", err)
+		}
+		res.JSON202 = &dest
+	}
+
+	return res, nil
+}
+
+// DownloadQRExportResponse is the response of DownloadQRExport.
+type DownloadQRExportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *DownloadQRExportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// DownloadQRExport downloads the rendered file of the QR export.
+//
+//	GET /api/user/qr-exports/{id}/download
+func (c *Client) DownloadQRExport(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DownloadQRExportResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/qr-exports/"+url.PathEscape(id)+"/download", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DownloadQRExportResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ExpandBatchResponse is the response of ExpandBatch.
+type ExpandBatchResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]ExpandBatchResponseItem
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ExpandBatchResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ExpandBatch returns the original URLs of multiple short URLs.
+//
+// Unknown and deleted short URLs are omitted from the response,
+// the rest keep the order of the request.
+//
+//	POST /api/expand/batch
+func (c *Client) ExpandBatch(ctx context.Context, body []string, reqEditors ...RequestEditorFn) (*ExpandBatchResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/expand/batch", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ExpandBatchResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []ExpandBatchResponseItem
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// ExportInstanceResponse is the response of ExportInstance.
+type ExportInstanceResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ExportInstanceResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ExportInstance exports the whole instance.
+//
+// Streams a tar.gz archive with all the URL records of all the users and the settings they depend on, restored with 'shortenerctl import' into an instance with any storage backend. Available from the trusted subnet or with the token of the admin role.
+//
+//	GET /api/admin/export
+func (c *Client) ExportInstance(ctx context.Context, reqEditors ...RequestEditorFn) (*ExportInstanceResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/admin/export", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ExportInstanceResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ExportUserURLsResponse is the response of ExportUserURLs.
+type ExportUserURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]UserURLExport
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ExportUserURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ExportUserURLs streams all the URLs of the user as a JSON array or a CSV file.
+//
+// The URLs, including the deleted ones, are ordered by their short URLs
+// and sent in batches. The CSV file starts with the header of the
+// columns named as the fields of UserURLExport. The failure after
+// the first batch is sent truncates the export.
+//
+//	GET /api/user/urls/export
+func (c *Client) ExportUserURLs(ctx context.Context, format *string, tz *string, reqEditors ...RequestEditorFn) (*ExportUserURLsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/urls/export", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if format != nil {
+		query.Set("format", fmt.Sprint(*format))
+	}
+	if tz != nil {
+		query.Set("tz", fmt.Sprint(*tz))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ExportUserURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []UserURLExport
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetAnyUserURLsResponse is the response of GetAnyUserURLs.
+type GetAnyUserURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]URLDetails
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetAnyUserURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetAnyUserURLs returns the full records of the URLs of any user.
+//
+// Includes the deleted URLs, ordered by the short URLs. Requires the token of the admin role.
+//
+//	GET /api/admin/users/{userID}/urls
+func (c *Client) GetAnyUserURLs(ctx context.Context, userID string, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetAnyUserURLsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/admin/users/"+url.PathEscape(userID)+"/urls", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetAnyUserURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []URLDetails
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetCampaignURLsResponse is the response of GetCampaignURLs.
+type GetCampaignURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]UserURL
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetCampaignURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetCampaignURLs returns the URLs of the user in the campaign.
+//
+// The campaign without links is empty. Requires the read scope
+// for the scoped callers.
+//
+//	GET /api/user/campaigns/{campaign}/urls
+func (c *Client) GetCampaignURLs(ctx context.Context, campaign string, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetCampaignURLsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/campaigns/"+url.PathEscape(campaign)+"/urls", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetCampaignURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []UserURL
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetCertificatesResponse is the response of GetCertificates.
+type GetCertificatesResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]CertificateHealth
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetCertificatesResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetCertificates returns the health of the served TLS certificates.
+//
+// The certificates are loaded from the files or issued by Let's Encrypt. The ones expiring within the configured warning are expiring, the ones issued by Let's Encrypt not renewed a day after the renewal time are renewal_overdue. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/tls/certificates
+func (c *Client) GetCertificates(ctx context.Context, reqEditors ...RequestEditorFn) (*GetCertificatesResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/tls/certificates", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetCertificatesResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []CertificateHealth
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetDNSMetricsResponse is the response of GetDNSMetrics.
+type GetDNSMetricsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *DNSMetrics
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetDNSMetricsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetDNSMetrics returns the metrics of the DNS cache.
+//
+// The hostnames resolved by the outbound requests, e.g. of the feeds and the click export, are cached for the configured TTLs, the failed lookups for the negative TTL. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/dns/metrics
+func (c *Client) GetDNSMetrics(ctx context.Context, reqEditors ...RequestEditorFn) (*GetDNSMetricsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/dns/metrics", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetDNSMetricsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest DNSMetrics
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetDeletionMetricsResponse is the response of GetDeletionMetrics.
+type GetDeletionMetricsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *DeletionMetrics
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetDeletionMetricsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetDeletionMetrics returns the metrics of the asynchronous deletion.
+//
+// The batches of the deleted URLs are flushed once they reach the size adapted to the arrival rate of the deletions and the latency of the storage, or once the oldest deletion has waited for the maximum delay. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/deletions/metrics
+func (c *Client) GetDeletionMetrics(ctx context.Context, reqEditors ...RequestEditorFn) (*GetDeletionMetricsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/deletions/metrics", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetDeletionMetricsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest DeletionMetrics
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetDomainBansResponse is the response of GetDomainBans.
+type GetDomainBansResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]DomainBan
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetDomainBansResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetDomainBans returns the banned destination domains.
+//
+// In the order they were banned. Requires the token of the admin role.
+//
+//	GET /api/admin/domain-bans
+func (c *Client) GetDomainBans(ctx context.Context, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetDomainBansResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/admin/domain-bans", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetDomainBansResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []DomainBan
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetFeedResponse is the response of GetFeed.
+type GetFeedResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *Feed
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetFeedResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetFeed returns the feed with the short URLs of its items.
+//
+//	GET /api/user/feeds/{id}
+func (c *Client) GetFeed(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetFeedResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/feeds/"+url.PathEscape(id), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetFeedResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest Feed
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetFlaggedURLsResponse is the response of GetFlaggedURLs.
+type GetFlaggedURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]URLDetails
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetFlaggedURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetFlaggedURLs returns the full records of the blocked URLs.
+//
+// Excludes the deleted URLs. Requires the token of the admin role.
+//
+//	GET /api/admin/flagged
+func (c *Client) GetFlaggedURLs(ctx context.Context, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetFlaggedURLsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/admin/flagged", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetFlaggedURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []URLDetails
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetImportResponse is the response of GetImport.
+type GetImportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *Import
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetImportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetImport returns the status of the import.
+//
+//	GET /api/user/imports/{id}
+func (c *Client) GetImport(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetImportResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/imports/"+url.PathEscape(id), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetImportResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest Import
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetPoolMetricsResponse is the response of GetPoolMetrics.
+type GetPoolMetricsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *PoolMetrics
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetPoolMetricsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetPoolMetrics returns the stats of the pool of the database connections.
+//
+// The pool is limited by the postgres settings of the configuration. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/db/metrics
+func (c *Client) GetPoolMetrics(ctx context.Context, reqEditors ...RequestEditorFn) (*GetPoolMetricsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/db/metrics", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetPoolMetricsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest PoolMetrics
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetQRExportResponse is the response of GetQRExport.
+type GetQRExportResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *QRExport
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetQRExportResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetQRExport returns the status of the QR export.
+//
+//	GET /api/user/qr-exports/{id}
+func (c *Client) GetQRExport(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetQRExportResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/qr-exports/"+url.PathEscape(id), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetQRExportResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest QRExport
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetSLOResponse is the response of GetSLO.
+type GetSLOResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *SLOReport
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetSLOResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetSLO returns the service level indicators.
+//
+// The availability of the requests and the latency of the redirects over the rolling windows of 5 minutes, 1 hour and 6 hours, with the burn rates of the error budgets of the configured objectives and the counters growing since the start. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/slo
+func (c *Client) GetSLO(ctx context.Context, reqEditors ...RequestEditorFn) (*GetSLOResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/slo", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetSLOResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest SLOReport
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetStatsResponse is the response of GetStats.
+type GetStatsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *Stats
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetStatsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetStats returns the numbers of the URLs and the users.
+//
+// The number of the short URLs not deleted and the number of the users owning them. The numbers may be estimated if the server is configured so. If the storage supports them, the statistics are extended with the number of the users who created URLs, the most clicked links and the daily numbers of the created URLs for the last days in UTC. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/stats
+func (c *Client) GetStats(ctx context.Context, days *int, top *int, reqEditors ...RequestEditorFn) (*GetStatsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/stats", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if days != nil {
+		query.Set("days", fmt.Sprint(*days))
+	}
+	if top != nil {
+		query.Set("top", fmt.Sprint(*top))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetStatsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest Stats
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetStatsTrendsResponse is the response of GetStatsTrends.
+type GetStatsTrendsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]DailyTrend
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetStatsTrendsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetStatsTrends returns the daily creation trends.
+//
+// The numbers of the created URLs and of the users who created them for the last days in UTC, today included, oldest first. Days without created URLs have zero counts. Available from the trusted subnet or with the token of the service role.
+//
+//	GET /api/internal/stats/trends
+func (c *Client) GetStatsTrends(ctx context.Context, days *int, reqEditors ...RequestEditorFn) (*GetStatsTrendsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/internal/stats/trends", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if days != nil {
+		query.Set("days", fmt.Sprint(*days))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetStatsTrendsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []DailyTrend
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetURLDetailsResponse is the response of GetURLDetails.
+type GetURLDetailsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *URLDetails
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetURLDetailsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetURLDetails returns the full record of the short URL.
+//
+// Available from the trusted subnet or with the token of the admin role.
+//
+//	GET /api/admin/urls/{shortURL}
+func (c *Client) GetURLDetails(ctx context.Context, shortURL string, reqEditors ...RequestEditorFn) (*GetURLDetailsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/admin/urls/"+url.PathEscape(shortURL), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetURLDetailsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest URLDetails
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetURLSharesResponse is the response of GetURLShares.
+type GetURLSharesResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]URLShare
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetURLSharesResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetURLShares returns the users the URL of the user is shared with.
+//
+//	GET /api/user/urls/{shortURL}/shares
+func (c *Client) GetURLShares(ctx context.Context, shortURL string, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetURLSharesResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/urls/"+url.PathEscape(shortURL)+"/shares", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetURLSharesResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []URLShare
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetURLStatsResponse is the response of GetURLStats.
+type GetURLStatsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *ClickStats
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetURLStatsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetURLStats returns the click statistics of the URL of the user.
+//
+// Clicks are saved in the background, so the latest redirects
+// may be missing for a few seconds.
+//
+//	GET /api/user/urls/{shortURL}/stats
+func (c *Client) GetURLStats(ctx context.Context, shortURL string, fields *string, reqEditors ...RequestEditorFn) (*GetURLStatsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/urls/"+url.PathEscape(shortURL)+"/stats", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if fields != nil {
+		query.Set("fields", fmt.Sprint(*fields))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetURLStatsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest ClickStats
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// GetUserURLsResponse is the response of GetUserURLs.
+type GetUserURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]UserURL
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *GetUserURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// GetUserURLs returns the URLs of the user.
+//
+//	GET /api/user/urls
+func (c *Client) GetUserURLs(ctx context.Context, fields *string, q *string, limit *int, offset *int, reqEditors ...RequestEditorFn) (*GetUserURLsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/urls", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if fields != nil {
+		query.Set("fields", fmt.Sprint(*fields))
+	}
+	if q != nil {
+		query.Set("q", fmt.Sprint(*q))
+	}
+	if limit != nil {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	if offset != nil {
+		query.Set("offset", fmt.Sprint(*offset))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetUserURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []UserURL
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// HealthzResponse is the response of Healthz.
+type HealthzResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *HealthzResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Healthz liveness probe, doesn't check the storage.
+//
+//	GET /healthz
+func (c *Client) Healthz(ctx context.Context, reqEditors ...RequestEditorFn) (*HealthzResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/healthz", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &HealthzResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ImportUserURLsResponse is the response of ImportUserURLs.
+type ImportUserURLsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *UserURLImport
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ImportUserURLsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ImportUserURLs shortens the URLs of the uploaded CSV or JSON file.
+//
+// The file field of the form is either CSV of the original URLs and
+// the optional aliases, with the optional original_url,alias header,
+// or JSON array of UserURLImportItem. The format is told by the
+// extension of the file name or its content type. The invalid rows
+// are skipped, the ones conflicting with the stored URLs are reported
+// as existing. The Content-Type header with the boundary of the form
+// is set by the request editor. Requires the shorten scope for
+// the scoped callers.
+//
+//	POST /api/user/urls/import
+func (c *Client) ImportUserURLs(ctx context.Context, body io.Reader, reqEditors ...RequestEditorFn) (*ImportUserURLsResponse, error) {
+	reqBody := body
+	req, err := c.newRequest(ctx, "POST", "/api/user/urls/import", "multipart/form-data", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ImportUserURLsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest UserURLImport
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// ListAPIKeysResponse is the response of ListAPIKeys.
+type ListAPIKeysResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]APIKeyUsage
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ListAPIKeysResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ListAPIKeys returns the API keys of the user with their usage.
+//
+// The keys themselves are never returned. Requires the admin scope
+// for the scoped callers.
+//
+//	GET /api/user/keys
+func (c *Client) ListAPIKeys(ctx context.Context, reqEditors ...RequestEditorFn) (*ListAPIKeysResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/keys", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListAPIKeysResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []APIKeyUsage
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// ListFeedsResponse is the response of ListFeeds.
+type ListFeedsResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *[]Feed
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ListFeedsResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ListFeeds returns the feeds of the user.
+//
+//	GET /api/user/feeds
+func (c *Client) ListFeeds(ctx context.Context, reqEditors ...RequestEditorFn) (*ListFeedsResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/api/user/feeds", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListFeedsResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest []Feed
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// LoginResponse is the response of Login.
+type LoginResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *AccountToken
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *LoginResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Login authenticates the user with the login and the password.
+//
+// The tokens of the user are passed in the cookies as on the registration.
+//
+//	POST /api/auth/login
+func (c *Client) Login(ctx context.Context, body Credentials, reqEditors ...RequestEditorFn) (*LoginResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/auth/login", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &LoginResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest AccountToken
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// LogoutResponse is the response of Logout.
+type LogoutResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *LogoutResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Logout revokes the tokens of the user.
+//
+// Revokes the token the request is authenticated with and the refresh token of the Refresh-Token cookie or of the request body, and removes their cookies. The revoked tokens are rejected with 401 Unauthorized.
+//
+//	POST /api/auth/logout
+func (c *Client) Logout(ctx context.Context, body RefreshRequest, reqEditors ...RequestEditorFn) (*LogoutResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/auth/logout", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &LogoutResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// MoveCampaignResponse is the response of MoveCampaign.
+type MoveCampaignResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *CampaignTransfer
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *MoveCampaignResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// MoveCampaign moves the links of the campaign to the other campaign.
+//
+// Copies the links like CopyCampaign and then empties the source
+// campaign in the same transaction. Requires the write scope for
+// the scoped callers.
+//
+//	POST /api/user/campaigns/{campaign}/move
+func (c *Client) MoveCampaign(ctx context.Context, campaign string, body CampaignTransferRequest, reqEditors ...RequestEditorFn) (*MoveCampaignResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/campaigns/"+url.PathEscape(campaign)+"/move", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &MoveCampaignResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest CampaignTransfer
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// PauseURLResponse is the response of PauseURL.
+type PauseURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *LinkStateResponse
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *PauseURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// PauseURL pauses the active URL of the user.
+//
+// The paused URL responds with 503 instead of the redirect until it is
+// resumed. Requires the write scope for the scoped callers.
+//
+//	POST /api/user/urls/{shortURL}/pause
+func (c *Client) PauseURL(ctx context.Context, shortURL string, reqEditors ...RequestEditorFn) (*PauseURLResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "POST", "/api/user/urls/"+url.PathEscape(shortURL)+"/pause", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PauseURLResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest LinkStateResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// PingResponse is the response of Ping.
+type PingResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *PingResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Ping checks the connection to the database.
+//
+//	GET /ping
+func (c *Client) Ping(ctx context.Context, reqEditors ...RequestEditorFn) (*PingResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/ping", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PingResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ReadyzResponse is the response of Readyz.
+type ReadyzResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ReadyzResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Readyz readiness probe, checks the storage.
+//
+//	GET /readyz
+func (c *Client) Readyz(ctx context.Context, verbose *string, reqEditors ...RequestEditorFn) (*ReadyzResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/readyz", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if verbose != nil {
+		query.Set("verbose", fmt.Sprint(*verbose))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ReadyzResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// RedirectResponse is the response of Redirect.
+type RedirectResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *RedirectResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Redirect redirects to the original URL.
+//
+//	GET /{shortURL}
+func (c *Client) Redirect(ctx context.Context, shortURL string, reqEditors ...RequestEditorFn) (*RedirectResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "GET", "/"+url.PathEscape(shortURL), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RedirectResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// RefreshResponse is the response of Refresh.
+type RefreshResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *AccountToken
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *RefreshResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Refresh issues the new tokens for the refresh token.
+//
+// The refresh token is taken from the Refresh-Token cookie or from the request body. It is revoked, so every refresh token is exchanged once.
+//
+//	POST /api/auth/refresh
+func (c *Client) Refresh(ctx context.Context, body RefreshRequest, reqEditors ...RequestEditorFn) (*RefreshResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/auth/refresh", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RefreshResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest AccountToken
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// RegisterResponse is the response of Register.
+type RegisterResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *AccountToken
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *RegisterResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// Register creates the account of the user.
+//
+// The account keeps the ID of the user the request is authenticated with, so the URLs shortened anonymously stay with the user. The registered token is passed in the Authorization cookie and, if the storage supports the token revocation, the refresh token in the Refresh-Token cookie. Scoped tokens can't register.
+//
+//	POST /api/auth/register
+func (c *Client) Register(ctx context.Context, body Credentials, reqEditors ...RequestEditorFn) (*RegisterResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/auth/register", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RegisterResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest AccountToken
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// ReserveCodesResponse is the response of ReserveCodes.
+type ReserveCodesResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *ReservationsResponse
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ReserveCodesResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ReserveCodes reserves short codes for the user.
+//
+// Available from the trusted subnet or with the token of the admin role.
+//
+//	POST /api/admin/reservations
+func (c *Client) ReserveCodes(ctx context.Context, body ReservationsRequest, reqEditors ...RequestEditorFn) (*ReserveCodesResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/admin/reservations", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ReserveCodesResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest ReservationsResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	}
+
+	return res, nil
+}
+
+// ResumeURLResponse is the response of ResumeURL.
+type ResumeURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *LinkStateResponse
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ResumeURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ResumeURL resumes the paused URL of the user.
+//
+// Requires the write scope for the scoped callers.
+//
+//	POST /api/user/urls/{shortURL}/resume
+func (c *Client) ResumeURL(ctx context.Context, shortURL string, reqEditors ...RequestEditorFn) (*ResumeURLResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "POST", "/api/user/urls/"+url.PathEscape(shortURL)+"/resume", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ResumeURLResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest LinkStateResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// RotateAPIKeyResponse is the response of RotateAPIKey.
+type RotateAPIKeyResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *APIKey
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *RotateAPIKeyResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// RotateAPIKey replaces the API key with the new one.
+//
+// The name, scopes and usage are kept. The old key is rejected at once,
+// the new one is returned once. Requires the admin scope for the scoped
+// callers, which can rotate the keys within their scopes only.
+//
+//	POST /api/user/keys/{id}/rotate
+func (c *Client) RotateAPIKey(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*RotateAPIKeyResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "POST", "/api/user/keys/"+url.PathEscape(id)+"/rotate", "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RotateAPIKeyResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest APIKey
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	}
+
+	return res, nil
+}
+
+// SetNoteResponse is the response of SetNote.
+type SetNoteResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *SetNoteResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// SetNote annotates the URL of the user with the free-text note.
+//
+// Empty note removes it. Requires the write scope for the scoped callers.
+//
+//	PUT /api/user/urls/{shortURL}/note
+func (c *Client) SetNote(ctx context.Context, shortURL string, body NoteRequest, reqEditors ...RequestEditorFn) (*SetNoteResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "PUT", "/api/user/urls/"+url.PathEscape(shortURL)+"/note", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SetNoteResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// SetRedirectLimitResponse is the response of SetRedirectLimit.
+type SetRedirectLimitResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *SetRedirectLimitResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// SetRedirectLimit caps the redirects per minute of the URL of the user.
+//
+// The redirects over the limit respond with 429 Too Many Requests.
+// Zero limit removes the cap. Requires the write scope for the scoped
+// callers.
+//
+//	PUT /api/user/urls/{shortURL}/redirect-limit
+func (c *Client) SetRedirectLimit(ctx context.Context, shortURL string, body RedirectLimitRequest, reqEditors ...RequestEditorFn) (*SetRedirectLimitResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "PUT", "/api/user/urls/"+url.PathEscape(shortURL)+"/redirect-limit", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SetRedirectLimitResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ShareURLResponse is the response of ShareURL.
+type ShareURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ShareURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ShareURL grants the user the read access to the URL of the caller.
+//
+// The shared URL is listed among the URLs of the user, but can't be
+// edited, deleted or transferred by them. Sharing the URL again keeps
+// the original share. Requires the write scope for the scoped callers.
+//
+//	PUT /api/user/urls/{shortURL}/shares/{userID}
+func (c *Client) ShareURL(ctx context.Context, shortURL string, userID string, reqEditors ...RequestEditorFn) (*ShareURLResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "PUT", "/api/user/urls/"+url.PathEscape(shortURL)+"/shares/"+url.PathEscape(userID), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ShareURLResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// ShortenBatchResponse is the response of ShortenBatch.
+type ShortenBatchResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *[]ShortenBatchResponseItem
+	// JSON422 is the decoded body of the 422 response.
+	JSON422 *HostBlocked
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ShortenBatchResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ShortenBatch shortens multiple URLs.
+//
+//	POST /api/shorten/batch
+func (c *Client) ShortenBatch(ctx context.Context, body []ShortenBatchRequestItem, idempotencyKey *string, reqEditors ...RequestEditorFn) (*ShortenBatchResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/shorten/batch", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if idempotencyKey != nil {
+		req.Header.Set("Idempotency-Key", fmt.Sprint(*idempotencyKey))
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ShortenBatchResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest []ShortenBatchResponseItem
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	case 422:
+		var dest HostBlocked
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 422 response: %w", err)
+		}
+		res.JSON422 = &dest
+	}
+
+	return res, nil
+}
+
+// ShortenJSONResponse is the response of ShortenJSON.
+type ShortenJSONResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON201 is the decoded body of the 201 response.
+	JSON201 *ShortenResponse
+	// JSON409 is the decoded body of the 409 response.
+	JSON409 *ShortenResponse
+	// JSON400 is the decoded body of the 400 response.
+	JSON400 *ShortenResponse
+	// JSON422 is the decoded body of the 422 response.
+	JSON422 *HostBlocked
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ShortenJSONResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ShortenJSON shortens the URL, optionally with the custom alias.
+//
+//	POST /api/shorten
+func (c *Client) ShortenJSON(ctx context.Context, body ShortenRequest, idempotencyKey *string, reqEditors ...RequestEditorFn) (*ShortenJSONResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/shorten", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if idempotencyKey != nil {
+		req.Header.Set("Idempotency-Key", fmt.Sprint(*idempotencyKey))
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ShortenJSONResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 201:
+		var dest ShortenResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 201 response: %w", err)
+		}
+		res.JSON201 = &dest
+	case 409:
+		var dest ShortenResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 409 response: %w", err)
+		}
+		res.JSON409 = &dest
+	case 400:
+		var dest ShortenResponse
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 400 response: %w", err)
+		}
+		res.JSON400 = &dest
+	case 422:
+		var dest HostBlocked
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 422 response: %w", err)
+		}
+		res.JSON422 = &dest
+	}
+
+	return res, nil
+}
+
+// ShortenTextResponse is the response of ShortenText.
+type ShortenTextResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON422 is the decoded body of the 422 response.
+	JSON422 *HostBlocked
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *ShortenTextResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// ShortenText shortens the URL given as plain text.
+//
+//	POST /
+func (c *Client) ShortenText(ctx context.Context, body string, ttl *int64, domain *string, reqEditors ...RequestEditorFn) (*ShortenTextResponse, error) {
+	reqBody := strings.NewReader(body)
+	req, err := c.newRequest(ctx, "POST", "/", "text/plain", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	if ttl != nil {
+		query.Set("ttl", fmt.Sprint(*ttl))
+	}
+	if domain != nil {
+		query.Set("domain", fmt.Sprint(*domain))
+	}
+	req.URL.RawQuery = query.Encode()
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ShortenTextResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 422:
+		var dest HostBlocked
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 422 response: %w", err)
+		}
+		res.JSON422 = &dest
+	}
+
+	return res, nil
+}
+
+// TransferURLResponse is the response of TransferURL.
+type TransferURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *TransferURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// TransferURL hands the URL of the user over to another user.
+//
+// The shares of the URL are dropped and it is removed from the campaigns
+// of the user. Requires the write scope for the scoped callers.
+//
+//	POST /api/user/urls/{shortURL}/transfer
+func (c *Client) TransferURL(ctx context.Context, shortURL string, body TransferRequest, reqEditors ...RequestEditorFn) (*TransferURLResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", "/api/user/urls/"+url.PathEscape(shortURL)+"/transfer", "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &TransferURLResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// UnshareURLResponse is the response of UnshareURL.
+type UnshareURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *UnshareURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// UnshareURL revokes the read access of the user to the URL of the caller.
+//
+// Requires the write scope for the scoped callers.
+//
+//	DELETE /api/user/urls/{shortURL}/shares/{userID}
+func (c *Client) UnshareURL(ctx context.Context, shortURL string, userID string, reqEditors ...RequestEditorFn) (*UnshareURLResponse, error) {
+	var reqBody io.Reader
+	req, err := c.newRequest(ctx, "DELETE", "/api/user/urls/"+url.PathEscape(shortURL)+"/shares/"+url.PathEscape(userID), "", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &UnshareURLResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
+
+// UpdateURLResponse is the response of UpdateURL.
+type UpdateURLResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+	// JSON200 is the decoded body of the 200 response.
+	JSON200 *UpdatedURL
+	// JSON422 is the decoded body of the 422 response.
+	JSON422 *HostBlocked
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *UpdateURLResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// UpdateURL changes the destination or the expiration of the URL of the user.
+//
+// The edit is applied to the given version of the URL only, so that
+// the concurrent edits are not lost. The current version is used if
+// it is omitted. The omitted fields are left unchanged. Requires
+// the write scope for the scoped callers.
+//
+//	PATCH /api/user/urls/{shortURL}
+func (c *Client) UpdateURL(ctx context.Context, shortURL string, body UpdateURLRequest, reqEditors ...RequestEditorFn) (*UpdateURLResponse, error) {
+	reqBody, err := jsonBody(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "PATCH", "/api/user/urls/"+url.PathEscape(shortURL), "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &UpdateURLResponse{HTTPResponse: httpRes, Body: resBody}
+	if !isJSON(httpRes) {
+		return res, nil
+	}
+
+	switch httpRes.StatusCode {
+	case 200:
+		var dest UpdatedURL
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 200 response: %w", err)
+		}
+		res.JSON200 = &dest
+	case 422:
+		var dest HostBlocked
+		if err = json.Unmarshal(resBody, &dest); err != nil {
+			return nil, fmt.Errorf("decode 422 response: %w", err)
+		}
+		res.JSON422 = &dest
+	}
+
+	return res, nil
+}
+
+// UploadImportChunkResponse is the response of UploadImportChunk.
+type UploadImportChunkResponse struct {
+	// HTTPResponse is the raw response, its body is already read.
+	HTTPResponse *http.Response
+	// Body is the raw response body.
+	Body []byte
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (r *UploadImportChunkResponse) StatusCode() int {
+	return r.HTTPResponse.StatusCode
+}
+
+// UploadImportChunk appends the chunk of the file to the import.
+//
+//	PATCH /api/user/imports/{id}
+func (c *Client) UploadImportChunk(ctx context.Context, id string, uploadOffset int64, body io.Reader, reqEditors ...RequestEditorFn) (*UploadImportChunkResponse, error) {
+	reqBody := body
+	req, err := c.newRequest(ctx, "PATCH", "/api/user/imports/"+url.PathEscape(id), "application/octet-stream", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Upload-Offset", fmt.Sprint(uploadOffset))
+
+	httpRes, resBody, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &UploadImportChunkResponse{HTTPResponse: httpRes, Body: resBody}
+	return res, nil
+}
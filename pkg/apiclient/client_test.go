@@ -0,0 +1,275 @@
+package apiclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KretovDmitry/shortener/internal/config"
+	"github.com/KretovDmitry/shortener/internal/handler"
+	"github.com/KretovDmitry/shortener/internal/logger"
+	"github.com/KretovDmitry/shortener/internal/repository/memstore"
+	"github.com/KretovDmitry/shortener/pkg/apiclient"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient starts the in-process server and returns the client of it
+// keeping the cookies like a browser does.
+func newTestClient(t *testing.T) *apiclient.Client {
+	t.Helper()
+	return newCookieClient(t, newTestServer(t).URL)
+}
+
+// newTestServer starts the in-process server.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	store := memstore.NewURLRepository()
+	cfg := config.NewForTest()
+	cfg.TrustedSubnet = "192.0.2.0/24"
+	// the test clients connect through the loopback as the proxy
+	cfg.TrustedProxies = []string{"127.0.0.1/32", "::1/128"}
+	cfg.Imports.Dir = t.TempDir()
+	cfg.Imports.MaxSize = 1 << 20
+	cfg.Idempotency.TTL = time.Hour
+
+	l, _ := logger.NewForTest()
+	h, err := handler.New(store, cfg, l, handler.WithReservations(store),
+		handler.WithClicks(store), handler.WithAPIKeys(store), handler.WithIdempotency(store))
+	require.NoError(t, err, "new handler error")
+	t.Cleanup(h.Stop)
+
+	server := httptest.NewServer(h.Register(chi.NewRouter(), cfg, l))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newCookieClient returns the client of the server
+// keeping the cookies like a browser does.
+func newCookieClient(t *testing.T, server string) *apiclient.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	c, err := apiclient.NewClient(server, apiclient.WithHTTPClient(&http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}))
+	require.NoError(t, err)
+
+	return c
+}
+
+// fromTrustedSubnet sets the client address inside the trusted subnet.
+func fromTrustedSubnet(_ context.Context, req *http.Request) error {
+	req.Header.Set("X-Real-IP", "192.0.2.1")
+	return nil
+}
+
+func TestClient_Shorten(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	text, err := c.ShortenText(ctx, "https://go.dev/", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, text.StatusCode())
+	shortURL := string(text.Body)
+	code := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+	again, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "https://go.dev/"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, again.StatusCode())
+	require.NotNil(t, again.JSON409)
+	assert.Equal(t, shortURL, again.JSON409.Result)
+
+	invalid, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "not a url"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, invalid.StatusCode())
+	require.NotNil(t, invalid.JSON400)
+	assert.False(t, invalid.JSON400.Success)
+
+	batch, err := c.ShortenBatch(ctx, []apiclient.ShortenBatchRequestItem{
+		{CorrelationID: "1", OriginalURL: "https://pkg.go.dev/"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/blog/"},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, batch.StatusCode())
+	require.NotNil(t, batch.JSON201)
+	require.Len(t, *batch.JSON201, 2)
+
+	redirect, err := c.Redirect(ctx, code)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, redirect.StatusCode())
+	assert.Equal(t, "https://go.dev/", redirect.HTTPResponse.Header.Get("Location"))
+
+	expand, err := c.ExpandBatch(ctx, []string{code, "Unknown"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, expand.StatusCode())
+	require.NotNil(t, expand.JSON200)
+	assert.Equal(t, []apiclient.ExpandBatchResponseItem{
+		{ShortURL: code, OriginalURL: "https://go.dev/"},
+	}, *expand.JSON200)
+
+	// clicks are saved in the background, so only the shape is checked
+	stats, err := c.GetURLStats(ctx, code, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, stats.StatusCode())
+	require.NotNil(t, stats.JSON200)
+	assert.Equal(t, code, stats.JSON200.ShortURL)
+
+	// the cookie issued by the first request identifies the user
+	fields := "short_url,original_url"
+	urls, err := c.GetUserURLs(ctx, &fields, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, urls.StatusCode())
+	require.NotNil(t, urls.JSON200)
+	assert.Len(t, *urls.JSON200, 3)
+	assert.Nil(t, (*urls.JSON200)[0].Metadata, "only the selected fields are returned")
+
+	deleted, err := c.DeleteUserURLs(ctx, []string{code}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, deleted.StatusCode())
+
+	sync := true
+	deleted, err = c.DeleteUserURLs(ctx, []string{code, "unknown"}, &sync)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, deleted.StatusCode())
+	require.NotNil(t, deleted.JSON200)
+	assert.Equal(t, []apiclient.DeleteURLResult{
+		{ShortURL: code, Status: "deleted"},
+		{ShortURL: "unknown", Status: "not_found"},
+	}, *deleted.JSON200)
+}
+
+func TestClient_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	// the retry with the same key gets the first response
+	key := "shorten-docs"
+	for i := 0; i < 2; i++ {
+		res, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "https://go.dev/doc/"}, &key)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, res.StatusCode())
+	}
+
+	res, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "https://go.dev/blog/"}, &key)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode())
+}
+
+func TestClient_APIKey(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newCookieClient(t, server.URL)
+
+	// the first request issues the cookie the key is minted with
+	_, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "https://go.dev/"}, nil)
+	require.NoError(t, err)
+	minted, err := c.CreateAPIKey(ctx, apiclient.APIKeyRequest{Name: "ci"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, minted.StatusCode())
+	require.NotNil(t, minted.JSON201)
+
+	// the client with the key needs no cookies
+	keyClient, err := apiclient.NewClient(server.URL, apiclient.WithRequestEditorFn(
+		func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "ApiKey "+minted.JSON201.Key)
+			return nil
+		}))
+	require.NoError(t, err)
+
+	urls, err := keyClient.GetUserURLs(ctx, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, urls.StatusCode())
+	require.NotNil(t, urls.JSON200)
+	assert.Len(t, *urls.JSON200, 1)
+
+	keys, err := c.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, keys.StatusCode())
+	require.NotNil(t, keys.JSON200)
+	require.Len(t, *keys.JSON200, 1)
+	assert.Equal(t, int64(1), (*keys.JSON200)[0].Requests)
+
+	rotated, err := c.RotateAPIKey(ctx, minted.JSON201.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rotated.StatusCode())
+	require.NotNil(t, rotated.JSON200)
+	assert.NotEqual(t, minted.JSON201.Key, rotated.JSON200.Key)
+
+	urls, err = keyClient.GetUserURLs(ctx, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, urls.StatusCode())
+}
+
+func TestClient_Admin(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	shorten, err := c.ShortenJSON(ctx, apiclient.ShortenRequest{URL: "https://go.dev/"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, shorten.StatusCode())
+	code := shorten.JSON201.Result[strings.LastIndex(shorten.JSON201.Result, "/")+1:]
+
+	forbidden, err := c.GetURLDetails(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, forbidden.StatusCode())
+
+	details, err := c.GetURLDetails(ctx, code, fromTrustedSubnet)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, details.StatusCode())
+	require.NotNil(t, details.JSON200)
+	assert.Equal(t, "https://go.dev/", details.JSON200.OriginalURL)
+	assert.Equal(t, "api", details.JSON200.Metadata.Origin)
+
+	reserved, err := c.ReserveCodes(ctx, apiclient.ReservationsRequest{UserID: "owner", Count: 3},
+		fromTrustedSubnet)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, reserved.StatusCode())
+	require.NotNil(t, reserved.JSON201)
+	assert.Len(t, reserved.JSON201.Codes, 3)
+}
+
+func TestClient_Import(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	// get the authorization cookie first
+	_, err := c.ShortenText(ctx, "https://go.dev/", nil, nil)
+	require.NoError(t, err)
+
+	created, err := c.CreateImport(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, created.StatusCode())
+	require.NotNil(t, created.JSON201)
+	id := created.JSON201.ID
+	assert.NotEmpty(t, created.JSON201.UploadURL)
+
+	chunk := "https://example.com/1\nhttps://example.com/2\n"
+	uploaded, err := c.UploadImportChunk(ctx, id, 0, strings.NewReader(chunk))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, uploaded.StatusCode())
+	assert.Equal(t, strconv.Itoa(len(chunk)), uploaded.HTTPResponse.Header.Get("Upload-Offset"))
+
+	completed, err := c.CompleteImport(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, completed.StatusCode())
+
+	require.Eventually(t, func() bool {
+		got, err := c.GetImport(ctx, id)
+		return err == nil && got.JSON200 != nil && got.JSON200.Status == "done" &&
+			got.JSON200.Imported == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
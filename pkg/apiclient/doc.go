@@ -0,0 +1,22 @@
+// Package apiclient is the typed client of the shortener HTTP API
+// generated from the OpenAPI spec in api/openapi.yaml.
+//
+// Every operation of the spec is the method of the Client returning the
+// raw response together with the decoded JSON bodies of the documented
+// status codes. The user is identified by the Authorization cookie,
+// so the HTTP client should have the cookie jar:
+//
+//	jar, _ := cookiejar.New(nil)
+//	c, err := apiclient.NewClient("http://localhost:8080",
+//		apiclient.WithHTTPClient(&http.Client{
+//			Jar: jar,
+//			CheckRedirect: func(*http.Request, []*http.Request) error {
+//				return http.ErrUseLastResponse
+//			},
+//		}))
+//
+// The TypeScript client generated from the same spec is in the ts directory.
+// Run go generate after changing the spec to update both of them.
+package apiclient
+
+//go:generate go run ../../cmd/apigen -spec ../../api/openapi.yaml -go client.gen.go -package apiclient -ts ts/client.gen.ts
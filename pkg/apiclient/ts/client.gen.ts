@@ -0,0 +1,2052 @@
+// Code generated by apigen from openapi.yaml. DO NOT EDIT.
+
+export interface CertificateHealth {
+  source: "file" | "acme";
+  names: string[];
+  not_after: string;
+  /** The time left until the expiry, negative once expired. */
+  expires_in_seconds: number;
+  /** The time the certificate issued by Let's Encrypt is renewed at. */
+  renew_at?: string;
+  status: "ok" | "expiring" | "renewal_overdue" | "expired";
+}
+
+export interface DNSMetrics {
+  /** The number of the cached hostnames. */
+  entries: number;
+  /** The lookups answered with the cached addresses. */
+  hits: number;
+  /** The lookups answered with the cached failure. */
+  negative_hits: number;
+  /** The lookups sent to the resolver. */
+  misses: number;
+  /** The failed lookups sent to the resolver. */
+  errors: number;
+}
+
+export interface SLOReport {
+  objectives: SLOObjectives;
+  counters: SLOCounters;
+  windows: SLOWindow[];
+}
+
+export interface SLOObjectives {
+  /** The share of the requests served without the server errors. */
+  availability: number;
+  /** The latency the redirects are served within. */
+  redirect_latency_ms: number;
+  /** The share of the redirects served within the latency. */
+  redirect_latency_target: number;
+}
+
+export interface SLOCounters {
+  requests_total: number;
+  /** The requests answered with the server errors. */
+  errors_total: number;
+  redirects_total: number;
+  /** The redirects slower than the objective. */
+  slow_redirects_total: number;
+}
+
+export interface SLOWindow {
+  /** The length of the window, e.g. 5m0s. */
+  window: string;
+  requests: number;
+  errors: number;
+  /** The share of the requests without the server errors, 1 if there were none. */
+  availability: number;
+  /** The share of the errors divided by the share allowed by the
+objective. The error budget lasts for the period if it is 1. */
+  availability_burn_rate: number;
+  redirects: number;
+  slow_redirects: number;
+  /** The upper bound of the histogram bucket of the 99th percentile
+of the redirect latency. */
+  redirect_p99_ms: number;
+  /** The share of the slow redirects divided by the share allowed by the objective. */
+  latency_burn_rate: number;
+}
+
+export interface PoolMetrics {
+  /** The configured limit, zero if unlimited. */
+  max_open_connections: number;
+  open_connections: number;
+  in_use: number;
+  idle: number;
+  /** The number of the connections waited for. */
+  wait_count: number;
+  /** The total time waited for the connections. */
+  wait_duration_ms: number;
+  /** The connections closed by the idle limit. */
+  max_idle_closed: number;
+  /** The connections closed by the idle time. */
+  max_idle_time_closed: number;
+  /** The connections closed by the lifetime limit. */
+  max_lifetime_closed: number;
+}
+
+export interface DeletionMetrics {
+  /** The number of the deletions waiting for the flush in the buffers of the workers. */
+  pending: number;
+  /** The number of the deletions waiting for the workers. */
+  queued: number;
+  /** The batch size flushed without waiting for the delay. */
+  target_batch_size: number;
+  /** The moving average of the deletions per second. */
+  arrival_rate: number;
+  /** The moving average of the flush duration. */
+  flush_latency_ms: number;
+  flushes: number;
+  failed_flushes: number;
+  deleted: number;
+  last_batch_size: number;
+  min_batch_size: number;
+  max_batch_size: number;
+  max_delay_ms: number;
+  /** The number of the workers flushing the batches. */
+  workers: number;
+}
+
+export interface DeleteURLResult {
+  short_url: string;
+  status: "deleted" | "not_found" | "forbidden";
+}
+
+export interface DeleteByOriginalResult {
+  original_url: string;
+  short_url: string;
+}
+
+export interface ServiceTokenRequest {
+  user_id?: string;
+  role?: Role;
+}
+
+/** The role of the token. Each role has the access of the ones before it: the service role also reads the statistics and the metrics under /api/internal, the admin one also moderates the URLs of all the users. The service tokens are of the service role by default. */
+export type Role = "user" | "service" | "admin";
+
+export interface Credentials {
+  /** Compared case-insensitively. */
+  login: string;
+  password: string;
+}
+
+export interface AccountToken {
+  user_id: string;
+  /** The value of the Authorization cookie. */
+  token: string;
+  token_type: "registered";
+  expires_at: string;
+  /** The value of the Refresh-Token cookie, omitted if the storage does not support the token revocation. */
+  refresh_token?: string;
+  refresh_expires_at?: string;
+}
+
+export interface RefreshRequest {
+  /** Used if there is no Refresh-Token cookie. */
+  refresh_token?: string;
+}
+
+export interface ServiceToken {
+  user_id: string;
+  /** The value of the Authorization cookie. */
+  token: string;
+  token_type: "anonymous" | "registered" | "service";
+  role: Role;
+  expires_at: string;
+}
+
+export interface APIKeyRequest {
+  /** The name telling the keys of the user apart. */
+  name?: string;
+  /** The scopes the key is limited to. */
+  scopes?: Scope[];
+}
+
+export interface APIKey {
+  id: string;
+  name: string;
+  /** The key passed in the Authorization header with the ApiKey scheme. */
+  key: string;
+  /** The scopes the key is limited to, null if not limited. */
+  scopes: Scope[];
+  created_at: string;
+}
+
+export interface APIKeyUsage {
+  id: string;
+  name: string;
+  /** The scopes the key is limited to, null if not limited. */
+  scopes: Scope[];
+  created_at: string;
+  /** The time of the last request with the key, null if not used yet. */
+  last_used_at?: string;
+  /** The number of the requests with the key. */
+  requests: number;
+}
+
+/** The part of the API the key gives access to. The write scope
+includes shorten, the admin scope allows to manage the API keys. */
+export type Scope = "shorten" | "read" | "delete" | "stats" | "write" | "admin";
+
+export interface ShortenRequest {
+  /** The URL to shorten. */
+  url: string;
+  /** The custom short code used instead of the generated one. */
+  alias?: string;
+  /** The number of seconds the short URL redirects for. */
+  ttl?: number;
+  /** The redirects allowed per minute, not limited if zero. */
+  redirect_limit?: number;
+  /** The free-text annotation of the link. */
+  note?: string;
+  /** The configured domain of the short URL, the return address if empty. */
+  domain?: string;
+}
+
+export interface Error {
+  /** The machine-readable code: invalid_request, unauthorized,
+forbidden, not_found, conflict, gone, too_large, unprocessable,
+too_many_requests, internal, not_implemented or unavailable. */
+  code: string;
+  message: string;
+  /** The cause of the failure, never set for the server errors. */
+  details?: string;
+  /** The X-Request-ID header of the request or the generated ID. */
+  request_id?: string;
+}
+
+export interface HostBlocked {
+  /** The host matches the denylist, is missing from the allowlist
+or is of the domain banned by the moderators. */
+  error: "host_denied" | "host_not_allowed" | "host_banned";
+  host: string;
+  message: string;
+  /** The batch item of the blocked URL. */
+  correlation_id?: string;
+}
+
+export interface RedirectLimitRequest {
+  /** The redirects allowed per minute, not limited if zero. */
+  limit: number;
+}
+
+export interface UpdateURLRequest {
+  /** The new destination of the short URL. */
+  url?: string;
+  /** The new lifetime in seconds counted from now, zero removes the expiration. */
+  ttl?: number;
+  /** The version of the URL the edit is based on. */
+  version?: number;
+}
+
+export interface UpdatedURL {
+  short_url: string;
+  original_url: string;
+  expires_at?: string;
+  /** The version of the URL after the edit. */
+  version: number;
+}
+
+export interface NoteRequest {
+  /** The free-text annotation of the link, removed if empty. */
+  note: string;
+}
+
+/** The state of the link: paused by the owner, expired, deleted
+or blocked links don't redirect. */
+export type LinkState = "active" | "paused" | "expired" | "deleted" | "blocked";
+
+export interface LinkStateResponse {
+  short_url: string;
+  state: LinkState;
+}
+
+export interface CampaignURLsResponse {
+  /** The number of the URLs added to the campaign. */
+  added: number;
+}
+
+export interface CampaignTransferRequest {
+  /** The target campaign. */
+  to: string;
+}
+
+export interface CampaignTransfer {
+  /** The number of the links added to the target campaign. */
+  copied: number;
+  /** The number of the links whose destinations were already
+in the target campaign. */
+  skipped: number;
+  /** The number of the links removed from the source campaign. */
+  removed: number;
+}
+
+export interface ShortenResponse {
+  /** The short URL. */
+  result: string;
+  /** The error message if the shortening failed. */
+  message: string;
+  success: boolean;
+}
+
+export interface ShortenBatchRequestItem {
+  correlation_id: string;
+  original_url: string;
+  /** The number of seconds the short URL redirects for. */
+  ttl?: number;
+  /** The configured domain of the short URL, the return address if empty. */
+  domain?: string;
+}
+
+export interface ShortenBatchResponseItem {
+  correlation_id: string;
+  short_url: string;
+}
+
+export interface ExpandBatchResponseItem {
+  short_url: string;
+  original_url: string;
+}
+
+/** The details of the client that created the URL. */
+export interface Metadata {
+  creator_ip?: string;
+  user_agent?: string;
+  origin?: "api" | "text" | "import";
+}
+
+export interface UserURL {
+  short_url: string;
+  original_url: string;
+  last_accessed_at?: string;
+  expires_at?: string;
+  redirect_limit?: number;
+  note?: string;
+  state: LinkState;
+  metadata?: Metadata;
+  /** The number of the edits of the URL, see UpdateURL. */
+  version?: number;
+  /** Set for the read-only URLs shared with the user by their owners. */
+  shared?: boolean;
+}
+
+export interface URLDetails {
+  id: string;
+  short_url: string;
+  original_url: string;
+  user_id: string;
+  is_deleted: boolean;
+  last_accessed_at?: string;
+  expires_at?: string;
+  redirect_limit?: number;
+  note?: string;
+  /** The configured domain of the short URL, empty for the return address. */
+  domain?: string;
+  /** The state set to the URL, empty if never set. See LinkState. */
+  state?: "active" | "paused" | "blocked";
+  metadata: Metadata;
+  version?: number;
+  /** Missing for the URLs created before the creation times were kept. */
+  created_at?: string;
+}
+
+export interface DomainBanRequest {
+  /** The domain name, compared case-insensitively. Wildcards are not allowed. */
+  domain: string;
+  reason?: string;
+}
+
+export interface UserURLExport {
+  short_url: string;
+  original_url: string;
+  /** Missing for the URLs created before the creation times were kept. */
+  created_at?: string;
+  expires_at?: string;
+  last_accessed_at?: string;
+  is_deleted: boolean;
+  state: LinkState;
+  note?: string;
+  clicks: number;
+}
+
+export interface UserURLImportItem {
+  original_url: string;
+  /** The short URL used instead of the generated one. */
+  alias?: string;
+}
+
+export interface UserURLImport {
+  created: number;
+  exists: number;
+  invalid: number;
+  rows: UserURLImportRow[];
+}
+
+export interface UserURLImportRow {
+  /** The number of the row from 1, not counting the CSV header. */
+  row: number;
+  original_url: string;
+  alias?: string;
+  short_url?: string;
+  status: "created" | "exists" | "invalid";
+  /** Why the invalid row is skipped. */
+  error?: string;
+}
+
+export interface TransferRequest {
+  /** The ID of the new owner. */
+  to: string;
+}
+
+export interface URLShare {
+  short_url: string;
+  /** The user the URL is shared with. */
+  user_id: string;
+  created_at: string;
+}
+
+export interface DomainBan {
+  domain: string;
+  reason?: string;
+  created_at: string;
+}
+
+export type DomainBanResult = Record<string, unknown>;
+
+export interface Stats {
+  urls: number;
+  users: number;
+  /** Set if the numbers are estimated. */
+  approximate?: boolean;
+  /** The number of the users who created URLs during the days. */
+  active_users?: number;
+  /** The links most clicked during the days, most clicked first. */
+  top_links?: TopLink[];
+  /** The numbers of the URLs created on each of the days, oldest first. */
+  daily?: DailyTrend[];
+}
+
+export interface TopLink {
+  short_url: string;
+  clicks: number;
+}
+
+export interface DailyTrend {
+  date: string;
+  created_urls: number;
+  active_users: number;
+}
+
+export interface ClickStats {
+  short_url: string;
+  total_clicks: number;
+  daily: DailyClicks[];
+  last_accessed_at?: string;
+  /** The clicks by the device class of the user agent: desktop, mobile, tablet, bot or unknown. Most clicked first. */
+  devices?: Share[];
+  /** The clicks by the operating system. Most clicked first. */
+  os?: Share[];
+  /** The clicks by the browser. Most clicked first. */
+  browsers?: Share[];
+}
+
+export interface Share {
+  name: string;
+  clicks: number;
+}
+
+export interface DailyClicks {
+  /** The day in UTC formatted as YYYY-MM-DD. */
+  date: string;
+  clicks: number;
+}
+
+export interface FeedRequest {
+  /** The HTTP or HTTPS URL of the feed. */
+  url: string;
+  /** The name the short URLs are grouped under. */
+  campaign?: string;
+}
+
+export interface Feed {
+  id: string;
+  url: string;
+  campaign?: string;
+  /** The shortened items, latest last. */
+  items: FeedItem[];
+  /** The reason the last poll failed. */
+  error?: string;
+  last_polled_at?: string;
+  created_at: string;
+}
+
+export interface FeedItem {
+  /** The GUID of the item, the link if it has none. */
+  id: string;
+  title?: string;
+  link: string;
+  published_at?: string;
+  short_url: string;
+  shortened_at: string;
+}
+
+export interface Import {
+  id: string;
+  status: "uploading" | "queued" | "processing" | "done" | "failed";
+  /** The number of bytes uploaded. */
+  size: number;
+  imported: number;
+  failed: number;
+  /** The reason of the import failure. */
+  error?: string;
+  /** The URL to upload the file to, while the import is uploading. */
+  upload_url?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface QRExportRequest {
+  format?: "zip" | "pdf";
+  /** The codes of the links to export. */
+  short_urls?: string[];
+  /** Exports the links of the feeds of the campaign instead. */
+  campaign?: string;
+}
+
+export interface QRExport {
+  id: string;
+  format: "zip" | "pdf";
+  status: "queued" | "processing" | "done" | "failed";
+  /** The number of the rendered codes. */
+  links: number;
+  /** The reason of the export failure. */
+  error?: string;
+  /** The URL to download the file from, once the export is done. */
+  download_url?: string;
+  created_at: string;
+  updated_at: string;
+  /** The time the export and its file are removed. */
+  expires_at: string;
+}
+
+export interface ReservationsRequest {
+  user_id: string;
+  codes?: string[];
+  count?: number;
+}
+
+export interface ReservationsResponse {
+  codes: string[];
+}
+
+/** ClientResponse is the raw response of the API. */
+export interface ClientResponse {
+  status: number;
+  headers: Headers;
+  body: string;
+}
+
+/** ClientOptions configures the client. */
+export interface ClientOptions {
+  /** fetch sends the requests, the global fetch by default. */
+  fetch?: typeof fetch;
+  /** init is applied to every request, e.g. to set the credentials mode. */
+  init?: RequestInit;
+}
+
+function isJSON(res: ClientResponse): boolean {
+  return (res.headers.get("Content-Type") ?? "").startsWith("application/json");
+}
+
+/** Client is the Shortener API client. */
+export class Client {
+  private readonly server: string;
+  private readonly options: ClientOptions;
+
+  /** server is the base URL of the API, e.g. "http://localhost:8080". */
+  constructor(server: string, options: ClientOptions = {}) {
+    this.server = server.replace(/\/+$/, "");
+    this.options = options;
+  }
+
+  /**
+   * addCampaignURLs adds the URLs of the user to the campaign.
+   *
+   * The URLs are added in a single transaction. Short URLs of other
+   * users, deleted or already in the campaign are skipped. Requires
+   * the write scope for the scoped callers.
+   *
+   * POST /api/user/campaigns/{campaign}/urls
+   */
+  async addCampaignURLs(campaign: string, body: string[], init?: RequestInit): Promise<AddCampaignURLsResponse> {
+    const res: AddCampaignURLsResponse = await this.do("POST", `/api/user/campaigns/${encodeURIComponent(campaign)}/urls`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as CampaignURLsResponse;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * banDomain bans the destination domain and its subdomains.
+   *
+   * The URLs to the domain can't be shortened any more, they are rejected with the host_banned reason. The existing links to the domain are blocked. Requires the token of the admin role.
+   *
+   * POST /api/admin/domain-bans
+   */
+  async banDomain(body: DomainBanRequest, init?: RequestInit): Promise<BanDomainResponse> {
+    const res: BanDomainResponse = await this.do("POST", `/api/admin/domain-bans`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as DomainBanResult;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * completeImport finishes the upload and schedules the import.
+   *
+   * POST /api/user/imports/{id}/complete
+   */
+  async completeImport(id: string, init?: RequestInit): Promise<CompleteImportResponse> {
+    const res: CompleteImportResponse = await this.do("POST", `/api/user/imports/${encodeURIComponent(id)}/complete`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 202:
+          res.json202 = JSON.parse(res.body) as Import;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * copyCampaign copies the links of the campaign to the other campaign.
+   *
+   * The links are copied in a single transaction and deduplicated by
+   * their destinations: the ones already in the target campaign are
+   * skipped. Requires the write scope for the scoped callers.
+   *
+   * POST /api/user/campaigns/{campaign}/copy
+   */
+  async copyCampaign(campaign: string, body: CampaignTransferRequest, init?: RequestInit): Promise<CopyCampaignResponse> {
+    const res: CopyCampaignResponse = await this.do("POST", `/api/user/campaigns/${encodeURIComponent(campaign)}/copy`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as CampaignTransfer;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * createAPIKey mints a new API key of the user.
+   *
+   * The key is returned once, only its hash is stored. The key without
+   * the scopes has the scopes of the caller, i.e. no limits for the
+   * Authorization cookie. Requires the admin scope for the scoped callers,
+   * which can't mint the keys with other scopes.
+   *
+   * POST /api/user/keys
+   */
+  async createAPIKey(body: APIKeyRequest, init?: RequestInit): Promise<CreateAPIKeyResponse> {
+    const res: CreateAPIKeyResponse = await this.do("POST", `/api/user/keys`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as APIKey;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * createFeed subscribes to RSS or Atom feed.
+   *
+   * The feed is polled right away and then on schedule. The link of
+   * every new item is shortened on behalf of the user.
+   *
+   * POST /api/user/feeds
+   */
+  async createFeed(body: FeedRequest, init?: RequestInit): Promise<CreateFeedResponse> {
+    const res: CreateFeedResponse = await this.do("POST", `/api/user/feeds`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as Feed;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * createImport starts the bulk import of URLs.
+   *
+   * The file with one URL per line is uploaded to the returned
+   * upload URL in chunks, then the import is completed.
+   *
+   * POST /api/user/imports
+   */
+  async createImport(init?: RequestInit): Promise<CreateImportResponse> {
+    const res: CreateImportResponse = await this.do("POST", `/api/user/imports`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as Import;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * createQRExport queues the bulk export of the QR codes.
+   *
+   * Renders the QR codes of the listed short URLs or of all the links
+   * of the feeds of the campaign in the background, as a ZIP of PNG
+   * images or a printable PDF sheet. The status is polled until
+   * the download URL is returned.
+   *
+   * POST /api/user/qr-exports
+   */
+  async createQRExport(body: QRExportRequest, init?: RequestInit): Promise<CreateQRExportResponse> {
+    const res: CreateQRExportResponse = await this.do("POST", `/api/user/qr-exports`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 202:
+          res.json202 = JSON.parse(res.body) as QRExport;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * createServiceToken issues the service token of the user.
+   *
+   * The token is passed in the Authorization cookie and expires as configured for the service tokens. A new user is created if the user ID is omitted. The token of the admin role moderates the URLs of all the users. Available only from the trusted subnet. The tokens of the given users and of the admin role also require the token of the admin role, the first one is minted with 'shortenerctl token'.
+   *
+   * POST /api/admin/tokens
+   */
+  async createServiceToken(body: ServiceTokenRequest, init?: RequestInit): Promise<CreateServiceTokenResponse> {
+    const res: CreateServiceTokenResponse = await this.do("POST", `/api/admin/tokens`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as ServiceToken;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * deleteAnyURL deletes the short URL of any user at once.
+   *
+   * Requires the token of the admin role.
+   *
+   * DELETE /api/admin/urls/{shortURL}
+   */
+  async deleteAnyURL(shortURL: string, init?: RequestInit): Promise<DeleteAnyURLResponse> {
+    const res: DeleteAnyURLResponse = await this.do("DELETE", `/api/admin/urls/${encodeURIComponent(shortURL)}`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * deleteFeed unsubscribes from the feed, the short URLs are kept.
+   *
+   * DELETE /api/user/feeds/{id}
+   */
+  async deleteFeed(id: string, init?: RequestInit): Promise<DeleteFeedResponse> {
+    const res: DeleteFeedResponse = await this.do("DELETE", `/api/user/feeds/${encodeURIComponent(id)}`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * deleteUserURLs schedules the deletion of the URLs of the user.
+   *
+   * DELETE /api/user/urls
+   */
+  async deleteUserURLs(body: string[], sync?: boolean, init?: RequestInit): Promise<DeleteUserURLsResponse> {
+    const query = new URLSearchParams();
+    if (sync !== undefined) query.set("sync", String(sync));
+    const res: DeleteUserURLsResponse = await this.do("DELETE", `/api/user/urls` + (query.toString() ? `?${query}` : ""), { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as DeleteURLResult[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * deleteUserURLsByOriginal schedules the deletion of the URLs of the user by their original URLs.
+   *
+   * Every short URL of the user pointing to one of the original URLs
+   * is deleted. Original URLs the user has not shortened are skipped.
+   *
+   * DELETE /api/user/urls/by-original
+   */
+  async deleteUserURLsByOriginal(body: string[], init?: RequestInit): Promise<DeleteUserURLsByOriginalResponse> {
+    const res: DeleteUserURLsByOriginalResponse = await this.do("DELETE", `/api/user/urls/by-original`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 202:
+          res.json202 = JSON.parse(res.body) as DeleteByOriginalResult[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * downloadQRExport downloads the rendered file of the QR export.
+   *
+   * GET /api/user/qr-exports/{id}/download
+   */
+  async downloadQRExport(id: string, init?: RequestInit): Promise<DownloadQRExportResponse> {
+    const res: DownloadQRExportResponse = await this.do("GET", `/api/user/qr-exports/${encodeURIComponent(id)}/download`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * expandBatch returns the original URLs of multiple short URLs.
+   *
+   * Unknown and deleted short URLs are omitted from the response,
+   * the rest keep the order of the request.
+   *
+   * POST /api/expand/batch
+   */
+  async expandBatch(body: string[], init?: RequestInit): Promise<ExpandBatchResponse> {
+    const res: ExpandBatchResponse = await this.do("POST", `/api/expand/batch`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as ExpandBatchResponseItem[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * exportInstance exports the whole instance.
+   *
+   * Streams a tar.gz archive with all the URL records of all the users and the settings they depend on, restored with 'shortenerctl import' into an instance with any storage backend. Available from the trusted subnet or with the token of the admin role.
+   *
+   * GET /api/admin/export
+   */
+  async exportInstance(init?: RequestInit): Promise<ExportInstanceResponse> {
+    const res: ExportInstanceResponse = await this.do("GET", `/api/admin/export`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * exportUserURLs streams all the URLs of the user as a JSON array or a CSV file.
+   *
+   * The URLs, including the deleted ones, are ordered by their short URLs
+   * and sent in batches. The CSV file starts with the header of the
+   * columns named as the fields of UserURLExport. The failure after
+   * the first batch is sent truncates the export.
+   *
+   * GET /api/user/urls/export
+   */
+  async exportUserURLs(format?: "json" | "csv", tz?: string, init?: RequestInit): Promise<ExportUserURLsResponse> {
+    const query = new URLSearchParams();
+    if (format !== undefined) query.set("format", String(format));
+    if (tz !== undefined) query.set("tz", String(tz));
+    const res: ExportUserURLsResponse = await this.do("GET", `/api/user/urls/export` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as UserURLExport[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getAnyUserURLs returns the full records of the URLs of any user.
+   *
+   * Includes the deleted URLs, ordered by the short URLs. Requires the token of the admin role.
+   *
+   * GET /api/admin/users/{userID}/urls
+   */
+  async getAnyUserURLs(userID: string, limit?: number, offset?: number, init?: RequestInit): Promise<GetAnyUserURLsResponse> {
+    const query = new URLSearchParams();
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetAnyUserURLsResponse = await this.do("GET", `/api/admin/users/${encodeURIComponent(userID)}/urls` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as URLDetails[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getCampaignURLs returns the URLs of the user in the campaign.
+   *
+   * The campaign without links is empty. Requires the read scope
+   * for the scoped callers.
+   *
+   * GET /api/user/campaigns/{campaign}/urls
+   */
+  async getCampaignURLs(campaign: string, limit?: number, offset?: number, init?: RequestInit): Promise<GetCampaignURLsResponse> {
+    const query = new URLSearchParams();
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetCampaignURLsResponse = await this.do("GET", `/api/user/campaigns/${encodeURIComponent(campaign)}/urls` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as UserURL[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getCertificates returns the health of the served TLS certificates.
+   *
+   * The certificates are loaded from the files or issued by Let's Encrypt. The ones expiring within the configured warning are expiring, the ones issued by Let's Encrypt not renewed a day after the renewal time are renewal_overdue. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/tls/certificates
+   */
+  async getCertificates(init?: RequestInit): Promise<GetCertificatesResponse> {
+    const res: GetCertificatesResponse = await this.do("GET", `/api/internal/tls/certificates`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as CertificateHealth[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getDNSMetrics returns the metrics of the DNS cache.
+   *
+   * The hostnames resolved by the outbound requests, e.g. of the feeds and the click export, are cached for the configured TTLs, the failed lookups for the negative TTL. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/dns/metrics
+   */
+  async getDNSMetrics(init?: RequestInit): Promise<GetDNSMetricsResponse> {
+    const res: GetDNSMetricsResponse = await this.do("GET", `/api/internal/dns/metrics`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as DNSMetrics;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getDeletionMetrics returns the metrics of the asynchronous deletion.
+   *
+   * The batches of the deleted URLs are flushed once they reach the size adapted to the arrival rate of the deletions and the latency of the storage, or once the oldest deletion has waited for the maximum delay. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/deletions/metrics
+   */
+  async getDeletionMetrics(init?: RequestInit): Promise<GetDeletionMetricsResponse> {
+    const res: GetDeletionMetricsResponse = await this.do("GET", `/api/internal/deletions/metrics`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as DeletionMetrics;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getDomainBans returns the banned destination domains.
+   *
+   * In the order they were banned. Requires the token of the admin role.
+   *
+   * GET /api/admin/domain-bans
+   */
+  async getDomainBans(limit?: number, offset?: number, init?: RequestInit): Promise<GetDomainBansResponse> {
+    const query = new URLSearchParams();
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetDomainBansResponse = await this.do("GET", `/api/admin/domain-bans` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as DomainBan[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getFeed returns the feed with the short URLs of its items.
+   *
+   * GET /api/user/feeds/{id}
+   */
+  async getFeed(id: string, init?: RequestInit): Promise<GetFeedResponse> {
+    const res: GetFeedResponse = await this.do("GET", `/api/user/feeds/${encodeURIComponent(id)}`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as Feed;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getFlaggedURLs returns the full records of the blocked URLs.
+   *
+   * Excludes the deleted URLs. Requires the token of the admin role.
+   *
+   * GET /api/admin/flagged
+   */
+  async getFlaggedURLs(limit?: number, offset?: number, init?: RequestInit): Promise<GetFlaggedURLsResponse> {
+    const query = new URLSearchParams();
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetFlaggedURLsResponse = await this.do("GET", `/api/admin/flagged` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as URLDetails[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getImport returns the status of the import.
+   *
+   * GET /api/user/imports/{id}
+   */
+  async getImport(id: string, init?: RequestInit): Promise<GetImportResponse> {
+    const res: GetImportResponse = await this.do("GET", `/api/user/imports/${encodeURIComponent(id)}`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as Import;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getPoolMetrics returns the stats of the pool of the database connections.
+   *
+   * The pool is limited by the postgres settings of the configuration. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/db/metrics
+   */
+  async getPoolMetrics(init?: RequestInit): Promise<GetPoolMetricsResponse> {
+    const res: GetPoolMetricsResponse = await this.do("GET", `/api/internal/db/metrics`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as PoolMetrics;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getQRExport returns the status of the QR export.
+   *
+   * GET /api/user/qr-exports/{id}
+   */
+  async getQRExport(id: string, init?: RequestInit): Promise<GetQRExportResponse> {
+    const res: GetQRExportResponse = await this.do("GET", `/api/user/qr-exports/${encodeURIComponent(id)}`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as QRExport;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getSLO returns the service level indicators.
+   *
+   * The availability of the requests and the latency of the redirects over the rolling windows of 5 minutes, 1 hour and 6 hours, with the burn rates of the error budgets of the configured objectives and the counters growing since the start. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/slo
+   */
+  async getSLO(init?: RequestInit): Promise<GetSLOResponse> {
+    const res: GetSLOResponse = await this.do("GET", `/api/internal/slo`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as SLOReport;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getStats returns the numbers of the URLs and the users.
+   *
+   * The number of the short URLs not deleted and the number of the users owning them. The numbers may be estimated if the server is configured so. If the storage supports them, the statistics are extended with the number of the users who created URLs, the most clicked links and the daily numbers of the created URLs for the last days in UTC. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/stats
+   */
+  async getStats(days?: number, top?: number, init?: RequestInit): Promise<GetStatsResponse> {
+    const query = new URLSearchParams();
+    if (days !== undefined) query.set("days", String(days));
+    if (top !== undefined) query.set("top", String(top));
+    const res: GetStatsResponse = await this.do("GET", `/api/internal/stats` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as Stats;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getStatsTrends returns the daily creation trends.
+   *
+   * The numbers of the created URLs and of the users who created them for the last days in UTC, today included, oldest first. Days without created URLs have zero counts. Available from the trusted subnet or with the token of the service role.
+   *
+   * GET /api/internal/stats/trends
+   */
+  async getStatsTrends(days?: number, init?: RequestInit): Promise<GetStatsTrendsResponse> {
+    const query = new URLSearchParams();
+    if (days !== undefined) query.set("days", String(days));
+    const res: GetStatsTrendsResponse = await this.do("GET", `/api/internal/stats/trends` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as DailyTrend[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getURLDetails returns the full record of the short URL.
+   *
+   * Available from the trusted subnet or with the token of the admin role.
+   *
+   * GET /api/admin/urls/{shortURL}
+   */
+  async getURLDetails(shortURL: string, init?: RequestInit): Promise<GetURLDetailsResponse> {
+    const res: GetURLDetailsResponse = await this.do("GET", `/api/admin/urls/${encodeURIComponent(shortURL)}`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as URLDetails;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getURLShares returns the users the URL of the user is shared with.
+   *
+   * GET /api/user/urls/{shortURL}/shares
+   */
+  async getURLShares(shortURL: string, limit?: number, offset?: number, init?: RequestInit): Promise<GetURLSharesResponse> {
+    const query = new URLSearchParams();
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetURLSharesResponse = await this.do("GET", `/api/user/urls/${encodeURIComponent(shortURL)}/shares` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as URLShare[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getURLStats returns the click statistics of the URL of the user.
+   *
+   * Clicks are saved in the background, so the latest redirects
+   * may be missing for a few seconds.
+   *
+   * GET /api/user/urls/{shortURL}/stats
+   */
+  async getURLStats(shortURL: string, fields?: string, init?: RequestInit): Promise<GetURLStatsResponse> {
+    const query = new URLSearchParams();
+    if (fields !== undefined) query.set("fields", String(fields));
+    const res: GetURLStatsResponse = await this.do("GET", `/api/user/urls/${encodeURIComponent(shortURL)}/stats` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as ClickStats;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * getUserURLs returns the URLs of the user.
+   *
+   * GET /api/user/urls
+   */
+  async getUserURLs(fields?: string, q?: string, limit?: number, offset?: number, init?: RequestInit): Promise<GetUserURLsResponse> {
+    const query = new URLSearchParams();
+    if (fields !== undefined) query.set("fields", String(fields));
+    if (q !== undefined) query.set("q", String(q));
+    if (limit !== undefined) query.set("limit", String(limit));
+    if (offset !== undefined) query.set("offset", String(offset));
+    const res: GetUserURLsResponse = await this.do("GET", `/api/user/urls` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as UserURL[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * healthz liveness probe, doesn't check the storage.
+   *
+   * GET /healthz
+   */
+  async healthz(init?: RequestInit): Promise<HealthzResponse> {
+    const res: HealthzResponse = await this.do("GET", `/healthz`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * importUserURLs shortens the URLs of the uploaded CSV or JSON file.
+   *
+   * The file field of the form is either CSV of the original URLs and
+   * the optional aliases, with the optional original_url,alias header,
+   * or JSON array of UserURLImportItem. The format is told by the
+   * extension of the file name or its content type. The invalid rows
+   * are skipped, the ones conflicting with the stored URLs are reported
+   * as existing. The Content-Type header with the boundary of the form
+   * is set by the request editor. Requires the shorten scope for
+   * the scoped callers.
+   *
+   * POST /api/user/urls/import
+   */
+  async importUserURLs(body: BodyInit, init?: RequestInit): Promise<ImportUserURLsResponse> {
+    const res: ImportUserURLsResponse = await this.do("POST", `/api/user/urls/import`, { "Content-Type": "multipart/form-data" }, body, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as UserURLImport;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * listAPIKeys returns the API keys of the user with their usage.
+   *
+   * The keys themselves are never returned. Requires the admin scope
+   * for the scoped callers.
+   *
+   * GET /api/user/keys
+   */
+  async listAPIKeys(init?: RequestInit): Promise<ListAPIKeysResponse> {
+    const res: ListAPIKeysResponse = await this.do("GET", `/api/user/keys`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as APIKeyUsage[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * listFeeds returns the feeds of the user.
+   *
+   * GET /api/user/feeds
+   */
+  async listFeeds(init?: RequestInit): Promise<ListFeedsResponse> {
+    const res: ListFeedsResponse = await this.do("GET", `/api/user/feeds`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as Feed[];
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * login authenticates the user with the login and the password.
+   *
+   * The tokens of the user are passed in the cookies as on the registration.
+   *
+   * POST /api/auth/login
+   */
+  async login(body: Credentials, init?: RequestInit): Promise<LoginResponse> {
+    const res: LoginResponse = await this.do("POST", `/api/auth/login`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as AccountToken;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * logout revokes the tokens of the user.
+   *
+   * Revokes the token the request is authenticated with and the refresh token of the Refresh-Token cookie or of the request body, and removes their cookies. The revoked tokens are rejected with 401 Unauthorized.
+   *
+   * POST /api/auth/logout
+   */
+  async logout(body: RefreshRequest, init?: RequestInit): Promise<LogoutResponse> {
+    const res: LogoutResponse = await this.do("POST", `/api/auth/logout`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    return res;
+  }
+
+  /**
+   * moveCampaign moves the links of the campaign to the other campaign.
+   *
+   * Copies the links like CopyCampaign and then empties the source
+   * campaign in the same transaction. Requires the write scope for
+   * the scoped callers.
+   *
+   * POST /api/user/campaigns/{campaign}/move
+   */
+  async moveCampaign(campaign: string, body: CampaignTransferRequest, init?: RequestInit): Promise<MoveCampaignResponse> {
+    const res: MoveCampaignResponse = await this.do("POST", `/api/user/campaigns/${encodeURIComponent(campaign)}/move`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as CampaignTransfer;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * pauseURL pauses the active URL of the user.
+   *
+   * The paused URL responds with 503 instead of the redirect until it is
+   * resumed. Requires the write scope for the scoped callers.
+   *
+   * POST /api/user/urls/{shortURL}/pause
+   */
+  async pauseURL(shortURL: string, init?: RequestInit): Promise<PauseURLResponse> {
+    const res: PauseURLResponse = await this.do("POST", `/api/user/urls/${encodeURIComponent(shortURL)}/pause`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as LinkStateResponse;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * ping checks the connection to the database.
+   *
+   * GET /ping
+   */
+  async ping(init?: RequestInit): Promise<PingResponse> {
+    const res: PingResponse = await this.do("GET", `/ping`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * readyz readiness probe, checks the storage.
+   *
+   * GET /readyz
+   */
+  async readyz(verbose?: string, init?: RequestInit): Promise<ReadyzResponse> {
+    const query = new URLSearchParams();
+    if (verbose !== undefined) query.set("verbose", String(verbose));
+    const res: ReadyzResponse = await this.do("GET", `/readyz` + (query.toString() ? `?${query}` : ""), {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * redirect redirects to the original URL.
+   *
+   * GET /{shortURL}
+   */
+  async redirect(shortURL: string, init?: RequestInit): Promise<RedirectResponse> {
+    const res: RedirectResponse = await this.do("GET", `/${encodeURIComponent(shortURL)}`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * refresh issues the new tokens for the refresh token.
+   *
+   * The refresh token is taken from the Refresh-Token cookie or from the request body. It is revoked, so every refresh token is exchanged once.
+   *
+   * POST /api/auth/refresh
+   */
+  async refresh(body: RefreshRequest, init?: RequestInit): Promise<RefreshResponse> {
+    const res: RefreshResponse = await this.do("POST", `/api/auth/refresh`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as AccountToken;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * register creates the account of the user.
+   *
+   * The account keeps the ID of the user the request is authenticated with, so the URLs shortened anonymously stay with the user. The registered token is passed in the Authorization cookie and, if the storage supports the token revocation, the refresh token in the Refresh-Token cookie. Scoped tokens can't register.
+   *
+   * POST /api/auth/register
+   */
+  async register(body: Credentials, init?: RequestInit): Promise<RegisterResponse> {
+    const res: RegisterResponse = await this.do("POST", `/api/auth/register`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as AccountToken;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * reserveCodes reserves short codes for the user.
+   *
+   * Available from the trusted subnet or with the token of the admin role.
+   *
+   * POST /api/admin/reservations
+   */
+  async reserveCodes(body: ReservationsRequest, init?: RequestInit): Promise<ReserveCodesResponse> {
+    const res: ReserveCodesResponse = await this.do("POST", `/api/admin/reservations`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as ReservationsResponse;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * resumeURL resumes the paused URL of the user.
+   *
+   * Requires the write scope for the scoped callers.
+   *
+   * POST /api/user/urls/{shortURL}/resume
+   */
+  async resumeURL(shortURL: string, init?: RequestInit): Promise<ResumeURLResponse> {
+    const res: ResumeURLResponse = await this.do("POST", `/api/user/urls/${encodeURIComponent(shortURL)}/resume`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as LinkStateResponse;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * rotateAPIKey replaces the API key with the new one.
+   *
+   * The name, scopes and usage are kept. The old key is rejected at once,
+   * the new one is returned once. Requires the admin scope for the scoped
+   * callers, which can rotate the keys within their scopes only.
+   *
+   * POST /api/user/keys/{id}/rotate
+   */
+  async rotateAPIKey(id: string, init?: RequestInit): Promise<RotateAPIKeyResponse> {
+    const res: RotateAPIKeyResponse = await this.do("POST", `/api/user/keys/${encodeURIComponent(id)}/rotate`, {}, undefined, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as APIKey;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * setNote annotates the URL of the user with the free-text note.
+   *
+   * Empty note removes it. Requires the write scope for the scoped callers.
+   *
+   * PUT /api/user/urls/{shortURL}/note
+   */
+  async setNote(shortURL: string, body: NoteRequest, init?: RequestInit): Promise<SetNoteResponse> {
+    const res: SetNoteResponse = await this.do("PUT", `/api/user/urls/${encodeURIComponent(shortURL)}/note`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    return res;
+  }
+
+  /**
+   * setRedirectLimit caps the redirects per minute of the URL of the user.
+   *
+   * The redirects over the limit respond with 429 Too Many Requests.
+   * Zero limit removes the cap. Requires the write scope for the scoped
+   * callers.
+   *
+   * PUT /api/user/urls/{shortURL}/redirect-limit
+   */
+  async setRedirectLimit(shortURL: string, body: RedirectLimitRequest, init?: RequestInit): Promise<SetRedirectLimitResponse> {
+    const res: SetRedirectLimitResponse = await this.do("PUT", `/api/user/urls/${encodeURIComponent(shortURL)}/redirect-limit`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    return res;
+  }
+
+  /**
+   * shareURL grants the user the read access to the URL of the caller.
+   *
+   * The shared URL is listed among the URLs of the user, but can't be
+   * edited, deleted or transferred by them. Sharing the URL again keeps
+   * the original share. Requires the write scope for the scoped callers.
+   *
+   * PUT /api/user/urls/{shortURL}/shares/{userID}
+   */
+  async shareURL(shortURL: string, userID: string, init?: RequestInit): Promise<ShareURLResponse> {
+    const res: ShareURLResponse = await this.do("PUT", `/api/user/urls/${encodeURIComponent(shortURL)}/shares/${encodeURIComponent(userID)}`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * shortenBatch shortens multiple URLs.
+   *
+   * POST /api/shorten/batch
+   */
+  async shortenBatch(body: ShortenBatchRequestItem[], idempotencyKey?: string, init?: RequestInit): Promise<ShortenBatchResponse> {
+    const res: ShortenBatchResponse = await this.do("POST", `/api/shorten/batch`, { "Content-Type": "application/json", ...(idempotencyKey !== undefined ? { "Idempotency-Key": String(idempotencyKey) } : {}) }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as ShortenBatchResponseItem[];
+          break;
+        case 422:
+          res.json422 = JSON.parse(res.body) as HostBlocked;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * shortenJSON shortens the URL, optionally with the custom alias.
+   *
+   * POST /api/shorten
+   */
+  async shortenJSON(body: ShortenRequest, idempotencyKey?: string, init?: RequestInit): Promise<ShortenJSONResponse> {
+    const res: ShortenJSONResponse = await this.do("POST", `/api/shorten`, { "Content-Type": "application/json", ...(idempotencyKey !== undefined ? { "Idempotency-Key": String(idempotencyKey) } : {}) }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 201:
+          res.json201 = JSON.parse(res.body) as ShortenResponse;
+          break;
+        case 409:
+          res.json409 = JSON.parse(res.body) as ShortenResponse;
+          break;
+        case 400:
+          res.json400 = JSON.parse(res.body) as ShortenResponse;
+          break;
+        case 422:
+          res.json422 = JSON.parse(res.body) as HostBlocked;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * shortenText shortens the URL given as plain text.
+   *
+   * POST /
+   */
+  async shortenText(body: string, ttl?: number, domain?: string, init?: RequestInit): Promise<ShortenTextResponse> {
+    const query = new URLSearchParams();
+    if (ttl !== undefined) query.set("ttl", String(ttl));
+    if (domain !== undefined) query.set("domain", String(domain));
+    const res: ShortenTextResponse = await this.do("POST", `/` + (query.toString() ? `?${query}` : ""), { "Content-Type": "text/plain" }, body, init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 422:
+          res.json422 = JSON.parse(res.body) as HostBlocked;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * transferURL hands the URL of the user over to another user.
+   *
+   * The shares of the URL are dropped and it is removed from the campaigns
+   * of the user. Requires the write scope for the scoped callers.
+   *
+   * POST /api/user/urls/{shortURL}/transfer
+   */
+  async transferURL(shortURL: string, body: TransferRequest, init?: RequestInit): Promise<TransferURLResponse> {
+    const res: TransferURLResponse = await this.do("POST", `/api/user/urls/${encodeURIComponent(shortURL)}/transfer`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    return res;
+  }
+
+  /**
+   * unshareURL revokes the read access of the user to the URL of the caller.
+   *
+   * Requires the write scope for the scoped callers.
+   *
+   * DELETE /api/user/urls/{shortURL}/shares/{userID}
+   */
+  async unshareURL(shortURL: string, userID: string, init?: RequestInit): Promise<UnshareURLResponse> {
+    const res: UnshareURLResponse = await this.do("DELETE", `/api/user/urls/${encodeURIComponent(shortURL)}/shares/${encodeURIComponent(userID)}`, {}, undefined, init);
+    return res;
+  }
+
+  /**
+   * updateURL changes the destination or the expiration of the URL of the user.
+   *
+   * The edit is applied to the given version of the URL only, so that
+   * the concurrent edits are not lost. The current version is used if
+   * it is omitted. The omitted fields are left unchanged. Requires
+   * the write scope for the scoped callers.
+   *
+   * PATCH /api/user/urls/{shortURL}
+   */
+  async updateURL(shortURL: string, body: UpdateURLRequest, init?: RequestInit): Promise<UpdateURLResponse> {
+    const res: UpdateURLResponse = await this.do("PATCH", `/api/user/urls/${encodeURIComponent(shortURL)}`, { "Content-Type": "application/json" }, JSON.stringify(body), init);
+    if (isJSON(res)) {
+      switch (res.status) {
+        case 200:
+          res.json200 = JSON.parse(res.body) as UpdatedURL;
+          break;
+        case 422:
+          res.json422 = JSON.parse(res.body) as HostBlocked;
+          break;
+      }
+    }
+    return res;
+  }
+
+  /**
+   * uploadImportChunk appends the chunk of the file to the import.
+   *
+   * PATCH /api/user/imports/{id}
+   */
+  async uploadImportChunk(id: string, uploadOffset: number, body: BodyInit, init?: RequestInit): Promise<UploadImportChunkResponse> {
+    const res: UploadImportChunkResponse = await this.do("PATCH", `/api/user/imports/${encodeURIComponent(id)}`, { "Content-Type": "application/octet-stream", "Upload-Offset": String(uploadOffset) }, body, init);
+    return res;
+  }
+
+  private async do(
+    method: string,
+    path: string,
+    headers: Record<string, string>,
+    body: BodyInit | undefined,
+    init?: RequestInit,
+  ): Promise<ClientResponse> {
+    const merged = new Headers(this.options.init?.headers);
+    new Headers(init?.headers).forEach((value, key) => merged.set(key, value));
+    Object.entries(headers).forEach(([key, value]) => merged.set(key, value));
+
+    const send = this.options.fetch ?? fetch;
+    const response = await send(this.server + path, {
+      credentials: "include",
+      redirect: "manual",
+      ...this.options.init,
+      ...init,
+      method,
+      headers: merged,
+      body,
+    });
+    return { status: response.status, headers: response.headers, body: await response.text() };
+  }
+}
+
+/** AddCampaignURLsResponse is the response of addCampaignURLs. */
+export interface AddCampaignURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: CampaignURLsResponse;
+}
+
+/** BanDomainResponse is the response of banDomain. */
+export interface BanDomainResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: DomainBanResult;
+}
+
+/** CompleteImportResponse is the response of completeImport. */
+export interface CompleteImportResponse extends ClientResponse {
+  /** json202 is the decoded body of the 202 response. */
+  json202?: Import;
+}
+
+/** CopyCampaignResponse is the response of copyCampaign. */
+export interface CopyCampaignResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: CampaignTransfer;
+}
+
+/** CreateAPIKeyResponse is the response of createAPIKey. */
+export interface CreateAPIKeyResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: APIKey;
+}
+
+/** CreateFeedResponse is the response of createFeed. */
+export interface CreateFeedResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: Feed;
+}
+
+/** CreateImportResponse is the response of createImport. */
+export interface CreateImportResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: Import;
+}
+
+/** CreateQRExportResponse is the response of createQRExport. */
+export interface CreateQRExportResponse extends ClientResponse {
+  /** json202 is the decoded body of the 202 response. */
+  json202?: QRExport;
+}
+
+/** CreateServiceTokenResponse is the response of createServiceToken. */
+export interface CreateServiceTokenResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: ServiceToken;
+}
+
+/** DeleteAnyURLResponse is the response of deleteAnyURL. */
+export interface DeleteAnyURLResponse extends ClientResponse {
+}
+
+/** DeleteFeedResponse is the response of deleteFeed. */
+export interface DeleteFeedResponse extends ClientResponse {
+}
+
+/** DeleteUserURLsResponse is the response of deleteUserURLs. */
+export interface DeleteUserURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: DeleteURLResult[];
+}
+
+/** DeleteUserURLsByOriginalResponse is the response of deleteUserURLsByOriginal. */
+export interface DeleteUserURLsByOriginalResponse extends ClientResponse {
+  /** json202 is the decoded body of the 202 response. */
+  json202?: DeleteByOriginalResult[];
+}
+
+/** DownloadQRExportResponse is the response of downloadQRExport. */
+export interface DownloadQRExportResponse extends ClientResponse {
+}
+
+/** ExpandBatchResponse is the response of expandBatch. */
+export interface ExpandBatchResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: ExpandBatchResponseItem[];
+}
+
+/** ExportInstanceResponse is the response of exportInstance. */
+export interface ExportInstanceResponse extends ClientResponse {
+}
+
+/** ExportUserURLsResponse is the response of exportUserURLs. */
+export interface ExportUserURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: UserURLExport[];
+}
+
+/** GetAnyUserURLsResponse is the response of getAnyUserURLs. */
+export interface GetAnyUserURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: URLDetails[];
+}
+
+/** GetCampaignURLsResponse is the response of getCampaignURLs. */
+export interface GetCampaignURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: UserURL[];
+}
+
+/** GetCertificatesResponse is the response of getCertificates. */
+export interface GetCertificatesResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: CertificateHealth[];
+}
+
+/** GetDNSMetricsResponse is the response of getDNSMetrics. */
+export interface GetDNSMetricsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: DNSMetrics;
+}
+
+/** GetDeletionMetricsResponse is the response of getDeletionMetrics. */
+export interface GetDeletionMetricsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: DeletionMetrics;
+}
+
+/** GetDomainBansResponse is the response of getDomainBans. */
+export interface GetDomainBansResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: DomainBan[];
+}
+
+/** GetFeedResponse is the response of getFeed. */
+export interface GetFeedResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: Feed;
+}
+
+/** GetFlaggedURLsResponse is the response of getFlaggedURLs. */
+export interface GetFlaggedURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: URLDetails[];
+}
+
+/** GetImportResponse is the response of getImport. */
+export interface GetImportResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: Import;
+}
+
+/** GetPoolMetricsResponse is the response of getPoolMetrics. */
+export interface GetPoolMetricsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: PoolMetrics;
+}
+
+/** GetQRExportResponse is the response of getQRExport. */
+export interface GetQRExportResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: QRExport;
+}
+
+/** GetSLOResponse is the response of getSLO. */
+export interface GetSLOResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: SLOReport;
+}
+
+/** GetStatsResponse is the response of getStats. */
+export interface GetStatsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: Stats;
+}
+
+/** GetStatsTrendsResponse is the response of getStatsTrends. */
+export interface GetStatsTrendsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: DailyTrend[];
+}
+
+/** GetURLDetailsResponse is the response of getURLDetails. */
+export interface GetURLDetailsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: URLDetails;
+}
+
+/** GetURLSharesResponse is the response of getURLShares. */
+export interface GetURLSharesResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: URLShare[];
+}
+
+/** GetURLStatsResponse is the response of getURLStats. */
+export interface GetURLStatsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: ClickStats;
+}
+
+/** GetUserURLsResponse is the response of getUserURLs. */
+export interface GetUserURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: UserURL[];
+}
+
+/** HealthzResponse is the response of healthz. */
+export interface HealthzResponse extends ClientResponse {
+}
+
+/** ImportUserURLsResponse is the response of importUserURLs. */
+export interface ImportUserURLsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: UserURLImport;
+}
+
+/** ListAPIKeysResponse is the response of listAPIKeys. */
+export interface ListAPIKeysResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: APIKeyUsage[];
+}
+
+/** ListFeedsResponse is the response of listFeeds. */
+export interface ListFeedsResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: Feed[];
+}
+
+/** LoginResponse is the response of login. */
+export interface LoginResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: AccountToken;
+}
+
+/** LogoutResponse is the response of logout. */
+export interface LogoutResponse extends ClientResponse {
+}
+
+/** MoveCampaignResponse is the response of moveCampaign. */
+export interface MoveCampaignResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: CampaignTransfer;
+}
+
+/** PauseURLResponse is the response of pauseURL. */
+export interface PauseURLResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: LinkStateResponse;
+}
+
+/** PingResponse is the response of ping. */
+export interface PingResponse extends ClientResponse {
+}
+
+/** ReadyzResponse is the response of readyz. */
+export interface ReadyzResponse extends ClientResponse {
+}
+
+/** RedirectResponse is the response of redirect. */
+export interface RedirectResponse extends ClientResponse {
+}
+
+/** RefreshResponse is the response of refresh. */
+export interface RefreshResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: AccountToken;
+}
+
+/** RegisterResponse is the response of register. */
+export interface RegisterResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: AccountToken;
+}
+
+/** ReserveCodesResponse is the response of reserveCodes. */
+export interface ReserveCodesResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: ReservationsResponse;
+}
+
+/** ResumeURLResponse is the response of resumeURL. */
+export interface ResumeURLResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: LinkStateResponse;
+}
+
+/** RotateAPIKeyResponse is the response of rotateAPIKey. */
+export interface RotateAPIKeyResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: APIKey;
+}
+
+/** SetNoteResponse is the response of setNote. */
+export interface SetNoteResponse extends ClientResponse {
+}
+
+/** SetRedirectLimitResponse is the response of setRedirectLimit. */
+export interface SetRedirectLimitResponse extends ClientResponse {
+}
+
+/** ShareURLResponse is the response of shareURL. */
+export interface ShareURLResponse extends ClientResponse {
+}
+
+/** ShortenBatchResponse is the response of shortenBatch. */
+export interface ShortenBatchResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: ShortenBatchResponseItem[];
+  /** json422 is the decoded body of the 422 response. */
+  json422?: HostBlocked;
+}
+
+/** ShortenJSONResponse is the response of shortenJSON. */
+export interface ShortenJSONResponse extends ClientResponse {
+  /** json201 is the decoded body of the 201 response. */
+  json201?: ShortenResponse;
+  /** json409 is the decoded body of the 409 response. */
+  json409?: ShortenResponse;
+  /** json400 is the decoded body of the 400 response. */
+  json400?: ShortenResponse;
+  /** json422 is the decoded body of the 422 response. */
+  json422?: HostBlocked;
+}
+
+/** ShortenTextResponse is the response of shortenText. */
+export interface ShortenTextResponse extends ClientResponse {
+  /** json422 is the decoded body of the 422 response. */
+  json422?: HostBlocked;
+}
+
+/** TransferURLResponse is the response of transferURL. */
+export interface TransferURLResponse extends ClientResponse {
+}
+
+/** UnshareURLResponse is the response of unshareURL. */
+export interface UnshareURLResponse extends ClientResponse {
+}
+
+/** UpdateURLResponse is the response of updateURL. */
+export interface UpdateURLResponse extends ClientResponse {
+  /** json200 is the decoded body of the 200 response. */
+  json200?: UpdatedURL;
+  /** json422 is the decoded body of the 422 response. */
+  json422?: HostBlocked;
+}
+
+/** UploadImportChunkResponse is the response of uploadImportChunk. */
+export interface UploadImportChunkResponse extends ClientResponse {
+}